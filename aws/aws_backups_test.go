@@ -0,0 +1,36 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"testing"
+)
+
+func TestParseS3URL(t *testing.T) {
+	bucket, key, err := parseS3URL("s3://mybucket/backups/db1.tar.gz")
+	if err != nil {
+		t.Fatalf("The URL should parse %s", err)
+	}
+	if bucket != "mybucket" || key != "backups/db1.tar.gz" {
+		t.Fatalf("Wrong bucket or key %s %s", bucket, key)
+	}
+	for _, bad := range []string{"mybucket/key", "s3://mybucket", "s3://mybucket/", "s3:///key", "gs://mybucket/key"} {
+		_, _, err = parseS3URL(bad)
+		if err == nil {
+			t.Fatalf("The URL %s should be refused", bad)
+		}
+	}
+}
@@ -16,11 +16,13 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/stardog-union/stardog-graviton/sdutils"
 )
@@ -59,16 +61,12 @@ func lineScanner(cliContext sdutils.AppContext, line string) *sdutils.ScanResult
 	return nil
 }
 
-func (a *awsPlugin) HaveImage(c sdutils.AppContext) bool {
-	amiMap, err := loadAmiAmp(c)
-	if err != nil {
-		return false
-	}
-	_, ok := amiMap[a.Region]
-	return ok
+func (a *awsPlugin) HaveImage(ctx context.Context, c sdutils.AppContext) bool {
+	_, err := findAmi(c, c.GetVersion(), a.Region)
+	return err == nil
 }
 
-func (a *awsPlugin) BuildImage(context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+func (a *awsPlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
 	context.Logf(sdutils.DEBUG, "Build AMI image\n")
 
 	neededEnvs := []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
@@ -97,6 +95,16 @@ func (a *awsPlugin) BuildImage(context sdutils.AppContext, sdReleaseFilePath str
 	if ami == "" {
 		ami = baseUbuntu1604[a.Region]
 	}
+	checksum, err := fileChecksum(sdReleaseFilePath)
+	if err != nil {
+		return err
+	}
+	logDir := path.Join(context.GetConfigDir(), "logs")
+	err = os.MkdirAll(logDir, 0755)
+	if err != nil {
+		return err
+	}
+	logPath := path.Join(logDir, fmt.Sprintf("packer-%s-%s-%d.log", version, a.Region, time.Now().Unix()))
 
 	workingDir := path.Join(dir, "etc/packer")
 	// packer build -machine-readable -var-file vars.json stardog.json
@@ -111,11 +119,12 @@ func (a *awsPlugin) BuildImage(context sdutils.AppContext, sdReleaseFilePath str
 		Path: cmdArray[0],
 		Args: cmdArray,
 		Dir:  workingDir,
+		Env:  append(os.Environ(), "PACKER_LOG=1", fmt.Sprintf("PACKER_LOG_PATH=%s", logPath)),
 	}
 
 	context.Logf(sdutils.DEBUG, "Start packer")
 	spin := sdutils.NewSpinner(context, 1, "Running packer to build the image")
-	results, err := sdutils.RunCommand(context, cmd, lineScanner, spin)
+	results, err := sdutils.RunCommand(ctx, context, cmd, lineScanner, spin)
 	if err != nil {
 		context.ConsoleLog(0, "We failed to build the image.  Please verify that you have sufficent EC2 access.")
 		return err
@@ -131,14 +140,19 @@ func (a *awsPlugin) BuildImage(context sdutils.AppContext, sdReleaseFilePath str
 	context.ConsoleLog(0, "AMI Successfully built: %s\n", (*results)[0].Value)
 	context.Logf(sdutils.DEBUG, "AMI Successfully built: %s\n", (*results)[0].Value)
 
-	amiMap, err := loadAmiAmp(context)
-	if err != nil {
-		return err
-	}
-	amiMap[a.Region] = (*results)[0].Value
-	err = saveAmiMap(context, amiMap)
+	cat, err := loadImageCatalog(context)
 	if err != nil {
 		return err
 	}
-	return nil
+	cat.add(sdutils.ImageDescription{
+		ID:              (*results)[0].Value,
+		Version:         version,
+		Region:          a.Region,
+		Architecture:    defaultArch,
+		BaseImage:       ami,
+		Built:           time.Now(),
+		ReleaseChecksum: checksum,
+		BuildLog:        logPath,
+	})
+	return cat.save()
 }
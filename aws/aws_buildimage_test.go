@@ -16,6 +16,7 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -58,13 +59,24 @@ func TestGoodPacker(t *testing.T) {
 	}
 
 	awsP := GetPlugin()
-	err = awsP.BuildImage(&app, "/etc/group", "4.2")
+	err = awsP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
 	if err != nil {
 		t.Fatalf("Packer failed %s", err)
 	}
-	if !awsP.HaveImage(&app) {
+	if !awsP.HaveImage(context.Background(), &app) {
 		t.Fatalf("The image should be there")
 	}
+	images, err := awsP.(sdutils.ImageCataloger).ListImages(context.Background(), &app)
+	if err != nil || len(images) != 1 {
+		t.Fatalf("The image should be in the catalog %s", err)
+	}
+	if images[0].ID != "ami-deadbeef" || images[0].Version != "4.2" || images[0].ReleaseChecksum == "" || images[0].BuildLog == "" {
+		t.Fatalf("The catalog entry is incomplete %v", images[0])
+	}
+	app.Version = "5.0"
+	if awsP.HaveImage(context.Background(), &app) {
+		t.Fatalf("There is no image for 5.0")
+	}
 }
 
 func TestBadRcPacker(t *testing.T) {
@@ -100,11 +112,11 @@ func TestBadRcPacker(t *testing.T) {
 	}
 
 	awsP := GetPlugin()
-	err = awsP.BuildImage(&app, "/etc/group", "4.2")
+	err = awsP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
 	if err == nil {
 		t.Fatalf("Should have failed")
 	}
-	if awsP.HaveImage(&app) {
+	if awsP.HaveImage(context.Background(), &app) {
 		t.Fatalf("The image should not be there")
 	}
 }
@@ -135,11 +147,11 @@ func TestBadAMIPacker(t *testing.T) {
 	}
 
 	awsP := GetPlugin()
-	err = awsP.BuildImage(&app, "/etc/group", "4.2")
+	err = awsP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
 	if err == nil {
 		t.Fatalf("Should have failed")
 	}
-	if awsP.HaveImage(&app) {
+	if awsP.HaveImage(context.Background(), &app) {
 		t.Fatalf("The image should not be there")
 	}
 }
@@ -153,7 +165,7 @@ func TestHaveNoAmi(t *testing.T) {
 		Version:   "4.2",
 	}
 	awsP := GetPlugin()
-	if awsP.HaveImage(&app) {
+	if awsP.HaveImage(context.Background(), &app) {
 		t.Fatalf("The image should not be there")
 	}
 }
@@ -16,12 +16,14 @@
 package aws
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
 
 	"time"
@@ -40,6 +42,8 @@ type awsDeploymentDescription struct {
 	HTTPMask        string `json:"http_mask,omitempty"`
 	VolumeType      string `json:"volume_type,omitempty"`
 	IoPsRatio       int    `json:"iops,omitempty"`
+	PreviousVersion string `json:"previous_version,omitempty"`
+	PreviousAmiID   string `json:"previous_ami_id,omitempty"`
 	Version         string `json:"-"`
 	Name            string `json:"-"`
 	deployDir       string
@@ -50,21 +54,23 @@ type awsDeploymentDescription struct {
 	plugin          *awsPlugin
 }
 
-func newAwsDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeployment, a *awsPlugin) (*awsDeploymentDescription, error) {
+func newAwsDeploymentDescription(ctx context.Context, c sdutils.AppContext, baseD *sdutils.BaseDeployment, a *awsPlugin) (*awsDeploymentDescription, error) {
 	var err error
 	createdKey := false
 
 	if a.AmiID == "" {
 		// If the ami is not specified look it up
-		amiMap, err := loadAmiAmp(c)
+		cat, err := loadImageCatalog(c)
 		if err != nil {
-			return nil, fmt.Errorf("Could not load the ami map: %s", err)
+			return nil, fmt.Errorf("Could not load the image catalog: %s", err)
 		}
-		ami, ok := amiMap[a.Region]
-		if !ok {
+		ami := ""
+		if img := cat.find(baseD.Version, a.Region, defaultArch); img != nil {
+			ami = img.ID
+		} else {
 			// No ami for the deployment
 			c.ConsoleLog(1, "A base AMI is required for launching the virtual appliance.  If you do not know this value you can build a new one with the 'baseami' command.\n")
-			ami, err = sdutils.AskUser("Stardog base AMI", "")
+			ami, err = c.Prompt("Stardog base AMI", "")
 			if err != nil {
 				return nil, err
 			}
@@ -75,19 +81,19 @@ func newAwsDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeploy
 		a.AmiID = ami
 	}
 	if a.Region == "" {
-		a.AwsKeyName, err = sdutils.AskUser("Region", "us-west-1")
+		a.AwsKeyName, err = c.Prompt("Region", "us-west-1")
 		if err != nil {
 			return nil, err
 		}
 	}
 	if a.AwsKeyName == "" && baseD.PrivateKey == "" {
-		if !c.GetInteractive() || sdutils.AskUserYesOrNo("Would you like to create an SSH key pair?") {
+		if !c.GetInteractive() || sdutils.AskYesOrNo(c, "Would you like to create an SSH key pair?") {
 			newKeyName := baseD.Name + "key"
 			privateKeyFilename, public, err := sdutils.GenerateKey(baseD.Directory, newKeyName)
 			if err != nil {
 				return nil, err
 			}
-			err = ImportKeyName(c, a, newKeyName, public)
+			err = ImportKeyName(ctx, c, a, newKeyName, public)
 			if err != nil {
 				return nil, err
 			}
@@ -97,13 +103,13 @@ func newAwsDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeploy
 		}
 	}
 	if a.AwsKeyName == "" {
-		a.AwsKeyName, err = sdutils.AskUser("EC2 keyname", "default")
+		a.AwsKeyName, err = c.Prompt("EC2 keyname", "default")
 		if err != nil {
 			return nil, err
 		}
 	}
 	if baseD.PrivateKey == "" {
-		baseD.PrivateKey, err = sdutils.AskUser("Private key path", "")
+		baseD.PrivateKey, err = c.Prompt("Private key path", "")
 		if err != nil {
 			return nil, err
 		}
@@ -119,7 +125,7 @@ func newAwsDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeploy
 	if fi.Mode()&0077 != 0 {
 		return nil, fmt.Errorf("The permissions on the private key %s must only allow for user access", baseD.PrivateKey)
 	}
-	b, err := CheckKeyName(c, a, a.AwsKeyName)
+	b, err := CheckKeyName(ctx, c, a, a.AwsKeyName)
 	if err != nil {
 		return nil, fmt.Errorf("There was an error checking the AWS environment: %s", err)
 	}
@@ -163,25 +169,25 @@ func newAwsDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeploy
 	return &dd, nil
 }
 
-func (dd *awsDeploymentDescription) DestroyDeployment() error {
+func (dd *awsDeploymentDescription) DestroyDeployment(ctx context.Context) error {
 	if dd.CreatedKey {
-		err := DeleteKeyPair(dd.ctx, dd.plugin, dd.AwsKeyName)
+		err := DeleteKeyPair(ctx, dd.ctx, dd.plugin, dd.AwsKeyName)
 		return err
 	}
 	return nil
 }
 
-func (dd *awsDeploymentDescription) CreateVolumeSet(licensePath string, sizeOfEachVolume int, clusterSize int) error {
+func (dd *awsDeploymentDescription) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
 	vm := NewAwsEbsVolumeManager(dd.ctx, dd)
-	return vm.CreateSet(licensePath, sizeOfEachVolume, clusterSize)
+	return vm.CreateSet(ctx, licensePath, sizeOfEachVolume, clusterSize)
 }
 
-func (dd *awsDeploymentDescription) DeleteVolumeSet() error {
+func (dd *awsDeploymentDescription) DeleteVolumeSet(ctx context.Context) error {
 	vm := NewAwsEbsVolumeManager(dd.ctx, dd)
 	if !vm.VolumeExists() {
 		return fmt.Errorf("No volume information exists for %s", dd.Name)
 	}
-	return vm.DeleteSet()
+	return vm.DeleteSet(ctx)
 }
 
 func (dd *awsDeploymentDescription) ClusterSize() (int, error) {
@@ -194,7 +200,7 @@ func (dd *awsDeploymentDescription) ClusterSize() (int, error) {
 		return -1, err
 	}
 	var size int
-	c, err := fmt.Sscanf(vols.ClusterSize, "%d", &size)
+	c, err := fmt.Sscanf(vols.nodeCount(), "%d", &size)
 	if err != nil {
 		return -1, err
 	}
@@ -204,12 +210,12 @@ func (dd *awsDeploymentDescription) ClusterSize() (int, error) {
 	return size, nil
 }
 
-func (dd *awsDeploymentDescription) StatusVolumeSet() error {
+func (dd *awsDeploymentDescription) StatusVolumeSet(ctx context.Context) error {
 	vm := NewAwsEbsVolumeManager(dd.ctx, dd)
 	if !vm.VolumeExists() {
 		return fmt.Errorf("No volume information exists for %s", dd.Name)
 	}
-	return vm.Status()
+	return vm.Status(ctx)
 }
 
 func (dd *awsDeploymentDescription) VolumeExists() bool {
@@ -217,41 +223,41 @@ func (dd *awsDeploymentDescription) VolumeExists() bool {
 	return vm.VolumeExists()
 }
 
-func (dd *awsDeploymentDescription) CreateInstance(volumeSize int, zookeeperSize int, idleTimeout int) error {
+func (dd *awsDeploymentDescription) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
 	im, err := NewEc2Instance(dd.ctx, dd)
 	if err != nil {
 		return err
 	}
-	return im.CreateInstance(volumeSize, zookeeperSize, idleTimeout)
+	return im.CreateInstance(ctx, volumeSize, zookeeperSize, idleTimeout)
 }
 
-func (dd *awsDeploymentDescription) OpenInstance(volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+func (dd *awsDeploymentDescription) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
 	im, err := NewEc2Instance(dd.ctx, dd)
 	if err != nil {
 		return err
 	}
-	return im.OpenInstance(volumeSize, zookeeperSize, mask, idleTimeout)
+	return im.OpenInstance(ctx, volumeSize, zookeeperSize, mask, idleTimeout)
 }
 
-func (dd *awsDeploymentDescription) DeleteInstance() error {
+func (dd *awsDeploymentDescription) DeleteInstance(ctx context.Context) error {
 	im, err := NewEc2Instance(dd.ctx, dd)
 	if err != nil {
 		return err
 	}
-	return im.DeleteInstance()
+	return im.DeleteInstance(ctx)
 }
 
-func (dd *awsDeploymentDescription) StatusInstance() error {
+func (dd *awsDeploymentDescription) StatusInstance(ctx context.Context) error {
 	im, err := NewEc2Instance(dd.ctx, dd)
 	if err != nil {
 		return err
 	}
-	return im.Status()
+	return im.Status(ctx)
 }
 
-func (dd *awsDeploymentDescription) FullStatus() (*sdutils.StardogDescription, error) {
+func (dd *awsDeploymentDescription) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
 	vm := NewAwsEbsVolumeManager(dd.ctx, dd)
-	volumeStatus, err := vm.getStatusInformation()
+	volumeStatus, err := vm.getStatusInformation(ctx)
 	if err != nil {
 		dd.ctx.ConsoleLog(1, "No volume information found %s\n", err)
 	}
@@ -260,7 +266,7 @@ func (dd *awsDeploymentDescription) FullStatus() (*sdutils.StardogDescription, e
 	if err != nil {
 		return nil, err
 	}
-	instS, err := getInstanceValues(im)
+	instS, err := getInstanceValues(ctx, im)
 	if err != nil {
 		dd.ctx.ConsoleLog(1, "No instance information found.\n")
 	}
@@ -285,6 +291,327 @@ func (dd *awsDeploymentDescription) InstanceExists() bool {
 	return im.InstanceExists()
 }
 
+// Scale changes the number of Stardog nodes.  Growing creates volumes only when
+// there are no spare ones left from an earlier scale down.  Shrinking drains
+// the nodes that are removed and then keeps or deletes their volumes.
+func (dd *awsDeploymentDescription) Scale(ctx context.Context, clusterSize int) error {
+	vm := NewAwsEbsVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadEbsVolume(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return err
+	}
+	vols.VolumeDir = vm.VolumeDir
+	vols.appContext = dd.ctx
+	current, err := dd.ClusterSize()
+	if err != nil {
+		return err
+	}
+	volumeCount, err := strconv.Atoi(vols.ClusterSize)
+	if err != nil {
+		return err
+	}
+	im, err := NewEc2Instance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	if !im.InstanceExists() {
+		return fmt.Errorf("There is no instance to scale in %s", dd.Name)
+	}
+	if clusterSize == current {
+		dd.ctx.ConsoleLog(1, "The deployment %s already has %d Stardog nodes.\n", dd.Name, current)
+		return nil
+	}
+
+	if clusterSize > current {
+		if clusterSize > volumeCount {
+			err = vols.Grow(ctx, clusterSize)
+			if err != nil {
+				return err
+			}
+		}
+		return dd.resize(ctx, im, vols, clusterSize, current, "Adding Stardog nodes...")
+	}
+
+	err = im.drainNodes(ctx, clusterSize, current)
+	if err != nil {
+		return err
+	}
+	err = dd.resize(ctx, im, vols, clusterSize, current, "Removing Stardog nodes...")
+	if err != nil {
+		return err
+	}
+	if !dd.deleteVolumes(volumeCount - clusterSize) {
+		dd.ctx.ConsoleLog(1, "Kept %d unused volumes.  They are used again when the deployment grows.\n", volumeCount-clusterSize)
+		return nil
+	}
+	return vols.Shrink(ctx, clusterSize)
+}
+
+// resize records the new node count with the volumes and applies it to the
+// instance.  The old count is put back if that fails.
+func (dd *awsDeploymentDescription) resize(ctx context.Context, im *Ec2Instance, vols *EbsVolumes, clusterSize int, current int, message string) error {
+	err := vols.setNodeCount(clusterSize)
+	if err != nil {
+		return err
+	}
+	err = im.Resize(ctx, message)
+	if err != nil {
+		serr := vols.setNodeCount(current)
+		if serr != nil {
+			dd.ctx.Logf(sdutils.WARN, "Failed to restore the node count %d: %s", current, serr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (dd *awsDeploymentDescription) deleteVolumes(count int) bool {
+	if count < 1 {
+		return false
+	}
+	scaleVolumes := "ask"
+	if dd.plugin != nil {
+		scaleVolumes = dd.plugin.ScaleVolumes
+	}
+	switch scaleVolumes {
+	case "delete":
+		return true
+	case "keep":
+		return false
+	}
+	if !dd.ctx.GetInteractive() {
+		return false
+	}
+	return sdutils.AskYesOrNo(dd.ctx, fmt.Sprintf("Delete the %d volumes that are no longer used?", count))
+}
+
+// ModifyVolumes changes the size, the type and the IOPS per gigabyte of the
+// volumes.  EBS volumes can only grow.  The IOPS are clamped the same way
+// they are when the volumes are created.
+func (dd *awsDeploymentDescription) ModifyVolumes(ctx context.Context, size int, volumeType string, iops int, waitTimeout int) error {
+	vols, err := dd.loadVolumes()
+	if err != nil {
+		return err
+	}
+	current, err := strconv.Atoi(vols.SizeOfEachVolume)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		size = current
+	}
+	if size < current {
+		return fmt.Errorf("EBS volumes cannot shrink from %d to %d gigabytes", current, size)
+	}
+	if volumeType == "" {
+		volumeType = vols.VolumeType
+	}
+	defaultRatio, ok := ValidVolumeTypes[volumeType]
+	if !ok {
+		return fmt.Errorf("%s is not a valid volume type", volumeType)
+	}
+	ratio := dd.IoPsRatio
+	if volumeType != vols.VolumeType {
+		ratio = defaultRatio
+	}
+	if iops != 0 {
+		if defaultRatio == 0 {
+			return fmt.Errorf("The IOPS of %s volumes cannot be set", volumeType)
+		}
+		ratio = iops
+	}
+	oldIOPS := vols.IoPs
+	vols.iopsRatio = ratio
+	vols.setIOPS(size)
+	if size == current && volumeType == vols.VolumeType && vols.IoPs == oldIOPS {
+		dd.ctx.ConsoleLog(1, "The volumes of %s already have these settings.\n", dd.Name)
+		return nil
+	}
+
+	err = vols.Modify(ctx, size, volumeType, ratio, waitTimeout)
+	if err != nil {
+		return err
+	}
+	dd.VolumeType = volumeType
+	dd.IoPsRatio = ratio
+	err = dd.saveConfig()
+	if err != nil {
+		return err
+	}
+	dd.ctx.ConsoleLog(1, "The volumes of %s are %s volumes of %d gigabytes.\n", dd.Name, volumeType, size)
+	return nil
+}
+
+// ResizeInstance changes the instance types of the Stardog and ZooKeeper nodes.
+// New launch configurations are created and then the node of each one node
+// autoscaling group is replaced, ZooKeeper first.  Nodes left on an old launch
+// configuration by an interrupted resize are replaced as well.
+func (dd *awsDeploymentDescription) ResizeInstance(ctx context.Context, sdInstanceType string, zkInstanceType string, waitTimeout int, creds sdutils.Credentials) error {
+	im, err := NewEc2Instance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	if !im.InstanceExists() {
+		return fmt.Errorf("There is no instance to resize in %s", dd.Name)
+	}
+	changed := false
+	if sdInstanceType != "" && sdInstanceType != dd.SdInstanceType {
+		dd.SdInstanceType = sdInstanceType
+		changed = true
+	}
+	if zkInstanceType != "" && zkInstanceType != dd.ZkInstanceType {
+		dd.ZkInstanceType = zkInstanceType
+		changed = true
+	}
+	if changed {
+		im, err = dd.newLaunchConfigurations(ctx, "Creating the new launch configurations...")
+		if err != nil {
+			return err
+		}
+	}
+	zkGroups, sdGroups, wait, err := dd.rollingGroups(im, waitTimeout, creds)
+	if err != nil {
+		return err
+	}
+	err = im.replaceNodes(ctx, append(zkGroups, sdGroups...), waitTimeout, wait)
+	if err != nil {
+		return err
+	}
+	dd.ctx.ConsoleLog(1, "All nodes run on the new instance types.\n")
+	return nil
+}
+
+// Upgrade moves the deployment to the base AMI of another Stardog version.
+// The first Stardog node is replaced on its own and when it does not come
+// back healthy the deployment is rolled back to the old AMI.  The version and
+// AMI the deployment ran before are kept in the deployment configuration.
+func (dd *awsDeploymentDescription) Upgrade(ctx context.Context, version string, waitTimeout int, creds sdutils.Credentials) error {
+	im, err := NewEc2Instance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	if !im.InstanceExists() {
+		return fmt.Errorf("There is no instance to upgrade in %s", dd.Name)
+	}
+	err = sdutils.CheckDataVersion(dd.Version, version, dd.PreviousVersion)
+	if err != nil {
+		return err
+	}
+	ami, err := findAmi(dd.ctx, version, dd.Region)
+	if err != nil {
+		return err
+	}
+
+	oldVersion, oldAmi := dd.Version, dd.AmiID
+	oldPreviousVersion, oldPreviousAmi := dd.PreviousVersion, dd.PreviousAmiID
+	dd.PreviousVersion, dd.PreviousAmiID = oldVersion, oldAmi
+	dd.Version, dd.AmiID = version, ami
+	im, err = dd.newLaunchConfigurations(ctx, fmt.Sprintf("Creating the launch configurations for Stardog %s...", version))
+	if err != nil {
+		return err
+	}
+	zkGroups, sdGroups, wait, err := dd.rollingGroups(im, waitTimeout, creds)
+	if err != nil {
+		return err
+	}
+	groups := append(sdGroups, zkGroups...)
+
+	err = im.replaceNodes(ctx, groups[:1], waitTimeout, wait)
+	if err != nil {
+		dd.ctx.ConsoleLog(0, "The first upgraded node did not become healthy: %s\n", err)
+		dd.Version, dd.AmiID = oldVersion, oldAmi
+		dd.PreviousVersion, dd.PreviousAmiID = oldPreviousVersion, oldPreviousAmi
+		im, rbErr := dd.newLaunchConfigurations(ctx, fmt.Sprintf("Restoring the launch configurations for Stardog %s...", oldVersion))
+		if rbErr == nil {
+			rbErr = im.replaceNodes(ctx, groups, waitTimeout, wait)
+		}
+		if rbErr != nil {
+			return fmt.Errorf("The upgrade failed (%s) and so did the roll back to Stardog %s: %s", err, oldVersion, rbErr)
+		}
+		return fmt.Errorf("The upgrade failed and the deployment was rolled back to Stardog %s: %s", oldVersion, err)
+	}
+	err = im.replaceNodes(ctx, groups[1:], waitTimeout, wait)
+	if err != nil {
+		dd.ctx.ConsoleLog(0, "The upgrade stopped before all nodes were replaced.  Run it again to finish it, or upgrade to %s to go back to the AMI %s.\n", oldVersion, oldAmi)
+		return err
+	}
+	dd.ctx.ConsoleLog(1, "All nodes run Stardog %s.  The deployment ran Stardog %s on the AMI %s before.\n", version, oldVersion, oldAmi)
+	return nil
+}
+
+// newLaunchConfigurations saves the deployment description and applies the
+// instance configuration again so that the autoscaling groups get launch
+// configurations for the new values.  The running nodes are not touched.
+func (dd *awsDeploymentDescription) newLaunchConfigurations(ctx context.Context, message string) (*Ec2Instance, error) {
+	err := dd.saveConfig()
+	if err != nil {
+		return nil, err
+	}
+	im, err := NewEc2Instance(dd.ctx, dd)
+	if err != nil {
+		return nil, err
+	}
+	err = im.Resize(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+// rollingGroups returns the ZooKeeper and Stardog groups of the instance and
+// the function that waits for the cluster to recover after one of their nodes
+// was replaced.  The cluster is checked with creds.
+func (dd *awsDeploymentDescription) rollingGroups(im *Ec2Instance, waitTimeout int, creds sdutils.Credentials) ([]nodeGroup, []nodeGroup, func(context.Context) error, error) {
+	var current Ec2Instance
+	err := sdutils.LoadJSON(&current, path.Join(dd.deployDir, "etc", "terraform", "instance", "instance.json"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	zkSize, err := strconv.Atoi(current.ZkSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sdSize, err := strconv.Atoi(current.SdSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	baseD := &sdutils.BaseDeployment{Name: dd.Name, PrivateKey: dd.PrivateKeyPath}
+	wait := func(ctx context.Context) error {
+		err := sdutils.WaitForHealth(ctx, dd.ctx, baseD, dd, waitTimeout, false)
+		if err != nil {
+			return err
+		}
+		sd, err := dd.FullStatus(ctx)
+		if err != nil {
+			return err
+		}
+		return sdutils.WaitForNClusterNodes(ctx, dd.ctx, sdSize, sd.StardogURL, creds, waitTimeout)
+	}
+	groups := im.nodeGroups(zkSize, sdSize)
+	return groups[:zkSize], groups[zkSize:], wait, nil
+}
+
+// saveConfig writes the deployment description back to the deployment
+// configuration so that later commands use the new values.
+func (dd *awsDeploymentDescription) saveConfig() error {
+	confPath := path.Join(dd.deployDir, "config.json")
+	var baseD sdutils.BaseDeployment
+	err := sdutils.LoadJSON(&baseD, confPath)
+	if err != nil {
+		return err
+	}
+	baseD.Version = dd.Version
+	baseD.CloudOpts = dd
+	data, err := json.Marshal(&baseD)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(confPath, data, 0600)
+}
+
 type awsPlugin struct {
 	Region         string `json:"region,omitempty"`
 	VolumeType     string `json:"volume_type,omitempty"`
@@ -294,6 +621,7 @@ type awsPlugin struct {
 	ZkInstanceType string `json:"zk_instance_type,omitempty"`
 	SdInstanceType string `json:"sd_instance_type,omitempty"`
 	BastionType    string `json:"bastion_instance_type,omitempty"`
+	ScaleVolumes   string `json:"-"`
 }
 
 // GetPlugin returns the plugin interface that this module represents.
@@ -307,6 +635,7 @@ func GetPlugin() sdutils.Plugin {
 		BastionType:    "t2.small",
 		VolumeType:     "gp2",
 		IoPs:           0,
+		ScaleVolumes:   "ask",
 	}
 }
 
@@ -336,6 +665,8 @@ func (a *awsPlugin) Register(cmdOpts *sdutils.CommandOpts) error {
 	cmdOpts.LaunchCmd.Flag("volume-type", fmt.Sprintf("The EBS volume type to use [%s]", strings.Join(GetValidVolumeTypes(), " | "))).Default(a.VolumeType).StringVar(&a.VolumeType)
 	cmdOpts.LaunchCmd.Flag("iops", "The IOPS for the volume type").IntVar(&a.IoPs)
 
+	cmdOpts.ScaleCmd.Flag("volumes", "What to do with the volumes of removed nodes [ask | keep | delete].").Default(a.ScaleVolumes).EnumVar(&a.ScaleVolumes, "ask", "keep", "delete")
+
 	cmdOpts.LeaksCmd.Flag("region", fmt.Sprintf("The aws region to use [%s]", strings.Join(ValidRegions, " | "))).Default(a.Region).StringVar(&a.Region)
 
 	cmdOpts.NewDeploymentCmd.Flag("region", fmt.Sprintf("The aws region to use [%s].", strings.Join(ValidRegions, " | "))).Default(a.Region).StringVar(&a.Region)
@@ -346,7 +677,7 @@ func (a *awsPlugin) Register(cmdOpts *sdutils.CommandOpts) error {
 	return nil
 }
 
-func (a *awsPlugin) DeploymentLoader(context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+func (a *awsPlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
 	neededEnvs := []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
 	for _, e := range neededEnvs {
 		if os.Getenv(e) == "" {
@@ -362,7 +693,7 @@ func (a *awsPlugin) DeploymentLoader(context sdutils.AppContext, baseD *sdutils.
 	}
 
 	if new {
-		awsDD, err := newAwsDeploymentDescription(context, baseD, a)
+		awsDD, err := newAwsDeploymentDescription(ctx, context, baseD, a)
 		if err != nil {
 			return nil, err
 		}
@@ -404,3 +735,7 @@ func (a *awsPlugin) DeploymentLoader(context sdutils.AppContext, baseD *sdutils.
 func (a *awsPlugin) GetName() string {
 	return "aws"
 }
+
+func (a *awsPlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true, Firewall: true, Scaling: true, Snapshots: true}
+}
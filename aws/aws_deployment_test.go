@@ -16,6 +16,7 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -101,12 +102,12 @@ func TestDeploymentLoadEnvs(t *testing.T) {
 		Version:    "4.2",
 		PrivateKey: sshKeyFile,
 	}
-	_, err := plugin.DeploymentLoader(&app, &baseD, true)
+	_, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
 	if err == nil {
 		t.Fatalf("The deployment should have failed")
 	}
 	os.Setenv("AWS_SECRET_ACCESS_KEY", keySave)
-	_, err = plugin.DeploymentLoader(&app, &baseD, true)
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
 	if err == nil {
 		t.Fatalf("The deployment should have failed")
 	}
@@ -150,7 +151,7 @@ func TestDeploymentLoadNoExes(t *testing.T) {
 		Version:    "4.2",
 		PrivateKey: sshKeyFile,
 	}
-	_, err := plugin.DeploymentLoader(&app, &baseD, true)
+	_, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
 	if err == nil {
 		t.Fatalf("The deployment should have failed")
 	}
@@ -205,12 +206,341 @@ func TestDeploymentLoadNew(t *testing.T) {
 		Version:    "4.2",
 		PrivateKey: sshKeyFile,
 	}
-	_, err = plugin.DeploymentLoader(&app, &baseD, true)
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
 	if err != nil {
 		t.Fatalf("The deployment should not have failed %s", err)
 	}
-	_, err = plugin.DeploymentLoader(&app, &baseD, false)
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, false)
 	if err != nil {
 		t.Fatalf("The deployment should not have failed %s", err)
 	}
 }
+
+func TestDeploymentScale(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshKeyFile := path.Join(dir, "keyfile")
+	ioutil.WriteFile(sshKeyFile, []byte("xxx"), 0600)
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("xxx"), 0600)
+
+	plugin := &awsPlugin{
+		Region:         "us-west-1",
+		AmiID:          "notreal",
+		AwsKeyName:     "somekey",
+		ZkInstanceType: "m3.large",
+		SdInstanceType: "m3.large",
+		VolumeType:     "gp2",
+		ScaleVolumes:   "keep",
+	}
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+	secretSave := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	defer os.Setenv("AWS_SECRET_ACCESS_KEY", secretSave)
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "gravitontest")
+
+	data := `{"volumes": { "sensitive": false, "type": "list", "value": ["vol-1", "vol-2", "vol-3", "vol-4", "vol-5"]}}`
+	exedirT, _, err := CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedirT)
+	exedirP, _, err := CreateTestExec("packer", "data", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedirP)
+
+	sPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", sPath)
+	os.Setenv("PATH", fmt.Sprintf("%s:%s:%s", exedirP, exedirT, sPath))
+
+	baseD := sdutils.BaseDeployment{
+		Type:       plugin.GetName(),
+		Name:       "testdep",
+		Directory:  dir,
+		Version:    "4.2",
+		PrivateKey: sshKeyFile,
+	}
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("The deployment should not have failed %s", err)
+	}
+	scaler := dep.(sdutils.Scaler)
+	err = scaler.Scale(context.Background(), 5)
+	if err == nil {
+		t.Fatalf("Scaling without volumes should fail")
+	}
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 1, 3)
+	if err != nil {
+		t.Fatalf("The volumes should have been created %s", err)
+	}
+	err = scaler.Scale(context.Background(), 5)
+	if err == nil {
+		t.Fatalf("Scaling without an instance should fail")
+	}
+	err = dep.CreateInstance(context.Background(), 8, 1, 60)
+	if err != nil {
+		t.Fatalf("The instance should have been created %s", err)
+	}
+
+	volDir := path.Join(sdutils.DeploymentDir(dir, baseD.Name), "etc", "terraform", "volumes")
+	instConf := path.Join(sdutils.DeploymentDir(dir, baseD.Name), "etc", "terraform", "instance", "instance.json")
+	checkSizes := func(nodes int, volumes string) {
+		size, err := dep.ClusterSize()
+		if err != nil || size != nodes {
+			t.Fatalf("The cluster should have %d nodes, not %d %s", nodes, size, err)
+		}
+		vols, err := LoadEbsVolume(&app, volDir)
+		if err != nil || vols.ClusterSize != volumes {
+			t.Fatalf("There should be %s volumes %s", volumes, err)
+		}
+		var inst Ec2Instance
+		err = sdutils.LoadJSON(&inst, instConf)
+		if err != nil || inst.SdSize != fmt.Sprintf("%d", nodes) {
+			t.Fatalf("The instance should have %d nodes %s", nodes, err)
+		}
+	}
+
+	err = scaler.Scale(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("Scaling up failed %s", err)
+	}
+	checkSizes(5, "5")
+	if sdutils.PathExists(path.Join(volDir, "builder.tf")) {
+		t.Fatalf("The volume builder should have been removed")
+	}
+
+	err = scaler.Scale(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Scaling down failed %s", err)
+	}
+	checkSizes(3, "5")
+
+	err = scaler.Scale(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("Scaling up onto the kept volumes failed %s", err)
+	}
+	checkSizes(4, "5")
+
+	plugin.ScaleVolumes = "delete"
+	err = scaler.Scale(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Scaling down failed %s", err)
+	}
+	checkSizes(3, "3")
+}
+
+func TestDeploymentResizeInstance(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshKeyFile := path.Join(dir, "keyfile")
+	ioutil.WriteFile(sshKeyFile, []byte("xxx"), 0600)
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("xxx"), 0600)
+
+	plugin := &awsPlugin{
+		Region:         "us-west-1",
+		AmiID:          "notreal",
+		AwsKeyName:     "somekey",
+		ZkInstanceType: "m3.large",
+		SdInstanceType: "m3.large",
+		VolumeType:     "gp2",
+	}
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+	secretSave := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	defer os.Setenv("AWS_SECRET_ACCESS_KEY", secretSave)
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "gravitontest")
+
+	data := `{"volumes": { "sensitive": false, "type": "list", "value": ["vol-1", "vol-2", "vol-3"]}}`
+	exedirT, _, err := CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedirT)
+	exedirP, _, err := CreateTestExec("packer", "data", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedirP)
+
+	sPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", sPath)
+	os.Setenv("PATH", fmt.Sprintf("%s:%s:%s", exedirP, exedirT, sPath))
+
+	baseD := sdutils.BaseDeployment{
+		Type:       plugin.GetName(),
+		Name:       "testdep",
+		Directory:  dir,
+		Version:    "4.2",
+		PrivateKey: sshKeyFile,
+	}
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("The deployment should not have failed %s", err)
+	}
+	resizer := dep.(sdutils.Resizer)
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 1, 3)
+	if err != nil {
+		t.Fatalf("The volumes should have been created %s", err)
+	}
+	err = resizer.ResizeInstance(context.Background(), "m4.xlarge", "", 60, sdutils.Credentials{Username: "admin", Password: "admin"})
+	if err == nil {
+		t.Fatalf("Resizing without an instance should fail")
+	}
+	err = dep.CreateInstance(context.Background(), 8, 1, 60)
+	if err != nil {
+		t.Fatalf("The instance should have been created %s", err)
+	}
+
+	err = resizer.ResizeInstance(context.Background(), "m4.xlarge", "", 60, sdutils.Credentials{Username: "admin", Password: "admin"})
+	if err != nil {
+		t.Fatalf("Resizing failed %s", err)
+	}
+
+	depDir := sdutils.DeploymentDir(dir, baseD.Name)
+	var inst Ec2Instance
+	err = sdutils.LoadJSON(&inst, path.Join(depDir, "etc", "terraform", "instance", "instance.json"))
+	if err != nil {
+		t.Fatalf("Failed to load the instance configuration %s", err)
+	}
+	if inst.SdInstanceType != "m4.xlarge" || inst.ZkInstanceType != "m3.large" {
+		t.Fatalf("The instance has the wrong types %s %s", inst.SdInstanceType, inst.ZkInstanceType)
+	}
+
+	baseD.CloudOpts = nil
+	err = sdutils.LoadJSON(&baseD, path.Join(depDir, "config.json"))
+	if err != nil {
+		t.Fatalf("Failed to load the deployment configuration %s", err)
+	}
+	loaded, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, false)
+	if err != nil {
+		t.Fatalf("The deployment should have loaded %s", err)
+	}
+	if loaded.(*awsDeploymentDescription).SdInstanceType != "m4.xlarge" {
+		t.Fatalf("The new Stardog instance type was not saved")
+	}
+}
+
+func TestDeploymentUpgrade(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshKeyFile := path.Join(dir, "keyfile")
+	ioutil.WriteFile(sshKeyFile, []byte("xxx"), 0600)
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("xxx"), 0600)
+
+	plugin := &awsPlugin{
+		Region:         "us-west-1",
+		AmiID:          "notreal",
+		AwsKeyName:     "somekey",
+		ZkInstanceType: "m3.large",
+		SdInstanceType: "m3.large",
+		VolumeType:     "gp2",
+	}
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+	secretSave := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	defer os.Setenv("AWS_SECRET_ACCESS_KEY", secretSave)
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "gravitontest")
+
+	data := `{"volumes": { "sensitive": false, "type": "list", "value": ["vol-1", "vol-2", "vol-3"]}}`
+	exedirT, _, err := CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedirT)
+	exedirP, _, err := CreateTestExec("packer", "data", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedirP)
+
+	sPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", sPath)
+	os.Setenv("PATH", fmt.Sprintf("%s:%s:%s", exedirP, exedirT, sPath))
+
+	baseD := sdutils.BaseDeployment{
+		Type:       plugin.GetName(),
+		Name:       "testdep",
+		Directory:  dir,
+		Version:    "4.2",
+		PrivateKey: sshKeyFile,
+	}
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("The deployment should not have failed %s", err)
+	}
+	upgrader := dep.(sdutils.Upgrader)
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 1, 3)
+	if err != nil {
+		t.Fatalf("The volumes should have been created %s", err)
+	}
+	err = dep.CreateInstance(context.Background(), 8, 1, 60)
+	if err != nil {
+		t.Fatalf("The instance should have been created %s", err)
+	}
+
+	err = upgrader.Upgrade(context.Background(), "4.2.1", 60, sdutils.Credentials{Username: "admin", Password: "admin"})
+	if err == nil {
+		t.Fatalf("Upgrading without an AMI for the version should fail")
+	}
+	err = ioutil.WriteFile(path.Join(dir, "amis-4.2.1.json"), []byte(`{"us-west-1": "ami-newer"}`), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write the AMI map %s", err)
+	}
+	err = upgrader.Upgrade(context.Background(), "5.0", 60, sdutils.Credentials{Username: "admin", Password: "admin"})
+	if err == nil {
+		t.Fatalf("Upgrading to a new major version should fail")
+	}
+	err = upgrader.Upgrade(context.Background(), "4.2.1", 60, sdutils.Credentials{Username: "admin", Password: "admin"})
+	if err != nil {
+		t.Fatalf("Upgrading failed %s", err)
+	}
+
+	depDir := sdutils.DeploymentDir(dir, baseD.Name)
+	var inst Ec2Instance
+	err = sdutils.LoadJSON(&inst, path.Join(depDir, "etc", "terraform", "instance", "instance.json"))
+	if err != nil {
+		t.Fatalf("Failed to load the instance configuration %s", err)
+	}
+	if inst.AmiID != "ami-newer" || inst.Version != "4.2.1" {
+		t.Fatalf("The instance was not moved to the new AMI %s %s", inst.AmiID, inst.Version)
+	}
+
+	baseD.CloudOpts = nil
+	err = sdutils.LoadJSON(&baseD, path.Join(depDir, "config.json"))
+	if err != nil {
+		t.Fatalf("Failed to load the deployment configuration %s", err)
+	}
+	if baseD.Version != "4.2.1" {
+		t.Fatalf("The new version was not saved")
+	}
+	loaded, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, false)
+	if err != nil {
+		t.Fatalf("The deployment should have loaded %s", err)
+	}
+	awsDD := loaded.(*awsDeploymentDescription)
+	if awsDD.PreviousVersion != "4.2" || awsDD.PreviousAmiID != "notreal" {
+		t.Fatalf("The previous version was not recorded %s %s", awsDD.PreviousVersion, awsDD.PreviousAmiID)
+	}
+}
@@ -0,0 +1,321 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// defaultArch is the architecture of the base Ubuntu AMIs that packer builds
+// on.
+const defaultArch = "x86_64"
+
+// imageCatalog lists the base AMIs that were built or copied by graviton.  It
+// is kept in images.json in the configuration directory.
+type imageCatalog struct {
+	Images []sdutils.ImageDescription `json:"images"`
+	path   string
+}
+
+func loadImageCatalog(c sdutils.AppContext) (*imageCatalog, error) {
+	cat := &imageCatalog{path: path.Join(c.GetConfigDir(), "images.json")}
+	if sdutils.PathExists(cat.path) {
+		err := sdutils.LoadJSON(cat, cat.path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := cat.importAmiMaps(c)
+	if err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+// importAmiMaps moves the entries of the amis-<version>.json files written by
+// older releases into the catalog.  The files are renamed afterwards so that
+// a deleted image does not come back.
+func (cat *imageCatalog) importAmiMaps(c sdutils.AppContext) error {
+	files, err := filepath.Glob(path.Join(c.GetConfigDir(), "amis-*.json"))
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	for _, f := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(path.Base(f), "amis-"), ".json")
+		amiMap, err := loadAmiAmp(c, version)
+		if err != nil {
+			return err
+		}
+		for region, id := range amiMap {
+			if cat.get(id) == nil {
+				cat.add(sdutils.ImageDescription{ID: id, Version: version, Region: region, Architecture: defaultArch})
+			}
+		}
+	}
+	err = cat.save()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		c.Logf(sdutils.INFO, "Imported the AMI file %s into the image catalog", f)
+		err = os.Rename(f, f+".imported")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cat *imageCatalog) save() error {
+	sort.Slice(cat.Images, func(i, j int) bool {
+		a, b := cat.Images[i], cat.Images[j]
+		if a.Version != b.Version {
+			return sdutils.CompareVersions(a.Version, b.Version) < 0
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Built.Before(b.Built)
+	})
+	return sdutils.WriteJSON(cat, cat.path)
+}
+
+func (cat *imageCatalog) get(id string) *sdutils.ImageDescription {
+	for i := range cat.Images {
+		if cat.Images[i].ID == id {
+			return &cat.Images[i]
+		}
+	}
+	return nil
+}
+
+// find returns the newest image of the Stardog version for the region and
+// architecture.
+func (cat *imageCatalog) find(version string, region string, arch string) *sdutils.ImageDescription {
+	var found *sdutils.ImageDescription
+	for i, img := range cat.Images {
+		if img.Version != version || img.Region != region || img.Architecture != arch {
+			continue
+		}
+		if found == nil || img.Built.After(found.Built) {
+			found = &cat.Images[i]
+		}
+	}
+	return found
+}
+
+func (cat *imageCatalog) add(img sdutils.ImageDescription) {
+	cat.remove(img.ID)
+	cat.Images = append(cat.Images, img)
+}
+
+func (cat *imageCatalog) remove(id string) {
+	images := []sdutils.ImageDescription{}
+	for _, img := range cat.Images {
+		if img.ID != id {
+			images = append(images, img)
+		}
+	}
+	cat.Images = images
+}
+
+// findAmi returns the AMI of the Stardog version for the region.
+func findAmi(c sdutils.AppContext, version string, region string) (string, error) {
+	cat, err := loadImageCatalog(c)
+	if err != nil {
+		return "", fmt.Errorf("Could not load the image catalog: %s", err)
+	}
+	img := cat.find(version, region, defaultArch)
+	if img == nil {
+		return "", fmt.Errorf("There is no base AMI for Stardog %s in %s", version, region)
+	}
+	return img.ID, nil
+}
+
+func fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func (a *awsPlugin) ListImages(ctx context.Context, c sdutils.AppContext) ([]sdutils.ImageDescription, error) {
+	cat, err := loadImageCatalog(c)
+	if err != nil {
+		return nil, err
+	}
+	return cat.Images, nil
+}
+
+// DeleteImage deregisters the AMI, deletes the snapshots behind it and takes
+// it out of the catalog.
+func (a *awsPlugin) DeleteImage(ctx context.Context, c sdutils.AppContext, id string) error {
+	cat, err := loadImageCatalog(c)
+	if err != nil {
+		return err
+	}
+	img := cat.get(id)
+	if img == nil {
+		return fmt.Errorf("The image %s is not in the catalog", id)
+	}
+	err = deregisterAmi(ctx, c, img.Region, id)
+	if err != nil {
+		return err
+	}
+	cat.remove(id)
+	return cat.save()
+}
+
+// CopyImage copies the AMI to another region and adds the copy to the
+// catalog.
+func (a *awsPlugin) CopyImage(ctx context.Context, c sdutils.AppContext, id string, region string) (*sdutils.ImageDescription, error) {
+	if !validRegion(region) {
+		return nil, fmt.Errorf("%s is not a valid region", region)
+	}
+	cat, err := loadImageCatalog(c)
+	if err != nil {
+		return nil, err
+	}
+	img := cat.get(id)
+	if img == nil {
+		return nil, fmt.Errorf("The image %s is not in the catalog", id)
+	}
+	if img.Region == region {
+		return nil, fmt.Errorf("The image %s is already in %s", id, region)
+	}
+	newID, err := copyAmi(ctx, c, img, region)
+	if err != nil {
+		return nil, err
+	}
+	cp := *img
+	cp.ID = newID
+	cp.Region = region
+	cp.SourceImage = img.ID
+	cat.add(cp)
+	err = cat.save()
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func validRegion(region string) bool {
+	for _, r := range ValidRegions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+func deregisterAmi(ctx context.Context, c sdutils.AppContext, region string, id string) error {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return nil
+	}
+	sess, err := session.NewSession()
+	if err != nil {
+		return err
+	}
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(region)})
+	resp, err := svc.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{ImageIds: []*string{aws.String(id)}})
+	if err != nil {
+		return err
+	}
+	snapshots := []*string{}
+	for _, image := range resp.Images {
+		for _, bd := range image.BlockDeviceMappings {
+			if bd.Ebs != nil && bd.Ebs.SnapshotId != nil {
+				snapshots = append(snapshots, bd.Ebs.SnapshotId)
+			}
+		}
+	}
+	c.ConsoleLog(1, "Deregistering the AMI %s.\n", id)
+	_, err = svc.DeregisterImageWithContext(ctx, &ec2.DeregisterImageInput{ImageId: aws.String(id)})
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		c.ConsoleLog(1, "Deleting the snapshot %s.\n", *s)
+		_, err = svc.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{SnapshotId: s})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyAmi(ctx context.Context, c sdutils.AppContext, img *sdutils.ImageDescription, region string) (string, error) {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return fmt.Sprintf("%s-%s", img.ID, region), nil
+	}
+	sess, err := session.NewSession()
+	if err != nil {
+		return "", err
+	}
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(region)})
+	input := ec2.CopyImageInput{
+		Name:          aws.String(fmt.Sprintf("stardog %s %s", img.Version, time.Now().Format("20060102150405"))),
+		Description:   aws.String(fmt.Sprintf("stardog graviton virtual appliance base ami %s copied from %s in %s", img.Version, img.ID, img.Region)),
+		SourceImageId: aws.String(img.ID),
+		SourceRegion:  aws.String(img.Region),
+	}
+	resp, err := svc.CopyImageWithContext(ctx, &input)
+	if err != nil {
+		return "", err
+	}
+	spin := sdutils.NewSpinner(c, 1, fmt.Sprintf("Copying the AMI %s to %s", img.ID, region))
+	defer spin.Close()
+	for {
+		images, err := svc.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{ImageIds: []*string{resp.ImageId}})
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to describe the AMI %s: %s", *resp.ImageId, err)
+		} else if len(images.Images) > 0 {
+			switch aws.StringValue(images.Images[0].State) {
+			case ec2.ImageStateAvailable:
+				return *resp.ImageId, nil
+			case ec2.ImageStateFailed, ec2.ImageStateError:
+				return "", fmt.Errorf("The copy %s of the AMI %s failed", *resp.ImageId, img.ID)
+			}
+		}
+		spin.EchoNext()
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,116 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestImageCatalogImportsAmiMaps(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	app := sdutils.TestContext{ConfigDir: dir, Version: "4.2"}
+
+	ioutil.WriteFile(path.Join(dir, "amis-4.2.json"), []byte(`{"us-west-1": "ami-42west", "us-east-1": "ami-42east"}`), 0600)
+	ioutil.WriteFile(path.Join(dir, "amis-5.0.json"), []byte(`{"us-west-1": "ami-50west"}`), 0600)
+
+	ami, err := findAmi(&app, "4.2", "us-east-1")
+	if err != nil || ami != "ami-42east" {
+		t.Fatalf("The imported image was not found %s %s", ami, err)
+	}
+	ami, err = findAmi(&app, "5.0", "us-west-1")
+	if err != nil || ami != "ami-50west" {
+		t.Fatalf("Building 5.0 should not hide the 4.2 image %s %s", ami, err)
+	}
+	if sdutils.PathExists(path.Join(dir, "amis-4.2.json")) {
+		t.Fatalf("The AMI file should have been moved aside")
+	}
+	_, err = findAmi(&app, "5.0", "us-east-1")
+	if err == nil {
+		t.Fatalf("There is no 5.0 image in us-east-1")
+	}
+}
+
+func TestImageCatalogFindsNewest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	app := sdutils.TestContext{ConfigDir: dir, Version: "4.2"}
+
+	cat, err := loadImageCatalog(&app)
+	if err != nil {
+		t.Fatalf("Failed to load the empty catalog %s", err)
+	}
+	now := time.Now()
+	cat.add(sdutils.ImageDescription{ID: "ami-new", Version: "4.2", Region: "us-west-1", Architecture: defaultArch, Built: now})
+	cat.add(sdutils.ImageDescription{ID: "ami-old", Version: "4.2", Region: "us-west-1", Architecture: defaultArch, Built: now.Add(-time.Hour)})
+	cat.add(sdutils.ImageDescription{ID: "ami-arm", Version: "4.2", Region: "us-west-1", Architecture: "arm64", Built: now.Add(time.Hour)})
+	err = cat.save()
+	if err != nil {
+		t.Fatalf("Failed to save the catalog %s", err)
+	}
+	ami, err := findAmi(&app, "4.2", "us-west-1")
+	if err != nil || ami != "ami-new" {
+		t.Fatalf("The newest image should have been found, not %s %s", ami, err)
+	}
+}
+
+func TestImageCopyAndDelete(t *testing.T) {
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	app := sdutils.TestContext{ConfigDir: dir, Version: "4.2"}
+	ioutil.WriteFile(path.Join(dir, "amis-4.2.json"), []byte(`{"us-west-1": "ami-42west"}`), 0600)
+
+	ic := GetPlugin().(sdutils.ImageCataloger)
+	_, err := ic.CopyImage(context.Background(), &app, "ami-42west", "nowhere")
+	if err == nil {
+		t.Fatalf("Copying to an unknown region should fail")
+	}
+	img, err := ic.CopyImage(context.Background(), &app, "ami-42west", "us-east-1")
+	if err != nil {
+		t.Fatalf("The copy failed %s", err)
+	}
+	if img.SourceImage != "ami-42west" || img.Version != "4.2" {
+		t.Fatalf("The copy does not describe its source %s %s", img.SourceImage, img.Version)
+	}
+	ami, err := findAmi(&app, "4.2", "us-east-1")
+	if err != nil || ami != img.ID {
+		t.Fatalf("The copy should be in the catalog %s %s", ami, err)
+	}
+
+	err = ic.DeleteImage(context.Background(), &app, "ami-42west")
+	if err != nil {
+		t.Fatalf("The delete failed %s", err)
+	}
+	images, err := ic.ListImages(context.Background(), &app)
+	if err != nil || len(images) != 1 || images[0].ID != img.ID {
+		t.Fatalf("Only the copy should be left %v %s", images, err)
+	}
+	err = ic.DeleteImage(context.Background(), &app, "ami-42west")
+	if err == nil {
+		t.Fatalf("Deleting an unknown image should fail")
+	}
+}
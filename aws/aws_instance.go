@@ -17,17 +17,28 @@ package aws
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/stardog-union/stardog-graviton/sdutils"
 )
 
+// drainTime is how long a Stardog node that is about to be removed keeps
+// running after it was taken out of the load balancers.
+var drainTime = 30 * time.Second
+
 // Ec2Instance represents an instance of a Stardog service in AWS.
 type Ec2Instance struct {
 	DeploymentName         string             `json:"deployment_name,omitempty"`
@@ -110,7 +121,7 @@ func volumeLineScanner(cliContext sdutils.AppContext, line string) *sdutils.Scan
 	return nil
 }
 
-func (awsI *Ec2Instance) runTerraformApply(volumeSize int, zookeeperSize int, mask string, idleTimeout int, message string) error {
+func (awsI *Ec2Instance) runTerraformApply(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int, message string) error {
 	awsI.ZkSize = fmt.Sprintf("%d", zookeeperSize)
 	awsI.ELBIdleTimeout = fmt.Sprintf("%d", idleTimeout)
 
@@ -119,7 +130,7 @@ func (awsI *Ec2Instance) runTerraformApply(volumeSize int, zookeeperSize int, ma
 		return err
 	}
 
-	awsI.SdSize = vol.ClusterSize
+	awsI.SdSize = vol.nodeCount()
 	awsI.HTTPMask = mask
 	awsI.RootVolumeType = "standard"
 	awsI.RootVolumeSize = volumeSize
@@ -149,7 +160,7 @@ func (awsI *Ec2Instance) runTerraformApply(volumeSize int, zookeeperSize int, ma
 	}
 	awsI.Ctx.Logf(sdutils.INFO, "Running terraform...\n")
 	spin := sdutils.NewSpinner(awsI.Ctx, 1, message)
-	_, err = sdutils.RunCommand(awsI.Ctx, cmd, volumeLineScanner, spin)
+	_, err = sdutils.RunCommand(ctx, awsI.Ctx, cmd, volumeLineScanner, spin)
 	if err != nil {
 		return err
 	}
@@ -157,8 +168,8 @@ func (awsI *Ec2Instance) runTerraformApply(volumeSize int, zookeeperSize int, ma
 }
 
 // CreateInstance will boot up a Stardog service in AWS.
-func (awsI *Ec2Instance) CreateInstance(volumeSize int, zookeeperSize int, idleTimeout int) error {
-	err := awsI.runTerraformApply(volumeSize, zookeeperSize, "0.0.0.0/32", idleTimeout, "Creating the instance VMs...")
+func (awsI *Ec2Instance) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	err := awsI.runTerraformApply(ctx, volumeSize, zookeeperSize, "0.0.0.0/32", idleTimeout, "Creating the instance VMs...")
 	if err != nil {
 		awsI.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
 		return err
@@ -169,8 +180,8 @@ func (awsI *Ec2Instance) CreateInstance(volumeSize int, zookeeperSize int, idleT
 
 // OpenInstance will open the firewall to allow incoming traffic to port 5821 from
 // the give CIDR.
-func (awsI *Ec2Instance) OpenInstance(volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
-	err := awsI.runTerraformApply(volumeSize, zookeeperSize, mask, idleTimeout, "Opening the firewall...")
+func (awsI *Ec2Instance) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	err := awsI.runTerraformApply(ctx, volumeSize, zookeeperSize, mask, idleTimeout, "Opening the firewall...")
 	if err != nil {
 		awsI.Ctx.ConsoleLog(1, "Failed to open up the instance.\n")
 		return err
@@ -179,8 +190,221 @@ func (awsI *Ec2Instance) OpenInstance(volumeSize int, zookeeperSize int, mask st
 	return nil
 }
 
+// Resize applies the instance configuration again so that the number of
+// Stardog autoscaling groups matches the node count of the volume set.
+func (awsI *Ec2Instance) Resize(ctx context.Context, message string) error {
+	instanceConfPath := path.Join(awsI.DeployDir, "etc", "terraform", "instance", "instance.json")
+	var current Ec2Instance
+	err := sdutils.LoadJSON(&current, instanceConfPath)
+	if err != nil {
+		return err
+	}
+	zkSize, err := strconv.Atoi(current.ZkSize)
+	if err != nil {
+		return err
+	}
+	idleTimeout, err := strconv.Atoi(current.ELBIdleTimeout)
+	if err != nil {
+		return err
+	}
+	err = awsI.runTerraformApply(ctx, current.RootVolumeSize, zkSize, current.HTTPMask, idleTimeout, message)
+	if err != nil {
+		awsI.Ctx.ConsoleLog(1, "Failed to resize the instance.\n")
+		return err
+	}
+	awsI.Ctx.ConsoleLog(1, "Successfully resized the instance.\n")
+	return nil
+}
+
+// drainNodes takes the Stardog nodes of the autoscaling groups from first up
+// to last out of the load balancers and gives them drainTime to finish the
+// requests they are working on.
+func (awsI *Ec2Instance) drainNodes(ctx context.Context, first int, last int) error {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return nil
+	}
+	conf := aws.Config{Region: aws.String(awsI.Region)}
+	sess, err := session.NewSession()
+	if err != nil {
+		return err
+	}
+	names := []*string{}
+	for i := first; i < last; i++ {
+		names = append(names, aws.String(fmt.Sprintf("%ssdasg%d", awsI.DeploymentName, i)))
+	}
+	asgSvc := autoscaling.New(sess, &conf)
+	resp, err := asgSvc.DescribeAutoScalingGroupsWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: names})
+	if err != nil {
+		return err
+	}
+	instances := []*elb.Instance{}
+	for _, g := range resp.AutoScalingGroups {
+		for _, inst := range g.Instances {
+			instances = append(instances, &elb.Instance{InstanceId: inst.InstanceId})
+		}
+	}
+	if len(instances) == 0 {
+		return nil
+	}
+
+	awsI.Ctx.ConsoleLog(1, "Draining %d Stardog nodes.\n", len(instances))
+	elbSvc := elb.New(sess, &conf)
+	for _, lb := range []string{"sdelb", "sdielb"} {
+		input := elb.DeregisterInstancesFromLoadBalancerInput{
+			LoadBalancerName: aws.String(awsI.DeploymentName + lb),
+			Instances:        instances,
+		}
+		_, err = elbSvc.DeregisterInstancesFromLoadBalancerWithContext(ctx, &input)
+		if err != nil {
+			return err
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(drainTime):
+	}
+	return nil
+}
+
+// nodeGroup is a one node autoscaling group and the load balancer that checks
+// the health of its node.
+type nodeGroup struct {
+	asg string
+	elb string
+}
+
+// nodeGroups returns the zookeeper groups followed by the Stardog groups.
+func (awsI *Ec2Instance) nodeGroups(zkSize int, sdSize int) []nodeGroup {
+	groups := []nodeGroup{}
+	for i := 0; i < zkSize; i++ {
+		groups = append(groups, nodeGroup{
+			asg: fmt.Sprintf("%szkasg%d", awsI.DeploymentName, i),
+			elb: fmt.Sprintf("%szkelb%d", awsI.DeploymentName, i),
+		})
+	}
+	for i := 0; i < sdSize; i++ {
+		groups = append(groups, nodeGroup{
+			asg: fmt.Sprintf("%ssdasg%d", awsI.DeploymentName, i),
+			elb: fmt.Sprintf("%ssdelb", awsI.DeploymentName),
+		})
+	}
+	return groups
+}
+
+// replaceNodes terminates the nodes that still run on an old launch
+// configuration, one group at a time.  The autoscaling group starts a new
+// node from its current launch configuration.  Once that node is in service
+// wait has to report that the cluster recovered before the next group goes.
+func (awsI *Ec2Instance) replaceNodes(ctx context.Context, groups []nodeGroup, waitTimeout int, wait func(context.Context) error) error {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return nil
+	}
+	conf := aws.Config{Region: aws.String(awsI.Region)}
+	sess, err := session.NewSession()
+	if err != nil {
+		return err
+	}
+	asgSvc := autoscaling.New(sess, &conf)
+	elbSvc := elb.New(sess, &conf)
+
+	for _, g := range groups {
+		group, err := describeGroup(ctx, asgSvc, g.asg)
+		if err != nil {
+			return err
+		}
+		old := oldInstances(group)
+		if len(old) == 0 {
+			awsI.Ctx.Logf(sdutils.DEBUG, "The node of %s is up to date", g.asg)
+			continue
+		}
+		awsI.Ctx.ConsoleLog(1, "Replacing the node of %s.\n", g.asg)
+		for _, id := range old {
+			input := autoscaling.TerminateInstanceInAutoScalingGroupInput{
+				InstanceId:                     id,
+				ShouldDecrementDesiredCapacity: aws.Bool(false),
+			}
+			_, err = asgSvc.TerminateInstanceInAutoScalingGroupWithContext(ctx, &input)
+			if err != nil {
+				return err
+			}
+		}
+		err = awsI.waitForNewNode(ctx, asgSvc, elbSvc, g, waitTimeout)
+		if err != nil {
+			return err
+		}
+		err = wait(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeGroup(ctx context.Context, svc *autoscaling.AutoScaling, name string) (*autoscaling.Group, error) {
+	input := autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: []*string{aws.String(name)}}
+	resp, err := svc.DescribeAutoScalingGroupsWithContext(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.AutoScalingGroups) != 1 {
+		return nil, fmt.Errorf("The autoscaling group %s does not exist", name)
+	}
+	return resp.AutoScalingGroups[0], nil
+}
+
+// oldInstances returns the instances of the group that were not started from
+// its current launch configuration.
+func oldInstances(group *autoscaling.Group) []*string {
+	old := []*string{}
+	for _, inst := range group.Instances {
+		if inst.LaunchConfigurationName == nil || group.LaunchConfigurationName == nil ||
+			*inst.LaunchConfigurationName != *group.LaunchConfigurationName {
+			old = append(old, inst.InstanceId)
+		}
+	}
+	return old
+}
+
+// waitForNewNode blocks until the group has only a node from its current
+// launch configuration and the load balancer reports that node in service.
+func (awsI *Ec2Instance) waitForNewNode(ctx context.Context, asgSvc *autoscaling.AutoScaling, elbSvc *elb.ELB, g nodeGroup, waitTimeout int) error {
+	pollInterval := 5
+	itCnt := waitTimeout / pollInterval
+	spin := sdutils.NewSpinner(awsI.Ctx, 1, fmt.Sprintf("Waiting for the new node of %s", g.asg))
+	for i := 0; ; i++ {
+		group, err := describeGroup(ctx, asgSvc, g.asg)
+		if err != nil {
+			awsI.Ctx.Logf(sdutils.WARN, "Failed to describe %s: %s", g.asg, err)
+		} else if len(group.Instances) > 0 && len(oldInstances(group)) == 0 {
+			input := elb.DescribeInstanceHealthInput{
+				LoadBalancerName: aws.String(g.elb),
+				Instances:        []*elb.Instance{{InstanceId: group.Instances[0].InstanceId}},
+			}
+			resp, err := elbSvc.DescribeInstanceHealthWithContext(ctx, &input)
+			if err != nil {
+				awsI.Ctx.Logf(sdutils.WARN, "Failed to get the health of %s: %s", *group.Instances[0].InstanceId, err)
+			} else if len(resp.InstanceStates) > 0 && aws.StringValue(resp.InstanceStates[0].State) == "InService" {
+				spin.Close()
+				return nil
+			}
+		}
+		if i >= itCnt {
+			spin.Close()
+			return fmt.Errorf("Timed out waiting for the new node of %s", g.asg)
+		}
+		spin.EchoNext()
+		select {
+		case <-ctx.Done():
+			spin.Close()
+			return ctx.Err()
+		case <-time.After(time.Duration(pollInterval) * time.Second):
+		}
+	}
+}
+
 // DeleteInstance will teardown the Stardog service.
-func (awsI *Ec2Instance) DeleteInstance() error {
+func (awsI *Ec2Instance) DeleteInstance(ctx context.Context) error {
 	instanceWorkingDir := path.Join(awsI.DeployDir, "etc", "terraform", "instance")
 	instanceConfPath := path.Join(instanceWorkingDir, "instance.json")
 	if !sdutils.PathExists(instanceConfPath) {
@@ -198,7 +422,7 @@ func (awsI *Ec2Instance) DeleteInstance() error {
 	}
 	awsI.Ctx.Logf(sdutils.INFO, "Running terraform...\n")
 	spin := sdutils.NewSpinner(awsI.Ctx, 1, "Deleting the instance VMs")
-	_, err = sdutils.RunCommand(awsI.Ctx, cmd, volumeLineScanner, spin)
+	_, err = sdutils.RunCommand(ctx, awsI.Ctx, cmd, volumeLineScanner, spin)
 	if err != nil {
 		return err
 	}
@@ -224,7 +448,7 @@ type OutputEntry struct {
 	Value     interface{} `json:"value,omitempty"`
 }
 
-func getInstanceValues(awsI *Ec2Instance) (*InstanceStatusDescription, error) {
+func getInstanceValues(ctx context.Context, awsI *Ec2Instance) (*InstanceStatusDescription, error) {
 	instanceWorkingDir := path.Join(awsI.DeployDir, "etc", "terraform", "instance")
 	instanceConfPath := path.Join(instanceWorkingDir, "instance.json")
 	if !sdutils.PathExists(instanceConfPath) {
@@ -234,12 +458,8 @@ func getInstanceValues(awsI *Ec2Instance) (*InstanceStatusDescription, error) {
 	if err != nil {
 		return nil, err
 	}
-	cmdArray := []string{terraformPath, "output", "-json"}
-	cmd := exec.Cmd{
-		Path: cmdArray[0],
-		Args: cmdArray,
-		Dir:  instanceWorkingDir,
-	}
+	cmd := exec.CommandContext(ctx, terraformPath, "output", "-json")
+	cmd.Dir = instanceWorkingDir
 	data, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -268,8 +488,8 @@ func getInstanceValues(awsI *Ec2Instance) (*InstanceStatusDescription, error) {
 }
 
 // Status will print the status of the ec2 instance.
-func (awsI *Ec2Instance) Status() error {
-	_, err := getInstanceValues(awsI)
+func (awsI *Ec2Instance) Status(ctx context.Context) error {
+	_, err := getInstanceValues(ctx, awsI)
 	if err != nil {
 		return err
 	}
@@ -16,6 +16,7 @@
 package aws
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path"
@@ -57,7 +58,7 @@ func TestInstanceNotThere(t *testing.T) {
 		Version:    version,
 		PrivateKey: sshKeyFile,
 	}
-	dd, err := newAwsDeploymentDescription(&app, &baseD, plugin)
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
 	if err != nil {
 		t.Fatalf("Failed to make the deployment manager %s", err)
 	}
@@ -66,19 +67,19 @@ func TestInstanceNotThere(t *testing.T) {
 	if inst.InstanceExists() {
 		t.Fatalf("The instance should not exist")
 	}
-	err = inst.DeleteInstance()
+	err = inst.DeleteInstance(context.Background())
 	if err == nil {
 		t.Fatalf("The instance should not exist for deletion")
 	}
-	err = inst.Status()
+	err = inst.Status(context.Background())
 	if err == nil {
 		t.Fatalf("The instance should not exist for status")
 	}
-	err = inst.CreateInstance(8, 1, 60)
+	err = inst.CreateInstance(context.Background(), 8, 1, 60)
 	if err == nil {
 		t.Fatalf("The instance should not exist for client")
 	}
-	err = inst.OpenInstance(8, 1, "0.0.0.0/0", 60)
+	err = inst.OpenInstance(context.Background(), 8, 1, "0.0.0.0/0", 60)
 	if err == nil {
 		t.Fatalf("The instance should not exist for client")
 	}
@@ -117,7 +118,7 @@ func TestInstanceFakeTerraform(t *testing.T) {
 		Version:    version,
 		PrivateKey: sshKeyFile,
 	}
-	dd, err := newAwsDeploymentDescription(&app, &baseD, plugin)
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
 	if err != nil {
 		t.Fatalf("Failed to make the deployment manager %s", err)
 	}
@@ -163,24 +164,24 @@ func TestInstanceFakeTerraform(t *testing.T) {
 	defer os.RemoveAll(exedir)
 
 	ebs := NewAwsEbsVolumeManager(&app, dd)
-	err = ebs.CreateSet("/path/", 1, 3)
+	err = ebs.CreateSet(context.Background(), "/path/", 1, 3)
 	if err != nil {
 		t.Fatalf("The create should have worked")
 	}
 
-	err = inst.CreateInstance(8, 1, 60)
+	err = inst.CreateInstance(context.Background(), 8, 1, 60)
 	if err != nil {
 		t.Fatalf("The instance should exist for client %s", err)
 	}
-	err = inst.OpenInstance(8, 1, "0.0.0.0/0", 60)
+	err = inst.OpenInstance(context.Background(), 8, 1, "0.0.0.0/0", 60)
 	if err != nil {
 		t.Fatalf("The instance should exist for client %s", err)
 	}
-	err = inst.Status()
+	err = inst.Status(context.Background())
 	if err != nil {
 		t.Fatalf("The instance should exist for status %s", err)
 	}
-	err = inst.DeleteInstance()
+	err = inst.DeleteInstance(context.Background())
 	if err != nil {
 		t.Fatalf("The instance should exist for deletion %s", err)
 	}
@@ -219,7 +220,7 @@ func TestInstanceFakeTerraformThroughDeployment(t *testing.T) {
 		Version:    version,
 		PrivateKey: sshKeyFile,
 	}
-	dd, err := newAwsDeploymentDescription(&app, &baseD, plugin)
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
 	if err != nil {
 		t.Fatalf("Failed to make the deployment manager %s", err)
 	}
@@ -260,34 +261,34 @@ func TestInstanceFakeTerraformThroughDeployment(t *testing.T) {
 	defer os.RemoveAll(exedir)
 
 	ebs := NewAwsEbsVolumeManager(&app, dd)
-	err = ebs.CreateSet("/path/", 1, 3)
+	err = ebs.CreateSet(context.Background(), "/path/", 1, 3)
 	if err != nil {
 		t.Fatalf("The create should have worked")
 	}
 
-	err = dd.CreateInstance(8, 1, 60)
+	err = dd.CreateInstance(context.Background(), 8, 1, 60)
 	if err != nil {
 		t.Fatalf("The instance should exist for client %s", err)
 	}
-	err = dd.OpenInstance(8, 1, "0.0.0.0/0", 60)
+	err = dd.OpenInstance(context.Background(), 8, 1, "0.0.0.0/0", 60)
 	if err != nil {
 		t.Fatalf("The instance should exist for client %s", err)
 	}
-	err = dd.StatusInstance()
+	err = dd.StatusInstance(context.Background())
 	if err != nil {
 		t.Fatalf("The instance should exist for status %s", err)
 	}
 	if !dd.InstanceExists() {
 		t.Fatalf("The instance should exist for status %s", err)
 	}
-	sd, err := dd.FullStatus()
+	sd, err := dd.FullStatus(context.Background())
 	if err != nil {
 		t.Fatalf("The instance should exist for deletion %s", err)
 	}
 	if sd.SSHHost != "bastion.com" {
 		t.Fatalf("The bastion host was not correct")
 	}
-	err = dd.DeleteInstance()
+	err = dd.DeleteInstance(context.Background())
 	if err != nil {
 		t.Fatalf("The instance should exist for deletion %s", err)
 	}
@@ -326,20 +327,20 @@ func TestInstanceNotThereThroughDd(t *testing.T) {
 		Version:    version,
 		PrivateKey: sshKeyFile,
 	}
-	dd, err := newAwsDeploymentDescription(&app, &baseD, plugin)
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
 	if err != nil {
 		t.Fatalf("Failed to make the deployment manager %s", err)
 	}
 
-	err = dd.DeleteInstance()
+	err = dd.DeleteInstance(context.Background())
 	if err == nil {
 		t.Fatalf("The instance should not exist for deletion")
 	}
-	err = dd.StatusInstance()
+	err = dd.StatusInstance(context.Background())
 	if err == nil {
 		t.Fatalf("The instance should not exist for status")
 	}
-	err = dd.CreateInstance(8, 1, 60)
+	err = dd.CreateInstance(context.Background(), 8, 1, 60)
 	if err == nil {
 		t.Fatalf("The instance should not exist for client")
 	}
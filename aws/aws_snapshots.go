@@ -0,0 +1,333 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// The tags put on every snapshot of a set.  The volume index is the position
+// of the volume in the terraform volume list so that a restored node gets the
+// data of the same node.
+const (
+	snapshotSetTag        = "StardogSnapshotSet"
+	snapshotDeploymentTag = "StardogVirtualAppliance"
+	snapshotIndexTag      = "StardogVolumeIndex"
+	snapshotCountTag      = "StardogVolumeCount"
+	snapshotVersionTag    = "StardogVersion"
+)
+
+func (dd *awsDeploymentDescription) loadVolumes() (*EbsVolumes, error) {
+	vm := NewAwsEbsVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return nil, fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadEbsVolume(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return nil, err
+	}
+	vols.VolumeDir = vm.VolumeDir
+	vols.appContext = dd.ctx
+	return vols, nil
+}
+
+// SnapshotVolumes starts a snapshot of each volume that backs a Stardog node.
+// Volumes kept unused after scaling down are left out.
+func (dd *awsDeploymentDescription) SnapshotVolumes(ctx context.Context) (*sdutils.SnapshotDescription, error) {
+	vols, err := dd.loadVolumes()
+	if err != nil {
+		return nil, err
+	}
+	nodeCount, err := dd.ClusterSize()
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.Atoi(vols.SizeOfEachVolume)
+	if err != nil {
+		return nil, err
+	}
+	status, err := vols.getStatusInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(status.VolumeIds) < nodeCount {
+		return nil, fmt.Errorf("The deployment %s has %d volumes for %d nodes", dd.Name, len(status.VolumeIds), nodeCount)
+	}
+	now := time.Now().UTC()
+	set := &sdutils.SnapshotDescription{
+		ID:         fmt.Sprintf("%s-%s", dd.Name, now.Format("20060102150405")),
+		Deployment: dd.Name,
+		Version:    dd.Version,
+		Created:    now,
+		VolumeSize: size,
+	}
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		for i := range status.VolumeIds[:nodeCount] {
+			set.SnapshotIDs = append(set.SnapshotIDs, fmt.Sprintf("snap-%s-%d", set.ID, i))
+		}
+		set.Complete = true
+		return set, nil
+	}
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(dd.Region)})
+	for i, volID := range status.VolumeIds[:nodeCount] {
+		snap, err := svc.CreateSnapshotWithContext(ctx, &ec2.CreateSnapshotInput{
+			VolumeId:    aws.String(volID),
+			Description: aws.String(fmt.Sprintf("Volume %d of the Stardog snapshot set %s", i, set.ID)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to snapshot the volume %s: %s", volID, err)
+		}
+		dd.ctx.ConsoleLog(1, "Started the snapshot %s of the volume %s.\n", *snap.SnapshotId, volID)
+		set.SnapshotIDs = append(set.SnapshotIDs, *snap.SnapshotId)
+		_, err = svc.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+			Resources: []*string{snap.SnapshotId},
+			Tags: []*ec2.Tag{
+				{Key: aws.String("Name"), Value: aws.String(fmt.Sprintf("%s-%d", set.ID, i))},
+				{Key: aws.String(snapshotSetTag), Value: aws.String(set.ID)},
+				{Key: aws.String(snapshotDeploymentTag), Value: aws.String(dd.Name)},
+				{Key: aws.String(snapshotIndexTag), Value: aws.String(strconv.Itoa(i))},
+				{Key: aws.String(snapshotCountTag), Value: aws.String(strconv.Itoa(nodeCount))},
+				{Key: aws.String(snapshotVersionTag), Value: aws.String(dd.Version)},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to tag the snapshot %s: %s", *snap.SnapshotId, err)
+		}
+	}
+	return set, nil
+}
+
+// ListSnapshots returns the snapshot sets of every deployment in the region of
+// this one, oldest first.
+func (dd *awsDeploymentDescription) ListSnapshots(ctx context.Context) ([]sdutils.SnapshotDescription, error) {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return nil, nil
+	}
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(dd.Region)})
+	resp, err := svc.DescribeSnapshotsWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		OwnerIds: []*string{aws.String("self")},
+		Filters: []*ec2.Filter{
+			{Name: aws.String("tag-key"), Values: []*string{aws.String(snapshotSetTag)}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshotSets(resp.Snapshots), nil
+}
+
+type indexedSnapshot struct {
+	index int
+	id    string
+}
+
+// snapshotSets groups the snapshots by their set tag.  A set is complete once
+// every snapshot it was started with has completed.
+func snapshotSets(snapshots []*ec2.Snapshot) []sdutils.SnapshotDescription {
+	sets := make(map[string]*sdutils.SnapshotDescription)
+	members := make(map[string][]indexedSnapshot)
+	counts := make(map[string]int)
+	completed := make(map[string]bool)
+	for _, snap := range snapshots {
+		tags := make(map[string]string)
+		for _, t := range snap.Tags {
+			tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+		}
+		id := tags[snapshotSetTag]
+		set, ok := sets[id]
+		if !ok {
+			set = &sdutils.SnapshotDescription{
+				ID:         id,
+				Deployment: tags[snapshotDeploymentTag],
+				Version:    tags[snapshotVersionTag],
+				Created:    aws.TimeValue(snap.StartTime),
+			}
+			sets[id] = set
+			counts[id], _ = strconv.Atoi(tags[snapshotCountTag])
+			completed[id] = true
+		}
+		if snap.StartTime != nil && snap.StartTime.Before(set.Created) {
+			set.Created = *snap.StartTime
+		}
+		if size := int(aws.Int64Value(snap.VolumeSize)); size > set.VolumeSize {
+			set.VolumeSize = size
+		}
+		if aws.StringValue(snap.State) != ec2.SnapshotStateCompleted {
+			completed[id] = false
+		}
+		index, err := strconv.Atoi(tags[snapshotIndexTag])
+		if err != nil {
+			completed[id] = false
+		}
+		members[id] = append(members[id], indexedSnapshot{index: index, id: aws.StringValue(snap.SnapshotId)})
+	}
+
+	result := []sdutils.SnapshotDescription{}
+	for id, set := range sets {
+		m := members[id]
+		sort.Slice(m, func(i, j int) bool { return m[i].index < m[j].index })
+		for _, s := range m {
+			set.SnapshotIDs = append(set.SnapshotIDs, s.id)
+		}
+		set.Complete = completed[id] && len(m) == counts[id]
+		result = append(result, *set)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.Before(result[j].Created) })
+	return result
+}
+
+func (dd *awsDeploymentDescription) findSnapshotSet(ctx context.Context, id string) (*sdutils.SnapshotDescription, error) {
+	sets, err := dd.ListSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sets {
+		if sets[i].ID == id {
+			return &sets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("The snapshot set %s does not exist in %s", id, dd.Region)
+}
+
+// DeleteSnapshots deletes every snapshot of the set id.
+func (dd *awsDeploymentDescription) DeleteSnapshots(ctx context.Context, id string) error {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return nil
+	}
+	set, err := dd.findSnapshotSet(ctx, id)
+	if err != nil {
+		return err
+	}
+	return deleteSnapshotIDs(ctx, dd.ctx, dd.Region, set.SnapshotIDs)
+}
+
+// RestoreVolumes creates the volume set of this deployment from the snapshot
+// set id.  The deployment must not have volumes yet.
+func (dd *awsDeploymentDescription) RestoreVolumes(ctx context.Context, id string) error {
+	vm := NewAwsEbsVolumeManager(dd.ctx, dd)
+	if vm.VolumeExists() {
+		return fmt.Errorf("The deployment %s already has volumes", dd.Name)
+	}
+	set, err := dd.findSnapshotSet(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !set.Complete {
+		return fmt.Errorf("The snapshot set %s is not complete", id)
+	}
+	return vm.RestoreSet(ctx, set.SnapshotIDs, set.VolumeSize)
+}
+
+// snapshotAndWait snapshots each volume and waits until all of the snapshots
+// are complete.  The snapshot IDs are returned in the order of the volumes.
+func snapshotAndWait(ctx context.Context, c sdutils.AppContext, region string, volumeIds []string, description string, waitTimeout int) ([]string, error) {
+	ids := []string{}
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		for _, volID := range volumeIds {
+			ids = append(ids, "snap-"+volID)
+		}
+		return ids, nil
+	}
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(region)})
+	for _, volID := range volumeIds {
+		snap, err := svc.CreateSnapshotWithContext(ctx, &ec2.CreateSnapshotInput{
+			VolumeId:    aws.String(volID),
+			Description: aws.String(description),
+		})
+		if err != nil {
+			return ids, fmt.Errorf("Failed to snapshot the volume %s: %s", volID, err)
+		}
+		c.ConsoleLog(1, "Started the snapshot %s of the volume %s.\n", *snap.SnapshotId, volID)
+		ids = append(ids, *snap.SnapshotId)
+	}
+
+	pollInterval := 10
+	itCnt := waitTimeout / pollInterval
+	spinner := sdutils.NewSpinner(c, 1, "Waiting for the snapshots to complete")
+	defer spinner.Close()
+	for i := 0; ; i++ {
+		resp, err := svc.DescribeSnapshotsWithContext(ctx, &ec2.DescribeSnapshotsInput{SnapshotIds: aws.StringSlice(ids)})
+		if err != nil {
+			return ids, err
+		}
+		done := 0
+		for _, snap := range resp.Snapshots {
+			switch aws.StringValue(snap.State) {
+			case ec2.SnapshotStateError:
+				return ids, fmt.Errorf("The snapshot %s failed: %s", aws.StringValue(snap.SnapshotId), aws.StringValue(snap.StateMessage))
+			case ec2.SnapshotStateCompleted:
+				done++
+			}
+		}
+		if done == len(ids) {
+			return ids, nil
+		}
+		if i >= itCnt {
+			return ids, fmt.Errorf("Timed out waiting for the snapshots %s", strings.Join(ids, ", "))
+		}
+		spinner.EchoNext()
+		select {
+		case <-ctx.Done():
+			return ids, ctx.Err()
+		case <-time.After(time.Duration(pollInterval) * time.Second):
+		}
+	}
+}
+
+// deleteSnapshotIDs deletes the snapshots one at a time and stops at the first
+// failure.
+func deleteSnapshotIDs(ctx context.Context, c sdutils.AppContext, region string, ids []string) error {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return nil
+	}
+	sess, err := session.NewSession()
+	if err != nil {
+		return err
+	}
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(region)})
+	for _, id := range ids {
+		c.ConsoleLog(1, "Deleting the snapshot %s.\n", id)
+		_, err = svc.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{SnapshotId: aws.String(id)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func testSnapshot(id string, set string, index string, count string, state string, start time.Time) *ec2.Snapshot {
+	return &ec2.Snapshot{
+		SnapshotId: aws.String(id),
+		State:      aws.String(state),
+		StartTime:  aws.Time(start),
+		VolumeSize: aws.Int64(10),
+		Tags: []*ec2.Tag{
+			{Key: aws.String(snapshotSetTag), Value: aws.String(set)},
+			{Key: aws.String(snapshotDeploymentTag), Value: aws.String("dep1")},
+			{Key: aws.String(snapshotIndexTag), Value: aws.String(index)},
+			{Key: aws.String(snapshotCountTag), Value: aws.String(count)},
+			{Key: aws.String(snapshotVersionTag), Value: aws.String("5.0.0")},
+		},
+	}
+}
+
+func TestSnapshotSets(t *testing.T) {
+	now := time.Now()
+	older := now.Add(-time.Hour)
+	sets := snapshotSets([]*ec2.Snapshot{
+		testSnapshot("snap-b1", "new", "1", "2", ec2.SnapshotStateCompleted, now),
+		testSnapshot("snap-a1", "old", "1", "2", ec2.SnapshotStateCompleted, older),
+		testSnapshot("snap-a0", "old", "0", "2", ec2.SnapshotStateCompleted, older),
+		testSnapshot("snap-b0", "new", "0", "2", ec2.SnapshotStatePending, now),
+		testSnapshot("snap-c0", "partial", "0", "2", ec2.SnapshotStateCompleted, now.Add(time.Hour)),
+	})
+	if len(sets) != 3 {
+		t.Fatalf("There should be 3 snapshot sets but there are %d", len(sets))
+	}
+	if sets[0].ID != "old" || sets[1].ID != "new" || sets[2].ID != "partial" {
+		t.Fatalf("The sets should be sorted by age %v", sets)
+	}
+	if sets[0].SnapshotIDs[0] != "snap-a0" || sets[0].SnapshotIDs[1] != "snap-a1" {
+		t.Fatalf("The snapshots should be sorted by volume index %v", sets[0].SnapshotIDs)
+	}
+	if !sets[0].Complete || sets[1].Complete || sets[2].Complete {
+		t.Fatalf("Only the old set is complete %v", sets)
+	}
+	if sets[0].Deployment != "dep1" || sets[0].Version != "5.0.0" || sets[0].VolumeSize != 10 {
+		t.Fatalf("The set was not described from the tags %v", sets[0])
+	}
+}
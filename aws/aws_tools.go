@@ -16,12 +16,14 @@
 package aws
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -69,12 +71,12 @@ func hasTag(tags []*ec2.Tag, tagVal string, possibleDelpoyNames *map[string]bool
 	return false
 }
 
-func getAsgLc(c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) ([]*autoscaling.LaunchConfiguration, []*autoscaling.Group) {
+func getAsgLc(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) ([]*autoscaling.LaunchConfiguration, []*autoscaling.Group) {
 	lcList := []*autoscaling.LaunchConfiguration{}
 	asgList := []*autoscaling.Group{}
 
 	autoscaleSvc := autoscaling.New(sess, conf)
-	asResp, err := autoscaleSvc.DescribeAutoScalingGroups(nil)
+	asResp, err := autoscaleSvc.DescribeAutoScalingGroupsWithContext(ctx, nil)
 	if err != nil {
 		c.ConsoleLog(1, "Failed to get autoscale groups: %s\n", err)
 		return lcList, asgList
@@ -90,7 +92,7 @@ func getAsgLc(c sdutils.AppContext, sess *session.Session, conf *aws.Config, tag
 					if g.LaunchConfigurationName != nil {
 						lcInput := autoscaling.DescribeLaunchConfigurationsInput{
 							LaunchConfigurationNames: []*string{g.LaunchConfigurationName}}
-						lcResp, err := autoscaleSvc.DescribeLaunchConfigurations(&lcInput)
+						lcResp, err := autoscaleSvc.DescribeLaunchConfigurationsWithContext(ctx, &lcInput)
 						if err != nil {
 							c.Logf(sdutils.WARN, "Failed to describe the launch configuration %s: %s", *g.LaunchConfigurationName, err)
 						} else {
@@ -104,13 +106,13 @@ func getAsgLc(c sdutils.AppContext, sess *session.Session, conf *aws.Config, tag
 	return lcList, asgList
 }
 
-func destroyAsgLc(c sdutils.AppContext, sess *session.Session, conf *aws.Config, lcList []*autoscaling.LaunchConfiguration, asgList []*autoscaling.Group) error {
+func destroyAsgLc(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, lcList []*autoscaling.LaunchConfiguration, asgList []*autoscaling.Group) error {
 	c.ConsoleLog(1, "Destroying the autoscaling groups\n")
 	autoscaleSvc := autoscaling.New(sess, conf)
 	for _, asg := range asgList {
 		c.ConsoleLog(2, "Destroying %s\n", *asg.AutoScalingGroupName)
 		input := autoscaling.DeleteAutoScalingGroupInput{AutoScalingGroupName: asg.AutoScalingGroupName}
-		_, err := autoscaleSvc.DeleteAutoScalingGroup(&input)
+		_, err := autoscaleSvc.DeleteAutoScalingGroupWithContext(ctx, &input)
 		if err != nil {
 			c.Logf(sdutils.WARN, "Failed to delete the ASG %s, %s", *asg.AutoScalingGroupName, err)
 			c.ConsoleLog(1, "Failed to delete the ASG %s, %s\n", *asg.AutoScalingGroupName, err)
@@ -120,7 +122,7 @@ func destroyAsgLc(c sdutils.AppContext, sess *session.Session, conf *aws.Config,
 	for _, lc := range lcList {
 		c.ConsoleLog(2, "Destroying %s\n", *lc.LaunchConfigurationName)
 		input := autoscaling.DeleteLaunchConfigurationInput{LaunchConfigurationName: lc.LaunchConfigurationName}
-		_, err := autoscaleSvc.DeleteLaunchConfiguration(&input)
+		_, err := autoscaleSvc.DeleteLaunchConfigurationWithContext(ctx, &input)
 		if err != nil {
 			c.Logf(sdutils.WARN, "Failed to delete the LC %s, %s", *lc.LaunchConfigurationName, err)
 			c.ConsoleLog(1, "Failed to delete the LC %s, %s\n", *lc.LaunchConfigurationName, err)
@@ -129,10 +131,10 @@ func destroyAsgLc(c sdutils.AppContext, sess *session.Session, conf *aws.Config,
 	return nil
 }
 
-func getInstances(c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) []*ec2.Instance {
+func getInstances(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) []*ec2.Instance {
 	instList := []*ec2.Instance{}
 	svc := ec2.New(sess, conf)
-	resp, err := svc.DescribeInstances(nil)
+	resp, err := svc.DescribeInstancesWithContext(ctx, nil)
 	if err != nil {
 		c.ConsoleLog(1, "Failed to get any instances: %s\n", err)
 		return instList
@@ -152,7 +154,7 @@ func getInstances(c sdutils.AppContext, sess *session.Session, conf *aws.Config,
 // CheckKeyName will return true or false based on the existance of the keyname in the
 // configured AWS environment.  If an error occurs while communicating with AWS an
 // error will be returned.
-func CheckKeyName(c sdutils.AppContext, a *awsPlugin, keyname string) (bool, error) {
+func CheckKeyName(ctx context.Context, c sdutils.AppContext, a *awsPlugin, keyname string) (bool, error) {
 	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
 		return true, nil
 	}
@@ -163,7 +165,7 @@ func CheckKeyName(c sdutils.AppContext, a *awsPlugin, keyname string) (bool, err
 	}
 
 	svc := ec2.New(sess, &conf)
-	keyOut, err := svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{})
+	keyOut, err := svc.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{})
 	if err != nil {
 		return false, err
 	}
@@ -176,7 +178,7 @@ func CheckKeyName(c sdutils.AppContext, a *awsPlugin, keyname string) (bool, err
 	return false, nil
 }
 
-func ImportKeyName(c sdutils.AppContext, a *awsPlugin, keyname string, publickey []byte) error {
+func ImportKeyName(ctx context.Context, c sdutils.AppContext, a *awsPlugin, keyname string, publickey []byte) error {
 	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
 		return nil
 	}
@@ -192,14 +194,14 @@ func ImportKeyName(c sdutils.AppContext, a *awsPlugin, keyname string, publickey
 		KeyName:           &keyname,
 		PublicKeyMaterial: publickey,
 	}
-	_, err = svc.ImportKeyPair(&keyInput)
+	_, err = svc.ImportKeyPairWithContext(ctx, &keyInput)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func DeleteKeyPair(c sdutils.AppContext, a *awsPlugin, keyname string) error {
+func DeleteKeyPair(ctx context.Context, c sdutils.AppContext, a *awsPlugin, keyname string) error {
 	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
 		return nil
 	}
@@ -209,26 +211,26 @@ func DeleteKeyPair(c sdutils.AppContext, a *awsPlugin, keyname string) error {
 		return err
 	}
 	svc := ec2.New(sess, &conf)
-	_, err = svc.DeleteKeyPair(&ec2.DeleteKeyPairInput{KeyName: &keyname})
+	_, err = svc.DeleteKeyPairWithContext(ctx, &ec2.DeleteKeyPairInput{KeyName: &keyname})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func destroyInstances(c sdutils.AppContext, sess *session.Session, conf *aws.Config, instList []*ec2.Instance) error {
+func destroyInstances(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, instList []*ec2.Instance) error {
 	svc := ec2.New(sess, conf)
 	for _, inst := range instList {
 		input := ec2.TerminateInstancesInput{InstanceIds: []*string{inst.InstanceId}}
-		svc.TerminateInstances(&input)
+		svc.TerminateInstancesWithContext(ctx, &input)
 	}
 	return nil
 }
 
-func getSecurityGroups(c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) []*ec2.SecurityGroup {
+func getSecurityGroups(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) []*ec2.SecurityGroup {
 	sgList := []*ec2.SecurityGroup{}
 	svc := ec2.New(sess, conf)
-	sgoa, err := svc.DescribeSecurityGroups(nil)
+	sgoa, err := svc.DescribeSecurityGroupsWithContext(ctx, nil)
 	if err != nil {
 		c.ConsoleLog(1, "Failed to get any security groups: %s\n", err)
 		return sgList
@@ -243,11 +245,11 @@ func getSecurityGroups(c sdutils.AppContext, sess *session.Session, conf *aws.Co
 	return sgList
 }
 
-func destroySecurityGroups(c sdutils.AppContext, sess *session.Session, conf *aws.Config, sgList []*ec2.SecurityGroup) error {
+func destroySecurityGroups(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, sgList []*ec2.SecurityGroup) error {
 	svc := ec2.New(sess, conf)
 	for _, sg := range sgList {
 		input := ec2.DeleteSecurityGroupInput{GroupId: sg.GroupId}
-		_, err := svc.DeleteSecurityGroup(&input)
+		_, err := svc.DeleteSecurityGroupWithContext(ctx, &input)
 		if err != nil {
 			c.Logf(sdutils.WARN, "Failed to delete the security group %s.  %s", *sg.GroupName, err)
 			c.ConsoleLog(1, "Failed to delete the security group %s. %s\n", *sg.GroupName, err)
@@ -256,10 +258,123 @@ func destroySecurityGroups(c sdutils.AppContext, sess *session.Session, conf *aw
 	return nil
 }
 
-func getElbs(c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string) []*elb.LoadBalancerDescription {
+// tagFilter limits a describe call to the resources that carry the graviton
+// deployment tag.
+func tagFilter() []*ec2.Filter {
+	return []*ec2.Filter{{Name: aws.String("tag-key"), Values: []*string{aws.String("StardogVirtualAppliance")}}}
+}
+
+func getVolumes(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) []*ec2.Volume {
+	volList := []*ec2.Volume{}
+	svc := ec2.New(sess, conf)
+	resp, err := svc.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{Filters: tagFilter()})
+	if err != nil {
+		c.ConsoleLog(1, "Failed to get any volumes: %s\n", err)
+		return volList
+	}
+	for _, vol := range resp.Volumes {
+		if hasTag(vol.Tags, tagVal, possibleDelpoyNames) && *vol.State != ec2.VolumeStateDeleting && *vol.State != ec2.VolumeStateDeleted {
+			c.Logf(sdutils.DEBUG, "Found volume %s", *vol.VolumeId)
+			volList = append(volList, vol)
+		}
+	}
+	return volList
+}
+
+func destroyVolumes(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, volList []*ec2.Volume) error {
+	svc := ec2.New(sess, conf)
+	for _, vol := range volList {
+		_, err := svc.DeleteVolumeWithContext(ctx, &ec2.DeleteVolumeInput{VolumeId: vol.VolumeId})
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to delete the volume %s.  %s", *vol.VolumeId, err)
+			c.ConsoleLog(1, "Failed to delete the volume %s. %s\n", *vol.VolumeId, err)
+		}
+	}
+	return nil
+}
+
+// getVpcs returns the VPCs that the volume builders run in along with their
+// subnets and internet gateways.
+func getVpcs(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string, possibleDelpoyNames *map[string]bool) ([]*ec2.Vpc, []*ec2.Subnet, []*ec2.InternetGateway) {
+	vpcList := []*ec2.Vpc{}
+	subnetList := []*ec2.Subnet{}
+	gwList := []*ec2.InternetGateway{}
+	svc := ec2.New(sess, conf)
+	vpcResp, err := svc.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{Filters: tagFilter()})
+	if err != nil {
+		c.ConsoleLog(1, "Failed to get any VPCs: %s\n", err)
+		return vpcList, subnetList, gwList
+	}
+	for _, vpc := range vpcResp.Vpcs {
+		if hasTag(vpc.Tags, tagVal, possibleDelpoyNames) {
+			c.Logf(sdutils.DEBUG, "Found VPC %s", *vpc.VpcId)
+			vpcList = append(vpcList, vpc)
+		}
+	}
+	subnetResp, err := svc.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: tagFilter()})
+	if err != nil {
+		c.ConsoleLog(1, "Failed to get any subnets: %s\n", err)
+	} else {
+		for _, subnet := range subnetResp.Subnets {
+			if hasTag(subnet.Tags, tagVal, possibleDelpoyNames) {
+				c.Logf(sdutils.DEBUG, "Found subnet %s", *subnet.SubnetId)
+				subnetList = append(subnetList, subnet)
+			}
+		}
+	}
+	gwResp, err := svc.DescribeInternetGatewaysWithContext(ctx, &ec2.DescribeInternetGatewaysInput{Filters: tagFilter()})
+	if err != nil {
+		c.ConsoleLog(1, "Failed to get any internet gateways: %s\n", err)
+	} else {
+		for _, gw := range gwResp.InternetGateways {
+			if hasTag(gw.Tags, tagVal, possibleDelpoyNames) {
+				c.Logf(sdutils.DEBUG, "Found internet gateway %s", *gw.InternetGatewayId)
+				gwList = append(gwList, gw)
+			}
+		}
+	}
+	return vpcList, subnetList, gwList
+}
+
+// destroyVpcs deletes the subnets and the internet gateways before the VPCs
+// because a VPC cannot be deleted while they exist.
+func destroyVpcs(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, vpcList []*ec2.Vpc, subnetList []*ec2.Subnet, gwList []*ec2.InternetGateway) error {
+	svc := ec2.New(sess, conf)
+	for _, subnet := range subnetList {
+		_, err := svc.DeleteSubnetWithContext(ctx, &ec2.DeleteSubnetInput{SubnetId: subnet.SubnetId})
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to delete the subnet %s.  %s", *subnet.SubnetId, err)
+			c.ConsoleLog(1, "Failed to delete the subnet %s. %s\n", *subnet.SubnetId, err)
+		}
+	}
+	for _, gw := range gwList {
+		for _, a := range gw.Attachments {
+			input := ec2.DetachInternetGatewayInput{InternetGatewayId: gw.InternetGatewayId, VpcId: a.VpcId}
+			_, err := svc.DetachInternetGatewayWithContext(ctx, &input)
+			if err != nil {
+				c.Logf(sdutils.WARN, "Failed to detach the internet gateway %s.  %s", *gw.InternetGatewayId, err)
+			}
+		}
+		_, err := svc.DeleteInternetGatewayWithContext(ctx, &ec2.DeleteInternetGatewayInput{InternetGatewayId: gw.InternetGatewayId})
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to delete the internet gateway %s.  %s", *gw.InternetGatewayId, err)
+			c.ConsoleLog(1, "Failed to delete the internet gateway %s. %s\n", *gw.InternetGatewayId, err)
+		}
+	}
+	for _, vpc := range vpcList {
+		_, err := svc.DeleteVpcWithContext(ctx, &ec2.DeleteVpcInput{VpcId: vpc.VpcId})
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to delete the VPC %s.  %s", *vpc.VpcId, err)
+			c.ConsoleLog(1, "Failed to delete the VPC %s. %s\n", *vpc.VpcId, err)
+		}
+	}
+	return nil
+}
+
+func getElbs(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagVal string) []*elb.LoadBalancerDescription {
 	elbList := []*elb.LoadBalancerDescription{}
 	svc := elb.New(sess, conf)
-	resp, err := svc.DescribeLoadBalancers(nil)
+	resp, err := svc.DescribeLoadBalancersWithContext(ctx, nil)
 	if err != nil {
 		c.ConsoleLog(1, "Failed to get any load balancers: %s\n", err)
 		return elbList
@@ -272,11 +387,11 @@ func getElbs(c sdutils.AppContext, sess *session.Session, conf *aws.Config, tagV
 	return elbList
 }
 
-func destroyLoadBalancers(c sdutils.AppContext, sess *session.Session, conf *aws.Config, elbList []*elb.LoadBalancerDescription) error {
+func destroyLoadBalancers(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, elbList []*elb.LoadBalancerDescription) error {
 	svc := elb.New(sess, conf)
 	for _, e := range elbList {
 		input := elb.DeleteLoadBalancerInput{LoadBalancerName: e.LoadBalancerName}
-		_, err := svc.DeleteLoadBalancer(&input)
+		_, err := svc.DeleteLoadBalancerWithContext(ctx, &input)
 		if err != nil {
 			c.Logf(sdutils.WARN, "Failed to delete the load balancer %s", *e.LoadBalancerName)
 			c.ConsoleLog(1, "Failed to delete the load balancer %s\n", *e.LoadBalancerName)
@@ -285,13 +400,197 @@ func destroyLoadBalancers(c sdutils.AppContext, sess *session.Session, conf *aws
 	return nil
 }
 
-func (a *awsPlugin) FindLeaks(c sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+// attachedVolumes returns the ids of the volumes from volumeIds that are
+// attached to an instance.
+func attachedVolumes(ctx context.Context, c sdutils.AppContext, region string, volumeIds []string) (map[string]bool, error) {
+	attached := make(map[string]bool)
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return attached, nil
+	}
+	conf := aws.Config{Region: aws.String(region)}
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	svc := ec2.New(sess, &conf)
+	resp, err := svc.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{VolumeIds: aws.StringSlice(volumeIds)})
+	if err != nil {
+		return nil, err
+	}
+	for _, vol := range resp.Volumes {
+		if len(vol.Attachments) > 0 {
+			c.Logf(sdutils.DEBUG, "The volume %s is attached", *vol.VolumeId)
+			attached[*vol.VolumeId] = true
+		}
+	}
+	return attached, nil
+}
+
+// modifyEbsVolumes asks EBS to change the size, the type and the IOPS of the
+// volumes.  Volumes that already have those settings, from an earlier run that
+// was interrupted, are skipped.  iops is the total for each volume and is only
+// used for io1 volumes.
+func modifyEbsVolumes(ctx context.Context, c sdutils.AppContext, region string, volumeIds []string, size int, volumeType string, iops int, waitTimeout int) error {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return nil
+	}
+	sess, err := session.NewSession()
+	if err != nil {
+		return err
+	}
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(region)})
+	resp, err := svc.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{VolumeIds: aws.StringSlice(volumeIds)})
+	if err != nil {
+		return err
+	}
+	for _, vol := range resp.Volumes {
+		input := &ec2.ModifyVolumeInput{
+			VolumeId:   vol.VolumeId,
+			Size:       aws.Int64(int64(size)),
+			VolumeType: aws.String(volumeType),
+		}
+		same := aws.Int64Value(vol.Size) == int64(size) && aws.StringValue(vol.VolumeType) == volumeType
+		if volumeType == ec2.VolumeTypeIo1 {
+			input.Iops = aws.Int64(int64(iops))
+			same = same && aws.Int64Value(vol.Iops) == int64(iops)
+		}
+		if same {
+			c.Logf(sdutils.INFO, "The volume %s already has the new settings", *vol.VolumeId)
+			continue
+		}
+		c.ConsoleLog(1, "Modifying the volume %s.\n", *vol.VolumeId)
+		_, err = svc.ModifyVolumeWithContext(ctx, input)
+		if err != nil {
+			return fmt.Errorf("Failed to modify the volume %s: %s", *vol.VolumeId, err)
+		}
+	}
+	return waitForVolumeModifications(ctx, c, svc, volumeIds, waitTimeout)
+}
+
+// waitForVolumeModifications blocks until every modification of the volumes
+// has reached the optimizing state.  The new size can be used from then on
+// even though EBS keeps optimizing the volume for hours.
+func waitForVolumeModifications(ctx context.Context, c sdutils.AppContext, svc *ec2.EC2, volumeIds []string, waitTimeout int) error {
+	pollInterval := 10
+	itCnt := waitTimeout / pollInterval
+	spinner := sdutils.NewSpinner(c, 1, "Waiting for the volume modifications")
+	defer spinner.Close()
+	for i := 0; ; i++ {
+		resp, err := svc.DescribeVolumesModificationsWithContext(ctx, &ec2.DescribeVolumesModificationsInput{VolumeIds: aws.StringSlice(volumeIds)})
+		if err != nil {
+			return err
+		}
+		done := 0
+		for _, m := range resp.VolumesModifications {
+			switch aws.StringValue(m.ModificationState) {
+			case ec2.VolumeModificationStateFailed:
+				return fmt.Errorf("The modification of the volume %s failed: %s", aws.StringValue(m.VolumeId), aws.StringValue(m.StatusMessage))
+			case ec2.VolumeModificationStateOptimizing, ec2.VolumeModificationStateCompleted:
+				done++
+			default:
+				c.Logf(sdutils.DEBUG, "The modification of %s is %d%% done", aws.StringValue(m.VolumeId), aws.Int64Value(m.Progress))
+			}
+		}
+		if done >= len(volumeIds) {
+			c.ConsoleLog(1, "%s\n", c.SuccessString("The volumes were modified"))
+			return nil
+		}
+		if i >= itCnt {
+			return fmt.Errorf("Timed out waiting for the volume modifications.  %d of %d are done and the others continue in the background", done, len(volumeIds))
+		}
+		spinner.EchoNext()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(pollInterval) * time.Second):
+		}
+	}
+}
+
+// awsLeaks holds the AWS resources found tagged for one or more deployments.
+type awsLeaks struct {
+	lcList     []*autoscaling.LaunchConfiguration
+	asgList    []*autoscaling.Group
+	elbList    []*elb.LoadBalancerDescription
+	instList   []*ec2.Instance
+	sgList     []*ec2.SecurityGroup
+	volList    []*ec2.Volume
+	vpcList    []*ec2.Vpc
+	subnetList []*ec2.Subnet
+	gwList     []*ec2.InternetGateway
+}
+
+// findAwsLeaks looks up the resources tagged for deploymentName, or for any
+// deployment when the name is empty.
+func findAwsLeaks(ctx context.Context, c sdutils.AppContext, sess *session.Session, conf *aws.Config, deploymentName string) *awsLeaks {
 	possibleDeployNames := make(map[string]bool)
 
 	if deploymentName != "" {
 		possibleDeployNames[deploymentName] = true
 	}
 
+	leaks := &awsLeaks{}
+	leaks.lcList, leaks.asgList = getAsgLc(ctx, c, sess, conf, deploymentName, &possibleDeployNames)
+	leaks.instList = getInstances(ctx, c, sess, conf, deploymentName, &possibleDeployNames)
+	leaks.sgList = getSecurityGroups(ctx, c, sess, conf, deploymentName, &possibleDeployNames)
+	leaks.volList = getVolumes(ctx, c, sess, conf, deploymentName, &possibleDeployNames)
+	leaks.vpcList, leaks.subnetList, leaks.gwList = getVpcs(ctx, c, sess, conf, deploymentName, &possibleDeployNames)
+
+	leaks.elbList = []*elb.LoadBalancerDescription{}
+	for tagName := range possibleDeployNames {
+		tmpElbList := getElbs(ctx, c, sess, conf, tagName)
+		leaks.elbList = append(leaks.elbList, tmpElbList...)
+	}
+	return leaks
+}
+
+func (l *awsLeaks) names() []string {
+	names := []string{}
+	for _, asg := range l.asgList {
+		names = append(names, fmt.Sprintf("autoscaling group %s", *asg.AutoScalingGroupName))
+	}
+	for _, lc := range l.lcList {
+		names = append(names, fmt.Sprintf("launch configuration %s", *lc.LaunchConfigurationName))
+	}
+	for _, e := range l.elbList {
+		names = append(names, fmt.Sprintf("load balancer %s", *e.LoadBalancerName))
+	}
+	for _, inst := range l.instList {
+		names = append(names, fmt.Sprintf("instance %s", *inst.InstanceId))
+	}
+	for _, sg := range l.sgList {
+		names = append(names, fmt.Sprintf("security group %s", *sg.GroupName))
+	}
+	for _, vol := range l.volList {
+		names = append(names, fmt.Sprintf("volume %s", *vol.VolumeId))
+	}
+	for _, subnet := range l.subnetList {
+		names = append(names, fmt.Sprintf("subnet %s", *subnet.SubnetId))
+	}
+	for _, gw := range l.gwList {
+		names = append(names, fmt.Sprintf("internet gateway %s", *gw.InternetGatewayId))
+	}
+	for _, vpc := range l.vpcList {
+		names = append(names, fmt.Sprintf("VPC %s", *vpc.VpcId))
+	}
+	return names
+}
+
+// leakedResources returns a description of every AWS resource still tagged for
+// the deployment.  The fake test credentials never talk to AWS.
+func leakedResources(ctx context.Context, c sdutils.AppContext, region string, deploymentName string) ([]string, error) {
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "gravitontest" {
+		return []string{}, nil
+	}
+	conf := aws.Config{Region: aws.String(region)}
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return findAwsLeaks(ctx, c, sess, &conf, deploymentName).names(), nil
+}
+
+func (a *awsPlugin) FindLeaks(ctx context.Context, c sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
 	conf := aws.Config{Region: aws.String(a.Region)}
 	sess, err := session.NewSession()
 	if err != nil {
@@ -300,60 +599,61 @@ func (a *awsPlugin) FindLeaks(c sdutils.AppContext, deploymentName string, destr
 
 	c.ConsoleLog(1, "Looking for AWS resources\n")
 
-	lcList, asgList := getAsgLc(c, sess, &conf, deploymentName, &possibleDeployNames)
-	instList := getInstances(c, sess, &conf, deploymentName, &possibleDeployNames)
-	sgList := getSecurityGroups(c, sess, &conf, deploymentName, &possibleDeployNames)
-
-	elbList := []*elb.LoadBalancerDescription{}
-	for tagName := range possibleDeployNames {
-		tmpElbList := getElbs(c, sess, &conf, tagName)
-		elbList = append(elbList, tmpElbList...)
-	}
-
-	c.ConsoleLog(1, "Found %d autoscaling groups\n", len(asgList))
-	for _, asg := range asgList {
+	leaks := findAwsLeaks(ctx, c, sess, &conf, deploymentName)
+	c.ConsoleLog(1, "Found %d autoscaling groups\n", len(leaks.asgList))
+	for _, asg := range leaks.asgList {
 		c.ConsoleLog(1, "\t%s\n", *asg.AutoScalingGroupName)
 	}
-	c.ConsoleLog(1, "Found %d launch configurations\n", len(lcList))
-	for _, lc := range lcList {
+	c.ConsoleLog(1, "Found %d launch configurations\n", len(leaks.lcList))
+	for _, lc := range leaks.lcList {
 		c.ConsoleLog(1, "\t%s\n", *lc.LaunchConfigurationName)
 	}
-	c.ConsoleLog(1, "Found %d load balancers\n", len(elbList))
-	for _, elb := range elbList {
+	c.ConsoleLog(1, "Found %d load balancers\n", len(leaks.elbList))
+	for _, elb := range leaks.elbList {
 		c.ConsoleLog(1, "\t%s\n", *elb.LoadBalancerName)
 	}
-	c.ConsoleLog(1, "Found %d instances\n", len(instList))
-	for _, inst := range instList {
+	c.ConsoleLog(1, "Found %d instances\n", len(leaks.instList))
+	for _, inst := range leaks.instList {
 		c.ConsoleLog(1, "\t%s\n", *inst.InstanceId)
 	}
-	c.ConsoleLog(1, "Found %d security groups\n", len(sgList))
-	for _, sg := range sgList {
+	c.ConsoleLog(1, "Found %d security groups\n", len(leaks.sgList))
+	for _, sg := range leaks.sgList {
 		c.ConsoleLog(1, "\t%s\n", *sg.GroupName)
 	}
+	c.ConsoleLog(1, "Found %d volumes\n", len(leaks.volList))
+	for _, vol := range leaks.volList {
+		c.ConsoleLog(1, "\t%s\n", *vol.VolumeId)
+	}
+	c.ConsoleLog(1, "Found %d VPCs with %d subnets and %d internet gateways\n", len(leaks.vpcList), len(leaks.subnetList), len(leaks.gwList))
+	for _, vpc := range leaks.vpcList {
+		c.ConsoleLog(1, "\t%s\n", *vpc.VpcId)
+	}
 
 	if !destroy {
 		return nil
 	}
 	if !force {
-		if !sdutils.AskUserYesOrNo("Would you like to destroy these resources?") {
+		if !sdutils.AskYesOrNo(c, "Would you like to destroy these resources?") {
 			return nil
 		}
 	}
-	destroyInstances(c, sess, &conf, instList)
-	destroyAsgLc(c, sess, &conf, lcList, asgList)
-	destroyLoadBalancers(c, sess, &conf, elbList)
-	destroySecurityGroups(c, sess, &conf, sgList)
+	destroyInstances(ctx, c, sess, &conf, leaks.instList)
+	destroyAsgLc(ctx, c, sess, &conf, leaks.lcList, leaks.asgList)
+	destroyLoadBalancers(ctx, c, sess, &conf, leaks.elbList)
+	destroySecurityGroups(ctx, c, sess, &conf, leaks.sgList)
+	destroyVolumes(ctx, c, sess, &conf, leaks.volList)
+	destroyVpcs(ctx, c, sess, &conf, leaks.vpcList, leaks.subnetList, leaks.gwList)
 
 	return nil
 }
 
-func amiFileName(cliContext sdutils.AppContext) string {
-	return path.Join(cliContext.GetConfigDir(), fmt.Sprintf("amis-%s.json", cliContext.GetVersion()))
+func amiFileName(cliContext sdutils.AppContext, version string) string {
+	return path.Join(cliContext.GetConfigDir(), fmt.Sprintf("amis-%s.json", version))
 }
 
-func loadAmiAmp(cliContext sdutils.AppContext) (map[string]string, error) {
+func loadAmiAmp(cliContext sdutils.AppContext, version string) (map[string]string, error) {
 	amiMap := make(map[string]string)
-	amiMapFile := amiFileName(cliContext)
+	amiMapFile := amiFileName(cliContext, version)
 	cliContext.Logf(sdutils.DEBUG, "Loading the AMI file %s\n", amiMapFile)
 	if _, err := os.Stat(amiMapFile); err == nil {
 		cliContext.Logf(sdutils.DEBUG, "Read AMI file\n")
@@ -372,20 +672,6 @@ func loadAmiAmp(cliContext sdutils.AppContext) (map[string]string, error) {
 	return amiMap, nil
 }
 
-func saveAmiMap(cliContext sdutils.AppContext, amiMap map[string]string) error {
-	data, err := json.Marshal(&amiMap)
-	if err != nil {
-		return err
-	}
-	amiMapFile := amiFileName(cliContext)
-	cliContext.Logf(sdutils.DEBUG, "Saving the AMI file %s\n", amiMapFile)
-	err = ioutil.WriteFile(amiMapFile, data, 0600)
-	if err != nil {
-		return err
-	}
-	return err
-}
-
 // PlaceAsset will write data that was compiled in with go-bindata to a file.
 func PlaceAsset(cliContext sdutils.AppContext, dir string, assentName string, temp bool) (string, error) {
 	var err error
@@ -16,11 +16,14 @@
 package aws
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/stardog-union/stardog-graviton/sdutils"
 )
@@ -33,18 +36,21 @@ type VolumeStatusDescription struct {
 
 // EbsVolumes describes the disk volumes used by aws to store STARDOG_HOME.
 type EbsVolumes struct {
-	DeploymentName   string `json:"deployment_name,omitempty"`
-	Region           string `json:"aws_region,omitempty"`
-	SizeOfEachVolume string `json:"storage_size,omitempty"`
-	ClusterSize      string `json:"cluster_size,omitempty"`
-	AwsKeyName       string `json:"aws_key_name,omitempty"`
-	KeyPath          string `json:"key_path,omitempty"`
-	AmiID            string `json:"ami,omitempty"`
-	InstanceType     string `json:"instance_type,omitempty"`
-	LicensePath      string `json:"stardog_license,omitempty"`
-	VolumeType       string `json:"volume_type,omitempty"`
-	IoPs             string `json:"iops,omitempty"`
-	VolumeDir        string `json:"-"`
+	DeploymentName   string            `json:"deployment_name,omitempty"`
+	Region           string            `json:"aws_region,omitempty"`
+	SizeOfEachVolume string            `json:"storage_size,omitempty"`
+	ClusterSize      string            `json:"cluster_size,omitempty"`
+	AwsKeyName       string            `json:"aws_key_name,omitempty"`
+	KeyPath          string            `json:"key_path,omitempty"`
+	AmiID            string            `json:"ami,omitempty"`
+	InstanceType     string            `json:"instance_type,omitempty"`
+	LicensePath      string            `json:"stardog_license,omitempty"`
+	VolumeType       string            `json:"volume_type,omitempty"`
+	IoPs             string            `json:"iops,omitempty"`
+	FirstNewVolume   string            `json:"first_new_volume,omitempty"`
+	NodeCount        string            `json:"node_count,omitempty"`
+	SnapshotIds      map[string]string `json:"snapshot_ids,omitempty"`
+	VolumeDir        string            `json:"-"`
 	iopsRatio        int
 	appContext       sdutils.AppContext
 }
@@ -88,8 +94,7 @@ func (v *EbsVolumes) VolumeExists() bool {
 }
 
 // CreateSet uses terraform to create and format the EBS volumes in AWS.
-func (v *EbsVolumes) CreateSet(licensePath string, sizeOfEachVolume int, clusterSize int) error {
-	// TODO make sure we clean up resources on failure
+func (v *EbsVolumes) CreateSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
 	v.appContext.ConsoleLog(2, "Creating an aws volume set in directory %s\n", v.VolumeDir)
 	terraformPath, err := exec.LookPath("terraform")
 	if err != nil {
@@ -98,6 +103,62 @@ func (v *EbsVolumes) CreateSet(licensePath string, sizeOfEachVolume int, cluster
 	v.ClusterSize = fmt.Sprintf("%d", clusterSize)
 	v.SizeOfEachVolume = fmt.Sprintf("%d", sizeOfEachVolume)
 	v.LicensePath = licensePath
+	v.setIOPS(sizeOfEachVolume)
+	v.FirstNewVolume = "0"
+	v.NodeCount = ""
+	v.SnapshotIds = nil
+	confFile := path.Join(v.VolumeDir, "config.json")
+	if _, err := os.Stat(confFile); err == nil {
+		v.appContext.ConsoleLog(1, "Volumes have already been created for the %s deployment, running terraform apply again.", v.DeploymentName)
+		v.appContext.Logf(sdutils.WARN, "Volumes have already been created for the %s deployment, running terraform apply again.", v.DeploymentName)
+	}
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
+	}
+
+	err = v.buildVolumes(ctx, terraformPath, confFile, "Calling out to terraform to create the volumes")
+	if err != nil {
+		return v.cleanupFailedCreate(ctx, terraformPath, confFile, err)
+	}
+	v.appContext.ConsoleLog(1, "Successfully created the volumes.\n")
+	return nil
+}
+
+// RestoreSet uses terraform to create the EBS volumes from snapshots.  The
+// volumes are not formatted because the snapshots already hold a Stardog home
+// with its license.
+func (v *EbsVolumes) RestoreSet(ctx context.Context, snapshotIds []string, sizeOfEachVolume int) error {
+	v.appContext.ConsoleLog(2, "Restoring an aws volume set in directory %s\n", v.VolumeDir)
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+	v.ClusterSize = fmt.Sprintf("%d", len(snapshotIds))
+	v.SizeOfEachVolume = fmt.Sprintf("%d", sizeOfEachVolume)
+	v.LicensePath = ""
+	v.setIOPS(sizeOfEachVolume)
+	v.FirstNewVolume = v.ClusterSize
+	v.NodeCount = ""
+	v.SnapshotIds = make(map[string]string)
+	for i, id := range snapshotIds {
+		v.SnapshotIds[fmt.Sprintf("%d", i)] = id
+	}
+	confFile := path.Join(v.VolumeDir, "config.json")
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
+	}
+
+	err = v.buildVolumes(ctx, terraformPath, confFile, "Calling out to terraform to restore the volumes")
+	if err != nil {
+		return v.cleanupFailedCreate(ctx, terraformPath, confFile, err)
+	}
+	v.appContext.ConsoleLog(1, "Successfully restored the volumes.\n")
+	return nil
+}
+
+func (v *EbsVolumes) setIOPS(sizeOfEachVolume int) {
 	normalizedIOPS := v.iopsRatio * sizeOfEachVolume
 	if normalizedIOPS > 0 {
 		if normalizedIOPS < 100 {
@@ -108,16 +169,30 @@ func (v *EbsVolumes) CreateSet(licensePath string, sizeOfEachVolume int, cluster
 		}
 	}
 	v.IoPs = fmt.Sprintf("%d", normalizedIOPS)
-	confFile := path.Join(v.VolumeDir, "config.json")
-	if _, err := os.Stat(confFile); err == nil {
-		v.appContext.ConsoleLog(1, "Volumes have already been created for the %s deployment, running terraform apply again.", v.DeploymentName)
-		v.appContext.Logf(sdutils.WARN, "Volumes have already been created for the %s deployment, running terraform apply again.", v.DeploymentName)
+}
+
+// buildVolumes runs terraform with the builder instances that format the
+// volumes and then again without them to stop the builders.  builder.tf is
+// put back first because an earlier build that failed after removing it
+// would otherwise leave the new volumes unformatted.
+func (v *EbsVolumes) buildVolumes(ctx context.Context, terraformPath string, confFile string, message string) error {
+	deployDir := path.Join(v.VolumeDir, "..", "..", "..")
+	err := RestoreAsset(deployDir, "etc/terraform/volumes/builder.tf")
+	if err != nil {
+		return err
 	}
-	err = sdutils.WriteJSON(v, confFile)
+	err = v.apply(ctx, terraformPath, confFile, message)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path.Join(v.VolumeDir, "builder.tf"))
 	if err != nil {
 		return err
 	}
+	return v.apply(ctx, terraformPath, confFile, "Calling out to terraform to stop builder instances")
+}
 
+func (v *EbsVolumes) apply(ctx context.Context, terraformPath string, confFile string, message string) error {
 	cmdArray := []string{terraformPath, "apply",
 		"-var-file", confFile}
 	cmd := exec.Cmd{
@@ -125,26 +200,106 @@ func (v *EbsVolumes) CreateSet(licensePath string, sizeOfEachVolume int, cluster
 		Args: cmdArray,
 		Dir:  v.VolumeDir,
 	}
-	spin := sdutils.NewSpinner(v.appContext, 1, "Calling out to terraform to create the volumes")
-	_, err = sdutils.RunCommand(v.appContext, cmd, nil, spin)
+	spin := sdutils.NewSpinner(v.appContext, 1, message)
+	_, err := sdutils.RunCommand(ctx, v.appContext, cmd, nil, spin)
+	return err
+}
+
+// cleanupFailedCreate destroys everything terraform recorded in its state
+// during a failed CreateSet, checks AWS for anything still tagged with the
+// deployment name and reports what was cleaned up and what must be removed by
+// hand.  The original error is always returned.
+func (v *EbsVolumes) cleanupFailedCreate(ctx context.Context, terraformPath string, confFile string, cause error) error {
+	// The cleanup has to run even when the create was interrupted.
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	v.appContext.Logf(sdutils.WARN, "Creating the volumes failed, cleaning up: %s", cause)
+	v.appContext.ConsoleLog(1, "Creating the volumes failed, cleaning up the resources that were created.\n")
+
+	created, err := v.stateList(ctx, terraformPath)
 	if err != nil {
-		return err
+		v.appContext.Logf(sdutils.WARN, "Failed to read the terraform state: %s", err)
 	}
-	err = os.Remove(path.Join(v.VolumeDir, "builder.tf"))
+	if len(created) > 0 {
+		cmdArray := []string{terraformPath, "destroy", "-force",
+			"-var-file", confFile}
+		for _, r := range created {
+			cmdArray = append(cmdArray, "-target", r)
+		}
+		cmd := exec.Cmd{
+			Path: cmdArray[0],
+			Args: cmdArray,
+			Dir:  v.VolumeDir,
+		}
+		spin := sdutils.NewSpinner(v.appContext, 1, "Calling out to terraform to destroy the partially created volumes")
+		_, err = sdutils.RunCommand(ctx, v.appContext, cmd, nil, spin)
+		if err != nil {
+			v.appContext.Logf(sdutils.WARN, "Failed to destroy the partially created volumes: %s", err)
+		}
+	}
+
+	remaining, err := v.stateList(ctx, terraformPath)
 	if err != nil {
-		return err
+		v.appContext.Logf(sdutils.WARN, "Failed to read the terraform state: %s", err)
+		remaining = created
 	}
-	spin = sdutils.NewSpinner(v.appContext, 1, "Calling out to terraform to stop builder instances")
-	_, err = sdutils.RunCommand(v.appContext, cmd, nil, spin)
+	leaked, err := leakedResources(ctx, v.appContext, v.Region, v.DeploymentName)
 	if err != nil {
-		return err
+		v.appContext.Logf(sdutils.WARN, "Failed to look for leaked resources: %s", err)
+		leaked = []string{fmt.Sprintf("unknown, the leak check failed: %s", err)}
 	}
-	v.appContext.ConsoleLog(1, "Successfully created the volumes.\n")
-	return nil
+
+	left := make(map[string]bool)
+	for _, r := range remaining {
+		left[r] = true
+	}
+	cleaned := []string{}
+	for _, r := range created {
+		if !left[r] {
+			cleaned = append(cleaned, r)
+		}
+	}
+	v.appContext.ConsoleLog(1, "Cleaned up %d resources\n", len(cleaned))
+	for _, r := range cleaned {
+		v.appContext.ConsoleLog(1, "\t%s\n", r)
+	}
+	if len(remaining) == 0 && len(leaked) == 0 {
+		os.Remove(confFile)
+		return cause
+	}
+	v.appContext.ConsoleLog(1, "The following resources must be removed by hand:\n")
+	for _, r := range append(remaining, leaked...) {
+		v.appContext.ConsoleLog(1, "\t%s\n", r)
+	}
+	v.appContext.ConsoleLog(1, "Run 'terraform destroy' in %s and 'leaks --type aws --deployment-name %s --destroy' once AWS is reachable.\n", v.VolumeDir, v.DeploymentName)
+	return cause
+}
+
+// stateList returns the addresses of the resources terraform has recorded
+// for the volume set.
+func (v *EbsVolumes) stateList(ctx context.Context, terraformPath string) ([]string, error) {
+	if !sdutils.PathExists(path.Join(v.VolumeDir, "terraform.tfstate")) {
+		return []string{}, nil
+	}
+	cmd := exec.CommandContext(ctx, terraformPath, "state", "list")
+	cmd.Dir = v.VolumeDir
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	resources := []string{}
+	for _, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			resources = append(resources, l)
+		}
+	}
+	return resources, nil
 }
 
 // DeleteSet will delete the EBS volumes from AWS.
-func (v *EbsVolumes) DeleteSet() error {
+func (v *EbsVolumes) DeleteSet(ctx context.Context) error {
 	confFile := path.Join(v.VolumeDir, "config.json")
 	terraformPath, err := exec.LookPath("terraform")
 	if err != nil {
@@ -159,7 +314,7 @@ func (v *EbsVolumes) DeleteSet() error {
 		Dir:  v.VolumeDir,
 	}
 	spin := sdutils.NewSpinner(v.appContext, 1, "Calling out to terraform to delete the images")
-	_, err = sdutils.RunCommand(v.appContext, cmd, nil, spin)
+	_, err = sdutils.RunCommand(ctx, v.appContext, cmd, nil, spin)
 	if err != nil {
 		return err
 	}
@@ -171,18 +326,250 @@ func (v *EbsVolumes) DeleteSet() error {
 	return nil
 }
 
-func (v *EbsVolumes) getStatusInformation() (*VolumeStatusDescription, error) {
+// Grow adds volumes to the set until there are clusterSize of them.  Only the
+// new volumes are formatted and seeded with the license.  If that fails the
+// new volumes are destroyed again.
+func (v *EbsVolumes) Grow(ctx context.Context, clusterSize int) error {
 	terraformPath, err := exec.LookPath("terraform")
 	if err != nil {
-		return nil, err
+		return err
+	}
+	current, err := strconv.Atoi(v.ClusterSize)
+	if err != nil {
+		return err
+	}
+	if clusterSize <= current {
+		return fmt.Errorf("The volume set already has %d volumes", current)
+	}
+	if !sdutils.PathExists(v.LicensePath) {
+		return fmt.Errorf("The license %s used to seed the volumes does not exist", v.LicensePath)
+	}
+	confFile := path.Join(v.VolumeDir, "config.json")
+	v.FirstNewVolume = v.ClusterSize
+	v.ClusterSize = fmt.Sprintf("%d", clusterSize)
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
 	}
 
-	cmdArray := []string{terraformPath, "output", "-json"}
-	cmd := exec.Cmd{
-		Path: cmdArray[0],
-		Args: cmdArray,
-		Dir:  v.VolumeDir,
+	err = v.buildVolumes(ctx, terraformPath, confFile, fmt.Sprintf("Calling out to terraform to create %d more volumes", clusterSize-current))
+	if err != nil {
+		// Going back to the old size destroys the new volumes and the
+		// builders, which has to happen even when the grow was interrupted.
+		if ctx.Err() != nil {
+			ctx = context.Background()
+		}
+		v.appContext.Logf(sdutils.WARN, "Adding volumes failed, removing the new ones: %s", err)
+		os.Remove(path.Join(v.VolumeDir, "builder.tf"))
+		v.ClusterSize = v.FirstNewVolume
+		v.FirstNewVolume = "0"
+		cerr := sdutils.WriteJSON(v, confFile)
+		if cerr == nil {
+			cerr = v.apply(ctx, terraformPath, confFile, "Calling out to terraform to remove the new volumes")
+		}
+		if cerr != nil {
+			v.appContext.Logf(sdutils.WARN, "Failed to remove the new volumes: %s", cerr)
+			v.appContext.ConsoleLog(1, "The new volumes could not be removed: %s\n", cerr)
+		}
+		return err
+	}
+	v.FirstNewVolume = "0"
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully added %d volumes.\n", clusterSize-current)
+	return nil
+}
+
+// Shrink deletes volumes that are not attached to a Stardog node until there
+// are clusterSize of them.  Terraform deletes the volumes with the highest
+// indexes so the unattached volumes are first moved there in its state.
+func (v *EbsVolumes) Shrink(ctx context.Context, clusterSize int) error {
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+	status, err := v.getStatusInformation(ctx)
+	if err != nil {
+		return err
+	}
+	current := len(status.VolumeIds)
+	if clusterSize >= current {
+		return fmt.Errorf("The volume set only has %d volumes", current)
+	}
+	attached, err := attachedVolumes(ctx, v.appContext, v.Region, status.VolumeIds)
+	if err != nil {
+		return err
+	}
+	if current-len(attached) < current-clusterSize {
+		return fmt.Errorf("Only %d volumes are unused but %d must be deleted", current-len(attached), current-clusterSize)
+	}
+
+	free := []int{}
+	for i := 0; i < clusterSize; i++ {
+		if !attached[status.VolumeIds[i]] {
+			free = append(free, i)
+		}
+	}
+	for i := clusterSize; i < current; i++ {
+		if !attached[status.VolumeIds[i]] {
+			continue
+		}
+		err = v.swapVolumes(ctx, terraformPath, i, free[0])
+		if err != nil {
+			return err
+		}
+		free = free[1:]
+	}
+
+	confFile := path.Join(v.VolumeDir, "config.json")
+	v.ClusterSize = fmt.Sprintf("%d", clusterSize)
+	v.NodeCount = ""
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
+	}
+	err = v.apply(ctx, terraformPath, confFile, "Calling out to terraform to delete the unused volumes")
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully deleted %d volumes.\n", current-clusterSize)
+	return nil
+}
+
+// Modify changes the size, the type and the IOPS per gigabyte of every
+// volume.  EBS changes the volumes in place unless magnetic volumes are
+// involved.  Those are snapshotted and created again from the snapshots, which
+// needs them to be detached.
+func (v *EbsVolumes) Modify(ctx context.Context, size int, volumeType string, iopsRatio int, waitTimeout int) error {
+	status, err := v.getStatusInformation(ctx)
+	if err != nil {
+		return err
+	}
+	confFile := path.Join(v.VolumeDir, "config.json")
+	recreate := v.VolumeType == "standard" || volumeType == "standard"
+	v.SizeOfEachVolume = fmt.Sprintf("%d", size)
+	v.VolumeType = volumeType
+	v.iopsRatio = iopsRatio
+	v.setIOPS(size)
+
+	if !recreate {
+		iops, err := strconv.Atoi(v.IoPs)
+		if err != nil {
+			return err
+		}
+		err = modifyEbsVolumes(ctx, v.appContext, v.Region, status.VolumeIds, size, volumeType, iops, waitTimeout)
+		if err != nil {
+			return err
+		}
+		// Terraform has to see the volumes as they are now or it would
+		// change them back
+		return sdutils.WriteJSON(v, confFile)
+	}
+	return v.recreate(ctx, status.VolumeIds, waitTimeout)
+}
+
+// recreate replaces every volume with a new one, made from a snapshot of the
+// old one, that has the settings in v.
+func (v *EbsVolumes) recreate(ctx context.Context, volumeIds []string, waitTimeout int) error {
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+	attached, err := attachedVolumes(ctx, v.appContext, v.Region, volumeIds)
+	if err != nil {
+		return err
+	}
+	if len(attached) > 0 {
+		return fmt.Errorf("Magnetic volumes are changed by creating them again.  Pause the deployment %s first", v.DeploymentName)
+	}
+	snapshots, err := snapshotAndWait(ctx, v.appContext, v.Region, volumeIds, fmt.Sprintf("A copy of a volume of %s before it was modified", v.DeploymentName), waitTimeout)
+	if err != nil {
+		if len(snapshots) > 0 {
+			v.appContext.ConsoleLog(0, "The snapshots %s may have to be deleted by hand.\n", strings.Join(snapshots, ", "))
+		}
+		return err
 	}
+	v.SnapshotIds = make(map[string]string)
+	for i, id := range snapshots {
+		v.SnapshotIds[fmt.Sprintf("%d", i)] = id
+	}
+	v.FirstNewVolume = v.ClusterSize
+	confFile := path.Join(v.VolumeDir, "config.json")
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
+	}
+	for i := range volumeIds {
+		resource := fmt.Sprintf("aws_ebs_volume.stardog_data.%d", i)
+		cmd := exec.CommandContext(ctx, terraformPath, "taint", resource)
+		cmd.Dir = v.VolumeDir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to mark %s to be created again: %s %s", resource, err, out)
+		}
+	}
+	err = v.apply(ctx, terraformPath, confFile, "Calling out to terraform to create the volumes again from their snapshots")
+	if err != nil {
+		v.appContext.ConsoleLog(0, "The volumes were not all created again.  Run the command again to finish.  The snapshots %s are kept until then.\n", strings.Join(snapshots, ", "))
+		return err
+	}
+	v.SnapshotIds = nil
+	v.FirstNewVolume = "0"
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
+	}
+	err = deleteSnapshotIDs(ctx, v.appContext, v.Region, snapshots)
+	if err != nil {
+		v.appContext.ConsoleLog(0, "Failed to delete the snapshots %s: %s\n", strings.Join(snapshots, ", "), err)
+	}
+	return nil
+}
+
+// swapVolumes exchanges the volumes at two indexes in the terraform state.
+func (v *EbsVolumes) swapVolumes(ctx context.Context, terraformPath string, i int, j int) error {
+	tmp := "aws_ebs_volume.stardog_data_swap"
+	a := fmt.Sprintf("aws_ebs_volume.stardog_data[%d]", i)
+	b := fmt.Sprintf("aws_ebs_volume.stardog_data[%d]", j)
+	v.appContext.Logf(sdutils.DEBUG, "Swapping the volumes %s and %s", a, b)
+	for _, mv := range [][]string{{a, tmp}, {b, a}, {tmp, b}} {
+		cmd := exec.CommandContext(ctx, terraformPath, "state", "mv", mv[0], mv[1])
+		cmd.Dir = v.VolumeDir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to move %s to %s in the terraform state: %s %s", mv[0], mv[1], err, out)
+		}
+	}
+	return nil
+}
+
+// nodeCount is the number of Stardog nodes that the volume set backs.  It is
+// smaller than the number of volumes when volumes were kept while scaling down.
+func (v *EbsVolumes) nodeCount() string {
+	if v.NodeCount != "" {
+		return v.NodeCount
+	}
+	return v.ClusterSize
+}
+
+func (v *EbsVolumes) setNodeCount(nodeCount int) error {
+	v.NodeCount = fmt.Sprintf("%d", nodeCount)
+	if v.NodeCount == v.ClusterSize {
+		v.NodeCount = ""
+	}
+	return sdutils.WriteJSON(v, path.Join(v.VolumeDir, "config.json"))
+}
+
+func (v *EbsVolumes) getStatusInformation(ctx context.Context) (*VolumeStatusDescription, error) {
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, terraformPath, "output", "-json")
+	cmd.Dir = v.VolumeDir
 	data, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -208,8 +595,8 @@ func (v *EbsVolumes) getStatusInformation() (*VolumeStatusDescription, error) {
 }
 
 // Status will print out status information about the EBS volumes.
-func (v *EbsVolumes) Status() error {
-	vD, err := v.getStatusInformation()
+func (v *EbsVolumes) Status(ctx context.Context) error {
+	vD, err := v.getStatusInformation(ctx)
 	if err != nil {
 		return err
 	}
@@ -16,10 +16,12 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/stardog-union/stardog-graviton/sdutils"
@@ -58,7 +60,7 @@ func TestVolumesNotThere(t *testing.T) {
 		Version:    version,
 		PrivateKey: sshKeyFile,
 	}
-	dd, err := newAwsDeploymentDescription(&app, &baseD, plugin)
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
 	if err != nil {
 		t.Fatalf("Failed to make the deployment manager %s", err)
 	}
@@ -66,15 +68,15 @@ func TestVolumesNotThere(t *testing.T) {
 	if ebs.VolumeExists() {
 		t.Fatalf("The volume shouldn't exist yet")
 	}
-	err = ebs.Status()
+	err = ebs.Status(context.Background())
 	if err == nil {
 		t.Fatalf("The volume shouldn't exist yet, status should fail")
 	}
-	err = ebs.DeleteSet()
+	err = ebs.DeleteSet(context.Background())
 	if err == nil {
 		t.Fatalf("The delete should have failed")
 	}
-	err = ebs.CreateSet("/path/", 1, 3)
+	err = ebs.CreateSet(context.Background(), "/path/", 1, 3)
 	if err == nil {
 		t.Fatalf("The create should have failed")
 	}
@@ -93,7 +95,7 @@ func TestVolumesNotThere(t *testing.T) {
 		t.Fatalf("Failed to set env %s", err)
 	}
 
-	err = ebs.CreateSet("/path/", 1, 3)
+	err = ebs.CreateSet(context.Background(), "/path/", 1, 3)
 	if err != nil {
 		t.Fatalf("The create should have worked")
 	}
@@ -111,7 +113,7 @@ func TestVolumesNotThere(t *testing.T) {
 		t.Fatalf("The volume reload should exist yet")
 	}
 
-	err = ebs.Status()
+	err = ebs.Status(context.Background())
 	if err == nil {
 		t.Fatalf("The status should have bad output")
 	}
@@ -123,12 +125,12 @@ func TestVolumesNotThere(t *testing.T) {
 	}
 	defer os.RemoveAll(exedir2)
 
-	err = ebs.Status()
+	err = ebs.Status(context.Background())
 	if err != nil {
 		t.Fatalf("The status should work %s", err)
 	}
 
-	err = ebs.DeleteSet()
+	err = ebs.DeleteSet(context.Background())
 	if err != nil {
 		t.Fatalf("The delete should not have failed")
 	}
@@ -167,22 +169,22 @@ func TestVolumesThroughDD(t *testing.T) {
 		Version:    version,
 		PrivateKey: sshKeyFile,
 	}
-	dd, err := newAwsDeploymentDescription(&app, &baseD, plugin)
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
 	if err != nil {
 		t.Fatalf("Failed to make the deployment manager %s", err)
 	}
 	if dd.VolumeExists() {
 		t.Fatalf("The volume shouldn't exist yet")
 	}
-	err = dd.StatusVolumeSet()
+	err = dd.StatusVolumeSet(context.Background())
 	if err == nil {
 		t.Fatalf("The volume shouldn't exist yet, status should fail")
 	}
-	err = dd.DeleteVolumeSet()
+	err = dd.DeleteVolumeSet(context.Background())
 	if err == nil {
 		t.Fatalf("The delete should have failed")
 	}
-	err = dd.CreateVolumeSet("/path/", 1, 3)
+	err = dd.CreateVolumeSet(context.Background(), "/path/", 1, 3)
 	if err == nil {
 		t.Fatalf("The create should have failed")
 	}
@@ -201,7 +203,7 @@ func TestVolumesThroughDD(t *testing.T) {
 		t.Fatalf("Failed to set env %s", err)
 	}
 
-	err = dd.CreateVolumeSet("/path/", 1, 3)
+	err = dd.CreateVolumeSet(context.Background(), "/path/", 1, 3)
 	if err != nil {
 		t.Fatalf("The create should have worked")
 	}
@@ -219,7 +221,7 @@ func TestVolumesThroughDD(t *testing.T) {
 		t.Fatalf("The volume reload should exist yet")
 	}
 
-	err = dd.StatusVolumeSet()
+	err = dd.StatusVolumeSet(context.Background())
 	if err == nil {
 		t.Fatalf("The status should have bad output")
 	}
@@ -231,13 +233,353 @@ func TestVolumesThroughDD(t *testing.T) {
 	}
 	defer os.RemoveAll(exedir2)
 
-	err = dd.StatusVolumeSet()
+	err = dd.StatusVolumeSet(context.Background())
 	if err != nil {
 		t.Fatalf("The status should work %s", err)
 	}
 
-	err = dd.DeleteVolumeSet()
+	err = dd.DeleteVolumeSet(context.Background())
 	if err != nil {
 		t.Fatalf("The delete should not have failed")
 	}
 }
+
+func TestVolumesCleanupOnFailure(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshKeyFile := path.Join(dir, "keyfile")
+	ioutil.WriteFile(sshKeyFile, []byte("xxx"), 0600)
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+	secretSave := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	defer os.Setenv("AWS_SECRET_ACCESS_KEY", secretSave)
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "somesecret")
+
+	version := "4.2"
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   version,
+	}
+	plugin := &awsPlugin{
+		Region:         "us-west-1",
+		AmiID:          "notreal",
+		AwsKeyName:     "somekey",
+		ZkInstanceType: "m3.large",
+		SdInstanceType: "m3.large",
+		VolumeType:     "gp2",
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:       plugin.GetName(),
+		Name:       "testdep",
+		Directory:  dir,
+		Version:    version,
+		PrivateKey: sshKeyFile,
+	}
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment manager %s", err)
+	}
+	ebs := NewAwsEbsVolumeManager(&app, dd)
+
+	// A terraform that fails the apply after creating some resources and
+	// forgets them when they are destroyed.
+	exedir, err := ioutil.TempDir("", "stardogtest")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	destroyParams := path.Join(exedir, "destroy")
+	script := fmt.Sprintf(`#!/usr/bin/env bash
+case $1 in
+apply)
+  echo '{}' > terraform.tfstate
+  exit 1;;
+state)
+  if [ ! -e %s ]; then
+    echo aws_vpc.main
+    echo aws_ebs_volume.stardog_data.0
+  fi;;
+destroy)
+  echo ${@} > %s;;
+esac
+`, destroyParams, destroyParams)
+	err = ioutil.WriteFile(path.Join(exedir, "terraform"), []byte(script), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", exedir, startPath))
+
+	err = ebs.CreateSet(context.Background(), "/path/", 1, 3)
+	if err == nil {
+		t.Fatalf("The create should have failed")
+	}
+	data, err := ioutil.ReadFile(destroyParams)
+	if err != nil {
+		t.Fatalf("The partially created volumes should have been destroyed %s", err)
+	}
+	params := string(data)
+	if !strings.Contains(params, "-target aws_vpc.main") || !strings.Contains(params, "-target aws_ebs_volume.stardog_data.0") {
+		t.Fatalf("The destroy should target the created resources: %s", params)
+	}
+	if ebs.VolumeExists() {
+		t.Fatalf("The volume configuration should be removed after a clean rollback")
+	}
+}
+
+func TestVolumesRetryAfterFailedBuilderRemoval(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshKeyFile := path.Join(dir, "keyfile")
+	ioutil.WriteFile(sshKeyFile, []byte("xxx"), 0600)
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+
+	version := "4.2"
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   version,
+	}
+	plugin := &awsPlugin{
+		Region:         "us-west-1",
+		AmiID:          "notreal",
+		AwsKeyName:     "somekey",
+		ZkInstanceType: "m3.large",
+		SdInstanceType: "m3.large",
+		VolumeType:     "gp2",
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:       plugin.GetName(),
+		Name:       "testdep",
+		Directory:  dir,
+		Version:    version,
+		PrivateKey: sshKeyFile,
+	}
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment manager %s", err)
+	}
+	ebs := NewAwsEbsVolumeManager(&app, dd)
+
+	// A terraform that records whether the builders were part of each apply
+	// and fails the apply that stops them while the fail file exists.
+	exedir, err := ioutil.TempDir("", "stardogtest")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	applies := path.Join(exedir, "applies")
+	failFile := path.Join(exedir, "fail")
+	script := fmt.Sprintf(`#!/usr/bin/env bash
+if [ "$1" == "apply" ]; then
+  if [ -e builder.tf ]; then
+    echo builder >> %s
+  else
+    echo nobuilder >> %s
+    if [ -e %s ]; then
+      exit 1
+    fi
+  fi
+fi
+`, applies, applies, failFile)
+	err = ioutil.WriteFile(path.Join(exedir, "terraform"), []byte(script), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	ioutil.WriteFile(failFile, []byte(""), 0644)
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", exedir, startPath))
+
+	err = ebs.CreateSet(context.Background(), "/path/", 1, 3)
+	if err == nil {
+		t.Fatalf("The create should have failed")
+	}
+	if ebs.VolumeExists() {
+		t.Fatalf("The volume configuration should be removed after a clean rollback")
+	}
+
+	os.Remove(failFile)
+	err = ebs.CreateSet(context.Background(), "/path/", 1, 3)
+	if err != nil {
+		t.Fatalf("The retried create failed %s", err)
+	}
+	data, err := ioutil.ReadFile(applies)
+	if err != nil {
+		t.Fatalf("Failed to read the applies %s", err)
+	}
+	expected := "builder\nnobuilder\nbuilder\nnobuilder\n"
+	if string(data) != expected {
+		t.Fatalf("The retry should format the volumes again, expected %q but got %q", expected, string(data))
+	}
+}
+
+func TestVolumesRestoreSet(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshKeyFile := path.Join(dir, "keyfile")
+	ioutil.WriteFile(sshKeyFile, []byte("xxx"), 0600)
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+
+	version := "4.2"
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   version,
+	}
+	plugin := &awsPlugin{
+		Region:         "us-west-1",
+		AmiID:          "notreal",
+		AwsKeyName:     "somekey",
+		ZkInstanceType: "m3.large",
+		SdInstanceType: "m3.large",
+		VolumeType:     "io1",
+		IoPs:           50,
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:       plugin.GetName(),
+		Name:       "testdep",
+		Directory:  dir,
+		Version:    version,
+		PrivateKey: sshKeyFile,
+	}
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment manager %s", err)
+	}
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	exedir, _, err := CreateTestExec("terraform", "data", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", exedir, startPath))
+
+	ebs := NewAwsEbsVolumeManager(&app, dd)
+	err = ebs.RestoreSet(context.Background(), []string{"snap-0", "snap-1"}, 10)
+	if err != nil {
+		t.Fatalf("The restore should have worked %s", err)
+	}
+	if !ebs.VolumeExists() {
+		t.Fatalf("The volume should exist")
+	}
+	loadedEbs, err := LoadEbsVolume(&app, ebs.VolumeDir)
+	if err != nil {
+		t.Fatalf("The re-load should not have failed %s", err)
+	}
+	if loadedEbs.ClusterSize != "2" || loadedEbs.FirstNewVolume != "2" {
+		t.Fatalf("The restored volumes must not be formatted %s %s", loadedEbs.ClusterSize, loadedEbs.FirstNewVolume)
+	}
+	if loadedEbs.SnapshotIds["0"] != "snap-0" || loadedEbs.SnapshotIds["1"] != "snap-1" {
+		t.Fatalf("The snapshots were not recorded %v", loadedEbs.SnapshotIds)
+	}
+	if loadedEbs.IoPs != "500" || loadedEbs.LicensePath != "" {
+		t.Fatalf("The volume settings are wrong %s %s", loadedEbs.IoPs, loadedEbs.LicensePath)
+	}
+}
+
+func TestVolumesModify(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshKeyFile := path.Join(dir, "keyfile")
+	ioutil.WriteFile(sshKeyFile, []byte("xxx"), 0600)
+	keySave := os.Getenv("AWS_ACCESS_KEY_ID")
+	defer os.Setenv("AWS_ACCESS_KEY_ID", keySave)
+	os.Setenv("AWS_ACCESS_KEY_ID", "gravitontest")
+
+	version := "4.2"
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   version,
+	}
+	plugin := &awsPlugin{
+		Region:         "us-west-1",
+		AmiID:          "notreal",
+		AwsKeyName:     "somekey",
+		ZkInstanceType: "m3.large",
+		SdInstanceType: "m3.large",
+		VolumeType:     "gp2",
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:       plugin.GetName(),
+		Name:       "testdep",
+		Directory:  dir,
+		Version:    version,
+		PrivateKey: sshKeyFile,
+	}
+	dd, err := newAwsDeploymentDescription(context.Background(), &app, &baseD, plugin)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment manager %s", err)
+	}
+	baseD.CloudOpts = dd
+	err = sdutils.WriteJSON(&baseD, path.Join(sdutils.DeploymentDir(dir, baseD.Name), "config.json"))
+	if err != nil {
+		t.Fatalf("Failed to write the deployment %s", err)
+	}
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	data := `{"volumes": { "sensitive": false, "type": "list", "value": ["vol-0", "vol-1"]}}`
+	exedir, _, err := CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", exedir, startPath))
+
+	ebs := NewAwsEbsVolumeManager(&app, dd)
+	err = ebs.CreateSet(context.Background(), "/path/", 10, 2)
+	if err != nil {
+		t.Fatalf("The create should have worked %s", err)
+	}
+
+	err = dd.ModifyVolumes(context.Background(), 5, "", 0, 1)
+	if err == nil {
+		t.Fatalf("The volumes should not shrink")
+	}
+	err = dd.ModifyVolumes(context.Background(), 0, "nosuchtype", 0, 1)
+	if err == nil {
+		t.Fatalf("The volume type should be refused")
+	}
+	err = dd.ModifyVolumes(context.Background(), 0, "gp2", 30, 1)
+	if err == nil {
+		t.Fatalf("The IOPS of gp2 volumes cannot be set")
+	}
+
+	err = dd.ModifyVolumes(context.Background(), 20, "io1", 0, 1)
+	if err != nil {
+		t.Fatalf("The modify should have worked %s", err)
+	}
+	loadedEbs, err := LoadEbsVolume(&app, ebs.VolumeDir)
+	if err != nil {
+		t.Fatalf("The re-load should not have failed %s", err)
+	}
+	if loadedEbs.SizeOfEachVolume != "20" || loadedEbs.VolumeType != "io1" || loadedEbs.IoPs != "400" {
+		t.Fatalf("The volume settings are wrong %s %s %s", loadedEbs.SizeOfEachVolume, loadedEbs.VolumeType, loadedEbs.IoPs)
+	}
+	if dd.VolumeType != "io1" || dd.IoPsRatio != 20 {
+		t.Fatalf("The deployment settings are wrong %s %d", dd.VolumeType, dd.IoPsRatio)
+	}
+
+	// Magnetic volumes are created again from snapshots
+	err = dd.ModifyVolumes(context.Background(), 30, "standard", 0, 1)
+	if err != nil {
+		t.Fatalf("The recreate should have worked %s", err)
+	}
+	loadedEbs, err = LoadEbsVolume(&app, ebs.VolumeDir)
+	if err != nil {
+		t.Fatalf("The re-load should not have failed %s", err)
+	}
+	if loadedEbs.SizeOfEachVolume != "30" || loadedEbs.VolumeType != "standard" || loadedEbs.ClusterSize != "2" {
+		t.Fatalf("The volume settings are wrong %s %s %s", loadedEbs.SizeOfEachVolume, loadedEbs.VolumeType, loadedEbs.ClusterSize)
+	}
+	if len(loadedEbs.SnapshotIds) != 0 || loadedEbs.FirstNewVolume != "0" {
+		t.Fatalf("The snapshots should be forgotten %v %s", loadedEbs.SnapshotIds, loadedEbs.FirstNewVolume)
+	}
+}
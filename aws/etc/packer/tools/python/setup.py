@@ -20,6 +20,9 @@ setuptools.setup(name='stardog-cluster-utils',
               "stardog-wait-for-socket=stardog.cluster.wait_for_socket:main",
               "stardog-wait-for-pgm=stardog.cluster.test_program:main",
               "stardog-gather-logs=stardog.cluster.gather_log:main",
+              "stardog-backup-db=stardog.cluster.backup:backup_main",
+              "stardog-restore-db=stardog.cluster.backup:restore_main",
+              "stardog-grow-fs=stardog.cluster.grow_fs:main",
               "stardog-monitor-zk=stardog.cluster.monitor_zk:main"
           ],
       },
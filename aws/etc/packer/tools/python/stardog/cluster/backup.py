@@ -0,0 +1,112 @@
+import logging
+import os
+import random
+import shlex
+import subprocess
+import sys
+import tarfile
+import tempfile
+
+import stardog.cluster.gather_log as gather_log
+
+
+# Stardog writes a backup on the node that runs it so it is moved through the
+# bastion.  The directory is under STARDOG_HOME because that is the volume with
+# room for it.
+BACKUP_ROOT = "/mnt/data/stardog-home/.graviton-backup"
+SSH_OPTS = ["-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null"]
+STARDOG_ADMIN = "/usr/local/stardog/bin/stardog-admin"
+
+
+def node_hosts(sd_url, pw):
+    cluster_doc = gather_log.get_cluster_doc(sd_url, pw)
+    return [hp.split(':')[0] for hp in cluster_doc['nodes']]
+
+
+# The commands are argument lists that run without a shell so that the
+# password and the names are never split or interpreted.  hide is left out of
+# the log.
+def run(cmd, stdin=subprocess.DEVNULL, stdout=subprocess.PIPE, hide=None):
+    logging.info("Running %s" % " ".join("****" if a == hide else a for a in cmd))
+    p = subprocess.Popen(cmd, stdin=stdin, stdout=stdout, stderr=subprocess.PIPE)
+    o, e = p.communicate()
+    logging.info("stderr output %s" % e)
+    return p.returncode == 0
+
+
+# ssh hands the remote command to a shell so its arguments are quoted.
+def ssh(host, *args):
+    return ["ssh"] + SSH_OPTS + [host] + [shlex.quote(a) for a in args]
+
+
+def remove_dir(hosts, backup_dir):
+    for host in hosts:
+        run(ssh(host, "sudo", "rm", "-rf", backup_dir))
+
+
+def backup(sd_url, db, dst_file, pw):
+    hosts = node_hosts(sd_url, pw)
+    backup_dir = os.path.join(BACKUP_ROOT, "%s-%d" % (db, random.randint(0, 1 << 31)))
+    cmd = [STARDOG_ADMIN, "--server", sd_url, "db", "backup", "-u", gather_log.admin_user(), "-p", pw, "--to", backup_dir, db]
+    if not run(cmd, hide=pw):
+        raise Exception("The backup of %s failed" % db)
+    try:
+        for host in hosts:
+            with open(dst_file, 'wb') as f:
+                if run(ssh(host, "sudo", "tar", "-czf", "-", "-C", backup_dir, "."), stdout=f):
+                    logging.info("Copied the backup of %s from %s" % (db, host))
+                    return 0
+        raise Exception("The backup of %s was not found on any node" % db)
+    finally:
+        remove_dir(hosts, backup_dir)
+
+
+def restore_path(src_file, backup_dir, db):
+    # The archive holds <db>/<backup time>/ as written by db backup
+    with tarfile.open(src_file, 'r:gz') as tar:
+        for name in tar.getnames():
+            parts = os.path.normpath(name).split(os.sep)
+            if len(parts) >= 2 and parts[0] == db:
+                return os.path.join(backup_dir, parts[0], parts[1])
+    raise Exception("The archive %s has no backup of %s" % (src_file, db))
+
+
+def restore(sd_url, db, src_file, pw, overwrite):
+    hosts = node_hosts(sd_url, pw)
+    backup_dir = os.path.join(BACKUP_ROOT, "%s-%d" % (db, random.randint(0, 1 << 31)))
+    path = restore_path(src_file, backup_dir, db)
+    try:
+        for host in hosts:
+            with open(src_file, 'rb') as f:
+                d = shlex.quote(backup_dir)
+                cmd = ["ssh"] + SSH_OPTS + [host, "sudo mkdir -p %s && sudo tar -xzf - -C %s" % (d, d)]
+                if not run(cmd, stdin=f):
+                    raise Exception("Failed to copy the backup to %s" % host)
+        cmd = [STARDOG_ADMIN, "--server", sd_url, "db", "restore", "-u", gather_log.admin_user(), "-p", pw]
+        if overwrite:
+            cmd.append("--overwrite")
+        cmd.append(path)
+        if not run(cmd, hide=pw):
+            raise Exception("The restore of %s failed" % db)
+    finally:
+        remove_dir(hosts, backup_dir)
+    return 0
+
+
+def backup_main():
+    sd_url = sys.argv[1]
+    db = sys.argv[2]
+    dst_file = sys.argv[3]
+    pw = gather_log.read_password()
+    return backup(sd_url, db, dst_file, pw)
+
+
+def restore_main():
+    # stardog-restore-db <url> <db> <file> [overwrite] with the password on
+    # the standard input
+    sd_url = sys.argv[1]
+    db = sys.argv[2]
+    src_file = sys.argv[3]
+    overwrite = len(sys.argv) > 4 and sys.argv[4] == "overwrite"
+    pw = gather_log.read_password()
+    return restore(sd_url, db, src_file, pw, overwrite)
@@ -56,6 +56,10 @@ def mount_format(device, mount_point):
         except FileExistsError:
             pass
         utils.command("chown -R ubuntu %s" % home_dir)
+    else:
+        # The volume may have been made bigger since it was formatted
+        if not utils.command("resize2fs %s" % device):
+            logging.warning("Failed to grow the filesystem on %s" % device)
 
 
 def main():
@@ -10,10 +10,21 @@ import subprocess
 import sys
 
 
+# graviton names the Stardog user in STARDOG_USER when it is not admin
+def admin_user():
+    return os.environ.get("STARDOG_USER", "admin")
+
+
+# graviton writes the password on the standard input so that it is never on a
+# command line
+def read_password():
+    return sys.stdin.readline().rstrip("\n")
+
+
 def get_cluster_doc(sd_url, pw):
     full_url = sd_url + "/admin/cluster"
     logging.info("Trying to contact %s" % full_url)
-    r = requests.get(sd_url + "/admin/cluster", auth=('admin', pw))
+    r = requests.get(sd_url + "/admin/cluster", auth=(admin_user(), pw))
     if r.status_code != 200:
         raise Exception("Unable to get the cluster document %d" % r.status_code)
     return r.json()
@@ -55,7 +66,7 @@ def create_tarball(src_dir, dst_file):
 def main():
     sd_url = sys.argv[1]
     dst_file = sys.argv[2]
-    pw = sys.argv[3]
+    pw = read_password()
     d = get_cluster_doc(sd_url, pw)
     n, log_dir = get_all_logs(d)
     logging.info("Retrieved %d logs" % n)
@@ -0,0 +1,33 @@
+import logging
+import subprocess
+import sys
+
+import stardog.cluster.gather_log as gather_log
+
+
+# The device that stardog-find-volume attaches the data volume to
+DEVICE = "/dev/xvdh"
+
+
+def grow(host):
+    ssh_opts = "-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
+    cmd = "ssh %s %s sudo resize2fs %s" % (ssh_opts, host, DEVICE)
+    print(cmd)
+    p = subprocess.Popen(cmd, shell=True, stdout=subprocess.PIPE, stderr=subprocess.PIPE)
+    o, e = p.communicate()
+    logging.info("resize2fs stdout output %s" % o)
+    logging.info("resize2fs stderr output %s" % e)
+    if p.returncode != 0:
+        logging.warning("Growing the filesystem on %s failed" % host)
+        return False
+    return True
+
+
+def main():
+    sd_url = sys.argv[1]
+    pw = gather_log.read_password()
+    d = gather_log.get_cluster_doc(sd_url, pw)
+    failed = [hp for hp in d['nodes'] if not grow(hp.split(':')[0])]
+    if failed:
+        raise Exception("The filesystems of %s were not grown" % ", ".join(failed))
+    return 0
@@ -24,6 +24,10 @@ resource "aws_launch_configuration" "bastion" {
   key_name = "${var.aws_key_name}"
   security_groups = ["${aws_security_group.bastion.id}"]
   associate_public_ip_address = true
+
+  lifecycle {
+    create_before_destroy = true
+  }
 }
 
 resource "aws_security_group" "bastion" {
@@ -59,6 +59,12 @@ resource "aws_launch_configuration" "stardog" {
     volume_size = "${var.root_volume_size}"
     delete_on_termination = "true"
   }
+
+  # A new launch configuration has to exist before the autoscaling groups can
+  # be moved off the old one.
+  lifecycle {
+    create_before_destroy = true
+  }
 }
 
 resource "aws_iam_instance_profile" "stardog" {
@@ -63,6 +63,10 @@ resource "aws_launch_configuration" "zookeeper" {
   security_groups = ["${aws_security_group.zookeeper.id}"]
   # XXX TODO figure out why we need a public ip for external routing
   associate_public_ip_address = true
+
+  lifecycle {
+    create_before_destroy = true
+  }
 }
 
 resource "aws_elb" "zookeeper" {
@@ -56,9 +56,11 @@ resource "aws_subnet" "stardog" {
   }
 }
 
+# Only the volumes from first_new_volume on are formatted so that growing the
+# set never touches volumes that already hold data.
 resource "aws_instance" "stardog_data" {
-  availability_zone = "${element(var.aws_az[var.aws_region], count.index % length(var.aws_az[var.aws_region]))}"
-  count = "${var.cluster_size}"
+  availability_zone = "${element(var.aws_az[var.aws_region], (count.index + var.first_new_volume) % length(var.aws_az[var.aws_region]))}"
+  count = "${var.cluster_size - var.first_new_volume}"
   tags {
     Name = "Volume builder"
     DeploymentName = "${var.deployment_name}"
@@ -69,19 +71,19 @@ resource "aws_instance" "stardog_data" {
   ami = "${var.ami}"
   key_name = "${var.aws_key_name}"
   security_groups = ["${aws_security_group.stardog_data.id}"]
-  subnet_id = "${element(aws_subnet.stardog.*.id, count.index)}"
+  subnet_id = "${element(aws_subnet.stardog.*.id, count.index + var.first_new_volume)}"
   depends_on = ["aws_ebs_volume.stardog_data"]
 }
 
 resource "aws_volume_attachment" "stardog_data" {
-  count = "${var.cluster_size}"
+  count = "${var.cluster_size - var.first_new_volume}"
   device_name = "/dev/xvdh"
-  volume_id = "${element(aws_ebs_volume.stardog_data.*.id, count.index)}"
+  volume_id = "${element(aws_ebs_volume.stardog_data.*.id, count.index + var.first_new_volume)}"
   instance_id = "${element(aws_instance.stardog_data.*.id, count.index)}"
 }
 
 resource "null_resource" "stardog_data" {
-  count = "${var.cluster_size}"
+  count = "${var.cluster_size - var.first_new_volume}"
 
   # Settings for SSH connection
   connection {
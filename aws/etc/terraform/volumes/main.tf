@@ -8,9 +8,16 @@ resource "aws_ebs_volume" "stardog_data" {
   size = "${var.storage_size}"
   type = "${var.volume_type}"
   iops = "${var.iops}"
+  snapshot_id = "${lookup(var.snapshot_ids, count.index, "")}"
   tags {
     Name = "Stardog data volume"
     DeploymentName = "${var.deployment_name}"
     StardogVirtualAppliance = "${var.deployment_name}"
   }
+
+  # The snapshot only matters when the volume is created.  Shrinking the set
+  # moves volumes to other indices and that must not replace them.
+  lifecycle {
+    ignore_changes = ["snapshot_id"]
+  }
 }
@@ -8,6 +8,18 @@ variable "cluster_size" {
   description = "The number of stardog nodes to use (must be odd and greater than 1)."
 }
 
+variable "first_new_volume" {
+  type = "string"
+  description = "The index of the first volume that the builder formats."
+  default = "0"
+}
+
+variable "node_count" {
+  type = "string"
+  description = "The number of volumes in use by Stardog nodes.  Graviton reads this, terraform does not."
+  default = "0"
+}
+
 variable "aws_region" {
   type = "string"
   description = "The AWS region to create things in."
@@ -74,6 +86,13 @@ variable "instance_type" {
 variable "stardog_license" {
   type = "string"
   description = "The path to your stardog license"
+  default = ""
+}
+
+variable "snapshot_ids" {
+  type = "map"
+  description = "The snapshot to create the volume of each index from."
+  default = {}
 }
 
 variable "volume_type" {
@@ -16,38 +16,11 @@
 package aws
 
 import (
-	"fmt"
-	"io/ioutil"
-	"os"
-	"path"
+	"github.com/stardog-union/stardog-graviton/sdutils"
 )
 
 // CreateTestExec writes a file to the system for use as a mock for packer or terraform
 // in testing.
 func CreateTestExec(pgmName string, output string, rc int) (string, string, error) {
-	exedir, err := ioutil.TempDir("/tmp", "stardogtest")
-	if err != nil {
-		return "", "", err
-	}
-
-	dataFile := path.Join(exedir, "datafile")
-	err = ioutil.WriteFile(dataFile, []byte(output), 0644)
-	if err != nil {
-		return "", "", fmt.Errorf("Failed to write the file %s", err)
-	}
-	paramsFile := path.Join(exedir, "params")
-
-	exeTemplate := fmt.Sprintf("#!/usr/bin/env bash\necho ${@} > %s\ncat %s\nexit %d", paramsFile, dataFile, rc)
-	exeFile := path.Join(exedir, pgmName)
-	err = ioutil.WriteFile(exeFile, []byte(exeTemplate), 0755)
-	if err != nil {
-		return "", "", fmt.Errorf("Failed to write the file %s", err)
-	}
-	startPath := os.Getenv("PATH")
-	newPath := fmt.Sprintf("%s:%s", exedir, startPath)
-	err = os.Setenv("PATH", newPath)
-	if err != nil {
-		return "", "", fmt.Errorf("Failed to set env %s", err)
-	}
-	return exedir, exeFile, nil
+	return sdutils.CreateTestExec(pgmName, output, rc)
 }
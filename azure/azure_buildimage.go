@@ -0,0 +1,128 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/aws"
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func lineScanner(cliContext sdutils.AppContext, line string) *sdutils.ScanResult {
+	marker := "azure-arm,artifact,0,id,"
+	ndx := strings.Index(line, marker)
+	if ndx == -1 {
+		return nil
+	}
+	image := strings.TrimSpace(line[ndx+len(marker):])
+	if !strings.Contains(image, "/images/") {
+		cliContext.Logf(sdutils.ERROR, "Did not find the managed image in the expected packer output.")
+		return nil
+	}
+	return &sdutils.ScanResult{Key: "IMAGE", Value: image}
+}
+
+func (a *azurePlugin) HaveImage(ctx context.Context, c sdutils.AppContext) bool {
+	imageMap, err := loadImageMap(c)
+	if err != nil {
+		return false
+	}
+	_, ok := imageMap[a.Location]
+	return ok
+}
+
+// BuildImage runs packer with the azure-arm builder to make a managed image.
+// The provisioning scripts are shared with the aws plugin so its packer
+// assets are extracted first and the azure template is placed over them.
+func (a *azurePlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	context.Logf(sdutils.DEBUG, "Build managed image\n")
+
+	err := checkEnvs()
+	if err != nil {
+		return err
+	}
+
+	// packer requires the resource group of the image to exist
+	_, err = runAz(ctx, context, "group", "create", "--name", a.ImageResourceGroup, "--location", a.Location)
+	if err != nil {
+		return err
+	}
+
+	dir, err := aws.PlaceAsset(context, context.GetConfigDir(), "etc/packer", true)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	err = RestoreAssets(dir, "etc/packer")
+	if err != nil {
+		return err
+	}
+	context.Logf(sdutils.DEBUG, "Extracting packer files to: %s\n", dir)
+	context.ConsoleLog(2, "Extracting packer files to: %s\n", dir)
+
+	packerPath, err := exec.LookPath("packer")
+	if err != nil {
+		return err
+	}
+
+	imageName := fmt.Sprintf("stardog-%s-%d", version, time.Now().Unix())
+	workingDir := path.Join(dir, "etc/packer")
+	cmdArray := []string{packerPath, "build", "-machine-readable",
+		"-var", fmt.Sprintf("stardog_release_file=%s", sdReleaseFilePath),
+		"-var", fmt.Sprintf("version=%s", version),
+		"-var", fmt.Sprintf("image_name=%s", imageName),
+		"-var", fmt.Sprintf("resource_group=%s", a.ImageResourceGroup),
+		"-var", fmt.Sprintf("location=%s", a.Location),
+		"stardog.json"}
+
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  workingDir,
+	}
+
+	context.Logf(sdutils.DEBUG, "Start packer")
+	spin := sdutils.NewSpinner(context, 1, "Running packer to build the image")
+	results, err := sdutils.RunCommand(ctx, context, cmd, lineScanner, spin)
+	if err != nil {
+		context.ConsoleLog(0, "We failed to build the image.  Please verify that the service principal has sufficent access.")
+		return err
+	}
+	if len(*results) < 1 {
+		return fmt.Errorf("Failed to find the image in the packer output")
+	}
+	if len(*results) > 1 {
+		context.Logf(sdutils.WARN, "We found more than 1 image")
+	}
+
+	context.ConsoleLog(1, "done\n")
+	context.ConsoleLog(0, "Image successfully built: %s\n", (*results)[0].Value)
+	context.Logf(sdutils.DEBUG, "Image successfully built: %s\n", (*results)[0].Value)
+
+	imageMap, err := loadImageMap(context)
+	if err != nil {
+		return err
+	}
+	imageMap[a.Location] = (*results)[0].Value
+	return saveImageMap(context, imageMap)
+}
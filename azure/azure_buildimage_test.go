@@ -0,0 +1,115 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+const fakeImageID = "/subscriptions/s/resourceGroups/images/providers/Microsoft.Compute/images/stardog-4.2-1500000000"
+
+func TestGoodPacker(t *testing.T) {
+	fakePacker := fmt.Sprintf("#!/usr/bin/env bash\necho 1500000000,azure-arm,artifact,0,id,%s\nexit 0", fakeImageID)
+
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	logFile := createFakeAz(t, dir)
+	err := ioutil.WriteFile(path.Join(dir, "packer"), []byte(fakePacker), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	azP := newTestPlugin()
+	if azP.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should not be there yet")
+	}
+	err = azP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
+	if err != nil {
+		t.Fatalf("Packer failed %s", err)
+	}
+	if !azP.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should be there")
+	}
+	imageMap, _ := loadImageMap(&app)
+	if imageMap[azP.Location] != fakeImageID {
+		t.Fatalf("The wrong image was recorded %s", imageMap[azP.Location])
+	}
+	calls, _ := ioutil.ReadFile(logFile)
+	if !strings.Contains(string(calls), "group create --name images --location eastus") {
+		t.Fatalf("The image resource group should have been created %s", calls)
+	}
+}
+
+func TestBadRcPacker(t *testing.T) {
+	fakePacker := fmt.Sprintf("#!/usr/bin/env bash\necho 1500000000,azure-arm,artifact,0,id,%s\nexit 1", fakeImageID)
+
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	createFakeAz(t, dir)
+	err := ioutil.WriteFile(path.Join(dir, "packer"), []byte(fakePacker), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	azP := newTestPlugin()
+	err = azP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
+	if err == nil {
+		t.Fatalf("Packer should have failed")
+	}
+	if azP.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should not be there")
+	}
+}
+
+func TestPackerNoEnvs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+	os.Unsetenv("ARM_TENANT_ID")
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	err := newTestPlugin().BuildImage(context.Background(), &app, "/etc/group", "4.2")
+	if err == nil {
+		t.Fatalf("The build should require the service principal")
+	}
+}
@@ -0,0 +1,371 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type azureDeploymentDescription struct {
+	Location        string `json:"location,omitempty"`
+	ImageID         string `json:"image_id,omitempty"`
+	ZkVMSize        string `json:"zk_vm_size,omitempty"`
+	SdVMSize        string `json:"sd_vm_size,omitempty"`
+	BastionVMSize   string `json:"bastion_vm_size,omitempty"`
+	DiskType        string `json:"disk_type,omitempty"`
+	PrivateKeyPath  string `json:"private_key_path,omitempty"`
+	CreatedKey      bool   `json:"created_key,omitempty"`
+	Version         string `json:"-"`
+	Name            string `json:"-"`
+	deployDir       string
+	customPropFile  string
+	environment     []string
+	disableSecurity bool
+	ctx             sdutils.AppContext
+	plugin          *azurePlugin
+}
+
+func validDiskType(diskType string) bool {
+	for _, t := range ValidDiskTypes {
+		if t == diskType {
+			return true
+		}
+	}
+	return false
+}
+
+func newAzureDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeployment, a *azurePlugin) (*azureDeploymentDescription, error) {
+	createdKey := false
+
+	err := ValidateDeploymentName(baseD.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !validDiskType(a.DiskType) {
+		return nil, fmt.Errorf("%s is not a valid disk type", a.DiskType)
+	}
+	if a.ImageID == "" {
+		// If the image is not specified look it up
+		imageMap, err := loadImageMap(c)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load the image map: %s", err)
+		}
+		image, ok := imageMap[a.Location]
+		if !ok {
+			c.ConsoleLog(1, "A base image is required for launching the virtual appliance.  If you do not know this value you can build a new one with the 'baseami' command.\n")
+			image, err = c.Prompt("Stardog base image id", "")
+			if err != nil {
+				return nil, err
+			}
+			if image == "" {
+				return nil, fmt.Errorf("An image is required.  Please see the 'baseami' subcommand")
+			}
+		}
+		a.ImageID = image
+	}
+
+	// Azure takes the public key in the VM profile so there is no key name to
+	// look up, only a key pair on disk.
+	if baseD.PrivateKey == "" {
+		privateKeyFilename, _, err := sdutils.GenerateKey(baseD.Directory, baseD.Name+"key")
+		if err != nil {
+			return nil, err
+		}
+		createdKey = true
+		baseD.PrivateKey = privateKeyFilename
+	}
+	fi, err := os.Stat(baseD.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("There was an error accessing the private key %s: %s", baseD.PrivateKey, err)
+	}
+	if fi.Mode()&0077 != 0 {
+		return nil, fmt.Errorf("The permissions on the private key %s must only allow for user access", baseD.PrivateKey)
+	}
+	if !sdutils.PathExists(baseD.PrivateKey + ".pub") {
+		return nil, fmt.Errorf("The public key %s.pub must exist next to the private key", baseD.PrivateKey)
+	}
+
+	deployDir := sdutils.DeploymentDir(c.GetConfigDir(), baseD.Name)
+	assetDir, err := PlaceAsset(c, deployDir, "etc/terraform", false)
+	if err != nil {
+		return nil, err
+	}
+	c.ConsoleLog(2, "Terraform configuration extracted to %s\n", assetDir)
+
+	dd := azureDeploymentDescription{
+		Location:        a.Location,
+		ImageID:         a.ImageID,
+		ZkVMSize:        a.ZkVMSize,
+		SdVMSize:        a.SdVMSize,
+		BastionVMSize:   a.BastionVMSize,
+		DiskType:        a.DiskType,
+		PrivateKeyPath:  baseD.PrivateKey,
+		CreatedKey:      createdKey,
+		Version:         baseD.Version,
+		Name:            baseD.Name,
+		ctx:             c,
+		deployDir:       deployDir,
+		customPropFile:  baseD.CustomPropsFile,
+		environment:     baseD.Environment,
+		disableSecurity: baseD.DisableSecurity,
+		plugin:          a,
+	}
+	return &dd, nil
+}
+
+func (dd *azureDeploymentDescription) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (dd *azureDeploymentDescription) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	vm := NewManagedDiskManager(dd.ctx, dd)
+	return vm.CreateSet(ctx, licensePath, sizeOfEachVolume, clusterSize)
+}
+
+func (dd *azureDeploymentDescription) DeleteVolumeSet(ctx context.Context) error {
+	vm := NewManagedDiskManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.DeleteSet(ctx)
+}
+
+func (dd *azureDeploymentDescription) ClusterSize() (int, error) {
+	vm := NewManagedDiskManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return -1, fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadManagedDisks(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return -1, err
+	}
+	var size int
+	c, err := fmt.Sscanf(vols.ClusterSize, "%d", &size)
+	if err != nil {
+		return -1, err
+	}
+	if c != 1 {
+		return -1, fmt.Errorf("Internal error: the cluster size is not coherent")
+	}
+	return size, nil
+}
+
+func (dd *azureDeploymentDescription) StatusVolumeSet(ctx context.Context) error {
+	vm := NewManagedDiskManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.Status(ctx)
+}
+
+func (dd *azureDeploymentDescription) VolumeExists() bool {
+	vm := NewManagedDiskManager(dd.ctx, dd)
+	return vm.VolumeExists()
+}
+
+func (dd *azureDeploymentDescription) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	im, err := NewScaleSetInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.CreateInstance(ctx, volumeSize, zookeeperSize, idleTimeout)
+}
+
+func (dd *azureDeploymentDescription) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	im, err := NewScaleSetInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.OpenInstance(ctx, volumeSize, zookeeperSize, mask, idleTimeout)
+}
+
+func (dd *azureDeploymentDescription) DeleteInstance(ctx context.Context) error {
+	im, err := NewScaleSetInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.DeleteInstance(ctx)
+}
+
+func (dd *azureDeploymentDescription) StatusInstance(ctx context.Context) error {
+	im, err := NewScaleSetInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.Status(ctx)
+}
+
+func (dd *azureDeploymentDescription) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	vm := NewManagedDiskManager(dd.ctx, dd)
+	volumeStatus, err := vm.getStatusInformation(ctx)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No volume information found %s\n", err)
+	}
+
+	im, err := NewScaleSetInstance(dd.ctx, dd)
+	if err != nil {
+		return nil, err
+	}
+	instS, err := getInstanceValues(ctx, im)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No instance information found.\n")
+	}
+
+	sD := sdutils.StardogDescription{
+		SSHHost:             im.BastionContact,
+		SSHUser:             "ubuntu",
+		StardogURL:          fmt.Sprintf("http://%s:5821", im.StardogContact),
+		StardogInternalURL:  fmt.Sprintf("http://%s:5821", im.StardogInternalContact),
+		VolumeDescription:   volumeStatus,
+		InstanceDescription: instS,
+		TimeStamp:           time.Now(),
+	}
+	return &sD, nil
+}
+
+func (dd *azureDeploymentDescription) InstanceExists() bool {
+	im, err := NewScaleSetInstance(dd.ctx, dd)
+	if err != nil {
+		return false
+	}
+	return im.InstanceExists()
+}
+
+type azurePlugin struct {
+	Location           string `json:"location,omitempty"`
+	ImageID            string `json:"image_id,omitempty"`
+	ImageResourceGroup string `json:"image_resource_group,omitempty"`
+	ZkVMSize           string `json:"zk_vm_size,omitempty"`
+	SdVMSize           string `json:"sd_vm_size,omitempty"`
+	BastionVMSize      string `json:"bastion_vm_size,omitempty"`
+	DiskType           string `json:"disk_type,omitempty"`
+}
+
+// GetPlugin returns the plugin interface that this module represents.
+func GetPlugin() sdutils.Plugin {
+	return &azurePlugin{
+		Location:           "eastus",
+		ImageResourceGroup: "stardog-graviton-images",
+		ZkVMSize:           "Standard_B1ms",
+		SdVMSize:           "Standard_D2s_v3",
+		BastionVMSize:      "Standard_B1s",
+		DiskType:           "Standard_LRS",
+	}
+}
+
+func (a *azurePlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	b, err := json.Marshal(defaultCliOpts)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, a)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *azurePlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	locationHelp := fmt.Sprintf("The Azure location to use [%s].", strings.Join(ValidLocations, " | "))
+	diskHelp := fmt.Sprintf("The managed disk type to use [%s].", strings.Join(ValidDiskTypes, " | "))
+
+	cmdOpts.BuildCmd.Flag("azure-location", locationHelp).Default(a.Location).StringVar(&a.Location)
+	cmdOpts.BuildCmd.Flag("azure-image-resource-group", "The resource group that holds the Azure images.").Default(a.ImageResourceGroup).StringVar(&a.ImageResourceGroup)
+
+	cmdOpts.NewVolumesCmd.Flag("azure-disk-type", diskHelp).Default(a.DiskType).StringVar(&a.DiskType)
+
+	cmdOpts.LaunchCmd.Flag("azure-location", locationHelp).Default(a.Location).StringVar(&a.Location)
+	cmdOpts.LaunchCmd.Flag("azure-image", "The id of the Azure managed image to boot.").Default(a.ImageID).StringVar(&a.ImageID)
+	cmdOpts.LaunchCmd.Flag("azure-zk-vm-size", "The VM size to use for zookeeper VMs.").Default(a.ZkVMSize).StringVar(&a.ZkVMSize)
+	cmdOpts.LaunchCmd.Flag("azure-sd-vm-size", "The VM size to use for stardog VMs.").Default(a.SdVMSize).StringVar(&a.SdVMSize)
+	cmdOpts.LaunchCmd.Flag("azure-disk-type", diskHelp).Default(a.DiskType).StringVar(&a.DiskType)
+
+	cmdOpts.NewDeploymentCmd.Flag("azure-location", locationHelp).Default(a.Location).StringVar(&a.Location)
+	cmdOpts.NewDeploymentCmd.Flag("azure-image", "The id of the Azure managed image to boot.").Default(a.ImageID).StringVar(&a.ImageID)
+	cmdOpts.NewDeploymentCmd.Flag("azure-zk-vm-size", "The VM size to use for zookeeper VMs.").Default(a.ZkVMSize).StringVar(&a.ZkVMSize)
+	cmdOpts.NewDeploymentCmd.Flag("azure-sd-vm-size", "The VM size to use for stardog VMs.").Default(a.SdVMSize).StringVar(&a.SdVMSize)
+
+	return nil
+}
+
+func (a *azurePlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	err := checkEnvs()
+	if err != nil {
+		return nil, err
+	}
+	neededPgms := []string{"terraform", "packer", "az"}
+	for _, e := range neededPgms {
+		_, err := exec.LookPath(e)
+		if err != nil {
+			return nil, fmt.Errorf("The program %s must be in the path when running this program", e)
+		}
+	}
+
+	if new {
+		azureDD, err := newAzureDeploymentDescription(context, baseD, a)
+		if err != nil {
+			return nil, err
+		}
+		baseD.CloudOpts = azureDD
+		data, err := json.Marshal(baseD)
+		if err != nil {
+			return nil, err
+		}
+		confPath := path.Join(azureDD.deployDir, "config.json")
+		err = ioutil.WriteFile(confPath, data, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return azureDD, nil
+	}
+	data, err := json.Marshal(baseD.CloudOpts)
+	if err != nil {
+		return nil, err
+	}
+	var dd azureDeploymentDescription
+	err = json.Unmarshal(data, &dd)
+	if err != nil {
+		return nil, err
+	}
+	dd.Name = baseD.Name
+	dd.Version = baseD.Version
+	dd.ctx = context
+	dd.deployDir = sdutils.DeploymentDir(context.GetConfigDir(), baseD.Name)
+	dd.customPropFile = baseD.CustomPropsFile
+	dd.environment = baseD.Environment
+	dd.disableSecurity = baseD.DisableSecurity
+	dd.plugin = a
+
+	return &dd, nil
+}
+
+func (a *azurePlugin) GetName() string {
+	return "azure"
+}
+
+func (a *azurePlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true, Firewall: true}
+}
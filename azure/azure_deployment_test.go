@@ -0,0 +1,222 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+var testEnvs = map[string]string{
+	"ARM_SUBSCRIPTION_ID": "gravitontest",
+	"ARM_CLIENT_ID":       "gravitontest",
+	"ARM_CLIENT_SECRET":   "somesecret",
+	"ARM_TENANT_ID":       "gravitontest",
+}
+
+// setTestEnvs sets fake service principal values and returns a function which
+// restores the previous ones.
+func setTestEnvs() func() {
+	saved := make(map[string]string)
+	for k, v := range testEnvs {
+		saved[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range saved {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+// createFakeAz puts an az program in the path which answers the queries made
+// by the plugin and records every call in az.log.
+func createFakeAz(t *testing.T, dir string) string {
+	logFile := path.Join(dir, "az.log")
+	script := fmt.Sprintf(`#!/usr/bin/env bash
+echo "$@" >> %s
+case "$*" in
+  *list-instances*) printf "0\t0\n1\t1\n2\t0\n" ;;
+  *"disk list"*) printf "/subscriptions/s/resourceGroups/testdep-data/providers/Microsoft.Compute/disks/testdep-data-0\n/subscriptions/s/resourceGroups/testdep-data/providers/Microsoft.Compute/disks/testdep-data-2\n" ;;
+  *"group list"*) printf "testdep-instance\ttestdep\n" ;;
+  *"resource list"*) printf "Microsoft.Compute/virtualMachines\ttestdep-zk-0\n" ;;
+esac
+exit 0
+`, logFile)
+	err := ioutil.WriteFile(path.Join(dir, "az"), []byte(script), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", dir, os.Getenv("PATH")))
+	return logFile
+}
+
+func newTestPlugin() *azurePlugin {
+	return &azurePlugin{
+		Location:           "eastus",
+		ImageID:            "/subscriptions/s/resourceGroups/images/providers/Microsoft.Compute/images/stardog-4.2-1",
+		ImageResourceGroup: "images",
+		ZkVMSize:           "Standard_B1ms",
+		SdVMSize:           "Standard_D2s_v3",
+		DiskType:           "Standard_LRS",
+	}
+}
+
+func newTestDeployment(t *testing.T, dir string) (*sdutils.TestContext, *azureDeploymentDescription) {
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:      "azure",
+		Name:      "testdep",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	dd, err := newAzureDeploymentDescription(&app, &baseD, newTestPlugin())
+	if err != nil {
+		t.Fatalf("Failed to make the deployment manager %s", err)
+	}
+	return &app, dd
+}
+
+func TestDeploymentLoadDefaults(t *testing.T) {
+	plugin := newTestPlugin()
+	i := make(map[string]string)
+	i["location"] = "westeurope"
+	i["image_id"] = "someimage"
+	i["zk_vm_size"] = "zkinst"
+	i["sd_vm_size"] = "sdinst"
+
+	err := plugin.LoadDefaults(&i)
+	if err != nil {
+		t.Fatalf("Failed to load defaults %s", err)
+	}
+	if plugin.Location != i["location"] {
+		t.Fatalf("location not set right")
+	}
+	if plugin.ImageID != i["image_id"] {
+		t.Fatalf("image_id not set right")
+	}
+	if plugin.ZkVMSize != i["zk_vm_size"] {
+		t.Fatalf("zk_vm_size not set right")
+	}
+	if plugin.SdVMSize != i["sd_vm_size"] {
+		t.Fatalf("sd_vm_size not set right")
+	}
+}
+
+func TestDeploymentLoadEnvs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+	os.Unsetenv("ARM_CLIENT_SECRET")
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	plugin := newTestPlugin()
+	baseD := sdutils.BaseDeployment{
+		Type:      plugin.GetName(),
+		Name:      "testdep",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	_, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The deployment should have failed without a client secret")
+	}
+}
+
+func TestDeploymentLoadGeneratesKey(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	for _, pgm := range []string{"terraform", "packer"} {
+		exedir, _, err := sdutils.CreateTestExec(pgm, "", 0)
+		if err != nil {
+			t.Fatalf("Failed to write the file %s", err)
+		}
+		defer os.RemoveAll(exedir)
+	}
+	createFakeAz(t, dir)
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	plugin := newTestPlugin()
+	baseD := sdutils.BaseDeployment{
+		Type:      plugin.GetName(),
+		Name:      "testdep",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("The deployment should have loaded %s", err)
+	}
+	dd := dep.(*azureDeploymentDescription)
+	if !dd.CreatedKey || !sdutils.PathExists(baseD.PrivateKey+".pub") {
+		t.Fatalf("A key pair should have been created")
+	}
+
+	loaded, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, false)
+	if err != nil {
+		t.Fatalf("The deployment should have reloaded %s", err)
+	}
+	ldd := loaded.(*azureDeploymentDescription)
+	if ldd.Location != dd.Location || ldd.ImageID != dd.ImageID || ldd.PrivateKeyPath != dd.PrivateKeyPath {
+		t.Fatalf("The deployment was not reloaded properly")
+	}
+}
+
+func TestDeploymentBadValues(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:      "azure",
+		Name:      "Bad_Name",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	_, err := newAzureDeploymentDescription(&app, &baseD, newTestPlugin())
+	if err == nil {
+		t.Fatalf("The deployment name should have been rejected")
+	}
+	baseD.Name = "testdep"
+	plugin := newTestPlugin()
+	plugin.DiskType = "gp2"
+	_, err = newAzureDeploymentDescription(&app, &baseD, plugin)
+	if err == nil {
+		t.Fatalf("The disk type should have been rejected")
+	}
+}
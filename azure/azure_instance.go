@@ -0,0 +1,331 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// ScaleSetInstance represents an instance of a Stardog service in Azure.  The
+// Stardog nodes run in a VM scale set behind an Azure load balancer.
+type ScaleSetInstance struct {
+	DeploymentName         string             `json:"deployment_name,omitempty"`
+	Location               string             `json:"location,omitempty"`
+	PublicKeyPath          string             `json:"public_key_path,omitempty"`
+	Version                string             `json:"version,omitempty"`
+	ZkVMSize               string             `json:"zk_vm_size,omitempty"`
+	SdVMSize               string             `json:"stardog_vm_size,omitempty"`
+	BastionVMSize          string             `json:"bastion_vm_size,omitempty"`
+	ZkSize                 string             `json:"zookeeper_size,omitempty"`
+	SdSize                 string             `json:"stardog_size,omitempty"`
+	ImageID                string             `json:"image,omitempty"`
+	HTTPMask               string             `json:"http_subnet,omitempty"`
+	IdleTimeout            string             `json:"idle_timeout,omitempty"`
+	CustomPropsData        string             `json:"custom_properties_data,omitempty"`
+	Environment            string             `json:"environment_variables,omitempty"`
+	StartOpts              string             `json:"stardog_start_opts,omitempty"`
+	RootVolumeType         string             `json:"root_volume_type"`
+	DeployDir              string             `json:"-"`
+	Ctx                    sdutils.AppContext `json:"-"`
+	BastionContact         string             `json:"-"`
+	StardogContact         string             `json:"-"`
+	StardogInternalContact string             `json:"-"`
+	ZkNodesContact         []string           `json:"-"`
+}
+
+// InstanceStatusDescription describes details about a running Stardog instance.
+// The zookeeper contact strings are described.
+type InstanceStatusDescription struct {
+	ZkNodesContact []string
+}
+
+// OutputEntry allows the plugin to return opaque information and mark it as sensitive
+// or not.  If it is sensitive the base code knows not to print it out or write it to a
+// log.
+type OutputEntry struct {
+	Sensitive bool        `json:"sensitive,omitempty"`
+	Type      string      `json:"type,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+}
+
+// NewScaleSetInstance instanciates a ScaleSetInstance object which will be used
+// to boot or inspect a Stardog deployment in Azure.
+func NewScaleSetInstance(ctx sdutils.AppContext, dd *azureDeploymentDescription) (*ScaleSetInstance, error) {
+	customData := ""
+	if dd.customPropFile != "" {
+		data, err := ioutil.ReadFile(dd.customPropFile)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid custom properties file: %s", err)
+		}
+		customData = string(data)
+	}
+
+	var envBuffer bytes.Buffer
+	for _, env := range dd.environment {
+		envBuffer.WriteString(fmt.Sprintf("export %s\n", env))
+	}
+	instance := ScaleSetInstance{
+		DeploymentName:  dd.Name,
+		Location:        dd.Location,
+		PublicKeyPath:   dd.PrivateKeyPath + ".pub",
+		Version:         dd.Version,
+		ZkVMSize:        dd.ZkVMSize,
+		SdVMSize:        dd.SdVMSize,
+		BastionVMSize:   dd.BastionVMSize,
+		ImageID:         dd.ImageID,
+		DeployDir:       dd.deployDir,
+		Ctx:             ctx,
+		CustomPropsData: customData,
+		Environment:     envBuffer.String(),
+	}
+	if dd.disableSecurity {
+		instance.StartOpts = "--disable-security"
+	}
+	return &instance, nil
+}
+
+func (azI *ScaleSetInstance) workingDir() string {
+	return path.Join(azI.DeployDir, "etc", "terraform", "instance")
+}
+
+func (azI *ScaleSetInstance) confPath() string {
+	return path.Join(azI.workingDir(), "instance.json")
+}
+
+// idleMinutes converts the idle timeout in seconds that graviton uses into
+// the whole minutes that the Azure load balancer accepts.
+func idleMinutes(idleTimeout int) int {
+	minutes := (idleTimeout + 59) / 60
+	if minutes < 4 {
+		return 4
+	}
+	if minutes > 30 {
+		return 30
+	}
+	return minutes
+}
+
+func (azI *ScaleSetInstance) runTerraformApply(ctx context.Context, zookeeperSize int, mask string, idleTimeout int, message string) error {
+	azI.ZkSize = fmt.Sprintf("%d", zookeeperSize)
+	azI.IdleTimeout = fmt.Sprintf("%d", idleMinutes(idleTimeout))
+
+	vol, err := LoadManagedDisks(azI.Ctx, path.Join(azI.DeployDir, "etc", "terraform", "volumes"))
+	if err != nil {
+		return err
+	}
+
+	azI.SdSize = vol.ClusterSize
+	azI.HTTPMask = mask
+	azI.RootVolumeType = "Standard_LRS"
+
+	if sdutils.PathExists(azI.confPath()) && mask == "" {
+		azI.Ctx.ConsoleLog(1, "The instance already exists.\n")
+		azI.Ctx.Logf(sdutils.INFO, "The instance already exists.")
+	}
+	err = sdutils.WriteJSON(azI, azI.confPath())
+	if err != nil {
+		return err
+	}
+
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+
+	cmdArray := []string{terraformPath, "apply", "-var-file",
+		azI.confPath()}
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  azI.workingDir(),
+	}
+	azI.Ctx.Logf(sdutils.INFO, "Running terraform...\n")
+	spin := sdutils.NewSpinner(azI.Ctx, 1, message)
+	_, err = sdutils.RunCommand(ctx, azI.Ctx, cmd, nil, spin)
+	if err != nil {
+		return err
+	}
+	return azI.attachDisks(ctx)
+}
+
+// attachDisks gives every scale set instance that has no data disk one of the
+// unattached managed disks of the deployment.  The Stardog boot script waits
+// for the disk to show up at lun 0 before mounting it.
+func (azI *ScaleSetInstance) attachDisks(ctx context.Context) error {
+	rg := instanceResourceGroup(azI.DeploymentName)
+	ss := scaleSetName(azI.DeploymentName)
+	instances, err := azLines(ctx, azI.Ctx, "vmss", "list-instances", "--resource-group", rg, "--name", ss,
+		"--query", "[].[instanceId, length(storageProfile.dataDisks)]")
+	if err != nil {
+		return err
+	}
+	freeDisks, err := azLines(ctx, azI.Ctx, "disk", "list", "--resource-group", dataResourceGroup(azI.DeploymentName),
+		"--query", "[?managedBy==`null`].[id]")
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		if len(inst) > 1 && inst[1] != "0" {
+			continue
+		}
+		if len(freeDisks) == 0 {
+			return fmt.Errorf("There are not enough volumes for the instance %s of %s", inst[0], ss)
+		}
+		disk := freeDisks[0][0]
+		freeDisks = freeDisks[1:]
+		azI.Ctx.ConsoleLog(2, "Attaching %s to instance %s\n", path.Base(disk), inst[0])
+		_, err = runAz(ctx, azI.Ctx, "vmss", "disk", "attach", "--resource-group", rg, "--vmss-name", ss,
+			"--instance-id", inst[0], "--lun", "0", "--disk", disk)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateInstance will boot up a Stardog service in Azure.
+func (azI *ScaleSetInstance) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	err := azI.runTerraformApply(ctx, zookeeperSize, "0.0.0.0/32", idleTimeout, "Creating the instance VMs...")
+	if err != nil {
+		azI.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+		return err
+	}
+	azI.Ctx.ConsoleLog(1, "Successfully created the instance.\n")
+	return nil
+}
+
+// OpenInstance will change the network security group rule to allow incoming
+// traffic to port 5821 from the give CIDR.
+func (azI *ScaleSetInstance) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	err := azI.runTerraformApply(ctx, zookeeperSize, mask, idleTimeout, "Opening the firewall...")
+	if err != nil {
+		azI.Ctx.ConsoleLog(1, "Failed to open up the instance.\n")
+		return err
+	}
+	azI.Ctx.ConsoleLog(1, "Successfully opened up the instance.\n")
+	return nil
+}
+
+// DeleteInstance will teardown the Stardog service.
+func (azI *ScaleSetInstance) DeleteInstance(ctx context.Context) error {
+	if !azI.InstanceExists() {
+		return fmt.Errorf("There is no configured instance")
+	}
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+	cmdArray := []string{terraformPath, "destroy", "-force", "-var-file", azI.confPath()}
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  azI.workingDir(),
+	}
+	azI.Ctx.Logf(sdutils.INFO, "Running terraform...\n")
+	spin := sdutils.NewSpinner(azI.Ctx, 1, "Deleting the instance VMs")
+	_, err = sdutils.RunCommand(ctx, azI.Ctx, cmd, nil, spin)
+	if err != nil {
+		return err
+	}
+	os.Remove(azI.confPath())
+	azI.Ctx.ConsoleLog(1, "Successfully destroyed the instance.\n")
+	return nil
+}
+
+// InstanceExists will return a bool if the associated ScaleSetInstance has
+// already been created.
+func (azI *ScaleSetInstance) InstanceExists() bool {
+	return sdutils.PathExists(azI.confPath())
+}
+
+func outputString(try map[string]OutputEntry, key string) (string, error) {
+	s, ok := try[key].Value.(string)
+	if !ok {
+		return "", fmt.Errorf("The terraform output is missing %s", key)
+	}
+	return s, nil
+}
+
+func getInstanceValues(ctx context.Context, azI *ScaleSetInstance) (*InstanceStatusDescription, error) {
+	if !azI.InstanceExists() {
+		return nil, fmt.Errorf("There is no configured instance")
+	}
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return nil, err
+	}
+	cmdArray := []string{terraformPath, "output", "-json"}
+	cmd := exec.CommandContext(ctx, cmdArray[0], cmdArray[1:]...)
+	cmd.Dir = azI.workingDir()
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	try := make(map[string]OutputEntry)
+	err = json.Unmarshal(data, &try)
+	if err != nil {
+		return nil, err
+	}
+
+	azI.StardogInternalContact, err = outputString(try, "stardog_internal_contact")
+	if err != nil {
+		return nil, err
+	}
+	azI.StardogContact, err = outputString(try, "stardog_contact")
+	if err != nil {
+		return nil, err
+	}
+	azI.BastionContact, err = outputString(try, "bastion_contact")
+	if err != nil {
+		return nil, err
+	}
+	interList, ok := try["zookeeper_nodes"].Value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("The terraform output is missing zookeeper_nodes")
+	}
+	azI.ZkNodesContact = make([]string, len(interList), len(interList))
+	for ndx, x := range interList {
+		azI.ZkNodesContact[ndx] = fmt.Sprintf("%v", x)
+	}
+
+	s := InstanceStatusDescription{
+		ZkNodesContact: azI.ZkNodesContact,
+	}
+	return &s, nil
+}
+
+// Status will print the status of the Azure instance.
+func (azI *ScaleSetInstance) Status(ctx context.Context) error {
+	_, err := getInstanceValues(ctx, azI)
+	if err != nil {
+		return err
+	}
+
+	azI.Ctx.ConsoleLog(1, "Stardog: %s\n", fmt.Sprintf("http://%s:5821", azI.StardogContact))
+	azI.Ctx.ConsoleLog(1, "SSH: %s\n", azI.BastionContact)
+	return nil
+}
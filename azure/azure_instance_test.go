@@ -0,0 +1,250 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestVolumes(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+
+	app, dd := newTestDeployment(t, dir)
+	disks := NewManagedDiskManager(app, dd)
+	if disks.VolumeExists() {
+		t.Fatalf("The volume shouldn't exist yet")
+	}
+	err := dd.StatusVolumeSet(context.Background())
+	if err == nil {
+		t.Fatalf("The volume shouldn't exist yet, status should fail")
+	}
+	err = dd.DeleteVolumeSet(context.Background())
+	if err == nil {
+		t.Fatalf("The delete should have failed")
+	}
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	os.Setenv("PATH", dir)
+	err = disks.CreateSet(context.Background(), "/path/", 1, 3)
+	if err == nil {
+		t.Fatalf("The create should have failed without terraform")
+	}
+	os.Setenv("PATH", startPath)
+
+	exedir, _, err := sdutils.CreateTestExec("terraform", "data", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+
+	err = dd.CreateVolumeSet(context.Background(), "/path/", 1, 3)
+	if err != nil {
+		t.Fatalf("The create should have worked %s", err)
+	}
+	if !dd.VolumeExists() {
+		t.Fatalf("The volume should exist")
+	}
+	if sdutils.PathExists(path.Join(disks.VolumeDir, "builder.tf")) {
+		t.Fatalf("The builder should have been removed")
+	}
+	size, err := dd.ClusterSize()
+	if err != nil || size != 3 {
+		t.Fatalf("The cluster size should be 3 %d %s", size, err)
+	}
+
+	err = dd.StatusVolumeSet(context.Background())
+	if err == nil {
+		t.Fatalf("The status should have bad output")
+	}
+	data := `{"volumes": { "sensitive": false, "type": "list", "value": ["testdep-data-0", "testdep-data-1", "testdep-data-2"]}}`
+	exedir2, _, err := sdutils.CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir2)
+	err = dd.StatusVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("The status should work %s", err)
+	}
+
+	err = dd.DeleteVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("The delete should not have failed %s", err)
+	}
+}
+
+func TestInstanceNotThere(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+
+	app, dd := newTestDeployment(t, dir)
+	inst, err := NewScaleSetInstance(app, dd)
+	if err != nil {
+		t.Fatalf("Failed to make the instance %s", err)
+	}
+	if inst.InstanceExists() {
+		t.Fatalf("The instance should not exist")
+	}
+	if inst.DeleteInstance(context.Background()) == nil {
+		t.Fatalf("The instance should not exist for deletion")
+	}
+	if inst.Status(context.Background()) == nil {
+		t.Fatalf("The instance should not exist for status")
+	}
+	if inst.CreateInstance(context.Background(), 16, 3, 60) == nil {
+		t.Fatalf("The instance should need volumes")
+	}
+}
+
+func TestInstanceAttachesDisks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+
+	app, dd := newTestDeployment(t, dir)
+	exedir, _, err := sdutils.CreateTestExec("terraform", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	logFile := createFakeAz(t, dir)
+
+	err = dd.CreateVolumeSet(context.Background(), "/path/", 1, 3)
+	if err != nil {
+		t.Fatalf("The volumes should have been created %s", err)
+	}
+	err = dd.CreateInstance(context.Background(), 16, 3, 600)
+	if err != nil {
+		t.Fatalf("The instance should have been created %s", err)
+	}
+
+	inst, _ := NewScaleSetInstance(app, dd)
+	sdutils.LoadJSON(inst, inst.confPath())
+	if inst.HTTPMask != "0.0.0.0/32" || inst.SdSize != "3" || inst.IdleTimeout != "10" {
+		t.Fatalf("The instance configuration is wrong %s %s %s", inst.HTTPMask, inst.SdSize, inst.IdleTimeout)
+	}
+
+	// Instance 1 already has a disk so 0 and 2 get the free ones
+	calls, _ := ioutil.ReadFile(logFile)
+	attaches := []string{}
+	for _, line := range strings.Split(string(calls), "\n") {
+		if strings.HasPrefix(line, "vmss disk attach") {
+			attaches = append(attaches, line)
+		}
+	}
+	if len(attaches) != 2 {
+		t.Fatalf("Two disks should have been attached %s", calls)
+	}
+	if !strings.Contains(attaches[0], "--instance-id 0") || !strings.Contains(attaches[0], "disks/testdep-data-0 ") {
+		t.Fatalf("The first disk went to the wrong instance %s", attaches[0])
+	}
+	if !strings.Contains(attaches[1], "--instance-id 2") || !strings.Contains(attaches[1], "testdep-data-2") {
+		t.Fatalf("The second disk went to the wrong instance %s", attaches[1])
+	}
+	if !strings.Contains(attaches[0], "--subscription gravitontest") {
+		t.Fatalf("The subscription should be passed to az %s", attaches[0])
+	}
+
+	err = dd.OpenInstance(context.Background(), 16, 3, "10.0.0.0/8", 60)
+	if err != nil {
+		t.Fatalf("The instance should have been opened %s", err)
+	}
+	sdutils.LoadJSON(inst, inst.confPath())
+	if inst.HTTPMask != "10.0.0.0/8" || inst.IdleTimeout != "4" {
+		t.Fatalf("The mask was not applied %s %s", inst.HTTPMask, inst.IdleTimeout)
+	}
+
+	data := `{
+    "bastion_contact": {"sensitive": false, "type": "string", "value": "40.1.2.3"},
+    "stardog_contact": {"sensitive": false, "type": "string", "value": "40.1.2.4"},
+    "stardog_internal_contact": {"sensitive": false, "type": "string", "value": "10.0.2.9"},
+    "zookeeper_nodes": {"sensitive": false, "type": "list", "value": ["10.0.1.10", "10.0.1.11", "10.0.1.12"]}
+}`
+	exedir2, _, err := sdutils.CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir2)
+
+	sd, err := dd.FullStatus(context.Background())
+	if err != nil {
+		t.Fatalf("The full status should have worked %s", err)
+	}
+	if sd.StardogURL != "http://40.1.2.4:5821" || sd.StardogInternalURL != "http://10.0.2.9:5821" || sd.SSHHost != "40.1.2.3" {
+		t.Fatalf("The status is wrong %v", sd)
+	}
+	if len(sd.InstanceDescription.(*InstanceStatusDescription).ZkNodesContact) != 3 {
+		t.Fatalf("There should be 3 zookeeper nodes")
+	}
+
+	err = dd.DeleteInstance(context.Background())
+	if err != nil {
+		t.Fatalf("The delete should have worked %s", err)
+	}
+	if dd.InstanceExists() {
+		t.Fatalf("The instance should be gone")
+	}
+}
+
+func TestFindLeaks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestEnvs()()
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	logFile := createFakeAz(t, dir)
+
+	plugin := newTestPlugin()
+	err := plugin.FindLeaks(context.Background(), &app, "testdep", false, false)
+	if err != nil {
+		t.Fatalf("Failed to look for leaks %s", err)
+	}
+	calls, _ := ioutil.ReadFile(logFile)
+	if !strings.Contains(string(calls), "--tag StardogVirtualAppliance=testdep") {
+		t.Fatalf("The deployment tag should be searched %s", calls)
+	}
+	if strings.Contains(string(calls), "group delete") {
+		t.Fatalf("Nothing should be deleted %s", calls)
+	}
+
+	err = plugin.FindLeaks(context.Background(), &app, "", true, true)
+	if err != nil {
+		t.Fatalf("Failed to destroy leaks %s", err)
+	}
+	calls, _ = ioutil.ReadFile(logFile)
+	if !strings.Contains(string(calls), "group delete --name testdep-instance --yes") {
+		t.Fatalf("The instance resource group should be deleted %s", calls)
+	}
+}
@@ -0,0 +1,226 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+var (
+	// ValidLocations is the list of Azure locations that are supported by this plugin
+	ValidLocations = []string{
+		"eastus", "eastus2", "westus", "westus2", "centralus",
+		"northeurope", "westeurope", "uksouth",
+	}
+	// ValidDiskTypes is the list of managed disk storage account types that
+	// are supported by this plugin
+	ValidDiskTypes = []string{"Standard_LRS", "Premium_LRS"}
+
+	// NeededEnvs are the service principal settings used by terraform and packer
+	NeededEnvs = []string{"ARM_SUBSCRIPTION_ID", "ARM_CLIENT_ID", "ARM_CLIENT_SECRET", "ARM_TENANT_ID"}
+
+	// The deployment name prefixes every resource and computer name.
+	deploymentNameRegex = regexp.MustCompile("^[a-z]([-a-z0-9]{0,30}[a-z0-9])?$")
+)
+
+// ValidateDeploymentName checks that the name can be used as a prefix for
+// Azure resource and computer names.
+func ValidateDeploymentName(name string) error {
+	if !deploymentNameRegex.MatchString(name) {
+		return fmt.Errorf("The deployment name %s is not valid for Azure.  It must start with a lower case letter and may only contain lower case letters, numbers and dashes", name)
+	}
+	return nil
+}
+
+func checkEnvs() error {
+	for _, e := range NeededEnvs {
+		if os.Getenv(e) == "" {
+			return fmt.Errorf("The environment variable %s must be set", e)
+		}
+	}
+	return nil
+}
+
+func dataResourceGroup(deploymentName string) string {
+	return fmt.Sprintf("%s-data", deploymentName)
+}
+
+func instanceResourceGroup(deploymentName string) string {
+	return fmt.Sprintf("%s-instance", deploymentName)
+}
+
+func scaleSetName(deploymentName string) string {
+	return fmt.Sprintf("%s-sd", deploymentName)
+}
+
+// runAz runs the azure cli against the subscription of the service principal.
+// The cli must already be logged in.
+func runAz(ctx context.Context, c sdutils.AppContext, args ...string) (string, error) {
+	azPath, err := exec.LookPath("az")
+	if err != nil {
+		return "", err
+	}
+	cmdArray := append([]string{azPath}, args...)
+	if sub := os.Getenv("ARM_SUBSCRIPTION_ID"); sub != "" {
+		cmdArray = append(cmdArray, "--subscription", sub)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmdArray[0], cmdArray[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	c.Logf(sdutils.DEBUG, "Running %s", strings.Join(cmdArray, " "))
+	err = cmd.Run()
+	if err != nil {
+		c.Logf(sdutils.WARN, "az failed: %s %s", err, stderr.String())
+		return "", fmt.Errorf("az failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// azLines runs the azure cli with tsv output and splits the result into the
+// fields of each line.
+func azLines(ctx context.Context, c sdutils.AppContext, args ...string) ([][]string, error) {
+	out, err := runAz(ctx, c, append(args, "--output", "tsv")...)
+	if err != nil {
+		return nil, err
+	}
+	lines := [][]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	return lines, nil
+}
+
+// FindLeaks looks for the instance resource groups of deployments.  All of the
+// VMs, scale sets, load balancers and networks of a deployment live in that
+// group so destroying it removes them.  The data resource groups are left
+// alone because they hold the Stardog data.
+func (a *azurePlugin) FindLeaks(ctx context.Context, c sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	tag := "StardogVirtualAppliance"
+	if deploymentName != "" {
+		tag = fmt.Sprintf("StardogVirtualAppliance=%s", deploymentName)
+	}
+
+	c.ConsoleLog(1, "Looking for Azure resources\n")
+	groups, err := azLines(ctx, c, "group", "list", "--tag", tag,
+		"--query", "[?tags.StardogComponent=='instance'].[name, tags.StardogVirtualAppliance]")
+	if err != nil {
+		return err
+	}
+
+	c.ConsoleLog(1, "Found %d resource groups\n", len(groups))
+	for _, g := range groups {
+		c.ConsoleLog(1, "\t%s\n", g[0])
+		resources, err := azLines(ctx, c, "resource", "list", "--resource-group", g[0], "--query", "[].[type, name]")
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to list the resources of %s: %s", g[0], err)
+			continue
+		}
+		for _, r := range resources {
+			c.ConsoleLog(1, "\t\t%s\n", strings.Join(r, " "))
+		}
+	}
+
+	if !destroy {
+		return nil
+	}
+	if !force {
+		if !sdutils.AskYesOrNo(c, "Would you like to destroy these resources?") {
+			return nil
+		}
+	}
+	for _, g := range groups {
+		c.ConsoleLog(2, "Destroying %s\n", g[0])
+		_, err := runAz(ctx, c, "group", "delete", "--name", g[0], "--yes")
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to delete the resource group %s, %s", g[0], err)
+			c.ConsoleLog(1, "Failed to delete the resource group %s, %s\n", g[0], err)
+		}
+	}
+	return nil
+}
+
+func imageFileName(cliContext sdutils.AppContext) string {
+	return path.Join(cliContext.GetConfigDir(), fmt.Sprintf("azure-images-%s.json", cliContext.GetVersion()))
+}
+
+func loadImageMap(cliContext sdutils.AppContext) (map[string]string, error) {
+	imageMap := make(map[string]string)
+	imageMapFile := imageFileName(cliContext)
+	cliContext.Logf(sdutils.DEBUG, "Loading the image file %s\n", imageMapFile)
+	if _, err := os.Stat(imageMapFile); err == nil {
+		data, err := ioutil.ReadFile(imageMapFile)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, &imageMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cliContext.Logf(sdutils.DEBUG, "Got the image map %s\n", imageMap)
+	return imageMap, nil
+}
+
+func saveImageMap(cliContext sdutils.AppContext, imageMap map[string]string) error {
+	data, err := json.Marshal(&imageMap)
+	if err != nil {
+		return err
+	}
+	imageMapFile := imageFileName(cliContext)
+	cliContext.Logf(sdutils.DEBUG, "Saving the image file %s\n", imageMapFile)
+	return ioutil.WriteFile(imageMapFile, data, 0600)
+}
+
+// PlaceAsset will write data that was compiled in with go-bindata to a file.
+func PlaceAsset(cliContext sdutils.AppContext, dir string, assentName string, temp bool) (string, error) {
+	var err error
+	if temp {
+		dir, err = ioutil.TempDir(dir, "stardog")
+		if err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			err = os.MkdirAll(dir, 0755)
+			if err != nil {
+				cliContext.ConsoleLog(0, "ERROR %s\n", err.Error())
+				return "", err
+			}
+		}
+	}
+
+	err = RestoreAssets(dir, assentName)
+	if err != nil {
+		cliContext.Logf(sdutils.ERROR, "The asset %s was not found", assentName)
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,209 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// VolumeStatusDescription is an opaque way to pass Azure specific information
+// to the calling code.
+type VolumeStatusDescription struct {
+	DiskNames []string
+}
+
+// ManagedDisks describes the managed disks used by Azure to store
+// STARDOG_HOME.
+type ManagedDisks struct {
+	DeploymentName   string `json:"deployment_name,omitempty"`
+	Location         string `json:"location,omitempty"`
+	SizeOfEachVolume string `json:"storage_size,omitempty"`
+	ClusterSize      string `json:"cluster_size,omitempty"`
+	KeyPath          string `json:"key_path,omitempty"`
+	PublicKeyPath    string `json:"public_key_path,omitempty"`
+	ImageID          string `json:"image,omitempty"`
+	VMSize           string `json:"vm_size,omitempty"`
+	LicensePath      string `json:"stardog_license,omitempty"`
+	DiskType         string `json:"disk_type,omitempty"`
+	VolumeDir        string `json:"-"`
+	appContext       sdutils.AppContext
+}
+
+// NewManagedDiskManager returns a ManagedDisks structure that will be used by
+// graviton to manage the volumes.
+func NewManagedDiskManager(ac sdutils.AppContext, dd *azureDeploymentDescription) *ManagedDisks {
+	volumeDir := path.Join(dd.deployDir, "etc", "terraform", "volumes")
+
+	return &ManagedDisks{
+		DeploymentName: dd.Name,
+		Location:       dd.Location,
+		KeyPath:        dd.PrivateKeyPath,
+		PublicKeyPath:  dd.PrivateKeyPath + ".pub",
+		ImageID:        dd.ImageID,
+		VMSize:         dd.SdVMSize,
+		DiskType:       dd.DiskType,
+		VolumeDir:      volumeDir,
+		appContext:     ac,
+	}
+}
+
+// LoadManagedDisks will inflate a ManagedDisks structure for the information
+// stored in the files under the configuration directory.
+func LoadManagedDisks(ac sdutils.AppContext, volDir string) (*ManagedDisks, error) {
+	var disks ManagedDisks
+	confFile := path.Join(volDir, "config.json")
+	err := sdutils.LoadJSON(&disks, confFile)
+	if err != nil {
+		return nil, err
+	}
+	return &disks, nil
+}
+
+// VolumeExists returns true or false based on whether or not the disks
+// already exist.
+func (v *ManagedDisks) VolumeExists() bool {
+	confFile := path.Join(v.VolumeDir, "config.json")
+	return sdutils.PathExists(confFile)
+}
+
+// CreateSet uses terraform to create and format the managed disks.
+func (v *ManagedDisks) CreateSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	v.appContext.ConsoleLog(2, "Creating an azure disk set in directory %s\n", v.VolumeDir)
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+	v.ClusterSize = fmt.Sprintf("%d", clusterSize)
+	v.SizeOfEachVolume = fmt.Sprintf("%d", sizeOfEachVolume)
+	v.LicensePath = licensePath
+	confFile := path.Join(v.VolumeDir, "config.json")
+	if _, err := os.Stat(confFile); err == nil {
+		v.appContext.ConsoleLog(1, "Volumes have already been created for the %s deployment, running terraform apply again.", v.DeploymentName)
+		v.appContext.Logf(sdutils.WARN, "Volumes have already been created for the %s deployment, running terraform apply again.", v.DeploymentName)
+	}
+	err = sdutils.WriteJSON(v, confFile)
+	if err != nil {
+		return err
+	}
+
+	cmdArray := []string{terraformPath, "apply",
+		"-var-file", confFile}
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  v.VolumeDir,
+	}
+	spin := sdutils.NewSpinner(v.appContext, 1, "Calling out to terraform to create the volumes")
+	_, err = sdutils.RunCommand(ctx, v.appContext, cmd, nil, spin)
+	if err != nil {
+		return err
+	}
+	// The builder VMs only exist to format the disks
+	err = os.Remove(path.Join(v.VolumeDir, "builder.tf"))
+	if err != nil {
+		return err
+	}
+	spin = sdutils.NewSpinner(v.appContext, 1, "Calling out to terraform to stop builder instances")
+	_, err = sdutils.RunCommand(ctx, v.appContext, cmd, nil, spin)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully created the volumes.\n")
+	return nil
+}
+
+// DeleteSet will delete the managed disks and their resource group.
+func (v *ManagedDisks) DeleteSet(ctx context.Context) error {
+	confFile := path.Join(v.VolumeDir, "config.json")
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+	cmdArray := []string{terraformPath, "destroy", "-force",
+		"-var-file", confFile}
+
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  v.VolumeDir,
+	}
+	spin := sdutils.NewSpinner(v.appContext, 1, "Calling out to terraform to delete the volumes")
+	_, err = sdutils.RunCommand(ctx, v.appContext, cmd, nil, spin)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(confFile)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully destroyed the volumes.\n")
+	return nil
+}
+
+func (v *ManagedDisks) getStatusInformation(ctx context.Context) (*VolumeStatusDescription, error) {
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return nil, err
+	}
+
+	cmdArray := []string{terraformPath, "output", "-json"}
+	cmd := exec.CommandContext(ctx, cmdArray[0], cmdArray[1:]...)
+	cmd.Dir = v.VolumeDir
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	try := make(map[string]OutputEntry)
+	err = json.Unmarshal(data, &try)
+	if err != nil {
+		return nil, err
+	}
+	volsEnt, ok := try["volumes"]
+	if !ok {
+		return nil, fmt.Errorf("Invalid volume results in terraform output")
+	}
+	interList, ok := volsEnt.Value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid volume results in terraform output")
+	}
+
+	volStatus := VolumeStatusDescription{}
+	volStatus.DiskNames = make([]string, len(interList), len(interList))
+	for ndx, x := range interList {
+		volStatus.DiskNames[ndx] = fmt.Sprintf("%v", x)
+	}
+	return &volStatus, nil
+}
+
+// Status will print out status information about the managed disks.
+func (v *ManagedDisks) Status(ctx context.Context) error {
+	vD, err := v.getStatusInformation(ctx)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Volumes:\n")
+	for _, x := range vD.DiskNames {
+		v.appContext.ConsoleLog(1, "%s\n", x)
+	}
+	return nil
+}
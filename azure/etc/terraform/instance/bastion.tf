@@ -0,0 +1,84 @@
+
+resource "azurerm_public_ip" "bastion" {
+  name = "${var.deployment_name}-bastion"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  public_ip_address_allocation = "static"
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
+
+resource "azurerm_network_security_group" "bastion" {
+  name = "${var.deployment_name}-bastion"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+
+  # allow ssh from anywhere
+  security_rule {
+    name = "ssh"
+    priority = 100
+    direction = "Inbound"
+    access = "Allow"
+    protocol = "Tcp"
+    source_port_range = "*"
+    destination_port_range = "22"
+    source_address_prefix = "*"
+    destination_address_prefix = "*"
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
+
+resource "azurerm_network_interface" "bastion" {
+  name = "${var.deployment_name}-bastion"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  network_security_group_id = "${azurerm_network_security_group.bastion.id}"
+
+  ip_configuration {
+    name = "bastion"
+    subnet_id = "${azurerm_subnet.zk.id}"
+    private_ip_address_allocation = "dynamic"
+    public_ip_address_id = "${azurerm_public_ip.bastion.id}"
+  }
+}
+
+resource "azurerm_virtual_machine" "bastion" {
+  name = "${var.deployment_name}-bastion"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  network_interface_ids = ["${azurerm_network_interface.bastion.id}"]
+  vm_size = "${var.bastion_vm_size}"
+  delete_os_disk_on_termination = true
+
+  storage_image_reference {
+    id = "${var.image}"
+  }
+
+  storage_os_disk {
+    name = "${var.deployment_name}-bastion-os"
+    caching = "ReadWrite"
+    create_option = "FromImage"
+    managed_disk_type = "Standard_LRS"
+  }
+
+  os_profile {
+    computer_name = "${var.deployment_name}-bastion"
+    admin_username = "ubuntu"
+  }
+
+  os_profile_linux_config {
+    disable_password_authentication = true
+    ssh_keys {
+      path = "/home/ubuntu/.ssh/authorized_keys"
+      key_data = "${file(var.public_key_path)}"
+    }
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
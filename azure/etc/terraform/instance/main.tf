@@ -0,0 +1,18 @@
+provider "azurerm" {
+}
+
+resource "azurerm_resource_group" "main" {
+  name = "${var.deployment_name}-instance"
+  location = "${var.location}"
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+    StardogComponent = "instance"
+  }
+}
+
+resource "azurerm_virtual_network" "main" {
+  name = "${var.deployment_name}-net"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  address_space = ["${var.internal_network}"]
+}
@@ -0,0 +1,23 @@
+output "stardog_contact" {
+  value = "${azurerm_public_ip.stardog.ip_address}"
+}
+
+output "stardog_internal_contact" {
+  value = "${azurerm_lb.stardoginternal.private_ip_address}"
+}
+
+output "bastion_contact" {
+  value = "${azurerm_public_ip.bastion.ip_address}"
+}
+
+output "zookeeper_nodes" {
+  value = ["${azurerm_network_interface.zookeeper.*.private_ip_address}"]
+}
+
+output "resource_group" {
+  value = "${azurerm_resource_group.main.name}"
+}
+
+output "scale_set" {
+  value = "${azurerm_virtual_machine_scale_set.stardog.name}"
+}
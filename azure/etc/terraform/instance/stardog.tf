@@ -0,0 +1,224 @@
+resource "azurerm_subnet" "stardog" {
+  name = "${var.deployment_name}-sd"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  virtual_network_name = "${azurerm_virtual_network.main.name}"
+  address_prefix = "${var.stardog_subnet}"
+  network_security_group_id = "${azurerm_network_security_group.stardog.id}"
+}
+
+data "template_file" "stardog_zk_server" {
+  count = "${var.zookeeper_size}"
+  template = "$${host}:2181"
+  vars {
+    host = "${cidrhost(var.zk_subnet, count.index + 10)}"
+  }
+}
+
+data "template_file" "stardog_properties" {
+  template = "${var.custom_stardog_properties}\n${file("stardog.properties.tpl")}"
+  vars {
+    zk_servers = "${join(",", data.template_file.stardog_zk_server.*.rendered)}"
+    custom_data = "${var.custom_properties_data}"
+  }
+}
+
+data "template_file" "stardog_userdata" {
+  template = "${file("stardog_userdata.tpl")}"
+  vars {
+    stardog_conf = "${data.template_file.stardog_properties.rendered}"
+    zk_servers = "${join(",", data.template_file.stardog_zk_server.*.rendered)}"
+    environment_variables = "${var.environment_variables}"
+    server_opts = "${var.stardog_start_opts}"
+  }
+}
+
+# OpenInstance changes the source of the first rule to let clients in.
+resource "azurerm_network_security_group" "stardog" {
+  name = "${var.deployment_name}-stardog"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+
+  security_rule {
+    name = "stardog"
+    priority = 100
+    direction = "Inbound"
+    access = "Allow"
+    protocol = "Tcp"
+    source_port_range = "*"
+    destination_port_range = "5821"
+    source_address_prefix = "${var.http_subnet}"
+    destination_address_prefix = "*"
+  }
+
+  security_rule {
+    name = "internal"
+    priority = 110
+    direction = "Inbound"
+    access = "Allow"
+    protocol = "*"
+    source_port_range = "*"
+    destination_port_range = "*"
+    source_address_prefix = "${var.internal_network}"
+    destination_address_prefix = "*"
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
+
+resource "azurerm_public_ip" "stardog" {
+  name = "${var.deployment_name}-sd-lb"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  public_ip_address_allocation = "static"
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
+
+resource "azurerm_lb" "stardog" {
+  name = "${var.deployment_name}-sd-lb"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+
+  frontend_ip_configuration {
+    name = "public"
+    public_ip_address_id = "${azurerm_public_ip.stardog.id}"
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
+
+resource "azurerm_lb_backend_address_pool" "stardog" {
+  name = "stardog"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  loadbalancer_id = "${azurerm_lb.stardog.id}"
+}
+
+resource "azurerm_lb_probe" "stardog" {
+  name = "stardog"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  loadbalancer_id = "${azurerm_lb.stardog.id}"
+  protocol = "Http"
+  port = 5821
+  request_path = "/admin/healthcheck"
+}
+
+resource "azurerm_lb_rule" "stardog" {
+  name = "stardog"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  loadbalancer_id = "${azurerm_lb.stardog.id}"
+  protocol = "Tcp"
+  frontend_port = 5821
+  backend_port = 5821
+  frontend_ip_configuration_name = "public"
+  backend_address_pool_id = "${azurerm_lb_backend_address_pool.stardog.id}"
+  probe_id = "${azurerm_lb_probe.stardog.id}"
+  idle_timeout_in_minutes = "${var.idle_timeout}"
+}
+
+resource "azurerm_lb" "stardoginternal" {
+  name = "${var.deployment_name}-sd-internal-lb"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+
+  frontend_ip_configuration {
+    name = "internal"
+    subnet_id = "${azurerm_subnet.stardog.id}"
+    private_ip_address_allocation = "dynamic"
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
+
+resource "azurerm_lb_backend_address_pool" "stardoginternal" {
+  name = "stardog"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  loadbalancer_id = "${azurerm_lb.stardoginternal.id}"
+}
+
+resource "azurerm_lb_probe" "stardoginternal" {
+  name = "stardog"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  loadbalancer_id = "${azurerm_lb.stardoginternal.id}"
+  protocol = "Tcp"
+  port = 5821
+}
+
+resource "azurerm_lb_rule" "stardoginternal" {
+  name = "stardog"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  loadbalancer_id = "${azurerm_lb.stardoginternal.id}"
+  protocol = "Tcp"
+  frontend_port = 5821
+  backend_port = 5821
+  frontend_ip_configuration_name = "internal"
+  backend_address_pool_id = "${azurerm_lb_backend_address_pool.stardoginternal.id}"
+  probe_id = "${azurerm_lb_probe.stardoginternal.id}"
+  idle_timeout_in_minutes = "${var.idle_timeout}"
+}
+
+# The data disks are attached to the scale set instances by graviton after
+# the scale set is created.  Overprovisioning is turned off so that there is
+# exactly one instance for every disk.
+resource "azurerm_virtual_machine_scale_set" "stardog" {
+  name = "${var.deployment_name}-sd"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  upgrade_policy_mode = "Manual"
+  overprovision = false
+
+  sku {
+    name = "${var.stardog_vm_size}"
+    tier = "Standard"
+    capacity = "${var.stardog_size}"
+  }
+
+  storage_profile_image_reference {
+    id = "${var.image}"
+  }
+
+  storage_profile_os_disk {
+    name = ""
+    caching = "ReadWrite"
+    create_option = "FromImage"
+    managed_disk_type = "${var.root_volume_type}"
+  }
+
+  os_profile {
+    computer_name_prefix = "${var.deployment_name}-sd"
+    admin_username = "ubuntu"
+    custom_data = "${data.template_file.stardog_userdata.rendered}"
+  }
+
+  os_profile_linux_config {
+    disable_password_authentication = true
+    ssh_keys {
+      path = "/home/ubuntu/.ssh/authorized_keys"
+      key_data = "${file(var.public_key_path)}"
+    }
+  }
+
+  network_profile {
+    name = "stardog"
+    primary = true
+
+    ip_configuration {
+      name = "stardog"
+      subnet_id = "${azurerm_subnet.stardog.id}"
+      load_balancer_backend_address_pool_ids = [
+        "${azurerm_lb_backend_address_pool.stardog.id}",
+        "${azurerm_lb_backend_address_pool.stardoginternal.id}"
+      ]
+    }
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
@@ -0,0 +1,34 @@
+#!/usr/bin/env bash
+
+set -e
+
+date > /tmp/boottime
+
+export STARDOG_HOME=/mnt/data/stardog-home
+${environment_variables}
+mkdir -p /mnt/data
+# graviton attaches the data disk at lun 0 once the instance exists
+/usr/local/bin/stardog-wait-for-pgm 100 test -e /dev/disk/azure/scsi1/lun0
+mountpoint -q /mnt/data || mount /dev/disk/azure/scsi1/lun0 /mnt/data
+
+echo '${stardog_conf}' > $STARDOG_HOME/stardog.properties
+MY_IP=`curl -s -H Metadata:true "http://169.254.169.254/metadata/instance/network/interface/0/ipv4/ipAddress/0/privateIpAddress?api-version=2017-08-01&format=text"`
+sed -i "s/@@LOCAL_IP@@/$MY_IP/" $STARDOG_HOME/stardog.properties
+
+/usr/local/bin/stardog-wait-for-socket 100 ${zk_servers}
+
+set +e
+cnt=0
+rc=1
+while [ $rc -ne 0 ]; do
+	cnt=`expr $cnt + 1`
+	if [ $cnt -gt 10 ]; then
+		exit 1
+	fi
+	sleep 30
+	rm -f /mnt/data/stardog-home/system.lock
+    /usr/local/bin/stardog-admin server start ${server_opts} --home $STARDOG_HOME --port 5821
+    /usr/local/bin/stardog-wait-for-socket 2 localhost:5821
+    rc=$?
+done
+exit 0
@@ -0,0 +1,109 @@
+variable "zk_vm_size" {
+  type = "string"
+  description = "The VM size for zookeeper nodes."
+}
+
+variable "stardog_vm_size" {
+  type = "string"
+  description = "The VM size for stardog."
+}
+
+variable "bastion_vm_size" {
+  type = "string"
+  default = "Standard_B1s"
+  description = "The VM size for the bastion."
+}
+
+variable "zookeeper_size" {
+  type = "string"
+  description = "The number of zookeeper nodes to use (must be odd and greater than 1)"
+}
+
+variable "stardog_size" {
+  type = "string"
+  description = "The number of stardog nodes to use (must be odd and greater than 1)"
+}
+
+variable "image" {
+  type = "string"
+  description = "The id of the managed image to boot."
+}
+
+variable "location" {
+  type = "string"
+  description = "The Azure location to create things in."
+}
+
+variable "public_key_path" {
+  type = "string"
+  description = "The path to the public key"
+}
+
+variable "deployment_name" {
+  type = "string"
+  description = "A string that is unique to this stardog data in a given subscription"
+}
+
+variable "version" {
+  type = "string"
+  description = "The version of stardog to launch."
+}
+
+variable "internal_network" {
+  type = "string"
+  description = "The internal network for the virtual appliance."
+  default = "10.0.0.0/16"
+}
+
+variable "zk_subnet" {
+  type = "string"
+  description = "The subnet of the zookeeper nodes and the bastion."
+  default = "10.0.1.0/24"
+}
+
+variable "stardog_subnet" {
+  type = "string"
+  description = "The subnet of the stardog scale set."
+  default = "10.0.2.0/24"
+}
+
+variable "http_subnet" {
+  type = "string"
+  description = "The subnet allowed to reach the stardog load balancer."
+}
+
+variable "idle_timeout" {
+  type = "string"
+  description = "The idle timeout of the load balancer in minutes."
+  default = "4"
+}
+
+variable "custom_stardog_properties" {
+  type = "string"
+  description = "Custom entries for stardog.properties"
+  default = ""
+}
+
+variable "custom_properties_data" {
+  type = "string"
+  description = "The custom data to add to the stardog properties"
+  default = ""
+}
+
+variable "environment_variables" {
+  type = "string"
+  description = "The environment variables to inject into the stardog script"
+  default = ""
+}
+
+variable "stardog_start_opts" {
+  type = "string"
+  description = "Options passed to stardog-admin server start"
+  default = ""
+}
+
+variable "root_volume_type" {
+  type = "string"
+  description = "The storage account type of the OS disks"
+  default = "Standard_LRS"
+}
@@ -0,0 +1,86 @@
+resource "azurerm_subnet" "zk" {
+  name = "${var.deployment_name}-zk"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  virtual_network_name = "${azurerm_virtual_network.main.name}"
+  address_prefix = "${var.zk_subnet}"
+}
+
+data "template_file" "zk_server" {
+  count = "${var.zookeeper_size}"
+  template = "${file("server-spec.tpl")}"
+  vars {
+    id = "${count.index + 1}"
+    host = "${cidrhost(var.zk_subnet, count.index + 10)}"
+  }
+}
+
+data "template_file" "zk_conf" {
+  template = "${file("zoo.cfg.tpl")}"
+  vars {
+    zk_server_list = "${join("", data.template_file.zk_server.*.rendered)}"
+  }
+}
+
+data "template_file" "zk_userdata" {
+  count = "${var.zookeeper_size}"
+  template = "${file("zk_userdata.tpl")}"
+  vars {
+    index = "${count.index + 1}"
+    zk_conf = "${data.template_file.zk_conf.rendered}"
+  }
+}
+
+# The zookeeper nodes need stable addresses so they are plain VMs with a
+# static private IP rather than a scale set.
+resource "azurerm_network_interface" "zookeeper" {
+  count = "${var.zookeeper_size}"
+  name = "${var.deployment_name}-zk-${count.index}"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+
+  ip_configuration {
+    name = "zookeeper"
+    subnet_id = "${azurerm_subnet.zk.id}"
+    private_ip_address_allocation = "static"
+    private_ip_address = "${cidrhost(var.zk_subnet, count.index + 10)}"
+  }
+}
+
+resource "azurerm_virtual_machine" "zookeeper" {
+  count = "${var.zookeeper_size}"
+  name = "${var.deployment_name}-zk-${count.index}"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.main.name}"
+  network_interface_ids = ["${element(azurerm_network_interface.zookeeper.*.id, count.index)}"]
+  vm_size = "${var.zk_vm_size}"
+  delete_os_disk_on_termination = true
+
+  storage_image_reference {
+    id = "${var.image}"
+  }
+
+  storage_os_disk {
+    name = "${var.deployment_name}-zk-os-${count.index}"
+    caching = "ReadWrite"
+    create_option = "FromImage"
+    managed_disk_type = "Standard_LRS"
+  }
+
+  os_profile {
+    computer_name = "${var.deployment_name}-zk-${count.index}"
+    admin_username = "ubuntu"
+    custom_data = "${element(data.template_file.zk_userdata.*.rendered, count.index)}"
+  }
+
+  os_profile_linux_config {
+    disable_password_authentication = true
+    ssh_keys {
+      path = "/home/ubuntu/.ssh/authorized_keys"
+      key_data = "${file(var.public_key_path)}"
+    }
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
@@ -0,0 +1,119 @@
+
+resource "azurerm_virtual_network" "builder" {
+  name = "${var.deployment_name}-builder"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.data.name}"
+  address_space = ["10.100.0.0/16"]
+}
+
+resource "azurerm_subnet" "builder" {
+  name = "${var.deployment_name}-builder"
+  resource_group_name = "${azurerm_resource_group.data.name}"
+  virtual_network_name = "${azurerm_virtual_network.builder.name}"
+  address_prefix = "10.100.1.0/24"
+}
+
+resource "azurerm_public_ip" "builder" {
+  count = "${var.cluster_size}"
+  name = "${var.deployment_name}-builder-${count.index}"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.data.name}"
+  public_ip_address_allocation = "static"
+}
+
+resource "azurerm_network_interface" "builder" {
+  count = "${var.cluster_size}"
+  name = "${var.deployment_name}-builder-${count.index}"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.data.name}"
+
+  ip_configuration {
+    name = "builder"
+    subnet_id = "${azurerm_subnet.builder.id}"
+    private_ip_address_allocation = "dynamic"
+    public_ip_address_id = "${element(azurerm_public_ip.builder.*.id, count.index)}"
+  }
+}
+
+resource "azurerm_virtual_machine" "builder" {
+  count = "${var.cluster_size}"
+  name = "${var.deployment_name}-builder-${count.index}"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.data.name}"
+  network_interface_ids = ["${element(azurerm_network_interface.builder.*.id, count.index)}"]
+  vm_size = "${var.vm_size}"
+  delete_os_disk_on_termination = true
+
+  storage_image_reference {
+    id = "${var.image}"
+  }
+
+  storage_os_disk {
+    name = "${var.deployment_name}-builder-os-${count.index}"
+    caching = "ReadWrite"
+    create_option = "FromImage"
+    managed_disk_type = "Standard_LRS"
+  }
+
+  storage_data_disk {
+    name = "${element(azurerm_managed_disk.stardog_data.*.name, count.index)}"
+    managed_disk_id = "${element(azurerm_managed_disk.stardog_data.*.id, count.index)}"
+    create_option = "Attach"
+    lun = 0
+    disk_size_gb = "${var.storage_size}"
+  }
+
+  os_profile {
+    computer_name = "${var.deployment_name}-builder-${count.index}"
+    admin_username = "ubuntu"
+  }
+
+  os_profile_linux_config {
+    disable_password_authentication = true
+    ssh_keys {
+      path = "/home/ubuntu/.ssh/authorized_keys"
+      key_data = "${file(var.public_key_path)}"
+    }
+  }
+
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
+
+resource "null_resource" "stardog_data" {
+  count = "${var.cluster_size}"
+
+  # Settings for SSH connection
+  connection {
+    user = "ubuntu"
+    host = "${element(azurerm_public_ip.builder.*.ip_address, count.index)}"
+    private_key = "${file(var.key_path)}"
+    agent = false
+    timeout = "10m"
+  }
+
+  depends_on = ["azurerm_virtual_machine.builder"]
+
+  provisioner "remote-exec" {
+    inline = [
+      "set -e",
+      "sudo mkfs -t ext4 /dev/disk/azure/scsi1/lun0",
+      "sudo mkdir -p /mnt/data",
+      "sudo mount /dev/disk/azure/scsi1/lun0 /mnt/data",
+      "sudo mkdir -p /mnt/data/stardog-home",
+      "sudo chown -R ubuntu /mnt/data/"
+    ]
+  }
+
+  provisioner "file" {
+    source = "${var.stardog_license}"
+    destination = "/mnt/data/stardog-home/stardog-license-key.bin"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "sudo umount /mnt/data/"
+    ]
+  }
+}
@@ -0,0 +1,24 @@
+provider "azurerm" {
+}
+
+resource "azurerm_resource_group" "data" {
+  name = "${var.deployment_name}-data"
+  location = "${var.location}"
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+    StardogComponent = "volumes"
+  }
+}
+
+resource "azurerm_managed_disk" "stardog_data" {
+  count = "${var.cluster_size}"
+  name = "${var.deployment_name}-data-${count.index}"
+  location = "${var.location}"
+  resource_group_name = "${azurerm_resource_group.data.name}"
+  storage_account_type = "${var.disk_type}"
+  create_option = "Empty"
+  disk_size_gb = "${var.storage_size}"
+  tags {
+    StardogVirtualAppliance = "${var.deployment_name}"
+  }
+}
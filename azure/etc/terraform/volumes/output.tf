@@ -0,0 +1,7 @@
+output "volumes" {
+  value = ["${azurerm_managed_disk.stardog_data.*.name}"]
+}
+
+output "resource_group" {
+  value = "${azurerm_resource_group.data.name}"
+}
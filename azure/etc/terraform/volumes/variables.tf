@@ -0,0 +1,50 @@
+variable "storage_size" {
+  type = "string"
+  description = "The size of the volume in gigabytes."
+}
+
+variable "cluster_size" {
+  type = "string"
+  description = "The number of stardog nodes to use (must be odd and greater than 1)."
+}
+
+variable "location" {
+  type = "string"
+  description = "The Azure location to create things in."
+}
+
+variable "deployment_name" {
+  type = "string"
+  description = "A string that is unique to this stardog data in a given subscription"
+}
+
+variable "key_path" {
+  type = "string"
+  description = "The path to the private key"
+}
+
+variable "public_key_path" {
+  type = "string"
+  description = "The path to the public key"
+}
+
+variable "image" {
+  type = "string"
+  description = "The id of the managed image used to format the disks"
+}
+
+variable "vm_size" {
+  type = "string"
+  description = "The size of the VMs used to format the disks"
+}
+
+variable "stardog_license" {
+  type = "string"
+  description = "The path to the stardog license"
+}
+
+variable "disk_type" {
+  type = "string"
+  description = "The storage account type of the managed disks"
+  default = "Standard_LRS"
+}
@@ -0,0 +1,86 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// releasePath is where the release that is pushed to the hosts is kept.  It
+// plays the role that an AMI plays for the aws plugin.
+func releasePath(confDir string, version string) string {
+	return path.Join(confDir, "baremetal", "releases", fmt.Sprintf("stardog-%s.zip", version))
+}
+
+func (p *baremetalPlugin) HaveImage(ctx context.Context, c sdutils.AppContext) bool {
+	return sdutils.PathExists(releasePath(c.GetConfigDir(), c.GetVersion()))
+}
+
+// isRelease checks that the zip file has the single top level directory of
+// a Stardog release.
+func isRelease(sdReleaseFilePath string) error {
+	r, err := zip.OpenReader(sdReleaseFilePath)
+	if err != nil {
+		return fmt.Errorf("Failed to open the Stardog release %s: %s", sdReleaseFilePath, err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		parts := strings.Split(f.Name, "/")
+		if len(parts) == 3 && parts[1] == "bin" && parts[2] == "stardog-admin" {
+			return nil
+		}
+	}
+	return fmt.Errorf("The file %s does not appear to be a Stardog release", sdReleaseFilePath)
+}
+
+// BuildImage keeps a copy of the release so that it can be installed on the
+// hosts when an instance is created.
+func (p *baremetalPlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	err := isRelease(sdReleaseFilePath)
+	if err != nil {
+		return err
+	}
+	dest := releasePath(context.GetConfigDir(), version)
+	err = os.MkdirAll(path.Dir(dest), 0755)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(sdReleaseFilePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	if err != nil {
+		os.Remove(dest)
+		return err
+	}
+	context.ConsoleLog(0, "Stardog %s is ready to be installed from %s\n", version, dest)
+	return nil
+}
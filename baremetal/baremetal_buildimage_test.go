@@ -0,0 +1,98 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func makeFakeRelease(dir string, version string, top string) (string, error) {
+	releaseFile := path.Join(dir, "stardog-"+version+".zip")
+	f, err := os.Create(releaseFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(top + "/bin/stardog-admin")
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write([]byte("#!/bin/sh\n"))
+	if err != nil {
+		return "", err
+	}
+	return releaseFile, zw.Close()
+}
+
+func TestBuildImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := GetPlugin()
+	if plugin.HaveImage(context.Background(), &app) {
+		t.Fatalf("There should not be a release yet")
+	}
+	releaseFile, err := makeFakeRelease(dir, "5.0", "stardog-5.0")
+	if err != nil {
+		t.Fatalf("Failed to make the release %s", err)
+	}
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err != nil {
+		t.Fatalf("Failed to keep the release %s", err)
+	}
+	if !plugin.HaveImage(context.Background(), &app) {
+		t.Fatalf("The release should exist")
+	}
+}
+
+func TestBuildImageNotRelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := GetPlugin()
+	releaseFile, err := makeFakeRelease(dir, "5.0", "stardog-5.0/lib")
+	if err != nil {
+		t.Fatalf("Failed to make the release %s", err)
+	}
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err == nil {
+		t.Fatalf("A zip without stardog-admin should be rejected")
+	}
+	ioutil.WriteFile(releaseFile, []byte("not a zip"), 0644)
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err == nil {
+		t.Fatalf("A file that is not a zip should be rejected")
+	}
+	if plugin.HaveImage(context.Background(), &app) {
+		t.Fatalf("There should not be a release")
+	}
+}
@@ -0,0 +1,282 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type baremetalDeploymentDescription struct {
+	Inventory       Inventory `json:"inventory,omitempty"`
+	PrivateKey      string    `json:"private_key,omitempty"`
+	Version         string    `json:"-"`
+	Name            string    `json:"-"`
+	deployDir       string
+	customPropFile  string
+	environment     []string
+	disableSecurity bool
+	ctx             sdutils.AppContext
+	plugin          *baremetalPlugin
+}
+
+func newBaremetalDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeployment, p *baremetalPlugin) (*baremetalDeploymentDescription, error) {
+	if !sdutils.PathExists(releasePath(c.GetConfigDir(), baseD.Version)) {
+		return nil, fmt.Errorf("There is no Stardog %s release to install.  Please see the 'baseami' subcommand", baseD.Version)
+	}
+	inv, err := LoadInventory(p.InventoryPath)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := baseD.PrivateKey
+	if privateKey == "" {
+		privateKey = inv.PrivateKey
+	}
+
+	dd := baremetalDeploymentDescription{
+		Inventory:       *inv,
+		PrivateKey:      privateKey,
+		Version:         baseD.Version,
+		Name:            baseD.Name,
+		deployDir:       sdutils.DeploymentDir(c.GetConfigDir(), baseD.Name),
+		customPropFile:  baseD.CustomPropsFile,
+		environment:     baseD.Environment,
+		disableSecurity: baseD.DisableSecurity,
+		ctx:             c,
+		plugin:          p,
+	}
+	return &dd, nil
+}
+
+func (dd *baremetalDeploymentDescription) shell() *remoteShell {
+	return newRemoteShell(dd.ctx, &dd.Inventory, dd.PrivateKey)
+}
+
+func (dd *baremetalDeploymentDescription) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (dd *baremetalDeploymentDescription) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	vm := NewBaremetalVolumeManager(dd.ctx, dd)
+	return vm.CreateSet(ctx, licensePath, sizeOfEachVolume, clusterSize)
+}
+
+func (dd *baremetalDeploymentDescription) DeleteVolumeSet(ctx context.Context) error {
+	vm := NewBaremetalVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	if dd.InstanceExists() {
+		return fmt.Errorf("The volumes of %s are in use by a running instance", dd.Name)
+	}
+	return vm.DeleteSet(ctx)
+}
+
+func (dd *baremetalDeploymentDescription) ClusterSize() (int, error) {
+	vm := NewBaremetalVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return -1, fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadHostVolumes(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return -1, err
+	}
+	return vols.ClusterSize, nil
+}
+
+func (dd *baremetalDeploymentDescription) StatusVolumeSet(ctx context.Context) error {
+	vm := NewBaremetalVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.Status(ctx)
+}
+
+func (dd *baremetalDeploymentDescription) VolumeExists() bool {
+	vm := NewBaremetalVolumeManager(dd.ctx, dd)
+	return vm.VolumeExists()
+}
+
+func (dd *baremetalDeploymentDescription) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	im, err := NewHostInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.CreateInstance(ctx, zookeeperSize)
+}
+
+func (dd *baremetalDeploymentDescription) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	// Access to existing hosts is governed by their own firewalls
+	dd.ctx.Logf(sdutils.DEBUG, "Ignoring the mask %s for the baremetal deployment %s", mask, dd.Name)
+	return nil
+}
+
+func (dd *baremetalDeploymentDescription) DeleteInstance(ctx context.Context) error {
+	im, err := NewHostInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.DeleteInstance(ctx)
+}
+
+func (dd *baremetalDeploymentDescription) StatusInstance(ctx context.Context) error {
+	im, err := NewHostInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.Status(ctx)
+}
+
+func (dd *baremetalDeploymentDescription) InstanceExists() bool {
+	im, err := NewHostInstance(dd.ctx, dd)
+	if err != nil {
+		return false
+	}
+	return im.InstanceExists()
+}
+
+// FullStatus is filled in from the inventory.  The bastion is the ssh host
+// and the first Stardog host serves the internal URL.
+func (dd *baremetalDeploymentDescription) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	vm := NewBaremetalVolumeManager(dd.ctx, dd)
+	volumeStatus, err := vm.getStatusInformation(ctx)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No volume information found %s\n", err)
+	}
+
+	im, err := NewHostInstance(dd.ctx, dd)
+	if err != nil {
+		return nil, err
+	}
+	instS, err := im.getStatusInformation(ctx)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No instance information found.\n")
+	}
+
+	internalURL := fmt.Sprintf("http://%s:5821", dd.Inventory.StardogHosts[0])
+	sdURL := internalURL
+	if dd.Inventory.LoadBalancer != "" {
+		sdURL = fmt.Sprintf("http://%s:5821", dd.Inventory.LoadBalancer)
+	}
+	sD := sdutils.StardogDescription{
+		StardogURL:          sdURL,
+		StardogInternalURL:  internalURL,
+		SSHHost:             dd.Inventory.Bastion,
+		SSHUser:             dd.Inventory.SSHUser,
+		VolumeDescription:   volumeStatus,
+		InstanceDescription: instS,
+		TimeStamp:           time.Now(),
+	}
+	if instS != nil {
+		for _, h := range instS.StardogHosts {
+			sD.StardogNodes = append(sD.StardogNodes, fmt.Sprintf("http://%s:5821", h))
+		}
+	}
+	return &sD, nil
+}
+
+func (dd *baremetalDeploymentDescription) GatherLogs(ctx context.Context, outfile string) error {
+	im, err := NewHostInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.GatherLogs(ctx, outfile)
+}
+
+type baremetalPlugin struct {
+	InventoryPath string `json:"inventory,omitempty"`
+}
+
+// GetPlugin returns the plugin interface that this module represents.
+func GetPlugin() sdutils.Plugin {
+	return &baremetalPlugin{
+		InventoryPath: "",
+	}
+}
+
+func (p *baremetalPlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	b, err := json.Marshal(defaultCliOpts)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *baremetalPlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	cmdOpts.LaunchCmd.Flag("inventory", "A JSON file listing the existing hosts of a baremetal deployment.").Default(p.InventoryPath).StringVar(&p.InventoryPath)
+	cmdOpts.NewDeploymentCmd.Flag("inventory", "A JSON file listing the existing hosts of a baremetal deployment.").Default(p.InventoryPath).StringVar(&p.InventoryPath)
+	cmdOpts.LeaksCmd.Flag("inventory", "A JSON file listing the hosts to search for servers.").Default(p.InventoryPath).StringVar(&p.InventoryPath)
+	return nil
+}
+
+func (p *baremetalPlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	if new {
+		bmDD, err := newBaremetalDeploymentDescription(context, baseD, p)
+		if err != nil {
+			return nil, err
+		}
+		baseD.CloudOpts = bmDD
+		data, err := json.Marshal(baseD)
+		if err != nil {
+			return nil, err
+		}
+		confPath := path.Join(bmDD.deployDir, "config.json")
+		err = ioutil.WriteFile(confPath, data, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return bmDD, nil
+	}
+	data, err := json.Marshal(baseD.CloudOpts)
+	if err != nil {
+		return nil, err
+	}
+	var dd baremetalDeploymentDescription
+	err = json.Unmarshal(data, &dd)
+	if err != nil {
+		return nil, err
+	}
+	dd.Name = baseD.Name
+	dd.Version = baseD.Version
+	dd.ctx = context
+	dd.deployDir = sdutils.DeploymentDir(context.GetConfigDir(), baseD.Name)
+	dd.customPropFile = baseD.CustomPropsFile
+	dd.environment = baseD.Environment
+	dd.disableSecurity = baseD.DisableSecurity
+	dd.plugin = p
+
+	return &dd, nil
+}
+
+func (p *baremetalPlugin) GetName() string {
+	return "baremetal"
+}
+
+// The hosts are reached through their own firewalls which graviton does not
+// manage.
+func (p *baremetalPlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true}
+}
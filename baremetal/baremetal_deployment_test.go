@@ -0,0 +1,177 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestDeploymentLoadDefaults(t *testing.T) {
+	plugin := GetPlugin().(*baremetalPlugin)
+	i := make(map[string]string)
+	i["inventory"] = "/etc/graviton/hosts.json"
+
+	err := plugin.LoadDefaults(&i)
+	if err != nil {
+		t.Fatalf("Failed to load defaults %s", err)
+	}
+	if plugin.InventoryPath != "/etc/graviton/hosts.json" {
+		t.Fatalf("inventory not set right")
+	}
+}
+
+func newTestDeployment(t *testing.T, dir string) sdutils.Deployment {
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := GetPlugin().(*baremetalPlugin)
+	plugin.InventoryPath = writeInventory(t, dir)
+
+	propsFile := path.Join(dir, "stardog.properties")
+	ioutil.WriteFile(propsFile, []byte("query.all.graphs=true\n"), 0644)
+	baseD := sdutils.BaseDeployment{
+		Type:            plugin.GetName(),
+		Name:            "testdep",
+		Directory:       sdutils.DeploymentDir(dir, "testdep"),
+		Version:         "5.0",
+		PrivateKey:      "/keys/id_rsa",
+		CustomPropsFile: propsFile,
+		Environment:     []string{"STARDOG_JAVA_ARGS=\"-Xmx2g\""},
+		DisableSecurity: true,
+	}
+	os.MkdirAll(baseD.Directory, 0755)
+	_, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("A deployment needs the release")
+	}
+	releaseFile, err := makeFakeRelease(dir, "5.0", "stardog-5.0")
+	if err != nil {
+		t.Fatalf("Failed to make the release %s", err)
+	}
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err != nil {
+		t.Fatalf("Failed to keep the release %s", err)
+	}
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment %s", err)
+	}
+	return dep
+}
+
+func TestDeploymentLifecycle(t *testing.T) {
+	fs := newFakeSSH(t)
+	defer fs.close()
+	dep := newTestDeployment(t, fs.dir)
+	deployDir := sdutils.DeploymentDir(fs.dir, "testdep")
+
+	licenseFile := path.Join(fs.dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+	err := dep.CreateVolumeSet(context.Background(), licenseFile, 20, 3)
+	if err == nil {
+		t.Fatalf("The inventory only has two Stardog hosts")
+	}
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 20, 2)
+	if err != nil {
+		t.Fatalf("Failed to create the volumes %s", err)
+	}
+	calls := fs.calls()
+	if !strings.Contains(calls, "centos@sd2 set -e; sudo mkdir -p /mnt/data/stardog-home") {
+		t.Fatalf("The data directory was not made %s", calls)
+	}
+	if !strings.Contains(calls, licenseFile+" centos@sd1:/mnt/data/stardog-home/stardog-license-key.bin") {
+		t.Fatalf("The license was not copied %s", calls)
+	}
+	size, err := dep.ClusterSize()
+	if err != nil || size != 2 {
+		t.Fatalf("The cluster size should be 2 %d %s", size, err)
+	}
+
+	err = dep.CreateInstance(context.Background(), 20, 4, 600)
+	if err == nil {
+		t.Fatalf("The inventory only has three ZooKeeper hosts")
+	}
+	fs.reset()
+	err = dep.CreateInstance(context.Background(), 20, 3, 600)
+	if err != nil {
+		t.Fatalf("Failed to create the instance %s", err)
+	}
+	calls = fs.calls()
+	for _, h := range []string{"sd1", "sd2", "bastion.example.com"} {
+		if !strings.Contains(calls, "/stardog-5.0.zip centos@"+h+":/tmp/stardog-5.0.zip") {
+			t.Fatalf("The release was not pushed to %s %s", h, calls)
+		}
+	}
+	if strings.Count(calls, "zkServer.sh start") != 3 {
+		t.Fatalf("ZooKeeper was not started on each host %s", calls)
+	}
+	if !strings.Contains(calls, "export STARDOG_JAVA_ARGS=\"-Xmx2g\"") || !strings.Contains(calls, "server start --disable-security --home $STARDOG_HOME") {
+		t.Fatalf("Stardog was not started right %s", calls)
+	}
+
+	zkConf, _ := ioutil.ReadFile(path.Join(deployDir, "instance", "zoo.cfg"))
+	if !strings.Contains(string(zkConf), "server.1=zk1:2888:3888\nserver.2=zk2:2888:3888\nserver.3=zk3:2888:3888\n") {
+		t.Fatalf("zoo.cfg is wrong %s", string(zkConf))
+	}
+	props, _ := ioutil.ReadFile(path.Join(deployDir, "instance", "stardog-1.properties"))
+	for _, s := range []string{"pack.node.address=sd2", "pack.zookeeper.address=zk1:2181,zk2:2181,zk3:2181", "query.all.graphs=true"} {
+		if !strings.Contains(string(props), s) {
+			t.Fatalf("stardog.properties is missing %s: %s", s, string(props))
+		}
+	}
+
+	sd, err := dep.FullStatus(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get the status %s", err)
+	}
+	if sd.SSHHost != "bastion.example.com" || sd.SSHUser != "centos" {
+		t.Fatalf("The ssh host is wrong %v", sd)
+	}
+	if sd.StardogURL != "http://sd1:5821" || sd.StardogInternalURL != "http://sd1:5821" || len(sd.StardogNodes) != 2 {
+		t.Fatalf("The URLs are wrong %v", sd)
+	}
+
+	err = dep.DeleteVolumeSet(context.Background())
+	if err == nil {
+		t.Fatalf("The volumes are in use")
+	}
+	fs.reset()
+	err = dep.DeleteInstance(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to delete the instance %s", err)
+	}
+	if strings.Count(fs.calls(), "zkServer.sh stop") != 3 {
+		t.Fatalf("ZooKeeper was not stopped %s", fs.calls())
+	}
+	if dep.InstanceExists() {
+		t.Fatalf("The instance should be gone")
+	}
+	err = dep.DeleteVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to delete the volumes %s", err)
+	}
+	if !strings.Contains(fs.calls(), "centos@sd2 sudo rm -rf /mnt/data/stardog-home") {
+		t.Fatalf("The data directory was not removed %s", fs.calls())
+	}
+	if dep.VolumeExists() {
+		t.Fatalf("The volumes should be gone")
+	}
+}
@@ -0,0 +1,377 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+const installScript = `set -e
+rm -rf /tmp/graviton-unpack
+mkdir /tmp/graviton-unpack
+unzip -q %[1]s -d /tmp/graviton-unpack
+sudo rm -rf %[2]s
+sudo mv /tmp/graviton-unpack/* %[2]s
+rm -rf /tmp/graviton-unpack %[1]s
+`
+
+const zookeeperScript = `set -e
+sudo mkdir -p /var/zkdata
+echo %[1]d | sudo tee /var/zkdata/myid > /dev/null
+sudo cp /tmp/zoo.cfg %[2]s/conf/zoo.cfg
+cd %[2]s
+sudo %[2]s/bin/zkServer.sh start
+`
+
+const stardogScript = `set -e
+export STARDOG_HOME=%[1]s
+%[2]s
+rm -f $STARDOG_HOME/system.lock
+nohup %[3]s/bin/stardog-admin server start %[4]s --home $STARDOG_HOME --port 5821 > $STARDOG_HOME/graviton-start.out 2>&1 < /dev/null
+`
+
+// HostInstance represents the ZooKeeper and Stardog servers that graviton
+// started on the hosts of the inventory.
+type HostInstance struct {
+	DeploymentName  string             `json:"deployment_name,omitempty"`
+	Version         string             `json:"version,omitempty"`
+	ZkHosts         []string           `json:"zookeeper_hosts,omitempty"`
+	StardogHosts    []string           `json:"stardog_hosts,omitempty"`
+	DataDir         string             `json:"data_dir,omitempty"`
+	ZkHome          string             `json:"zookeeper_home,omitempty"`
+	CustomPropsData string             `json:"custom_properties_data,omitempty"`
+	Environment     string             `json:"environment_variables,omitempty"`
+	StartOpts       string             `json:"stardog_start_opts,omitempty"`
+	InstanceDir     string             `json:"-"`
+	Ctx             sdutils.AppContext `json:"-"`
+	dd              *baremetalDeploymentDescription
+	shell           *remoteShell
+}
+
+// InstanceStatusDescription describes details about a running Stardog instance.
+type InstanceStatusDescription struct {
+	ZkNodesContact []string
+	StardogHosts   []string
+}
+
+// NewHostInstance returns a HostInstance which will be used to start or
+// inspect the servers of a deployment.
+func NewHostInstance(ctx sdutils.AppContext, dd *baremetalDeploymentDescription) (*HostInstance, error) {
+	customData := ""
+	if dd.customPropFile != "" {
+		data, err := ioutil.ReadFile(dd.customPropFile)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid custom properties file: %s", err)
+		}
+		customData = string(data)
+	}
+
+	var envBuffer bytes.Buffer
+	for _, env := range dd.environment {
+		envBuffer.WriteString(fmt.Sprintf("export %s\n", env))
+	}
+	instance := HostInstance{
+		DeploymentName:  dd.Name,
+		Version:         dd.Version,
+		DataDir:         dd.Inventory.DataDir,
+		ZkHome:          dd.Inventory.ZkHome,
+		CustomPropsData: customData,
+		Environment:     envBuffer.String(),
+		InstanceDir:     path.Join(dd.deployDir, "instance"),
+		Ctx:             ctx,
+		dd:              dd,
+		shell:           dd.shell(),
+	}
+	if dd.disableSecurity {
+		instance.StartOpts = "--disable-security"
+	}
+	return &instance, nil
+}
+
+func (hi *HostInstance) confPath() string {
+	return path.Join(hi.InstanceDir, "instance.json")
+}
+
+func (hi *HostInstance) load() error {
+	err := sdutils.LoadJSON(hi, hi.confPath())
+	if err != nil {
+		return fmt.Errorf("There is no configured instance")
+	}
+	return nil
+}
+
+// InstanceExists will return true if the servers were started.
+func (hi *HostInstance) InstanceExists() bool {
+	return sdutils.PathExists(hi.confPath())
+}
+
+func (hi *HostInstance) zkContacts() []string {
+	contacts := make([]string, len(hi.ZkHosts))
+	for i, h := range hi.ZkHosts {
+		contacts[i] = fmt.Sprintf("%s:2181", h)
+	}
+	return contacts
+}
+
+// renderConfigs writes zoo.cfg and the stardog.properties of each node into
+// the instance directory.
+func (hi *HostInstance) renderConfigs() error {
+	var serverList bytes.Buffer
+	for i, h := range hi.ZkHosts {
+		spec, err := renderTemplate("server-spec.tpl", map[string]string{
+			"id":   fmt.Sprintf("%d", i+1),
+			"host": h,
+		})
+		if err != nil {
+			return err
+		}
+		serverList.WriteString(spec)
+	}
+	zkConf, err := renderTemplate("zoo.cfg.tpl", map[string]string{"zk_server_list": serverList.String()})
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path.Join(hi.InstanceDir, "zoo.cfg"), []byte(zkConf), 0644)
+	if err != nil {
+		return err
+	}
+
+	props, err := renderTemplate("stardog.properties.tpl", map[string]string{
+		"zk_servers":  strings.Join(hi.zkContacts(), ","),
+		"custom_data": hi.CustomPropsData,
+	})
+	if err != nil {
+		return err
+	}
+	for i, h := range hi.StardogHosts {
+		nodeProps := strings.Replace(props, "@@LOCAL_IP@@", h, -1)
+		err = ioutil.WriteFile(hi.propertiesPath(i), []byte(nodeProps), 0600)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (hi *HostInstance) propertiesPath(index int) string {
+	return path.Join(hi.InstanceDir, fmt.Sprintf("stardog-%d.properties", index))
+}
+
+// installHosts are the hosts that need the release.  The bastion needs it
+// to run the stardog client commands.
+func (hi *HostInstance) installHosts() []string {
+	hosts := append([]string{}, hi.StardogHosts...)
+	for _, h := range hosts {
+		if h == hi.shell.inv.Bastion {
+			return hosts
+		}
+	}
+	return append(hosts, hi.shell.inv.Bastion)
+}
+
+// CreateInstance configures and starts ZooKeeper on the first zookeeperSize
+// ZooKeeper hosts, then pushes the release to the Stardog hosts and starts
+// Stardog on each host that has a volume.
+func (hi *HostInstance) CreateInstance(ctx context.Context, zookeeperSize int) error {
+	if hi.InstanceExists() {
+		return fmt.Errorf("The instance already exists")
+	}
+	vol, err := LoadHostVolumes(hi.Ctx, path.Join(hi.dd.deployDir, "volumes"))
+	if err != nil {
+		return fmt.Errorf("The volumes must be created before the instance: %s", err)
+	}
+	if zookeeperSize < 1 || zookeeperSize > len(hi.shell.inv.ZkHosts) {
+		return fmt.Errorf("The inventory lists %d ZooKeeper hosts, %d were requested", len(hi.shell.inv.ZkHosts), zookeeperSize)
+	}
+	hi.ZkHosts = hi.shell.inv.ZkHosts[:zookeeperSize]
+	hi.StardogHosts = vol.Hosts
+
+	err = os.MkdirAll(hi.InstanceDir, 0755)
+	if err != nil {
+		return err
+	}
+	err = hi.renderConfigs()
+	if err != nil {
+		return err
+	}
+	err = sdutils.WriteJSON(hi, hi.confPath())
+	if err != nil {
+		return err
+	}
+
+	err = hi.start(ctx)
+	if err != nil {
+		hi.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+		return err
+	}
+	hi.Ctx.ConsoleLog(1, "Successfully created the instance.\n")
+	return nil
+}
+
+func (hi *HostInstance) start(ctx context.Context) error {
+	for i, h := range hi.ZkHosts {
+		hi.Ctx.ConsoleLog(1, "Starting ZooKeeper on %s\n", h)
+		err := hi.shell.copyTo(ctx, h, path.Join(hi.InstanceDir, "zoo.cfg"), "/tmp/zoo.cfg")
+		if err != nil {
+			return err
+		}
+		_, err = hi.shell.run(ctx, h, fmt.Sprintf(zookeeperScript, i+1, hi.ZkHome))
+		if err != nil {
+			return err
+		}
+	}
+
+	release := releasePath(hi.Ctx.GetConfigDir(), hi.Version)
+	remoteRelease := fmt.Sprintf("/tmp/stardog-%s.zip", hi.Version)
+	for _, h := range hi.installHosts() {
+		hi.Ctx.ConsoleLog(1, "Installing Stardog %s on %s\n", hi.Version, h)
+		err := hi.shell.copyTo(ctx, h, release, remoteRelease)
+		if err != nil {
+			return err
+		}
+		_, err = hi.shell.run(ctx, h, fmt.Sprintf(installScript, remoteRelease, installDir))
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, h := range hi.StardogHosts {
+		hi.Ctx.ConsoleLog(1, "Starting Stardog on %s\n", h)
+		err := hi.shell.copyTo(ctx, h, hi.propertiesPath(i), path.Join(hi.DataDir, "stardog.properties"))
+		if err != nil {
+			return err
+		}
+		_, err = hi.shell.run(ctx, h, fmt.Sprintf(stardogScript, hi.DataDir, hi.Environment, installDir, hi.StartOpts))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteInstance stops every server.  The data directories are left in place.
+func (hi *HostInstance) DeleteInstance(ctx context.Context) error {
+	err := hi.load()
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, h := range hi.StardogHosts {
+		_, err = hi.shell.run(ctx, h, fmt.Sprintf("pkill -f -- '--home %s' || true", hi.DataDir))
+		if err != nil {
+			hi.Ctx.ConsoleLog(1, "Failed to stop Stardog on %s: %s\n", h, err)
+			lastErr = err
+		}
+	}
+	for _, h := range hi.ZkHosts {
+		_, err = hi.shell.run(ctx, h, fmt.Sprintf("sudo %s/bin/zkServer.sh stop", hi.ZkHome))
+		if err != nil {
+			hi.Ctx.ConsoleLog(1, "Failed to stop ZooKeeper on %s: %s\n", h, err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		hi.Ctx.ConsoleLog(1, "Failed to destroy the instance.\n")
+		return lastErr
+	}
+	os.RemoveAll(hi.InstanceDir)
+	hi.Ctx.ConsoleLog(1, "Successfully destroyed the instance.\n")
+	return nil
+}
+
+func (hi *HostInstance) getStatusInformation(ctx context.Context) (*InstanceStatusDescription, error) {
+	err := hi.load()
+	if err != nil {
+		return nil, err
+	}
+	return &InstanceStatusDescription{
+		ZkNodesContact: hi.zkContacts(),
+		StardogHosts:   hi.StardogHosts,
+	}, nil
+}
+
+func (hi *HostInstance) hostState(ctx context.Context, host string, pattern string) string {
+	out, err := hi.shell.run(ctx, host, pattern+" || true")
+	if err != nil {
+		return "unreachable"
+	}
+	if strings.TrimSpace(out) == "" {
+		return "stopped"
+	}
+	return "running"
+}
+
+// Status prints whether each server is running.
+func (hi *HostInstance) Status(ctx context.Context) error {
+	err := hi.load()
+	if err != nil {
+		return err
+	}
+	for _, h := range hi.ZkHosts {
+		hi.Ctx.ConsoleLog(1, "ZooKeeper on %s: %s\n", h, hi.hostState(ctx, h, zookeeperPattern))
+	}
+	for _, h := range hi.StardogHosts {
+		hi.Ctx.ConsoleLog(1, "Stardog on %s: %s\n", h, hi.hostState(ctx, h, stardogPattern(hi.DataDir)))
+	}
+	return nil
+}
+
+// GatherLogs copies the Stardog and ZooKeeper logs of every host into a
+// gzipped tarball.
+func (hi *HostInstance) GatherLogs(ctx context.Context, outfile string) error {
+	err := hi.load()
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "stardoglogs")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	type remoteLog struct {
+		host string
+		file string
+		name string
+	}
+	logs := []remoteLog{}
+	for _, h := range hi.StardogHosts {
+		logs = append(logs, remoteLog{h, path.Join(hi.DataDir, "stardog.log"), fmt.Sprintf("stardog-%s.log", h)})
+	}
+	for _, h := range hi.ZkHosts {
+		logs = append(logs, remoteLog{h, path.Join(hi.ZkHome, "zookeeper.out"), fmt.Sprintf("zookeeper-%s.out", h)})
+	}
+	for _, l := range logs {
+		out, err := hi.shell.run(ctx, l.host, "cat "+l.file)
+		if err != nil {
+			hi.Ctx.Logf(sdutils.WARN, "Could not get %s from %s: %s", l.file, l.host, err)
+			continue
+		}
+		err = ioutil.WriteFile(path.Join(dir, l.name), []byte(out), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return sdutils.TarDirectory(dir, outfile)
+}
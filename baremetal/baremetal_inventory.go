@@ -0,0 +1,75 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"fmt"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// Inventory lists the existing hosts that a baremetal deployment runs on.
+// It is read from a JSON file given with --inventory.
+type Inventory struct {
+	Bastion      string   `json:"bastion,omitempty"`
+	ZkHosts      []string `json:"zookeeper_hosts,omitempty"`
+	StardogHosts []string `json:"stardog_hosts,omitempty"`
+	LoadBalancer string   `json:"load_balancer,omitempty"`
+	SSHUser      string   `json:"ssh_user,omitempty"`
+	PrivateKey   string   `json:"private_key,omitempty"`
+	DataDir      string   `json:"data_dir,omitempty"`
+	ZkHome       string   `json:"zookeeper_home,omitempty"`
+}
+
+// LoadInventory reads an inventory file and fills in the defaults.  The
+// defaults match the layout of the images built for aws.
+func LoadInventory(inventoryPath string) (*Inventory, error) {
+	if inventoryPath == "" {
+		return nil, fmt.Errorf("The host inventory must be set with --inventory")
+	}
+	var inv Inventory
+	err := sdutils.LoadJSON(&inv, inventoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the inventory %s: %s", inventoryPath, err)
+	}
+	if inv.SSHUser == "" {
+		inv.SSHUser = "ubuntu"
+	}
+	if inv.DataDir == "" {
+		inv.DataDir = "/mnt/data/stardog-home"
+	}
+	if inv.ZkHome == "" {
+		inv.ZkHome = "/usr/local/zookeeper-3.4.9"
+	}
+	err = inv.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (inv *Inventory) validate() error {
+	if inv.Bastion == "" {
+		return fmt.Errorf("The inventory must name a bastion host")
+	}
+	if len(inv.ZkHosts) < 1 {
+		return fmt.Errorf("The inventory must list at least one ZooKeeper host")
+	}
+	if len(inv.StardogHosts) < 1 {
+		return fmt.Errorf("The inventory must list at least one Stardog host")
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/aws"
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// The release is installed where the stardog client commands expect it
+const installDir = "/usr/local/stardog"
+
+// remoteShell runs commands on the hosts of an inventory.  Every host other
+// than the bastion is reached by way of the bastion.
+type remoteShell struct {
+	inv        *Inventory
+	privateKey string
+	ctx        sdutils.AppContext
+}
+
+func newRemoteShell(ctx sdutils.AppContext, inv *Inventory, privateKey string) *remoteShell {
+	if privateKey == "" {
+		privateKey = inv.PrivateKey
+	}
+	return &remoteShell{inv: inv, privateKey: privateKey, ctx: ctx}
+}
+
+func (rs *remoteShell) sshOpts(host string) []string {
+	opts := []string{
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "BatchMode=yes",
+	}
+	if rs.privateKey != "" {
+		opts = append(opts, "-i", rs.privateKey)
+	}
+	if host != rs.inv.Bastion {
+		proxy := fmt.Sprintf("ssh %s -W %%h:%%p %s@%s", strings.Join(opts, " "), rs.inv.SSHUser, rs.inv.Bastion)
+		opts = append(opts, "-o", "ProxyCommand="+proxy)
+	}
+	return opts
+}
+
+func (rs *remoteShell) runTool(ctx context.Context, cmdArray []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmdArray[0], cmdArray[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		rs.ctx.Logf(sdutils.WARN, "%s failed: %s %s", cmdArray[0], err, stderr.String())
+		return "", fmt.Errorf("%s failed: %s", path.Base(cmdArray[0]), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// run executes the shell script on the host and returns what it printed.
+func (rs *remoteShell) run(ctx context.Context, host string, script string) (string, error) {
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return "", err
+	}
+	cmdArray := append([]string{sshPath}, rs.sshOpts(host)...)
+	cmdArray = append(cmdArray, fmt.Sprintf("%s@%s", rs.inv.SSHUser, host), script)
+	rs.ctx.Logf(sdutils.DEBUG, "Running on %s: %s", host, script)
+	out, err := rs.runTool(ctx, cmdArray)
+	if err != nil {
+		return "", fmt.Errorf("The command failed on %s: %s", host, err)
+	}
+	return out, nil
+}
+
+// copyTo uploads a local file to the host.
+func (rs *remoteShell) copyTo(ctx context.Context, host string, local string, remote string) error {
+	scpPath, err := exec.LookPath("scp")
+	if err != nil {
+		return err
+	}
+	cmdArray := append([]string{scpPath, "-q"}, rs.sshOpts(host)...)
+	cmdArray = append(cmdArray, local, fmt.Sprintf("%s@%s:%s", rs.inv.SSHUser, host, remote))
+	rs.ctx.Logf(sdutils.DEBUG, "Copying %s to %s:%s", local, host, remote)
+	_, err = rs.runTool(ctx, cmdArray)
+	if err != nil {
+		return fmt.Errorf("Failed to copy %s to %s: %s", local, host, err)
+	}
+	return nil
+}
+
+// renderTemplate fills in one of the terraform templates of the aws plugin so
+// that the hosts are configured the same way as the aws instances.
+func renderTemplate(assetName string, vars map[string]string) (string, error) {
+	data, err := aws.Asset(path.Join("etc/terraform/instance", assetName))
+	if err != nil {
+		return "", err
+	}
+	return os.Expand(string(data), func(key string) string {
+		return vars[key]
+	}), nil
+}
+
+// Matches the command line of a Stardog server using the data directory
+func stardogPattern(dataDir string) string {
+	return fmt.Sprintf("pgrep -f -- '--home %s'", dataDir)
+}
+
+const zookeeperPattern = "pgrep -f org.apache.zookeeper.server.quorum.QuorumPeerMain"
+
+// FindLeaks looks for Stardog and ZooKeeper servers running on the hosts of
+// the inventory.  Destroying them stops the servers but leaves the data.
+func (p *baremetalPlugin) FindLeaks(ctx context.Context, context sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	inv, err := LoadInventory(p.InventoryPath)
+	if err != nil {
+		return err
+	}
+	rs := newRemoteShell(context, inv, "")
+
+	type service struct {
+		name string
+		host string
+		stop string
+	}
+	found := []service{}
+	for _, h := range inv.StardogHosts {
+		out, err := rs.run(ctx, h, stardogPattern(inv.DataDir)+" || true")
+		if err != nil {
+			context.ConsoleLog(1, "Could not inspect %s: %s\n", h, err)
+			continue
+		}
+		if strings.TrimSpace(out) != "" {
+			found = append(found, service{"Stardog", h, fmt.Sprintf("pkill -f -- '--home %s'", inv.DataDir)})
+		}
+	}
+	for _, h := range inv.ZkHosts {
+		out, err := rs.run(ctx, h, zookeeperPattern+" || true")
+		if err != nil {
+			context.ConsoleLog(1, "Could not inspect %s: %s\n", h, err)
+			continue
+		}
+		if strings.TrimSpace(out) != "" {
+			found = append(found, service{"ZooKeeper", h, fmt.Sprintf("sudo %s/bin/zkServer.sh stop", inv.ZkHome)})
+		}
+	}
+
+	for _, s := range found {
+		context.ConsoleLog(1, "Found %s running on %s\n", s.name, s.host)
+		if !destroy {
+			continue
+		}
+		if !force && !sdutils.AskYesOrNo(context, fmt.Sprintf("Do you want to stop %s on %s", s.name, s.host)) {
+			continue
+		}
+		_, err = rs.run(ctx, s.host, s.stop)
+		if err != nil {
+			context.ConsoleLog(1, "Failed to stop %s on %s: %s\n", s.name, s.host, err)
+		} else {
+			context.ConsoleLog(1, "Stopped %s on %s\n", s.name, s.host)
+		}
+	}
+	return nil
+}
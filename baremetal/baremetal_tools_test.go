@@ -0,0 +1,182 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// fakeSSH provides ssh and scp programs that record every call in the file
+// calls.  ssh prints the contents of the file ssh-output when it exists.
+type fakeSSH struct {
+	dir     string
+	oldPath string
+}
+
+const fakeSSHScript = `#!/usr/bin/env bash
+echo "%s $@" >> %s/calls
+if [ -f "%s/%s-output" ]; then
+    cat "%s/%s-output"
+fi
+exit 0
+`
+
+func newFakeSSH(t *testing.T) *fakeSSH {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	for _, pgm := range []string{"ssh", "scp"} {
+		script := fmt.Sprintf(fakeSSHScript, pgm, dir, dir, pgm, dir, pgm)
+		err = ioutil.WriteFile(path.Join(dir, pgm), []byte(script), 0755)
+		if err != nil {
+			t.Fatalf("Failed to write the fake %s %s", pgm, err)
+		}
+	}
+	fs := fakeSSH{dir: dir, oldPath: os.Getenv("PATH")}
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", dir, fs.oldPath))
+	return &fs
+}
+
+func (fs *fakeSSH) setOutput(t *testing.T, output string) {
+	err := ioutil.WriteFile(path.Join(fs.dir, "ssh-output"), []byte(output), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write the fake output %s", err)
+	}
+}
+
+func (fs *fakeSSH) calls() string {
+	data, _ := ioutil.ReadFile(path.Join(fs.dir, "calls"))
+	return string(data)
+}
+
+func (fs *fakeSSH) reset() {
+	os.Remove(path.Join(fs.dir, "calls"))
+}
+
+func (fs *fakeSSH) close() {
+	os.Setenv("PATH", fs.oldPath)
+	os.RemoveAll(fs.dir)
+}
+
+func writeInventory(t *testing.T, dir string) string {
+	inv := Inventory{
+		Bastion:      "bastion.example.com",
+		ZkHosts:      []string{"zk1", "zk2", "zk3"},
+		StardogHosts: []string{"sd1", "sd2"},
+		SSHUser:      "centos",
+	}
+	invPath := path.Join(dir, "inventory.json")
+	err := sdutils.WriteJSON(&inv, invPath)
+	if err != nil {
+		t.Fatalf("Failed to write the inventory %s", err)
+	}
+	return invPath
+}
+
+func TestLoadInventory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	inv, err := LoadInventory(writeInventory(t, dir))
+	if err != nil {
+		t.Fatalf("Failed to load the inventory %s", err)
+	}
+	if inv.SSHUser != "centos" || inv.DataDir != "/mnt/data/stardog-home" || inv.ZkHome != "/usr/local/zookeeper-3.4.9" {
+		t.Fatalf("The defaults are wrong %v", inv)
+	}
+
+	_, err = LoadInventory("")
+	if err == nil {
+		t.Fatalf("An inventory must be required")
+	}
+	badPath := path.Join(dir, "bad.json")
+	sdutils.WriteJSON(&Inventory{ZkHosts: []string{"zk1"}, StardogHosts: []string{"sd1"}}, badPath)
+	_, err = LoadInventory(badPath)
+	if err == nil {
+		t.Fatalf("An inventory without a bastion should be rejected")
+	}
+	sdutils.WriteJSON(&Inventory{Bastion: "b", StardogHosts: []string{"sd1"}}, badPath)
+	_, err = LoadInventory(badPath)
+	if err == nil {
+		t.Fatalf("An inventory without ZooKeeper hosts should be rejected")
+	}
+}
+
+func TestSSHOpts(t *testing.T) {
+	inv := Inventory{Bastion: "b", SSHUser: "centos"}
+	rs := newRemoteShell(&sdutils.TestContext{}, &inv, "/keys/id_rsa")
+	opts := strings.Join(rs.sshOpts("b"), " ")
+	if !strings.Contains(opts, "-i /keys/id_rsa") || strings.Contains(opts, "ProxyCommand") {
+		t.Fatalf("The bastion should be reached directly %s", opts)
+	}
+	opts = strings.Join(rs.sshOpts("sd1"), " ")
+	if !strings.Contains(opts, "ProxyCommand=ssh ") || !strings.Contains(opts, "-W %h:%p centos@b") {
+		t.Fatalf("Other hosts should be reached by way of the bastion %s", opts)
+	}
+
+	inv.PrivateKey = "/keys/inventory_rsa"
+	rs = newRemoteShell(&sdutils.TestContext{}, &inv, "")
+	if !strings.Contains(strings.Join(rs.sshOpts("b"), " "), "-i /keys/inventory_rsa") {
+		t.Fatalf("The key of the inventory should be used")
+	}
+}
+
+func TestFindLeaks(t *testing.T) {
+	fs := newFakeSSH(t)
+	defer fs.close()
+	app := sdutils.TestContext{ConfigDir: fs.dir, Version: "5.0"}
+
+	plugin := GetPlugin().(*baremetalPlugin)
+	err := plugin.FindLeaks(context.Background(), &app, "", false, false)
+	if err == nil {
+		t.Fatalf("Leaks can only be found with an inventory")
+	}
+
+	plugin.InventoryPath = writeInventory(t, fs.dir)
+	err = plugin.FindLeaks(context.Background(), &app, "", false, false)
+	if err != nil {
+		t.Fatalf("Failed to find leaks %s", err)
+	}
+	if strings.Contains(fs.calls(), "pkill") || strings.Contains(fs.calls(), "stop") {
+		t.Fatalf("Nothing should be stopped %s", fs.calls())
+	}
+
+	fs.reset()
+	fs.setOutput(t, "1234\n")
+	err = plugin.FindLeaks(context.Background(), &app, "", true, true)
+	if err != nil {
+		t.Fatalf("Failed to destroy leaks %s", err)
+	}
+	calls := fs.calls()
+	if strings.Count(calls, "pkill -f -- '--home /mnt/data/stardog-home'") != 2 {
+		t.Fatalf("Stardog should be stopped on both hosts %s", calls)
+	}
+	if strings.Count(calls, "sudo /usr/local/zookeeper-3.4.9/bin/zkServer.sh stop") != 3 {
+		t.Fatalf("ZooKeeper should be stopped on every host %s", calls)
+	}
+}
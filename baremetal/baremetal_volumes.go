@@ -0,0 +1,169 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// VolumeStatusDescription lists the data directories of each Stardog host.
+type VolumeStatusDescription struct {
+	Directories []string
+}
+
+// HostVolumes describes the STARDOG_HOME directories that are prepared on the
+// Stardog hosts of the inventory.
+type HostVolumes struct {
+	DeploymentName   string   `json:"deployment_name,omitempty"`
+	SizeOfEachVolume int      `json:"storage_size,omitempty"`
+	ClusterSize      int      `json:"cluster_size,omitempty"`
+	LicensePath      string   `json:"stardog_license,omitempty"`
+	Hosts            []string `json:"hosts,omitempty"`
+	DataDir          string   `json:"data_dir,omitempty"`
+	VolumeDir        string   `json:"-"`
+	appContext       sdutils.AppContext
+	shell            *remoteShell
+}
+
+// NewBaremetalVolumeManager returns a HostVolumes structure that will be used
+// by graviton to manage the volumes.
+func NewBaremetalVolumeManager(ac sdutils.AppContext, dd *baremetalDeploymentDescription) *HostVolumes {
+	return &HostVolumes{
+		DeploymentName: dd.Name,
+		DataDir:        dd.Inventory.DataDir,
+		VolumeDir:      path.Join(dd.deployDir, "volumes"),
+		appContext:     ac,
+		shell:          dd.shell(),
+	}
+}
+
+// LoadHostVolumes will inflate a HostVolumes structure from the information
+// stored under the configuration directory.
+func LoadHostVolumes(ac sdutils.AppContext, volDir string) (*HostVolumes, error) {
+	var v HostVolumes
+	err := sdutils.LoadJSON(&v, path.Join(volDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	v.VolumeDir = volDir
+	v.appContext = ac
+	return &v, nil
+}
+
+// VolumeExists returns true or false based on whether or not the volumes
+// already exist.
+func (v *HostVolumes) VolumeExists() bool {
+	return sdutils.PathExists(path.Join(v.VolumeDir, "config.json"))
+}
+
+// CreateSet makes the data directory on the first clusterSize Stardog hosts
+// and copies the license into it.
+func (v *HostVolumes) CreateSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	if clusterSize < 1 {
+		return fmt.Errorf("At least one Stardog node is required")
+	}
+	if clusterSize > len(v.shell.inv.StardogHosts) {
+		return fmt.Errorf("The inventory only lists %d Stardog hosts", len(v.shell.inv.StardogHosts))
+	}
+	if !sdutils.PathExists(licensePath) {
+		return fmt.Errorf("Could not read the license %s", licensePath)
+	}
+	v.appContext.Logf(sdutils.INFO, "Host volumes are not limited to %d gigabytes", sizeOfEachVolume)
+
+	hosts := v.shell.inv.StardogHosts[:clusterSize]
+	prepare := fmt.Sprintf("set -e; sudo mkdir -p %s; sudo chown $(id -u):$(id -g) %s", v.DataDir, v.DataDir)
+	for _, h := range hosts {
+		v.appContext.ConsoleLog(1, "Preparing %s on %s\n", v.DataDir, h)
+		_, err := v.shell.run(ctx, h, prepare)
+		if err != nil {
+			v.appContext.ConsoleLog(1, "Failed to create the volumes.\n")
+			return err
+		}
+		err = v.shell.copyTo(ctx, h, licensePath, path.Join(v.DataDir, "stardog-license-key.bin"))
+		if err != nil {
+			v.appContext.ConsoleLog(1, "Failed to create the volumes.\n")
+			return err
+		}
+	}
+
+	v.ClusterSize = clusterSize
+	v.SizeOfEachVolume = sizeOfEachVolume
+	v.LicensePath = licensePath
+	v.Hosts = hosts
+	err := os.MkdirAll(v.VolumeDir, 0755)
+	if err != nil {
+		return err
+	}
+	err = sdutils.WriteJSON(v, path.Join(v.VolumeDir, "config.json"))
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully created the volumes.\n")
+	return nil
+}
+
+// DeleteSet removes the data directory from every host that it was made on.
+func (v *HostVolumes) DeleteSet(ctx context.Context) error {
+	hv, err := LoadHostVolumes(v.appContext, v.VolumeDir)
+	if err != nil {
+		return err
+	}
+	for _, h := range hv.Hosts {
+		_, err = v.shell.run(ctx, h, fmt.Sprintf("sudo rm -rf %s", hv.DataDir))
+		if err != nil {
+			v.appContext.ConsoleLog(1, "Failed to destroy the volumes.\n")
+			return err
+		}
+	}
+	err = os.RemoveAll(v.VolumeDir)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully destroyed the volumes.\n")
+	return nil
+}
+
+func (v *HostVolumes) getStatusInformation(ctx context.Context) (*VolumeStatusDescription, error) {
+	hv, err := LoadHostVolumes(v.appContext, v.VolumeDir)
+	if err != nil {
+		return nil, err
+	}
+	volStatus := VolumeStatusDescription{
+		Directories: make([]string, len(hv.Hosts)),
+	}
+	for i, h := range hv.Hosts {
+		volStatus.Directories[i] = fmt.Sprintf("%s:%s", h, hv.DataDir)
+	}
+	return &volStatus, nil
+}
+
+// Status will print out the directories backing each node.
+func (v *HostVolumes) Status(ctx context.Context) error {
+	vD, err := v.getStatusInformation(ctx)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Volumes:\n")
+	for _, x := range vD.Directories {
+		v.appContext.ConsoleLog(1, "%s\n", x)
+	}
+	return nil
+}
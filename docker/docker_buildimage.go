@@ -0,0 +1,81 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func imageName(version string) string {
+	return fmt.Sprintf("stardog-graviton:%s", version)
+}
+
+func (p *dockerPlugin) HaveImage(ctx context.Context, c sdutils.AppContext) bool {
+	_, err := runDocker(ctx, c, "image", "inspect", imageName(c.GetVersion()))
+	return err == nil
+}
+
+func (p *dockerPlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	context.Logf(sdutils.DEBUG, "Build docker image\n")
+
+	dockerPath, err := exec.LookPath("docker")
+	if err != nil {
+		return err
+	}
+	release, err := ioutil.ReadFile(sdReleaseFilePath)
+	if err != nil {
+		return fmt.Errorf("Failed to read the Stardog release %s: %s", sdReleaseFilePath, err)
+	}
+
+	dir, err := PlaceAsset(context, context.GetConfigDir(), "etc/docker", true)
+	if err != nil {
+		return err
+	}
+	context.Logf(sdutils.DEBUG, "Extracting docker files to: %s\n", dir)
+	defer os.RemoveAll(dir)
+
+	workingDir := path.Join(dir, "etc/docker")
+	err = ioutil.WriteFile(path.Join(workingDir, "stardog.zip"), release, 0644)
+	if err != nil {
+		return err
+	}
+
+	cmdArray := []string{dockerPath, "build",
+		"--label", fmt.Sprintf("%s=%s", applianceLabel, version),
+		"-t", imageName(version),
+		"."}
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  workingDir,
+	}
+
+	spin := sdutils.NewSpinner(context, 1, "Running docker to build the image")
+	_, err = sdutils.RunCommand(ctx, context, cmd, nil, spin)
+	if err != nil {
+		context.ConsoleLog(0, "We failed to build the image.  Please verify that the docker daemon is running.\n")
+		return err
+	}
+	context.ConsoleLog(0, "Image successfully built: %s\n", imageName(version))
+	return nil
+}
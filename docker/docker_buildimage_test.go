@@ -0,0 +1,72 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestHaveImage(t *testing.T) {
+	fd := newFakeDocker(t)
+	defer fd.close()
+	app := sdutils.TestContext{ConfigDir: fd.dir, Version: "5.0"}
+
+	plugin := GetPlugin()
+	if !plugin.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should be found")
+	}
+	if !fd.called("image inspect stardog-graviton:5.0") {
+		t.Fatalf("The wrong image was inspected %v", fd.calls())
+	}
+	os.Setenv("FAKE_DOCKER_FAIL", "image inspect*")
+	if plugin.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should not be found")
+	}
+}
+
+func TestBuildImage(t *testing.T) {
+	fd := newFakeDocker(t)
+	defer fd.close()
+	app := sdutils.TestContext{ConfigDir: fd.dir, Version: "5.0"}
+
+	plugin := GetPlugin()
+	err := plugin.BuildImage(context.Background(), &app, path.Join(fd.dir, "nothere.zip"), "5.0")
+	if err == nil {
+		t.Fatalf("A missing release should fail")
+	}
+
+	releaseFile := path.Join(fd.dir, "stardog.zip")
+	ioutil.WriteFile(releaseFile, []byte("release"), 0644)
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err != nil {
+		t.Fatalf("Failed to build the image %s", err)
+	}
+	if !fd.called("build --label StardogVirtualAppliance=5.0 -t stardog-graviton:5.0 .") {
+		t.Fatalf("The image was not built %v", fd.calls())
+	}
+
+	os.Setenv("FAKE_DOCKER_FAIL", "build*")
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err == nil {
+		t.Fatalf("A failed build should be reported")
+	}
+}
@@ -0,0 +1,268 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type dockerDeploymentDescription struct {
+	BasePort        int    `json:"base_port,omitempty"`
+	ZkImage         string `json:"zk_image,omitempty"`
+	LbImage         string `json:"lb_image,omitempty"`
+	Version         string `json:"-"`
+	Name            string `json:"-"`
+	deployDir       string
+	customPropFile  string
+	environment     []string
+	disableSecurity bool
+	ctx             sdutils.AppContext
+	plugin          *dockerPlugin
+}
+
+func newDockerDeploymentDescription(ctx context.Context, c sdutils.AppContext, baseD *sdutils.BaseDeployment, p *dockerPlugin) (*dockerDeploymentDescription, error) {
+	_, err := runDocker(ctx, c, "image", "inspect", imageName(baseD.Version))
+	if err != nil {
+		return nil, fmt.Errorf("There is no docker image for Stardog %s.  Please see the 'baseami' subcommand", baseD.Version)
+	}
+
+	dd := dockerDeploymentDescription{
+		BasePort:        p.BasePort,
+		ZkImage:         p.ZkImage,
+		LbImage:         p.LbImage,
+		Version:         baseD.Version,
+		Name:            baseD.Name,
+		deployDir:       sdutils.DeploymentDir(c.GetConfigDir(), baseD.Name),
+		customPropFile:  baseD.CustomPropsFile,
+		environment:     baseD.Environment,
+		disableSecurity: baseD.DisableSecurity,
+		ctx:             c,
+		plugin:          p,
+	}
+	return &dd, nil
+}
+
+func (dd *dockerDeploymentDescription) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (dd *dockerDeploymentDescription) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	vm := NewDockerVolumeManager(dd.ctx, dd)
+	return vm.CreateSet(ctx, licensePath, sizeOfEachVolume, clusterSize)
+}
+
+func (dd *dockerDeploymentDescription) DeleteVolumeSet(ctx context.Context) error {
+	vm := NewDockerVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	if dd.InstanceExists() {
+		return fmt.Errorf("The volumes of %s are in use by a running instance", dd.Name)
+	}
+	return vm.DeleteSet(ctx)
+}
+
+func (dd *dockerDeploymentDescription) ClusterSize() (int, error) {
+	vm := NewDockerVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return -1, fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadNamedVolumes(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return -1, err
+	}
+	return vols.ClusterSize, nil
+}
+
+func (dd *dockerDeploymentDescription) StatusVolumeSet(ctx context.Context) error {
+	vm := NewDockerVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.Status(ctx)
+}
+
+func (dd *dockerDeploymentDescription) VolumeExists() bool {
+	vm := NewDockerVolumeManager(dd.ctx, dd)
+	return vm.VolumeExists()
+}
+
+func (dd *dockerDeploymentDescription) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	im, err := NewDockerInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.CreateInstance(ctx, zookeeperSize)
+}
+
+func (dd *dockerDeploymentDescription) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	im, err := NewDockerInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.OpenInstance(ctx, mask, idleTimeout)
+}
+
+func (dd *dockerDeploymentDescription) DeleteInstance(ctx context.Context) error {
+	im, err := NewDockerInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.DeleteInstance(ctx)
+}
+
+func (dd *dockerDeploymentDescription) StatusInstance(ctx context.Context) error {
+	im, err := NewDockerInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.Status(ctx)
+}
+
+func (dd *dockerDeploymentDescription) InstanceExists() bool {
+	im, err := NewDockerInstance(dd.ctx, dd)
+	if err != nil {
+		return false
+	}
+	return im.InstanceExists()
+}
+
+func (dd *dockerDeploymentDescription) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	vm := NewDockerVolumeManager(dd.ctx, dd)
+	volumeStatus, err := vm.getStatusInformation(ctx)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No volume information found %s\n", err)
+	}
+
+	im, err := NewDockerInstance(dd.ctx, dd)
+	if err != nil {
+		return nil, err
+	}
+	instS, err := im.getStatusInformation(ctx)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No instance information found.\n")
+	}
+
+	sD := sdutils.StardogDescription{
+		StardogURL:          fmt.Sprintf("http://localhost:%d", dd.BasePort),
+		StardogInternalURL:  fmt.Sprintf("http://127.0.0.1:%d", dd.BasePort+1),
+		VolumeDescription:   volumeStatus,
+		InstanceDescription: instS,
+		TimeStamp:           time.Now(),
+	}
+	return &sD, nil
+}
+
+func (dd *dockerDeploymentDescription) GatherLogs(ctx context.Context, outfile string) error {
+	im, err := NewDockerInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.GatherLogs(ctx, outfile)
+}
+
+type dockerPlugin struct {
+	BasePort int    `json:"base_port,omitempty"`
+	ZkImage  string `json:"zk_image,omitempty"`
+	LbImage  string `json:"lb_image,omitempty"`
+}
+
+// GetPlugin returns the plugin interface that this module represents.
+func GetPlugin() sdutils.Plugin {
+	return &dockerPlugin{
+		BasePort: 5821,
+		ZkImage:  "zookeeper:3.4",
+		LbImage:  "haproxy:1.7",
+	}
+}
+
+func (p *dockerPlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	b, err := json.Marshal(defaultCliOpts)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *dockerPlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	cmdOpts.LaunchCmd.Flag("docker-port", "The host port on which a docker deployment is published.").Default(fmt.Sprintf("%d", p.BasePort)).IntVar(&p.BasePort)
+	cmdOpts.LaunchCmd.Flag("docker-zk-image", "The ZooKeeper image used by docker deployments.").Default(p.ZkImage).StringVar(&p.ZkImage)
+	cmdOpts.LaunchCmd.Flag("docker-lb-image", "The haproxy image used to balance docker deployments.").Default(p.LbImage).StringVar(&p.LbImage)
+
+	cmdOpts.NewDeploymentCmd.Flag("docker-port", "The host port on which a docker deployment is published.").Default(fmt.Sprintf("%d", p.BasePort)).IntVar(&p.BasePort)
+	cmdOpts.NewDeploymentCmd.Flag("docker-zk-image", "The ZooKeeper image used by docker deployments.").Default(p.ZkImage).StringVar(&p.ZkImage)
+	cmdOpts.NewDeploymentCmd.Flag("docker-lb-image", "The haproxy image used to balance docker deployments.").Default(p.LbImage).StringVar(&p.LbImage)
+
+	return nil
+}
+
+func (p *dockerPlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	if new {
+		dockerDD, err := newDockerDeploymentDescription(ctx, context, baseD, p)
+		if err != nil {
+			return nil, err
+		}
+		baseD.CloudOpts = dockerDD
+		data, err := json.Marshal(baseD)
+		if err != nil {
+			return nil, err
+		}
+		confPath := path.Join(dockerDD.deployDir, "config.json")
+		err = ioutil.WriteFile(confPath, data, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return dockerDD, nil
+	}
+	data, err := json.Marshal(baseD.CloudOpts)
+	if err != nil {
+		return nil, err
+	}
+	var dd dockerDeploymentDescription
+	err = json.Unmarshal(data, &dd)
+	if err != nil {
+		return nil, err
+	}
+	dd.Name = baseD.Name
+	dd.Version = baseD.Version
+	dd.ctx = context
+	dd.deployDir = sdutils.DeploymentDir(context.GetConfigDir(), baseD.Name)
+	dd.customPropFile = baseD.CustomPropsFile
+	dd.environment = baseD.Environment
+	dd.disableSecurity = baseD.DisableSecurity
+	dd.plugin = p
+
+	return &dd, nil
+}
+
+func (p *dockerPlugin) GetName() string {
+	return "docker"
+}
+
+func (p *dockerPlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true, Firewall: true}
+}
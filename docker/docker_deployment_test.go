@@ -0,0 +1,183 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestDeploymentLoadDefaults(t *testing.T) {
+	plugin := GetPlugin().(*dockerPlugin)
+	i := make(map[string]interface{})
+	i["base_port"] = 6000
+	i["zk_image"] = "zookeeper:3.4.10"
+
+	err := plugin.LoadDefaults(&i)
+	if err != nil {
+		t.Fatalf("Failed to load defaults %s", err)
+	}
+	if plugin.BasePort != 6000 {
+		t.Fatalf("base_port not set right")
+	}
+	if plugin.ZkImage != "zookeeper:3.4.10" {
+		t.Fatalf("zk_image not set right")
+	}
+	if plugin.LbImage != "haproxy:1.7" {
+		t.Fatalf("lb_image should keep its default")
+	}
+}
+
+func newTestDeployment(t *testing.T, fd *fakeDocker) (*sdutils.TestContext, sdutils.Deployment) {
+	app := sdutils.TestContext{ConfigDir: fd.dir, Version: "5.0"}
+	plugin := GetPlugin()
+	baseD := sdutils.BaseDeployment{
+		Type:        plugin.GetName(),
+		Name:        "testdep",
+		Directory:   sdutils.DeploymentDir(fd.dir, "testdep"),
+		Version:     "5.0",
+		Environment: []string{"STARDOG_JAVA_ARGS=\"-Xmx2g\""},
+	}
+	os.MkdirAll(baseD.Directory, 0755)
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment %s", err)
+	}
+	return &app, dep
+}
+
+func TestNoImage(t *testing.T) {
+	fd := newFakeDocker(t)
+	defer fd.close()
+	app := sdutils.TestContext{ConfigDir: fd.dir, Version: "5.0"}
+
+	os.Setenv("FAKE_DOCKER_FAIL", "image inspect*")
+	baseD := sdutils.BaseDeployment{Name: "testdep", Version: "5.0"}
+	_, err := GetPlugin().DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The deployment should need an image")
+	}
+}
+
+func TestDockerLifecycle(t *testing.T) {
+	fd := newFakeDocker(t)
+	defer fd.close()
+	_, dep := newTestDeployment(t, fd)
+
+	licenseFile := path.Join(fd.dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+
+	err := dep.CreateInstance(context.Background(), 10, 1, 0)
+	if err == nil {
+		t.Fatalf("The instance should need volumes")
+	}
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 10, 2)
+	if err != nil {
+		t.Fatalf("Failed to make the volumes %s", err)
+	}
+	for _, c := range []string{"volume create --label StardogVirtualAppliance=testdep testdep-stardog-0",
+		"volume create --label StardogVirtualAppliance=testdep testdep-stardog-1",
+		"cp " + licenseFile + " testdep-stardog-1-seed:/var/opt/stardog/stardog-license-key.bin",
+		"rm -f testdep-stardog-1-seed"} {
+		if !fd.called(c) {
+			t.Fatalf("Expected the call %s in %v", c, fd.calls())
+		}
+	}
+	size, err := dep.ClusterSize()
+	if err != nil || size != 2 {
+		t.Fatalf("The cluster size should be 2 %d %s", size, err)
+	}
+
+	fd.reset()
+	err = dep.CreateInstance(context.Background(), 10, 3, 0)
+	if err != nil {
+		t.Fatalf("Failed to create the instance %s", err)
+	}
+	for _, c := range []string{"network create --label StardogVirtualAppliance=testdep graviton-testdep",
+		"run -d --name testdep-zk2 --network graviton-testdep --network-alias zk2",
+		"run -d --name testdep-stardog1 --network graviton-testdep --network-alias stardog1 --label StardogVirtualAppliance=testdep --restart unless-stopped -p 127.0.0.1:5823:5821 -v testdep-stardog-1:/var/opt/stardog -e PACK_NODE_ADDRESS=stardog1:5821 -e PACK_ZOOKEEPER_ADDRESS=zk0:2181,zk1:2181,zk2:2181 -e STARDOG_JAVA_ARGS=-Xmx2g stardog-graviton:5.0"} {
+		if !fd.called(c) {
+			t.Fatalf("Expected the call %s in %v", c, fd.calls())
+		}
+	}
+	if !dep.InstanceExists() {
+		t.Fatalf("The instance should exist")
+	}
+	err = dep.DeleteVolumeSet(context.Background())
+	if err == nil {
+		t.Fatalf("The volumes should not be deleted while in use")
+	}
+
+	fd.reset()
+	err = dep.OpenInstance(context.Background(), 10, 3, "127.0.0.1/32", 600)
+	if err != nil {
+		t.Fatalf("Failed to open the instance %s", err)
+	}
+	if !fd.called("run -d --name testdep-lb --network graviton-testdep --network-alias lb --label StardogVirtualAppliance=testdep --restart unless-stopped -p 127.0.0.1:5821:5821") {
+		t.Fatalf("The load balancer was not started %v", fd.calls())
+	}
+	cfg, err := ioutil.ReadFile(path.Join(sdutils.DeploymentDir(fd.dir, "testdep"), "instance", "haproxy.cfg"))
+	if err != nil {
+		t.Fatalf("The haproxy config was not written %s", err)
+	}
+	if !strings.Contains(string(cfg), "server stardog1 stardog1:5821 check") || !strings.Contains(string(cfg), "timeout client 600s") {
+		t.Fatalf("The haproxy config is wrong %s", string(cfg))
+	}
+
+	sd, err := dep.FullStatus(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get the status %s", err)
+	}
+	if sd.StardogURL != "http://localhost:5821" || sd.StardogInternalURL != "http://127.0.0.1:5822" || sd.SSHHost != "" {
+		t.Fatalf("The status is wrong %v", sd)
+	}
+	instS := sd.InstanceDescription.(*InstanceStatusDescription)
+	if len(instS.Containers) != 6 {
+		t.Fatalf("The status should have 6 containers %v", instS.Containers)
+	}
+
+	fd.setOutput(t, "ps--a", "testdep-stardog0\ntestdep-lb\n")
+	fd.setOutput(t, "network-ls", "graviton-testdep\n")
+	fd.reset()
+	err = dep.DeleteInstance(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to delete the instance %s", err)
+	}
+	if !fd.called("rm -f testdep-stardog0 testdep-lb") || !fd.called("network rm graviton-testdep") {
+		t.Fatalf("The containers were not removed %v", fd.calls())
+	}
+	if dep.InstanceExists() {
+		t.Fatalf("The instance should be gone")
+	}
+
+	fd.setOutput(t, "volume-ls", "testdep-stardog-0\ntestdep-stardog-1\n")
+	err = dep.DeleteVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to delete the volumes %s", err)
+	}
+	if !fd.called("volume rm testdep-stardog-0 testdep-stardog-1") {
+		t.Fatalf("The volumes were not removed %v", fd.calls())
+	}
+	if dep.VolumeExists() {
+		t.Fatalf("The volumes should be gone")
+	}
+}
@@ -0,0 +1,374 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// ContainerInstance represents the containers and network that make up a
+// running Stardog cluster.
+type ContainerInstance struct {
+	DeploymentName    string             `json:"deployment_name,omitempty"`
+	Network           string             `json:"network,omitempty"`
+	StardogContainers []string           `json:"stardog_containers,omitempty"`
+	ZkContainers      []string           `json:"zookeeper_containers,omitempty"`
+	LbContainer       string             `json:"lb_container,omitempty"`
+	InstanceDir       string             `json:"-"`
+	Ctx               sdutils.AppContext `json:"-"`
+	dd                *dockerDeploymentDescription
+}
+
+// InstanceStatusDescription describes details about a running docker instance.
+type InstanceStatusDescription struct {
+	Network        string
+	ZkNodesContact []string
+	Containers     []string
+}
+
+// NewDockerInstance returns a ContainerInstance which will be used to run or
+// inspect the containers of a deployment.
+func NewDockerInstance(ctx sdutils.AppContext, dd *dockerDeploymentDescription) (*ContainerInstance, error) {
+	instance := ContainerInstance{
+		DeploymentName: dd.Name,
+		Network:        fmt.Sprintf("graviton-%s", dd.Name),
+		InstanceDir:    path.Join(dd.deployDir, "instance"),
+		Ctx:            ctx,
+		dd:             dd,
+	}
+	return &instance, nil
+}
+
+func (ci *ContainerInstance) confPath() string {
+	return path.Join(ci.InstanceDir, "instance.json")
+}
+
+func (ci *ContainerInstance) load() error {
+	err := sdutils.LoadJSON(ci, ci.confPath())
+	if err != nil {
+		return fmt.Errorf("There is no configured instance")
+	}
+	return nil
+}
+
+func (ci *ContainerInstance) save() error {
+	return sdutils.WriteJSON(ci, ci.confPath())
+}
+
+func zkAlias(index int) string {
+	return fmt.Sprintf("zk%d", index)
+}
+
+func stardogAlias(index int) string {
+	return fmt.Sprintf("stardog%d", index)
+}
+
+func (ci *ContainerInstance) zkServers() []string {
+	servers := make([]string, len(ci.ZkContainers))
+	for i := range ci.ZkContainers {
+		servers[i] = fmt.Sprintf("%s:2181", zkAlias(i))
+	}
+	return servers
+}
+
+func (ci *ContainerInstance) runContainer(ctx context.Context, name string, alias string, args ...string) error {
+	runArgs := []string{"run", "-d",
+		"--name", name,
+		"--network", ci.Network,
+		"--network-alias", alias,
+		"--label", labelArg(ci.DeploymentName),
+		"--restart", "unless-stopped"}
+	_, err := runDocker(ctx, ci.Ctx, append(runArgs, args...)...)
+	return err
+}
+
+func (ci *ContainerInstance) startZookeeper(ctx context.Context, index int, zookeeperSize int) error {
+	servers := make([]string, zookeeperSize)
+	for i := 0; i < zookeeperSize; i++ {
+		servers[i] = fmt.Sprintf("server.%d=%s:2888:3888", i+1, zkAlias(i))
+	}
+	name := fmt.Sprintf("%s-%s", ci.DeploymentName, zkAlias(index))
+	err := ci.runContainer(ctx, name, zkAlias(index),
+		"-e", fmt.Sprintf("ZOO_MY_ID=%d", index+1),
+		"-e", fmt.Sprintf("ZOO_SERVERS=%s", strings.Join(servers, " ")),
+		ci.dd.ZkImage)
+	if err != nil {
+		return err
+	}
+	ci.ZkContainers = append(ci.ZkContainers, name)
+	return nil
+}
+
+func (ci *ContainerInstance) startStardog(ctx context.Context, index int) error {
+	name := fmt.Sprintf("%s-%s", ci.DeploymentName, stardogAlias(index))
+	args := []string{
+		"-p", fmt.Sprintf("127.0.0.1:%d:5821", ci.dd.BasePort+1+index),
+		"-v", fmt.Sprintf("%s:/var/opt/stardog", volumeName(ci.DeploymentName, index)),
+		"-e", fmt.Sprintf("PACK_NODE_ADDRESS=%s:5821", stardogAlias(index)),
+		"-e", fmt.Sprintf("PACK_ZOOKEEPER_ADDRESS=%s", strings.Join(ci.zkServers(), ",")),
+	}
+	if ci.dd.customPropFile != "" {
+		data, err := ioutil.ReadFile(ci.dd.customPropFile)
+		if err != nil {
+			return fmt.Errorf("Invalid custom properties file: %s", err)
+		}
+		args = append(args, "-e", fmt.Sprintf("STARDOG_CUSTOM_PROPERTIES=%s", string(data)))
+	}
+	if ci.dd.disableSecurity {
+		args = append(args, "-e", "STARDOG_SERVER_ARGS=--disable-security")
+	}
+	for _, e := range ci.dd.environment {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		// docker passes the value verbatim so the shell quoting must go
+		args = append(args, "-e", fmt.Sprintf("%s=%s", kv[0], strings.Trim(kv[1], "\"'")))
+	}
+	args = append(args, imageName(ci.dd.Version))
+
+	err := ci.runContainer(ctx, name, stardogAlias(index), args...)
+	if err != nil {
+		return err
+	}
+	ci.StardogContainers = append(ci.StardogContainers, name)
+	return nil
+}
+
+// CreateInstance makes the network and starts the ZooKeeper and Stardog
+// containers.  Each Stardog node is published on the loopback interface only.
+func (ci *ContainerInstance) CreateInstance(ctx context.Context, zookeeperSize int) error {
+	if ci.InstanceExists() {
+		ci.Ctx.ConsoleLog(1, "The instance already exists.\n")
+		ci.Ctx.Logf(sdutils.INFO, "The instance already exists.")
+		return nil
+	}
+	vols, err := LoadNamedVolumes(ci.Ctx, path.Join(ci.dd.deployDir, "volumes"))
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(ci.InstanceDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	_, err = runDocker(ctx, ci.Ctx, "network", "create", "--label", labelArg(ci.DeploymentName), ci.Network)
+	if err != nil {
+		return err
+	}
+	err = ci.save()
+	if err != nil {
+		return err
+	}
+
+	spin := sdutils.NewSpinner(ci.Ctx, 1, "Starting the containers")
+	for i := 0; i < zookeeperSize; i++ {
+		spin.EchoNext()
+		err = ci.startZookeeper(ctx, i, zookeeperSize)
+		if err != nil {
+			ci.save()
+			ci.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+			return err
+		}
+	}
+	for i := 0; i < vols.ClusterSize; i++ {
+		spin.EchoNext()
+		err = ci.startStardog(ctx, i)
+		if err != nil {
+			ci.save()
+			ci.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+			return err
+		}
+	}
+	spin.Close()
+	err = ci.save()
+	if err != nil {
+		return err
+	}
+	ci.Ctx.ConsoleLog(1, "Successfully created the instance.\n")
+	return nil
+}
+
+func (ci *ContainerInstance) writeLbConf(idleTimeout int) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString("global\n    maxconn 256\n\n")
+	buf.WriteString("defaults\n    mode http\n    timeout connect 5s\n")
+	buf.WriteString(fmt.Sprintf("    timeout client %ds\n    timeout server %ds\n\n", idleTimeout, idleTimeout))
+	buf.WriteString("frontend stardog\n    bind *:5821\n    default_backend stardog_nodes\n\n")
+	buf.WriteString("backend stardog_nodes\n    option httpchk GET /admin/healthcheck\n")
+	for i := range ci.StardogContainers {
+		buf.WriteString(fmt.Sprintf("    server %s %s:5821 check\n", stardogAlias(i), stardogAlias(i)))
+	}
+	confFile := path.Join(ci.InstanceDir, "haproxy.cfg")
+	err := ioutil.WriteFile(confFile, buf.Bytes(), 0644)
+	if err != nil {
+		return "", err
+	}
+	return confFile, nil
+}
+
+// OpenInstance starts a load balancer in front of the Stardog nodes and
+// publishes it on the base port.
+func (ci *ContainerInstance) OpenInstance(ctx context.Context, mask string, idleTimeout int) error {
+	err := ci.load()
+	if err != nil {
+		return err
+	}
+	if ci.LbContainer != "" {
+		ci.Ctx.Logf(sdutils.INFO, "The load balancer %s is already running", ci.LbContainer)
+		return nil
+	}
+	if idleTimeout < 1 {
+		idleTimeout = 3600
+	}
+	confFile, err := ci.writeLbConf(idleTimeout)
+	if err != nil {
+		return err
+	}
+
+	// docker can only choose the interface, not filter by network mask
+	bindAddr := "0.0.0.0"
+	if strings.HasPrefix(mask, "127.") {
+		bindAddr = "127.0.0.1"
+	} else {
+		ci.Ctx.Logf(sdutils.WARN, "Publishing %s on all interfaces, the mask %s is not enforced", ci.DeploymentName, mask)
+	}
+	name := fmt.Sprintf("%s-lb", ci.DeploymentName)
+	err = ci.runContainer(ctx, name, "lb",
+		"-p", fmt.Sprintf("%s:%d:5821", bindAddr, ci.dd.BasePort),
+		"-v", fmt.Sprintf("%s:/usr/local/etc/haproxy/haproxy.cfg:ro", confFile),
+		ci.dd.LbImage)
+	if err != nil {
+		return err
+	}
+	ci.LbContainer = name
+	return ci.save()
+}
+
+// DeleteInstance removes every container and the network of the deployment.
+func (ci *ContainerInstance) DeleteInstance(ctx context.Context) error {
+	err := ci.load()
+	if err != nil {
+		return err
+	}
+	names, err := listContainers(ctx, ci.Ctx, ci.DeploymentName)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		_, err = runDocker(ctx, ci.Ctx, append([]string{"rm", "-f"}, names...)...)
+		if err != nil {
+			return err
+		}
+	}
+	networks, err := listLabeled(ctx, ci.Ctx, ci.DeploymentName, "network", "ls")
+	if err != nil {
+		return err
+	}
+	for _, n := range networks {
+		_, err = runDocker(ctx, ci.Ctx, "network", "rm", n)
+		if err != nil {
+			return err
+		}
+	}
+	os.Remove(ci.confPath())
+	ci.Ctx.ConsoleLog(1, "Successfully destroyed the instance.\n")
+	return nil
+}
+
+// InstanceExists will return true if containers were started for this deployment.
+func (ci *ContainerInstance) InstanceExists() bool {
+	return sdutils.PathExists(ci.confPath())
+}
+
+func (ci *ContainerInstance) getStatusInformation(ctx context.Context) (*InstanceStatusDescription, error) {
+	err := ci.load()
+	if err != nil {
+		return nil, err
+	}
+	s := InstanceStatusDescription{
+		Network:        ci.Network,
+		ZkNodesContact: ci.zkServers(),
+		Containers:     append(ci.StardogContainers, ci.ZkContainers...),
+	}
+	if ci.LbContainer != "" {
+		s.Containers = append(s.Containers, ci.LbContainer)
+	}
+	return &s, nil
+}
+
+// Status prints the state of each container in the deployment.
+func (ci *ContainerInstance) Status(ctx context.Context) error {
+	err := ci.load()
+	if err != nil {
+		return err
+	}
+	out, err := runDocker(ctx, ci.Ctx, "ps", "-a", "--filter", labelFilter(ci.DeploymentName), "--format", "{{.Names}}\t{{.Status}}\t{{.Ports}}")
+	if err != nil {
+		return err
+	}
+	ci.Ctx.ConsoleLog(1, "Containers:\n")
+	for _, l := range outputLines(out) {
+		ci.Ctx.ConsoleLog(1, "%s\n", l)
+	}
+	return nil
+}
+
+// GatherLogs collects the container output and the Stardog log of every node
+// into a gzipped tarball.
+func (ci *ContainerInstance) GatherLogs(ctx context.Context, outfile string) error {
+	err := ci.load()
+	if err != nil {
+		return err
+	}
+	dockerPath, err := exec.LookPath("docker")
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "stardoglogs")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	for _, c := range append(ci.StardogContainers, ci.ZkContainers...) {
+		out, err := exec.CommandContext(ctx, dockerPath, "logs", c).CombinedOutput()
+		if err != nil {
+			ci.Ctx.Logf(sdutils.WARN, "Could not get the logs of %s: %s", c, err)
+			continue
+		}
+		err = ioutil.WriteFile(path.Join(dir, c+".out"), out, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range ci.StardogContainers {
+		_, err = runDocker(ctx, ci.Ctx, "cp", fmt.Sprintf("%s:/var/opt/stardog/stardog.log", c), path.Join(dir, c+"-stardog.log"))
+		if err != nil {
+			ci.Ctx.Logf(sdutils.WARN, "Could not copy the stardog log of %s: %s", c, err)
+		}
+	}
+	return sdutils.TarDirectory(dir, outfile)
+}
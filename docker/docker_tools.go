@@ -0,0 +1,162 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+const applianceLabel = "StardogVirtualAppliance"
+
+func labelFilter(deploymentName string) string {
+	if deploymentName == "" {
+		return fmt.Sprintf("label=%s", applianceLabel)
+	}
+	return fmt.Sprintf("label=%s=%s", applianceLabel, deploymentName)
+}
+
+func labelArg(deploymentName string) string {
+	return fmt.Sprintf("%s=%s", applianceLabel, deploymentName)
+}
+
+// runDocker runs a short lived docker command and returns its standard output.
+func runDocker(ctx context.Context, c sdutils.AppContext, args ...string) (string, error) {
+	dockerPath, err := exec.LookPath("docker")
+	if err != nil {
+		return "", fmt.Errorf("The docker client could not be found: %s", err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, dockerPath, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	c.Logf(sdutils.DEBUG, "Running docker %s", strings.Join(args, " "))
+	err = cmd.Run()
+	if err != nil {
+		c.Logf(sdutils.WARN, "docker %s failed: %s %s", args[0], err, stderr.String())
+		return "", fmt.Errorf("docker %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func outputLines(out string) []string {
+	lines := []string{}
+	for _, l := range strings.Split(out, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func listLabeled(ctx context.Context, c sdutils.AppContext, deploymentName string, listCmd ...string) ([]string, error) {
+	args := append(listCmd, "--filter", labelFilter(deploymentName), "--format", "{{.Name}}")
+	out, err := runDocker(ctx, c, args...)
+	if err != nil {
+		return nil, err
+	}
+	return outputLines(out), nil
+}
+
+func listContainers(ctx context.Context, c sdutils.AppContext, deploymentName string) ([]string, error) {
+	out, err := runDocker(ctx, c, "ps", "-a", "--filter", labelFilter(deploymentName), "--format", "{{.Names}}")
+	if err != nil {
+		return nil, err
+	}
+	return outputLines(out), nil
+}
+
+// PlaceAsset writes the docker build context into a directory.
+func PlaceAsset(cliContext sdutils.AppContext, dir string, assentName string, temp bool) (string, error) {
+	var err error
+	if temp {
+		dir, err = ioutil.TempDir(dir, "stardog")
+		if err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			err = os.MkdirAll(dir, 0755)
+			if err != nil {
+				cliContext.ConsoleLog(0, "ERROR %s\n", err.Error())
+				return "", err
+			}
+		}
+	}
+
+	err = RestoreAssets(dir, assentName)
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func (p *dockerPlugin) FindLeaks(ctx context.Context, context sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	type leakKind struct {
+		name    string
+		list    func() ([]string, error)
+		destroy []string
+	}
+	kinds := []leakKind{
+		{
+			name:    "container",
+			list:    func() ([]string, error) { return listContainers(ctx, context, deploymentName) },
+			destroy: []string{"rm", "-f"},
+		},
+		{
+			name:    "volume",
+			list:    func() ([]string, error) { return listLabeled(ctx, context, deploymentName, "volume", "ls") },
+			destroy: []string{"volume", "rm"},
+		},
+		{
+			name:    "network",
+			list:    func() ([]string, error) { return listLabeled(ctx, context, deploymentName, "network", "ls") },
+			destroy: []string{"network", "rm"},
+		},
+	}
+
+	for _, k := range kinds {
+		names, err := k.list()
+		if err != nil {
+			return err
+		}
+		for _, n := range names {
+			context.ConsoleLog(1, "Found the %s %s\n", k.name, n)
+			if !destroy {
+				continue
+			}
+			if !force && !sdutils.AskYesOrNo(context, fmt.Sprintf("Do you want to delete the %s %s", k.name, n)) {
+				continue
+			}
+			_, err = runDocker(ctx, context, append(k.destroy, n)...)
+			if err != nil {
+				context.ConsoleLog(1, "Failed to delete the %s %s: %s\n", k.name, n, err)
+			} else {
+				context.ConsoleLog(1, "Deleted the %s %s\n", k.name, n)
+			}
+		}
+	}
+	return nil
+}
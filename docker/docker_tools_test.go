@@ -0,0 +1,137 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// fakeDocker records every call in the file calls and prints the contents of
+// a file named after the first two arguments, ie: volume-ls.  Any call that
+// matches the glob in FAKE_DOCKER_FAIL exits with an error.
+type fakeDocker struct {
+	dir     string
+	oldPath string
+}
+
+const fakeDockerScript = `#!/usr/bin/env bash
+echo "$@" >> %s/calls
+if [[ -n "$FAKE_DOCKER_FAIL" && "$*" == $FAKE_DOCKER_FAIL ]]; then
+    echo "fake failure" >&2
+    exit 1
+fi
+out="%s/$1-$2"
+if [ -f "$out" ]; then
+    cat "$out"
+fi
+exit 0
+`
+
+func newFakeDocker(t *testing.T) *fakeDocker {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	script := fmt.Sprintf(fakeDockerScript, dir, dir)
+	err = ioutil.WriteFile(path.Join(dir, "docker"), []byte(script), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the fake docker %s", err)
+	}
+	fd := fakeDocker{dir: dir, oldPath: os.Getenv("PATH")}
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", dir, fd.oldPath))
+	return &fd
+}
+
+func (fd *fakeDocker) setOutput(t *testing.T, key string, output string) {
+	err := ioutil.WriteFile(path.Join(fd.dir, key), []byte(output), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write the fake output %s", err)
+	}
+}
+
+func (fd *fakeDocker) calls() []string {
+	data, err := ioutil.ReadFile(path.Join(fd.dir, "calls"))
+	if err != nil {
+		return []string{}
+	}
+	return outputLines(string(data))
+}
+
+func (fd *fakeDocker) called(prefix string) bool {
+	for _, c := range fd.calls() {
+		if strings.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fd *fakeDocker) reset() {
+	os.Remove(path.Join(fd.dir, "calls"))
+}
+
+func (fd *fakeDocker) close() {
+	os.Setenv("PATH", fd.oldPath)
+	os.Unsetenv("FAKE_DOCKER_FAIL")
+	os.RemoveAll(fd.dir)
+}
+
+func TestFindLeaks(t *testing.T) {
+	fd := newFakeDocker(t)
+	defer fd.close()
+	app := sdutils.TestContext{ConfigDir: fd.dir, Version: "5.0"}
+
+	fd.setOutput(t, "ps--a", "dep1-stardog0\ndep1-zk0\n")
+	fd.setOutput(t, "volume-ls", "dep1-stardog-0\n")
+	fd.setOutput(t, "network-ls", "graviton-dep1\n")
+
+	plugin := GetPlugin()
+	err := plugin.FindLeaks(context.Background(), &app, "dep1", false, false)
+	if err != nil {
+		t.Fatalf("Failed to find leaks %s", err)
+	}
+	if fd.called("rm") || fd.called("volume rm") {
+		t.Fatalf("Nothing should be removed without destroy %v", fd.calls())
+	}
+	if !fd.called("ps -a --filter label=StardogVirtualAppliance=dep1") {
+		t.Fatalf("The containers should be filtered by the deployment %v", fd.calls())
+	}
+
+	fd.reset()
+	err = plugin.FindLeaks(context.Background(), &app, "", true, true)
+	if err != nil {
+		t.Fatalf("Failed to destroy leaks %s", err)
+	}
+	for _, c := range []string{"rm -f dep1-stardog0", "rm -f dep1-zk0", "volume rm dep1-stardog-0", "network rm graviton-dep1"} {
+		if !fd.called(c) {
+			t.Fatalf("Expected the call %s in %v", c, fd.calls())
+		}
+	}
+
+	os.Setenv("FAKE_DOCKER_FAIL", "ps*")
+	err = plugin.FindLeaks(context.Background(), &app, "", false, false)
+	if err == nil {
+		t.Fatalf("A docker failure should be reported")
+	}
+}
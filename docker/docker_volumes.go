@@ -0,0 +1,182 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// VolumeStatusDescription lists the docker volumes of a deployment.
+type VolumeStatusDescription struct {
+	VolumeNames []string
+}
+
+// NamedVolumes represents the docker named volumes that hold STARDOG_HOME for
+// each node.
+type NamedVolumes struct {
+	DeploymentName   string `json:"deployment_name,omitempty"`
+	SizeOfEachVolume int    `json:"storage_size,omitempty"`
+	ClusterSize      int    `json:"cluster_size,omitempty"`
+	LicensePath      string `json:"stardog_license,omitempty"`
+	Version          string `json:"version,omitempty"`
+	VolumeDir        string `json:"-"`
+	appContext       sdutils.AppContext
+}
+
+// NewDockerVolumeManager returns a NamedVolumes structure that will be used by
+// graviton to manage the volumes.
+func NewDockerVolumeManager(ac sdutils.AppContext, dd *dockerDeploymentDescription) *NamedVolumes {
+	return &NamedVolumes{
+		DeploymentName: dd.Name,
+		Version:        dd.Version,
+		VolumeDir:      path.Join(dd.deployDir, "volumes"),
+		appContext:     ac,
+	}
+}
+
+// LoadNamedVolumes will inflate a NamedVolumes structure from the information
+// stored under the configuration directory.
+func LoadNamedVolumes(ac sdutils.AppContext, volDir string) (*NamedVolumes, error) {
+	var v NamedVolumes
+	err := sdutils.LoadJSON(&v, path.Join(volDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	v.VolumeDir = volDir
+	v.appContext = ac
+	return &v, nil
+}
+
+func volumeName(deploymentName string, index int) string {
+	return fmt.Sprintf("%s-stardog-%d", deploymentName, index)
+}
+
+// VolumeExists returns true or false based on whether or not the volumes
+// already exist.
+func (v *NamedVolumes) VolumeExists() bool {
+	return sdutils.PathExists(path.Join(v.VolumeDir, "config.json"))
+}
+
+// seedLicense copies the license into a volume by way of a container that
+// is created but never started.
+func (v *NamedVolumes) seedLicense(ctx context.Context, volName string, licensePath string) error {
+	tmpName := fmt.Sprintf("%s-seed", volName)
+	_, err := runDocker(ctx, v.appContext, "container", "create",
+		"--name", tmpName,
+		"--label", labelArg(v.DeploymentName),
+		"-v", fmt.Sprintf("%s:/var/opt/stardog", volName),
+		imageName(v.Version))
+	if err != nil {
+		return err
+	}
+	defer runDocker(ctx, v.appContext, "rm", "-f", tmpName)
+
+	_, err = runDocker(ctx, v.appContext, "cp", licensePath, fmt.Sprintf("%s:/var/opt/stardog/stardog-license-key.bin", tmpName))
+	return err
+}
+
+// CreateSet makes a named volume for every node and seeds each of them with
+// the license.
+func (v *NamedVolumes) CreateSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	if clusterSize < 1 {
+		return fmt.Errorf("At least one Stardog node is required")
+	}
+	if !sdutils.PathExists(licensePath) {
+		return fmt.Errorf("The license %s does not exist", licensePath)
+	}
+	v.appContext.Logf(sdutils.INFO, "Docker volumes are not limited to %d gigabytes", sizeOfEachVolume)
+
+	spin := sdutils.NewSpinner(v.appContext, 1, "Creating the docker volumes")
+	for i := 0; i < clusterSize; i++ {
+		spin.EchoNext()
+		volName := volumeName(v.DeploymentName, i)
+		_, err := runDocker(ctx, v.appContext, "volume", "create", "--label", labelArg(v.DeploymentName), volName)
+		if err != nil {
+			return err
+		}
+		err = v.seedLicense(ctx, volName, licensePath)
+		if err != nil {
+			return err
+		}
+	}
+	spin.Close()
+
+	v.ClusterSize = clusterSize
+	v.SizeOfEachVolume = sizeOfEachVolume
+	v.LicensePath = licensePath
+	err := os.MkdirAll(v.VolumeDir, 0755)
+	if err != nil {
+		return err
+	}
+	err = sdutils.WriteJSON(v, path.Join(v.VolumeDir, "config.json"))
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully created the volumes.\n")
+	return nil
+}
+
+// DeleteSet removes the named volumes.
+func (v *NamedVolumes) DeleteSet(ctx context.Context) error {
+	names, err := listLabeled(ctx, v.appContext, v.DeploymentName, "volume", "ls")
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		_, err = runDocker(ctx, v.appContext, append([]string{"volume", "rm"}, names...)...)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.RemoveAll(v.VolumeDir)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully destroyed the volumes.\n")
+	return nil
+}
+
+func (v *NamedVolumes) getStatusInformation(ctx context.Context) (*VolumeStatusDescription, error) {
+	nv, err := LoadNamedVolumes(v.appContext, v.VolumeDir)
+	if err != nil {
+		return nil, err
+	}
+	volStatus := VolumeStatusDescription{
+		VolumeNames: make([]string, nv.ClusterSize),
+	}
+	for i := 0; i < nv.ClusterSize; i++ {
+		volStatus.VolumeNames[i] = volumeName(v.DeploymentName, i)
+	}
+	return &volStatus, nil
+}
+
+// Status will print out the docker volumes backing each node.
+func (v *NamedVolumes) Status(ctx context.Context) error {
+	vD, err := v.getStatusInformation(ctx)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Volumes:\n")
+	for _, x := range vD.VolumeNames {
+		v.appContext.ConsoleLog(1, "%s\n", x)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+#!/usr/bin/env bash
+
+set -e
+
+# The properties are rebuilt on every start so that changes to the cluster
+# layout are picked up.
+props=${STARDOG_HOME}/stardog.properties
+if [ -n "${PACK_ZOOKEEPER_ADDRESS}" ]; then
+    echo "pack.enabled=true" > ${props}
+    echo "pack.node.address=${PACK_NODE_ADDRESS}" >> ${props}
+    echo "pack.zookeeper.address=${PACK_ZOOKEEPER_ADDRESS}" >> ${props}
+else
+    echo "" > ${props}
+fi
+if [ -n "${STARDOG_CUSTOM_PROPERTIES}" ]; then
+    echo "${STARDOG_CUSTOM_PROPERTIES}" >> ${props}
+fi
+
+rm -f ${STARDOG_HOME}/system.lock
+exec /usr/local/stardog/bin/stardog-admin server start --foreground --home ${STARDOG_HOME} ${STARDOG_SERVER_ARGS}
@@ -0,0 +1,39 @@
+
+resource "google_compute_instance" "bastion" {
+  name = "${var.deployment_name}-bastion"
+  zone = "${var.zone}"
+  machine_type = "${var.bastion_machine_type}"
+  tags = ["${var.deployment_name}-bastion"]
+
+  boot_disk {
+    initialize_params {
+      image = "${var.baseimage}"
+    }
+  }
+
+  network_interface {
+    subnetwork = "${google_compute_subnetwork.main.self_link}"
+    access_config {}
+  }
+
+  metadata {
+    ssh-keys = "ubuntu:${file(var.public_key_path)}"
+  }
+
+  labels {
+    stardog-virtual-appliance = "${var.deployment_name}"
+  }
+}
+
+resource "google_compute_firewall" "bastion" {
+  name = "${var.deployment_name}-bastion-ssh"
+  network = "${google_compute_network.main.name}"
+  target_tags = ["${var.deployment_name}-bastion"]
+
+  # allow ssh from anywhere
+  allow {
+    protocol = "tcp"
+    ports = ["22"]
+  }
+  source_ranges = ["0.0.0.0/0"]
+}
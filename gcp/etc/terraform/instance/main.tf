@@ -0,0 +1,30 @@
+provider "google" {
+  credentials = "${file(var.credentials_path)}"
+  project = "${var.project}"
+  region = "${var.region}"
+}
+
+resource "google_compute_network" "main" {
+  name = "${var.deployment_name}-net"
+  auto_create_subnetworks = false
+}
+
+resource "google_compute_subnetwork" "main" {
+  name = "${var.deployment_name}-subnet"
+  region = "${var.region}"
+  network = "${google_compute_network.main.self_link}"
+  ip_cidr_range = "${var.internal_network}"
+}
+
+resource "google_compute_firewall" "internal" {
+  name = "${var.deployment_name}-internal"
+  network = "${google_compute_network.main.name}"
+
+  allow {
+    protocol = "tcp"
+  }
+  allow {
+    protocol = "icmp"
+  }
+  source_ranges = ["${var.internal_network}"]
+}
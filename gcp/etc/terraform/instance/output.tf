@@ -0,0 +1,15 @@
+output "stardog_contact" {
+  value = "${google_compute_forwarding_rule.stardog.ip_address}"
+}
+
+output "stardog_internal_contact" {
+  value = "${google_compute_forwarding_rule.stardoginternal.ip_address}"
+}
+
+output "bastion_contact" {
+  value = "${google_compute_instance.bastion.network_interface.0.access_config.0.assigned_nat_ip}"
+}
+
+output "zookeeper_nodes" {
+  value = ["${google_compute_instance.zookeeper.*.name}"]
+}
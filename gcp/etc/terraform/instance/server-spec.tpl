@@ -0,0 +1 @@
+server.${id}=${host}:2888:3888
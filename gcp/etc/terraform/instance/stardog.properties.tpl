@@ -0,0 +1,4 @@
+pack.enabled=true
+pack.node.address=@@LOCAL_IP@@
+pack.zookeeper.address=${zk_servers}
+${custom_data}
\ No newline at end of file
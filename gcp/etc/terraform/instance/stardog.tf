@@ -0,0 +1,128 @@
+data "template_file" "stardog_zk_server" {
+  count = "${var.zookeeper_size}"
+  template = "$${host}:2181"
+  vars {
+    host = "${element(google_compute_instance.zookeeper.*.name, count.index)}"
+  }
+}
+
+data "template_file" "stardog_properties" {
+  template = "${var.custom_stardog_properties}\n${file("stardog.properties.tpl")}"
+  vars {
+    zk_servers = "${join(",", data.template_file.stardog_zk_server.*.rendered)}"
+    custom_data = "${var.custom_properties_data}"
+  }
+}
+
+data "template_file" "stardog_userdata" {
+  template = "${file("stardog_userdata.tpl")}"
+  vars {
+    stardog_conf = "${data.template_file.stardog_properties.rendered}"
+    zk_servers = "${join(",", data.template_file.stardog_zk_server.*.rendered)}"
+    environment_variables = "${var.environment_variables}"
+    server_opts = "${var.stardog_start_opts}"
+  }
+}
+
+# Each node mounts the disk of the same index that was made by the volumes step
+resource "google_compute_instance" "stardog" {
+  count = "${var.stardog_size}"
+  name = "${var.deployment_name}-sd-${count.index}"
+  zone = "${var.zone}"
+  machine_type = "${var.stardog_machine_type}"
+  tags = ["${var.deployment_name}-stardog"]
+
+  boot_disk {
+    initialize_params {
+      image = "${var.baseimage}"
+      type = "${var.root_volume_type}"
+      size = "${var.root_volume_size}"
+    }
+  }
+
+  attached_disk {
+    source = "${var.deployment_name}-data-${count.index}"
+    device_name = "stardog-data"
+  }
+
+  network_interface {
+    subnetwork = "${google_compute_subnetwork.main.self_link}"
+  }
+
+  metadata {
+    ssh-keys = "ubuntu:${file(var.public_key_path)}"
+  }
+
+  metadata_startup_script = "${data.template_file.stardog_userdata.rendered}"
+
+  labels {
+    stardog-virtual-appliance = "${var.deployment_name}"
+  }
+}
+
+resource "google_compute_instance_group" "stardog" {
+  name = "${var.deployment_name}-sd-group"
+  zone = "${var.zone}"
+  network = "${google_compute_network.main.self_link}"
+  instances = ["${google_compute_instance.stardog.*.self_link}"]
+}
+
+resource "google_compute_firewall" "stardog" {
+  name = "${var.deployment_name}-stardog"
+  network = "${google_compute_network.main.name}"
+  target_tags = ["${var.deployment_name}-stardog"]
+
+  # the network load balancer passes the client address through
+  allow {
+    protocol = "tcp"
+    ports = ["5821"]
+  }
+  source_ranges = ["${concat(list(var.http_subnet), var.health_check_ranges)}"]
+}
+
+resource "google_compute_http_health_check" "stardog" {
+  name = "${var.deployment_name}-sd-check"
+  port = 5821
+  request_path = "/admin/healthcheck"
+}
+
+resource "google_compute_target_pool" "stardog" {
+  name = "${var.deployment_name}-sd-pool"
+  region = "${var.region}"
+  instances = ["${formatlist("%s/%s", var.zone, google_compute_instance.stardog.*.name)}"]
+  health_checks = ["${google_compute_http_health_check.stardog.name}"]
+}
+
+resource "google_compute_forwarding_rule" "stardog" {
+  name = "${var.deployment_name}-sd-lb"
+  region = "${var.region}"
+  target = "${google_compute_target_pool.stardog.self_link}"
+  port_range = "5821"
+}
+
+resource "google_compute_health_check" "stardoginternal" {
+  name = "${var.deployment_name}-sd-internal-check"
+  tcp_health_check {
+    port = "5821"
+  }
+}
+
+resource "google_compute_region_backend_service" "stardoginternal" {
+  name = "${var.deployment_name}-sd-internal"
+  region = "${var.region}"
+  protocol = "TCP"
+  health_checks = ["${google_compute_health_check.stardoginternal.self_link}"]
+  backend {
+    group = "${google_compute_instance_group.stardog.self_link}"
+  }
+}
+
+resource "google_compute_forwarding_rule" "stardoginternal" {
+  name = "${var.deployment_name}-sd-internal-lb"
+  region = "${var.region}"
+  load_balancing_scheme = "INTERNAL"
+  backend_service = "${google_compute_region_backend_service.stardoginternal.self_link}"
+  ports = ["5821"]
+  network = "${google_compute_network.main.self_link}"
+  subnetwork = "${google_compute_subnetwork.main.self_link}"
+}
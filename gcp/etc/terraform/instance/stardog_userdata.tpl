@@ -0,0 +1,32 @@
+#!/usr/bin/env bash
+
+set -e
+
+date > /tmp/boottime
+
+export STARDOG_HOME=/mnt/data/stardog-home
+${environment_variables}
+mkdir -p /mnt/data
+mountpoint -q /mnt/data || mount /dev/disk/by-id/google-stardog-data /mnt/data
+
+echo '${stardog_conf}' > $STARDOG_HOME/stardog.properties
+MY_IP=`curl -H "Metadata-Flavor: Google" http://metadata.google.internal/computeMetadata/v1/instance/network-interfaces/0/ip`
+sed -i "s/@@LOCAL_IP@@/$MY_IP/" $STARDOG_HOME/stardog.properties
+
+/usr/local/bin/stardog-wait-for-socket 100 ${zk_servers}
+
+set +e
+cnt=0
+rc=1
+while [ $rc -ne 0 ]; do
+	cnt=`expr $cnt + 1`
+	if [ $cnt -gt 10 ]; then
+		exit 1
+	fi
+	sleep 30
+	rm -f /mnt/data/stardog-home/system.lock
+    /usr/local/bin/stardog-admin server start ${server_opts} --home $STARDOG_HOME --port 5821
+    /usr/local/bin/stardog-wait-for-socket 2 localhost:5821
+    rc=$?
+done
+exit 0
@@ -0,0 +1,117 @@
+variable "zk_machine_type" {
+  type = "string"
+  description = "The machine type for zookeeper nodes."
+}
+
+variable "stardog_machine_type" {
+  type = "string"
+  description = "The machine type for stardog."
+}
+
+variable "bastion_machine_type" {
+  type = "string"
+  default = "g1-small"
+  description = "The machine type for the bastion."
+}
+
+variable "zookeeper_size" {
+  type = "string"
+  description = "The number of zookeeper nodes to use (must be odd and greater than 1)"
+}
+
+variable "stardog_size" {
+  type = "string"
+  description = "The number of stardog nodes to use (must be odd and greater than 1)"
+}
+
+variable "baseimage" {
+  type = "string"
+}
+
+variable "project" {
+  type = "string"
+  description = "The Google Cloud project to create things in."
+}
+
+variable "region" {
+  type = "string"
+  description = "The Google Cloud region to create things in."
+}
+
+variable "zone" {
+  type = "string"
+  description = "The Google Cloud zone of the disks and instances."
+}
+
+variable "credentials_path" {
+  type = "string"
+  description = "The path to the service account key file"
+}
+
+variable "public_key_path" {
+  type = "string"
+  description = "The path to the public key"
+}
+
+variable "deployment_name" {
+  type = "string"
+  description = "A string that is unique to this stardog data in a given project"
+}
+
+variable "version" {
+  type = "string"
+  description = "The version of stardog to launch."
+}
+
+variable "internal_network" {
+  type = "string"
+  description = "The internal subnet for the virtual appliance."
+  default = "10.0.0.0/16"
+}
+
+variable "http_subnet" {
+  type = "string"
+  description = "The subnet allowed to reach the stardog load balancer."
+}
+
+variable "health_check_ranges" {
+  type = "list"
+  description = "The ranges that Google Cloud health checks come from."
+  default = ["35.191.0.0/16", "130.211.0.0/22", "209.85.152.0/22", "209.85.204.0/22"]
+}
+
+variable "custom_stardog_properties" {
+  type = "string"
+  description = "Custom entries for stardog.properties"
+  default = ""
+}
+
+variable "custom_properties_data" {
+  type = "string"
+  description = "The custom data to add to the stardog properties"
+  default = ""
+}
+
+variable "environment_variables" {
+  type = "string"
+  description = "The environment variables to inject into the stardog script"
+  default = ""
+}
+
+variable "stardog_start_opts" {
+  type = "string"
+  description = "Options passed to stardog-admin server start"
+  default = ""
+}
+
+variable "root_volume_type" {
+  type = "string"
+  description = "The type of disk to use for the boot disk"
+  default = "pd-standard"
+}
+
+variable "root_volume_size" {
+  type = "string"
+  description = "The size of the boot disk in gigabytes"
+  default = "16"
+}
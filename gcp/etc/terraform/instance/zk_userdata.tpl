@@ -0,0 +1,13 @@
+#!/bin/bash
+
+set -e
+
+date > /tmp/boottime
+
+echo '${zk_conf}' > /usr/local/zookeeper-3.4.9/conf/zoo.cfg
+echo ${index} > /var/zkdata/myid
+
+ln -sf /etc/sv/zkmon /etc/service/
+/usr/local/zookeeper-3.4.9/bin/zkServer.sh start
+
+date >> /tmp/boottime
@@ -0,0 +1,52 @@
+data "template_file" "zk_server" {
+  count = "${var.zookeeper_size}"
+  template = "${file("server-spec.tpl")}"
+  vars {
+    id = "${count.index + 1}"
+    host = "${var.deployment_name}-zk-${count.index}"
+  }
+}
+
+data "template_file" "zk_conf" {
+  template = "${file("zoo.cfg.tpl")}"
+  vars {
+    zk_server_list = "${join("", data.template_file.zk_server.*.rendered)}"
+  }
+}
+
+data "template_file" "zk_userdata" {
+  count = "${var.zookeeper_size}"
+  template = "${file("zk_userdata.tpl")}"
+  vars {
+    index = "${count.index + 1}"
+    zk_conf = "${data.template_file.zk_conf.rendered}"
+  }
+}
+
+resource "google_compute_instance" "zookeeper" {
+  count = "${var.zookeeper_size}"
+  name = "${var.deployment_name}-zk-${count.index}"
+  zone = "${var.zone}"
+  machine_type = "${var.zk_machine_type}"
+  tags = ["${var.deployment_name}-zookeeper"]
+
+  boot_disk {
+    initialize_params {
+      image = "${var.baseimage}"
+    }
+  }
+
+  network_interface {
+    subnetwork = "${google_compute_subnetwork.main.self_link}"
+  }
+
+  metadata {
+    ssh-keys = "ubuntu:${file(var.public_key_path)}"
+  }
+
+  metadata_startup_script = "${element(data.template_file.zk_userdata.*.rendered, count.index)}"
+
+  labels {
+    stardog-virtual-appliance = "${var.deployment_name}"
+  }
+}
@@ -0,0 +1,85 @@
+
+resource "google_compute_network" "builder" {
+  name = "${var.deployment_name}-builder"
+  auto_create_subnetworks = true
+}
+
+resource "google_compute_firewall" "builder" {
+  name = "${var.deployment_name}-builder-ssh"
+  network = "${google_compute_network.builder.name}"
+
+  # allow ssh from anywhere
+  allow {
+    protocol = "tcp"
+    ports = ["22"]
+  }
+  source_ranges = ["0.0.0.0/0"]
+}
+
+resource "google_compute_instance" "stardog_data" {
+  count = "${var.cluster_size}"
+  name = "${var.deployment_name}-builder-${count.index}"
+  zone = "${var.zone}"
+  machine_type = "${var.machine_type}"
+
+  boot_disk {
+    initialize_params {
+      image = "${var.image}"
+    }
+  }
+
+  attached_disk {
+    source = "${element(google_compute_disk.stardog_data.*.self_link, count.index)}"
+    device_name = "stardog-data"
+  }
+
+  network_interface {
+    network = "${google_compute_network.builder.name}"
+    access_config {}
+  }
+
+  metadata {
+    ssh-keys = "ubuntu:${file(var.public_key_path)}"
+  }
+
+  labels {
+    stardog-virtual-appliance = "${var.deployment_name}"
+  }
+
+  depends_on = ["google_compute_firewall.builder"]
+}
+
+resource "null_resource" "stardog_data" {
+  count = "${var.cluster_size}"
+
+  # Settings for SSH connection
+  connection {
+    user = "ubuntu"
+    host = "${element(google_compute_instance.stardog_data.*.network_interface.0.access_config.0.assigned_nat_ip, count.index)}"
+    private_key = "${file(var.key_path)}"
+    agent = false
+    timeout = "10m"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "set -e",
+      "sudo mkfs -t ext4 /dev/disk/by-id/google-stardog-data",
+      "sudo mkdir -p /mnt/data",
+      "sudo mount /dev/disk/by-id/google-stardog-data /mnt/data",
+      "sudo mkdir -p /mnt/data/stardog-home",
+      "sudo chown -R ubuntu /mnt/data/"
+    ]
+  }
+
+  provisioner "file" {
+    source = "${var.stardog_license}"
+    destination = "/mnt/data/stardog-home/stardog-license-key.bin"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "sudo umount /mnt/data/"
+    ]
+  }
+}
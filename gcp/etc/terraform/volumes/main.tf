@@ -0,0 +1,16 @@
+provider "google" {
+  credentials = "${file(var.credentials_path)}"
+  project = "${var.project}"
+  region = "${var.region}"
+}
+
+resource "google_compute_disk" "stardog_data" {
+  count = "${var.cluster_size}"
+  name = "${var.deployment_name}-data-${count.index}"
+  zone = "${var.zone}"
+  size = "${var.storage_size}"
+  type = "${var.disk_type}"
+  labels {
+    stardog-virtual-appliance = "${var.deployment_name}"
+  }
+}
@@ -0,0 +1,3 @@
+output "volumes" {
+  value = ["${google_compute_disk.stardog_data.*.name}"]
+}
@@ -0,0 +1,65 @@
+variable "storage_size" {
+  type = "string"
+  description = "The size of the volume in gigabytes."
+}
+
+variable "cluster_size" {
+  type = "string"
+  description = "The number of stardog nodes to use (must be odd and greater than 1)."
+}
+
+variable "project" {
+  type = "string"
+  description = "The Google Cloud project to create things in."
+}
+
+variable "region" {
+  type = "string"
+  description = "The Google Cloud region to create things in."
+}
+
+variable "zone" {
+  type = "string"
+  description = "The Google Cloud zone of the disks."
+}
+
+variable "credentials_path" {
+  type = "string"
+  description = "The path to the service account key file"
+}
+
+variable "deployment_name" {
+  type = "string"
+  description = "A string that is unique to this stardog data in a given project"
+}
+
+variable "key_path" {
+  type = "string"
+  description = "The path to the private key"
+}
+
+variable "public_key_path" {
+  type = "string"
+  description = "The path to the public key"
+}
+
+variable "image" {
+  type = "string"
+  description = "The image to use for formatting the disks"
+}
+
+variable "machine_type" {
+  type = "string"
+  description = "The machine type for formating the disks"
+}
+
+variable "stardog_license" {
+  type = "string"
+  description = "The path to your stardog license"
+}
+
+variable "disk_type" {
+  type = "string"
+  description = "The persistent disk type"
+  default = "pd-standard"
+}
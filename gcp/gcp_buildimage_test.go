@@ -0,0 +1,149 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestGoodPacker(t *testing.T) {
+	fakePacker := "#!/usr/bin/env bash\necho 1500000000,googlecompute,artifact,0,id,stardog-4-2-1500000000\nexit 0"
+
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	packerFile := path.Join(dir, "packer")
+	err := ioutil.WriteFile(packerFile, []byte(fakePacker), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+
+	newPath := fmt.Sprintf("%s:%s", dir, startPath)
+	err = os.Setenv("PATH", newPath)
+	if err != nil {
+		t.Fatalf("Failed to set env %s", err)
+	}
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+
+	gcpP := newTestPlugin()
+	if gcpP.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should not be there yet")
+	}
+	err = gcpP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
+	if err != nil {
+		t.Fatalf("Packer failed %s", err)
+	}
+	if !gcpP.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should be there")
+	}
+	imageMap, err := loadImageMap(&app)
+	if err != nil {
+		t.Fatalf("The image map should load %s", err)
+	}
+	if imageMap[gcpP.Project] != "stardog-4-2-1500000000" {
+		t.Fatalf("The wrong image was recorded %s", imageMap[gcpP.Project])
+	}
+}
+
+func TestBadRcPacker(t *testing.T) {
+	fakePacker := "#!/usr/bin/env bash\necho 1500000000,googlecompute,artifact,0,id,stardog-4-2-1500000000\nexit 1"
+
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	packerFile := path.Join(dir, "packer")
+	err := ioutil.WriteFile(packerFile, []byte(fakePacker), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+
+	newPath := fmt.Sprintf("%s:%s", dir, startPath)
+	err = os.Setenv("PATH", newPath)
+	if err != nil {
+		t.Fatalf("Failed to set env %s", err)
+	}
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	gcpP := newTestPlugin()
+	err = gcpP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
+	if err == nil {
+		t.Fatalf("Packer should have failed")
+	}
+	if gcpP.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should not be there")
+	}
+}
+
+func TestNoImagePacker(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	exedir, _, err := sdutils.CreateTestExec("packer", "no image here", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	gcpP := newTestPlugin()
+	err = gcpP.BuildImage(context.Background(), &app, "/etc/group", "4.2")
+	if err == nil {
+		t.Fatalf("Packer should have failed to find the image")
+	}
+}
+
+func TestPackerNoCredentials(t *testing.T) {
+	credsSave := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	defer os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credsSave)
+	os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	err := newTestPlugin().BuildImage(context.Background(), &app, "/etc/group", "4.2")
+	if err == nil {
+		t.Fatalf("The build should require credentials")
+	}
+}
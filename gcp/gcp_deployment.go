@@ -0,0 +1,389 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type gcpDeploymentDescription struct {
+	Project            string `json:"project,omitempty"`
+	Zone               string `json:"zone,omitempty"`
+	ImageName          string `json:"image_name,omitempty"`
+	ZkMachineType      string `json:"zk_machine_type,omitempty"`
+	SdMachineType      string `json:"sd_machine_type,omitempty"`
+	BastionMachineType string `json:"bastion_machine_type,omitempty"`
+	DiskType           string `json:"disk_type,omitempty"`
+	CredentialsPath    string `json:"credentials_path,omitempty"`
+	PrivateKeyPath     string `json:"private_key_path,omitempty"`
+	CreatedKey         bool   `json:"created_key,omitempty"`
+	Version            string `json:"-"`
+	Name               string `json:"-"`
+	deployDir          string
+	customPropFile     string
+	environment        []string
+	disableSecurity    bool
+	ctx                sdutils.AppContext
+	plugin             *gcpPlugin
+}
+
+func validDiskType(diskType string) bool {
+	for _, t := range ValidDiskTypes {
+		if t == diskType {
+			return true
+		}
+	}
+	return false
+}
+
+func newGcpDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeployment, p *gcpPlugin) (*gcpDeploymentDescription, error) {
+	var err error
+	createdKey := false
+
+	err = ValidateDeploymentName(baseD.Name)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	if p.Project == "" {
+		p.Project, err = c.Prompt("Google Cloud project", "")
+		if err != nil {
+			return nil, err
+		}
+		if p.Project == "" {
+			return nil, fmt.Errorf("A Google Cloud project is required")
+		}
+	}
+	if !validDiskType(p.DiskType) {
+		return nil, fmt.Errorf("%s is not a valid disk type", p.DiskType)
+	}
+	if p.ImageName == "" {
+		// If the image is not specified look it up
+		imageMap, err := loadImageMap(c)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load the image map: %s", err)
+		}
+		image, ok := imageMap[p.Project]
+		if !ok {
+			c.ConsoleLog(1, "A base image is required for launching the virtual appliance.  If you do not know this value you can build a new one with the 'baseami' command.\n")
+			image, err = c.Prompt("Stardog base image", "")
+			if err != nil {
+				return nil, err
+			}
+			if image == "" {
+				return nil, fmt.Errorf("An image is required.  Please see the 'baseami' subcommand")
+			}
+		}
+		p.ImageName = image
+	}
+
+	// Google Cloud takes the public key in the instance metadata so there is
+	// no key name to look up, only a key pair on disk.
+	if baseD.PrivateKey == "" {
+		privateKeyFilename, _, err := sdutils.GenerateKey(baseD.Directory, baseD.Name+"key")
+		if err != nil {
+			return nil, err
+		}
+		createdKey = true
+		baseD.PrivateKey = privateKeyFilename
+	}
+	fi, err := os.Stat(baseD.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("There was an error accessing the private key %s: %s", baseD.PrivateKey, err)
+	}
+	if fi.Mode()&0077 != 0 {
+		return nil, fmt.Errorf("The permissions on the private key %s must only allow for user access", baseD.PrivateKey)
+	}
+	if !sdutils.PathExists(baseD.PrivateKey + ".pub") {
+		return nil, fmt.Errorf("The public key %s.pub must exist next to the private key", baseD.PrivateKey)
+	}
+
+	deployDir := sdutils.DeploymentDir(c.GetConfigDir(), baseD.Name)
+	assetDir, err := PlaceAsset(c, deployDir, "etc/terraform", false)
+	if err != nil {
+		return nil, err
+	}
+	c.ConsoleLog(2, "Terraform configuration extracted to %s\n", assetDir)
+
+	dd := gcpDeploymentDescription{
+		Project:            p.Project,
+		Zone:               p.Zone,
+		ImageName:          p.ImageName,
+		ZkMachineType:      p.ZkMachineType,
+		SdMachineType:      p.SdMachineType,
+		BastionMachineType: p.BastionMachineType,
+		DiskType:           p.DiskType,
+		CredentialsPath:    creds,
+		PrivateKeyPath:     baseD.PrivateKey,
+		CreatedKey:         createdKey,
+		Version:            baseD.Version,
+		Name:               baseD.Name,
+		ctx:                c,
+		deployDir:          deployDir,
+		customPropFile:     baseD.CustomPropsFile,
+		environment:        baseD.Environment,
+		disableSecurity:    baseD.DisableSecurity,
+		plugin:             p,
+	}
+	return &dd, nil
+}
+
+func (dd *gcpDeploymentDescription) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (dd *gcpDeploymentDescription) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	vm := NewGceDiskManager(dd.ctx, dd)
+	return vm.CreateSet(ctx, licensePath, sizeOfEachVolume, clusterSize)
+}
+
+func (dd *gcpDeploymentDescription) DeleteVolumeSet(ctx context.Context) error {
+	vm := NewGceDiskManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.DeleteSet(ctx)
+}
+
+func (dd *gcpDeploymentDescription) ClusterSize() (int, error) {
+	vm := NewGceDiskManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return -1, fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadGceDisks(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return -1, err
+	}
+	var size int
+	c, err := fmt.Sscanf(vols.ClusterSize, "%d", &size)
+	if err != nil {
+		return -1, err
+	}
+	if c != 1 {
+		return -1, fmt.Errorf("Internal error: the cluster size is not coherent")
+	}
+	return size, nil
+}
+
+func (dd *gcpDeploymentDescription) StatusVolumeSet(ctx context.Context) error {
+	vm := NewGceDiskManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.Status(ctx)
+}
+
+func (dd *gcpDeploymentDescription) VolumeExists() bool {
+	vm := NewGceDiskManager(dd.ctx, dd)
+	return vm.VolumeExists()
+}
+
+func (dd *gcpDeploymentDescription) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	im, err := NewGceInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.CreateInstance(ctx, volumeSize, zookeeperSize, idleTimeout)
+}
+
+func (dd *gcpDeploymentDescription) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	im, err := NewGceInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.OpenInstance(ctx, volumeSize, zookeeperSize, mask, idleTimeout)
+}
+
+func (dd *gcpDeploymentDescription) DeleteInstance(ctx context.Context) error {
+	im, err := NewGceInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.DeleteInstance(ctx)
+}
+
+func (dd *gcpDeploymentDescription) StatusInstance(ctx context.Context) error {
+	im, err := NewGceInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.Status(ctx)
+}
+
+func (dd *gcpDeploymentDescription) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	vm := NewGceDiskManager(dd.ctx, dd)
+	volumeStatus, err := vm.getStatusInformation(ctx)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No volume information found %s\n", err)
+	}
+
+	im, err := NewGceInstance(dd.ctx, dd)
+	if err != nil {
+		return nil, err
+	}
+	instS, err := getInstanceValues(ctx, im)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No instance information found.\n")
+	}
+
+	sD := sdutils.StardogDescription{
+		SSHHost:             im.BastionContact,
+		SSHUser:             "ubuntu",
+		StardogURL:          fmt.Sprintf("http://%s:5821", im.StardogContact),
+		StardogInternalURL:  fmt.Sprintf("http://%s:5821", im.StardogInternalContact),
+		VolumeDescription:   volumeStatus,
+		InstanceDescription: instS,
+		TimeStamp:           time.Now(),
+	}
+	return &sD, nil
+}
+
+func (dd *gcpDeploymentDescription) InstanceExists() bool {
+	im, err := NewGceInstance(dd.ctx, dd)
+	if err != nil {
+		return false
+	}
+	return im.InstanceExists()
+}
+
+type gcpPlugin struct {
+	Project            string `json:"project,omitempty"`
+	Zone               string `json:"zone,omitempty"`
+	ImageName          string `json:"image_name,omitempty"`
+	ZkMachineType      string `json:"zk_machine_type,omitempty"`
+	SdMachineType      string `json:"sd_machine_type,omitempty"`
+	BastionMachineType string `json:"bastion_machine_type,omitempty"`
+	DiskType           string `json:"disk_type,omitempty"`
+}
+
+// GetPlugin returns the plugin interface that this module represents.
+func GetPlugin() sdutils.Plugin {
+	return &gcpPlugin{
+		Project:            os.Getenv("CLOUDSDK_CORE_PROJECT"),
+		Zone:               "us-central1-a",
+		ZkMachineType:      "g1-small",
+		SdMachineType:      "n1-standard-2",
+		BastionMachineType: "g1-small",
+		DiskType:           "pd-standard",
+	}
+}
+
+func (p *gcpPlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	b, err := json.Marshal(defaultCliOpts)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *gcpPlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	zoneHelp := fmt.Sprintf("The Google Cloud zone to use [%s].", strings.Join(ValidZones, " | "))
+	diskHelp := fmt.Sprintf("The persistent disk type to use [%s].", strings.Join(ValidDiskTypes, " | "))
+
+	cmdOpts.BuildCmd.Flag("gcp-project", "The Google Cloud project to use.").Default(p.Project).StringVar(&p.Project)
+	cmdOpts.BuildCmd.Flag("gcp-zone", zoneHelp).Default(p.Zone).StringVar(&p.Zone)
+
+	cmdOpts.NewVolumesCmd.Flag("gcp-disk-type", diskHelp).Default(p.DiskType).StringVar(&p.DiskType)
+
+	cmdOpts.LaunchCmd.Flag("gcp-project", "The Google Cloud project to use.").Default(p.Project).StringVar(&p.Project)
+	cmdOpts.LaunchCmd.Flag("gcp-zone", zoneHelp).Default(p.Zone).StringVar(&p.Zone)
+	cmdOpts.LaunchCmd.Flag("gcp-image", "The Google Cloud image to boot.").Default(p.ImageName).StringVar(&p.ImageName)
+	cmdOpts.LaunchCmd.Flag("gcp-zk-machine-type", "The machine type to use for zookeeper VMs.").Default(p.ZkMachineType).StringVar(&p.ZkMachineType)
+	cmdOpts.LaunchCmd.Flag("gcp-sd-machine-type", "The machine type to use for stardog VMs.").Default(p.SdMachineType).StringVar(&p.SdMachineType)
+	cmdOpts.LaunchCmd.Flag("gcp-disk-type", diskHelp).Default(p.DiskType).StringVar(&p.DiskType)
+
+	cmdOpts.LeaksCmd.Flag("gcp-project", "The Google Cloud project to search.").Default(p.Project).StringVar(&p.Project)
+
+	cmdOpts.NewDeploymentCmd.Flag("gcp-project", "The Google Cloud project to use.").Default(p.Project).StringVar(&p.Project)
+	cmdOpts.NewDeploymentCmd.Flag("gcp-zone", zoneHelp).Default(p.Zone).StringVar(&p.Zone)
+	cmdOpts.NewDeploymentCmd.Flag("gcp-image", "The Google Cloud image to boot.").Default(p.ImageName).StringVar(&p.ImageName)
+	cmdOpts.NewDeploymentCmd.Flag("gcp-zk-machine-type", "The machine type to use for zookeeper VMs.").Default(p.ZkMachineType).StringVar(&p.ZkMachineType)
+	cmdOpts.NewDeploymentCmd.Flag("gcp-sd-machine-type", "The machine type to use for stardog VMs.").Default(p.SdMachineType).StringVar(&p.SdMachineType)
+
+	return nil
+}
+
+func (p *gcpPlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	neededPgms := []string{"terraform", "packer"}
+	for _, e := range neededPgms {
+		_, err := exec.LookPath(e)
+		if err != nil {
+			return nil, fmt.Errorf("The program %s must be in the path when running this program", e)
+		}
+	}
+
+	if new {
+		gcpDD, err := newGcpDeploymentDescription(context, baseD, p)
+		if err != nil {
+			return nil, err
+		}
+		baseD.CloudOpts = gcpDD
+		data, err := json.Marshal(baseD)
+		if err != nil {
+			return nil, err
+		}
+		confPath := path.Join(gcpDD.deployDir, "config.json")
+		err = ioutil.WriteFile(confPath, data, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return gcpDD, nil
+	}
+	data, err := json.Marshal(baseD.CloudOpts)
+	if err != nil {
+		return nil, err
+	}
+	var dd gcpDeploymentDescription
+	err = json.Unmarshal(data, &dd)
+	if err != nil {
+		return nil, err
+	}
+	dd.Name = baseD.Name
+	dd.Version = baseD.Version
+	dd.ctx = context
+	dd.deployDir = sdutils.DeploymentDir(context.GetConfigDir(), baseD.Name)
+	dd.customPropFile = baseD.CustomPropsFile
+	dd.environment = baseD.Environment
+	dd.disableSecurity = baseD.DisableSecurity
+	dd.plugin = p
+
+	return &dd, nil
+}
+
+func (p *gcpPlugin) GetName() string {
+	return "gcp"
+}
+
+func (p *gcpPlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true, Firewall: true}
+}
@@ -0,0 +1,228 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func newTestPlugin() *gcpPlugin {
+	return &gcpPlugin{
+		Project:       "graviton-test",
+		Zone:          "us-central1-a",
+		ImageName:     "stardog-4-2-1500000000",
+		ZkMachineType: "g1-small",
+		SdMachineType: "n1-standard-2",
+		DiskType:      "pd-standard",
+	}
+}
+
+// setTestCredentials points GOOGLE_APPLICATION_CREDENTIALS at a fake key file
+// and returns a function which restores the previous value.
+func setTestCredentials(dir string) func() {
+	credsSave := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	credsFile := path.Join(dir, "account.json")
+	ioutil.WriteFile(credsFile, []byte("{}"), 0600)
+	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credsFile)
+	return func() { os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credsSave) }
+}
+
+func TestDeploymentLoadDefaults(t *testing.T) {
+	plugin := newTestPlugin()
+	i := make(map[string]string)
+	i["project"] = "otherproject"
+	i["zone"] = "europe-west1-b"
+	i["image_name"] = "someimage"
+	i["zk_machine_type"] = "zkinst"
+	i["sd_machine_type"] = "sdinst"
+
+	err := plugin.LoadDefaults(&i)
+	if err != nil {
+		t.Fatalf("Failed to load defaults %s", err)
+	}
+	if plugin.Project != i["project"] {
+		t.Fatalf("project not set right")
+	}
+	if plugin.Zone != i["zone"] {
+		t.Fatalf("zone not set right")
+	}
+	if plugin.ImageName != i["image_name"] {
+		t.Fatalf("image_name not set right")
+	}
+	if plugin.ZkMachineType != i["zk_machine_type"] {
+		t.Fatalf("zk_machine_type not set right")
+	}
+	if plugin.SdMachineType != i["sd_machine_type"] {
+		t.Fatalf("sd_machine_type not set right")
+	}
+}
+
+func TestDeploymentNames(t *testing.T) {
+	good := []string{"testdep", "a", "my-cluster-1"}
+	for _, n := range good {
+		if err := ValidateDeploymentName(n); err != nil {
+			t.Fatalf("%s should be a valid name: %s", n, err)
+		}
+	}
+	bad := []string{"", "TestDep", "1dep", "dep-", "my_dep", "averyveryveryveryveryveryverylongdeploymentname"}
+	for _, n := range bad {
+		if err := ValidateDeploymentName(n); err == nil {
+			t.Fatalf("%s should not be a valid name", n)
+		}
+	}
+	if regionFromZone("us-central1-a") != "us-central1" {
+		t.Fatalf("The region was not found")
+	}
+}
+
+func TestDeploymentLoadNoCredentials(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+
+	credsSave := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	defer os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credsSave)
+	os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	for _, pgm := range []string{"terraform", "packer"} {
+		exedir, _, err := sdutils.CreateTestExec(pgm, "", 0)
+		if err != nil {
+			t.Fatalf("Failed to write the file %s", err)
+		}
+		defer os.RemoveAll(exedir)
+	}
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	plugin := newTestPlugin()
+	baseD := sdutils.BaseDeployment{
+		Type:      plugin.GetName(),
+		Name:      "testdep",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	_, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The deployment should have failed without credentials")
+	}
+}
+
+func TestDeploymentLoadNoExes(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	os.Setenv("PATH", dir)
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	plugin := newTestPlugin()
+	baseD := sdutils.BaseDeployment{
+		Type:      plugin.GetName(),
+		Name:      "testdep",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	_, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The deployment should have failed without terraform")
+	}
+}
+
+func TestDeploymentLoadGeneratesKey(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	for _, pgm := range []string{"terraform", "packer"} {
+		exedir, _, err := sdutils.CreateTestExec(pgm, "", 0)
+		if err != nil {
+			t.Fatalf("Failed to write the file %s", err)
+		}
+		defer os.RemoveAll(exedir)
+	}
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	plugin := newTestPlugin()
+	baseD := sdutils.BaseDeployment{
+		Type:      plugin.GetName(),
+		Name:      "testdep",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("The deployment should have loaded %s", err)
+	}
+	dd := dep.(*gcpDeploymentDescription)
+	if !dd.CreatedKey || baseD.PrivateKey == "" {
+		t.Fatalf("A key should have been created")
+	}
+	if !sdutils.PathExists(baseD.PrivateKey + ".pub") {
+		t.Fatalf("The public key should exist")
+	}
+	if dd.CredentialsPath != os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") {
+		t.Fatalf("The credentials path was not recorded")
+	}
+
+	loaded, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, false)
+	if err != nil {
+		t.Fatalf("The deployment should have reloaded %s", err)
+	}
+	ldd := loaded.(*gcpDeploymentDescription)
+	if ldd.Project != dd.Project || ldd.ImageName != dd.ImageName || ldd.PrivateKeyPath != dd.PrivateKeyPath {
+		t.Fatalf("The deployment was not reloaded properly")
+	}
+}
+
+func TestDeploymentBadName(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:      "gcp",
+		Name:      "Bad_Name",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	_, err := newGcpDeploymentDescription(&app, &baseD, newTestPlugin())
+	if err == nil {
+		t.Fatalf("The deployment name should have been rejected")
+	}
+}
@@ -0,0 +1,285 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// GceInstance represents an instance of a Stardog service in Google Cloud.
+type GceInstance struct {
+	DeploymentName         string             `json:"deployment_name,omitempty"`
+	Project                string             `json:"project,omitempty"`
+	Region                 string             `json:"region,omitempty"`
+	Zone                   string             `json:"zone,omitempty"`
+	CredentialsPath        string             `json:"credentials_path,omitempty"`
+	PublicKeyPath          string             `json:"public_key_path,omitempty"`
+	Version                string             `json:"version,omitempty"`
+	ZkMachineType          string             `json:"zk_machine_type,omitempty"`
+	SdMachineType          string             `json:"stardog_machine_type,omitempty"`
+	BastionMachineType     string             `json:"bastion_machine_type,omitempty"`
+	ZkSize                 string             `json:"zookeeper_size,omitempty"`
+	SdSize                 string             `json:"stardog_size,omitempty"`
+	ImageName              string             `json:"baseimage,omitempty"`
+	HTTPMask               string             `json:"http_subnet,omitempty"`
+	CustomPropsData        string             `json:"custom_properties_data,omitempty"`
+	Environment            string             `json:"environment_variables,omitempty"`
+	StartOpts              string             `json:"stardog_start_opts,omitempty"`
+	RootVolumeSize         int                `json:"root_volume_size"`
+	RootVolumeType         string             `json:"root_volume_type"`
+	DeployDir              string             `json:"-"`
+	Ctx                    sdutils.AppContext `json:"-"`
+	BastionContact         string             `json:"-"`
+	StardogContact         string             `json:"-"`
+	StardogInternalContact string             `json:"-"`
+	ZkNodesContact         []string           `json:"-"`
+}
+
+// InstanceStatusDescription describes details about a running Stardog instance.
+// The zookeeper contact strings are described.
+type InstanceStatusDescription struct {
+	ZkNodesContact []string
+}
+
+// OutputEntry allows the plugin to return opaque information and mark it as sensitive
+// or not.  If it is sensitive the base code knows not to print it out or write it to a
+// log.
+type OutputEntry struct {
+	Sensitive bool        `json:"sensitive,omitempty"`
+	Type      string      `json:"type,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+}
+
+// NewGceInstance instanciates a GceInstance object which will be used to boot or
+// inspect a Stardog deployment in Google Cloud.
+func NewGceInstance(ctx sdutils.AppContext, dd *gcpDeploymentDescription) (*GceInstance, error) {
+	customData := ""
+	if dd.customPropFile != "" {
+		data, err := ioutil.ReadFile(dd.customPropFile)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid custom properties file: %s", err)
+		}
+		customData = string(data)
+	}
+
+	var envBuffer bytes.Buffer
+	for _, env := range dd.environment {
+		envBuffer.WriteString(fmt.Sprintf("export %s\n", env))
+	}
+	instance := GceInstance{
+		DeploymentName:     dd.Name,
+		Project:            dd.Project,
+		Region:             regionFromZone(dd.Zone),
+		Zone:               dd.Zone,
+		CredentialsPath:    dd.CredentialsPath,
+		PublicKeyPath:      dd.PrivateKeyPath + ".pub",
+		Version:            dd.Version,
+		ZkMachineType:      dd.ZkMachineType,
+		SdMachineType:      dd.SdMachineType,
+		BastionMachineType: dd.BastionMachineType,
+		ImageName:          dd.ImageName,
+		DeployDir:          dd.deployDir,
+		Ctx:                ctx,
+		CustomPropsData:    customData,
+		Environment:        envBuffer.String(),
+	}
+	if dd.disableSecurity {
+		instance.StartOpts = "--disable-security"
+	}
+	return &instance, nil
+}
+
+func (gceI *GceInstance) workingDir() string {
+	return path.Join(gceI.DeployDir, "etc", "terraform", "instance")
+}
+
+func (gceI *GceInstance) confPath() string {
+	return path.Join(gceI.workingDir(), "instance.json")
+}
+
+func (gceI *GceInstance) runTerraformApply(ctx context.Context, volumeSize int, zookeeperSize int, mask string, message string) error {
+	gceI.ZkSize = fmt.Sprintf("%d", zookeeperSize)
+
+	vol, err := LoadGceDisks(gceI.Ctx, path.Join(gceI.DeployDir, "etc", "terraform", "volumes"))
+	if err != nil {
+		return err
+	}
+
+	gceI.SdSize = vol.ClusterSize
+	gceI.HTTPMask = mask
+	gceI.RootVolumeType = "pd-standard"
+	gceI.RootVolumeSize = volumeSize
+
+	if sdutils.PathExists(gceI.confPath()) && mask == "" {
+		gceI.Ctx.ConsoleLog(1, "The instance already exists.\n")
+		gceI.Ctx.Logf(sdutils.INFO, "The instance already exists.")
+	}
+	err = sdutils.WriteJSON(gceI, gceI.confPath())
+	if err != nil {
+		return err
+	}
+
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+
+	cmdArray := []string{terraformPath, "apply", "-var-file",
+		gceI.confPath()}
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  gceI.workingDir(),
+	}
+	gceI.Ctx.Logf(sdutils.INFO, "Running terraform...\n")
+	spin := sdutils.NewSpinner(gceI.Ctx, 1, message)
+	_, err = sdutils.RunCommand(ctx, gceI.Ctx, cmd, nil, spin)
+	return err
+}
+
+// CreateInstance will boot up a Stardog service in Google Cloud.  The network
+// load balancer has no idle timeout so that value is ignored.
+func (gceI *GceInstance) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	err := gceI.runTerraformApply(ctx, volumeSize, zookeeperSize, "0.0.0.0/32", "Creating the instance VMs...")
+	if err != nil {
+		gceI.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+		return err
+	}
+	gceI.Ctx.ConsoleLog(1, "Successfully created the instance.\n")
+	return nil
+}
+
+// OpenInstance will open the firewall to allow incoming traffic to port 5821 from
+// the give CIDR.
+func (gceI *GceInstance) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	err := gceI.runTerraformApply(ctx, volumeSize, zookeeperSize, mask, "Opening the firewall...")
+	if err != nil {
+		gceI.Ctx.ConsoleLog(1, "Failed to open up the instance.\n")
+		return err
+	}
+	gceI.Ctx.ConsoleLog(1, "Successfully opened up the instance.\n")
+	return nil
+}
+
+// DeleteInstance will teardown the Stardog service.
+func (gceI *GceInstance) DeleteInstance(ctx context.Context) error {
+	if !gceI.InstanceExists() {
+		return fmt.Errorf("There is no configured instance")
+	}
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return err
+	}
+	cmdArray := []string{terraformPath, "destroy", "-force", "-var-file", gceI.confPath()}
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+		Dir:  gceI.workingDir(),
+	}
+	gceI.Ctx.Logf(sdutils.INFO, "Running terraform...\n")
+	spin := sdutils.NewSpinner(gceI.Ctx, 1, "Deleting the instance VMs")
+	_, err = sdutils.RunCommand(ctx, gceI.Ctx, cmd, nil, spin)
+	if err != nil {
+		return err
+	}
+	os.Remove(gceI.confPath())
+	gceI.Ctx.ConsoleLog(1, "Successfully destroyed the instance.\n")
+	return nil
+}
+
+// InstanceExists will return a bool if the associated GceInstance has already been
+// created.
+func (gceI *GceInstance) InstanceExists() bool {
+	return sdutils.PathExists(gceI.confPath())
+}
+
+func outputString(try map[string]OutputEntry, key string) (string, error) {
+	s, ok := try[key].Value.(string)
+	if !ok {
+		return "", fmt.Errorf("The terraform output is missing %s", key)
+	}
+	return s, nil
+}
+
+func getInstanceValues(ctx context.Context, gceI *GceInstance) (*InstanceStatusDescription, error) {
+	if !gceI.InstanceExists() {
+		return nil, fmt.Errorf("There is no configured instance")
+	}
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return nil, err
+	}
+	cmdArray := []string{terraformPath, "output", "-json"}
+	cmd := exec.CommandContext(ctx, cmdArray[0], cmdArray[1:]...)
+	cmd.Dir = gceI.workingDir()
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	try := make(map[string]OutputEntry)
+	err = json.Unmarshal(data, &try)
+	if err != nil {
+		return nil, err
+	}
+
+	gceI.StardogInternalContact, err = outputString(try, "stardog_internal_contact")
+	if err != nil {
+		return nil, err
+	}
+	gceI.StardogContact, err = outputString(try, "stardog_contact")
+	if err != nil {
+		return nil, err
+	}
+	gceI.BastionContact, err = outputString(try, "bastion_contact")
+	if err != nil {
+		return nil, err
+	}
+	interList, ok := try["zookeeper_nodes"].Value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("The terraform output is missing zookeeper_nodes")
+	}
+	gceI.ZkNodesContact = make([]string, len(interList), len(interList))
+	for ndx, x := range interList {
+		gceI.ZkNodesContact[ndx] = fmt.Sprintf("%v", x)
+	}
+
+	s := InstanceStatusDescription{
+		ZkNodesContact: gceI.ZkNodesContact,
+	}
+	return &s, nil
+}
+
+// Status will print the status of the Google Cloud instance.
+func (gceI *GceInstance) Status(ctx context.Context) error {
+	_, err := getInstanceValues(ctx, gceI)
+	if err != nil {
+		return err
+	}
+
+	gceI.Ctx.ConsoleLog(1, "Stardog: %s\n", fmt.Sprintf("http://%s:5821", gceI.StardogContact))
+	gceI.Ctx.ConsoleLog(1, "SSH: %s\n", gceI.BastionContact)
+	return nil
+}
@@ -0,0 +1,173 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestInstanceNotThere(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	app, dd := newTestDeployment(t, dir)
+	inst, err := NewGceInstance(app, dd)
+	if err != nil {
+		t.Fatalf("Failed to make the instance %s", err)
+	}
+	if inst.InstanceExists() {
+		t.Fatalf("The instance should not exist")
+	}
+	err = inst.DeleteInstance(context.Background())
+	if err == nil {
+		t.Fatalf("The instance should not exist for deletion")
+	}
+	err = inst.Status(context.Background())
+	if err == nil {
+		t.Fatalf("The instance should not exist for status")
+	}
+	// No volumes have been created
+	err = inst.CreateInstance(context.Background(), 16, 3, 60)
+	if err == nil {
+		t.Fatalf("The instance should need volumes")
+	}
+	err = inst.OpenInstance(context.Background(), 16, 3, "0.0.0.0/0", 60)
+	if err == nil {
+		t.Fatalf("The instance should need volumes")
+	}
+}
+
+func TestInstanceFakeTerraform(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+
+	app, dd := newTestDeployment(t, dir)
+	exedir, _, err := sdutils.CreateTestExec("terraform", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	err = dd.CreateVolumeSet(context.Background(), "/path/", 1, 3)
+	if err != nil {
+		t.Fatalf("The volumes should have been created %s", err)
+	}
+
+	err = dd.CreateInstance(context.Background(), 16, 3, 60)
+	if err != nil {
+		t.Fatalf("The instance should have been created %s", err)
+	}
+	if !dd.InstanceExists() {
+		t.Fatalf("The instance should exist")
+	}
+	params, _ := ioutil.ReadFile(path.Join(exedir, "params"))
+	if !strings.HasPrefix(string(params), "apply -var-file") {
+		t.Fatalf("terraform apply should have been called %s", params)
+	}
+
+	inst, _ := NewGceInstance(app, dd)
+	err = sdutils.LoadJSON(inst, inst.confPath())
+	if err != nil {
+		t.Fatalf("The instance config should load %s", err)
+	}
+	if inst.HTTPMask != "0.0.0.0/32" || inst.SdSize != "3" || inst.ZkSize != "3" {
+		t.Fatalf("The instance should be created closed %s %s %s", inst.HTTPMask, inst.SdSize, inst.ZkSize)
+	}
+
+	err = dd.OpenInstance(context.Background(), 16, 3, "10.0.0.0/8", 60)
+	if err != nil {
+		t.Fatalf("The instance should have been opened %s", err)
+	}
+	sdutils.LoadJSON(inst, inst.confPath())
+	if inst.HTTPMask != "10.0.0.0/8" {
+		t.Fatalf("The mask was not applied %s", inst.HTTPMask)
+	}
+
+	// Bad terraform output
+	err = dd.StatusInstance(context.Background())
+	if err == nil {
+		t.Fatalf("The status should have failed on bad output")
+	}
+
+	data := `{
+    "bastion_contact": {
+        "sensitive": false,
+        "type": "string",
+        "value": "35.1.2.3"
+    },
+    "stardog_contact": {
+        "sensitive": false,
+        "type": "string",
+        "value": "35.1.2.4"
+    },
+    "stardog_internal_contact": {
+        "sensitive": false,
+        "type": "string",
+        "value": "10.0.0.9"
+    },
+    "zookeeper_nodes": {
+        "sensitive": false,
+        "type": "list",
+        "value": [
+            "testdep-zk-0",
+            "testdep-zk-1",
+            "testdep-zk-2"
+        ]
+    }
+}`
+	exedir2, _, err := sdutils.CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir2)
+
+	err = dd.StatusInstance(context.Background())
+	if err != nil {
+		t.Fatalf("The status should have worked %s", err)
+	}
+	sd, err := dd.FullStatus(context.Background())
+	if err != nil {
+		t.Fatalf("The full status should have worked %s", err)
+	}
+	if sd.StardogURL != "http://35.1.2.4:5821" || sd.StardogInternalURL != "http://10.0.0.9:5821" {
+		t.Fatalf("The urls are wrong %s %s", sd.StardogURL, sd.StardogInternalURL)
+	}
+	if sd.SSHHost != "35.1.2.3" || sd.SSHUser != "ubuntu" {
+		t.Fatalf("The ssh information is wrong %s %s", sd.SSHHost, sd.SSHUser)
+	}
+	if len(sd.InstanceDescription.(*InstanceStatusDescription).ZkNodesContact) != 3 {
+		t.Fatalf("There should be 3 zookeeper nodes")
+	}
+
+	err = dd.DeleteInstance(context.Background())
+	if err != nil {
+		t.Fatalf("The delete should have worked %s", err)
+	}
+	if dd.InstanceExists() {
+		t.Fatalf("The instance should be gone")
+	}
+}
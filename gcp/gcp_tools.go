@@ -0,0 +1,306 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+const (
+	// LabelKey is the label put on every Google Cloud resource that supports
+	// labels.  Label keys must be lower case so the aws tag name is not used.
+	LabelKey = "stardog-virtual-appliance"
+)
+
+var (
+	// ValidZones is the list of zones that are supported by this plugin
+	ValidZones = []string{
+		"us-central1-a", "us-central1-b", "us-central1-c", "us-central1-f",
+		"us-east1-b", "us-east1-c", "us-east1-d",
+		"us-west1-a", "us-west1-b", "us-west1-c",
+		"europe-west1-b", "europe-west1-c", "europe-west1-d",
+		"asia-east1-a", "asia-east1-b", "asia-east1-c",
+	}
+	// ValidDiskTypes is the list of persistent disk types that are supported
+	ValidDiskTypes = []string{"pd-standard", "pd-ssd"}
+
+	// Resource names in Google Cloud are limited to 63 characters and the
+	// deployment name is used as a prefix for all of them.
+	deploymentNameRegex = regexp.MustCompile("^[a-z]([-a-z0-9]{0,40}[a-z0-9])?$")
+)
+
+// gceKind describes one type of Google Cloud resource that graviton creates.
+// The scope is the gcloud flag used to locate the resource, either zone,
+// region or empty for global resources.
+type gceKind struct {
+	Desc    string
+	Command []string
+	Scope   string
+	Labeled bool
+}
+
+// The kinds are listed in the order in which they must be destroyed.
+var leakKinds = []gceKind{
+	{Desc: "forwarding rules", Command: []string{"compute", "forwarding-rules"}, Scope: "region"},
+	{Desc: "target pools", Command: []string{"compute", "target-pools"}, Scope: "region"},
+	{Desc: "backend services", Command: []string{"compute", "backend-services"}, Scope: "region"},
+	{Desc: "http health checks", Command: []string{"compute", "http-health-checks"}},
+	{Desc: "health checks", Command: []string{"compute", "health-checks"}},
+	{Desc: "instance groups", Command: []string{"compute", "instance-groups", "unmanaged"}, Scope: "zone"},
+	{Desc: "instances", Command: []string{"compute", "instances"}, Scope: "zone", Labeled: true},
+	{Desc: "firewall rules", Command: []string{"compute", "firewall-rules"}},
+	{Desc: "subnetworks", Command: []string{"compute", "networks", "subnets"}, Scope: "region"},
+	{Desc: "networks", Command: []string{"compute", "networks"}},
+}
+
+type gceResource struct {
+	Name     string
+	Location string
+}
+
+// ValidateDeploymentName checks that the name can be used as a prefix for
+// Google Cloud resource names and as a label value.
+func ValidateDeploymentName(name string) error {
+	if !deploymentNameRegex.MatchString(name) {
+		return fmt.Errorf("The deployment name %s is not valid for Google Cloud.  It must start with a lower case letter and may only contain lower case letters, numbers and dashes", name)
+	}
+	return nil
+}
+
+func regionFromZone(zone string) string {
+	ndx := strings.LastIndex(zone, "-")
+	if ndx < 1 {
+		return zone
+	}
+	return zone[:ndx]
+}
+
+func credentialsPath() (string, error) {
+	creds := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if creds == "" {
+		return "", fmt.Errorf("The environment variable GOOGLE_APPLICATION_CREDENTIALS must be set")
+	}
+	return creds, nil
+}
+
+func runGcloud(ctx context.Context, c sdutils.AppContext, project string, args ...string) (string, error) {
+	gcloudPath, err := exec.LookPath("gcloud")
+	if err != nil {
+		return "", err
+	}
+	cmdArray := append([]string{gcloudPath}, args...)
+	cmdArray = append(cmdArray, "--project", project, "--quiet")
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmdArray[0], cmdArray[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	c.Logf(sdutils.DEBUG, "Running %s", strings.Join(cmdArray, " "))
+	err = cmd.Run()
+	if err != nil {
+		c.Logf(sdutils.WARN, "gcloud failed: %s %s", err, stderr.String())
+		return "", fmt.Errorf("gcloud failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (k *gceKind) format() string {
+	fields := []string{"name"}
+	if k.Scope != "" {
+		fields = append(fields, fmt.Sprintf("%s.basename()", k.Scope))
+	}
+	if k.Labeled {
+		fields = append(fields, fmt.Sprintf("labels.%s", LabelKey))
+	}
+	return fmt.Sprintf("value(%s)", strings.Join(fields, ","))
+}
+
+// list returns the resources of this kind that belong to the deployment.
+// Labeled resources are found by their label and the deployment names seen
+// are recorded.  The rest are found by the name prefix of each deployment.
+func (k *gceKind) list(ctx context.Context, c sdutils.AppContext, project string, deploymentName string, possibleDeployNames map[string]bool) []gceResource {
+	filters := []string{}
+	if k.Labeled {
+		if deploymentName == "" {
+			filters = append(filters, fmt.Sprintf("labels.%s:*", LabelKey))
+		} else {
+			filters = append(filters, fmt.Sprintf("labels.%s=%s", LabelKey, deploymentName))
+		}
+	} else {
+		for name := range possibleDeployNames {
+			filters = append(filters, fmt.Sprintf("name~^%s-", name))
+		}
+	}
+
+	resList := []gceResource{}
+	for _, filter := range filters {
+		args := append([]string{}, k.Command...)
+		args = append(args, "list", "--filter", filter, "--format", k.format())
+		out, err := runGcloud(ctx, c, project, args...)
+		if err != nil {
+			c.ConsoleLog(1, "Failed to get the %s: %s\n", k.Desc, err)
+			continue
+		}
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			r := gceResource{Name: fields[0]}
+			ndx := 1
+			if k.Scope != "" && len(fields) > ndx {
+				r.Location = fields[ndx]
+				ndx++
+			}
+			if k.Labeled && len(fields) > ndx {
+				possibleDeployNames[fields[ndx]] = true
+			}
+			c.Logf(sdutils.DEBUG, "Found %s %s", k.Desc, r.Name)
+			resList = append(resList, r)
+		}
+	}
+	return resList
+}
+
+func (k *gceKind) destroy(ctx context.Context, c sdutils.AppContext, project string, resList []gceResource) {
+	for _, r := range resList {
+		c.ConsoleLog(2, "Destroying %s\n", r.Name)
+		args := append([]string{}, k.Command...)
+		args = append(args, "delete", r.Name)
+		if k.Scope != "" {
+			args = append(args, fmt.Sprintf("--%s", k.Scope), r.Location)
+		}
+		_, err := runGcloud(ctx, c, project, args...)
+		if err != nil {
+			c.Logf(sdutils.WARN, "Failed to delete the %s %s, %s", k.Desc, r.Name, err)
+			c.ConsoleLog(1, "Failed to delete the %s %s, %s\n", k.Desc, r.Name, err)
+		}
+	}
+}
+
+func (p *gcpPlugin) FindLeaks(ctx context.Context, c sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	if p.Project == "" {
+		return fmt.Errorf("A Google Cloud project is required to look for leaks")
+	}
+	possibleDeployNames := make(map[string]bool)
+	if deploymentName != "" {
+		possibleDeployNames[deploymentName] = true
+	}
+
+	c.ConsoleLog(1, "Looking for Google Cloud resources\n")
+
+	// Labeled resources are listed first so that their deployment names
+	// can be used to find the resources that cannot be labeled.
+	found := make([][]gceResource, len(leakKinds))
+	for i := range leakKinds {
+		if leakKinds[i].Labeled {
+			found[i] = leakKinds[i].list(ctx, c, p.Project, deploymentName, possibleDeployNames)
+		}
+	}
+	for i := range leakKinds {
+		if !leakKinds[i].Labeled {
+			found[i] = leakKinds[i].list(ctx, c, p.Project, deploymentName, possibleDeployNames)
+		}
+	}
+
+	for i := range leakKinds {
+		c.ConsoleLog(1, "Found %d %s\n", len(found[i]), leakKinds[i].Desc)
+		for _, r := range found[i] {
+			c.ConsoleLog(1, "\t%s\n", r.Name)
+		}
+	}
+
+	if !destroy {
+		return nil
+	}
+	if !force {
+		if !sdutils.AskYesOrNo(c, "Would you like to destroy these resources?") {
+			return nil
+		}
+	}
+	for i := range leakKinds {
+		leakKinds[i].destroy(ctx, c, p.Project, found[i])
+	}
+	return nil
+}
+
+func imageFileName(cliContext sdutils.AppContext) string {
+	return path.Join(cliContext.GetConfigDir(), fmt.Sprintf("gce-images-%s.json", cliContext.GetVersion()))
+}
+
+func loadImageMap(cliContext sdutils.AppContext) (map[string]string, error) {
+	imageMap := make(map[string]string)
+	imageMapFile := imageFileName(cliContext)
+	cliContext.Logf(sdutils.DEBUG, "Loading the image file %s\n", imageMapFile)
+	if _, err := os.Stat(imageMapFile); err == nil {
+		data, err := ioutil.ReadFile(imageMapFile)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, &imageMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cliContext.Logf(sdutils.DEBUG, "Got the image map %s\n", imageMap)
+	return imageMap, nil
+}
+
+func saveImageMap(cliContext sdutils.AppContext, imageMap map[string]string) error {
+	data, err := json.Marshal(&imageMap)
+	if err != nil {
+		return err
+	}
+	imageMapFile := imageFileName(cliContext)
+	cliContext.Logf(sdutils.DEBUG, "Saving the image file %s\n", imageMapFile)
+	return ioutil.WriteFile(imageMapFile, data, 0600)
+}
+
+// PlaceAsset will write data that was compiled in with go-bindata to a file.
+func PlaceAsset(cliContext sdutils.AppContext, dir string, assentName string, temp bool) (string, error) {
+	var err error
+	if temp {
+		dir, err = ioutil.TempDir(dir, "stardog")
+		if err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			err = os.MkdirAll(dir, 0755)
+			if err != nil {
+				cliContext.ConsoleLog(0, "ERROR %s\n", err.Error())
+				return "", err
+			}
+		}
+	}
+
+	err = RestoreAssets(dir, assentName)
+	if err != nil {
+		cliContext.Logf(sdutils.ERROR, "The asset %s was not found", assentName)
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,82 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestFindLeaks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	exedir, _, err := sdutils.CreateTestExec("gcloud", "testdep-zk-0 us-central1-a testdep\n", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+
+	plugin := newTestPlugin()
+	err = plugin.FindLeaks(context.Background(), &app, "", false, false)
+	if err != nil {
+		t.Fatalf("Failed to look for leaks %s", err)
+	}
+	params, _ := ioutil.ReadFile(path.Join(exedir, "params"))
+	// The unlabeled kinds are searched with the name found on the instances
+	if !strings.Contains(string(params), "name~^testdep-") {
+		t.Fatalf("The deployment name was not used to search %s", params)
+	}
+
+	err = plugin.FindLeaks(context.Background(), &app, "testdep", true, true)
+	if err != nil {
+		t.Fatalf("Failed to destroy leaks %s", err)
+	}
+	params, _ = ioutil.ReadFile(path.Join(exedir, "params"))
+	if !strings.HasPrefix(string(params), "compute networks delete testdep-zk-0 --project graviton-test") {
+		t.Fatalf("The networks should be deleted last %s", params)
+	}
+
+	plugin.Project = ""
+	err = plugin.FindLeaks(context.Background(), &app, "testdep", false, false)
+	if err == nil {
+		t.Fatalf("A project should be required")
+	}
+}
+
+func TestLeakKindFormat(t *testing.T) {
+	for _, k := range leakKinds {
+		f := k.format()
+		if k.Scope != "" && !strings.Contains(f, k.Scope+".basename()") {
+			t.Fatalf("The %s format is missing the scope %s", k.Desc, f)
+		}
+		if k.Labeled && !strings.Contains(f, LabelKey) {
+			t.Fatalf("The %s format is missing the label %s", k.Desc, f)
+		}
+	}
+}
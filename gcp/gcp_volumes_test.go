@@ -0,0 +1,133 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func newTestDeployment(t *testing.T, dir string) (*sdutils.TestContext, *gcpDeploymentDescription) {
+	app := sdutils.TestContext{
+		ConfigDir: dir,
+		Version:   "4.2",
+	}
+	baseD := sdutils.BaseDeployment{
+		Type:      "gcp",
+		Name:      "testdep",
+		Directory: dir,
+		Version:   "4.2",
+	}
+	dd, err := newGcpDeploymentDescription(&app, &baseD, newTestPlugin())
+	if err != nil {
+		t.Fatalf("Failed to make the deployment manager %s", err)
+	}
+	return &app, dd
+}
+
+func TestVolumesNotThere(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	defer setTestCredentials(dir)()
+
+	app, dd := newTestDeployment(t, dir)
+	disks := NewGceDiskManager(app, dd)
+	if disks.VolumeExists() {
+		t.Fatalf("The volume shouldn't exist yet")
+	}
+	if disks.Region != "us-central1" {
+		t.Fatalf("The region should come from the zone")
+	}
+	err := disks.Status(context.Background())
+	if err == nil {
+		t.Fatalf("The volume shouldn't exist yet, status should fail")
+	}
+
+	startPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", startPath)
+	os.Setenv("PATH", dir)
+	err = disks.CreateSet(context.Background(), "/path/", 1, 3)
+	if err == nil {
+		t.Fatalf("The create should have failed")
+	}
+	os.Setenv("PATH", startPath)
+
+	exedir, _, err := sdutils.CreateTestExec("terraform", "data", 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir)
+
+	err = disks.CreateSet(context.Background(), "/path/", 1, 3)
+	if err != nil {
+		t.Fatalf("The create should have worked %s", err)
+	}
+	if !disks.VolumeExists() {
+		t.Fatalf("The volume should exist")
+	}
+	if sdutils.PathExists(path.Join(disks.VolumeDir, "builder.tf")) {
+		t.Fatalf("The builder should have been removed")
+	}
+
+	loaded, err := LoadGceDisks(app, disks.VolumeDir)
+	if err != nil {
+		t.Fatalf("The re-load should not have failed %s", err)
+	}
+	if loaded.ClusterSize != "3" || loaded.PublicKeyPath != dd.PrivateKeyPath+".pub" {
+		t.Fatalf("The volume configuration was not saved properly")
+	}
+	size, err := dd.ClusterSize()
+	if err != nil || size != 3 {
+		t.Fatalf("The cluster size should be 3 %d %s", size, err)
+	}
+
+	err = disks.Status(context.Background())
+	if err == nil {
+		t.Fatalf("The status should have bad output")
+	}
+	data := `{"volumes": { "sensitive": false, "type": "list", "value": ["testdep-data-0", "testdep-data-1", "testdep-data-2"]}}`
+	exedir2, _, err := sdutils.CreateTestExec("terraform", data, 0)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	defer os.RemoveAll(exedir2)
+
+	err = dd.StatusVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("The status should work %s", err)
+	}
+	vs, err := disks.getStatusInformation(context.Background())
+	if err != nil || len(vs.DiskNames) != 3 {
+		t.Fatalf("The disks should be listed %s", err)
+	}
+
+	err = dd.DeleteVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("The delete should not have failed %s", err)
+	}
+	if dd.VolumeExists() {
+		t.Fatalf("The volume should be gone")
+	}
+	err = dd.DeleteVolumeSet(context.Background())
+	if err == nil {
+		t.Fatalf("The second delete should have failed")
+	}
+}
@@ -0,0 +1,848 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graviton drives Stardog deployments without the command line.  A
+// Client is created from a Config and never prompts; every value that the
+// command line would ask for must be in the Config.
+package graviton
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// Config holds everything a Client needs to manage one deployment.  Zero
+// values are replaced by the same defaults the command line uses.
+type Config struct {
+	// AppContext receives the console output and log lines.  When nil they
+	// are discarded.  Its GetConfigDir, GetVersion, GetInteractive and
+	// Prompt methods are never used.
+	AppContext sdutils.AppContext `json:"-"`
+	// Prompter answers the questions some plugins still ask.  When nil every
+	// question fails with an error naming the missing value.
+	Prompter sdutils.Prompter `json:"-"`
+
+	ConfigDir       string      `json:"config_dir,omitempty"`
+	DeploymentName  string      `json:"deployment_name"`
+	CloudType       string      `json:"cloud_type,omitempty"`
+	CloudOptions    interface{} `json:"cloud_options,omitempty"`
+	Version         string      `json:"sd_version,omitempty"`
+	ReleaseFile     string      `json:"release_file,omitempty"`
+	LicensePath     string      `json:"license_path,omitempty"`
+	PrivateKey      string      `json:"private_key,omitempty"`
+	CustomPropsFile string      `json:"custom_props,omitempty"`
+	Environment     []string    `json:"environment,omitempty"`
+	DisableSecurity bool        `json:"disable_security,omitempty"`
+	VolumeSize      int         `json:"volume_size,omitempty"`
+	ClusterSize     int         `json:"cluster_size,omitempty"`
+	ZookeeperSize   int         `json:"zookeeper_size,omitempty"`
+	RootVolumeSize  int         `json:"root_volume_size,omitempty"`
+	HTTPMask        string      `json:"http_mask,omitempty"`
+	IdleTimeout     int         `json:"idle_timeout,omitempty"`
+	WaitTimeout     int         `json:"wait_timeout,omitempty"`
+	NoWait          bool        `json:"no_wait,omitempty"`
+	InternalHealth  bool        `json:"internal_health,omitempty"`
+	// AdminUser and AdminPassword are the Stardog credentials used to
+	// manage the databases.  They default to admin and to the password
+	// sdutils.AdminPassword returns.
+	AdminUser     string `json:"admin_user,omitempty"`
+	AdminPassword string `json:"-"`
+	// Resume continues an unfinished launch from the first step that did
+	// not finish.  Without it Launch refuses to start over an unfinished
+	// launch.
+	Resume bool `json:"resume,omitempty"`
+}
+
+// Client launches and manages a single deployment.
+type Client struct {
+	conf    Config
+	app     *clientContext
+	plugin  sdutils.Plugin
+	closers []io.Closer
+}
+
+// NewClient checks the configuration and returns a Client for it.  When the
+// cloud type has not been registered by the program the built in and
+// external plugins are registered first.
+func NewClient(conf Config) (*Client, error) {
+	if conf.DeploymentName == "" {
+		return nil, fmt.Errorf("A deployment name is required")
+	}
+	setDefaults(&conf)
+
+	c := &Client{conf: conf}
+	c.app = &clientContext{log: conf.AppContext, conf: &c.conf}
+
+	p, err := sdutils.GetPlugin(conf.CloudType)
+	if err != nil {
+		for _, p := range Plugins(conf.ConfigDir) {
+			sdutils.AddCloudType(p)
+			if closer, ok := p.(io.Closer); ok {
+				c.closers = append(c.closers, closer)
+			}
+		}
+		p, err = sdutils.GetPlugin(conf.CloudType)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if conf.CloudOptions != nil {
+		err = p.LoadDefaults(conf.CloudOptions)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("The cloud options are not valid for %s: %s", conf.CloudType, err)
+		}
+	}
+	c.plugin = p
+	return c, nil
+}
+
+func setDefaults(conf *Config) {
+	if conf.ConfigDir == "" {
+		conf.ConfigDir = os.Getenv("STARDOG_VIRTUAL_APPLIANCE_CONFIG_DIR")
+	}
+	if conf.ConfigDir == "" {
+		usr, _ := user.Current()
+		conf.ConfigDir = filepath.Join(usr.HomeDir, ".graviton")
+	}
+	conf.CloudType = strings.ToLower(conf.CloudType)
+	if conf.CloudType == "" {
+		conf.CloudType = "aws"
+	}
+	if conf.VolumeSize == 0 {
+		conf.VolumeSize = 10
+	}
+	if conf.ClusterSize == 0 {
+		conf.ClusterSize = 3
+	}
+	if conf.ZookeeperSize == 0 {
+		conf.ZookeeperSize = 3
+	}
+	if conf.RootVolumeSize == 0 {
+		conf.RootVolumeSize = 16
+	}
+	if conf.HTTPMask == "" {
+		conf.HTTPMask = sdutils.GetLocalOnlyHTTPMask()
+	}
+	if conf.IdleTimeout == 0 {
+		conf.IdleTimeout = 300
+	}
+	if conf.WaitTimeout == 0 {
+		conf.WaitTimeout = 600
+	}
+	if conf.AdminUser == "" {
+		conf.AdminUser = "admin"
+	}
+	if conf.AdminPassword == "" {
+		conf.AdminPassword = sdutils.AdminPassword()
+	}
+}
+
+// credentials returns the Stardog user and password of the Config.
+func (c *Client) credentials() sdutils.Credentials {
+	return sdutils.Credentials{Username: c.conf.AdminUser, Password: c.conf.AdminPassword}
+}
+
+func refusePrompt(prompt string, defaultValue string) (string, error) {
+	return "", fmt.Errorf("No value was configured for '%s'", prompt)
+}
+
+// Close stops any external plugins that the Client started.
+func (c *Client) Close() error {
+	for _, closer := range c.closers {
+		closer.Close()
+	}
+	c.closers = nil
+	return nil
+}
+
+// Capabilities returns the capabilities of the plugin for the cloud type.
+func (c *Client) Capabilities() sdutils.Capabilities {
+	return c.plugin.Capabilities()
+}
+
+func (c *Client) baseDeployment() sdutils.BaseDeployment {
+	return sdutils.BaseDeployment{
+		Name:            c.conf.DeploymentName,
+		Version:         c.conf.Version,
+		Type:            c.conf.CloudType,
+		Directory:       sdutils.DeploymentDir(c.conf.ConfigDir, c.conf.DeploymentName),
+		PrivateKey:      c.conf.PrivateKey,
+		CustomPropsFile: c.conf.CustomPropsFile,
+		Environment:     c.conf.Environment,
+		DisableSecurity: c.conf.DisableSecurity,
+	}
+}
+
+func (c *Client) load(ctx context.Context) (sdutils.Deployment, *sdutils.BaseDeployment, sdutils.Capabilities, error) {
+	baseD := c.baseDeployment()
+	dep, err := sdutils.LoadDeployment(ctx, c.app, &baseD, false)
+	if err != nil {
+		return nil, nil, sdutils.Capabilities{}, err
+	}
+	p, err := sdutils.GetPlugin(baseD.Type)
+	if err != nil {
+		return nil, nil, sdutils.Capabilities{}, err
+	}
+	return dep, &baseD, p.Capabilities(), nil
+}
+
+// Deployment loads the existing deployment.
+func (c *Client) Deployment(ctx context.Context) (sdutils.Deployment, error) {
+	dep, _, _, err := c.load(ctx)
+	return dep, err
+}
+
+// Launch takes the deployment from wherever it is to a running cluster.  The
+// base image is built from ReleaseFile when there is none, the deployment
+// and its volumes are created when they do not exist yet and then the
+// instance is started.  Every step is recorded in a journal in the
+// deployment directory until the launch is complete, see Resume and
+// Rollback.  The description is returned even when the final status check
+// fails.
+func (c *Client) Launch(ctx context.Context) (*sdutils.StardogDescription, error) {
+	err := sdutils.CheckLaunchCredentials(c.credentials())
+	if err != nil {
+		return nil, err
+	}
+	baseD := c.baseDeployment()
+	journal := sdutils.LoadJournal(c.app, baseD.Directory)
+	if journal != nil && !c.conf.Resume {
+		return nil, fmt.Errorf("An earlier launch of %s did not finish.  Resume it or roll it back", c.conf.DeploymentName)
+	}
+	if journal == nil {
+		if c.conf.Resume {
+			return nil, fmt.Errorf("There is no unfinished launch of %s to resume", c.conf.DeploymentName)
+		}
+		journal = sdutils.NewJournal(c.app, baseD.Directory)
+	}
+
+	caps := c.plugin.Capabilities()
+	if caps.Images && !c.plugin.HaveImage(ctx, c.app) {
+		if c.conf.ReleaseFile == "" {
+			return nil, fmt.Errorf("There is no base image for version %s and no release file was given", c.conf.Version)
+		}
+		err := journal.Step(sdutils.StepBuildImage, map[string]interface{}{"version": c.conf.Version, "release_file": c.conf.ReleaseFile}, func() error {
+			c.app.ConsoleLog(1, "Building the base image for version %s.\n", c.conf.Version)
+			err := c.plugin.BuildImage(ctx, c.app, c.conf.ReleaseFile, c.conf.Version)
+			if err != nil {
+				c.app.ConsoleLog(0, "Failed to make the stardog base image: %s\n", err.Error())
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dep, err := sdutils.LoadDeployment(ctx, c.app, &baseD, false)
+	if err != nil {
+		err = journal.Step(sdutils.StepCreateDeployment, map[string]interface{}{"cloud_type": baseD.Type, "version": baseD.Version}, func() error {
+			c.app.ConsoleLog(1, "Creating the new deployment %s\n", c.conf.DeploymentName)
+			dep, err = sdutils.LoadDeployment(ctx, c.app, &baseD, true)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if caps.Volumes && !dep.VolumeExists() {
+		if c.conf.LicensePath == "" {
+			return nil, fmt.Errorf("A license file is needed to create the volumes of %s", c.conf.DeploymentName)
+		}
+		err = journal.Step(sdutils.StepCreateVolumes, map[string]interface{}{"volume_size": c.conf.VolumeSize, "cluster_size": c.conf.ClusterSize}, func() error {
+			return dep.CreateVolumeSet(ctx, c.conf.LicensePath, c.conf.VolumeSize, c.conf.ClusterSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = sdutils.CreateInstance(ctx, c.app, &baseD, dep, c.credentials(), c.conf.RootVolumeSize, c.conf.ZookeeperSize, c.conf.WaitTimeout, c.conf.IdleTimeout, c.conf.HTTPMask, c.conf.NoWait, journal)
+	if err != nil {
+		return nil, err
+	}
+	journal.Remove()
+	sd, err := sdutils.DeploymentStatus(ctx, c.app, &baseD, dep, c.credentials(), c.conf.InternalHealth)
+	if err != nil && sd != nil && c.conf.NoWait {
+		// The cluster is expected to still be forming
+		c.app.Logf(sdutils.INFO, "The cluster nodes are not known yet: %s", err)
+		return sd, nil
+	}
+	return sd, err
+}
+
+// Rollback undoes the steps of an unfinished launch in reverse order,
+// including the step that failed.  The base image is kept because other
+// deployments may use it.  Steps are forgotten as they are undone so a
+// rollback that fails can be run again.
+func (c *Client) Rollback(ctx context.Context) error {
+	baseD := c.baseDeployment()
+	journal := sdutils.LoadJournal(c.app, baseD.Directory)
+	if journal == nil {
+		return fmt.Errorf("There is no unfinished launch of %s to roll back", c.conf.DeploymentName)
+	}
+	// The deployment does not exist yet when the launch failed before
+	// creating it.  When it exists but cannot be loaded the instance and
+	// the volumes cannot be deleted, so those steps fail and the deployment
+	// directory that records them is kept.
+	dep, loadErr := sdutils.LoadDeployment(ctx, c.app, &baseD, false)
+	// Every step is tried even when one fails.  Only the steps that were
+	// undone are forgotten so that rolling back again finishes the job.
+	failed := []string{}
+	for i := len(journal.Entries) - 1; i >= 0; i-- {
+		step := journal.Entries[i].Step
+		var err error
+		switch step {
+		case sdutils.StepCreateInstance:
+			if dep == nil {
+				err = fmt.Errorf("The deployment could not be loaded: %s", loadErr)
+				break
+			}
+			c.app.ConsoleLog(1, "Deleting the instance...\n")
+			err = dep.DeleteInstance(ctx)
+		case sdutils.StepCreateVolumes:
+			if dep == nil {
+				err = fmt.Errorf("The deployment could not be loaded: %s", loadErr)
+				break
+			}
+			c.app.ConsoleLog(1, "Deleting the volumes...\n")
+			err = dep.DeleteVolumeSet(ctx)
+		case sdutils.StepCreateDeployment:
+			if len(failed) > 0 {
+				// The deployment directory is needed to find what is left
+				c.app.ConsoleLog(1, "Keeping the deployment %s until the other steps are rolled back.\n", c.conf.DeploymentName)
+				continue
+			}
+			c.app.ConsoleLog(1, "Deleting the deployment %s...\n", c.conf.DeploymentName)
+			if dep != nil {
+				err = dep.DestroyDeployment(ctx)
+			}
+			if err == nil {
+				sdutils.DeleteDeployment(c.app, c.conf.DeploymentName)
+				return nil
+			}
+		case sdutils.StepBuildImage:
+			c.app.ConsoleLog(1, "Keeping the base image for version %s.\n", c.conf.Version)
+		default:
+			c.app.Logf(sdutils.DEBUG, "Nothing to undo for the launch step %s", step)
+		}
+		if err != nil {
+			c.app.ConsoleLog(1, "%s\n", c.app.FailString(fmt.Sprintf("Failed to roll back the %s step: %s", step, err)))
+			failed = append(failed, step)
+			continue
+		}
+		err = journal.Forget(step)
+		if err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to roll back the steps %s.  Roll back again to finish", strings.Join(failed, ", "))
+	}
+	journal.Remove()
+	return nil
+}
+
+// Status returns the state of the deployment, including whether it is
+// healthy and the nodes in the cluster.  The description is returned even
+// when the cluster nodes could not be listed.
+func (c *Client) Status(ctx context.Context) (*sdutils.StardogDescription, error) {
+	dep, baseD, _, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sdutils.DeploymentStatus(ctx, c.app, baseD, dep, c.credentials(), c.conf.InternalHealth)
+}
+
+// Destroy removes the instance, the volumes and the deployment itself.  It
+// keeps going when the instance or the volumes cannot be removed, just like
+// the destroy command.
+func (c *Client) Destroy(ctx context.Context) error {
+	dep, _, caps, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	if dep.InstanceExists() {
+		err = dep.DeleteInstance(ctx)
+		if err != nil {
+			c.app.ConsoleLog(1, "The instance was not destroyed.  %s\n", err)
+		}
+	}
+	if caps.Volumes && dep.VolumeExists() {
+		err = dep.DeleteVolumeSet(ctx)
+		if err != nil {
+			c.app.ConsoleLog(1, "The volumes were not destroyed.  %s\n", err)
+		}
+	}
+	err = dep.DestroyDeployment(ctx)
+	if err != nil {
+		c.app.ConsoleLog(1, "Failed to destroy the deployment %s\n", err)
+		return err
+	}
+	sdutils.DeleteDeployment(c.app, c.conf.DeploymentName)
+	return nil
+}
+
+// Pause destroys the instance of the deployment but keeps its volumes and
+// configuration.  Resume brings it back.
+func (c *Client) Pause(ctx context.Context) error {
+	dep, baseD, _, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	if !dep.InstanceExists() {
+		return fmt.Errorf("The deployment %s has no instance to pause", baseD.Name)
+	}
+	if baseD.Instance == nil {
+		c.app.ConsoleLog(0, "The instance settings of %s were not recorded.  It will have to be started with 'instance new'.\n", baseD.Name)
+	}
+	err = dep.DeleteInstance(ctx)
+	if err != nil {
+		return err
+	}
+	c.app.ConsoleLog(1, "The deployment %s is paused.  Its volumes were kept.\n", baseD.Name)
+	return nil
+}
+
+// Resume creates the instance of a paused deployment with the settings it
+// had before and, unless NoWait is set, waits until it is healthy and the
+// cluster has all of its nodes.
+func (c *Client) Resume(ctx context.Context) error {
+	dep, baseD, caps, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	if dep.InstanceExists() {
+		return fmt.Errorf("The deployment %s is already running", baseD.Name)
+	}
+	if caps.Volumes && !dep.VolumeExists() {
+		return fmt.Errorf("The deployment %s has no volumes to resume with", baseD.Name)
+	}
+	return sdutils.ResumeInstance(ctx, c.app, baseD, dep, c.credentials(), c.conf.WaitTimeout, c.conf.NoWait)
+}
+
+// CreateInstance starts the instance of a deployment whose volumes already
+// exist, changes the default admin password to AdminPassword and, unless
+// NoWait is set, waits for the cluster.  It is not recorded in a journal.
+func (c *Client) CreateInstance(ctx context.Context) error {
+	dep, baseD, _, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	return sdutils.CreateInstance(ctx, c.app, baseD, dep, c.credentials(), c.conf.RootVolumeSize, c.conf.ZookeeperSize, c.conf.WaitTimeout, c.conf.IdleTimeout, c.conf.HTTPMask, c.conf.NoWait, nil)
+}
+
+// Scale changes the number of Stardog nodes and, unless NoWait is set,
+// waits until the cluster reports them all.
+func (c *Client) Scale(ctx context.Context, clusterSize int) error {
+	if clusterSize < 1 {
+		return fmt.Errorf("The cluster size must be at least 1")
+	}
+	dep, baseD, caps, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	scaler, ok := dep.(sdutils.Scaler)
+	if !caps.Scaling || !ok {
+		return fmt.Errorf("The cloud type %s cannot scale deployments", baseD.Type)
+	}
+	err = scaler.Scale(ctx, clusterSize)
+	if err != nil {
+		return err
+	}
+	if c.conf.NoWait {
+		return nil
+	}
+	sd, err := dep.FullStatus(ctx)
+	if err != nil {
+		return err
+	}
+	return sdutils.WaitForNClusterNodes(ctx, c.app, clusterSize, sd.StardogURL, c.credentials(), c.conf.WaitTimeout)
+}
+
+// Resize replaces the nodes of the deployment one at a time with nodes of the
+// given instance types.  An empty type is left unchanged.
+func (c *Client) Resize(ctx context.Context, sdInstanceType string, zkInstanceType string) error {
+	if sdInstanceType == "" && zkInstanceType == "" {
+		return fmt.Errorf("A new Stardog or ZooKeeper instance type is required")
+	}
+	dep, baseD, caps, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	resizer, ok := dep.(sdutils.Resizer)
+	if !caps.Scaling || !ok {
+		return fmt.Errorf("The cloud type %s cannot resize instances", baseD.Type)
+	}
+	return resizer.ResizeInstance(ctx, sdInstanceType, zkInstanceType, c.conf.WaitTimeout, c.credentials())
+}
+
+// Upgrade moves the deployment to the Stardog release version.  The base
+// image for that version is built from releaseFile when there is none.
+func (c *Client) Upgrade(ctx context.Context, version string, releaseFile string) error {
+	dep, baseD, caps, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	upgrader, ok := dep.(sdutils.Upgrader)
+	if !caps.Images || !ok {
+		return fmt.Errorf("The cloud type %s cannot upgrade deployments", baseD.Type)
+	}
+	if baseD.Version == version {
+		return fmt.Errorf("The deployment %s already runs Stardog %s", baseD.Name, version)
+	}
+	p, err := sdutils.GetPlugin(baseD.Type)
+	if err != nil {
+		return err
+	}
+	app := &versionContext{AppContext: c.app, version: version}
+	if !p.HaveImage(ctx, app) {
+		if releaseFile == "" {
+			return fmt.Errorf("There is no base image for version %s and no release file was given", version)
+		}
+		c.app.ConsoleLog(1, "Building the base image for version %s.\n", version)
+		err = p.BuildImage(ctx, app, releaseFile, version)
+		if err != nil {
+			return err
+		}
+	}
+	return upgrader.Upgrade(ctx, version, c.conf.WaitTimeout, c.credentials())
+}
+
+// ModifyVolumes changes the size, the type and the IOPS per gigabyte of the
+// volumes of the deployment.  Zero values and an empty type are left
+// unchanged.  When the volumes grow the filesystems of the running nodes are
+// grown with them.
+func (c *Client) ModifyVolumes(ctx context.Context, size int, volumeType string, iops int) error {
+	if size == 0 && volumeType == "" && iops == 0 {
+		return fmt.Errorf("A new size, volume type or IOPS is required")
+	}
+	dep, baseD, caps, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	modifier, ok := dep.(sdutils.VolumeModifier)
+	if !caps.Volumes || !ok {
+		return fmt.Errorf("The cloud type %s cannot modify volumes", baseD.Type)
+	}
+	err = modifier.ModifyVolumes(ctx, size, volumeType, iops, c.conf.WaitTimeout)
+	if err != nil {
+		return err
+	}
+	if size == 0 || !dep.InstanceExists() {
+		return nil
+	}
+	return sdutils.GrowFilesystems(ctx, c.app, baseD, dep, c.credentials())
+}
+
+func (c *Client) snapshotter(ctx context.Context) (sdutils.Snapshotter, sdutils.Deployment, *sdutils.BaseDeployment, error) {
+	dep, baseD, caps, err := c.load(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	snapshotter, ok := dep.(sdutils.Snapshotter)
+	if !caps.Snapshots || !ok {
+		return nil, nil, nil, fmt.Errorf("The cloud type %s cannot snapshot volumes", baseD.Type)
+	}
+	return snapshotter, dep, baseD, nil
+}
+
+// Snapshot takes a snapshot set of the volumes of the deployment.  With
+// pauseWrites every database is taken offline until all of the snapshots were
+// started.  Unless NoWait is set it waits until the snapshots complete.
+func (c *Client) Snapshot(ctx context.Context, pauseWrites bool) (*sdutils.SnapshotDescription, error) {
+	snapshotter, dep, baseD, err := c.snapshotter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resume func(context.Context) error
+	if pauseWrites {
+		if !dep.InstanceExists() {
+			return nil, fmt.Errorf("The deployment %s is not running so its writes cannot be paused", baseD.Name)
+		}
+		sd, err := dep.FullStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resume, err = sdutils.PauseWrites(ctx, c.app, sd.StardogURL, c.credentials())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to pause the writes: %s", err)
+		}
+	}
+	set, err := snapshotter.SnapshotVolumes(ctx)
+	if resume != nil {
+		rerr := resume(ctx)
+		if rerr != nil {
+			c.app.ConsoleLog(0, "Some databases could not be brought back online.  %s\n", rerr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.app.ConsoleLog(1, "Started the snapshot set %s.\n", set.ID)
+	if c.conf.NoWait || set.Complete {
+		return set, nil
+	}
+	return c.waitForSnapshots(ctx, snapshotter, set.ID)
+}
+
+func (c *Client) waitForSnapshots(ctx context.Context, snapshotter sdutils.Snapshotter, id string) (*sdutils.SnapshotDescription, error) {
+	pollInterval := 10
+	itCnt := c.conf.WaitTimeout / pollInterval
+	spinner := sdutils.NewSpinner(c.app, 1, "Waiting for the snapshots to complete")
+	defer spinner.Close()
+	for i := 0; ; i++ {
+		sets, err := snapshotter.ListSnapshots(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, set := range sets {
+			if set.ID == id && set.Complete {
+				c.app.ConsoleLog(1, "%s\n", c.app.SuccessString("The snapshot set is complete"))
+				return &set, nil
+			}
+		}
+		if i >= itCnt {
+			return nil, fmt.Errorf("Timed out waiting for the snapshot set %s", id)
+		}
+		spinner.EchoNext()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(pollInterval) * time.Second):
+		}
+	}
+}
+
+// Snapshots returns the snapshot sets of the deployment, oldest first.
+func (c *Client) Snapshots(ctx context.Context) ([]sdutils.SnapshotDescription, error) {
+	snapshotter, _, baseD, err := c.snapshotter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := snapshotter.ListSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []sdutils.SnapshotDescription{}
+	for _, set := range sets {
+		if set.Deployment == baseD.Name {
+			result = append(result, set)
+		}
+	}
+	return result, nil
+}
+
+// SelectSnapshotsToPrune returns the snapshot sets of the deployment that a
+// prune removes.  The keep newest complete sets are always kept.  When
+// olderThan is not zero only the sets older than that are returned.
+// Incomplete sets are never pruned because they may still be in progress.
+func (c *Client) SelectSnapshotsToPrune(ctx context.Context, keep int, olderThan time.Duration) ([]sdutils.SnapshotDescription, error) {
+	sets, err := c.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return selectPrunable(sets, keep, olderThan, time.Now()), nil
+}
+
+func selectPrunable(sets []sdutils.SnapshotDescription, keep int, olderThan time.Duration, now time.Time) []sdutils.SnapshotDescription {
+	prune := []sdutils.SnapshotDescription{}
+	kept := 0
+	for i := len(sets) - 1; i >= 0; i-- {
+		if !sets[i].Complete {
+			continue
+		}
+		if kept < keep {
+			kept++
+			continue
+		}
+		if olderThan > 0 && now.Sub(sets[i].Created) < olderThan {
+			continue
+		}
+		prune = append(prune, sets[i])
+	}
+	return prune
+}
+
+// DeleteSnapshots deletes the snapshot sets ids.
+func (c *Client) DeleteSnapshots(ctx context.Context, ids []string) error {
+	snapshotter, _, _, err := c.snapshotter(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		c.app.ConsoleLog(1, "Deleting the snapshot set %s.\n", id)
+		err = snapshotter.DeleteSnapshots(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore creates the volumes of the deployment from the snapshot set id.
+// The deployment must have been created with the Stardog version of the
+// snapshots, or a later one of the same major version, and have no volumes.
+func (c *Client) Restore(ctx context.Context, id string) error {
+	snapshotter, dep, baseD, err := c.snapshotter(ctx)
+	if err != nil {
+		return err
+	}
+	if dep.VolumeExists() {
+		return fmt.Errorf("The deployment %s already has volumes", baseD.Name)
+	}
+	sets, err := snapshotter.ListSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+	for _, set := range sets {
+		if set.ID != id {
+			continue
+		}
+		if !set.Complete {
+			return fmt.Errorf("The snapshot set %s is not complete", id)
+		}
+		if set.Version != "" {
+			err = sdutils.CheckDataVersion(set.Version, baseD.Version, "")
+			if err != nil {
+				return err
+			}
+		}
+		err = snapshotter.RestoreVolumes(ctx, id)
+		if err != nil {
+			return err
+		}
+		c.app.ConsoleLog(1, "The volumes of %s were restored from %s.\n", baseD.Name, id)
+		return nil
+	}
+	return fmt.Errorf("The snapshot set %s does not exist", id)
+}
+
+// Backup backs up the databases dbs, or every database when dbs is empty, and
+// copies the backups to dest.  See sdutils.BackupDatabases.
+func (c *Client) Backup(ctx context.Context, dbs []string, dest string) ([]sdutils.BackupDescription, error) {
+	dep, baseD, _, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sdutils.BackupDatabases(ctx, c.app, baseD, dep, c.credentials(), dbs, dest)
+}
+
+// Backups returns the backups in the catalog of the deployment, oldest first.
+// The catalog is kept after the deployment is destroyed.
+func (c *Client) Backups(ctx context.Context) ([]sdutils.BackupDescription, error) {
+	return sdutils.ListBackups(c.conf.ConfigDir, c.conf.DeploymentName)
+}
+
+// RestoreBackup restores the database db from the backup id.  The backup is
+// looked up in the catalog of the deployment from, or of this deployment when
+// from is empty.
+func (c *Client) RestoreBackup(ctx context.Context, db string, id string, from string, overwrite bool) error {
+	dep, baseD, _, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	return sdutils.RestoreBackup(ctx, c.app, baseD, dep, c.credentials(), db, id, from, overwrite)
+}
+
+// GatherLogs collects the Stardog logs of every node into outfile.
+func (c *Client) GatherLogs(ctx context.Context, outfile string) error {
+	dep, baseD, _, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	return sdutils.GatherLogs(ctx, c.app, baseD, dep, c.credentials(), outfile)
+}
+
+// versionContext makes the plugins look up and build the base image of a
+// version other than the one the client was configured with.
+type versionContext struct {
+	sdutils.AppContext
+	version string
+}
+
+func (v *versionContext) GetVersion() string {
+	return v.version
+}
+
+// clientContext is the AppContext given to the plugins.  It forwards output
+// to the configured AppContext and answers the rest from the Config.
+type clientContext struct {
+	log  sdutils.AppContext
+	conf *Config
+}
+
+func (c *clientContext) ConsoleLog(level int, format string, v ...interface{}) {
+	if c.log != nil {
+		c.log.ConsoleLog(level, format, v...)
+	}
+}
+
+func (c *clientContext) Logf(level int, format string, v ...interface{}) {
+	if c.log != nil {
+		c.log.Logf(level, format, v...)
+	}
+}
+
+func (c *clientContext) GetConfigDir() string {
+	return c.conf.ConfigDir
+}
+
+func (c *clientContext) GetVersion() string {
+	return c.conf.Version
+}
+
+func (c *clientContext) GetInteractive() bool {
+	return false
+}
+
+// Prompt answers with the Prompter of the Config.  Without one every
+// question is refused so that a Client never blocks on the console.
+func (c *clientContext) Prompt(prompt string, defaultValue string) (string, error) {
+	if c.conf.Prompter == nil {
+		return refusePrompt(prompt, defaultValue)
+	}
+	return c.conf.Prompter(prompt, defaultValue)
+}
+
+func (c *clientContext) HighlightString(a ...interface{}) string {
+	if c.log != nil {
+		return c.log.HighlightString(a...)
+	}
+	return fmt.Sprint(a...)
+}
+
+func (c *clientContext) SuccessString(a ...interface{}) string {
+	if c.log != nil {
+		return c.log.SuccessString(a...)
+	}
+	return fmt.Sprint(a...)
+}
+
+func (c *clientContext) FailString(a ...interface{}) string {
+	if c.log != nil {
+		return c.log.FailString(a...)
+	}
+	return fmt.Sprint(a...)
+}
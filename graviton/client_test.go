@@ -0,0 +1,849 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type fakePlugin struct {
+	name         string
+	caps         sdutils.Capabilities
+	image        bool
+	builds       int
+	options      interface{}
+	failInstance bool
+	failDelete   bool
+	failLoad     bool
+	creds        sdutils.Credentials
+	snapshots    []sdutils.SnapshotDescription
+	sdURL        string
+}
+
+type fakeDeployment struct {
+	plugin       *fakePlugin
+	baseD        *sdutils.BaseDeployment
+	state        *fakeState
+	failInstance bool
+}
+
+type fakeState struct {
+	Volumes  bool   `json:"volumes"`
+	Instance bool   `json:"instance"`
+	Size     int    `json:"size"`
+	SdType   string `json:"sd_type,omitempty"`
+	Version  string `json:"version,omitempty"`
+	ZkSize   int    `json:"zk_size,omitempty"`
+	Mask     string `json:"mask,omitempty"`
+	VolSize  int    `json:"vol_size,omitempty"`
+	VolType  string `json:"vol_type,omitempty"`
+}
+
+func (p *fakePlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	return nil
+}
+
+func (p *fakePlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	d := &fakeDeployment{plugin: p, baseD: baseD, state: &fakeState{}, failInstance: p.failInstance}
+	if new {
+		data, err := json.Marshal(baseD)
+		if err != nil {
+			return nil, err
+		}
+		return d, ioutil.WriteFile(filepath.Join(baseD.Directory, "config.json"), data, 0644)
+	}
+	if p.failLoad {
+		return nil, fmt.Errorf("The deployment is broken")
+	}
+	data, err := ioutil.ReadFile(d.statePath())
+	if err == nil {
+		err = json.Unmarshal(data, d.state)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (p *fakePlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	p.options = defaultCliOpts
+	return nil
+}
+
+func (p *fakePlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	p.builds++
+	p.image = true
+	return nil
+}
+
+func (p *fakePlugin) GetName() string {
+	return p.name
+}
+
+func (p *fakePlugin) Capabilities() sdutils.Capabilities {
+	return p.caps
+}
+
+func (p *fakePlugin) FindLeaks(ctx context.Context, context sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	return nil
+}
+
+func (p *fakePlugin) HaveImage(ctx context.Context, context sdutils.AppContext) bool {
+	return p.image
+}
+
+func (d *fakeDeployment) statePath() string {
+	return filepath.Join(d.baseD.Directory, "state.json")
+}
+
+func (d *fakeDeployment) save() error {
+	data, err := json.Marshal(d.state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(d.statePath(), data, 0644)
+}
+
+func (d *fakeDeployment) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	d.state.Volumes = true
+	d.state.Size = clusterSize
+	return d.save()
+}
+
+func (d *fakeDeployment) DeleteVolumeSet(ctx context.Context) error {
+	d.state.Volumes = false
+	return d.save()
+}
+
+func (d *fakeDeployment) StatusVolumeSet(ctx context.Context) error {
+	return nil
+}
+
+func (d *fakeDeployment) VolumeExists() bool {
+	return d.state.Volumes
+}
+
+func (d *fakeDeployment) CreateInstance(ctx context.Context, rootSize int, zookeeperSize int, idleTimeout int) error {
+	d.state.Instance = true
+	d.state.ZkSize = zookeeperSize
+	err := d.save()
+	if err != nil {
+		return err
+	}
+	if d.failInstance {
+		return fmt.Errorf("The instance failed to start")
+	}
+	return nil
+}
+
+func (d *fakeDeployment) OpenInstance(ctx context.Context, rootSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	d.state.Mask = mask
+	return d.save()
+}
+
+func (d *fakeDeployment) DeleteInstance(ctx context.Context) error {
+	if d.plugin.failDelete {
+		return fmt.Errorf("The instance could not be deleted")
+	}
+	d.state.Instance = false
+	return d.save()
+}
+
+func (d *fakeDeployment) StatusInstance(ctx context.Context) error {
+	return nil
+}
+
+func (d *fakeDeployment) InstanceExists() bool {
+	return d.state.Instance
+}
+
+func (d *fakeDeployment) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	if !d.state.Instance {
+		return nil, fmt.Errorf("The instance does not exist")
+	}
+	if d.plugin.sdURL != "" {
+		return &sdutils.StardogDescription{StardogURL: d.plugin.sdURL}, nil
+	}
+	return &sdutils.StardogDescription{StardogURL: "http://localhost:5820"}, nil
+}
+
+func (d *fakeDeployment) ClusterSize() (int, error) {
+	return d.state.Size, nil
+}
+
+func (d *fakeDeployment) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (d *fakeDeployment) Scale(ctx context.Context, clusterSize int) error {
+	d.state.Size = clusterSize
+	return d.save()
+}
+
+func (d *fakeDeployment) ResizeInstance(ctx context.Context, sdInstanceType string, zkInstanceType string, waitTimeout int, creds sdutils.Credentials) error {
+	d.plugin.creds = creds
+	if sdInstanceType != "" {
+		d.state.SdType = sdInstanceType
+	}
+	return d.save()
+}
+
+func (d *fakeDeployment) Upgrade(ctx context.Context, version string, waitTimeout int, creds sdutils.Credentials) error {
+	d.plugin.creds = creds
+	d.state.Version = version
+	return d.save()
+}
+
+func (d *fakeDeployment) ModifyVolumes(ctx context.Context, size int, volumeType string, iops int, waitTimeout int) error {
+	if size != 0 {
+		d.state.VolSize = size
+	}
+	if volumeType != "" {
+		d.state.VolType = volumeType
+	}
+	return d.save()
+}
+
+func (d *fakeDeployment) SnapshotVolumes(ctx context.Context) (*sdutils.SnapshotDescription, error) {
+	set := sdutils.SnapshotDescription{
+		ID:         fmt.Sprintf("%s-%d", d.baseD.Name, len(d.plugin.snapshots)),
+		Deployment: d.baseD.Name,
+		Version:    d.baseD.Version,
+		Created:    time.Now(),
+		VolumeSize: 10,
+		Complete:   true,
+	}
+	for i := 0; i < d.state.Size; i++ {
+		set.SnapshotIDs = append(set.SnapshotIDs, fmt.Sprintf("snap-%s-%d", set.ID, i))
+	}
+	d.plugin.snapshots = append(d.plugin.snapshots, set)
+	return &set, nil
+}
+
+func (d *fakeDeployment) ListSnapshots(ctx context.Context) ([]sdutils.SnapshotDescription, error) {
+	return d.plugin.snapshots, nil
+}
+
+func (d *fakeDeployment) DeleteSnapshots(ctx context.Context, id string) error {
+	for i, set := range d.plugin.snapshots {
+		if set.ID == id {
+			d.plugin.snapshots = append(d.plugin.snapshots[:i], d.plugin.snapshots[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("The snapshot set %s does not exist", id)
+}
+
+func (d *fakeDeployment) RestoreVolumes(ctx context.Context, id string) error {
+	for _, set := range d.plugin.snapshots {
+		if set.ID == id {
+			d.state.Volumes = true
+			d.state.Size = len(set.SnapshotIDs)
+			return d.save()
+		}
+	}
+	return fmt.Errorf("The snapshot set %s does not exist", id)
+}
+
+func newTestClient(t *testing.T, caps sdutils.Capabilities) (*Client, *fakePlugin, string) {
+	dir, err := ioutil.TempDir("", "graviton")
+	if err != nil {
+		t.Fatalf("Failed to make a temp dir %s", err)
+	}
+	p := &fakePlugin{name: "fake-" + filepath.Base(dir), caps: caps}
+	sdutils.AddCloudType(p)
+	c, err := NewClient(Config{
+		AppContext:     &sdutils.TestContext{ConfigDir: dir, Version: "5.0.0"},
+		ConfigDir:      dir,
+		DeploymentName: "dep1",
+		CloudType:      p.name,
+		CloudOptions:   "options",
+		Version:        "5.0.0",
+		ReleaseFile:    "/nothing/stardog-5.0.0.zip",
+		LicensePath:    "/nothing/stardog-license-key.bin",
+		NoWait:         true,
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("The client should have been created %s", err)
+	}
+	return c, p, dir
+}
+
+func TestNewClientNeedsName(t *testing.T) {
+	_, err := NewClient(Config{})
+	if err == nil {
+		t.Fatal("A client without a deployment name should fail")
+	}
+}
+
+func TestNewClientUnknownType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graviton")
+	if err != nil {
+		t.Fatalf("Failed to make a temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+	_, err = NewClient(Config{ConfigDir: dir, DeploymentName: "dep1", CloudType: "nosuchcloud"})
+	if err == nil {
+		t.Fatal("An unknown cloud type should fail")
+	}
+}
+
+func TestClientNeverPrompts(t *testing.T) {
+	c, _, dir := newTestClient(t, sdutils.Capabilities{})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	answering, _, dir2 := newTestClient(t, sdutils.Capabilities{})
+	defer os.RemoveAll(dir2)
+	defer answering.Close()
+	answering.conf.Prompter = func(prompt string, defaultValue string) (string, error) {
+		return "eu-west-1", nil
+	}
+
+	_, err := c.app.Prompt("Region", "us-west-1")
+	if err == nil || !strings.Contains(err.Error(), "Region") {
+		t.Fatalf("The question should have been refused with its name %v", err)
+	}
+	answer, err := answering.app.Prompt("Region", "us-west-1")
+	if err != nil || answer != "eu-west-1" {
+		t.Fatalf("The client with a Prompter should answer %s %v", answer, err)
+	}
+}
+
+func TestClientLifecycle(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	if p.options != "options" {
+		t.Fatalf("The cloud options were not given to the plugin")
+	}
+	_, err := c.Deployment(context.Background())
+	if err == nil {
+		t.Fatal("The deployment should not exist yet")
+	}
+	sd, err := c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	if !sd.Healthy {
+		t.Fatal("The deployment should be healthy")
+	}
+	if p.builds != 1 {
+		t.Fatalf("The image should have been built once, not %d times", p.builds)
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil {
+		t.Fatalf("The deployment should exist %s", err)
+	}
+	if !dep.VolumeExists() || !dep.InstanceExists() {
+		t.Fatal("The volumes and the instance should exist")
+	}
+	size, _ := dep.ClusterSize()
+	if size != 3 {
+		t.Fatalf("The default cluster size was not used %d", size)
+	}
+
+	_, err = c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("A second launch should reuse the deployment %s", err)
+	}
+	if p.builds != 1 {
+		t.Fatal("The image should not be rebuilt")
+	}
+
+	sd, err = c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status failed %s", err)
+	}
+	if sd.StardogURL != "http://localhost:5820" {
+		t.Fatalf("Wrong URL %s", sd.StardogURL)
+	}
+
+	err = c.Scale(context.Background(), 5)
+	if err == nil {
+		t.Fatal("Scaling should be refused without the capability")
+	}
+
+	err = c.Destroy(context.Background())
+	if err != nil {
+		t.Fatalf("Destroy failed %s", err)
+	}
+	if _, err := os.Stat(sdutils.DeploymentDir(dir, "dep1")); !os.IsNotExist(err) {
+		t.Fatal("The deployment directory should be gone")
+	}
+}
+
+func TestClientScale(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, _, dir := newTestClient(t, sdutils.Capabilities{Scaling: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	_, err := c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	err = c.Scale(context.Background(), 0)
+	if err == nil {
+		t.Fatal("A cluster of 0 nodes should be refused")
+	}
+	err = c.Scale(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("Scale failed %s", err)
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil {
+		t.Fatalf("The deployment should exist %s", err)
+	}
+	size, _ := dep.ClusterSize()
+	if size != 5 {
+		t.Fatalf("The cluster should have 5 nodes, not %d", size)
+	}
+}
+
+func TestClientResize(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, _, dir := newTestClient(t, sdutils.Capabilities{Scaling: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	_, err := c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	err = c.Resize(context.Background(), "", "")
+	if err == nil {
+		t.Fatal("A resize without instance types should be refused")
+	}
+	err = c.Resize(context.Background(), "m4.xlarge", "")
+	if err != nil {
+		t.Fatalf("Resize failed %s", err)
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil {
+		t.Fatalf("The deployment should exist %s", err)
+	}
+	if dep.(*fakeDeployment).state.SdType != "m4.xlarge" {
+		t.Fatalf("The Stardog instance type was not changed")
+	}
+}
+
+func TestClientUpgrade(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+	c.conf.AdminUser = "ops"
+	c.conf.AdminPassword = "secret"
+
+	_, err := c.Launch(context.Background())
+	if err == nil {
+		t.Fatal("A new cluster only has the admin user to launch it with")
+	}
+	if _, err := os.Stat(sdutils.DeploymentDir(dir, "dep1")); !os.IsNotExist(err) {
+		t.Fatal("Nothing should be created when the launch is refused")
+	}
+	c.conf.AdminUser = "admin"
+	_, err = c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	c.conf.AdminUser = "ops"
+	err = c.Upgrade(context.Background(), "5.0.0", "")
+	if err == nil {
+		t.Fatal("An upgrade to the running version should be refused")
+	}
+	p.image = false
+	err = c.Upgrade(context.Background(), "5.0.1", "")
+	if err == nil {
+		t.Fatal("An upgrade without an image or a release should be refused")
+	}
+	err = c.Upgrade(context.Background(), "5.0.1", "/nothing/stardog-5.0.1.zip")
+	if err != nil {
+		t.Fatalf("Upgrade failed %s", err)
+	}
+	if p.builds != 2 {
+		t.Fatalf("The image for the new version should have been built")
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil {
+		t.Fatalf("The deployment should exist %s", err)
+	}
+	if dep.(*fakeDeployment).state.Version != "5.0.1" {
+		t.Fatalf("The deployment was not upgraded")
+	}
+	if p.creds.Username != "ops" || p.creds.Password != "secret" {
+		t.Fatalf("The upgrade should use the configured credentials %v", p.creds)
+	}
+}
+
+func TestClientModifyVolumes(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, _, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	_, err := c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	err = c.ModifyVolumes(context.Background(), 0, "", 0)
+	if err == nil {
+		t.Fatal("A modify without new settings should be refused")
+	}
+	err = c.ModifyVolumes(context.Background(), 0, "io1", 0)
+	if err != nil {
+		t.Fatalf("Modify failed %s", err)
+	}
+	err = c.Pause(context.Background())
+	if err != nil {
+		t.Fatalf("Pause failed %s", err)
+	}
+	err = c.ModifyVolumes(context.Background(), 20, "", 0)
+	if err != nil {
+		t.Fatalf("Modify failed %s", err)
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil {
+		t.Fatalf("The deployment should exist %s", err)
+	}
+	state := dep.(*fakeDeployment).state
+	if state.VolType != "io1" || state.VolSize != 20 {
+		t.Fatalf("The volumes were not modified %s %d", state.VolType, state.VolSize)
+	}
+
+	c2, _, dir2 := newTestClient(t, sdutils.Capabilities{Images: true})
+	defer os.RemoveAll(dir2)
+	defer c2.Close()
+	_, err = c2.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	err = c2.ModifyVolumes(context.Background(), 20, "", 0)
+	if err == nil {
+		t.Fatal("A cloud without volumes cannot modify them")
+	}
+}
+
+func TestClientCreateInstance(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, _, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	_, err := c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	err = c.Pause(context.Background())
+	if err != nil {
+		t.Fatalf("Pause failed %s", err)
+	}
+	c.conf.AdminUser = "ops"
+	err = c.CreateInstance(context.Background())
+	if err == nil {
+		t.Fatal("A new instance only has the admin user to start it with")
+	}
+	c.conf.AdminUser = "admin"
+	c.conf.ZookeeperSize = 5
+	err = c.CreateInstance(context.Background())
+	if err != nil {
+		t.Fatalf("CreateInstance failed %s", err)
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil || !dep.InstanceExists() || dep.(*fakeDeployment).state.ZkSize != 5 {
+		t.Fatalf("The instance should have been created with the config %v", err)
+	}
+}
+
+func TestClientPauseResume(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, _, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	c.conf.ZookeeperSize = 5
+	c.conf.HTTPMask = "10.0.0.0/8"
+	_, err := c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	err = c.Resume(context.Background())
+	if err == nil {
+		t.Fatal("A running deployment cannot be resumed")
+	}
+	err = c.Pause(context.Background())
+	if err != nil {
+		t.Fatalf("Pause failed %s", err)
+	}
+	err = c.Pause(context.Background())
+	if err == nil {
+		t.Fatal("A paused deployment cannot be paused again")
+	}
+
+	c.conf.ZookeeperSize = 3
+	c.conf.HTTPMask = "0.0.0.0/0"
+	err = c.Resume(context.Background())
+	if err != nil {
+		t.Fatalf("Resume failed %s", err)
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil {
+		t.Fatalf("The deployment should exist %s", err)
+	}
+	state := dep.(*fakeDeployment).state
+	if !state.Instance || !state.Volumes {
+		t.Fatal("The resumed deployment should have an instance and its volumes")
+	}
+	if state.ZkSize != 5 || state.Mask != "10.0.0.0/8" {
+		t.Fatalf("The instance was not resumed with its launch settings %d %s", state.ZkSize, state.Mask)
+	}
+}
+
+func TestClientResume(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	p.failInstance = true
+	_, err := c.Launch(context.Background())
+	if err == nil {
+		t.Fatal("The launch should have failed")
+	}
+	_, err = c.Launch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Fatalf("A launch over an unfinished launch should be refused %v", err)
+	}
+
+	p.failInstance = false
+	c.conf.Resume = true
+	_, err = c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("The resumed launch failed %s", err)
+	}
+	if p.builds != 1 {
+		t.Fatalf("The image should have been built once, not %d times", p.builds)
+	}
+	if sdutils.LoadJournal(c.app, sdutils.DeploymentDir(dir, "dep1")) != nil {
+		t.Fatal("The journal should be removed once the launch is complete")
+	}
+	_, err = c.Launch(context.Background())
+	if err == nil {
+		t.Fatal("There is nothing left to resume")
+	}
+}
+
+func TestClientRollback(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	err := c.Rollback(context.Background())
+	if err == nil {
+		t.Fatal("There is no launch to roll back")
+	}
+	p.failInstance = true
+	_, err = c.Launch(context.Background())
+	if err == nil {
+		t.Fatal("The launch should have failed")
+	}
+	dep, err := c.Deployment(context.Background())
+	if err != nil || !dep.VolumeExists() || !dep.InstanceExists() {
+		t.Fatalf("The failed launch should have left the volumes and a partial instance %v", err)
+	}
+
+	p.failLoad = true
+	err = c.Rollback(context.Background())
+	if err == nil {
+		t.Fatal("The rollback should fail when the deployment cannot be loaded")
+	}
+	p.failLoad = false
+	dep, err = c.Deployment(context.Background())
+	if err != nil || !dep.VolumeExists() || !dep.InstanceExists() {
+		t.Fatalf("Nothing should be forgotten when the deployment cannot be loaded %v", err)
+	}
+
+	p.failDelete = true
+	err = c.Rollback(context.Background())
+	if err == nil {
+		t.Fatal("The rollback should report the instance it could not delete")
+	}
+	dep, err = c.Deployment(context.Background())
+	if err != nil || dep.VolumeExists() || !dep.InstanceExists() {
+		t.Fatalf("The volumes should be gone and the deployment kept for the instance %v", err)
+	}
+
+	p.failDelete = false
+	err = c.Rollback(context.Background())
+	if err != nil {
+		t.Fatalf("Rollback failed %s", err)
+	}
+	if _, err := os.Stat(sdutils.DeploymentDir(dir, "dep1")); !os.IsNotExist(err) {
+		t.Fatal("The deployment directory should be gone")
+	}
+	if !p.image {
+		t.Fatal("The base image should be kept")
+	}
+}
+
+func TestClientSnapshots(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	_, err := c.Launch(context.Background())
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	_, err = c.Snapshot(context.Background(), false)
+	if err == nil {
+		t.Fatal("A snapshot should be refused without the capability")
+	}
+	p.caps.Snapshots = true
+	for i := 0; i < 3; i++ {
+		_, err = c.Snapshot(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Snapshot failed %s", err)
+		}
+	}
+	p.snapshots = append(p.snapshots, sdutils.SnapshotDescription{ID: "other-0", Deployment: "other", Version: "5.0.0", Complete: true})
+	sets, err := c.Snapshots(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshots failed %s", err)
+	}
+	if len(sets) != 3 {
+		t.Fatalf("There should be 3 snapshot sets of dep1 but there are %d", len(sets))
+	}
+
+	err = c.Restore(context.Background(), sets[0].ID)
+	if err == nil {
+		t.Fatal("A restore over existing volumes should be refused")
+	}
+	c2, err := NewClient(Config{
+		AppContext:     &sdutils.TestContext{ConfigDir: dir, Version: "5.0.1"},
+		ConfigDir:      dir,
+		DeploymentName: "dep2",
+		CloudType:      p.name,
+		Version:        "5.0.1",
+		NoWait:         true,
+	})
+	if err != nil {
+		t.Fatalf("The client should have been created %s", err)
+	}
+	defer c2.Close()
+	baseD := c2.baseDeployment()
+	_, err = sdutils.LoadDeployment(context.Background(), c2.app, &baseD, true)
+	if err != nil {
+		t.Fatalf("The deployment should have been created %s", err)
+	}
+	err = c2.Restore(context.Background(), "nothing")
+	if err == nil {
+		t.Fatal("A restore from a missing snapshot set should fail")
+	}
+	err = c2.Restore(context.Background(), sets[2].ID)
+	if err != nil {
+		t.Fatalf("Restore failed %s", err)
+	}
+	dep, err := c2.Deployment(context.Background())
+	if err != nil {
+		t.Fatalf("The deployment should exist %s", err)
+	}
+	if !dep.VolumeExists() {
+		t.Fatal("The volumes were not restored")
+	}
+
+	prune, err := c.SelectSnapshotsToPrune(context.Background(), 1, 0)
+	if err != nil {
+		t.Fatalf("SelectSnapshotsToPrune failed %s", err)
+	}
+	if len(prune) != 2 {
+		t.Fatalf("2 snapshot sets should be pruned but %d were selected", len(prune))
+	}
+	ids := []string{}
+	for _, set := range prune {
+		ids = append(ids, set.ID)
+	}
+	err = c.DeleteSnapshots(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("DeleteSnapshots failed %s", err)
+	}
+	sets, err = c.Snapshots(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshots failed %s", err)
+	}
+	if len(sets) != 1 || sets[0].ID != "dep1-2" {
+		t.Fatalf("Only the newest snapshot set should be left %v", sets)
+	}
+}
+
+func TestSelectPrunable(t *testing.T) {
+	now := time.Now()
+	sets := []sdutils.SnapshotDescription{
+		{ID: "a", Created: now.Add(-72 * time.Hour), Complete: true},
+		{ID: "b", Created: now.Add(-48 * time.Hour), Complete: true},
+		{ID: "c", Created: now.Add(-24 * time.Hour), Complete: true},
+		{ID: "d", Created: now.Add(-1 * time.Hour), Complete: false},
+		{ID: "e", Created: now, Complete: true},
+	}
+	prune := selectPrunable(sets, 2, 0, now)
+	if len(prune) != 2 || prune[0].ID != "b" || prune[1].ID != "a" {
+		t.Fatalf("The wrong snapshot sets were selected %v", prune)
+	}
+	prune = selectPrunable(sets, 0, 36*time.Hour, now)
+	if len(prune) != 2 || prune[0].ID != "b" || prune[1].ID != "a" {
+		t.Fatalf("Only the sets older than 36 hours should be selected %v", prune)
+	}
+	prune = selectPrunable(sets, 10, 0, now)
+	if len(prune) != 0 {
+		t.Fatalf("Nothing should be pruned when fewer sets exist than are kept %v", prune)
+	}
+}
@@ -0,0 +1,252 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// DefaultDatabaseOptions are the options DatabaseOptions shows when no names
+// are given.
+var DefaultDatabaseOptions = []string{
+	"database.name",
+	"database.online",
+	"database.archetypes",
+	"index.type",
+	"reasoning.type",
+	"search.enabled",
+	"strict.parsing",
+	"query.all.graphs",
+	"transaction.isolation",
+}
+
+// stardog returns a client for the Stardog API of the running deployment.
+func (c *Client) stardog(ctx context.Context) (*sdutils.StardogClient, error) {
+	dep, baseD, _, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !dep.InstanceExists() {
+		return nil, fmt.Errorf("The deployment %s is not running", baseD.Name)
+	}
+	sd, err := dep.FullStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sd.StardogURL == "" {
+		return nil, fmt.Errorf("The deployment %s has no Stardog URL", baseD.Name)
+	}
+	creds := c.credentials()
+	return sdutils.NewStardogClient(c.app, sd.StardogURL, creds.Username, creds.Password), nil
+}
+
+// Databases returns the names of the databases in the deployment.
+func (c *Client) Databases(ctx context.Context) ([]string, error) {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dbs, err := sd.ListDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dbs)
+	return dbs, nil
+}
+
+// CreateDatabase creates the database db with the options and loads the data
+// files into it, all in one request the way stardog-admin db create does.
+func (c *Client) CreateDatabase(ctx context.Context, db string, options map[string]interface{}, files []sdutils.DatabaseFile) error {
+	names := make(map[string]string)
+	for _, f := range files {
+		if !sdutils.PathExists(f.Path) {
+			return fmt.Errorf("The data file %s does not exist", f.Path)
+		}
+		// Stardog matches the uploaded files by their names
+		base := filepath.Base(f.Path)
+		if other, ok := names[base]; ok {
+			return fmt.Errorf("The data files %s and %s have the same name", other, f.Path)
+		}
+		names[base] = f.Path
+	}
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	c.app.ConsoleLog(1, "Creating the database %s with %d data files...\n", db, len(files))
+	err = sd.CreateDatabase(ctx, db, options, files)
+	if err != nil {
+		return err
+	}
+	c.app.ConsoleLog(1, "%s\n", c.app.SuccessString(fmt.Sprintf("Created the database %s", db)))
+	return nil
+}
+
+// DropDatabase deletes the database db and all of its data.
+func (c *Client) DropDatabase(ctx context.Context, db string) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.DropDatabase(ctx, db)
+}
+
+// SetDatabaseOnline brings the database db online or takes it offline.
+func (c *Client) SetDatabaseOnline(ctx context.Context, db string, online bool) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.SetDatabaseOnline(ctx, db, online)
+}
+
+// DatabaseOptions returns the values of the named options of the database db,
+// or of DefaultDatabaseOptions when names is empty.
+func (c *Client) DatabaseOptions(ctx context.Context, db string, names []string) (map[string]interface{}, error) {
+	if len(names) == 0 {
+		names = DefaultDatabaseOptions
+	}
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sd.GetDatabaseOptions(ctx, db, names)
+}
+
+// SetDatabaseOptions changes the options of the database db.  Stardog only
+// changes most options of an offline database, so an online database is taken
+// offline for the change and brought back online afterwards.
+func (c *Client) SetDatabaseOptions(ctx context.Context, db string, options map[string]interface{}) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := sd.GetDatabaseOptions(ctx, db, []string{"database.online"})
+	if err != nil {
+		return err
+	}
+	online, _ := current["database.online"].(bool)
+	if online {
+		c.app.ConsoleLog(1, "Taking the database %s offline to change its options.\n", db)
+		err = sd.SetDatabaseOnline(ctx, db, false)
+		if err != nil {
+			return err
+		}
+	}
+	err = sd.SetDatabaseOptions(ctx, db, options)
+	if online {
+		oerr := sd.SetDatabaseOnline(ctx, db, true)
+		if oerr != nil {
+			c.app.ConsoleLog(0, "Failed to bring the database %s back online: %s\n", db, oerr)
+			if err == nil {
+				err = oerr
+			}
+		}
+	}
+	return err
+}
+
+// LoadDatabaseOptions reads database options from a file.  A file that ends
+// in .json holds a JSON object.  Any other file is a Java properties file like
+// the one stardog-admin db create takes, see ParseDatabaseOptions.
+func LoadDatabaseOptions(path string) (map[string]interface{}, error) {
+	if strings.HasSuffix(path, ".json") {
+		options := make(map[string]interface{})
+		err := sdutils.LoadJSON(&options, path)
+		if err != nil {
+			return nil, fmt.Errorf("The options file %s is not valid: %s", path, err)
+		}
+		return options, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	options, err := ParseDatabaseOptions(lines)
+	if err != nil {
+		return nil, fmt.Errorf("The options file %s is not valid: %s", path, err)
+	}
+	return options, nil
+}
+
+// ParseDatabaseOptions turns name=value pairs into database options.  The
+// values true and false become booleans and every other value is sent to
+// Stardog as a string.
+func ParseDatabaseOptions(pairs []string) (map[string]interface{}, error) {
+	options := make(map[string]interface{})
+	for _, p := range pairs {
+		i := strings.IndexAny(p, "=:")
+		if i < 1 {
+			return nil, fmt.Errorf("The option %s is not of the form name=value", p)
+		}
+		name := strings.TrimSpace(p[:i])
+		value := strings.TrimSpace(p[i+1:])
+		switch value {
+		case "true":
+			options[name] = true
+		case "false":
+			options[name] = false
+		default:
+			options[name] = value
+		}
+	}
+	return options, nil
+}
+
+// FormatDatabaseOptions returns the options as name=value lines sorted by
+// name.
+func FormatDatabaseOptions(options map[string]interface{}) string {
+	names := []string{}
+	for n := range options {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	for _, n := range names {
+		v := options[n]
+		if _, simple := v.(string); !simple {
+			data, err := json.Marshal(v)
+			if err == nil {
+				v = string(data)
+			}
+		}
+		fmt.Fprintf(&b, "%s=%v\n", n, v)
+	}
+	return b.String()
+}
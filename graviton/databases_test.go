@@ -0,0 +1,134 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestClientDatabases(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	calls := []string{}
+	online := true
+	var setOptions map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/admin/databases":
+			w.Write([]byte(`{"databases":["zeta","alpha"]}`))
+		case r.Method == "PUT" && r.URL.Path == "/admin/databases/alpha/options":
+			w.Write([]byte(`{"database.online":` + map[bool]string{true: "true", false: "false"}[online] + `}`))
+		case r.Method == "POST" && r.URL.Path == "/admin/databases/alpha/options":
+			json.NewDecoder(r.Body).Decode(&setOptions)
+		case r.URL.Path == "/admin/databases/alpha/offline":
+			online = false
+		case r.URL.Path == "/admin/databases/alpha/online":
+			online = true
+		}
+	}))
+	defer ts.Close()
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+	p.sdURL = ts.URL
+
+	ctx := context.Background()
+	_, err := c.Databases(ctx)
+	if err == nil {
+		t.Fatal("The databases of a deployment that does not exist cannot be listed")
+	}
+	_, err = c.Launch(ctx)
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	dbs, err := c.Databases(ctx)
+	if err != nil {
+		t.Fatalf("Listing the databases failed %s", err)
+	}
+	if len(dbs) != 2 || dbs[0] != "alpha" || dbs[1] != "zeta" {
+		t.Fatalf("The databases are wrong %v", dbs)
+	}
+
+	err = c.SetDatabaseOptions(ctx, "alpha", map[string]interface{}{"search.enabled": true})
+	if err != nil {
+		t.Fatalf("Setting the options failed %s", err)
+	}
+	if setOptions["search.enabled"] != true || !online {
+		t.Fatalf("The options were not set on an offline database %v %t", setOptions, online)
+	}
+	if calls[len(calls)-3] != "PUT /admin/databases/alpha/offline" || calls[len(calls)-1] != "PUT /admin/databases/alpha/online" {
+		t.Fatalf("The database should have been taken offline for the change %v", calls)
+	}
+
+	err = c.CreateDatabase(ctx, "beta", nil, []sdutils.DatabaseFile{{Path: filepath.Join(dir, "nothere.ttl")}})
+	if err == nil {
+		t.Fatal("A missing data file should be refused")
+	}
+	os.MkdirAll(filepath.Join(dir, "a"), 0755)
+	os.MkdirAll(filepath.Join(dir, "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a", "rows.ttl"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b", "rows.ttl"), []byte(""), 0644)
+	files := []sdutils.DatabaseFile{{Path: filepath.Join(dir, "a", "rows.ttl")}, {Path: filepath.Join(dir, "b", "rows.ttl")}}
+	err = c.CreateDatabase(ctx, "beta", nil, files)
+	if err == nil {
+		t.Fatal("Data files with the same name should be refused")
+	}
+}
+
+func TestLoadDatabaseOptions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "graviton")
+	defer os.RemoveAll(dir)
+
+	props := filepath.Join(dir, "db.properties")
+	ioutil.WriteFile(props, []byte("# comment\nsearch.enabled = true\nindex.type=disk\n\nreasoning.type: SL\n"), 0644)
+	options, err := LoadDatabaseOptions(props)
+	if err != nil {
+		t.Fatalf("The properties file should load %s", err)
+	}
+	if len(options) != 3 || options["search.enabled"] != true || options["index.type"] != "disk" || options["reasoning.type"] != "SL" {
+		t.Fatalf("The options are wrong %v", options)
+	}
+
+	jsonFile := filepath.Join(dir, "db.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"search.enabled": false, "database.archetypes": ["skos"]}`), 0644)
+	options, err = LoadDatabaseOptions(jsonFile)
+	if err != nil {
+		t.Fatalf("The JSON file should load %s", err)
+	}
+	if options["search.enabled"] != false || len(options["database.archetypes"].([]interface{})) != 1 {
+		t.Fatalf("The options are wrong %v", options)
+	}
+	if FormatDatabaseOptions(options) != "database.archetypes=[\"skos\"]\nsearch.enabled=false\n" {
+		t.Fatalf("The options were not formatted %s", FormatDatabaseOptions(options))
+	}
+
+	_, err = ParseDatabaseOptions([]string{"novalue"})
+	if err == nil {
+		t.Fatal("An option without a value should be refused")
+	}
+}
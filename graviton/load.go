@@ -0,0 +1,378 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// LoadOptions controls how Load sends data to Stardog.  Zero values are
+// replaced by the defaults.
+type LoadOptions struct {
+	// ChunkSize is the number of triples that are added in one
+	// transaction.  N-Triples and N-Quads files are cut after exactly that
+	// many triples.  Turtle, TriG and RDF/XML files are cut between
+	// statements once about that many triples were read.  JSON-LD files,
+	// and RDF/XML files whose root is not rdf:RDF, are streamed whole in
+	// one transaction.
+	ChunkSize int
+	// Parallel is the number of transactions that run at the same time.
+	Parallel int
+	// Retries is how many times a chunk that failed is sent again.
+	Retries int
+	// Graph is the named graph to load the data into.  The default graph is
+	// used when it is empty.
+	Graph string
+}
+
+// LoadResult describes what Load sent to Stardog.  Triples only counts the
+// triples of N-Triples and N-Quads files, the chunks of the other formats are
+// counted in Uncounted.  SizeAfter - SizeBefore is how much the database grew.
+type LoadResult struct {
+	Files      int
+	Chunks     int
+	Triples    int64
+	Uncounted  int
+	SizeBefore int64
+	SizeAfter  int64
+}
+
+// chunkSplitter reads a file of one format and sends it in chunks of about
+// chunkSize triples.  It returns errNotSplittable, before sending anything,
+// when the file has to be sent whole.
+type chunkSplitter func(r io.Reader, path string, contentType string, chunkSize int, send func(loadChunk) error) error
+
+type rdfFormat struct {
+	contentType string
+	split       chunkSplitter
+}
+
+var rdfFormats = map[string]rdfFormat{
+	".nt":     {"application/n-triples", readLineChunks},
+	".nq":     {"application/n-quads", readLineChunks},
+	".ttl":    {"text/turtle", readTurtleChunks},
+	".rdf":    {"application/rdf+xml", readXMLChunks},
+	".owl":    {"application/rdf+xml", readXMLChunks},
+	".xml":    {"application/rdf+xml", readXMLChunks},
+	".trig":   {"application/trig", readTurtleChunks},
+	".jsonld": {"application/ld+json", nil},
+}
+
+// loadChunk is a part of a file that is added in one transaction.  A chunk
+// without data is the whole file, it is read from the disk every time it is
+// sent so it never sits in memory.  triples is -1 when they were not
+// counted.
+type loadChunk struct {
+	file        string
+	index       int
+	data        []byte
+	gzipped     bool
+	contentType string
+	triples     int64
+}
+
+type chunkResult struct {
+	chunk loadChunk
+	err   error
+}
+
+// fileFormat returns the RDF format of the file from its extension.  A .gz
+// extension means the file is compressed with gzip.
+func fileFormat(path string) (rdfFormat, bool, error) {
+	name := strings.ToLower(path)
+	gzipped := strings.HasSuffix(name, ".gz")
+	if gzipped {
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	format, ok := rdfFormats[filepath.Ext(name)]
+	if !ok {
+		return format, false, fmt.Errorf("The format of %s is not known from its extension", path)
+	}
+	return format, gzipped, nil
+}
+
+// Load streams the RDF files into the database db.  The files are cut into
+// chunks and every chunk is added in its own transaction, several at a time.
+// A chunk that fails is rolled back and sent again.  The chunks that were
+// committed stay in the database when the load fails.
+func (c *Client) Load(ctx context.Context, db string, files []string, opts LoadOptions) (*LoadResult, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No files to load were given")
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 10000
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = 4
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	for _, f := range files {
+		if !sdutils.PathExists(f) {
+			return nil, fmt.Errorf("The data file %s does not exist", f)
+		}
+		_, _, err := fileFormat(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &LoadResult{Files: len(files)}
+	result.SizeBefore, err = sd.DatabaseSize(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	loadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks := make(chan loadChunk, opts.Parallel)
+	results := make(chan chunkResult)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(chunks)
+		readErr <- readChunks(loadCtx, files, opts.ChunkSize, chunks)
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ch := range chunks {
+				results <- chunkResult{chunk: ch, err: c.loadChunk(loadCtx, sd, db, ch, opts)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	spinner := sdutils.NewSpinner(c.app, 1, fmt.Sprintf("Loading the data into %s", db))
+	var firstErr error
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("Failed to load chunk %d of %s: %s", r.chunk.index, r.chunk.file, r.err)
+				cancel()
+			}
+			continue
+		}
+		result.Chunks++
+		if r.chunk.triples < 0 {
+			result.Uncounted++
+		} else {
+			result.Triples += r.chunk.triples
+		}
+		spinner.SetMessage(fmt.Sprintf("Loaded %d chunks with %d triples into %s", result.Chunks, result.Triples, db))
+		spinner.EchoNext()
+	}
+	spinner.Close()
+	err = <-readErr
+	if firstErr == nil && err != nil {
+		firstErr = err
+	}
+	if firstErr != nil {
+		c.app.ConsoleLog(0, "%d chunks with %d triples were committed before the load failed.\n", result.Chunks, result.Triples)
+		return result, firstErr
+	}
+
+	result.SizeAfter, err = sd.DatabaseSize(ctx, db)
+	if err != nil {
+		return result, err
+	}
+	grew := result.SizeAfter - result.SizeBefore
+	if result.Uncounted == 0 {
+		if grew != result.Triples {
+			c.app.ConsoleLog(0, "%s\n", c.app.FailString(fmt.Sprintf("%d triples were sent but the database grew by %d.  The data may have duplicates or triples that were already in the database.",
+				result.Triples, grew)))
+		}
+		return result, nil
+	}
+	c.app.ConsoleLog(1, "The triples of %d chunks were not counted, %d triples were counted in the N-Triples and N-Quads files and the database grew by %d.\n",
+		result.Uncounted, result.Triples, grew)
+	if grew < result.Triples {
+		c.app.ConsoleLog(0, "%s\n", c.app.FailString(fmt.Sprintf("The database grew by %d which is less than the %d triples that were counted.  The data may have duplicates or triples that were already in the database.",
+			grew, result.Triples)))
+	}
+	return result, nil
+}
+
+// loadChunk adds the chunk in a transaction and tries again with a growing
+// delay when that fails.  Adding a chunk again is harmless because the
+// triples of a graph are a set.
+func (c *Client) loadChunk(ctx context.Context, sd *sdutils.StardogClient, db string, ch loadChunk, opts LoadOptions) error {
+	delay := time.Second
+	for i := 0; ; i++ {
+		err := addChunk(ctx, sd, db, ch, opts.Graph)
+		if err == nil {
+			return nil
+		}
+		if i >= opts.Retries || ctx.Err() != nil {
+			return err
+		}
+		c.app.Logf(sdutils.WARN, "Chunk %d of %s failed, sending it again in %s: %s", ch.index, ch.file, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = delay * 2
+	}
+}
+
+func addChunk(ctx context.Context, sd *sdutils.StardogClient, db string, ch loadChunk, graph string) error {
+	var data io.Reader = bytes.NewReader(ch.data)
+	if ch.data == nil {
+		f, err := openData(ch.file, ch.gzipped)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		data = f
+	}
+	tx, err := sd.BeginTransaction(ctx, db)
+	if err != nil {
+		return err
+	}
+	err = sd.AddData(ctx, db, tx, data, ch.contentType, graph)
+	if err != nil {
+		// The context may be done so the rollback gets its own
+		rerr := sd.RollbackTransaction(context.Background(), db, tx)
+		if rerr != nil {
+			return fmt.Errorf("%s and the rollback failed: %s", err, rerr)
+		}
+		return err
+	}
+	return sd.CommitTransaction(ctx, db, tx)
+}
+
+// dataFile reads a data file that may be compressed with gzip.
+type dataFile struct {
+	io.Reader
+	f *os.File
+}
+
+func (d *dataFile) Close() error {
+	return d.f.Close()
+}
+
+func openData(path string, gzipped bool) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !gzipped {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("The file %s is not valid gzip: %s", path, err)
+	}
+	return &dataFile{Reader: gz, f: f}, nil
+}
+
+// readChunks cuts the files into chunks of about chunkSize triples.  The
+// files that cannot be cut are sent whole and read again from the disk when
+// the chunk is added.
+func readChunks(ctx context.Context, files []string, chunkSize int, chunks chan<- loadChunk) error {
+	send := func(ch loadChunk) error {
+		select {
+		case chunks <- ch:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for _, path := range files {
+		format, gzipped, err := fileFormat(path)
+		if err != nil {
+			return err
+		}
+		if format.split != nil {
+			r, err := openData(path, gzipped)
+			if err != nil {
+				return err
+			}
+			err = format.split(r, path, format.contentType, chunkSize, send)
+			r.Close()
+			if err == nil {
+				continue
+			}
+			if err != errNotSplittable {
+				return err
+			}
+		}
+		err = send(loadChunk{file: path, gzipped: gzipped, contentType: format.contentType, triples: -1})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLineChunks cuts N-Triples and N-Quads files, where every line that is
+// not blank or a comment is one triple.
+func readLineChunks(r io.Reader, path string, contentType string, chunkSize int, send func(loadChunk) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var buf bytes.Buffer
+	var triples int64
+	index := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		buf.Write(line)
+		buf.WriteByte('\n')
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		triples++
+		if triples < int64(chunkSize) {
+			continue
+		}
+		err := send(loadChunk{file: path, index: index, data: append([]byte(nil), buf.Bytes()...), contentType: contentType, triples: triples})
+		if err != nil {
+			return err
+		}
+		index++
+		buf.Reset()
+		triples = 0
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Failed to read %s: %s", path, err)
+	}
+	if triples == 0 {
+		return nil
+	}
+	return send(loadChunk{file: path, index: index, data: buf.Bytes(), contentType: contentType, triples: triples})
+}
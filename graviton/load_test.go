@@ -0,0 +1,153 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// fakeStore is a Stardog that treats every line it is sent as a triple.
+type fakeStore struct {
+	mu        sync.Mutex
+	triples   map[string]bool
+	pending   map[string][]string
+	nextTx    int
+	failAdds  int
+	rollbacks int
+}
+
+func (f *fakeStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[1] == "size":
+		fmt.Fprintf(w, "%d", len(f.triples))
+	case len(parts) == 3 && parts[2] == "begin":
+		f.nextTx++
+		tx := fmt.Sprintf("tx%d", f.nextTx)
+		f.pending[tx] = []string{}
+		w.Write([]byte(tx))
+	case len(parts) == 3 && parts[2] == "add":
+		if f.failAdds > 0 {
+			f.failAdds--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		for _, l := range strings.Split(string(data), "\n") {
+			l = strings.TrimSpace(l)
+			if l != "" && !strings.HasPrefix(l, "#") {
+				f.pending[parts[1]] = append(f.pending[parts[1]], l)
+			}
+		}
+	case len(parts) == 4 && parts[2] == "commit":
+		for _, l := range f.pending[parts[3]] {
+			f.triples[l] = true
+		}
+		delete(f.pending, parts[3])
+	case len(parts) == 4 && parts[2] == "rollback":
+		f.rollbacks++
+		delete(f.pending, parts[3])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClientLoad(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	store := &fakeStore{triples: make(map[string]bool), pending: make(map[string][]string), failAdds: 1}
+	ts := httptest.NewServer(store)
+	defer ts.Close()
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+	p.sdURL = ts.URL
+	ctx := context.Background()
+	_, err := c.Launch(ctx)
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+
+	var nt bytes.Buffer
+	nt.WriteString("# some rows\n")
+	for i := 0; i < 25; i++ {
+		fmt.Fprintf(&nt, "<urn:s%d> <urn:p> \"%d\" .\n\n", i, i)
+	}
+	ntFile := filepath.Join(dir, "rows.nt")
+	ioutil.WriteFile(ntFile, nt.Bytes(), 0644)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(zw, "<urn:g%d> <urn:p> <urn:o> .\n", i)
+	}
+	zw.Close()
+	gzFile := filepath.Join(dir, "more.nt.gz")
+	ioutil.WriteFile(gzFile, gz.Bytes(), 0644)
+
+	ttlFile := filepath.Join(dir, "extra.ttl")
+	ioutil.WriteFile(ttlFile, []byte("<urn:t1> <urn:p> <urn:o> .\n<urn:t2> <urn:p> <urn:o> .\n"), 0644)
+
+	jsonFile := filepath.Join(dir, "one.jsonld")
+	ioutil.WriteFile(jsonFile, []byte(`{"@id": "urn:j", "urn:p": "v"}`+"\n"), 0644)
+
+	_, err = c.Load(ctx, "mydb", []string{filepath.Join(dir, "rows.csv")}, LoadOptions{})
+	if err == nil {
+		t.Fatal("A file that does not exist should be refused")
+	}
+	ioutil.WriteFile(filepath.Join(dir, "rows.csv"), []byte(""), 0644)
+	_, err = c.Load(ctx, "mydb", []string{filepath.Join(dir, "rows.csv")}, LoadOptions{})
+	if err == nil {
+		t.Fatal("A file of an unknown format should be refused")
+	}
+
+	result, err := c.Load(ctx, "mydb", []string{ntFile, gzFile, ttlFile, jsonFile}, LoadOptions{ChunkSize: 10, Parallel: 3, Retries: 2})
+	if err != nil {
+		t.Fatalf("The load failed %s", err)
+	}
+	if result.Files != 4 || result.Chunks != 6 || result.Triples != 35 || result.Uncounted != 2 {
+		t.Fatalf("The load result is wrong %+v", result)
+	}
+	if result.SizeBefore != 0 || result.SizeAfter != 38 {
+		t.Fatalf("The database size is wrong %+v", result)
+	}
+	if store.rollbacks != 1 || len(store.pending) != 0 {
+		t.Fatalf("The failed chunk should have been rolled back %d %v", store.rollbacks, store.pending)
+	}
+
+	store.failAdds = 100
+	_, err = c.Load(ctx, "mydb", []string{ntFile}, LoadOptions{ChunkSize: 10, Retries: 1})
+	if err == nil {
+		t.Fatal("The load should fail when a chunk keeps failing")
+	}
+}
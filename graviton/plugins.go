@@ -0,0 +1,54 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"github.com/stardog-union/stardog-graviton/aws"
+	"github.com/stardog-union/stardog-graviton/azure"
+	"github.com/stardog-union/stardog-graviton/baremetal"
+	"github.com/stardog-union/stardog-graviton/docker"
+	"github.com/stardog-union/stardog-graviton/gcp"
+	"github.com/stardog-union/stardog-graviton/kubernetes"
+	"github.com/stardog-union/stardog-graviton/local"
+	"github.com/stardog-union/stardog-graviton/rpcplugin"
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// Plugins returns the built in plugins followed by the external plugins
+// found for the configuration directory.  A built in plugin wins over an
+// external one with the same name.
+func Plugins(confDir string) []sdutils.Plugin {
+	plugins := []sdutils.Plugin{
+		aws.GetPlugin(),
+		local.GetPlugin(),
+		docker.GetPlugin(),
+		kubernetes.GetPlugin(),
+		baremetal.GetPlugin(),
+		gcp.GetPlugin(),
+		azure.GetPlugin(),
+	}
+	names := make(map[string]bool)
+	for _, p := range plugins {
+		names[p.GetName()] = true
+	}
+	for _, p := range rpcplugin.Discover(rpcplugin.SearchPath(confDir)) {
+		if names[p.GetName()] {
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins
+}
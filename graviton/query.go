@@ -0,0 +1,190 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// QueryFormats are the output formats of Query.  Select and ask results can
+// be written as a table, CSV, TSV or SPARQL-JSON.  Construct and describe
+// results are written as Turtle, or as JSON-LD when json is asked for.
+var QueryFormats = []string{"table", "csv", "tsv", "json", "turtle"}
+
+type sparqlTerm struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Lang     string `json:"xml:lang"`
+	Datatype string `json:"datatype"`
+}
+
+type sparqlResults struct {
+	Head struct {
+		Vars []string `json:"vars"`
+	} `json:"head"`
+	Boolean *bool `json:"boolean"`
+	Results struct {
+		Bindings []map[string]sparqlTerm `json:"bindings"`
+	} `json:"results"`
+}
+
+// queryAccept returns the media type to ask Stardog for so that the results of
+// a query of queryType can be written in format.
+func queryAccept(queryType string, format string) (string, error) {
+	switch queryType {
+	case sdutils.SelectQuery, sdutils.AskQuery:
+		switch format {
+		case "table", "json":
+			return "application/sparql-results+json", nil
+		case "csv":
+			if queryType == sdutils.AskQuery {
+				return "text/boolean", nil
+			}
+			return "text/csv", nil
+		case "tsv":
+			if queryType == sdutils.AskQuery {
+				return "text/boolean", nil
+			}
+			return "text/tab-separated-values", nil
+		}
+	case sdutils.ConstructQuery, sdutils.DescribeQuery:
+		switch format {
+		case "table", "turtle":
+			return "text/turtle", nil
+		case "json":
+			return "application/ld+json", nil
+		}
+	case sdutils.UpdateQuery:
+		return "", nil
+	}
+	return "", fmt.Errorf("The results of a %s query cannot be written as %s", queryType, format)
+}
+
+// Query runs the SPARQL query or update q against the database db and writes
+// the results to out in format, one of QueryFormats.
+func (c *Client) Query(ctx context.Context, db string, q string, opts sdutils.QueryOptions, format string, out io.Writer) error {
+	queryType, err := sdutils.QueryType(q)
+	if err != nil {
+		return err
+	}
+	accept, err := queryAccept(queryType, format)
+	if err != nil {
+		return err
+	}
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	// Give Stardog the time to answer that the query may take
+	if opts.Timeout+time.Minute > sd.Timeout {
+		sd.Timeout = opts.Timeout + time.Minute
+	}
+	if queryType == sdutils.UpdateQuery {
+		err = sd.Update(ctx, db, q, opts)
+		if err != nil {
+			return err
+		}
+		c.app.ConsoleLog(1, "Update query processed successfully.\n")
+		return nil
+	}
+	content, err := sd.Query(ctx, db, q, opts, accept)
+	if err != nil {
+		return err
+	}
+	if format != "table" || accept != "application/sparql-results+json" {
+		_, err = out.Write(content)
+		return err
+	}
+	return writeResultsTable(content, out)
+}
+
+func (t sparqlTerm) String() string {
+	switch t.Type {
+	case "bnode":
+		return "_:" + t.Value
+	case "literal", "typed-literal":
+		if t.Lang != "" {
+			return fmt.Sprintf("\"%s\"@%s", t.Value, t.Lang)
+		}
+	}
+	return t.Value
+}
+
+// writeResultsTable writes SPARQL-JSON results as a text table, the way the
+// Stardog command line shows them.
+func writeResultsTable(content []byte, out io.Writer) error {
+	var results sparqlResults
+	err := json.Unmarshal(content, &results)
+	if err != nil {
+		return fmt.Errorf("The query results are not valid SPARQL-JSON: %s", err)
+	}
+	if results.Boolean != nil {
+		_, err = fmt.Fprintf(out, "%t\n", *results.Boolean)
+		return err
+	}
+
+	vars := results.Head.Vars
+	rows := [][]string{}
+	widths := make([]int, len(vars))
+	for i, v := range vars {
+		widths[i] = utf8.RuneCountInString(v)
+	}
+	for _, b := range results.Results.Bindings {
+		row := make([]string, len(vars))
+		for i, v := range vars {
+			if term, ok := b[v]; ok {
+				row[i] = strings.Replace(term.String(), "\n", "\\n", -1)
+			}
+			if w := utf8.RuneCountInString(row[i]); w > widths[i] {
+				widths[i] = w
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	line := func() {
+		for _, w := range widths {
+			buf.WriteString("+" + strings.Repeat("-", w+2))
+		}
+		buf.WriteString("+\n")
+	}
+	cells := func(values []string) {
+		for i, v := range values {
+			buf.WriteString("| " + v + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)) + " ")
+		}
+		buf.WriteString("|\n")
+	}
+	line()
+	cells(vars)
+	line()
+	for _, row := range rows {
+		cells(row)
+	}
+	line()
+	fmt.Fprintf(&buf, "\nQuery returned %d results.\n", len(rows))
+	_, err = out.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,104 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestClientQuery(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	var accept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		switch accept {
+		case "application/sparql-results+json":
+			w.Write([]byte(`{"head":{"vars":["s","name"]},"results":{"bindings":[
+				{"s":{"type":"uri","value":"urn:a"},"name":{"type":"literal","value":"Zoë","xml:lang":"fr"}},
+				{"s":{"type":"bnode","value":"b0"}}]}}`))
+		case "text/turtle":
+			w.Write([]byte("<urn:a> <urn:b> <urn:c> .\n"))
+		}
+	}))
+	defer ts.Close()
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+	p.sdURL = ts.URL
+	ctx := context.Background()
+	_, err := c.Launch(ctx)
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+
+	var out bytes.Buffer
+	err = c.Query(ctx, "mydb", "select ?s ?name { ?s ?p ?name }", sdutils.QueryOptions{}, "table", &out)
+	if err != nil {
+		t.Fatalf("The query failed %s", err)
+	}
+	expected := `+-------+----------+
+| s     | name     |
++-------+----------+
+| urn:a | "Zoë"@fr |
+| _:b0  |          |
++-------+----------+
+
+Query returned 2 results.
+`
+	if out.String() != expected {
+		t.Fatalf("The table is wrong\n%s", out.String())
+	}
+
+	out.Reset()
+	err = c.Query(ctx, "mydb", "construct { ?s ?p ?o } where { ?s ?p ?o }", sdutils.QueryOptions{}, "table", &out)
+	if err != nil {
+		t.Fatalf("The query failed %s", err)
+	}
+	if accept != "text/turtle" || out.String() != "<urn:a> <urn:b> <urn:c> .\n" {
+		t.Fatalf("The graph was not written as turtle %s %s", accept, out.String())
+	}
+
+	err = c.Query(ctx, "mydb", "select * { ?s ?p ?o }", sdutils.QueryOptions{}, "turtle", &out)
+	if err == nil {
+		t.Fatal("Select results cannot be written as turtle")
+	}
+	err = c.Query(ctx, "mydb", "construct { ?s ?p ?o } where { ?s ?p ?o }", sdutils.QueryOptions{}, "csv", &out)
+	if err == nil {
+		t.Fatal("A graph cannot be written as CSV")
+	}
+}
+
+func TestResultsTableAsk(t *testing.T) {
+	var out bytes.Buffer
+	err := writeResultsTable([]byte(`{"head":{},"boolean":true}`), &out)
+	if err != nil || out.String() != "true\n" {
+		t.Fatalf("The ask result is wrong %s %v", out.String(), err)
+	}
+	err = writeResultsTable([]byte(`not json`), &out)
+	if err == nil {
+		t.Fatal("Invalid results should be refused")
+	}
+}
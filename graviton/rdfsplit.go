@@ -0,0 +1,418 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errNotSplittable is returned by a chunkSplitter when the file has to be
+// sent whole.
+var errNotSplittable = errors.New("The file cannot be cut into chunks")
+
+// turtleChunker collects the statements of a Turtle or TriG file into
+// chunks.  Every chunk starts with the prefix and base directives that were
+// read before it so that it parses on its own.  A chunk that is cut inside a
+// TriG graph block closes the block and the next chunk opens it again.
+type turtleChunker struct {
+	path        string
+	contentType string
+	chunkSize   int
+	send        func(loadChunk) error
+
+	directives bytes.Buffer
+	chunk      bytes.Buffer
+	chunkGraph string
+	estimate   int64
+	index      int
+}
+
+func (t *turtleChunker) directive(text string) error {
+	t.directives.WriteString(text)
+	t.directives.WriteByte('\n')
+	if t.chunk.Len() == 0 {
+		return nil
+	}
+	t.closeGraph()
+	t.chunk.WriteString(text)
+	t.chunk.WriteByte('\n')
+	return nil
+}
+
+// statement adds a statement of the graph block graph, which is empty
+// outside of blocks.  triples is an estimate of the triples it has.
+func (t *turtleChunker) statement(text string, graph string, triples int64) error {
+	if t.chunk.Len() == 0 {
+		t.chunk.Write(t.directives.Bytes())
+	}
+	if graph != t.chunkGraph {
+		t.closeGraph()
+		if graph != "" {
+			t.chunk.WriteString(graph)
+			t.chunk.WriteByte('\n')
+		}
+		t.chunkGraph = graph
+	}
+	t.chunk.WriteString(text)
+	if graph != "" && !strings.HasSuffix(text, ".") {
+		// The last statement of a block does not need a dot
+		t.chunk.WriteString(" .")
+	}
+	t.chunk.WriteByte('\n')
+	t.estimate += triples
+	if t.estimate < int64(t.chunkSize) {
+		return nil
+	}
+	return t.flush()
+}
+
+func (t *turtleChunker) closeGraph() {
+	if t.chunkGraph != "" {
+		t.chunk.WriteString("}\n")
+		t.chunkGraph = ""
+	}
+}
+
+func (t *turtleChunker) flush() error {
+	if t.estimate == 0 {
+		return nil
+	}
+	t.closeGraph()
+	err := t.send(loadChunk{file: t.path, index: t.index, data: append([]byte(nil), t.chunk.Bytes()...), contentType: t.contentType, triples: -1})
+	if err != nil {
+		return err
+	}
+	t.index++
+	t.chunk.Reset()
+	t.estimate = 0
+	return nil
+}
+
+func isDirective(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "@prefix", "@base", "prefix", "base":
+		return true
+	}
+	return false
+}
+
+// isSparqlDirective tells if text is a PREFIX or BASE directive, which ends
+// with its IRI instead of a dot.
+func isSparqlDirective(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "prefix", "base":
+		return true
+	}
+	return false
+}
+
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' ||
+		b == '_' || b == '-' || b == ':' || b == '%' || b >= 0x80
+}
+
+// readTurtleChunks cuts Turtle and TriG files between statements.  The
+// triples are not counted, the size of a chunk is estimated from the
+// separators of its statements.  Blank node labels are scoped to the chunk
+// they are in so a label that is used across chunks makes different nodes.
+func readTurtleChunks(r io.Reader, path string, contentType string, chunkSize int, send func(loadChunk) error) error {
+	const (
+		normal = iota
+		inIRI
+		inString
+		inComment
+	)
+	t := &turtleChunker{path: path, contentType: contentType, chunkSize: chunkSize, send: send}
+	in := bufio.NewReader(r)
+	var stmt bytes.Buffer
+	state := normal
+	var quote byte
+	long := false
+	quotes := 0
+	depth := 0
+	graph := ""
+	inGraph := false
+	content := false
+	var triples int64 = 1
+
+	finish := func() error {
+		text := strings.TrimSpace(stmt.String())
+		hasContent := content
+		n := triples
+		stmt.Reset()
+		content = false
+		triples = 1
+		if !hasContent {
+			return nil
+		}
+		if isDirective(text) {
+			return t.directive(text)
+		}
+		key := ""
+		if inGraph {
+			key = graph
+		}
+		return t.statement(text, key, n)
+	}
+
+	for {
+		b, err := in.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read %s: %s", path, err)
+		}
+		switch state {
+		case inComment:
+			// Comments are dropped
+			if b == '\n' {
+				stmt.WriteByte(b)
+				state = normal
+			}
+			continue
+		case inIRI:
+			stmt.WriteByte(b)
+			if b == '>' {
+				state = normal
+				if depth == 0 && isSparqlDirective(stmt.String()) {
+					err = finish()
+				}
+			}
+		case inString:
+			stmt.WriteByte(b)
+			if b == '\\' {
+				next, err := in.ReadByte()
+				if err == nil {
+					stmt.WriteByte(next)
+				}
+				continue
+			}
+			if b != quote {
+				quotes = 0
+				continue
+			}
+			quotes++
+			if !long || quotes == 3 {
+				state = normal
+			}
+		default:
+			if b != ' ' && b != '\t' && b != '\r' && b != '\n' && b != '#' {
+				content = true
+			}
+			switch b {
+			case '#':
+				state = inComment
+			case '<':
+				state = inIRI
+				stmt.WriteByte(b)
+			case '"', '\'':
+				state = inString
+				quote = b
+				quotes = 0
+				long = false
+				stmt.WriteByte(b)
+				if next, _ := in.Peek(2); len(next) == 2 && next[0] == b && next[1] == b {
+					long = true
+					in.Discard(2)
+					stmt.WriteByte(b)
+					stmt.WriteByte(b)
+				}
+			case '[', '(':
+				depth++
+				stmt.WriteByte(b)
+			case ']', ')':
+				depth--
+				stmt.WriteByte(b)
+			case ',', ';':
+				triples++
+				stmt.WriteByte(b)
+			case '{':
+				if depth != 0 || inGraph {
+					stmt.WriteByte(b)
+					break
+				}
+				graph = strings.TrimSpace(strings.TrimSpace(stmt.String()) + " {")
+				inGraph = true
+				stmt.Reset()
+				content = false
+				triples = 1
+			case '}':
+				if depth != 0 || !inGraph {
+					stmt.WriteByte(b)
+					break
+				}
+				err = finish()
+				inGraph = false
+				graph = ""
+			case '.':
+				stmt.WriteByte(b)
+				if depth != 0 {
+					break
+				}
+				prev := byte(' ')
+				if stmt.Len() > 1 {
+					prev = stmt.Bytes()[stmt.Len()-2]
+				}
+				next, _ := in.Peek(1)
+				if isNameByte(prev) && len(next) == 1 && (isNameByte(next[0]) || next[0] == '.') {
+					// A dot inside a prefixed name or a number
+					break
+				}
+				err = finish()
+			default:
+				stmt.WriteByte(b)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err := finish()
+	if err != nil {
+		return err
+	}
+	return t.flush()
+}
+
+// xmlBuffer keeps the bytes that the XML decoder read so that the raw text
+// of an element can be sliced out by the offsets of the decoder.
+type xmlBuffer struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+}
+
+func (x *xmlBuffer) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	x.buf = append(x.buf, p[:n]...)
+	return n, err
+}
+
+func (x *xmlBuffer) slice(from int64, to int64) []byte {
+	return x.buf[from-x.base : to-x.base]
+}
+
+// discard forgets the bytes before the offset to.
+func (x *xmlBuffer) discard(to int64) {
+	x.buf = append(x.buf[:0], x.buf[to-x.base:]...)
+	x.base = to
+}
+
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// readXMLChunks cuts RDF/XML files between the node elements of the rdf:RDF
+// root.  Every chunk has the text of the file up to the end of the root start
+// tag, so the namespaces and the entities of the file are kept, followed by
+// whole node elements.  The triples are not counted, the size of a chunk is
+// estimated from the elements in it.  Files with another root are sent whole.
+func readXMLChunks(r io.Reader, path string, contentType string, chunkSize int, send func(loadChunk) error) error {
+	raw := &xmlBuffer{r: r}
+	d := xml.NewDecoder(raw)
+	d.Strict = false
+	var head []byte
+	var tail string
+	var chunk bytes.Buffer
+	var estimate int64
+	var start int64
+	index := 0
+	depth := 0
+	flush := func() error {
+		if estimate == 0 {
+			return nil
+		}
+		data := append(append([]byte(nil), head...), chunk.Bytes()...)
+		data = append(data, tail...)
+		err := send(loadChunk{file: path, index: index, data: data, contentType: contentType, triples: -1})
+		if err != nil {
+			return err
+		}
+		index++
+		chunk.Reset()
+		estimate = 0
+		return nil
+	}
+	for {
+		offset := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if head == nil {
+				return errNotSplittable
+			}
+			return fmt.Errorf("Failed to read %s: %s", path, err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				if tok.Name.Local != "RDF" {
+					return errNotSplittable
+				}
+				end := d.InputOffset()
+				head = append(append([]byte(nil), raw.slice(raw.base, end)...), '\n')
+				tail = "</" + xmlName(tok.Name) + ">\n"
+				raw.discard(end)
+			case depth == 2:
+				start = offset
+				estimate++
+			default:
+				estimate++
+			}
+		case xml.EndElement:
+			depth--
+			if depth != 1 {
+				break
+			}
+			end := d.InputOffset()
+			chunk.Write(raw.slice(start, end))
+			chunk.WriteByte('\n')
+			raw.discard(end)
+			if estimate >= int64(chunkSize) {
+				err = flush()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if head == nil {
+		return errNotSplittable
+	}
+	return flush()
+}
@@ -0,0 +1,144 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func splitAll(t *testing.T, split chunkSplitter, data string, chunkSize int) []string {
+	var chunks []string
+	err := split(strings.NewReader(data), "test", "text/test", chunkSize, func(ch loadChunk) error {
+		if ch.index != len(chunks) || ch.triples != -1 {
+			t.Fatalf("The chunk is wrong %+v", ch)
+		}
+		chunks = append(chunks, string(ch.data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("The split failed %s", err)
+	}
+	return chunks
+}
+
+func TestTurtleChunks(t *testing.T) {
+	data := `# people
+@prefix ex: <http://example.org/#> .
+ex:alice ex:name "Alice. #1" ;
+    ex:age 30.5 ;
+    ex:knows ex:bob, ex:carol .
+ex:bob ex:note """a long. note
+with "quotes" .""" .
+PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+ex:carol foaf:knows [ ex:name "Dan." ] .
+ex:dan ex:list ( ex:a ex:b ) .
+`
+	chunks := splitAll(t, readTurtleChunks, data, 3)
+	expected := []string{
+		"@prefix ex: <http://example.org/#> .\n" +
+			"ex:alice ex:name \"Alice. #1\" ;\n    ex:age 30.5 ;\n    ex:knows ex:bob, ex:carol .\n",
+		"@prefix ex: <http://example.org/#> .\n" +
+			"ex:bob ex:note \"\"\"a long. note\nwith \"quotes\" .\"\"\" .\n" +
+			"PREFIX foaf: <http://xmlns.com/foaf/0.1/>\n" +
+			"ex:carol foaf:knows [ ex:name \"Dan.\" ] .\n" +
+			"ex:dan ex:list ( ex:a ex:b ) .\n",
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks but got %q", len(expected), chunks)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Fatalf("Chunk %d is wrong, expected %q but got %q", i, expected[i], chunks[i])
+		}
+	}
+
+	chunks = splitAll(t, readTurtleChunks, data, 1)
+	if len(chunks) != 4 || !strings.HasPrefix(chunks[3], "@prefix ex: <http://example.org/#> .\nPREFIX foaf: <http://xmlns.com/foaf/0.1/>\nex:dan") {
+		t.Fatalf("Every chunk should start with the directives before it %q", chunks)
+	}
+}
+
+func TestTriGChunks(t *testing.T) {
+	data := `@prefix ex: <http://example.org/#> .
+ex:g1 {
+  ex:a ex:p ex:o1 .
+  ex:a ex:p ex:o2 .
+  ex:a ex:p ex:o3
+}
+{ ex:b ex:p ex:o }
+`
+	chunks := splitAll(t, readTurtleChunks, data, 2)
+	expected := []string{
+		"@prefix ex: <http://example.org/#> .\nex:g1 {\nex:a ex:p ex:o1 .\nex:a ex:p ex:o2 .\n}\n",
+		"@prefix ex: <http://example.org/#> .\nex:g1 {\nex:a ex:p ex:o3 .\n}\n{\nex:b ex:p ex:o .\n}\n",
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks but got %q", len(expected), chunks)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Fatalf("Chunk %d is wrong, expected %q but got %q", i, expected[i], chunks[i])
+		}
+	}
+}
+
+func TestXMLChunks(t *testing.T) {
+	head := `<?xml version="1.0"?>
+<!DOCTYPE rdf:RDF [ <!ENTITY ex "http://example.org/#"> ]>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="&ex;">`
+	data := head + `
+  <!-- rows -->
+  <rdf:Description rdf:about="&ex;a"><ex:p>1</ex:p></rdf:Description>
+  <ex:Thing rdf:about="&ex;b"><ex:p>2</ex:p></ex:Thing>
+  <rdf:Description rdf:about="&ex;c"/>
+</rdf:RDF>
+`
+	chunks := splitAll(t, readXMLChunks, data, 3)
+	expected := []string{
+		head + "\n" + `<rdf:Description rdf:about="&ex;a"><ex:p>1</ex:p></rdf:Description>` + "\n" + `<ex:Thing rdf:about="&ex;b"><ex:p>2</ex:p></ex:Thing>` + "\n</rdf:RDF>\n",
+		head + "\n" + `<rdf:Description rdf:about="&ex;c"/>` + "\n</rdf:RDF>\n",
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks but got %q", len(expected), chunks)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Fatalf("Chunk %d is wrong, expected %q but got %q", i, expected[i], chunks[i])
+		}
+		d := xml.NewDecoder(strings.NewReader(chunks[i]))
+		d.Strict = false
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Chunk %d is not valid XML %s", i, err)
+			}
+		}
+	}
+
+	err := readXMLChunks(strings.NewReader(`<owl:Ontology xmlns:owl="http://www.w3.org/2002/07/owl#"/>`), "test", "text/test", 3, func(ch loadChunk) error {
+		t.Fatalf("Nothing should be sent %+v", ch)
+		return nil
+	})
+	if err != errNotSplittable {
+		t.Fatalf("A file whose root is not rdf:RDF cannot be cut %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// The kinds of the principals that permissions are granted to.
+const (
+	PrincipalUser = "user"
+	PrincipalRole = "role"
+)
+
+// UserDescription is a Stardog user with its roles and the permissions that
+// were granted to it directly.
+type UserDescription struct {
+	Name        string                      `json:"name"`
+	Roles       []string                    `json:"roles"`
+	Permissions []sdutils.StardogPermission `json:"permissions"`
+}
+
+// RoleDescription is a Stardog role with its permissions.
+type RoleDescription struct {
+	Name        string                      `json:"name"`
+	Permissions []sdutils.StardogPermission `json:"permissions"`
+}
+
+// SecurityDocument holds the roles of a deployment and the roles and
+// permissions of its users.  ExportSecurity writes it and ImportSecurity
+// applies it to another deployment.  Passwords are not part of it.
+type SecurityDocument struct {
+	Roles []RoleDescription `json:"roles"`
+	Users []UserDescription `json:"users"`
+}
+
+// Users returns the users of the deployment with their roles and
+// permissions.
+func (c *Client) Users(ctx context.Context) ([]UserDescription, error) {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return users(ctx, sd)
+}
+
+func users(ctx context.Context, sd *sdutils.StardogClient) ([]UserDescription, error) {
+	names, err := sd.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	users := []UserDescription{}
+	for _, n := range names {
+		roles, err := sd.UserRoles(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(roles)
+		perms, err := sd.UserPermissions(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, UserDescription{Name: n, Roles: roles, Permissions: perms})
+	}
+	return users, nil
+}
+
+// AddUser creates the user with password and gives it the roles.
+func (c *Client) AddUser(ctx context.Context, user string, password string, superuser bool, roles []string) error {
+	if password == "" {
+		return fmt.Errorf("The password of %s cannot be empty", user)
+	}
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	err = sd.CreateUser(ctx, user, password, superuser)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		err = sd.AddUserRole(ctx, user, r)
+		if err != nil {
+			return fmt.Errorf("The user %s was added but the role %s could not be given to it: %s", user, r, err)
+		}
+	}
+	return nil
+}
+
+// RemoveUser deletes the user.
+func (c *Client) RemoveUser(ctx context.Context, user string) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.DeleteUser(ctx, user)
+}
+
+// ChangePassword sets the password of the user.
+func (c *Client) ChangePassword(ctx context.Context, user string, password string) error {
+	if password == "" {
+		return fmt.Errorf("The password of %s cannot be empty", user)
+	}
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.ChangePassword(ctx, user, password)
+}
+
+// Roles returns the roles of the deployment with their permissions.
+func (c *Client) Roles(ctx context.Context) ([]RoleDescription, error) {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return roles(ctx, sd)
+}
+
+func roles(ctx context.Context, sd *sdutils.StardogClient) ([]RoleDescription, error) {
+	names, err := sd.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	roles := []RoleDescription{}
+	for _, n := range names {
+		perms, err := sd.RolePermissions(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, RoleDescription{Name: n, Permissions: perms})
+	}
+	return roles, nil
+}
+
+// AddRole creates the role.
+func (c *Client) AddRole(ctx context.Context, role string) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.CreateRole(ctx, role)
+}
+
+// RemoveRole deletes the role.  A role that users still have is only deleted
+// when force is set.
+func (c *Client) RemoveRole(ctx context.Context, role string, force bool) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.DeleteRole(ctx, role, force)
+}
+
+// GrantRole gives the role to the user.
+func (c *Client) GrantRole(ctx context.Context, role string, user string) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.AddUserRole(ctx, user, role)
+}
+
+// RevokeRole takes the role away from the user.
+func (c *Client) RevokeRole(ctx context.Context, role string, user string) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	return sd.RemoveUserRole(ctx, user, role)
+}
+
+// Permissions returns the permissions of the principal name of kind, which
+// is PrincipalUser or PrincipalRole.
+func (c *Client) Permissions(ctx context.Context, kind string, name string) ([]sdutils.StardogPermission, error) {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case PrincipalUser:
+		return sd.UserPermissions(ctx, name)
+	case PrincipalRole:
+		return sd.RolePermissions(ctx, name)
+	}
+	return nil, fmt.Errorf("Permissions belong to a user or a role, not a %s", kind)
+}
+
+// GrantPermission grants the permission to the principal name of kind.
+func (c *Client) GrantPermission(ctx context.Context, kind string, name string, perm sdutils.StardogPermission) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case PrincipalUser:
+		return sd.GrantUserPermission(ctx, name, perm)
+	case PrincipalRole:
+		return sd.GrantRolePermission(ctx, name, perm)
+	}
+	return fmt.Errorf("Permissions belong to a user or a role, not a %s", kind)
+}
+
+// RevokePermission revokes the permission from the principal name of kind.
+func (c *Client) RevokePermission(ctx context.Context, kind string, name string, perm sdutils.StardogPermission) error {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case PrincipalUser:
+		return sd.RevokeUserPermission(ctx, name, perm)
+	case PrincipalRole:
+		return sd.RevokeRolePermission(ctx, name, perm)
+	}
+	return fmt.Errorf("Permissions belong to a user or a role, not a %s", kind)
+}
+
+// ExportSecurity returns the roles and the users of the deployment with their
+// permissions.
+func (c *Client) ExportSecurity(ctx context.Context) (*SecurityDocument, error) {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc := &SecurityDocument{}
+	doc.Roles, err = roles(ctx, sd)
+	if err != nil {
+		return nil, err
+	}
+	doc.Users, err = users(ctx, sd)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ImportSecurity applies the document to the deployment.  Missing roles are
+// created and missing roles and permissions are granted.  Nothing is revoked,
+// so importing a document again changes nothing.  Users cannot be created
+// without a password, so the users that do not exist are skipped and
+// returned.
+func (c *Client) ImportSecurity(ctx context.Context, doc *SecurityDocument) ([]string, error) {
+	sd, err := c.stardog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := sd.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range doc.Roles {
+		if !contains(existing, role.Name) {
+			c.app.ConsoleLog(1, "Adding the role %s.\n", role.Name)
+			err = sd.CreateRole(ctx, role.Name)
+			if err != nil {
+				return nil, err
+			}
+		}
+		current, err := sd.RolePermissions(ctx, role.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range role.Permissions {
+			if hasPermission(current, p) {
+				continue
+			}
+			c.app.ConsoleLog(1, "Granting %s to the role %s.\n", formatPermission(p), role.Name)
+			err = sd.GrantRolePermission(ctx, role.Name, p)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	existing, err = sd.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	skipped := []string{}
+	for _, user := range doc.Users {
+		if !contains(existing, user.Name) {
+			skipped = append(skipped, user.Name)
+			continue
+		}
+		currentRoles, err := sd.UserRoles(ctx, user.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range user.Roles {
+			if contains(currentRoles, r) {
+				continue
+			}
+			c.app.ConsoleLog(1, "Giving the role %s to the user %s.\n", r, user.Name)
+			err = sd.AddUserRole(ctx, user.Name, r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		current, err := sd.UserPermissions(ctx, user.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range user.Permissions {
+			if hasPermission(current, p) {
+				continue
+			}
+			c.app.ConsoleLog(1, "Granting %s to the user %s.\n", formatPermission(p), user.Name)
+			err = sd.GrantUserPermission(ctx, user.Name, p)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return skipped, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPermission(perms []sdutils.StardogPermission, p sdutils.StardogPermission) bool {
+	want := append([]string(nil), p.Resource...)
+	sort.Strings(want)
+	for _, have := range perms {
+		if have.Action != p.Action || have.ResourceType != p.ResourceType || len(have.Resource) != len(want) {
+			continue
+		}
+		got := append([]string(nil), have.Resource...)
+		sort.Strings(got)
+		same := true
+		for i := range got {
+			if got[i] != want[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+func formatPermission(p sdutils.StardogPermission) string {
+	return fmt.Sprintf("%s on %s %v", p.Action, p.ResourceType, p.Resource)
+}
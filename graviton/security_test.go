@@ -0,0 +1,169 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graviton
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// fakeSecurity keeps the users, roles and permissions of a Stardog server
+// and counts the requests that change them.
+type fakeSecurity struct {
+	userRoles map[string][]string
+	perms     map[string][]sdutils.StardogPermission
+	roles     []string
+	writes    int
+}
+
+func newFakeSecurity(users ...string) *fakeSecurity {
+	f := &fakeSecurity{userRoles: map[string][]string{}, perms: map[string][]sdutils.StardogPermission{}}
+	for _, u := range users {
+		f.userRoles[u] = []string{}
+	}
+	return f
+}
+
+func (f *fakeSecurity) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if r.Method != "GET" {
+		f.writes++
+	}
+	var body map[string]interface{}
+	var perm sdutils.StardogPermission
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/admin/users":
+		users := []string{}
+		for u := range f.userRoles {
+			users = append(users, u)
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"users": users})
+	case r.Method == "GET" && r.URL.Path == "/admin/roles":
+		json.NewEncoder(w).Encode(map[string][]string{"roles": f.roles})
+	case r.Method == "POST" && r.URL.Path == "/admin/roles":
+		json.NewDecoder(r.Body).Decode(&body)
+		f.roles = append(f.roles, body["rolename"].(string))
+	case len(parts) == 4 && parts[3] == "roles" && r.Method == "GET":
+		json.NewEncoder(w).Encode(map[string][]string{"roles": f.userRoles[parts[2]]})
+	case len(parts) == 4 && parts[3] == "roles" && r.Method == "POST":
+		json.NewDecoder(r.Body).Decode(&body)
+		f.userRoles[parts[2]] = append(f.userRoles[parts[2]], body["rolename"].(string))
+	case len(parts) == 4 && parts[1] == "permissions" && r.Method == "GET":
+		perms := f.perms[parts[2]+"/"+parts[3]]
+		if perms == nil {
+			perms = []sdutils.StardogPermission{}
+		}
+		json.NewEncoder(w).Encode(map[string][]sdutils.StardogPermission{"permissions": perms})
+	case len(parts) == 4 && parts[1] == "permissions" && r.Method == "PUT":
+		json.NewDecoder(r.Body).Decode(&perm)
+		f.perms[parts[2]+"/"+parts[3]] = append(f.perms[parts[2]+"/"+parts[3]], perm)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSecurityExportImport(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	readDb := sdutils.StardogPermission{Action: "read", ResourceType: "db", Resource: []string{"people"}}
+	writeGraph := sdutils.StardogPermission{Action: "write", ResourceType: "named-graph", Resource: []string{"people", "urn:g"}}
+	source := newFakeSecurity("admin", "alice", "bob")
+	source.roles = []string{"reader"}
+	source.perms["role/reader"] = []sdutils.StardogPermission{readDb}
+	source.perms["user/alice"] = []sdutils.StardogPermission{writeGraph}
+	source.userRoles["alice"] = []string{"reader"}
+	source.userRoles["bob"] = []string{"reader"}
+	ts := httptest.NewServer(source)
+	defer ts.Close()
+
+	c, p, dir := newTestClient(t, sdutils.Capabilities{Images: true, Volumes: true})
+	defer os.RemoveAll(dir)
+	defer c.Close()
+	p.sdURL = ts.URL
+	ctx := context.Background()
+	_, err := c.Launch(ctx)
+	if err != nil {
+		t.Fatalf("Launch failed %s", err)
+	}
+	doc, err := c.ExportSecurity(ctx)
+	if err != nil {
+		t.Fatalf("Export failed %s", err)
+	}
+	if len(doc.Roles) != 1 || doc.Roles[0].Name != "reader" || !reflect.DeepEqual(doc.Roles[0].Permissions, []sdutils.StardogPermission{readDb}) {
+		t.Fatalf("The roles were not exported %v", doc.Roles)
+	}
+	if len(doc.Users) != 3 || doc.Users[1].Name != "alice" || !reflect.DeepEqual(doc.Users[1].Permissions, []sdutils.StardogPermission{writeGraph}) {
+		t.Fatalf("The users were not exported %v", doc.Users)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = &SecurityDocument{}
+	err = json.Unmarshal(data, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := newFakeSecurity("admin", "alice")
+	ts2 := httptest.NewServer(target)
+	defer ts2.Close()
+	p.sdURL = ts2.URL
+	skipped, err := c.ImportSecurity(ctx, doc)
+	if err != nil {
+		t.Fatalf("Import failed %s", err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"bob"}) {
+		t.Fatalf("Only bob should have been skipped %v", skipped)
+	}
+	if !reflect.DeepEqual(target.roles, []string{"reader"}) || !reflect.DeepEqual(target.userRoles["alice"], []string{"reader"}) {
+		t.Fatalf("The roles were not imported %v %v", target.roles, target.userRoles)
+	}
+	if !reflect.DeepEqual(target.perms["role/reader"], []sdutils.StardogPermission{readDb}) || !reflect.DeepEqual(target.perms["user/alice"], []sdutils.StardogPermission{writeGraph}) {
+		t.Fatalf("The permissions were not imported %v", target.perms)
+	}
+
+	writes := target.writes
+	_, err = c.ImportSecurity(ctx, doc)
+	if err != nil {
+		t.Fatalf("The second import failed %s", err)
+	}
+	if target.writes != writes {
+		t.Fatalf("Importing the same document again should change nothing, %d requests were made", target.writes-writes)
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	perms := []sdutils.StardogPermission{{Action: "read", ResourceType: "named-graph", Resource: []string{"db", "urn:g"}}}
+	if !hasPermission(perms, sdutils.StardogPermission{Action: "read", ResourceType: "named-graph", Resource: []string{"urn:g", "db"}}) {
+		t.Fatal("The order of the resource parts should not matter")
+	}
+	if hasPermission(perms, sdutils.StardogPermission{Action: "write", ResourceType: "named-graph", Resource: []string{"db", "urn:g"}}) {
+		t.Fatal("A different action is a different permission")
+	}
+	if hasPermission(perms, sdutils.StardogPermission{Action: "read", ResourceType: "named-graph", Resource: []string{"db"}}) {
+		t.Fatal("A different resource is a different permission")
+	}
+}
@@ -0,0 +1,72 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/docker"
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func (p *kubernetesPlugin) imageRef(version string) string {
+	image := fmt.Sprintf("stardog-graviton:%s", version)
+	if p.Registry == "" {
+		return image
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(p.Registry, "/"), image)
+}
+
+func (p *kubernetesPlugin) HaveImage(ctx context.Context, c sdutils.AppContext) bool {
+	dockerPath, err := exec.LookPath("docker")
+	if err != nil {
+		return false
+	}
+	_, err = runTool(ctx, c, []string{dockerPath, "image", "inspect", p.imageRef(c.GetVersion())})
+	return err == nil
+}
+
+// BuildImage builds the same image as the docker plugin and pushes it to the
+// registry when one is configured.  Without a registry the cluster must share
+// the local image store, as minikube does.
+func (p *kubernetesPlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	err := docker.GetPlugin().BuildImage(ctx, context, sdReleaseFilePath, version)
+	if err != nil {
+		return err
+	}
+	if p.Registry == "" {
+		return nil
+	}
+	dockerPath, err := exec.LookPath("docker")
+	if err != nil {
+		return err
+	}
+	ref := p.imageRef(version)
+	_, err = runTool(ctx, context, []string{dockerPath, "tag", fmt.Sprintf("stardog-graviton:%s", version), ref})
+	if err != nil {
+		return err
+	}
+	context.ConsoleLog(1, "Pushing the image %s\n", ref)
+	_, err = runTool(ctx, context, []string{dockerPath, "push", ref})
+	if err != nil {
+		return err
+	}
+	context.ConsoleLog(0, "Image successfully pushed: %s\n", ref)
+	return nil
+}
@@ -0,0 +1,322 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type kubernetesDeploymentDescription struct {
+	Namespace       string `json:"namespace,omitempty"`
+	KubeContext     string `json:"kube_context,omitempty"`
+	KubectlPath     string `json:"kubectl_path,omitempty"`
+	StorageClass    string `json:"storage_class,omitempty"`
+	Registry        string `json:"registry,omitempty"`
+	ZkImage         string `json:"zk_image,omitempty"`
+	ServiceType     string `json:"service_type,omitempty"`
+	Version         string `json:"-"`
+	Name            string `json:"-"`
+	deployDir       string
+	customPropFile  string
+	environment     []string
+	disableSecurity bool
+	ctx             sdutils.AppContext
+	plugin          *kubernetesPlugin
+}
+
+func newKubernetesDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeployment, p *kubernetesPlugin) (*kubernetesDeploymentDescription, error) {
+	if p.ServiceType != "LoadBalancer" && p.ServiceType != "NodePort" && p.ServiceType != "ClusterIP" {
+		return nil, fmt.Errorf("%s is not a valid service type", p.ServiceType)
+	}
+	_, err := p.kubectlBase()
+	if err != nil {
+		return nil, err
+	}
+	dd := kubernetesDeploymentDescription{
+		Namespace:       p.Namespace,
+		KubeContext:     p.KubeContext,
+		KubectlPath:     p.KubectlPath,
+		StorageClass:    p.StorageClass,
+		Registry:        p.Registry,
+		ZkImage:         p.ZkImage,
+		ServiceType:     p.ServiceType,
+		Version:         baseD.Version,
+		Name:            baseD.Name,
+		deployDir:       sdutils.DeploymentDir(c.GetConfigDir(), baseD.Name),
+		customPropFile:  baseD.CustomPropsFile,
+		environment:     baseD.Environment,
+		disableSecurity: baseD.DisableSecurity,
+		ctx:             c,
+		plugin:          p,
+	}
+	return &dd, nil
+}
+
+func (dd *kubernetesDeploymentDescription) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (dd *kubernetesDeploymentDescription) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	vm := NewKubernetesVolumeManager(dd.ctx, dd)
+	return vm.CreateSet(ctx, licensePath, sizeOfEachVolume, clusterSize)
+}
+
+func (dd *kubernetesDeploymentDescription) DeleteVolumeSet(ctx context.Context) error {
+	vm := NewKubernetesVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	if dd.InstanceExists() {
+		return fmt.Errorf("The volumes of %s are in use by a running instance", dd.Name)
+	}
+	return vm.DeleteSet(ctx)
+}
+
+func (dd *kubernetesDeploymentDescription) ClusterSize() (int, error) {
+	vm := NewKubernetesVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return -1, fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadClaimVolumes(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return -1, err
+	}
+	return vols.ClusterSize, nil
+}
+
+func (dd *kubernetesDeploymentDescription) StatusVolumeSet(ctx context.Context) error {
+	vm := NewKubernetesVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.Status(ctx)
+}
+
+func (dd *kubernetesDeploymentDescription) VolumeExists() bool {
+	vm := NewKubernetesVolumeManager(dd.ctx, dd)
+	return vm.VolumeExists()
+}
+
+func (dd *kubernetesDeploymentDescription) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	im, err := NewStatefulInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.CreateInstance(ctx, volumeSize, zookeeperSize, idleTimeout)
+}
+
+func (dd *kubernetesDeploymentDescription) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	im, err := NewStatefulInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.OpenInstance(ctx, volumeSize, zookeeperSize, mask, idleTimeout)
+}
+
+func (dd *kubernetesDeploymentDescription) DeleteInstance(ctx context.Context) error {
+	im, err := NewStatefulInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.DeleteInstance(ctx)
+}
+
+func (dd *kubernetesDeploymentDescription) StatusInstance(ctx context.Context) error {
+	im, err := NewStatefulInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.Status(ctx)
+}
+
+func (dd *kubernetesDeploymentDescription) InstanceExists() bool {
+	im, err := NewStatefulInstance(dd.ctx, dd)
+	if err != nil {
+		return false
+	}
+	return im.InstanceExists()
+}
+
+func (dd *kubernetesDeploymentDescription) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	vm := NewKubernetesVolumeManager(dd.ctx, dd)
+	volumeStatus, err := vm.getStatusInformation(ctx)
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No volume information found %s\n", err)
+	}
+
+	im, err := NewStatefulInstance(dd.ctx, dd)
+	if err != nil {
+		return nil, err
+	}
+	instS, err := im.getStatusInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := im.serviceAddress(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sD := sdutils.StardogDescription{
+		StardogURL: fmt.Sprintf("http://%s:5821", addr),
+		// The internal URL is used from inside of the first Stardog pod
+		StardogInternalURL:  "http://localhost:5821",
+		VolumeDescription:   volumeStatus,
+		InstanceDescription: instS,
+		TimeStamp:           time.Now(),
+	}
+	return &sD, nil
+}
+
+// RemoteCommand runs commands in the first Stardog pod in place of ssh.
+func (dd *kubernetesDeploymentDescription) RemoteCommand(interactive bool) ([]string, error) {
+	base, err := dd.plugin.kubectlBase()
+	if err != nil {
+		return nil, err
+	}
+	base = append(base, "exec", "-i")
+	if interactive {
+		base = append(base, "-t")
+	}
+	return append(base, fmt.Sprintf("%s-stardog-0", dd.Name), "--"), nil
+}
+
+func (dd *kubernetesDeploymentDescription) GatherLogs(ctx context.Context, outfile string) error {
+	im, err := NewStatefulInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.GatherLogs(ctx, outfile)
+}
+
+type kubernetesPlugin struct {
+	Namespace    string `json:"namespace,omitempty"`
+	KubeContext  string `json:"kube_context,omitempty"`
+	KubectlPath  string `json:"kubectl_path,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+	Registry     string `json:"registry,omitempty"`
+	ZkImage      string `json:"zk_image,omitempty"`
+	ServiceType  string `json:"service_type,omitempty"`
+}
+
+// GetPlugin returns the plugin interface that this module represents.
+func GetPlugin() sdutils.Plugin {
+	return &kubernetesPlugin{
+		Namespace:   "default",
+		KubectlPath: "kubectl",
+		ZkImage:     "zookeeper:3.4",
+		ServiceType: "LoadBalancer",
+	}
+}
+
+func (p *kubernetesPlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	b, err := json.Marshal(defaultCliOpts)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *kubernetesPlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	cmdOpts.LaunchCmd.Flag("kube-namespace", "The kubernetes namespace in which to deploy.").Default(p.Namespace).StringVar(&p.Namespace)
+	cmdOpts.LaunchCmd.Flag("kube-context", "The kubectl context to use.").Default(p.KubeContext).StringVar(&p.KubeContext)
+	cmdOpts.LaunchCmd.Flag("kubectl", "The path to the kubectl program.").Default(p.KubectlPath).StringVar(&p.KubectlPath)
+	cmdOpts.LaunchCmd.Flag("kube-storage-class", "The storage class of the volume claims.").Default(p.StorageClass).StringVar(&p.StorageClass)
+	cmdOpts.LaunchCmd.Flag("kube-registry", "The registry to which the Stardog image is pushed.").Default(p.Registry).StringVar(&p.Registry)
+	cmdOpts.LaunchCmd.Flag("kube-zk-image", "The ZooKeeper image.").Default(p.ZkImage).StringVar(&p.ZkImage)
+	cmdOpts.LaunchCmd.Flag("kube-service-type", "The type of the Stardog service [LoadBalancer | NodePort | ClusterIP].").Default(p.ServiceType).StringVar(&p.ServiceType)
+
+	cmdOpts.NewDeploymentCmd.Flag("kube-namespace", "The kubernetes namespace in which to deploy.").Default(p.Namespace).StringVar(&p.Namespace)
+	cmdOpts.NewDeploymentCmd.Flag("kube-context", "The kubectl context to use.").Default(p.KubeContext).StringVar(&p.KubeContext)
+	cmdOpts.NewDeploymentCmd.Flag("kubectl", "The path to the kubectl program.").Default(p.KubectlPath).StringVar(&p.KubectlPath)
+	cmdOpts.NewDeploymentCmd.Flag("kube-storage-class", "The storage class of the volume claims.").Default(p.StorageClass).StringVar(&p.StorageClass)
+	cmdOpts.NewDeploymentCmd.Flag("kube-registry", "The registry to which the Stardog image is pushed.").Default(p.Registry).StringVar(&p.Registry)
+	cmdOpts.NewDeploymentCmd.Flag("kube-zk-image", "The ZooKeeper image.").Default(p.ZkImage).StringVar(&p.ZkImage)
+	cmdOpts.NewDeploymentCmd.Flag("kube-service-type", "The type of the Stardog service [LoadBalancer | NodePort | ClusterIP].").Default(p.ServiceType).StringVar(&p.ServiceType)
+
+	cmdOpts.LeaksCmd.Flag("kube-namespace", "The kubernetes namespace to search.").Default(p.Namespace).StringVar(&p.Namespace)
+	cmdOpts.LeaksCmd.Flag("kube-context", "The kubectl context to use.").Default(p.KubeContext).StringVar(&p.KubeContext)
+
+	cmdOpts.BuildCmd.Flag("kube-registry", "The registry to which the Stardog image is pushed.").Default(p.Registry).StringVar(&p.Registry)
+	return nil
+}
+
+func (p *kubernetesPlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	if new {
+		kubeDD, err := newKubernetesDeploymentDescription(context, baseD, p)
+		if err != nil {
+			return nil, err
+		}
+		baseD.CloudOpts = kubeDD
+		data, err := json.Marshal(baseD)
+		if err != nil {
+			return nil, err
+		}
+		confPath := path.Join(kubeDD.deployDir, "config.json")
+		err = ioutil.WriteFile(confPath, data, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return kubeDD, nil
+	}
+	data, err := json.Marshal(baseD.CloudOpts)
+	if err != nil {
+		return nil, err
+	}
+	var dd kubernetesDeploymentDescription
+	err = json.Unmarshal(data, &dd)
+	if err != nil {
+		return nil, err
+	}
+	dd.Name = baseD.Name
+	dd.Version = baseD.Version
+	dd.ctx = context
+	dd.deployDir = sdutils.DeploymentDir(context.GetConfigDir(), baseD.Name)
+	dd.customPropFile = baseD.CustomPropsFile
+	dd.environment = baseD.Environment
+	dd.disableSecurity = baseD.DisableSecurity
+	// The cluster that was used at creation time is stored with the deployment
+	dd.plugin = &kubernetesPlugin{
+		Namespace:    dd.Namespace,
+		KubeContext:  dd.KubeContext,
+		KubectlPath:  dd.KubectlPath,
+		StorageClass: dd.StorageClass,
+		Registry:     dd.Registry,
+		ZkImage:      dd.ZkImage,
+		ServiceType:  dd.ServiceType,
+	}
+
+	return &dd, nil
+}
+
+func (p *kubernetesPlugin) GetName() string {
+	return "kubernetes"
+}
+
+func (p *kubernetesPlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true, Firewall: true}
+}
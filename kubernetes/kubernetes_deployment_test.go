@@ -0,0 +1,183 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestDeploymentLoadDefaults(t *testing.T) {
+	plugin := GetPlugin().(*kubernetesPlugin)
+	i := make(map[string]string)
+	i["namespace"] = "stardog"
+	i["storage_class"] = "fast"
+	i["service_type"] = "NodePort"
+
+	err := plugin.LoadDefaults(&i)
+	if err != nil {
+		t.Fatalf("Failed to load defaults %s", err)
+	}
+	if plugin.Namespace != "stardog" {
+		t.Fatalf("namespace not set right")
+	}
+	if plugin.StorageClass != "fast" {
+		t.Fatalf("storage_class not set right")
+	}
+	if plugin.ServiceType != "NodePort" {
+		t.Fatalf("service_type not set right")
+	}
+	if plugin.KubectlPath != "kubectl" {
+		t.Fatalf("kubectl_path should keep its default")
+	}
+}
+
+func readParams(t *testing.T, exedir string) string {
+	data, err := ioutil.ReadFile(path.Join(exedir, "params"))
+	if err != nil {
+		t.Fatalf("kubectl was not run %s", err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func newTestDeployment(t *testing.T, kubectl string) (string, sdutils.Deployment) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := GetPlugin().(*kubernetesPlugin)
+	plugin.KubectlPath = kubectl
+	plugin.Namespace = "graviton"
+	plugin.Registry = "registry.example.com/"
+
+	propsFile := path.Join(dir, "stardog.properties")
+	ioutil.WriteFile(propsFile, []byte("query.all.graphs=true\n"), 0644)
+	baseD := sdutils.BaseDeployment{
+		Type:            plugin.GetName(),
+		Name:            "testdep",
+		Directory:       sdutils.DeploymentDir(dir, "testdep"),
+		Version:         "5.0",
+		CustomPropsFile: propsFile,
+		Environment:     []string{"STARDOG_JAVA_ARGS=\"-Xmx2g -Xms2g\""},
+		DisableSecurity: true,
+	}
+	os.MkdirAll(baseD.Directory, 0755)
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment %s", err)
+	}
+	return dir, dep
+}
+
+func TestBadServiceType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := GetPlugin().(*kubernetesPlugin)
+	plugin.ServiceType = "Ingress"
+	baseD := sdutils.BaseDeployment{Name: "testdep", Version: "5.0"}
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The service type should be rejected")
+	}
+	plugin.ServiceType = "LoadBalancer"
+	plugin.KubectlPath = path.Join(dir, "nokubectl")
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("A missing kubectl should be rejected")
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	exedir, kubectl, err := sdutils.CreateTestExec("kubectl", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to make the fake kubectl %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	dir, dep := newTestDeployment(t, kubectl)
+	defer os.RemoveAll(dir)
+
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 20, 2)
+	if err != nil {
+		t.Fatalf("Failed to create the volumes %s", err)
+	}
+	manifest := path.Join(sdutils.DeploymentDir(dir, "testdep"), "volumes", "volumes.yaml")
+	if readParams(t, exedir) != "--namespace graviton apply -f "+manifest {
+		t.Fatalf("The volumes were not applied: %s", readParams(t, exedir))
+	}
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		t.Fatalf("The manifest was not written %s", err)
+	}
+	for _, s := range []string{"stardog-license-key.bin: bGljZW5zZQ==",
+		"name: stardog-home-testdep-stardog-1",
+		"storage: 20Gi",
+		"StardogVirtualAppliance: testdep"} {
+		if !strings.Contains(string(data), s) {
+			t.Fatalf("The manifest is missing %s:\n%s", s, string(data))
+		}
+	}
+	if strings.Contains(string(data), "storageClassName") {
+		t.Fatalf("No storage class was requested")
+	}
+	size, err := dep.ClusterSize()
+	if err != nil || size != 2 {
+		t.Fatalf("The cluster size should be 2 %d %s", size, err)
+	}
+
+	err = dep.DeleteVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to delete the volumes %s", err)
+	}
+	if readParams(t, exedir) != "--namespace graviton delete --ignore-not-found -f "+manifest {
+		t.Fatalf("The volumes were not deleted: %s", readParams(t, exedir))
+	}
+	if dep.VolumeExists() {
+		t.Fatalf("The volumes should be gone")
+	}
+}
+
+func TestVolumesFail(t *testing.T) {
+	exedir, kubectl, err := sdutils.CreateTestExec("kubectl", "", 1)
+	if err != nil {
+		t.Fatalf("Failed to make the fake kubectl %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	dir, dep := newTestDeployment(t, kubectl)
+	defer os.RemoveAll(dir)
+
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 20, 2)
+	if err == nil {
+		t.Fatalf("A kubectl failure should fail the create")
+	}
+	if dep.VolumeExists() {
+		t.Fatalf("The volumes should not exist")
+	}
+}
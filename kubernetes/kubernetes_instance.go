@@ -0,0 +1,339 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// EnvVar is a single environment variable of the Stardog containers.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// StatefulInstance represents the StatefulSets and Services that make up a
+// Stardog cluster in kubernetes.  It takes the same inputs as the aws
+// Ec2Instance.
+type StatefulInstance struct {
+	DeploymentName  string             `json:"deployment_name,omitempty"`
+	Version         string             `json:"version,omitempty"`
+	Image           string             `json:"image,omitempty"`
+	ZkImage         string             `json:"zk_image,omitempty"`
+	ZkSize          int                `json:"zookeeper_size,omitempty"`
+	SdSize          int                `json:"stardog_size,omitempty"`
+	VolumeSize      int                `json:"volume_size,omitempty"`
+	StorageClass    string             `json:"storage_class,omitempty"`
+	ServiceType     string             `json:"service_type,omitempty"`
+	HTTPMask        string             `json:"http_subnet,omitempty"`
+	IdleTimeout     int                `json:"idle_timeout,omitempty"`
+	CustomPropsData string             `json:"custom_properties_data,omitempty"`
+	Environment     []EnvVar           `json:"environment_variables,omitempty"`
+	StartOpts       string             `json:"stardog_start_opts,omitempty"`
+	InstanceDir     string             `json:"-"`
+	Ctx             sdutils.AppContext `json:"-"`
+	plugin          *kubernetesPlugin
+	deployDir       string
+}
+
+// InstanceStatusDescription describes details about a running Stardog instance.
+type InstanceStatusDescription struct {
+	ZkNodesContact []string
+	StardogPods    []string
+	ServiceType    string
+}
+
+type serviceStatus struct {
+	Spec struct {
+		ClusterIP string `json:"clusterIP"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer struct {
+			Ingress []struct {
+				IP       string `json:"ip"`
+				Hostname string `json:"hostname"`
+			} `json:"ingress"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+// NewStatefulInstance returns a StatefulInstance which will be used to apply or
+// inspect the Stardog StatefulSets.
+func NewStatefulInstance(ctx sdutils.AppContext, dd *kubernetesDeploymentDescription) (*StatefulInstance, error) {
+	customData := ""
+	if dd.customPropFile != "" {
+		data, err := ioutil.ReadFile(dd.customPropFile)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid custom properties file: %s", err)
+		}
+		customData = string(data)
+	}
+	envList := []EnvVar{}
+	for _, e := range dd.environment {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		envList = append(envList, EnvVar{Name: kv[0], Value: strings.Trim(kv[1], "\"'")})
+	}
+
+	instance := StatefulInstance{
+		DeploymentName:  dd.Name,
+		Version:         dd.Version,
+		Image:           dd.plugin.imageRef(dd.Version),
+		ZkImage:         dd.ZkImage,
+		StorageClass:    dd.StorageClass,
+		ServiceType:     dd.ServiceType,
+		CustomPropsData: customData,
+		Environment:     envList,
+		InstanceDir:     path.Join(dd.deployDir, "instance"),
+		Ctx:             ctx,
+		plugin:          dd.plugin,
+		deployDir:       dd.deployDir,
+	}
+	if dd.disableSecurity {
+		instance.StartOpts = "--disable-security"
+	}
+	return &instance, nil
+}
+
+func (si *StatefulInstance) confPath() string {
+	return path.Join(si.InstanceDir, "instance.json")
+}
+
+func (si *StatefulInstance) instanceManifest() string {
+	return path.Join(si.InstanceDir, "instance.yaml")
+}
+
+func (si *StatefulInstance) serviceManifest() string {
+	return path.Join(si.InstanceDir, "service.yaml")
+}
+
+// Name is used by the manifest templates.
+func (si *StatefulInstance) Name() string {
+	return si.DeploymentName
+}
+
+// ZkServers is the ensemble definition given to every ZooKeeper pod.
+func (si *StatefulInstance) ZkServers() string {
+	servers := make([]string, si.ZkSize)
+	for i := 0; i < si.ZkSize; i++ {
+		servers[i] = fmt.Sprintf("server.%d=%s-zk-%d.%s-zk:2888:3888", i+1, si.DeploymentName, i, si.DeploymentName)
+	}
+	return strings.Join(servers, " ")
+}
+
+func (si *StatefulInstance) zkContacts() []string {
+	contacts := make([]string, si.ZkSize)
+	for i := 0; i < si.ZkSize; i++ {
+		contacts[i] = fmt.Sprintf("%s-zk-%d.%s-zk:2181", si.DeploymentName, i, si.DeploymentName)
+	}
+	return contacts
+}
+
+// ZkAddress is the ZooKeeper connection string given to Stardog.
+func (si *StatefulInstance) ZkAddress() string {
+	return strings.Join(si.zkContacts(), ",")
+}
+
+func (si *StatefulInstance) apply(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int, message string) error {
+	vol, err := LoadClaimVolumes(si.Ctx, path.Join(si.deployDir, "volumes"))
+	if err != nil {
+		return err
+	}
+	si.SdSize = vol.ClusterSize
+	si.VolumeSize = vol.SizeOfEachVolume
+	si.ZkSize = zookeeperSize
+	si.HTTPMask = mask
+	si.IdleTimeout = idleTimeout
+
+	err = renderManifest("etc/kubernetes/instance.yaml", si, si.instanceManifest())
+	if err != nil {
+		return err
+	}
+	err = renderManifest("etc/kubernetes/service.yaml", si, si.serviceManifest())
+	if err != nil {
+		return err
+	}
+	err = sdutils.WriteJSON(si, si.confPath())
+	if err != nil {
+		return err
+	}
+	err = si.plugin.applyManifest(ctx, si.Ctx, si.instanceManifest(), message)
+	if err != nil {
+		return err
+	}
+	return si.plugin.applyManifest(ctx, si.Ctx, si.serviceManifest(), message)
+}
+
+// CreateInstance applies the ZooKeeper and Stardog StatefulSets.  The external
+// Service is created closed, like the aws load balancer.
+func (si *StatefulInstance) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	if si.InstanceExists() {
+		si.Ctx.ConsoleLog(1, "The instance already exists.\n")
+		si.Ctx.Logf(sdutils.INFO, "The instance already exists.")
+	}
+	err := si.apply(ctx, volumeSize, zookeeperSize, "0.0.0.0/32", idleTimeout, "Creating the StatefulSets")
+	if err != nil {
+		si.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+		return err
+	}
+	si.Ctx.ConsoleLog(1, "Successfully created the instance.\n")
+	return nil
+}
+
+// OpenInstance allows traffic from the given CIDR to reach the Service.
+func (si *StatefulInstance) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	err := si.apply(ctx, volumeSize, zookeeperSize, mask, idleTimeout, "Opening the service")
+	if err != nil {
+		si.Ctx.ConsoleLog(1, "Failed to open up the instance.\n")
+		return err
+	}
+	si.Ctx.ConsoleLog(1, "Successfully opened up the instance.\n")
+	return nil
+}
+
+// DeleteInstance removes the StatefulSets and Services.  The claims are left
+// in place.
+func (si *StatefulInstance) DeleteInstance(ctx context.Context) error {
+	if !si.InstanceExists() {
+		return fmt.Errorf("There is no configured instance")
+	}
+	err := si.plugin.deleteManifests(ctx, si.Ctx, si.serviceManifest(), si.instanceManifest())
+	if err != nil {
+		si.Ctx.ConsoleLog(1, "Failed to destroy the instance.\n")
+		return err
+	}
+	os.Remove(si.confPath())
+	si.Ctx.ConsoleLog(1, "Successfully destroyed the instance.\n")
+	return nil
+}
+
+// InstanceExists will return true if the StatefulSets were applied.
+func (si *StatefulInstance) InstanceExists() bool {
+	return sdutils.PathExists(si.confPath())
+}
+
+func (si *StatefulInstance) load() error {
+	err := sdutils.LoadJSON(si, si.confPath())
+	if err != nil {
+		return fmt.Errorf("There is no configured instance")
+	}
+	return nil
+}
+
+func (si *StatefulInstance) getStatusInformation(ctx context.Context) (*InstanceStatusDescription, error) {
+	err := si.load()
+	if err != nil {
+		return nil, err
+	}
+	s := InstanceStatusDescription{
+		ZkNodesContact: si.zkContacts(),
+		ServiceType:    si.ServiceType,
+	}
+	for i := 0; i < si.SdSize; i++ {
+		s.StardogPods = append(s.StardogPods, fmt.Sprintf("%s-stardog-%d", si.DeploymentName, i))
+	}
+	return &s, nil
+}
+
+// serviceAddress returns the external address of the Stardog Service.  When
+// no load balancer was assigned the cluster IP is used.
+func (si *StatefulInstance) serviceAddress(ctx context.Context) (string, error) {
+	out, err := si.plugin.kubectl(ctx, si.Ctx, "get", "service", fmt.Sprintf("%s-stardog", si.DeploymentName), "-o", "json")
+	if err != nil {
+		return "", err
+	}
+	var svc serviceStatus
+	err = json.Unmarshal([]byte(out), &svc)
+	if err != nil {
+		return "", fmt.Errorf("Could not parse the service description: %s", err)
+	}
+	for _, ing := range svc.Status.LoadBalancer.Ingress {
+		if ing.Hostname != "" {
+			return ing.Hostname, nil
+		}
+		if ing.IP != "" {
+			return ing.IP, nil
+		}
+	}
+	if svc.Spec.ClusterIP == "" {
+		return "", fmt.Errorf("The service has no address yet")
+	}
+	return svc.Spec.ClusterIP, nil
+}
+
+// Status prints the state of the pods and services.
+func (si *StatefulInstance) Status(ctx context.Context) error {
+	if !si.InstanceExists() {
+		return fmt.Errorf("There is no configured instance")
+	}
+	out, err := si.plugin.kubectl(ctx, si.Ctx, "get", "statefulsets,pods,services", "-l", labelSelector(si.DeploymentName))
+	if err != nil {
+		return err
+	}
+	si.Ctx.ConsoleLog(1, "%s", out)
+	return nil
+}
+
+// GatherLogs collects the output of every pod and the Stardog log of each
+// node into a gzipped tarball.
+func (si *StatefulInstance) GatherLogs(ctx context.Context, outfile string) error {
+	err := si.load()
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "stardoglogs")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	pods := []string{}
+	for i := 0; i < si.ZkSize; i++ {
+		pods = append(pods, fmt.Sprintf("%s-zk-%d", si.DeploymentName, i))
+	}
+	for i := 0; i < si.SdSize; i++ {
+		pod := fmt.Sprintf("%s-stardog-%d", si.DeploymentName, i)
+		pods = append(pods, pod)
+		out, err := si.plugin.kubectl(ctx, si.Ctx, "exec", pod, "--", "cat", "/var/opt/stardog/stardog.log")
+		if err != nil {
+			si.Ctx.Logf(sdutils.WARN, "Could not get the stardog log of %s: %s", pod, err)
+		} else {
+			ioutil.WriteFile(path.Join(dir, pod+"-stardog.log"), []byte(out), 0644)
+		}
+	}
+	for _, pod := range pods {
+		out, err := si.plugin.kubectl(ctx, si.Ctx, "logs", pod)
+		if err != nil {
+			si.Ctx.Logf(sdutils.WARN, "Could not get the logs of %s: %s", pod, err)
+			continue
+		}
+		err = ioutil.WriteFile(path.Join(dir, pod+".out"), []byte(out), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return sdutils.TarDirectory(dir, outfile)
+}
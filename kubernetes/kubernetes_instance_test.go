@@ -0,0 +1,164 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+const serviceJSON = `{
+  "spec": {"clusterIP": "10.0.0.12"},
+  "status": {"loadBalancer": {"ingress": [{"hostname": "stardog.elb.example.com"}]}}
+}`
+
+func TestInstance(t *testing.T) {
+	exedir, kubectl, err := sdutils.CreateTestExec("kubectl", serviceJSON, 0)
+	if err != nil {
+		t.Fatalf("Failed to make the fake kubectl %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	dir, dep := newTestDeployment(t, kubectl)
+	defer os.RemoveAll(dir)
+	deployDir := sdutils.DeploymentDir(dir, "testdep")
+
+	err = dep.CreateInstance(context.Background(), 10, 3, 600)
+	if err == nil {
+		t.Fatalf("The instance should need volumes")
+	}
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 20, 2)
+	if err != nil {
+		t.Fatalf("Failed to create the volumes %s", err)
+	}
+
+	err = dep.CreateInstance(context.Background(), 10, 3, 600)
+	if err != nil {
+		t.Fatalf("Failed to create the instance %s", err)
+	}
+	if !dep.InstanceExists() {
+		t.Fatalf("The instance should exist")
+	}
+	serviceManifest := path.Join(deployDir, "instance", "service.yaml")
+	if readParams(t, exedir) != "--namespace graviton apply -f "+serviceManifest {
+		t.Fatalf("The service was not applied: %s", readParams(t, exedir))
+	}
+	data, err := ioutil.ReadFile(path.Join(deployDir, "instance", "instance.yaml"))
+	if err != nil {
+		t.Fatalf("The manifest was not written %s", err)
+	}
+	for _, s := range []string{"replicas: 3",
+		"replicas: 2",
+		"image: registry.example.com/stardog-graviton:5.0",
+		"value: \"server.1=testdep-zk-0.testdep-zk:2888:3888 server.2=testdep-zk-1.testdep-zk:2888:3888 server.3=testdep-zk-2.testdep-zk:2888:3888\"",
+		"value: \"testdep-zk-0.testdep-zk:2181,testdep-zk-1.testdep-zk:2181,testdep-zk-2.testdep-zk:2181\"",
+		"value: \"--disable-security\"",
+		"custom.properties: \"query.all.graphs=true\\n\"",
+		"- name: STARDOG_JAVA_ARGS\n          value: \"-Xmx2g -Xms2g\"",
+		"storage: 20Gi"} {
+		if !strings.Contains(string(data), s) {
+			t.Fatalf("The manifest is missing %s:\n%s", s, string(data))
+		}
+	}
+	data, err = ioutil.ReadFile(serviceManifest)
+	if err != nil {
+		t.Fatalf("The manifest was not written %s", err)
+	}
+	if !strings.Contains(string(data), "- 0.0.0.0/32") || !strings.Contains(string(data), "idle-timeout: \"600\"") {
+		t.Fatalf("The service should be closed:\n%s", string(data))
+	}
+
+	err = dep.OpenInstance(context.Background(), 10, 3, "1.2.3.4/32", 600)
+	if err != nil {
+		t.Fatalf("Failed to open the instance %s", err)
+	}
+	data, _ = ioutil.ReadFile(serviceManifest)
+	if !strings.Contains(string(data), "- 1.2.3.4/32") {
+		t.Fatalf("The service should be open:\n%s", string(data))
+	}
+
+	sd, err := dep.FullStatus(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get the status %s", err)
+	}
+	if sd.StardogURL != "http://stardog.elb.example.com:5821" || sd.SSHHost != "" {
+		t.Fatalf("The status is wrong %v", sd)
+	}
+	if readParams(t, exedir) != "--namespace graviton get service testdep-stardog -o json" {
+		t.Fatalf("The service was not inspected: %s", readParams(t, exedir))
+	}
+	instS := sd.InstanceDescription.(*InstanceStatusDescription)
+	if len(instS.ZkNodesContact) != 3 || len(instS.StardogPods) != 2 {
+		t.Fatalf("The instance description is wrong %v", instS)
+	}
+
+	rr, ok := dep.(sdutils.RemoteRunner)
+	if !ok {
+		t.Fatalf("The deployment should run commands in the pods")
+	}
+	cmd, err := rr.RemoteCommand(true)
+	if err != nil {
+		t.Fatalf("Failed to get the remote command %s", err)
+	}
+	if strings.Join(cmd[1:], " ") != "--namespace graviton exec -i -t testdep-stardog-0 --" {
+		t.Fatalf("The remote command is wrong %v", cmd)
+	}
+
+	err = dep.DeleteInstance(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to delete the instance %s", err)
+	}
+	if readParams(t, exedir) != "--namespace graviton delete --ignore-not-found -f "+serviceManifest+" -f "+path.Join(deployDir, "instance", "instance.yaml") {
+		t.Fatalf("The instance was not deleted: %s", readParams(t, exedir))
+	}
+	if dep.InstanceExists() {
+		t.Fatalf("The instance should be gone")
+	}
+	err = dep.DeleteInstance(context.Background())
+	if err == nil {
+		t.Fatalf("Deleting a missing instance should fail")
+	}
+}
+
+func TestServiceAddress(t *testing.T) {
+	exedir, kubectl, err := sdutils.CreateTestExec("kubectl", `{"spec": {"clusterIP": "10.0.0.12"}}`, 0)
+	if err != nil {
+		t.Fatalf("Failed to make the fake kubectl %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	dir, dep := newTestDeployment(t, kubectl)
+	defer os.RemoveAll(dir)
+
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+	dep.CreateVolumeSet(context.Background(), licenseFile, 20, 1)
+	dep.CreateInstance(context.Background(), 10, 1, 600)
+
+	sd, err := dep.FullStatus(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get the status %s", err)
+	}
+	if sd.StardogURL != "http://10.0.0.12:5821" {
+		t.Fatalf("The cluster IP should be used without a load balancer %s", sd.StardogURL)
+	}
+}
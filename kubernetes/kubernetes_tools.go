@@ -0,0 +1,156 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+const applianceLabel = "StardogVirtualAppliance"
+
+// The kinds of object that graviton creates and labels
+const labeledKinds = "statefulsets,services,configmaps,secrets,persistentvolumeclaims"
+
+func labelSelector(deploymentName string) string {
+	if deploymentName == "" {
+		return applianceLabel
+	}
+	return fmt.Sprintf("%s=%s", applianceLabel, deploymentName)
+}
+
+// kubectlBase returns the kubectl command with the cluster options that every
+// call needs.
+func (p *kubernetesPlugin) kubectlBase() ([]string, error) {
+	kubectlPath, err := exec.LookPath(p.KubectlPath)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl could not be found at %s: %s", p.KubectlPath, err)
+	}
+	cmdArray := []string{kubectlPath}
+	if p.KubeContext != "" {
+		cmdArray = append(cmdArray, "--context", p.KubeContext)
+	}
+	return append(cmdArray, "--namespace", p.Namespace), nil
+}
+
+func runTool(ctx context.Context, c sdutils.AppContext, cmdArray []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmdArray[0], cmdArray[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	c.Logf(sdutils.DEBUG, "Running %s", strings.Join(cmdArray, " "))
+	err := cmd.Run()
+	if err != nil {
+		c.Logf(sdutils.WARN, "%s failed: %s %s", cmdArray[0], err, stderr.String())
+		return "", fmt.Errorf("%s failed: %s", path.Base(cmdArray[0]), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (p *kubernetesPlugin) kubectl(ctx context.Context, c sdutils.AppContext, args ...string) (string, error) {
+	base, err := p.kubectlBase()
+	if err != nil {
+		return "", err
+	}
+	return runTool(ctx, c, append(base, args...))
+}
+
+func (p *kubernetesPlugin) applyManifest(ctx context.Context, c sdutils.AppContext, manifest string, message string) error {
+	base, err := p.kubectlBase()
+	if err != nil {
+		return err
+	}
+	cmdArray := append(base, "apply", "-f", manifest)
+	cmd := exec.Cmd{
+		Path: cmdArray[0],
+		Args: cmdArray,
+	}
+	spin := sdutils.NewSpinner(c, 1, message)
+	_, err = sdutils.RunCommand(ctx, c, cmd, nil, spin)
+	return err
+}
+
+func (p *kubernetesPlugin) deleteManifests(ctx context.Context, c sdutils.AppContext, manifests ...string) error {
+	args := []string{"delete", "--ignore-not-found"}
+	for _, m := range manifests {
+		if sdutils.PathExists(m) {
+			args = append(args, "-f", m)
+		}
+	}
+	if len(args) == 2 {
+		return nil
+	}
+	_, err := p.kubectl(ctx, c, args...)
+	return err
+}
+
+// renderManifest fills in one of the manifest templates and writes it to outFile.
+func renderManifest(assetName string, values interface{}, outFile string) error {
+	data, err := Asset(assetName)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(path.Base(assetName)).Parse(string(data))
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, values)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Dir(outFile), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outFile, buf.Bytes(), 0600)
+}
+
+func (p *kubernetesPlugin) FindLeaks(ctx context.Context, context sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	out, err := p.kubectl(ctx, context, "get", labeledKinds, "-l", labelSelector(deploymentName), "-o", "name")
+	if err != nil {
+		return err
+	}
+	for _, obj := range strings.Split(out, "\n") {
+		obj = strings.TrimSpace(obj)
+		if obj == "" {
+			continue
+		}
+		context.ConsoleLog(1, "Found %s\n", obj)
+		if !destroy {
+			continue
+		}
+		if !force && !sdutils.AskYesOrNo(context, fmt.Sprintf("Do you want to delete %s", obj)) {
+			continue
+		}
+		_, err = p.kubectl(ctx, context, "delete", obj)
+		if err != nil {
+			context.ConsoleLog(1, "Failed to delete %s: %s\n", obj, err)
+		} else {
+			context.ConsoleLog(1, "Deleted %s\n", obj)
+		}
+	}
+	return nil
+}
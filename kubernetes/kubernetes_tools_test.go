@@ -0,0 +1,68 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestFindLeaks(t *testing.T) {
+	exedir, kubectl, err := sdutils.CreateTestExec("kubectl", "statefulset.apps/dep1-zk\nservice/dep1-stardog\n", 0)
+	if err != nil {
+		t.Fatalf("Failed to make the fake kubectl %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	app := sdutils.TestContext{ConfigDir: exedir, Version: "5.0"}
+
+	plugin := GetPlugin().(*kubernetesPlugin)
+	plugin.KubectlPath = kubectl
+	plugin.KubeContext = "minikube"
+	err = plugin.FindLeaks(context.Background(), &app, "dep1", false, false)
+	if err != nil {
+		t.Fatalf("Failed to find leaks %s", err)
+	}
+	if readParams(t, exedir) != "--context minikube --namespace default get "+labeledKinds+" -l StardogVirtualAppliance=dep1 -o name" {
+		t.Fatalf("The leaks were not searched by label: %s", readParams(t, exedir))
+	}
+
+	err = plugin.FindLeaks(context.Background(), &app, "", true, true)
+	if err != nil {
+		t.Fatalf("Failed to destroy leaks %s", err)
+	}
+	if readParams(t, exedir) != "--context minikube --namespace default delete service/dep1-stardog" {
+		t.Fatalf("The leaks were not deleted: %s", readParams(t, exedir))
+	}
+}
+
+func TestFindLeaksFail(t *testing.T) {
+	exedir, kubectl, err := sdutils.CreateTestExec("kubectl", "", 1)
+	if err != nil {
+		t.Fatalf("Failed to make the fake kubectl %s", err)
+	}
+	defer os.RemoveAll(exedir)
+	app := sdutils.TestContext{ConfigDir: exedir, Version: "5.0"}
+
+	plugin := GetPlugin().(*kubernetesPlugin)
+	plugin.KubectlPath = kubectl
+	err = plugin.FindLeaks(context.Background(), &app, "", false, false)
+	if err == nil {
+		t.Fatalf("A kubectl failure should be reported")
+	}
+}
@@ -0,0 +1,171 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// VolumeStatusDescription lists the claims that hold the data of each node.
+type VolumeStatusDescription struct {
+	ClaimNames []string
+}
+
+// ClaimVolumes represents the PersistentVolumeClaims that hold STARDOG_HOME for
+// each node of the Stardog StatefulSet.
+type ClaimVolumes struct {
+	DeploymentName   string `json:"deployment_name,omitempty"`
+	SizeOfEachVolume int    `json:"storage_size,omitempty"`
+	ClusterSize      int    `json:"cluster_size,omitempty"`
+	LicensePath      string `json:"stardog_license,omitempty"`
+	StorageClass     string `json:"storage_class,omitempty"`
+	VolumeDir        string `json:"-"`
+	appContext       sdutils.AppContext
+	plugin           *kubernetesPlugin
+}
+
+type volumesManifest struct {
+	Name         string
+	LicenseData  string
+	Nodes        []int
+	StorageClass string
+	VolumeSize   int
+}
+
+// NewKubernetesVolumeManager returns a ClaimVolumes structure that will be used
+// by graviton to manage the volumes.
+func NewKubernetesVolumeManager(ac sdutils.AppContext, dd *kubernetesDeploymentDescription) *ClaimVolumes {
+	return &ClaimVolumes{
+		DeploymentName: dd.Name,
+		StorageClass:   dd.StorageClass,
+		VolumeDir:      path.Join(dd.deployDir, "volumes"),
+		appContext:     ac,
+		plugin:         dd.plugin,
+	}
+}
+
+// LoadClaimVolumes will inflate a ClaimVolumes structure from the information
+// stored under the configuration directory.
+func LoadClaimVolumes(ac sdutils.AppContext, volDir string) (*ClaimVolumes, error) {
+	var v ClaimVolumes
+	err := sdutils.LoadJSON(&v, path.Join(volDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	v.VolumeDir = volDir
+	v.appContext = ac
+	return &v, nil
+}
+
+// claimName matches the name the StatefulSet gives to the claim of each pod
+// so that the pods bind to the claims made here.
+func claimName(deploymentName string, index int) string {
+	return fmt.Sprintf("stardog-home-%s-stardog-%d", deploymentName, index)
+}
+
+func (v *ClaimVolumes) manifestPath() string {
+	return path.Join(v.VolumeDir, "volumes.yaml")
+}
+
+// VolumeExists returns true or false based on whether or not the volumes
+// already exist.
+func (v *ClaimVolumes) VolumeExists() bool {
+	return sdutils.PathExists(path.Join(v.VolumeDir, "config.json"))
+}
+
+// CreateSet makes a claim for every node and stores the license in a secret.
+func (v *ClaimVolumes) CreateSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	if clusterSize < 1 {
+		return fmt.Errorf("At least one Stardog node is required")
+	}
+	license, err := ioutil.ReadFile(licensePath)
+	if err != nil {
+		return fmt.Errorf("Could not read the license %s: %s", licensePath, err)
+	}
+	m := volumesManifest{
+		Name:         v.DeploymentName,
+		LicenseData:  base64.StdEncoding.EncodeToString(license),
+		StorageClass: v.StorageClass,
+		VolumeSize:   sizeOfEachVolume,
+	}
+	for i := 0; i < clusterSize; i++ {
+		m.Nodes = append(m.Nodes, i)
+	}
+	err = renderManifest("etc/kubernetes/volumes.yaml", &m, v.manifestPath())
+	if err != nil {
+		return err
+	}
+	err = v.plugin.applyManifest(ctx, v.appContext, v.manifestPath(), "Creating the volume claims")
+	if err != nil {
+		v.appContext.ConsoleLog(1, "Failed to create the volumes.\n")
+		return err
+	}
+
+	v.ClusterSize = clusterSize
+	v.SizeOfEachVolume = sizeOfEachVolume
+	v.LicensePath = licensePath
+	err = sdutils.WriteJSON(v, path.Join(v.VolumeDir, "config.json"))
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully created the volumes.\n")
+	return nil
+}
+
+// DeleteSet removes the claims and the license secret.
+func (v *ClaimVolumes) DeleteSet(ctx context.Context) error {
+	err := v.plugin.deleteManifests(ctx, v.appContext, v.manifestPath())
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(v.VolumeDir)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully destroyed the volumes.\n")
+	return nil
+}
+
+func (v *ClaimVolumes) getStatusInformation(ctx context.Context) (*VolumeStatusDescription, error) {
+	cv, err := LoadClaimVolumes(v.appContext, v.VolumeDir)
+	if err != nil {
+		return nil, err
+	}
+	volStatus := VolumeStatusDescription{
+		ClaimNames: make([]string, cv.ClusterSize),
+	}
+	for i := 0; i < cv.ClusterSize; i++ {
+		volStatus.ClaimNames[i] = claimName(v.DeploymentName, i)
+	}
+	return &volStatus, nil
+}
+
+// Status will print out the state of the claims.
+func (v *ClaimVolumes) Status(ctx context.Context) error {
+	out, err := v.plugin.kubectl(ctx, v.appContext, "get", "persistentvolumeclaims", "-l", labelSelector(v.DeploymentName))
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Volumes:\n%s", out)
+	return nil
+}
@@ -0,0 +1,121 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// imageDir is where an unpacked Stardog release lives.  It plays the role
+// that an AMI plays for the aws plugin.
+func imageDir(confDir string, version string) string {
+	return path.Join(confDir, "local", "images", version)
+}
+
+func (p *localPlugin) HaveImage(ctx context.Context, c sdutils.AppContext) bool {
+	return sdutils.PathExists(path.Join(imageDir(c.GetConfigDir(), c.GetVersion()), "bin", "stardog-admin"))
+}
+
+func unzipFile(f *zip.File, dest string) error {
+	target := filepath.Join(dest, f.Name)
+	if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return fmt.Errorf("The release contains the invalid path %s", f.Name)
+	}
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode()|0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// BuildImage unpacks the Stardog release so that local deployments of the
+// version can run it.
+func (p *localPlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	context.Logf(sdutils.DEBUG, "Unpacking the release %s\n", sdReleaseFilePath)
+
+	r, err := zip.OpenReader(sdReleaseFilePath)
+	if err != nil {
+		return fmt.Errorf("Failed to open the Stardog release %s: %s", sdReleaseFilePath, err)
+	}
+	defer r.Close()
+
+	imagesDir := path.Dir(imageDir(context.GetConfigDir(), version))
+	err = os.MkdirAll(imagesDir, 0755)
+	if err != nil {
+		return err
+	}
+	workDir, err := ioutil.TempDir(imagesDir, "unpack")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	spin := sdutils.NewSpinner(context, 1, "Unpacking the Stardog release")
+	for _, f := range r.File {
+		spin.EchoNext()
+		err = unzipFile(f, workDir)
+		if err != nil {
+			return err
+		}
+	}
+	spin.Close()
+
+	// Releases have a single top level directory named after the version
+	matches, err := filepath.Glob(path.Join(workDir, "*", "bin", "stardog-admin"))
+	if err != nil {
+		return err
+	}
+	if len(matches) != 1 {
+		return fmt.Errorf("The file %s does not appear to be a Stardog release", sdReleaseFilePath)
+	}
+	releaseDir := path.Dir(path.Dir(matches[0]))
+
+	dest := imageDir(context.GetConfigDir(), version)
+	err = os.RemoveAll(dest)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(releaseDir, dest)
+	if err != nil {
+		return err
+	}
+	context.ConsoleLog(0, "Stardog %s unpacked to %s\n", version, dest)
+	return nil
+}
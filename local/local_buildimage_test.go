@@ -0,0 +1,103 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+const fakeServer = "#!/bin/sh\nsleep 60\n"
+
+func makeFakeRelease(dir string, version string) (string, error) {
+	releaseFile := path.Join(dir, "stardog-"+version+".zip")
+	f, err := os.Create(releaseFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	hdr := zip.FileHeader{Name: "stardog-" + version + "/bin/stardog-admin", Method: zip.Deflate}
+	hdr.SetMode(0755)
+	w, err := zw.CreateHeader(&hdr)
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write([]byte(fakeServer))
+	if err != nil {
+		return "", err
+	}
+	return releaseFile, zw.Close()
+}
+
+func TestBuildImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := GetPlugin()
+	if plugin.HaveImage(context.Background(), &app) {
+		t.Fatalf("There should not be an image yet")
+	}
+	releaseFile, err := makeFakeRelease(dir, "5.0")
+	if err != nil {
+		t.Fatalf("Failed to make the release %s", err)
+	}
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err != nil {
+		t.Fatalf("Failed to unpack the release %s", err)
+	}
+	if !plugin.HaveImage(context.Background(), &app) {
+		t.Fatalf("The image should exist")
+	}
+	fi, err := os.Stat(path.Join(imageDir(dir, "5.0"), "bin", "stardog-admin"))
+	if err != nil {
+		t.Fatalf("The admin script is missing %s", err)
+	}
+	if fi.Mode()&0100 == 0 {
+		t.Fatalf("The admin script should be executable")
+	}
+}
+
+func TestBuildImageNotARelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := GetPlugin()
+	notZip := path.Join(dir, "release.zip")
+	ioutil.WriteFile(notZip, []byte("nope"), 0644)
+	err = plugin.BuildImage(context.Background(), &app, notZip, "5.0")
+	if err == nil {
+		t.Fatalf("Unpacking a bad release should fail")
+	}
+	err = plugin.BuildImage(context.Background(), &app, path.Join(dir, "missing.zip"), "5.0")
+	if err == nil {
+		t.Fatalf("Unpacking a missing release should fail")
+	}
+}
@@ -0,0 +1,276 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type localDeploymentDescription struct {
+	ZkHome          string `json:"zk_home,omitempty"`
+	StardogHome     string `json:"stardog_install,omitempty"`
+	BasePort        int    `json:"base_port,omitempty"`
+	ZkBasePort      int    `json:"zk_base_port,omitempty"`
+	Version         string `json:"-"`
+	Name            string `json:"-"`
+	deployDir       string
+	customPropFile  string
+	environment     []string
+	disableSecurity bool
+	ctx             sdutils.AppContext
+	plugin          *localPlugin
+}
+
+func newLocalDeploymentDescription(c sdutils.AppContext, baseD *sdutils.BaseDeployment, p *localPlugin) (*localDeploymentDescription, error) {
+	installDir := imageDir(c.GetConfigDir(), baseD.Version)
+	if !sdutils.PathExists(path.Join(installDir, "bin", "stardog-admin")) {
+		return nil, fmt.Errorf("Stardog %s has not been unpacked.  Please see the 'baseami' subcommand", baseD.Version)
+	}
+	if p.ZkHome == "" {
+		return nil, fmt.Errorf("The path to a ZooKeeper installation must be set with --local-zk-home")
+	}
+	if !sdutils.PathExists(path.Join(p.ZkHome, "bin", "zkServer.sh")) {
+		return nil, fmt.Errorf("%s does not appear to be a ZooKeeper installation", p.ZkHome)
+	}
+
+	dd := localDeploymentDescription{
+		ZkHome:          p.ZkHome,
+		StardogHome:     installDir,
+		BasePort:        p.BasePort,
+		ZkBasePort:      p.ZkBasePort,
+		Version:         baseD.Version,
+		Name:            baseD.Name,
+		deployDir:       sdutils.DeploymentDir(c.GetConfigDir(), baseD.Name),
+		customPropFile:  baseD.CustomPropsFile,
+		environment:     baseD.Environment,
+		disableSecurity: baseD.DisableSecurity,
+		ctx:             c,
+		plugin:          p,
+	}
+	return &dd, nil
+}
+
+func (dd *localDeploymentDescription) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (dd *localDeploymentDescription) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	vm := NewLocalVolumeManager(dd.ctx, dd)
+	return vm.CreateSet(licensePath, sizeOfEachVolume, clusterSize)
+}
+
+func (dd *localDeploymentDescription) DeleteVolumeSet(ctx context.Context) error {
+	vm := NewLocalVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	if dd.InstanceExists() {
+		return fmt.Errorf("The volumes of %s are in use by a running instance", dd.Name)
+	}
+	return vm.DeleteSet()
+}
+
+func (dd *localDeploymentDescription) ClusterSize() (int, error) {
+	vm := NewLocalVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return -1, fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	vols, err := LoadDirectoryVolumes(dd.ctx, vm.VolumeDir)
+	if err != nil {
+		return -1, err
+	}
+	return vols.ClusterSize, nil
+}
+
+func (dd *localDeploymentDescription) StatusVolumeSet(ctx context.Context) error {
+	vm := NewLocalVolumeManager(dd.ctx, dd)
+	if !vm.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", dd.Name)
+	}
+	return vm.Status()
+}
+
+func (dd *localDeploymentDescription) VolumeExists() bool {
+	vm := NewLocalVolumeManager(dd.ctx, dd)
+	return vm.VolumeExists()
+}
+
+func (dd *localDeploymentDescription) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	im, err := NewLocalInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.CreateInstance(ctx, zookeeperSize)
+}
+
+func (dd *localDeploymentDescription) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	// Processes listen on the local host so there is no firewall to open
+	dd.ctx.Logf(sdutils.DEBUG, "Ignoring the mask %s for the local deployment %s", mask, dd.Name)
+	return nil
+}
+
+func (dd *localDeploymentDescription) DeleteInstance(ctx context.Context) error {
+	im, err := NewLocalInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.DeleteInstance()
+}
+
+func (dd *localDeploymentDescription) StatusInstance(ctx context.Context) error {
+	im, err := NewLocalInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.Status()
+}
+
+func (dd *localDeploymentDescription) InstanceExists() bool {
+	im, err := NewLocalInstance(dd.ctx, dd)
+	if err != nil {
+		return false
+	}
+	return im.InstanceExists()
+}
+
+func (dd *localDeploymentDescription) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	vm := NewLocalVolumeManager(dd.ctx, dd)
+	volumeStatus, err := vm.getStatusInformation()
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No volume information found %s\n", err)
+	}
+
+	im, err := NewLocalInstance(dd.ctx, dd)
+	if err != nil {
+		return nil, err
+	}
+	instS, err := im.getStatusInformation()
+	if err != nil {
+		dd.ctx.ConsoleLog(1, "No instance information found.\n")
+	}
+
+	sdURL := fmt.Sprintf("http://localhost:%d", dd.BasePort)
+	sD := sdutils.StardogDescription{
+		StardogURL:          sdURL,
+		StardogInternalURL:  sdURL,
+		VolumeDescription:   volumeStatus,
+		InstanceDescription: instS,
+		TimeStamp:           time.Now(),
+	}
+	return &sD, nil
+}
+
+func (dd *localDeploymentDescription) GatherLogs(ctx context.Context, outfile string) error {
+	im, err := NewLocalInstance(dd.ctx, dd)
+	if err != nil {
+		return err
+	}
+	return im.GatherLogs(outfile)
+}
+
+type localPlugin struct {
+	ZkHome     string `json:"zk_home,omitempty"`
+	BasePort   int    `json:"base_port,omitempty"`
+	ZkBasePort int    `json:"zk_base_port,omitempty"`
+}
+
+// GetPlugin returns the plugin interface that this module represents.
+func GetPlugin() sdutils.Plugin {
+	return &localPlugin{
+		ZkHome:     "",
+		BasePort:   5821,
+		ZkBasePort: 2181,
+	}
+}
+
+func (p *localPlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	b, err := json.Marshal(defaultCliOpts)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *localPlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	cmdOpts.LaunchCmd.Flag("local-zk-home", "The ZooKeeper installation used by local deployments.").Default(p.ZkHome).StringVar(&p.ZkHome)
+	cmdOpts.LaunchCmd.Flag("local-port", "The port of the first Stardog node in a local deployment.").Default(fmt.Sprintf("%d", p.BasePort)).IntVar(&p.BasePort)
+	cmdOpts.LaunchCmd.Flag("local-zk-port", "The client port of the first ZooKeeper node in a local deployment.").Default(fmt.Sprintf("%d", p.ZkBasePort)).IntVar(&p.ZkBasePort)
+
+	cmdOpts.NewDeploymentCmd.Flag("local-zk-home", "The ZooKeeper installation used by local deployments.").Default(p.ZkHome).StringVar(&p.ZkHome)
+	cmdOpts.NewDeploymentCmd.Flag("local-port", "The port of the first Stardog node in a local deployment.").Default(fmt.Sprintf("%d", p.BasePort)).IntVar(&p.BasePort)
+	cmdOpts.NewDeploymentCmd.Flag("local-zk-port", "The client port of the first ZooKeeper node in a local deployment.").Default(fmt.Sprintf("%d", p.ZkBasePort)).IntVar(&p.ZkBasePort)
+
+	return nil
+}
+
+func (p *localPlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	if new {
+		localDD, err := newLocalDeploymentDescription(context, baseD, p)
+		if err != nil {
+			return nil, err
+		}
+		baseD.CloudOpts = localDD
+		data, err := json.Marshal(baseD)
+		if err != nil {
+			return nil, err
+		}
+		confPath := path.Join(localDD.deployDir, "config.json")
+		err = ioutil.WriteFile(confPath, data, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return localDD, nil
+	}
+	data, err := json.Marshal(baseD.CloudOpts)
+	if err != nil {
+		return nil, err
+	}
+	var dd localDeploymentDescription
+	err = json.Unmarshal(data, &dd)
+	if err != nil {
+		return nil, err
+	}
+	dd.Name = baseD.Name
+	dd.Version = baseD.Version
+	dd.ctx = context
+	dd.deployDir = sdutils.DeploymentDir(context.GetConfigDir(), baseD.Name)
+	dd.customPropFile = baseD.CustomPropsFile
+	dd.environment = baseD.Environment
+	dd.disableSecurity = baseD.DisableSecurity
+	dd.plugin = p
+
+	return &dd, nil
+}
+
+func (p *localPlugin) GetName() string {
+	return "local"
+}
+
+// Local processes listen on the local host so there is no firewall to open.
+func (p *localPlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true}
+}
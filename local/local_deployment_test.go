@@ -0,0 +1,259 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+func TestDeploymentLoadDefaults(t *testing.T) {
+	plugin := &localPlugin{BasePort: 5821, ZkBasePort: 2181}
+	i := make(map[string]interface{})
+	i["zk_home"] = "/opt/zookeeper"
+	i["base_port"] = 6000
+
+	err := plugin.LoadDefaults(&i)
+	if err != nil {
+		t.Fatalf("Failed to load defaults %s", err)
+	}
+	if plugin.ZkHome != "/opt/zookeeper" {
+		t.Fatalf("zk_home not set right")
+	}
+	if plugin.BasePort != 6000 {
+		t.Fatalf("base_port not set right")
+	}
+	if plugin.ZkBasePort != 2181 {
+		t.Fatalf("zk_base_port should keep its default")
+	}
+}
+
+func setupLocalDeployment(t *testing.T) (string, *sdutils.TestContext, *sdutils.BaseDeployment, sdutils.Deployment) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := &localPlugin{BasePort: 5821, ZkBasePort: 2181}
+
+	releaseFile, err := makeFakeRelease(dir, "5.0")
+	if err != nil {
+		t.Fatalf("Failed to make the release %s", err)
+	}
+	err = plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	if err != nil {
+		t.Fatalf("Failed to unpack the release %s", err)
+	}
+
+	plugin.ZkHome = path.Join(dir, "zookeeper")
+	os.MkdirAll(path.Join(plugin.ZkHome, "bin"), 0755)
+	err = ioutil.WriteFile(path.Join(plugin.ZkHome, "bin", "zkServer.sh"), []byte(fakeServer), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the fake zookeeper %s", err)
+	}
+
+	baseD := sdutils.BaseDeployment{
+		Type:      plugin.GetName(),
+		Name:      "testdep",
+		Directory: sdutils.DeploymentDir(dir, "testdep"),
+		Version:   "5.0",
+	}
+	os.MkdirAll(baseD.Directory, 0755)
+	dep, err := plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err != nil {
+		t.Fatalf("Failed to make the deployment %s", err)
+	}
+	return dir, &app, &baseD, dep
+}
+
+func TestNoZookeeper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtests")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+	app := sdutils.TestContext{ConfigDir: dir, Version: "5.0"}
+	plugin := &localPlugin{}
+	baseD := sdutils.BaseDeployment{Name: "testdep", Version: "5.0"}
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The deployment should fail without an unpacked release")
+	}
+
+	releaseFile, _ := makeFakeRelease(dir, "5.0")
+	plugin.BuildImage(context.Background(), &app, releaseFile, "5.0")
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The deployment should fail without zookeeper")
+	}
+	plugin.ZkHome = dir
+	_, err = plugin.DeploymentLoader(context.Background(), &app, &baseD, true)
+	if err == nil {
+		t.Fatalf("The deployment should fail with a bad zookeeper home")
+	}
+}
+
+func TestLocalLifecycle(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	dir, _, baseD, dep := setupLocalDeployment(t)
+	defer os.RemoveAll(dir)
+
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+
+	if dep.VolumeExists() {
+		t.Fatalf("The volumes should not exist yet")
+	}
+	err := dep.CreateInstance(context.Background(), 10, 1, 0)
+	if err == nil {
+		t.Fatalf("The instance should need volumes")
+	}
+	err = dep.CreateVolumeSet(context.Background(), licenseFile, 10, 2)
+	if err != nil {
+		t.Fatalf("Failed to make the volumes %s", err)
+	}
+	size, err := dep.ClusterSize()
+	if err != nil || size != 2 {
+		t.Fatalf("The cluster size should be 2 %d %s", size, err)
+	}
+	for i := 0; i < 2; i++ {
+		lic := path.Join(nodeHome(path.Join(baseD.Directory, "volumes"), i), "stardog-license-key.bin")
+		if !sdutils.PathExists(lic) {
+			t.Fatalf("The license was not copied to node %d", i)
+		}
+	}
+
+	err = dep.CreateInstance(context.Background(), 10, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to start the instance %s", err)
+	}
+	if !dep.InstanceExists() {
+		t.Fatalf("The instance should exist")
+	}
+	err = dep.DeleteVolumeSet(context.Background())
+	if err == nil {
+		t.Fatalf("The volumes should not be deleted while in use")
+	}
+
+	sd, err := dep.FullStatus(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get the status %s", err)
+	}
+	instS := sd.InstanceDescription.(*InstanceStatusDescription)
+	if len(instS.StardogPids) != 2 || len(instS.ZookeeperPids) != 1 {
+		t.Fatalf("The processes are not running %v", instS)
+	}
+	props, err := ioutil.ReadFile(path.Join(nodeHome(path.Join(baseD.Directory, "volumes"), 1), "stardog.properties"))
+	if err != nil {
+		t.Fatalf("The properties were not written %s", err)
+	}
+	if string(props) != "pack.enabled=true\npack.node.address=127.0.0.1:5822\npack.zookeeper.address=127.0.0.1:2181\n" {
+		t.Fatalf("The properties are wrong %s", string(props))
+	}
+
+	pids, _ := filepath.Glob(path.Join(pidDir(dir), "testdep-*.pid"))
+	if len(pids) != 3 {
+		t.Fatalf("There should be 3 registered processes %v", pids)
+	}
+
+	logFile := path.Join(dir, "logs.tar.gz")
+	err = dep.(sdutils.LogGatherer).GatherLogs(context.Background(), logFile)
+	if err != nil || !sdutils.PathExists(logFile) {
+		t.Fatalf("Failed to gather the logs %s", err)
+	}
+
+	err = dep.DeleteInstance(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to stop the instance %s", err)
+	}
+	for _, pid := range append(instS.StardogPids, instS.ZookeeperPids...) {
+		if processRunning(pid) {
+			t.Fatalf("The process %d is still running", pid)
+		}
+	}
+	pids, _ = filepath.Glob(path.Join(pidDir(dir), "testdep-*.pid"))
+	if len(pids) != 0 {
+		t.Fatalf("The processes should be unregistered %v", pids)
+	}
+	err = dep.DeleteVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to delete the volumes %s", err)
+	}
+}
+
+func TestFindLeaks(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	dir, app, _, dep := setupLocalDeployment(t)
+	defer os.RemoveAll(dir)
+
+	licenseFile := path.Join(dir, "license")
+	ioutil.WriteFile(licenseFile, []byte("license"), 0644)
+	err := dep.CreateVolumeSet(context.Background(), licenseFile, 10, 1)
+	if err != nil {
+		t.Fatalf("Failed to make the volumes %s", err)
+	}
+	err = dep.CreateInstance(context.Background(), 10, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to start the instance %s", err)
+	}
+	// Simulate a deployment directory that was removed while running
+	os.RemoveAll(sdutils.DeploymentDir(dir, "testdep"))
+
+	registerProcess(app, "gone", LocalProcess{Name: "stardog0", Pid: 999999, Dir: dir})
+
+	plugin := GetPlugin()
+	err = plugin.FindLeaks(context.Background(), app, "", true, true)
+	if err != nil {
+		t.Fatalf("Failed to look for leaks %s", err)
+	}
+	pids, _ := filepath.Glob(path.Join(pidDir(dir), "*.pid"))
+	if len(pids) != 0 {
+		t.Fatalf("The leaked processes should be gone %v", pids)
+	}
+}
+
+func TestFindLeaksPrefix(t *testing.T) {
+	os.Setenv("STARDOG_GRAVITON_UNIT_TEST", "1")
+	defer os.Unsetenv("STARDOG_GRAVITON_UNIT_TEST")
+
+	dir, app, _, _ := setupLocalDeployment(t)
+	defer os.RemoveAll(dir)
+
+	registerProcess(app, "dev", LocalProcess{Name: "zk", Pid: 999999, Dir: dir})
+	registerProcess(app, "dev-2", LocalProcess{Name: "zk", Pid: 999998, Dir: dir})
+
+	plugin := GetPlugin()
+	err := plugin.FindLeaks(context.Background(), app, "dev", true, true)
+	if err != nil {
+		t.Fatalf("Failed to look for leaks %s", err)
+	}
+	if _, err := os.Stat(pidFile(dir, "dev", "zk")); !os.IsNotExist(err) {
+		t.Fatalf("The stale pid file of dev should be gone")
+	}
+	if _, err := os.Stat(pidFile(dir, "dev-2", "zk")); err != nil {
+		t.Fatalf("The pid file of dev-2 should not be touched %s", err)
+	}
+}
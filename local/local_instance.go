@@ -0,0 +1,370 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// LocalProcess is a single ZooKeeper or Stardog server running on this host.
+type LocalProcess struct {
+	Name string `json:"name,omitempty"`
+	Pid  int    `json:"pid,omitempty"`
+	Port int    `json:"port,omitempty"`
+	Dir  string `json:"dir,omitempty"`
+	Log  string `json:"log,omitempty"`
+}
+
+// ProcessInstance represents the set of processes that make up a running
+// Stardog cluster on this host.
+type ProcessInstance struct {
+	DeploymentName string             `json:"deployment_name,omitempty"`
+	StardogNodes   []LocalProcess     `json:"stardog_nodes,omitempty"`
+	ZkNodes        []LocalProcess     `json:"zookeeper_nodes,omitempty"`
+	InstanceDir    string             `json:"-"`
+	Ctx            sdutils.AppContext `json:"-"`
+	dd             *localDeploymentDescription
+}
+
+// InstanceStatusDescription describes details about a running local instance.
+type InstanceStatusDescription struct {
+	ZkNodesContact []string
+	StardogPids    []int
+	ZookeeperPids  []int
+}
+
+// NewLocalInstance returns a ProcessInstance which will be used to start or
+// inspect the Stardog processes of a deployment.
+func NewLocalInstance(ctx sdutils.AppContext, dd *localDeploymentDescription) (*ProcessInstance, error) {
+	instance := ProcessInstance{
+		DeploymentName: dd.Name,
+		InstanceDir:    path.Join(dd.deployDir, "instance"),
+		Ctx:            ctx,
+		dd:             dd,
+	}
+	return &instance, nil
+}
+
+func (li *ProcessInstance) confPath() string {
+	return path.Join(li.InstanceDir, "instance.json")
+}
+
+func (li *ProcessInstance) load() error {
+	err := sdutils.LoadJSON(li, li.confPath())
+	if err != nil {
+		return fmt.Errorf("There is no configured instance")
+	}
+	return nil
+}
+
+func (li *ProcessInstance) save() error {
+	return sdutils.WriteJSON(li, li.confPath())
+}
+
+func (li *ProcessInstance) zkServers() []string {
+	servers := make([]string, len(li.ZkNodes))
+	for i, zk := range li.ZkNodes {
+		servers[i] = fmt.Sprintf("127.0.0.1:%d", zk.Port)
+	}
+	return servers
+}
+
+func (li *ProcessInstance) environment(extra ...string) []string {
+	env := os.Environ()
+	for _, e := range li.dd.environment {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s=%s", kv[0], strings.Trim(kv[1], "\"'")))
+	}
+	return append(env, extra...)
+}
+
+func (li *ProcessInstance) writeZkConf(index int, zkSize int, dir string) (string, error) {
+	dataDir := path.Join(dir, "data")
+	err := os.MkdirAll(dataDir, 0755)
+	if err != nil {
+		return "", err
+	}
+	err = ioutil.WriteFile(path.Join(dataDir, "myid"), []byte(fmt.Sprintf("%d\n", index+1)), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("tickTime=3000\ninitLimit=10\nsyncLimit=5\n")
+	buf.WriteString(fmt.Sprintf("dataDir=%s\n", dataDir))
+	buf.WriteString(fmt.Sprintf("clientPort=%d\n", li.dd.ZkBasePort+index))
+	for i := 0; i < zkSize; i++ {
+		// Every server on this host needs its own quorum and election ports
+		buf.WriteString(fmt.Sprintf("server.%d=127.0.0.1:%d:%d\n", i+1, li.dd.ZkBasePort+100+i, li.dd.ZkBasePort+200+i))
+	}
+	confFile := path.Join(dir, "zoo.cfg")
+	err = ioutil.WriteFile(confFile, buf.Bytes(), 0644)
+	if err != nil {
+		return "", err
+	}
+	return confFile, nil
+}
+
+func (li *ProcessInstance) writeStardogConf(home string, port int) error {
+	var buf bytes.Buffer
+	buf.WriteString("pack.enabled=true\n")
+	buf.WriteString(fmt.Sprintf("pack.node.address=127.0.0.1:%d\n", port))
+	buf.WriteString(fmt.Sprintf("pack.zookeeper.address=%s\n", strings.Join(li.zkServers(), ",")))
+	if li.dd.customPropFile != "" {
+		data, err := ioutil.ReadFile(li.dd.customPropFile)
+		if err != nil {
+			return fmt.Errorf("Invalid custom properties file: %s", err)
+		}
+		buf.Write(data)
+	}
+	return ioutil.WriteFile(path.Join(home, "stardog.properties"), buf.Bytes(), 0644)
+}
+
+func (li *ProcessInstance) startZookeeper(index int, zkSize int) error {
+	dir := path.Join(li.InstanceDir, fmt.Sprintf("zk%d", index))
+	confFile, err := li.writeZkConf(index, zkSize, dir)
+	if err != nil {
+		return err
+	}
+	proc := LocalProcess{
+		Name: fmt.Sprintf("zk%d", index),
+		Port: li.dd.ZkBasePort + index,
+		Dir:  dir,
+		Log:  path.Join(dir, "zookeeper.out"),
+	}
+	cmdArray := []string{path.Join(li.dd.ZkHome, "bin", "zkServer.sh"), "start-foreground", confFile}
+	proc.Pid, err = startProcess(li.Ctx, cmdArray, li.environment(fmt.Sprintf("ZOO_LOG_DIR=%s", dir)), dir, proc.Log)
+	if err != nil {
+		return err
+	}
+	li.ZkNodes = append(li.ZkNodes, proc)
+	return registerProcess(li.Ctx, li.DeploymentName, proc)
+}
+
+func (li *ProcessInstance) startStardog(index int) error {
+	home := nodeHome(path.Join(li.dd.deployDir, "volumes"), index)
+	port := li.dd.BasePort + index
+	err := li.writeStardogConf(home, port)
+	if err != nil {
+		return err
+	}
+	os.Remove(path.Join(home, "system.lock"))
+
+	proc := LocalProcess{
+		Name: fmt.Sprintf("stardog%d", index),
+		Port: port,
+		Dir:  home,
+		Log:  path.Join(li.InstanceDir, fmt.Sprintf("stardog%d.out", index)),
+	}
+	cmdArray := []string{path.Join(li.dd.StardogHome, "bin", "stardog-admin"), "server", "start",
+		"--foreground", "--home", home, "--port", fmt.Sprintf("%d", port)}
+	if li.dd.disableSecurity {
+		cmdArray = append(cmdArray, "--disable-security")
+	}
+	proc.Pid, err = startProcess(li.Ctx, cmdArray, li.environment(fmt.Sprintf("STARDOG_HOME=%s", home)), home, proc.Log)
+	if err != nil {
+		return err
+	}
+	li.StardogNodes = append(li.StardogNodes, proc)
+	return registerProcess(li.Ctx, li.DeploymentName, proc)
+}
+
+// CreateInstance starts the ZooKeeper ensemble and then one Stardog server
+// for each volume.
+func (li *ProcessInstance) CreateInstance(ctx context.Context, zookeeperSize int) error {
+	if li.InstanceExists() {
+		li.Ctx.ConsoleLog(1, "The instance already exists.\n")
+		li.Ctx.Logf(sdutils.INFO, "The instance already exists.")
+		return nil
+	}
+	vols, err := LoadDirectoryVolumes(li.Ctx, path.Join(li.dd.deployDir, "volumes"))
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(li.InstanceDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	li.StardogNodes = []LocalProcess{}
+	li.ZkNodes = []LocalProcess{}
+	spin := sdutils.NewSpinner(li.Ctx, 1, "Starting the ZooKeeper nodes")
+	for i := 0; i < zookeeperSize; i++ {
+		spin.EchoNext()
+		err = li.startZookeeper(i, zookeeperSize)
+		if err != nil {
+			li.save()
+			li.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+			return err
+		}
+	}
+	spin.Close()
+	err = li.save()
+	if err != nil {
+		return err
+	}
+	err = waitForPorts(ctx, li.zkServers(), 100)
+	if err != nil {
+		return err
+	}
+
+	spin = sdutils.NewSpinner(li.Ctx, 1, "Starting the Stardog nodes")
+	for i := 0; i < vols.ClusterSize; i++ {
+		spin.EchoNext()
+		err = li.startStardog(i)
+		if err != nil {
+			li.save()
+			li.Ctx.ConsoleLog(1, "Failed to create the instance.\n")
+			return err
+		}
+	}
+	spin.Close()
+	err = li.save()
+	if err != nil {
+		return err
+	}
+	li.Ctx.ConsoleLog(1, "Successfully created the instance.\n")
+	return nil
+}
+
+// DeleteInstance stops every process of the deployment.
+func (li *ProcessInstance) DeleteInstance() error {
+	err := li.load()
+	if err != nil {
+		return err
+	}
+	spin := sdutils.NewSpinner(li.Ctx, 1, "Stopping the Stardog processes")
+	failed := []string{}
+	for _, p := range append(li.StardogNodes, li.ZkNodes...) {
+		spin.EchoNext()
+		err = stopProcess(li.Ctx, li.DeploymentName, p)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%d)", p.Name, p.Pid))
+		}
+	}
+	spin.Close()
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to stop the processes %s", strings.Join(failed, ", "))
+	}
+	os.Remove(li.confPath())
+	li.Ctx.ConsoleLog(1, "Successfully destroyed the instance.\n")
+	return nil
+}
+
+// InstanceExists will return true if processes were started for this deployment.
+func (li *ProcessInstance) InstanceExists() bool {
+	return sdutils.PathExists(li.confPath())
+}
+
+func (li *ProcessInstance) getStatusInformation() (*InstanceStatusDescription, error) {
+	err := li.load()
+	if err != nil {
+		return nil, err
+	}
+	s := InstanceStatusDescription{
+		ZkNodesContact: li.zkServers(),
+	}
+	for _, p := range li.StardogNodes {
+		if processRunning(p.Pid) {
+			s.StardogPids = append(s.StardogPids, p.Pid)
+		}
+	}
+	for _, p := range li.ZkNodes {
+		if processRunning(p.Pid) {
+			s.ZookeeperPids = append(s.ZookeeperPids, p.Pid)
+		}
+	}
+	return &s, nil
+}
+
+// Status will print the state of every process in the deployment.
+func (li *ProcessInstance) Status() error {
+	err := li.load()
+	if err != nil {
+		return err
+	}
+	for _, p := range li.StardogNodes {
+		li.Ctx.ConsoleLog(1, "Stardog: http://localhost:%d %s\n", p.Port, processState(p.Pid))
+	}
+	for _, p := range li.ZkNodes {
+		li.Ctx.ConsoleLog(1, "ZooKeeper: 127.0.0.1:%d %s\n", p.Port, processState(p.Pid))
+	}
+	return nil
+}
+
+// GatherLogs writes the Stardog and ZooKeeper logs into a gzipped tarball.
+func (li *ProcessInstance) GatherLogs(outfile string) error {
+	err := li.load()
+	if err != nil {
+		return err
+	}
+	logFiles := []string{}
+	for _, p := range li.StardogNodes {
+		logFiles = append(logFiles, path.Join(p.Dir, "stardog.log"), p.Log)
+	}
+	for _, p := range li.ZkNodes {
+		logFiles = append(logFiles, path.Join(p.Dir, "zookeeper.log"), p.Log)
+	}
+
+	f, err := os.Create(outfile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for _, logFile := range logFiles {
+		fi, err := os.Stat(logFile)
+		if err != nil {
+			li.Ctx.Logf(sdutils.DEBUG, "Skipping the log file %s: %s", logFile, err)
+			continue
+		}
+		name := strings.TrimPrefix(logFile, li.dd.deployDir+"/")
+		hdr := tar.Header{Name: name, Mode: 0644, Size: fi.Size(), ModTime: fi.ModTime()}
+		err = tw.WriteHeader(&hdr)
+		if err != nil {
+			return err
+		}
+		lf, err := os.Open(logFile)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(tw, lf, fi.Size())
+		lf.Close()
+		if err != nil {
+			return err
+		}
+	}
+	li.Ctx.Logf(sdutils.INFO, "Wrote the logs to %s", outfile)
+	return nil
+}
@@ -0,0 +1,193 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// The pid registry lives outside of the deployment directories so that
+// processes can still be found after a deployment was removed.
+func pidDir(confDir string) string {
+	return path.Join(confDir, "local", "pids")
+}
+
+func pidFile(confDir string, deploymentName string, name string) string {
+	return path.Join(pidDir(confDir), fmt.Sprintf("%s-%s.pid", deploymentName, name))
+}
+
+func registerProcess(ctx sdutils.AppContext, deploymentName string, p LocalProcess) error {
+	err := os.MkdirAll(pidDir(ctx.GetConfigDir()), 0755)
+	if err != nil {
+		return err
+	}
+	return sdutils.WriteJSON(&p, pidFile(ctx.GetConfigDir(), deploymentName, p.Name))
+}
+
+func startProcess(ctx sdutils.AppContext, cmdArray []string, env []string, dir string, logPath string) (int, error) {
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return -1, err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Cmd{
+		Path:   cmdArray[0],
+		Args:   cmdArray,
+		Env:    env,
+		Dir:    dir,
+		Stdout: logFile,
+		Stderr: logFile,
+		// Put every server in its own process group so that it survives the
+		// graviton process and can be stopped along with its children.
+		SysProcAttr: &syscall.SysProcAttr{Setsid: true},
+	}
+	ctx.Logf(sdutils.INFO, "Starting %s", strings.Join(cmdArray, " "))
+	err = cmd.Start()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to start %s: %s", cmdArray[0], err)
+	}
+	go cmd.Wait()
+	return cmd.Process.Pid, nil
+}
+
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return syscall.Kill(pid, syscall.Signal(0)) == nil
+}
+
+func processState(pid int) string {
+	if processRunning(pid) {
+		return fmt.Sprintf("running (pid %d)", pid)
+	}
+	return "stopped"
+}
+
+// processOwned verifies that the pid was not recycled by an unrelated
+// process by looking for the node directory in its command line.
+func processOwned(p LocalProcess) bool {
+	if !processRunning(p.Pid) {
+		return false
+	}
+	out, err := exec.Command("ps", "-o", "command=", "-p", fmt.Sprintf("%d", p.Pid)).Output()
+	if err != nil {
+		// Without ps there is no way to tell so trust the pid
+		_, lookErr := exec.LookPath("ps")
+		return lookErr != nil
+	}
+	return strings.Contains(string(out), p.Dir)
+}
+
+func stopProcess(ctx sdutils.AppContext, deploymentName string, p LocalProcess) error {
+	if processOwned(p) {
+		ctx.Logf(sdutils.INFO, "Sending SIGTERM to %s (%d)", p.Name, p.Pid)
+		syscall.Kill(-p.Pid, syscall.SIGTERM)
+		for i := 0; i < 30 && processRunning(p.Pid); i++ {
+			time.Sleep(time.Second)
+		}
+		if processRunning(p.Pid) {
+			ctx.Logf(sdutils.WARN, "Sending SIGKILL to %s (%d)", p.Name, p.Pid)
+			syscall.Kill(-p.Pid, syscall.SIGKILL)
+			time.Sleep(time.Second)
+		}
+		if processRunning(p.Pid) {
+			return fmt.Errorf("The process %d could not be stopped", p.Pid)
+		}
+	}
+	os.Remove(pidFile(ctx.GetConfigDir(), deploymentName, p.Name))
+	return nil
+}
+
+func waitForPorts(ctx context.Context, addrs []string, waitTimeout int) error {
+	if os.Getenv("STARDOG_GRAVITON_UNIT_TEST") != "" {
+		return nil
+	}
+	for _, addr := range addrs {
+		start := time.Now()
+		for {
+			conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			if time.Since(start) > time.Duration(waitTimeout)*time.Second {
+				return fmt.Errorf("Timed out waiting for %s to listen", addr)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+	}
+	return nil
+}
+
+// FindLeaks looks for graviton started processes that are still running.
+func (p *localPlugin) FindLeaks(ctx context.Context, context sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	pattern := "*.pid"
+	if deploymentName != "" {
+		pattern = fmt.Sprintf("%s-*.pid", deploymentName)
+	}
+	files, err := filepath.Glob(path.Join(pidDir(context.GetConfigDir()), pattern))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		var proc LocalProcess
+		err = sdutils.LoadJSON(&proc, f)
+		if err != nil {
+			context.Logf(sdutils.WARN, "Could not read the pid file %s: %s", f, err)
+			continue
+		}
+		// The glob for dev also matches the files of dev-2 so only the
+		// exact <deployment>-<name>.pid file belongs to the deployment
+		owner := strings.TrimSuffix(strings.TrimSuffix(path.Base(f), ".pid"), "-"+proc.Name)
+		if deploymentName != "" && owner != deploymentName {
+			continue
+		}
+		if !processOwned(proc) {
+			context.Logf(sdutils.INFO, "Removing the stale pid file %s", f)
+			os.Remove(f)
+			continue
+		}
+		context.ConsoleLog(1, "Process %d (%s) of deployment %s is running\n", proc.Pid, proc.Name, owner)
+		if destroy {
+			if !force && !sdutils.AskYesOrNo(context, fmt.Sprintf("Do you want to stop process %d", proc.Pid)) {
+				continue
+			}
+			err = stopProcess(context, owner, proc)
+			if err != nil {
+				context.ConsoleLog(1, "%s\n", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// VolumeStatusDescription lists the directories that act as the volumes of a
+// local deployment.
+type VolumeStatusDescription struct {
+	Directories []string
+}
+
+// DirectoryVolumes describes the directories used to store STARDOG_HOME for each
+// node of a local deployment.
+type DirectoryVolumes struct {
+	DeploymentName   string `json:"deployment_name,omitempty"`
+	SizeOfEachVolume int    `json:"storage_size,omitempty"`
+	ClusterSize      int    `json:"cluster_size,omitempty"`
+	LicensePath      string `json:"stardog_license,omitempty"`
+	VolumeDir        string `json:"-"`
+	appContext       sdutils.AppContext
+}
+
+// NewLocalVolumeManager returns a DirectoryVolumes structure that will be used by
+// graviton to manage the volumes.
+func NewLocalVolumeManager(ac sdutils.AppContext, dd *localDeploymentDescription) *DirectoryVolumes {
+	return &DirectoryVolumes{
+		DeploymentName: dd.Name,
+		VolumeDir:      path.Join(dd.deployDir, "volumes"),
+		appContext:     ac,
+	}
+}
+
+// LoadDirectoryVolumes will inflate a DirectoryVolumes structure from the information
+// stored under the configuration directory.
+func LoadDirectoryVolumes(ac sdutils.AppContext, volDir string) (*DirectoryVolumes, error) {
+	var lv DirectoryVolumes
+	err := sdutils.LoadJSON(&lv, path.Join(volDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	lv.VolumeDir = volDir
+	lv.appContext = ac
+	return &lv, nil
+}
+
+func nodeHome(volumeDir string, index int) string {
+	return path.Join(volumeDir, fmt.Sprintf("node%d", index), "stardog-home")
+}
+
+// VolumeExists returns true or false based on whether or not the volumes
+// already exist.
+func (v *DirectoryVolumes) VolumeExists() bool {
+	return sdutils.PathExists(path.Join(v.VolumeDir, "config.json"))
+}
+
+// CreateSet makes a STARDOG_HOME directory for every node and copies the
+// license into each of them.
+func (v *DirectoryVolumes) CreateSet(licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	v.appContext.ConsoleLog(2, "Creating a local volume set in directory %s\n", v.VolumeDir)
+	if clusterSize < 1 {
+		return fmt.Errorf("At least one Stardog node is required")
+	}
+	license, err := ioutil.ReadFile(licensePath)
+	if err != nil {
+		return fmt.Errorf("Could not read the license %s: %s", licensePath, err)
+	}
+	v.appContext.Logf(sdutils.INFO, "Local volumes are not limited to %d gigabytes", sizeOfEachVolume)
+
+	for i := 0; i < clusterSize; i++ {
+		home := nodeHome(v.VolumeDir, i)
+		err = os.MkdirAll(home, 0755)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(path.Join(home, "stardog-license-key.bin"), license, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	v.ClusterSize = clusterSize
+	v.SizeOfEachVolume = sizeOfEachVolume
+	v.LicensePath = licensePath
+	err = sdutils.WriteJSON(v, path.Join(v.VolumeDir, "config.json"))
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully created the volumes.\n")
+	return nil
+}
+
+// DeleteSet removes all of the node directories.
+func (v *DirectoryVolumes) DeleteSet() error {
+	err := os.RemoveAll(v.VolumeDir)
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Successfully destroyed the volumes.\n")
+	return nil
+}
+
+func (v *DirectoryVolumes) getStatusInformation() (*VolumeStatusDescription, error) {
+	lv, err := LoadDirectoryVolumes(v.appContext, v.VolumeDir)
+	if err != nil {
+		return nil, err
+	}
+	volStatus := VolumeStatusDescription{
+		Directories: make([]string, lv.ClusterSize),
+	}
+	for i := 0; i < lv.ClusterSize; i++ {
+		volStatus.Directories[i] = nodeHome(v.VolumeDir, i)
+	}
+	return &volStatus, nil
+}
+
+// Status will print out the directories backing each node.
+func (v *DirectoryVolumes) Status() error {
+	vD, err := v.getStatusInformation()
+	if err != nil {
+		return err
+	}
+	v.appContext.ConsoleLog(1, "Volumes:\n")
+	for _, x := range vD.Directories {
+		v.appContext.ConsoleLog(1, "%s\n", x)
+	}
+	return nil
+}
@@ -16,18 +16,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"os/user"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
-	"github.com/stardog-union/stardog-graviton/aws"
+	"github.com/stardog-union/stardog-graviton/graviton"
 	"github.com/stardog-union/stardog-graviton/sdutils"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -73,10 +78,56 @@ type CliContext struct {
 	Interactive       bool               `json:"-"`
 	Destroy           bool               `json:"-"`
 	NoWaitForHealthy  bool               `json:"-"`
+	Resume            bool               `json:"-"`
+	Rollback          bool               `json:"-"`
+	SdInstanceType    string             `json:"-"`
+	ImageID           string             `json:"-"`
+	ToRegion          string             `json:"-"`
+	SnapshotSet       string             `json:"-"`
+	PauseWrites       bool               `json:"-"`
+	KeepSnapshots     int                `json:"-"`
+	OlderThan         time.Duration      `json:"-"`
+	Databases         []string           `json:"-"`
+	Database          string             `json:"-"`
+	BackupID          string             `json:"-"`
+	BackupDest        string             `json:"-"`
+	FromDeployment    string             `json:"-"`
+	Overwrite         bool               `json:"-"`
+	DataFiles         []string           `json:"-"`
+	OptionsFile       string             `json:"-"`
+	DbOptions         []string           `json:"-"`
+	OptionNames       []string           `json:"-"`
+	Graph             string             `json:"-"`
+	QueryFile         string             `json:"-"`
+	QueryString       string             `json:"-"`
+	QueryFormat       string             `json:"-"`
+	QueryTimeout      time.Duration      `json:"-"`
+	Reasoning         bool               `json:"-"`
+	DefaultGraphs     []string           `json:"-"`
+	NamedGraphs       []string           `json:"-"`
+	ChunkSize         int                `json:"-"`
+	Parallel          int                `json:"-"`
+	LoadRetries       int                `json:"-"`
+	AdminUser         string             `json:"-"`
+	UserName          string             `json:"-"`
+	RoleName          string             `json:"-"`
+	Roles             []string           `json:"-"`
+	Superuser         bool               `json:"-"`
+	ForceRole         bool               `json:"-"`
+	PermAction        string             `json:"-"`
+	PermResourceType  string             `json:"-"`
+	PermResources     []string           `json:"-"`
+	SecurityFile      string             `json:"-"`
+	NewVolumeSize     int                `json:"-"`
+	NewVolumeType     string             `json:"-"`
+	NewVolumeIops     int                `json:"-"`
+	ZkInstanceType    string             `json:"-"`
 	WaitMaxTimeSec    int                `json:"-"`
 	ConsoleFile       string             `json:"-"`
 	ConsoleWriter     io.Writer          `json:"-"`
 	EnvList           []string           `json:"-"`
+	ctx               context.Context
+	command           string
 	highlight         sdutils.ConsoleEffect
 	red               sdutils.ConsoleEffect
 	green             sdutils.ConsoleEffect
@@ -87,15 +138,48 @@ func main() {
 	os.Exit(rc)
 }
 
+func cloudTypes() []string {
+	names := []string{}
+	for name := range pluginsMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func realMain(args []string) int {
 	pluginsMap = make(map[string]sdutils.Plugin)
-	awsPlugin := aws.GetPlugin()
-	pluginsMap[awsPlugin.GetName()] = awsPlugin
+	for _, p := range graviton.Plugins(defaultConfigDir()) {
+		pluginsMap[p.GetName()] = p
+		if closer, ok := p.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
 
-	app, err := parseParameters(args)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+	go func() {
+		_, ok := <-sigs
+		if !ok {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "\nInterrupted.  Waiting for the running step to stop, press Ctrl-C again to exit now.\n")
+		cancel()
+		if _, ok = <-sigs; ok {
+			os.Exit(130)
+		}
+	}()
+
+	app, err := parseParameters(ctx, args)
 	if consoleFile != nil {
 		consoleFile.Close()
 	}
+	if err != nil && ctx.Err() != nil {
+		return interrupted(app, err)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s %s\n", app.FailString("Failed:"), err.Error())
 		fmt.Fprintf(os.Stderr, "Please check the log files:\n")
@@ -107,10 +191,42 @@ func realMain(args []string) int {
 		}
 		return 1
 	}
-	app.ConsoleLog(1, app.SuccessString("Success.\n"))
+	if app.DeploymentName != "" {
+		depDir := sdutils.DeploymentDir(app.ConfigDir, app.DeploymentName)
+		if step := sdutils.LoadInterruptedStep(depDir); step != nil && step.Command == app.command {
+			sdutils.ClearInterruptedStep(depDir)
+		}
+	}
+	app.ConsoleLog(1, "%s", app.SuccessString("Success.\n"))
 	return 0
 }
 
+// interrupted records the step that was stopped by Ctrl-C in the deployment
+// directory and tells the user how to pick up from there.
+func interrupted(app *CliContext, err error) int {
+	step := &sdutils.InterruptedStep{Command: app.command, Time: time.Now()}
+	if ie, ok := err.(*sdutils.InterruptedError); ok {
+		step.Program = ie.Program
+	}
+	if app.Logger != nil {
+		app.Logf(sdutils.WARN, "The command %s was interrupted: %s", step.Command, err)
+	}
+	fmt.Fprintf(os.Stderr, "%s The command '%s' was interrupted.\n", app.FailString("Stopped:"), step.Command)
+	if step.Program != "" {
+		fmt.Fprintf(os.Stderr, "%s was stopped before it finished.\n", step.Program)
+	}
+	if app.DeploymentName == "" {
+		return 130
+	}
+	depDir := sdutils.DeploymentDir(app.ConfigDir, app.DeploymentName)
+	if sdutils.RecordInterruptedStep(depDir, step) == nil {
+		fmt.Fprintf(os.Stderr, "The deployment %s may be partially changed.  Check it with:\n", app.DeploymentName)
+		fmt.Fprintf(os.Stderr, "\t%s status %s\n", os.Args[0], app.DeploymentName)
+		fmt.Fprintf(os.Stderr, "and run the command again or destroy the deployment.\n")
+	}
+	return 130
+}
+
 func (cliContext *CliContext) sshIn(c *kingpin.ParseContext) error {
 	baseD := sdutils.BaseDeployment{
 		Name:            cliContext.DeploymentName,
@@ -120,11 +236,11 @@ func (cliContext *CliContext) sshIn(c *kingpin.ParseContext) error {
 		PrivateKey:      cliContext.PrivateKeyPath,
 		CustomPropsFile: cliContext.CustomSdProps,
 	}
-	d, err := sdutils.LoadDeployment(cliContext, &baseD, false)
+	d, err := sdutils.LoadDeployment(cliContext.ctx, cliContext, &baseD, false)
 	if err != nil {
 		return err
 	}
-	return sdutils.RunSSH(cliContext, &baseD, d)
+	return sdutils.RunSSH(cliContext.ctx, cliContext, &baseD, d)
 }
 
 func (cliContext *CliContext) aboutCommand(c *kingpin.ParseContext) error {
@@ -202,281 +318,910 @@ func (cliContext *CliContext) interactive(c *kingpin.ParseContext) error {
 	if cliContext.Force {
 		cliContext.Interactive = false
 	}
+	if cliContext.Rollback {
+		return cliContext.rollbackLaunch()
+	}
 
 	err = envNormalize(cliContext)
 	if err != nil {
 		return err
 	}
-
-	plugin, err := sdutils.GetPlugin(cliContext.CloudType)
+
+	plugin, err := sdutils.GetPlugin(cliContext.CloudType)
+	if err != nil {
+		return err
+	}
+
+	err = sdutils.AskUserInteractiveString("What version of stardog are you launching?", cliContext.Version, !cliContext.Interactive, &cliContext.Version)
+	if err != nil {
+		return err
+	}
+
+	caps := plugin.Capabilities()
+	if caps.Images && !plugin.HaveImage(cliContext.ctx, cliContext) {
+		err = sdutils.AskUserInteractiveString("What is the path to the Stardog release?", cliContext.SdReleaseFilePath, !cliContext.Interactive, &cliContext.SdReleaseFilePath)
+		if err != nil {
+			return err
+		}
+		cliContext.ConsoleLog(0, "There is no base image for version %s.\n", cliContext.Version)
+		if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you wish to build one?") {
+			return fmt.Errorf("A base image is needed in order to launch a stardog-graviton cluster")
+		}
+	}
+	err = sdutils.AskUserInteractiveString("What would you like to name this deployment?", cliContext.DeploymentName, !cliContext.Interactive, &cliContext.DeploymentName)
+	if err != nil {
+		return err
+	}
+
+	if caps.Firewall {
+		err = sdutils.AskUserInteractiveString("What CIDR will be allowed to access stardog?", cliContext.HTTPMask, !cliContext.Interactive, &cliContext.HTTPMask)
+		if err != nil {
+			return err
+		}
+	}
+	needVolumes := caps.Volumes
+	if dep, err := loadDepWrapper(cliContext, false); err == nil && dep.VolumeExists() {
+		needVolumes = false
+	}
+	if needVolumes {
+		err = sdutils.AskUserInteractiveString("What is the path to your Stardog license?", cliContext.LicensePath, !cliContext.Interactive, &cliContext.LicensePath)
+		if err != nil {
+			return err
+		}
+		err = sdutils.AskUserInteractiveInt("How big should each disk be in gigabytes?", cliContext.VolumeSize, !cliContext.Interactive, &cliContext.VolumeSize)
+		if err != nil {
+			return err
+		}
+		err = sdutils.AskUserInteractiveInt("How many Stardog nodes will be in the cluster?", cliContext.ClusterSize, !cliContext.Interactive, &cliContext.ClusterSize)
+		if err != nil {
+			return err
+		}
+	}
+	err = sdutils.AskUserInteractiveInt("How many Zookeeper nodes will be used?", cliContext.ZkClusterSize, !cliContext.Interactive, &cliContext.ZkClusterSize)
+	if err != nil {
+		return err
+	}
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	sd, err := client.Launch(cliContext.ctx)
+	if sd == nil {
+		depDir := sdutils.DeploymentDir(cliContext.ConfigDir, cliContext.DeploymentName)
+		if !cliContext.Resume && sdutils.LoadJournal(cliContext, depDir) != nil {
+			cliContext.ConsoleLog(0, "The launch can be continued with --resume or undone with --rollback.\n")
+		}
+		return err
+	}
+	perr := sdutils.PrintStatus(cliContext, sd, cliContext.OutputFile)
+	if err != nil {
+		return err
+	}
+	return perr
+}
+
+func (cliContext *CliContext) rollbackLaunch() error {
+	if cliContext.Resume {
+		return fmt.Errorf("A launch cannot be resumed and rolled back at once")
+	}
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(0, "This will undo the unfinished launch of %s.\n", cliContext.DeploymentName)
+	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to roll back?") {
+		return nil
+	}
+	return client.Rollback(cliContext.ctx)
+}
+
+// newClient returns a graviton Client for the deployment named on the
+// command line.  Plugins may still prompt on the console.
+func (cliContext *CliContext) newClient() (*graviton.Client, error) {
+	return graviton.NewClient(graviton.Config{
+		AppContext:      cliContext,
+		Prompter:        sdutils.ConsolePrompt,
+		ConfigDir:       cliContext.ConfigDir,
+		DeploymentName:  cliContext.DeploymentName,
+		CloudType:       cliContext.CloudType,
+		Version:         cliContext.Version,
+		ReleaseFile:     cliContext.SdReleaseFilePath,
+		LicensePath:     cliContext.LicensePath,
+		PrivateKey:      cliContext.PrivateKeyPath,
+		CustomPropsFile: cliContext.CustomSdProps,
+		Environment:     cliContext.EnvList,
+		DisableSecurity: cliContext.DisableSecurity,
+		VolumeSize:      cliContext.VolumeSize,
+		ClusterSize:     cliContext.ClusterSize,
+		ZookeeperSize:   cliContext.ZkClusterSize,
+		RootVolumeSize:  cliContext.RootVolumeSize,
+		HTTPMask:        cliContext.HTTPMask,
+		IdleTimeout:     cliContext.ConnectionTimeout,
+		WaitTimeout:     cliContext.WaitMaxTimeSec,
+		NoWait:          cliContext.NoWaitForHealthy,
+		InternalHealth:  cliContext.InternalHealth,
+		Resume:          cliContext.Resume,
+		AdminUser:       cliContext.AdminUser,
+	})
+}
+
+func (cliContext *CliContext) baseAmiAction(c *kingpin.ParseContext) error {
+	p, err := sdutils.GetPlugin(cliContext.CloudType)
+	if err != nil {
+		return err
+	}
+	if !p.Capabilities().Images {
+		return fmt.Errorf("The cloud type %s does not use base images", p.GetName())
+	}
+	err = p.BuildImage(cliContext.ctx, cliContext, cliContext.SdReleaseFilePath, cliContext.Version)
+	if err != nil {
+		cliContext.ConsoleLog(0, "Failed to make the stardog base image: %s\n", err.Error())
+		return err
+	}
+	cliContext.ConsoleLog(1, "The image was successfully created.\n")
+	return nil
+}
+
+func (cliContext *CliContext) imageCataloger() (sdutils.ImageCataloger, error) {
+	p, err := sdutils.GetPlugin(cliContext.CloudType)
+	if err != nil {
+		return nil, err
+	}
+	ic, ok := p.(sdutils.ImageCataloger)
+	if !p.Capabilities().Images || !ok {
+		return nil, fmt.Errorf("The cloud type %s does not keep an image catalog", p.GetName())
+	}
+	return ic, nil
+}
+
+func (cliContext *CliContext) listImages(c *kingpin.ParseContext) error {
+	ic, err := cliContext.imageCataloger()
+	if err != nil {
+		return err
+	}
+	images, err := ic.ListImages(cliContext.ctx, cliContext)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(0, "%-22s %-10s %-15s %-8s %s\n", "ID", "VERSION", "REGION", "ARCH", "BUILT")
+	for _, img := range images {
+		built := "unknown"
+		if !img.Built.IsZero() {
+			built = img.Built.Format(time.RFC1123)
+		}
+		cliContext.ConsoleLog(0, "%-22s %-10s %-15s %-8s %s\n", img.ID, img.Version, img.Region, img.Architecture, built)
+	}
+	return nil
+}
+
+func (cliContext *CliContext) showImage(c *kingpin.ParseContext) error {
+	ic, err := cliContext.imageCataloger()
+	if err != nil {
+		return err
+	}
+	images, err := ic.ListImages(cliContext.ctx, cliContext)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if img.ID != cliContext.ImageID {
+			continue
+		}
+		cliContext.ConsoleLog(0, "Image:            %s\n", img.ID)
+		cliContext.ConsoleLog(0, "Stardog version:  %s\n", img.Version)
+		cliContext.ConsoleLog(0, "Region:           %s\n", img.Region)
+		cliContext.ConsoleLog(0, "Architecture:     %s\n", img.Architecture)
+		if img.BaseImage != "" {
+			cliContext.ConsoleLog(0, "Base image:       %s\n", img.BaseImage)
+		}
+		if img.SourceImage != "" {
+			cliContext.ConsoleLog(0, "Copied from:      %s\n", img.SourceImage)
+		}
+		if !img.Built.IsZero() {
+			cliContext.ConsoleLog(0, "Built:            %s\n", img.Built.Format(time.RFC1123))
+		}
+		if img.ReleaseChecksum != "" {
+			cliContext.ConsoleLog(0, "Release SHA-256:  %s\n", img.ReleaseChecksum)
+		}
+		if img.BuildLog != "" {
+			cliContext.ConsoleLog(0, "Build log:        %s\n", img.BuildLog)
+		}
+		return nil
+	}
+	return fmt.Errorf("The image %s is not in the catalog", cliContext.ImageID)
+}
+
+func (cliContext *CliContext) deleteImage(c *kingpin.ParseContext) error {
+	ic, err := cliContext.imageCataloger()
+	if err != nil {
+		return err
+	}
+	if !cliContext.Force && !sdutils.AskUserYesOrNo(fmt.Sprintf("Do you really want to delete the image %s and its snapshots?", cliContext.ImageID)) {
+		return nil
+	}
+	err = ic.DeleteImage(cliContext.ctx, cliContext, cliContext.ImageID)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(1, "The image %s was deleted.\n", cliContext.ImageID)
+	return nil
+}
+
+func (cliContext *CliContext) copyImage(c *kingpin.ParseContext) error {
+	ic, err := cliContext.imageCataloger()
+	if err != nil {
+		return err
+	}
+	img, err := ic.CopyImage(cliContext.ctx, cliContext, cliContext.ImageID, cliContext.ToRegion)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(1, "The image %s was copied to %s as %s.\n", cliContext.ImageID, img.Region, img.ID)
+	return nil
+}
+
+func (cliContext *CliContext) leaks(c *kingpin.ParseContext) error {
+	p, err := sdutils.GetPlugin(cliContext.CloudType)
+	if err != nil {
+		return err
+	}
+	return p.FindLeaks(cliContext.ctx, cliContext, cliContext.DeploymentName, cliContext.Destroy, cliContext.Force)
+}
+
+func (cliContext *CliContext) newDeployment(c *kingpin.ParseContext) error {
+	err := envNormalize(cliContext)
+	if err != nil {
+		return err
+	}
+
+	// In the future we will check instance types
+	baseD := sdutils.BaseDeployment{
+		Name:            cliContext.DeploymentName,
+		Version:         cliContext.Version,
+		Type:            strings.ToLower(cliContext.CloudType),
+		Directory:       sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName),
+		PrivateKey:      cliContext.PrivateKeyPath,
+		CustomPropsFile: cliContext.CustomSdProps,
+		Environment:     cliContext.EnvList,
+		DisableSecurity: cliContext.DisableSecurity,
+	}
+	_, err = sdutils.LoadDeployment(cliContext.ctx, cliContext, &baseD, true)
+	return err
+}
+
+func (cliContext *CliContext) deploymentList(c *kingpin.ParseContext) error {
+	depDir := sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName)
+	files, _ := ioutil.ReadDir(depDir)
+	for _, f := range files {
+		if f.IsDir() {
+			cliContext.ConsoleLog(0, "%s\n", f.Name())
+		}
+	}
+	return nil
+}
+
+func (cliContext *CliContext) destroyDeployment(c *kingpin.ParseContext) error {
+	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to destroy?") {
+		return nil
+	}
+	d, caps, err := loadDepCapabilities(cliContext)
+	if err != nil {
+		return err
+	}
+	if d.InstanceExists() {
+		return fmt.Errorf("An instance exist in this deployment.  Please destroy it before removing the deployment")
+	}
+	if d.VolumeExists() {
+		return fmt.Errorf("Volumes exist in this deployment.  Please destroy them before removing the deployment")
+	}
+	cliContext.Force = true
+	err = cliContext.destroyInstance(c)
+	if err != nil {
+		cliContext.ConsoleLog(1, "The instance was not destroyed %s\n", err)
+	}
+	if caps.Volumes {
+		err = cliContext.destroyVolumes(c)
+		if err != nil {
+			cliContext.ConsoleLog(1, "The volumes were not destroyed %s\n", err)
+		}
+	}
+	err = d.DestroyDeployment(cliContext.ctx)
+	if err != nil {
+		cliContext.ConsoleLog(1, "Failed to destroy the deployment %s\n", err)
+		return err
+	}
+	sdutils.DeleteDeployment(cliContext, cliContext.DeploymentName)
+	return nil
+}
+
+func (cliContext *CliContext) scaleDeployment(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.Scale(cliContext.ctx, cliContext.ClusterSize)
+}
+
+func (cliContext *CliContext) upgradeDeployment(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.Upgrade(cliContext.ctx, cliContext.Version, cliContext.SdReleaseFilePath)
+}
+
+func (cliContext *CliContext) gatherLogs(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.GatherLogs(cliContext.ctx, cliContext.OutputFile)
+}
+
+func (cliContext *CliContext) fullStatus(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	depDir := sdutils.DeploymentDir(cliContext.ConfigDir, cliContext.DeploymentName)
+	if step := sdutils.LoadInterruptedStep(depDir); step != nil {
+		cliContext.ConsoleLog(0, "%s The command '%s' was interrupted at %s.\n", cliContext.FailString("Warning:"), step.Command, step.Time.Format(time.RFC1123))
+	}
+	cliContext.ConsoleLog(2, "Checking status...\n")
+	sd, err := client.Status(cliContext.ctx)
+	if sd == nil {
+		return err
+	}
+	perr := sdutils.PrintStatus(cliContext, sd, cliContext.OutputFile)
+	if err != nil {
+		return err
+	}
+	return perr
+}
+
+func (cliContext *CliContext) destroyFullDeployment(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.Deployment(cliContext.ctx)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(0, "This will destroy all volumes and instances associated with this deployment.\n")
+	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to destroy?") {
+		return nil
+	}
+	return client.Destroy(cliContext.ctx)
+}
+
+func (cliContext *CliContext) newVolumes(c *kingpin.ParseContext) error {
+	d, err := loadVolumeDeployment(cliContext)
+	if err != nil {
+		return err
+	}
+	return d.CreateVolumeSet(cliContext.ctx, cliContext.LicensePath, cliContext.VolumeSize, cliContext.ClusterSize)
+}
+
+func (cliContext *CliContext) destroyVolumes(c *kingpin.ParseContext) error {
+	d, err := loadVolumeDeployment(cliContext)
+	if err != nil {
+		return err
+	}
+	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to destroy?") {
+		return nil
+	}
+	return d.DeleteVolumeSet(cliContext.ctx)
+}
+
+func (cliContext *CliContext) statusVolumes(c *kingpin.ParseContext) error {
+	d, err := loadVolumeDeployment(cliContext)
+	if err != nil {
+		return err
+	}
+	return d.StatusVolumeSet(cliContext.ctx)
+}
+
+func (cliContext *CliContext) snapshotVolumes(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	set, err := client.Snapshot(cliContext.ctx, cliContext.PauseWrites)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(1, "The snapshot set %s has %d snapshots.\n", set.ID, len(set.SnapshotIDs))
+	return nil
+}
+
+func (cliContext *CliContext) modifyVolumes(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.ModifyVolumes(cliContext.ctx, cliContext.NewVolumeSize, cliContext.NewVolumeType, cliContext.NewVolumeIops)
+}
+
+func (cliContext *CliContext) listSnapshots(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	sets, err := client.Snapshots(cliContext.ctx)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(0, "%-36s %-10s %-8s %-10s %s\n", "ID", "VERSION", "VOLUMES", "STATE", "CREATED")
+	for _, set := range sets {
+		state := "pending"
+		if set.Complete {
+			state = "complete"
+		}
+		cliContext.ConsoleLog(0, "%-36s %-10s %-8d %-10s %s\n", set.ID, set.Version, len(set.SnapshotIDs), state, set.Created.Format(time.RFC1123))
+	}
+	return nil
+}
+
+func (cliContext *CliContext) pruneSnapshots(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	sets, err := client.SelectSnapshotsToPrune(cliContext.ctx, cliContext.KeepSnapshots, cliContext.OlderThan)
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		cliContext.ConsoleLog(1, "There are no snapshot sets to prune.\n")
+		return nil
+	}
+	ids := []string{}
+	for _, set := range sets {
+		cliContext.ConsoleLog(1, "%s\n", set.ID)
+		ids = append(ids, set.ID)
+	}
+	if !cliContext.Force && !sdutils.AskUserYesOrNo(fmt.Sprintf("Do you really want to delete these %d snapshot sets?", len(ids))) {
+		return nil
+	}
+	return client.DeleteSnapshots(cliContext.ctx, ids)
+}
+
+func (cliContext *CliContext) restoreVolumes(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.Restore(cliContext.ctx, cliContext.SnapshotSet)
+}
+
+func (cliContext *CliContext) launchInstance(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.CreateInstance(cliContext.ctx)
+}
+
+func (cliContext *CliContext) pauseDeployment(c *kingpin.ParseContext) error {
+	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to destroy the instance?") {
+		return nil
+	}
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.Pause(cliContext.ctx)
+}
+
+func (cliContext *CliContext) resumeDeployment(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.Resume(cliContext.ctx)
+}
+
+func (cliContext *CliContext) newBackup(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.Backup(cliContext.ctx, cliContext.Databases, cliContext.BackupDest)
+	return err
+}
+
+func (cliContext *CliContext) listBackups(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	backups, err := client.Backups(cliContext.ctx)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(0, "%-36s %-20s %-10s %-12s %s\n", "ID", "DATABASE", "VERSION", "SIZE", "LOCATION")
+	for _, b := range backups {
+		cliContext.ConsoleLog(0, "%-36s %-20s %-10s %-12d %s\n", b.ID, b.Database, b.Version, b.Size, b.Location)
+	}
+	return nil
+}
+
+func (cliContext *CliContext) restoreBackup(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.RestoreBackup(cliContext.ctx, cliContext.Database, cliContext.BackupID, cliContext.FromDeployment, cliContext.Overwrite)
+}
+
+func (cliContext *CliContext) listDatabases(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	dbs, err := client.Databases(cliContext.ctx)
+	if err != nil {
+		return err
+	}
+	for _, db := range dbs {
+		cliContext.ConsoleLog(0, "%s\n", db)
+	}
+	return nil
+}
+
+func (cliContext *CliContext) createDatabase(c *kingpin.ParseContext) error {
+	options := make(map[string]interface{})
+	if cliContext.OptionsFile != "" {
+		fileOptions, err := graviton.LoadDatabaseOptions(cliContext.OptionsFile)
+		if err != nil {
+			return err
+		}
+		options = fileOptions
+	}
+	flagOptions, err := graviton.ParseDatabaseOptions(cliContext.DbOptions)
+	if err != nil {
+		return err
+	}
+	for n, v := range flagOptions {
+		options[n] = v
+	}
+	files := []sdutils.DatabaseFile{}
+	for _, f := range cliContext.DataFiles {
+		files = append(files, sdutils.DatabaseFile{Path: f, Graph: cliContext.Graph})
+	}
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.CreateDatabase(cliContext.ctx, cliContext.Database, options, files)
+}
+
+func (cliContext *CliContext) dropDatabase(c *kingpin.ParseContext) error {
+	if !cliContext.Force && !sdutils.AskUserYesOrNo(fmt.Sprintf("Do you really want to delete the database %s and all of its data?", cliContext.Database)) {
+		return nil
+	}
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	err = client.DropDatabase(cliContext.ctx, cliContext.Database)
 	if err != nil {
 		return err
 	}
+	cliContext.ConsoleLog(1, "Deleted the database %s.\n", cliContext.Database)
+	return nil
+}
 
-	err = sdutils.AskUserInteractiveString("What version of stardog are you launching?", cliContext.Version, !cliContext.Interactive, &cliContext.Version)
+func (cliContext *CliContext) databaseOptions(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-
-	if !plugin.HaveImage(cliContext) {
-		err = sdutils.AskUserInteractiveString("What is the path to the Stardog release?", cliContext.SdReleaseFilePath, !cliContext.Interactive, &cliContext.SdReleaseFilePath)
+	if len(cliContext.DbOptions) > 0 {
+		options, err := graviton.ParseDatabaseOptions(cliContext.DbOptions)
 		if err != nil {
 			return err
 		}
-		cliContext.ConsoleLog(0, "There is no base image for version %s.\n", cliContext.Version)
-		if cliContext.Force || sdutils.AskUserYesOrNo("Do you wish to build one?") {
-			err = plugin.BuildImage(cliContext, cliContext.SdReleaseFilePath, cliContext.Version)
-			if err != nil {
-				cliContext.ConsoleLog(0, "Failed to make the stardog base image: %s\n", err.Error())
-				return err
-			}
-		} else {
-			return fmt.Errorf("A base image is needed in order to launch a stardog-graviton cluster")
+		err = client.SetDatabaseOptions(cliContext.ctx, cliContext.Database, options)
+		if err != nil {
+			return err
+		}
+		for n := range options {
+			cliContext.OptionNames = append(cliContext.OptionNames, n)
 		}
 	}
-	err = sdutils.AskUserInteractiveString("What would you like to name this deployment?", cliContext.DeploymentName, !cliContext.Interactive, &cliContext.DeploymentName)
+	options, err := client.DatabaseOptions(cliContext.ctx, cliContext.Database, cliContext.OptionNames)
 	if err != nil {
 		return err
 	}
+	cliContext.ConsoleLog(0, "%s", graviton.FormatDatabaseOptions(options))
+	return nil
+}
 
-	err = sdutils.AskUserInteractiveString("What CIDR will be allowed to access stardog?", cliContext.HTTPMask, !cliContext.Interactive, &cliContext.HTTPMask)
+func (cliContext *CliContext) onlineDatabase(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	baseD := sdutils.BaseDeployment{
-		Name:            cliContext.DeploymentName,
-		Version:         cliContext.Version,
-		Type:            strings.ToLower(cliContext.CloudType),
-		Directory:       sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName),
-		PrivateKey:      cliContext.PrivateKeyPath,
-		CustomPropsFile: cliContext.CustomSdProps,
-		Environment:     cliContext.EnvList,
-		DisableSecurity: cliContext.DisableSecurity,
+	return client.SetDatabaseOnline(cliContext.ctx, cliContext.Database, true)
+}
+
+func (cliContext *CliContext) offlineDatabase(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
 	}
-	dep, err := sdutils.LoadDeployment(cliContext, &baseD, false)
+	return client.SetDatabaseOnline(cliContext.ctx, cliContext.Database, false)
+}
+
+func (cliContext *CliContext) listUsers(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
 	if err != nil {
-		cliContext.ConsoleLog(1, "Creating the new deployment %s\n", cliContext.DeploymentName)
-		dep, err = sdutils.LoadDeployment(cliContext, &baseD, true)
-		if err != nil {
-			return err
-		}
+		return err
 	}
-	if !dep.VolumeExists() {
-		err = sdutils.AskUserInteractiveString("What is the path to your Stardog license?", cliContext.LicensePath, !cliContext.Interactive, &cliContext.LicensePath)
-		if err != nil {
-			return err
-		}
-		err = sdutils.AskUserInteractiveInt("How big should each disk be in gigabytes?", cliContext.VolumeSize, !cliContext.Interactive, &cliContext.VolumeSize)
-		if err != nil {
-			return err
-		}
-		err = sdutils.AskUserInteractiveInt("How many Stardog nodes will be in the cluster?", cliContext.ClusterSize, !cliContext.Interactive, &cliContext.ClusterSize)
-		if err != nil {
-			return err
-		}
-		err = dep.CreateVolumeSet(cliContext.LicensePath, cliContext.VolumeSize, cliContext.ClusterSize)
-		if err != nil {
-			return err
-		}
+	users, err := client.Users(cliContext.ctx)
+	if err != nil {
+		return err
 	}
-	err = sdutils.AskUserInteractiveInt("How many Zookeeper nodes will be used?", cliContext.ZkClusterSize, !cliContext.Interactive, &cliContext.ZkClusterSize)
+	for _, u := range users {
+		cliContext.ConsoleLog(0, "%s %v\n", u.Name, u.Roles)
+	}
+	return nil
+}
+
+func (cliContext *CliContext) addUser(c *kingpin.ParseContext) error {
+	password, err := sdutils.ReadSecret(fmt.Sprintf("Password for %s", cliContext.UserName), true)
 	if err != nil {
 		return err
 	}
-	err = sdutils.CreateInstance(cliContext, &baseD, dep, cliContext.RootVolumeSize, cliContext.ZkClusterSize, cliContext.WaitMaxTimeSec, cliContext.ConnectionTimeout, cliContext.HTTPMask, cliContext.NoWaitForHealthy)
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	return sdutils.FullStatus(cliContext, &baseD, dep, false, cliContext.OutputFile)
+	err = client.AddUser(cliContext.ctx, cliContext.UserName, password, cliContext.Superuser, cliContext.Roles)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(1, "Added the user %s.\n", cliContext.UserName)
+	return nil
 }
 
-func (cliContext *CliContext) baseAmiAction(c *kingpin.ParseContext) error {
-	p, err := sdutils.GetPlugin(cliContext.CloudType)
+func (cliContext *CliContext) removeUser(c *kingpin.ParseContext) error {
+	if !cliContext.Force && !sdutils.AskUserYesOrNo(fmt.Sprintf("Do you really want to delete the user %s?", cliContext.UserName)) {
+		return nil
+	}
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	err = p.BuildImage(cliContext, cliContext.SdReleaseFilePath, cliContext.Version)
+	err = client.RemoveUser(cliContext.ctx, cliContext.UserName)
 	if err != nil {
-		cliContext.ConsoleLog(0, "Failed to make the stardog base image: %s\n", err.Error())
 		return err
 	}
-	cliContext.ConsoleLog(1, "The image was successfully created.\n")
+	cliContext.ConsoleLog(1, "Deleted the user %s.\n", cliContext.UserName)
 	return nil
 }
 
-func (cliContext *CliContext) leaks(c *kingpin.ParseContext) error {
-	p, err := sdutils.GetPlugin(cliContext.CloudType)
+func (cliContext *CliContext) passwd(c *kingpin.ParseContext) error {
+	password, err := sdutils.ReadSecret(fmt.Sprintf("New password for %s", cliContext.UserName), true)
 	if err != nil {
 		return err
 	}
-	return p.FindLeaks(cliContext, cliContext.DeploymentName, cliContext.Destroy, cliContext.Force)
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	err = client.ChangePassword(cliContext.ctx, cliContext.UserName, password)
+	if err != nil {
+		return err
+	}
+	cliContext.ConsoleLog(1, "Changed the password of %s.\n", cliContext.UserName)
+	if cliContext.UserName == "admin" {
+		cliContext.ConsoleLog(1, "Set STARDOG_ADMIN_PASSWORD to the new password to keep managing the deployment.\n")
+	}
+	return nil
 }
 
-func (cliContext *CliContext) newDeployment(c *kingpin.ParseContext) error {
-	err := envNormalize(cliContext)
+func (cliContext *CliContext) listRoles(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	roles, err := client.Roles(cliContext.ctx)
 	if err != nil {
 		return err
 	}
+	for _, r := range roles {
+		cliContext.ConsoleLog(0, "%s\n", r.Name)
+	}
+	return nil
+}
 
-	// In the future we will check instance types
-	baseD := sdutils.BaseDeployment{
-		Name:            cliContext.DeploymentName,
-		Version:         cliContext.Version,
-		Type:            strings.ToLower(cliContext.CloudType),
-		Directory:       sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName),
-		PrivateKey:      cliContext.PrivateKeyPath,
-		CustomPropsFile: cliContext.CustomSdProps,
-		Environment:     cliContext.EnvList,
-		DisableSecurity: cliContext.DisableSecurity,
+func (cliContext *CliContext) addRole(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
 	}
-	_, err = sdutils.LoadDeployment(cliContext, &baseD, true)
-	return err
+	return client.AddRole(cliContext.ctx, cliContext.RoleName)
 }
 
-func (cliContext *CliContext) deploymentList(c *kingpin.ParseContext) error {
-	depDir := sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName)
-	files, _ := ioutil.ReadDir(depDir)
-	for _, f := range files {
-		if f.IsDir() {
-			cliContext.ConsoleLog(0, "%s\n", f.Name())
-		}
+func (cliContext *CliContext) removeRole(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
 	}
-	return nil
+	return client.RemoveRole(cliContext.ctx, cliContext.RoleName, cliContext.ForceRole)
 }
 
-func (cliContext *CliContext) destroyDeployment(c *kingpin.ParseContext) error {
-	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to destroy?") {
-		return nil
+func (cliContext *CliContext) grantRole(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
 	}
-	d, err := loadDepWrapper(cliContext, false)
+	return client.GrantRole(cliContext.ctx, cliContext.RoleName, cliContext.UserName)
+}
+
+func (cliContext *CliContext) revokeRole(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	if d.InstanceExists() {
-		return fmt.Errorf("An instance exist in this deployment.  Please destroy it before removing the deployment")
+	return client.RevokeRole(cliContext.ctx, cliContext.RoleName, cliContext.UserName)
+}
+
+func (cliContext *CliContext) principal() (string, string, error) {
+	if (cliContext.UserName == "") == (cliContext.RoleName == "") {
+		return "", "", fmt.Errorf("Exactly one of --user and --role is required")
 	}
-	if d.VolumeExists() {
-		return fmt.Errorf("Volumes exist in this deployment.  Please destroy them before removing the deployment")
+	if cliContext.UserName != "" {
+		return graviton.PrincipalUser, cliContext.UserName, nil
 	}
-	cliContext.Force = true
-	err = cliContext.destroyInstance(c)
+	return graviton.PrincipalRole, cliContext.RoleName, nil
+}
+
+func (cliContext *CliContext) permission() sdutils.StardogPermission {
+	return sdutils.StardogPermission{
+		Action:       cliContext.PermAction,
+		ResourceType: cliContext.PermResourceType,
+		Resource:     cliContext.PermResources,
+	}
+}
+
+func (cliContext *CliContext) listPermissions(c *kingpin.ParseContext) error {
+	kind, name, err := cliContext.principal()
 	if err != nil {
-		cliContext.ConsoleLog(1, "The instance was not destroyed %s\n", err)
+		return err
 	}
-	err = cliContext.destroyVolumes(c)
+	client, err := cliContext.newClient()
 	if err != nil {
-		cliContext.ConsoleLog(1, "The volumes were not destroyed %s\n", err)
+		return err
 	}
-	err = d.DestroyDeployment()
+	perms, err := client.Permissions(cliContext.ctx, kind, name)
 	if err != nil {
-		cliContext.ConsoleLog(1, "Failed to destroy the deployment %s\n", err)
 		return err
 	}
-	sdutils.DeleteDeployment(cliContext, cliContext.DeploymentName)
+	for _, p := range perms {
+		cliContext.ConsoleLog(0, "%s %s %s\n", p.Action, p.ResourceType, strings.Join(p.Resource, " "))
+	}
 	return nil
 }
 
-func (cliContext *CliContext) gatherLogs(c *kingpin.ParseContext) error {
-	baseD := sdutils.BaseDeployment{
-		Name:            cliContext.DeploymentName,
-		Version:         cliContext.Version,
-		Type:            strings.ToLower(cliContext.CloudType),
-		Directory:       sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName),
-		PrivateKey:      cliContext.PrivateKeyPath,
-		CustomPropsFile: cliContext.CustomSdProps,
+func (cliContext *CliContext) grantPermission(c *kingpin.ParseContext) error {
+	kind, name, err := cliContext.principal()
+	if err != nil {
+		return err
 	}
-	d, err := sdutils.LoadDeployment(cliContext, &baseD, false)
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	return sdutils.GatherLogs(cliContext, &baseD, d, cliContext.OutputFile)
+	return client.GrantPermission(cliContext.ctx, kind, name, cliContext.permission())
 }
 
-func (cliContext *CliContext) fullStatus(c *kingpin.ParseContext) error {
-	baseD := sdutils.BaseDeployment{
-		Name:            cliContext.DeploymentName,
-		Version:         cliContext.Version,
-		Type:            strings.ToLower(cliContext.CloudType),
-		Directory:       sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName),
-		PrivateKey:      cliContext.PrivateKeyPath,
-		CustomPropsFile: cliContext.CustomSdProps,
+func (cliContext *CliContext) revokePermission(c *kingpin.ParseContext) error {
+	kind, name, err := cliContext.principal()
+	if err != nil {
+		return err
 	}
-	d, err := sdutils.LoadDeployment(cliContext, &baseD, false)
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	return sdutils.FullStatus(cliContext, &baseD, d, cliContext.InternalHealth, cliContext.OutputFile)
+	return client.RevokePermission(cliContext.ctx, kind, name, cliContext.permission())
 }
 
-func (cliContext *CliContext) destroyFullDeployment(c *kingpin.ParseContext) error {
-	d, err := loadDepWrapper(cliContext, false)
+func (cliContext *CliContext) exportPermissions(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	cliContext.ConsoleLog(0, "This will destroy all volumes and instances associated with this deployment.\n")
-	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to destroy?") {
+	doc, err := client.ExportSecurity(cliContext.ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if cliContext.SecurityFile == "" {
+		cliContext.ConsoleLog(0, "%s", data)
 		return nil
 	}
-	cliContext.Force = true
-	err = cliContext.destroyInstance(c)
+	return ioutil.WriteFile(cliContext.SecurityFile, data, 0600)
+}
+
+func (cliContext *CliContext) importPermissions(c *kingpin.ParseContext) error {
+	data, err := ioutil.ReadFile(cliContext.SecurityFile)
 	if err != nil {
-		cliContext.ConsoleLog(1, "The instance was not destroyed.  %s\n", err)
+		return err
 	}
-	err = cliContext.destroyVolumes(c)
+	doc := &graviton.SecurityDocument{}
+	err = json.Unmarshal(data, doc)
 	if err != nil {
-		cliContext.ConsoleLog(1, "The volumes were not destroyed.  %s\n", err)
+		return fmt.Errorf("The file %s is not a permission document: %s", cliContext.SecurityFile, err)
 	}
-	err = d.DestroyDeployment()
+	client, err := cliContext.newClient()
 	if err != nil {
-		cliContext.ConsoleLog(1, "Failed to destroy the deployment %s\n", err)
 		return err
 	}
-	sdutils.DeleteDeployment(cliContext, cliContext.DeploymentName)
-	return nil
-}
-
-func (cliContext *CliContext) newVolumes(c *kingpin.ParseContext) error {
-	d, err := loadDepWrapper(cliContext, false)
+	skipped, err := client.ImportSecurity(cliContext.ctx, doc)
 	if err != nil {
 		return err
 	}
-	return d.CreateVolumeSet(cliContext.LicensePath, cliContext.VolumeSize, cliContext.ClusterSize)
+	for _, u := range skipped {
+		cliContext.ConsoleLog(1, "%s\n", cliContext.FailString(fmt.Sprintf("The user %s does not exist.  Add it with user add and import again.", u)))
+	}
+	return nil
 }
 
-func (cliContext *CliContext) destroyVolumes(c *kingpin.ParseContext) error {
-	if !cliContext.Force && !sdutils.AskUserYesOrNo("Do you really want to destroy?") {
-		return nil
+func (cliContext *CliContext) query(c *kingpin.ParseContext) error {
+	q := cliContext.QueryString
+	if cliContext.QueryFile != "" {
+		if q != "" {
+			return fmt.Errorf("Only one of --file and --query can be given")
+		}
+		data, err := ioutil.ReadFile(cliContext.QueryFile)
+		if err != nil {
+			return err
+		}
+		q = string(data)
 	}
-	d, err := loadDepWrapper(cliContext, false)
+	if q == "" {
+		return fmt.Errorf("A query is required, use --file or --query")
+	}
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	return d.DeleteVolumeSet()
+	opts := sdutils.QueryOptions{
+		Reasoning:     cliContext.Reasoning,
+		Timeout:       cliContext.QueryTimeout,
+		DefaultGraphs: cliContext.DefaultGraphs,
+		NamedGraphs:   cliContext.NamedGraphs,
+	}
+	return client.Query(cliContext.ctx, cliContext.Database, q, opts, cliContext.QueryFormat, cliContext.ConsoleWriter)
 }
 
-func (cliContext *CliContext) statusVolumes(c *kingpin.ParseContext) error {
-	d, err := loadDepWrapper(cliContext, false)
+func (cliContext *CliContext) load(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
 	if err != nil {
 		return err
 	}
-	return d.StatusVolumeSet()
-}
-
-func (cliContext *CliContext) launchInstance(c *kingpin.ParseContext) error {
-	baseD := sdutils.BaseDeployment{
-		Name:            cliContext.DeploymentName,
-		Version:         cliContext.Version,
-		Type:            strings.ToLower(cliContext.CloudType),
-		Directory:       sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName),
-		PrivateKey:      cliContext.PrivateKeyPath,
-		CustomPropsFile: cliContext.CustomSdProps,
+	opts := graviton.LoadOptions{
+		ChunkSize: cliContext.ChunkSize,
+		Parallel:  cliContext.Parallel,
+		Retries:   cliContext.LoadRetries,
+		Graph:     cliContext.Graph,
 	}
-	dep, err := sdutils.LoadDeployment(cliContext, &baseD, false)
+	result, err := client.Load(cliContext.ctx, cliContext.Database, cliContext.DataFiles, opts)
 	if err != nil {
 		return err
 	}
-
-	return sdutils.CreateInstance(cliContext, &baseD, dep, cliContext.RootVolumeSize, cliContext.ZkClusterSize, cliContext.WaitMaxTimeSec, cliContext.ConnectionTimeout, cliContext.HTTPMask, cliContext.NoWaitForHealthy)
+	cliContext.ConsoleLog(1, "Loaded %d files in %d transactions.  The database %s grew from %d to %d triples.\n",
+		result.Files, result.Chunks, cliContext.Database, result.SizeBefore, result.SizeAfter)
+	return nil
 }
 
 func (cliContext *CliContext) destroyInstance(c *kingpin.ParseContext) error {
@@ -487,7 +1232,15 @@ func (cliContext *CliContext) destroyInstance(c *kingpin.ParseContext) error {
 	if err != nil {
 		return err
 	}
-	return d.DeleteInstance()
+	return d.DeleteInstance(cliContext.ctx)
+}
+
+func (cliContext *CliContext) resizeInstance(c *kingpin.ParseContext) error {
+	client, err := cliContext.newClient()
+	if err != nil {
+		return err
+	}
+	return client.Resize(cliContext.ctx, cliContext.SdInstanceType, cliContext.ZkInstanceType)
 }
 
 func (cliContext *CliContext) statusInstance(c *kingpin.ParseContext) error {
@@ -495,7 +1248,7 @@ func (cliContext *CliContext) statusInstance(c *kingpin.ParseContext) error {
 	if err != nil {
 		return err
 	}
-	return d.StatusInstance()
+	return d.StatusInstance(cliContext.ctx)
 }
 
 // GetInteractive returns a bool indicating whether or not the user should be bothered
@@ -504,6 +1257,11 @@ func (cliContext *CliContext) GetInteractive() bool {
 	return !cliContext.Force
 }
 
+// Prompt asks the question on the console.
+func (cliContext *CliContext) Prompt(prompt string, defaultValue string) (string, error) {
+	return sdutils.ConsolePrompt(prompt, defaultValue)
+}
+
 // ConsoleLog will write the formatted string to the console if the user is interested
 // in information at the associated level (regaring --versbose --quiet)
 func (cliContext *CliContext) ConsoleLog(level int, format string, v ...interface{}) {
@@ -612,14 +1370,19 @@ func (cliContext *CliContext) topValidate(a *kingpin.Application) error {
 	return nil
 }
 
-func loadDefaultCliOptions() *CliContext {
-	var err error
-
-	usr, _ := user.Current()
+func defaultConfigDir() string {
 	confDir := os.Getenv("STARDOG_VIRTUAL_APPLIANCE_CONFIG_DIR")
 	if confDir == "" {
+		usr, _ := user.Current()
 		confDir = filepath.Join(usr.HomeDir, ".graviton")
 	}
+	return confDir
+}
+
+func loadDefaultCliOptions() *CliContext {
+	var err error
+
+	confDir := defaultConfigDir()
 	// Setup defaults here
 	cliContext := CliContext{
 		DeploymentName:    "",
@@ -670,9 +1433,10 @@ func loadDefaultCliOptions() *CliContext {
 	return &cliContext
 }
 
-func parseParameters(args []string) (*CliContext, error) {
+func parseParameters(ctx context.Context, args []string) (*CliContext, error) {
 	// Setup defaults here
 	cliContext := loadDefaultCliOptions()
+	cliContext.ctx = ctx
 
 	cmdOpts := sdutils.CommandOpts{}
 	cli := kingpin.New("stardog-graviton", "The stardog virtual appliance manager.")
@@ -690,12 +1454,21 @@ func parseParameters(args []string) (*CliContext, error) {
 	cli.Flag("config-dir", "The path for the log file").Default(cliContext.ConfigDir).StringVar(&cliContext.ConfigDir)
 	cli.Flag("verbose", "How much output to send to the console").CounterVar(&cliContext.VerboseLevel)
 	cli.Flag("quiet", "Minimal console output").Default(fmt.Sprintf("%t", cliContext.Quiet)).BoolVar(&cliContext.Quiet)
+	cli.Flag("admin-user", "The Stardog user to manage the deployments as.  Its password is read from STARDOG_ADMIN_PASSWORD.").Default("admin").StringVar(&cliContext.AdminUser)
 	cli.Validate(cliContext.topValidate)
+	cli.PreAction(func(c *kingpin.ParseContext) error {
+		if c.SelectedCommand != nil {
+			cliContext.command = c.SelectedCommand.FullCommand()
+		}
+		return nil
+	})
+
+	typeHelp := fmt.Sprintf("The type of cloud with which graviton will interact (%s).", strings.Join(cloudTypes(), ", "))
 
 	cmdOpts.LaunchCmd = cli.Command("launch", "Walk through a launch from scratch.")
 	cmdOpts.LaunchCmd.Flag("interactive", "Ask all questions even if there are default values.").Default(fmt.Sprintf("%t", cliContext.Interactive)).BoolVar(&cliContext.Interactive)
 	cmdOpts.LaunchCmd.Flag("force", "Do not ask questions.").Default(fmt.Sprintf("%t", cliContext.Force)).BoolVar(&cliContext.Force)
-	cmdOpts.LaunchCmd.Flag("type", "The type of cloud with which graviton will interact (only aws supported).").Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
+	cmdOpts.LaunchCmd.Flag("type", typeHelp).Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
 	cmdOpts.LaunchCmd.Flag("name", "The name of the deployment.  It must be unique to this account.").StringVar(&cliContext.DeploymentName)
 	cmdOpts.LaunchCmd.Flag("sd-version", "The stardog version to associate with this deployment.").Default(cliContext.Version).StringVar(&cliContext.Version)
 	cmdOpts.LaunchCmd.Flag("private-key", "The path to the private key").Default(cliContext.PrivateKeyPath).StringVar(&cliContext.PrivateKeyPath)
@@ -717,6 +1490,8 @@ func parseParameters(args []string) (*CliContext, error) {
 	cmdOpts.LaunchCmd.Flag("memory-max", "The maximum amount of memory to give the JVM that runs Stardog nodes.").StringVar(&cliContext.MemoryMax)
 	cmdOpts.LaunchCmd.Flag("memory-start", "The starting amount of memory to give the JVM that runs Stardog nodes.").StringVar(&cliContext.MemoryStart)
 	cmdOpts.LaunchCmd.Flag("disable-security", "Run the Stardog servers without security.").Default(fmt.Sprintf("%t", cliContext.DisableSecurity)).BoolVar(&cliContext.DisableSecurity)
+	cmdOpts.LaunchCmd.Flag("resume", "Continue an unfinished launch from the first step that did not finish.").BoolVar(&cliContext.Resume)
+	cmdOpts.LaunchCmd.Flag("rollback", "Undo the steps of an unfinished launch in reverse order.").BoolVar(&cliContext.Rollback)
 	cmdOpts.LaunchCmd.Validate(cliContext.envValidate)
 	cmdOpts.LaunchCmd.Action(cliContext.interactive)
 
@@ -725,6 +1500,17 @@ func parseParameters(args []string) (*CliContext, error) {
 	cmdOpts.DestroyCmd.Flag("force", "Do not verify with the destruction.").Default("false").BoolVar(&cliContext.Force)
 	cmdOpts.DestroyCmd.Action(cliContext.destroyFullDeployment)
 
+	cmdOpts.PauseCmd = cli.Command("pause", "Destroy the instance of a deployment but keep its volumes so that it can be resumed.")
+	cmdOpts.PauseCmd.Arg("name", "The name of the deployment to pause.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.PauseCmd.Flag("force", "Do not verify with the destruction of the instance.").Default("false").BoolVar(&cliContext.Force)
+	cmdOpts.PauseCmd.Action(cliContext.pauseDeployment)
+
+	cmdOpts.ResumeCmd = cli.Command("resume", "Create the instance of a paused deployment with the settings it had before.")
+	cmdOpts.ResumeCmd.Arg("name", "The name of the deployment to resume.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ResumeCmd.Flag("no-wait", "Do not block until the stardog instance is healthy.").Default(fmt.Sprintf("%t", cliContext.NoWaitForHealthy)).BoolVar(&cliContext.NoWaitForHealthy)
+	cmdOpts.ResumeCmd.Flag("wait-timeout", "The number of seconds to block waiting for the stardog instance to become healthy.").Default(fmt.Sprintf("%d", cliContext.WaitMaxTimeSec)).IntVar(&cliContext.WaitMaxTimeSec)
+	cmdOpts.ResumeCmd.Action(cliContext.resumeDeployment)
+
 	cmdOpts.StatusCmd = cli.Command("status", "Check the status of a full deployment.")
 	cmdOpts.StatusCmd.Arg("deployment name", "The name of the deployment to inspect.").Required().StringVar(&cliContext.DeploymentName)
 	cmdOpts.StatusCmd.Flag("json-file", "The path to the json output file.").StringVar(&cliContext.OutputFile)
@@ -740,6 +1526,7 @@ func parseParameters(args []string) (*CliContext, error) {
 	cmdOpts.LeaksCmd.Flag("destroy", "Destroy any of the resources found.").Default("false").BoolVar(&cliContext.Destroy)
 	cmdOpts.LeaksCmd.Flag("force", "Destroy any of the resources found without first asking.").Default("false").BoolVar(&cliContext.Force)
 	cmdOpts.LeaksCmd.Flag("deployment-name", "Limit the search to a particular deployment name.").StringVar(&cliContext.DeploymentName)
+	cmdOpts.LeaksCmd.Flag("type", typeHelp).Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
 	cmdOpts.LeaksCmd.Action(cliContext.leaks)
 
 	cmdOpts.SSHCmd = cli.Command("ssh", "ssh into the bastion node.")
@@ -752,11 +1539,34 @@ func parseParameters(args []string) (*CliContext, error) {
 	cmdOpts.BuildCmd = cli.Command("baseami", "Create a base ami.")
 	cmdOpts.BuildCmd.Arg("release", "The stardog release file.").Required().StringVar(&cliContext.SdReleaseFilePath)
 	cmdOpts.BuildCmd.Arg("sd-version", "The stardog release version to will be baked into this file.").Required().StringVar(&cliContext.Version)
+	cmdOpts.BuildCmd.Flag("type", typeHelp).Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
 	cmdOpts.BuildCmd.Action(cliContext.baseAmiAction)
 
+	imageCmd := cli.Command("image", "Manage the catalog of base images.")
+	cmdOpts.ListImagesCmd = imageCmd.Command("list", "List the base images in the catalog.")
+	cmdOpts.ListImagesCmd.Flag("type", typeHelp).Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
+	cmdOpts.ListImagesCmd.Action(cliContext.listImages)
+
+	cmdOpts.ShowImageCmd = imageCmd.Command("show", "Display everything the catalog knows about a base image.")
+	cmdOpts.ShowImageCmd.Arg("image", "The ID of the image.").Required().StringVar(&cliContext.ImageID)
+	cmdOpts.ShowImageCmd.Flag("type", typeHelp).Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
+	cmdOpts.ShowImageCmd.Action(cliContext.showImage)
+
+	cmdOpts.DeleteImageCmd = imageCmd.Command("delete", "Delete a base image and the snapshots behind it.")
+	cmdOpts.DeleteImageCmd.Arg("image", "The ID of the image.").Required().StringVar(&cliContext.ImageID)
+	cmdOpts.DeleteImageCmd.Flag("type", typeHelp).Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
+	cmdOpts.DeleteImageCmd.Flag("force", "Do not verify with the deletion.").Default("false").BoolVar(&cliContext.Force)
+	cmdOpts.DeleteImageCmd.Action(cliContext.deleteImage)
+
+	cmdOpts.CopyImageCmd = imageCmd.Command("copy", "Copy a base image to another region.")
+	cmdOpts.CopyImageCmd.Arg("image", "The ID of the image.").Required().StringVar(&cliContext.ImageID)
+	cmdOpts.CopyImageCmd.Flag("to-region", "The region to copy the image to.").Required().StringVar(&cliContext.ToRegion)
+	cmdOpts.CopyImageCmd.Flag("type", typeHelp).Default(cliContext.CloudType).StringVar(&cliContext.CloudType)
+	cmdOpts.CopyImageCmd.Action(cliContext.copyImage)
+
 	deployCmd := cli.Command("deployment", "Manage and inspect deployments.")
 	cmdOpts.NewDeploymentCmd = deployCmd.Command("new", "Define a new deployment but do not create volumes or launch an instance.")
-	cmdOpts.NewDeploymentCmd.Flag("type", "The type of cloud with which graviton will interact (only aws supported).").Default("aws").StringVar(&cliContext.CloudType)
+	cmdOpts.NewDeploymentCmd.Flag("type", typeHelp).Default("aws").StringVar(&cliContext.CloudType)
 	cmdOpts.NewDeploymentCmd.Arg("name", "The name of the deployment.  It must be unique to this account.").Required().StringVar(&cliContext.DeploymentName)
 	cmdOpts.NewDeploymentCmd.Arg("sd-version", "The stardog version to associate with this deployment.").Required().StringVar(&cliContext.Version)
 	cmdOpts.NewDeploymentCmd.Flag("private-key", "The path to the private key.").Default(cliContext.PrivateKeyPath).StringVar(&cliContext.PrivateKeyPath)
@@ -778,6 +1588,21 @@ func parseParameters(args []string) (*CliContext, error) {
 	cmdOpts.ListDeploymentCmd = deployCmd.Command("list", "List the knwon deployments.")
 	cmdOpts.ListDeploymentCmd.Action(cliContext.deploymentList)
 
+	cmdOpts.ScaleCmd = deployCmd.Command("scale", "Change the number of Stardog nodes in a running deployment.")
+	cmdOpts.ScaleCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ScaleCmd.Arg("count", "The new number of Stardog nodes.").Required().IntVar(&cliContext.ClusterSize)
+	cmdOpts.ScaleCmd.Flag("force", "Do not ask questions.").Default("false").BoolVar(&cliContext.Force)
+	cmdOpts.ScaleCmd.Flag("no-wait", "Do not block until the cluster has the new number of nodes.").Default(fmt.Sprintf("%t", cliContext.NoWaitForHealthy)).BoolVar(&cliContext.NoWaitForHealthy)
+	cmdOpts.ScaleCmd.Flag("wait-timeout", "The number of seconds to block waiting for the new nodes to join the cluster.").Default(fmt.Sprintf("%d", cliContext.WaitMaxTimeSec)).IntVar(&cliContext.WaitMaxTimeSec)
+	cmdOpts.ScaleCmd.Action(cliContext.scaleDeployment)
+
+	cmdOpts.UpgradeCmd = deployCmd.Command("upgrade", "Move a running deployment to another Stardog version one node at a time.")
+	cmdOpts.UpgradeCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.UpgradeCmd.Arg("sd-version", "The Stardog version to upgrade to.").Required().StringVar(&cliContext.Version)
+	cmdOpts.UpgradeCmd.Flag("release", "Path to the stardog release zip file used to build the base image when there is none.").StringVar(&cliContext.SdReleaseFilePath)
+	cmdOpts.UpgradeCmd.Flag("wait-timeout", "The number of seconds to block waiting for each new node to become healthy.").Default(fmt.Sprintf("%d", cliContext.WaitMaxTimeSec)).IntVar(&cliContext.WaitMaxTimeSec)
+	cmdOpts.UpgradeCmd.Action(cliContext.upgradeDeployment)
+
 	volumesCmd := cli.Command("volume", "Manage storage volumes.")
 	cmdOpts.NewVolumesCmd = volumesCmd.Command("new", "Create new backing storage.")
 	cmdOpts.NewVolumesCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
@@ -795,6 +1620,196 @@ func parseParameters(args []string) (*CliContext, error) {
 	cmdOpts.StatusVolumesCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
 	cmdOpts.StatusVolumesCmd.Action(cliContext.statusVolumes)
 
+	cmdOpts.ModifyVolumesCmd = volumesCmd.Command("modify", "Change the size, the type or the IOPS of the volumes.")
+	cmdOpts.ModifyVolumesCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ModifyVolumesCmd.Flag("size", "The new size of each storage volume in gigabytes.  Volumes can only grow.").Default("0").IntVar(&cliContext.NewVolumeSize)
+	cmdOpts.ModifyVolumesCmd.Flag("volume-type", "The new volume type.").StringVar(&cliContext.NewVolumeType)
+	cmdOpts.ModifyVolumesCmd.Flag("iops", "The new IOPS per gigabyte of the volumes.").Default("0").IntVar(&cliContext.NewVolumeIops)
+	cmdOpts.ModifyVolumesCmd.Flag("wait-timeout", "The number of seconds to block waiting for the volumes to change.").Default(fmt.Sprintf("%d", cliContext.WaitMaxTimeSec)).IntVar(&cliContext.WaitMaxTimeSec)
+	cmdOpts.ModifyVolumesCmd.Action(cliContext.modifyVolumes)
+
+	cmdOpts.SnapshotVolumesCmd = volumesCmd.Command("snapshot", "Take a snapshot set of the volumes of a deployment.")
+	cmdOpts.SnapshotVolumesCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.SnapshotVolumesCmd.Flag("pause-writes", "Take every database offline until all of the snapshots were started.").Default("false").BoolVar(&cliContext.PauseWrites)
+	cmdOpts.SnapshotVolumesCmd.Flag("no-wait", "Do not block until the snapshots are complete.").Default(fmt.Sprintf("%t", cliContext.NoWaitForHealthy)).BoolVar(&cliContext.NoWaitForHealthy)
+	cmdOpts.SnapshotVolumesCmd.Flag("wait-timeout", "The number of seconds to block waiting for the snapshots to complete.").Default(fmt.Sprintf("%d", cliContext.WaitMaxTimeSec)).IntVar(&cliContext.WaitMaxTimeSec)
+	cmdOpts.SnapshotVolumesCmd.Action(cliContext.snapshotVolumes)
+
+	cmdOpts.ListSnapshotsCmd = volumesCmd.Command("snapshots", "List the snapshot sets of a deployment.")
+	cmdOpts.ListSnapshotsCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ListSnapshotsCmd.Action(cliContext.listSnapshots)
+
+	cmdOpts.PruneSnapshotsCmd = volumesCmd.Command("prune-snapshots", "Delete the old snapshot sets of a deployment.")
+	cmdOpts.PruneSnapshotsCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.PruneSnapshotsCmd.Flag("keep", "The number of the newest complete snapshot sets to keep.").Default("1").IntVar(&cliContext.KeepSnapshots)
+	cmdOpts.PruneSnapshotsCmd.Flag("older-than", "Only delete the snapshot sets older than this, for example 720h.").DurationVar(&cliContext.OlderThan)
+	cmdOpts.PruneSnapshotsCmd.Flag("force", "Do not verify with the deletion.").Default("false").BoolVar(&cliContext.Force)
+	cmdOpts.PruneSnapshotsCmd.Action(cliContext.pruneSnapshots)
+
+	cmdOpts.RestoreVolumesCmd = volumesCmd.Command("restore", "Create the volumes of a new deployment from a snapshot set.")
+	cmdOpts.RestoreVolumesCmd.Arg("snapshot-set", "The ID of the snapshot set.").Required().StringVar(&cliContext.SnapshotSet)
+	cmdOpts.RestoreVolumesCmd.Arg("deployment", "The name of the new deployment.  It must have been created with 'deployment new'.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.RestoreVolumesCmd.Action(cliContext.restoreVolumes)
+
+	backupCmd := cli.Command("backup", "Manage logical backups of the Stardog databases.")
+	cmdOpts.NewBackupCmd = backupCmd.Command("new", "Back up databases through the bastion node.")
+	cmdOpts.NewBackupCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.NewBackupCmd.Flag("db", "A database to back up.  This option can be used multiple times.  Every database is backed up when it is not given.").StringsVar(&cliContext.Databases)
+	cmdOpts.NewBackupCmd.Flag("to", "A local directory or an object store URL such as s3://bucket/prefix.  The default is the backups directory of the deployment in the graviton configuration directory.").StringVar(&cliContext.BackupDest)
+	cmdOpts.NewBackupCmd.Action(cliContext.newBackup)
+
+	cmdOpts.ListBackupsCmd = backupCmd.Command("list", "List the backups in the catalog of a deployment.")
+	cmdOpts.ListBackupsCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ListBackupsCmd.Action(cliContext.listBackups)
+
+	cmdOpts.RestoreBackupCmd = backupCmd.Command("restore", "Restore a database from a backup.")
+	cmdOpts.RestoreBackupCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.RestoreBackupCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.RestoreBackupCmd.Arg("backup-id", "The ID of the backup.").Required().StringVar(&cliContext.BackupID)
+	cmdOpts.RestoreBackupCmd.Flag("from", "Look the backup up in the catalog of this deployment.").StringVar(&cliContext.FromDeployment)
+	cmdOpts.RestoreBackupCmd.Flag("overwrite", "Replace the database when it exists.").Default("false").BoolVar(&cliContext.Overwrite)
+	cmdOpts.RestoreBackupCmd.Action(cliContext.restoreBackup)
+
+	dbCmd := cli.Command("db", "Manage the Stardog databases of a deployment.")
+	cmdOpts.ListDatabasesCmd = dbCmd.Command("list", "List the databases.")
+	cmdOpts.ListDatabasesCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ListDatabasesCmd.Action(cliContext.listDatabases)
+
+	cmdOpts.CreateDatabaseCmd = dbCmd.Command("create", "Create a database and load data files into it.")
+	cmdOpts.CreateDatabaseCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.CreateDatabaseCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.CreateDatabaseCmd.Arg("files", "RDF files to load into the new database.  They may be compressed with gzip.").StringsVar(&cliContext.DataFiles)
+	cmdOpts.CreateDatabaseCmd.Flag("options-file", "A properties or JSON file with the database options.").StringVar(&cliContext.OptionsFile)
+	cmdOpts.CreateDatabaseCmd.Flag("option", "A database option as name=value.  This option can be used multiple times and overrides the options file.").StringsVar(&cliContext.DbOptions)
+	cmdOpts.CreateDatabaseCmd.Flag("graph", "The named graph to load the files into.  The default graph is used when it is not given.").StringVar(&cliContext.Graph)
+	cmdOpts.CreateDatabaseCmd.Action(cliContext.createDatabase)
+
+	cmdOpts.DropDatabaseCmd = dbCmd.Command("drop", "Delete a database and all of its data.")
+	cmdOpts.DropDatabaseCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.DropDatabaseCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.DropDatabaseCmd.Flag("force", "Do not verify with the deletion.").Default("false").BoolVar(&cliContext.Force)
+	cmdOpts.DropDatabaseCmd.Action(cliContext.dropDatabase)
+
+	cmdOpts.DatabaseOptionsCmd = dbCmd.Command("options", "Display or change the options of a database.")
+	cmdOpts.DatabaseOptionsCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.DatabaseOptionsCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.DatabaseOptionsCmd.Arg("names", "The options to display.  A common set is displayed when none are given.").StringsVar(&cliContext.OptionNames)
+	cmdOpts.DatabaseOptionsCmd.Flag("set", "Change an option with name=value.  This option can be used multiple times.").StringsVar(&cliContext.DbOptions)
+	cmdOpts.DatabaseOptionsCmd.Action(cliContext.databaseOptions)
+
+	cmdOpts.OnlineDatabaseCmd = dbCmd.Command("online", "Bring a database online.")
+	cmdOpts.OnlineDatabaseCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.OnlineDatabaseCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.OnlineDatabaseCmd.Action(cliContext.onlineDatabase)
+
+	cmdOpts.OfflineDatabaseCmd = dbCmd.Command("offline", "Take a database offline.")
+	cmdOpts.OfflineDatabaseCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.OfflineDatabaseCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.OfflineDatabaseCmd.Action(cliContext.offlineDatabase)
+
+	cmdOpts.QueryCmd = cli.Command("query", "Run a SPARQL query or update against a database.")
+	cmdOpts.QueryCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.QueryCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.QueryCmd.Flag("file", "A file with the query.").StringVar(&cliContext.QueryFile)
+	cmdOpts.QueryCmd.Flag("query", "The query.").StringVar(&cliContext.QueryString)
+	cmdOpts.QueryCmd.Flag("format", "The format of the results.").Default("table").EnumVar(&cliContext.QueryFormat, graviton.QueryFormats...)
+	cmdOpts.QueryCmd.Flag("timeout", "How long the query may run, for example 30s or 5m.  The server default is used when it is not given.").DurationVar(&cliContext.QueryTimeout)
+	cmdOpts.QueryCmd.Flag("reasoning", "Run the query with reasoning.").Default("false").BoolVar(&cliContext.Reasoning)
+	cmdOpts.QueryCmd.Flag("default-graph", "A graph to use as the default graph.  This option can be used multiple times.").StringsVar(&cliContext.DefaultGraphs)
+	cmdOpts.QueryCmd.Flag("named-graph", "A named graph the query can use.  This option can be used multiple times.").StringsVar(&cliContext.NamedGraphs)
+	cmdOpts.QueryCmd.Action(cliContext.query)
+
+	cmdOpts.LoadCmd = cli.Command("load", "Load RDF files into a database in transactions.")
+	cmdOpts.LoadCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.LoadCmd.Arg("db", "The name of the database.").Required().StringVar(&cliContext.Database)
+	cmdOpts.LoadCmd.Arg("files", "N-Triples, N-Quads, Turtle, RDF/XML, TriG or JSON-LD files.  They may be compressed with gzip.").Required().StringsVar(&cliContext.DataFiles)
+	cmdOpts.LoadCmd.Flag("chunk-size", "The number of triples to add in one transaction.  Turtle, TriG and RDF/XML files are cut near it, JSON-LD files are not cut.").Default("10000").IntVar(&cliContext.ChunkSize)
+	cmdOpts.LoadCmd.Flag("parallel", "The number of transactions to run at the same time.").Default("4").IntVar(&cliContext.Parallel)
+	cmdOpts.LoadCmd.Flag("retries", "The number of times to send a chunk again when it fails.").Default("3").IntVar(&cliContext.LoadRetries)
+	cmdOpts.LoadCmd.Flag("graph", "The named graph to load the files into.  The default graph is used when it is not given.").StringVar(&cliContext.Graph)
+	cmdOpts.LoadCmd.Action(cliContext.load)
+
+	userCmd := cli.Command("user", "Manage the Stardog users of a deployment.  Passwords are read from a prompt or from stdin.")
+	cmdOpts.ListUsersCmd = userCmd.Command("list", "List the users and their roles.")
+	cmdOpts.ListUsersCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ListUsersCmd.Action(cliContext.listUsers)
+
+	cmdOpts.AddUserCmd = userCmd.Command("add", "Add a user.")
+	cmdOpts.AddUserCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.AddUserCmd.Arg("user", "The name of the user.").Required().StringVar(&cliContext.UserName)
+	cmdOpts.AddUserCmd.Flag("superuser", "Make the user a superuser.").Default("false").BoolVar(&cliContext.Superuser)
+	cmdOpts.AddUserCmd.Flag("role", "A role to give the user.  This option can be used multiple times.").StringsVar(&cliContext.Roles)
+	cmdOpts.AddUserCmd.Action(cliContext.addUser)
+
+	cmdOpts.RemoveUserCmd = userCmd.Command("remove", "Delete a user.")
+	cmdOpts.RemoveUserCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.RemoveUserCmd.Arg("user", "The name of the user.").Required().StringVar(&cliContext.UserName)
+	cmdOpts.RemoveUserCmd.Flag("force", "Do not verify with the deletion.").Default("false").BoolVar(&cliContext.Force)
+	cmdOpts.RemoveUserCmd.Action(cliContext.removeUser)
+
+	cmdOpts.PasswdCmd = userCmd.Command("passwd", "Change the password of a user.")
+	cmdOpts.PasswdCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.PasswdCmd.Arg("user", "The name of the user.").Default("admin").StringVar(&cliContext.UserName)
+	cmdOpts.PasswdCmd.Action(cliContext.passwd)
+
+	roleCmd := cli.Command("role", "Manage the Stardog roles of a deployment.")
+	cmdOpts.ListRolesCmd = roleCmd.Command("list", "List the roles.")
+	cmdOpts.ListRolesCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ListRolesCmd.Action(cliContext.listRoles)
+
+	cmdOpts.AddRoleCmd = roleCmd.Command("add", "Add a role.")
+	cmdOpts.AddRoleCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.AddRoleCmd.Arg("role", "The name of the role.").Required().StringVar(&cliContext.RoleName)
+	cmdOpts.AddRoleCmd.Action(cliContext.addRole)
+
+	cmdOpts.RemoveRoleCmd = roleCmd.Command("remove", "Delete a role.")
+	cmdOpts.RemoveRoleCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.RemoveRoleCmd.Arg("role", "The name of the role.").Required().StringVar(&cliContext.RoleName)
+	cmdOpts.RemoveRoleCmd.Flag("force", "Delete the role even when users have it.").Default("false").BoolVar(&cliContext.ForceRole)
+	cmdOpts.RemoveRoleCmd.Action(cliContext.removeRole)
+
+	cmdOpts.GrantRoleCmd = roleCmd.Command("grant", "Give a role to a user.")
+	cmdOpts.GrantRoleCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.GrantRoleCmd.Arg("role", "The name of the role.").Required().StringVar(&cliContext.RoleName)
+	cmdOpts.GrantRoleCmd.Arg("user", "The name of the user.").Required().StringVar(&cliContext.UserName)
+	cmdOpts.GrantRoleCmd.Action(cliContext.grantRole)
+
+	cmdOpts.RevokeRoleCmd = roleCmd.Command("revoke", "Take a role away from a user.")
+	cmdOpts.RevokeRoleCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.RevokeRoleCmd.Arg("role", "The name of the role.").Required().StringVar(&cliContext.RoleName)
+	cmdOpts.RevokeRoleCmd.Arg("user", "The name of the user.").Required().StringVar(&cliContext.UserName)
+	cmdOpts.RevokeRoleCmd.Action(cliContext.revokeRole)
+
+	permissionCmd := cli.Command("permission", "Manage the permissions of the users and roles of a deployment.")
+	cmdOpts.ListPermissionsCmd = permissionCmd.Command("list", "List the permissions of a user or a role.")
+	cmdOpts.ListPermissionsCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ListPermissionsCmd.Flag("user", "The user.").StringVar(&cliContext.UserName)
+	cmdOpts.ListPermissionsCmd.Flag("role", "The role.").StringVar(&cliContext.RoleName)
+	cmdOpts.ListPermissionsCmd.Action(cliContext.listPermissions)
+
+	cmdOpts.GrantPermissionCmd = permissionCmd.Command("grant", "Grant a permission to a user or a role.")
+	cmdOpts.RevokePermissionCmd = permissionCmd.Command("revoke", "Revoke a permission from a user or a role.")
+	for _, cmd := range []*kingpin.CmdClause{cmdOpts.GrantPermissionCmd, cmdOpts.RevokePermissionCmd} {
+		cmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+		cmd.Arg("action", "The action.").Required().EnumVar(&cliContext.PermAction, "read", "write", "create", "delete", "grant", "revoke", "execute", "all")
+		cmd.Arg("resource-type", "The type of the resource, for example db, named-graph, user, role or *.").Required().StringVar(&cliContext.PermResourceType)
+		cmd.Arg("resource", "The resource, for example a database name or a database and a graph.").Required().StringsVar(&cliContext.PermResources)
+		cmd.Flag("user", "The user.").StringVar(&cliContext.UserName)
+		cmd.Flag("role", "The role.").StringVar(&cliContext.RoleName)
+	}
+	cmdOpts.GrantPermissionCmd.Action(cliContext.grantPermission)
+	cmdOpts.RevokePermissionCmd.Action(cliContext.revokePermission)
+
+	cmdOpts.ExportPermissionsCmd = permissionCmd.Command("export", "Write the roles and the roles and permissions of the users as JSON.")
+	cmdOpts.ExportPermissionsCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ExportPermissionsCmd.Flag("output", "The file to write.  The document is displayed when it is not given.").StringVar(&cliContext.SecurityFile)
+	cmdOpts.ExportPermissionsCmd.Action(cliContext.exportPermissions)
+
+	cmdOpts.ImportPermissionsCmd = permissionCmd.Command("import", "Add the roles and permissions of an exported document that a deployment is missing.")
+	cmdOpts.ImportPermissionsCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ImportPermissionsCmd.Arg("file", "The exported document.").Required().ExistingFileVar(&cliContext.SecurityFile)
+	cmdOpts.ImportPermissionsCmd.Action(cliContext.importPermissions)
+
 	instanceCmd := cli.Command("instance", "Manage the instance.")
 	cmdOpts.LaunchInstanceCmd = instanceCmd.Command("new", "Create new set of VMs running Stardog.")
 	cmdOpts.LaunchInstanceCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
@@ -815,11 +1830,19 @@ func parseParameters(args []string) (*CliContext, error) {
 	cmdOpts.StatusInstanceCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
 	cmdOpts.StatusInstanceCmd.Action(cliContext.statusInstance)
 
+	cmdOpts.ResizeInstanceCmd = instanceCmd.Command("resize", "Replace the nodes one at a time with nodes of a new instance type.")
+	cmdOpts.ResizeInstanceCmd.Arg("deployment", "The name of the deployment.").Required().StringVar(&cliContext.DeploymentName)
+	cmdOpts.ResizeInstanceCmd.Flag("sd-instance-type", "The new instance type of the Stardog nodes.").StringVar(&cliContext.SdInstanceType)
+	cmdOpts.ResizeInstanceCmd.Flag("zk-instance-type", "The new instance type of the ZooKeeper nodes.").StringVar(&cliContext.ZkInstanceType)
+	cmdOpts.ResizeInstanceCmd.Flag("wait-timeout", "The number of seconds to block waiting for each new node to become healthy.").Default(fmt.Sprintf("%d", cliContext.WaitMaxTimeSec)).IntVar(&cliContext.WaitMaxTimeSec)
+	cmdOpts.ResizeInstanceCmd.Action(cliContext.resizeInstance)
+
 	// Add all the options for all the plugins
 	for _, p := range pluginsMap {
 		p.Register(&cmdOpts)
 		sdutils.AddCloudType(p)
 	}
+	hideUnsupported(&cmdOpts)
 
 	_, err = cli.Parse(args)
 	if err != nil {
@@ -838,5 +1861,74 @@ func loadDepWrapper(cliContext *CliContext, new bool) (sdutils.Deployment, error
 		PrivateKey:      cliContext.PrivateKeyPath,
 		CustomPropsFile: cliContext.CustomSdProps,
 	}
-	return sdutils.LoadDeployment(cliContext, &baseD, new)
+	return sdutils.LoadDeployment(cliContext.ctx, cliContext, &baseD, new)
+}
+
+// loadDepCapabilities loads an existing deployment along with the
+// capabilities of the plugin that manages it.
+func loadDepCapabilities(cliContext *CliContext) (sdutils.Deployment, sdutils.Capabilities, error) {
+	baseD := sdutils.BaseDeployment{
+		Name:            cliContext.DeploymentName,
+		Version:         cliContext.Version,
+		Type:            strings.ToLower(cliContext.CloudType),
+		Directory:       sdutils.DeploymentDir(cliContext.GetConfigDir(), cliContext.DeploymentName),
+		PrivateKey:      cliContext.PrivateKeyPath,
+		CustomPropsFile: cliContext.CustomSdProps,
+	}
+	d, err := sdutils.LoadDeployment(cliContext.ctx, cliContext, &baseD, false)
+	if err != nil {
+		return nil, sdutils.Capabilities{}, err
+	}
+	p, err := sdutils.GetPlugin(baseD.Type)
+	if err != nil {
+		return nil, sdutils.Capabilities{}, err
+	}
+	return d, p.Capabilities(), nil
+}
+
+func loadVolumeDeployment(cliContext *CliContext) (sdutils.Deployment, error) {
+	d, caps, err := loadDepCapabilities(cliContext)
+	if err != nil {
+		return nil, err
+	}
+	if !caps.Volumes {
+		return nil, fmt.Errorf("The deployment %s does not use volumes", cliContext.DeploymentName)
+	}
+	return d, nil
+}
+
+// hideUnsupported hides the commands that none of the plugins can run.
+func hideUnsupported(cmdOpts *sdutils.CommandOpts) {
+	var all sdutils.Capabilities
+	for _, p := range pluginsMap {
+		caps := p.Capabilities()
+		all.Images = all.Images || caps.Images
+		all.Volumes = all.Volumes || caps.Volumes
+		all.Scaling = all.Scaling || caps.Scaling
+		all.Snapshots = all.Snapshots || caps.Snapshots
+	}
+	if !all.Images {
+		cmdOpts.BuildCmd.Hidden()
+		cmdOpts.UpgradeCmd.Hidden()
+		cmdOpts.ListImagesCmd.Hidden()
+		cmdOpts.ShowImageCmd.Hidden()
+		cmdOpts.DeleteImageCmd.Hidden()
+		cmdOpts.CopyImageCmd.Hidden()
+	}
+	if !all.Volumes {
+		cmdOpts.NewVolumesCmd.Hidden()
+		cmdOpts.DestroyVolumesCmd.Hidden()
+		cmdOpts.StatusVolumesCmd.Hidden()
+		cmdOpts.ModifyVolumesCmd.Hidden()
+	}
+	if !all.Snapshots {
+		cmdOpts.SnapshotVolumesCmd.Hidden()
+		cmdOpts.ListSnapshotsCmd.Hidden()
+		cmdOpts.PruneSnapshotsCmd.Hidden()
+		cmdOpts.RestoreVolumesCmd.Hidden()
+	}
+	if !all.Scaling {
+		cmdOpts.ScaleCmd.Hidden()
+		cmdOpts.ResizeInstanceCmd.Hidden()
+	}
 }
@@ -16,7 +16,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -166,15 +165,23 @@ func buildImage(amiName string, confDir string, version string, releasefile stri
 	return nil
 }
 
-func loadAmiFile(filepath string) (map[string]string, error) {
-	b, err := ioutil.ReadFile(filepath)
+// loadAmiFile returns the newest image of the version in each region of the
+// image catalog.
+func loadAmiFile(confDir string, version string) (map[string]string, error) {
+	var cat struct {
+		Images []sdutils.ImageDescription `json:"images"`
+	}
+	err := sdutils.LoadJSON(&cat, path.Join(confDir, "images.json"))
 	if err != nil {
 		return nil, err
 	}
 	m := make(map[string]string)
-	err = json.Unmarshal(b, &m)
-	if err != nil {
-		return nil, err
+	built := make(map[string]time.Time)
+	for _, img := range cat.Images {
+		if img.Version == version && !img.Built.Before(built[img.Region]) {
+			m[img.Region] = img.ID
+			built[img.Region] = img.Built
+		}
 	}
 	return m, nil
 }
@@ -207,11 +214,7 @@ func TestBuildImageTop(t *testing.T) {
 		t.Fatalf("Failed to make the ami %s", err)
 	}
 
-	ami42Path := path.Join(confDir, "amis-4.2.json")
-	if !sdutils.PathExists(ami42Path) {
-		t.Fatalf("The 4.2 file doesn't exist %s", ami42Path)
-	}
-	m42, err := loadAmiFile(ami42Path)
+	m42, err := loadAmiFile(confDir, "4.2")
 	if err != nil {
 		t.Fatalf("Failed to load the 4.2 ami info %s", err)
 	}
@@ -230,11 +233,7 @@ func TestBuildImageTop(t *testing.T) {
 		t.Fatalf("The wrong ami was found")
 	}
 
-	ami43Path := path.Join(confDir, "amis-4.3.json")
-	if !sdutils.PathExists(ami43Path) {
-		t.Fatalf("The 4.3 file doesn't exist %s", ami43Path)
-	}
-	m43, err := loadAmiFile(ami43Path)
+	m43, err := loadAmiFile(confDir, "4.3")
 	if err != nil {
 		t.Fatalf("Failed to load the 4.3 ami info %s", err)
 	}
@@ -245,6 +244,15 @@ func TestBuildImageTop(t *testing.T) {
 	if ent != "ami-43xxeast" {
 		t.Fatalf("The wrong ami was found")
 	}
+
+	rc := realMain([]string{"--quiet", "--config-dir", confDir, "image", "list"})
+	if rc != 0 {
+		t.Fatalf("image list should return 0")
+	}
+	rc = realMain([]string{"--quiet", "--config-dir", confDir, "image", "show", "ami-43xxeast"})
+	if rc != 0 {
+		t.Fatalf("image show should return 0")
+	}
 }
 
 func TestBasicDeploy(t *testing.T) {
@@ -0,0 +1,519 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcplugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+// Plugin is the graviton side of an external plugin.  It implements
+// sdutils.Plugin by forwarding every call to the plugin executable, which
+// is started the first time it is needed and lives until Close is called.
+type Plugin struct {
+	name    string
+	path    string
+	cmd     *exec.Cmd
+	w       io.WriteCloser
+	r       *bufio.Scanner
+	nextID  int
+	desc    *DescribeResult
+	options map[string]*flagValue
+	stop    func()
+	lock    sync.Mutex
+}
+
+// NewPlugin returns a plugin backed by the executable at path.  The cloud
+// type is the file name without the graviton-plugin- prefix.
+func NewPlugin(path string) *Plugin {
+	name := strings.TrimSuffix(filepath.Base(path), ".exe")
+	return &Plugin{
+		name:    strings.TrimPrefix(name, ExecutablePrefix),
+		path:    path,
+		options: make(map[string]*flagValue),
+	}
+}
+
+func newConnPlugin(name string, r io.Reader, w io.WriteCloser) *Plugin {
+	p := NewPlugin(ExecutablePrefix + name)
+	p.setConn(r, w)
+	return p
+}
+
+func (p *Plugin) setConn(r io.Reader, w io.WriteCloser) {
+	p.w = w
+	p.r = bufio.NewScanner(r)
+	p.r.Buffer(make([]byte, 64*1024), 16*1024*1024)
+}
+
+// SearchPath returns the directories searched for plugin executables, the
+// plugins directory under the graviton configuration directory followed
+// by the PATH.
+func SearchPath(confDir string) []string {
+	dirs := []string{filepath.Join(confDir, "plugins")}
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// Discover looks for plugin executables in the given directories.  When the
+// same cloud type is found more than once the first directory wins.
+func Discover(dirs []string) []*Plugin {
+	seen := make(map[string]bool)
+	plugins := []*Plugin{}
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		names := []string{}
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ExecutablePrefix) {
+				names = append(names, e.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			exePath := filepath.Join(dir, n)
+			fi, err := os.Stat(exePath)
+			if err != nil || fi.IsDir() || fi.Mode()&0111 == 0 {
+				continue
+			}
+			p := NewPlugin(exePath)
+			if p.name == "" || seen[p.name] {
+				continue
+			}
+			seen[p.name] = true
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+func (p *Plugin) start() error {
+	if p.w != nil {
+		return nil
+	}
+	cmd := exec.Command(p.path)
+	cmd.Stderr = os.Stderr
+	// Like the tools run by RunCommand the plugin is kept out of the
+	// terminals process group.  Graviton forwards a single interrupt when
+	// the request it is serving is cancelled.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("The plugin %s could not be started: %s", p.path, err)
+	}
+	p.cmd = cmd
+	p.stop = func() { cmd.Process.Signal(os.Interrupt) }
+	p.setConn(r, w)
+	return nil
+}
+
+// Close shuts down the plugin executable if it was started.
+func (p *Plugin) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.w == nil {
+		return nil
+	}
+	p.w.Close()
+	p.w = nil
+	if p.cmd == nil {
+		return nil
+	}
+	return p.cmd.Wait()
+}
+
+func (p *Plugin) call(ctx context.Context, app sdutils.AppContext, method string, params *Params, result interface{}) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if ctx.Err() != nil {
+		return &sdutils.InterruptedError{Program: p.path}
+	}
+	err := p.start()
+	if err != nil {
+		return err
+	}
+	p.nextID++
+	msg := Message{ID: p.nextID, Method: method, Options: make(map[string]string)}
+	for name, v := range p.options {
+		msg.Options[name] = v.value
+	}
+	if app != nil {
+		msg.Context = &ContextInfo{ConfigDir: app.GetConfigDir(), Version: app.GetVersion()}
+	}
+	msg.Params, err = json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(append(b, '\n'))
+	if err != nil {
+		return fmt.Errorf("The plugin %s is not accepting requests: %s", p.name, err)
+	}
+
+	// The plugin keeps reading requests while it works so the only way to
+	// reach it is a signal.  It is expected to stop the tool it is running
+	// and answer the request with an error.
+	answered := make(chan struct{})
+	defer close(answered)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if p.stop != nil {
+				p.stop()
+			}
+		case <-answered:
+		}
+	}()
+
+	for p.r.Scan() {
+		var in Message
+		err = json.Unmarshal(p.r.Bytes(), &in)
+		if err != nil {
+			return fmt.Errorf("The plugin %s sent an invalid message: %s", p.name, err)
+		}
+		if in.ID == 0 && in.Method != "" {
+			p.log(app, &in)
+			continue
+		}
+		if in.ID != msg.ID {
+			if in.Error != "" {
+				return fmt.Errorf("The plugin %s rejected the request: %s", p.name, in.Error)
+			}
+			continue
+		}
+		if in.Error != "" {
+			if ctx.Err() != nil {
+				return &sdutils.InterruptedError{Program: p.path}
+			}
+			return errors.New(in.Error)
+		}
+		if result != nil && len(in.Result) > 0 {
+			return json.Unmarshal(in.Result, result)
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		return &sdutils.InterruptedError{Program: p.path}
+	}
+	return fmt.Errorf("The plugin %s stopped before answering %s", p.name, method)
+}
+
+func (p *Plugin) log(app sdutils.AppContext, msg *Message) {
+	if app == nil {
+		return
+	}
+	var lp LogParams
+	err := json.Unmarshal(msg.Params, &lp)
+	if err != nil {
+		app.Logf(sdutils.WARN, "The plugin %s sent a bad %s notification: %s", p.name, msg.Method, err)
+		return
+	}
+	switch msg.Method {
+	case NotifyConsoleLog:
+		app.ConsoleLog(lp.Level, "%s", lp.Message)
+	case NotifyLogf:
+		app.Logf(lp.Level, "%s", lp.Message)
+	default:
+		app.Logf(sdutils.WARN, "The plugin %s sent the unknown notification %s", p.name, msg.Method)
+	}
+}
+
+// GetName returns the cloud type served by the plugin.
+func (p *Plugin) GetName() string {
+	return p.name
+}
+
+func (p *Plugin) describe() (*DescribeResult, error) {
+	if p.desc != nil {
+		return p.desc, nil
+	}
+	var desc DescribeResult
+	err := p.call(context.Background(), nil, MethodDescribe, &Params{ProtocolVersion: ProtocolVersion}, &desc)
+	if err != nil {
+		return nil, err
+	}
+	if desc.Name != p.name {
+		return nil, fmt.Errorf("The plugin %s describes itself as %s", p.path, desc.Name)
+	}
+	p.desc = &desc
+	return p.desc, nil
+}
+
+// Capabilities returns the capabilities the plugin described.  A plugin
+// that cannot be described supports nothing.
+func (p *Plugin) Capabilities() sdutils.Capabilities {
+	desc, err := p.describe()
+	if err != nil {
+		return sdutils.Capabilities{}
+	}
+	return desc.Capabilities
+}
+
+// Register asks the plugin for its flags and adds them to the graviton
+// commands.  The parsed values are sent along with every later request.
+func (p *Plugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	desc, err := p.describe()
+	if err != nil {
+		return err
+	}
+	clauses := commandClauses(cmdOpts)
+	for _, spec := range desc.Flags {
+		clause, ok := clauses[spec.Command]
+		if !ok || *clause == nil {
+			continue
+		}
+		v, ok := p.options[spec.Name]
+		if !ok {
+			v = &flagValue{value: spec.Default, isBool: spec.Bool}
+			p.options[spec.Name] = v
+		}
+		f := (*clause).Flag(spec.Name, spec.Help)
+		if spec.Default != "" {
+			f.Default(spec.Default)
+		}
+		f.SetValue(v)
+	}
+	return nil
+}
+
+// LoadDefaults passes the cloud options of the defaults file to the plugin.
+func (p *Plugin) LoadDefaults(defaultCliOpts interface{}) error {
+	return p.call(context.Background(), nil, MethodLoadDefaults, &Params{Defaults: defaultCliOpts}, nil)
+}
+
+// BuildImage has the plugin build the base image for a Stardog release.
+func (p *Plugin) BuildImage(ctx context.Context, app sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	return p.call(ctx, app, MethodBuildImage, &Params{ReleaseFile: sdReleaseFilePath, Version: version}, nil)
+}
+
+// FindLeaks has the plugin look for resources left behind by a deployment.
+func (p *Plugin) FindLeaks(ctx context.Context, app sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	return p.call(ctx, app, MethodFindLeaks, &Params{DeploymentName: deploymentName, Destroy: destroy, Force: force}, nil)
+}
+
+// HaveImage reports whether the plugin has a base image for this version.
+func (p *Plugin) HaveImage(ctx context.Context, app sdutils.AppContext) bool {
+	var res Result
+	err := p.call(ctx, app, MethodHaveImage, &Params{}, &res)
+	if err != nil {
+		app.Logf(sdutils.WARN, "The plugin %s could not check for an image: %s", p.name, err)
+		return false
+	}
+	return res.Bool
+}
+
+// DeploymentLoader loads or creates a deployment in the plugin.  The plugin
+// is stateless between calls so the returned Deployment sends the
+// BaseDeployment, including the plugins own CloudOpts, with every request.
+func (p *Plugin) DeploymentLoader(ctx context.Context, app sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	var res Result
+	err := p.call(ctx, app, MethodDeploymentLoader, &Params{Base: baseD, New: new}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Base != nil {
+		baseD.CloudOpts = res.Base.CloudOpts
+	}
+	d := &deployment{plugin: p, app: app, base: baseD}
+	switch {
+	case res.GatherLogs && res.Remote:
+		return &fullDeployment{d}, nil
+	case res.GatherLogs:
+		return &gathererDeployment{d}, nil
+	case res.Remote:
+		return &runnerDeployment{d}, nil
+	}
+	return d, nil
+}
+
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) Set(s string) error {
+	f.value = s
+	return nil
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+type deployment struct {
+	plugin *Plugin
+	app    sdutils.AppContext
+	base   *sdutils.BaseDeployment
+}
+
+func (d *deployment) call(ctx context.Context, method string, params *Params) (*Result, error) {
+	params.Base = d.base
+	var res Result
+	err := d.plugin.call(ctx, d.app, method, params, &res)
+	return &res, err
+}
+
+func (d *deployment) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	_, err := d.call(ctx, MethodCreateVolumeSet, &Params{LicensePath: licensePath, VolumeSize: sizeOfEachVolume, ClusterSize: clusterSize})
+	return err
+}
+
+func (d *deployment) DeleteVolumeSet(ctx context.Context) error {
+	_, err := d.call(ctx, MethodDeleteVolumeSet, &Params{})
+	return err
+}
+
+func (d *deployment) StatusVolumeSet(ctx context.Context) error {
+	_, err := d.call(ctx, MethodStatusVolumeSet, &Params{})
+	return err
+}
+
+func (d *deployment) VolumeExists() bool {
+	res, err := d.call(context.Background(), MethodVolumeExists, &Params{})
+	if err != nil {
+		d.app.Logf(sdutils.WARN, "Failed to check for volumes: %s", err)
+		return false
+	}
+	return res.Bool
+}
+
+func (d *deployment) ClusterSize() (int, error) {
+	res, err := d.call(context.Background(), MethodClusterSize, &Params{})
+	if err != nil {
+		return -1, err
+	}
+	return res.Int, nil
+}
+
+func (d *deployment) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	_, err := d.call(ctx, MethodCreateInstance, &Params{VolumeSize: volumeSize, ZookeeperSize: zookeeperSize, IdleTimeout: idleTimeout})
+	return err
+}
+
+func (d *deployment) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	_, err := d.call(ctx, MethodOpenInstance, &Params{VolumeSize: volumeSize, ZookeeperSize: zookeeperSize, Mask: mask, IdleTimeout: idleTimeout})
+	return err
+}
+
+func (d *deployment) DeleteInstance(ctx context.Context) error {
+	_, err := d.call(ctx, MethodDeleteInstance, &Params{})
+	return err
+}
+
+func (d *deployment) StatusInstance(ctx context.Context) error {
+	_, err := d.call(ctx, MethodStatusInstance, &Params{})
+	return err
+}
+
+func (d *deployment) InstanceExists() bool {
+	res, err := d.call(context.Background(), MethodInstanceExists, &Params{})
+	if err != nil {
+		d.app.Logf(sdutils.WARN, "Failed to check for an instance: %s", err)
+		return false
+	}
+	return res.Bool
+}
+
+func (d *deployment) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	res, err := d.call(ctx, MethodFullStatus, &Params{})
+	if err != nil {
+		return nil, err
+	}
+	if res.Description == nil {
+		return nil, fmt.Errorf("The plugin %s returned no status", d.plugin.name)
+	}
+	return res.Description, nil
+}
+
+func (d *deployment) DestroyDeployment(ctx context.Context) error {
+	_, err := d.call(ctx, MethodDestroyDeployment, &Params{})
+	return err
+}
+
+func (d *deployment) gatherLogs(ctx context.Context, outfile string) error {
+	_, err := d.call(ctx, MethodGatherLogs, &Params{Outfile: outfile})
+	return err
+}
+
+func (d *deployment) remoteCommand(interactive bool) ([]string, error) {
+	res, err := d.call(context.Background(), MethodRemoteCommand, &Params{Interactive: interactive})
+	if err != nil {
+		return nil, err
+	}
+	return res.Command, nil
+}
+
+// The optional interfaces are only visible to graviton when the deployment
+// in the plugin implements them.
+
+type gathererDeployment struct {
+	*deployment
+}
+
+func (d *gathererDeployment) GatherLogs(ctx context.Context, outfile string) error {
+	return d.gatherLogs(ctx, outfile)
+}
+
+type runnerDeployment struct {
+	*deployment
+}
+
+func (d *runnerDeployment) RemoteCommand(interactive bool) ([]string, error) {
+	return d.remoteCommand(interactive)
+}
+
+type fullDeployment struct {
+	*deployment
+}
+
+func (d *fullDeployment) GatherLogs(ctx context.Context, outfile string) error {
+	return d.gatherLogs(ctx, outfile)
+}
+
+func (d *fullDeployment) RemoteCommand(interactive bool) ([]string, error) {
+	return d.remoteCommand(interactive)
+}
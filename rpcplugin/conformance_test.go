@@ -0,0 +1,207 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcplugin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// The conformance tests run against a real plugin executable.  By default
+// they build and check the reference plugin.  Any other plugin can be
+// checked with:
+//
+//   GRAVITON_PLUGIN=/path/to/graviton-plugin-foo go test ./rpcplugin -run Conformance
+//
+// The lifecycle test creates volumes and an instance, so for other plugins
+// it only runs when GRAVITON_PLUGIN_LIFECYCLE is also set.
+
+func conformancePlugin(t *testing.T) (*Plugin, bool, func()) {
+	exe := os.Getenv("GRAVITON_PLUGIN")
+	if exe != "" {
+		return NewPlugin(exe), os.Getenv("GRAVITON_PLUGIN_LIFECYCLE") != "", func() {}
+	}
+	dir, err := ioutil.TempDir("", "conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe = path.Join(dir, ExecutablePrefix+"example")
+	out, err := exec.Command("go", "build", "-o", exe, "./graviton-plugin-example").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Failed to build the reference plugin %s: %s", err, string(out))
+	}
+	return NewPlugin(exe), true, func() { os.RemoveAll(dir) }
+}
+
+func allCommandOpts() *sdutils.CommandOpts {
+	app := kingpin.New("conformance", "")
+	cmdOpts := &sdutils.CommandOpts{Cli: app}
+	for name, clause := range commandClauses(cmdOpts) {
+		*clause = app.Command(strings.Replace(name, " ", "-", -1), "")
+	}
+	return cmdOpts
+}
+
+func TestConformanceProtocol(t *testing.T) {
+	p, _, cleanup := conformancePlugin(t)
+	defer cleanup()
+	defer p.Close()
+
+	var desc DescribeResult
+	err := p.call(context.Background(), nil, MethodDescribe, &Params{ProtocolVersion: ProtocolVersion}, &desc)
+	if err != nil {
+		t.Fatalf("Describe failed %s", err)
+	}
+	if desc.Name != p.GetName() || desc.ProtocolVersion != ProtocolVersion {
+		t.Fatalf("The plugin described itself as %s version %d", desc.Name, desc.ProtocolVersion)
+	}
+	known := commandClauses(&sdutils.CommandOpts{})
+	for _, f := range desc.Flags {
+		if _, ok := known[f.Command]; !ok {
+			t.Errorf("The flag %s is on the unknown command %s", f.Name, f.Command)
+		}
+		if !strings.HasPrefix(f.Name, p.GetName()+"-") {
+			t.Errorf("The flag %s must start with %s-", f.Name, p.GetName())
+		}
+	}
+	err = p.Register(allCommandOpts())
+	if err != nil {
+		t.Fatalf("Register failed %s", err)
+	}
+
+	err = p.call(context.Background(), nil, MethodDescribe, &Params{ProtocolVersion: ProtocolVersion + 100}, nil)
+	if err == nil {
+		t.Error("An unknown protocol version must be refused")
+	}
+	err = p.call(context.Background(), nil, "Plugin.NoSuchMethod", &Params{}, nil)
+	if err == nil {
+		t.Error("An unknown method must return an error")
+	}
+	err = p.call(context.Background(), nil, MethodVolumeExists, &Params{}, nil)
+	if err == nil {
+		t.Error("A deployment method without a deployment must return an error")
+	}
+
+	p.w.Write([]byte("this is not json\n"))
+	err = p.call(context.Background(), nil, MethodLoadDefaults, &Params{Defaults: map[string]interface{}{}}, nil)
+	if err == nil {
+		t.Error("A malformed request must be answered with an error")
+	}
+	err = p.LoadDefaults(map[string]interface{}{})
+	if err != nil {
+		t.Errorf("The plugin must keep serving after a malformed request %s", err)
+	}
+}
+
+func TestConformanceLifecycle(t *testing.T) {
+	p, lifecycle, cleanup := conformancePlugin(t)
+	defer cleanup()
+	defer p.Close()
+	if !lifecycle {
+		t.Skip("Set GRAVITON_PLUGIN_LIFECYCLE to create real resources with the plugin")
+	}
+
+	confDir, err := ioutil.TempDir("", "conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(confDir)
+	license := path.Join(confDir, "stardog-license-key.bin")
+	ioutil.WriteFile(license, []byte("license"), 0644)
+
+	ctx := &recordContext{TestContext: sdutils.TestContext{ConfigDir: confDir, Version: "5.0.0"}}
+	err = p.Register(allCommandOpts())
+	if err != nil {
+		t.Fatalf("Register failed %s", err)
+	}
+	baseD := sdutils.BaseDeployment{
+		Name:      "conformance",
+		Type:      p.GetName(),
+		Version:   "5.0.0",
+		Directory: sdutils.DeploymentDir(confDir, "conformance"),
+	}
+	os.MkdirAll(baseD.Directory, 0755)
+	d, err := p.DeploymentLoader(context.Background(), ctx, &baseD, true)
+	if err != nil {
+		t.Fatalf("Creating the deployment failed %s", err)
+	}
+	if d.VolumeExists() || d.InstanceExists() {
+		t.Fatal("A new deployment must have no volumes and no instance")
+	}
+	caps := p.Capabilities()
+	if caps.Volumes {
+		err = d.CreateVolumeSet(context.Background(), license, 10, 3)
+		if err != nil {
+			t.Fatalf("CreateVolumeSet failed %s", err)
+		}
+		size, err := d.ClusterSize()
+		if err != nil || size != 3 {
+			t.Fatalf("The cluster size should be 3 %d %v", size, err)
+		}
+	}
+	err = d.CreateInstance(context.Background(), 10, 3, 0)
+	if err != nil {
+		t.Fatalf("CreateInstance failed %s", err)
+	}
+	err = d.OpenInstance(context.Background(), 10, 3, "127.0.0.1/32", 0)
+	if err != nil {
+		t.Fatalf("OpenInstance failed %s", err)
+	}
+
+	var loadedD sdutils.BaseDeployment
+	err = sdutils.LoadJSON(&loadedD, path.Join(baseD.Directory, "config.json"))
+	if err != nil {
+		t.Fatalf("The plugin must write config.json for a new deployment %s", err)
+	}
+	d, err = p.DeploymentLoader(context.Background(), ctx, &loadedD, false)
+	if err != nil {
+		t.Fatalf("Loading the deployment failed %s", err)
+	}
+	if d.VolumeExists() != caps.Volumes || !d.InstanceExists() {
+		t.Fatal("The loaded deployment must see the volumes and the instance")
+	}
+	sd, err := d.FullStatus(context.Background())
+	if err != nil || sd == nil {
+		t.Fatalf("FullStatus failed %v", err)
+	}
+
+	err = d.DeleteInstance(context.Background())
+	if err != nil {
+		t.Fatalf("DeleteInstance failed %s", err)
+	}
+	if caps.Volumes {
+		err = d.DeleteVolumeSet(context.Background())
+		if err != nil {
+			t.Fatalf("DeleteVolumeSet failed %s", err)
+		}
+	}
+	if d.VolumeExists() || d.InstanceExists() {
+		t.Fatal("The volumes and instance must be gone")
+	}
+	err = d.DestroyDeployment(context.Background())
+	if err != nil {
+		t.Fatalf("DestroyDeployment failed %s", err)
+	}
+}
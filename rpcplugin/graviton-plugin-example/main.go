@@ -0,0 +1,253 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// graviton-plugin-example is the reference external plugin.  It does not
+// start anything, it only records the volumes and instance it was asked for
+// in the deployment directory, which makes it useful for trying out the
+// plugin protocol and for running the conformance tests.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/rpcplugin"
+	"github.com/stardog-union/stardog-graviton/sdutils"
+)
+
+type examplePlugin struct {
+	URL string `json:"url,omitempty"`
+}
+
+type exampleVolumes struct {
+	VolumeSize  int `json:"volume_size"`
+	ClusterSize int `json:"cluster_size"`
+}
+
+type exampleInstance struct {
+	ZookeeperSize int    `json:"zookeeper_size"`
+	Mask          string `json:"mask,omitempty"`
+}
+
+type exampleDeployment struct {
+	URL  string `json:"url,omitempty"`
+	name string
+	dir  string
+	ctx  sdutils.AppContext
+}
+
+func (p *examplePlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	cmdOpts.LaunchCmd.Flag("example-url", "The Stardog URL reported by example deployments.").Default(p.URL).StringVar(&p.URL)
+	cmdOpts.NewDeploymentCmd.Flag("example-url", "The Stardog URL reported by example deployments.").Default(p.URL).StringVar(&p.URL)
+	return nil
+}
+
+func (p *examplePlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	b, err := json.Marshal(defaultCliOpts)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, p)
+}
+
+func (p *examplePlugin) GetName() string {
+	return "example"
+}
+
+func (p *examplePlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Images: true, Volumes: true, Firewall: true}
+}
+
+func imagePath(c sdutils.AppContext, version string) string {
+	return path.Join(c.GetConfigDir(), "example-images", version)
+}
+
+func (p *examplePlugin) HaveImage(ctx context.Context, c sdutils.AppContext) bool {
+	return sdutils.PathExists(imagePath(c, c.GetVersion()))
+}
+
+func (p *examplePlugin) BuildImage(ctx context.Context, c sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	if !sdutils.PathExists(sdReleaseFilePath) {
+		return fmt.Errorf("The release file %s does not exist", sdReleaseFilePath)
+	}
+	imageFile := imagePath(c, version)
+	os.MkdirAll(path.Dir(imageFile), 0755)
+	err := ioutil.WriteFile(imageFile, []byte(sdReleaseFilePath), 0644)
+	if err != nil {
+		return err
+	}
+	c.ConsoleLog(1, "Recorded the image for %s\n", version)
+	return nil
+}
+
+func (p *examplePlugin) FindLeaks(ctx context.Context, c sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	c.ConsoleLog(1, "The example plugin never leaks resources.\n")
+	return nil
+}
+
+func (p *examplePlugin) DeploymentLoader(ctx context.Context, c sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	d := exampleDeployment{URL: p.URL}
+	if !new {
+		data, err := json.Marshal(baseD.CloudOpts)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, &d)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d.name = baseD.Name
+	d.dir = sdutils.DeploymentDir(c.GetConfigDir(), baseD.Name)
+	d.ctx = c
+	if new {
+		baseD.CloudOpts = &d
+		err := sdutils.WriteJSON(baseD, path.Join(d.dir, "config.json"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &d, nil
+}
+
+func (d *exampleDeployment) volumesPath() string {
+	return path.Join(d.dir, "volumes.json")
+}
+
+func (d *exampleDeployment) instancePath() string {
+	return path.Join(d.dir, "instance.json")
+}
+
+func (d *exampleDeployment) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	if d.VolumeExists() {
+		return fmt.Errorf("The volumes for %s already exist", d.name)
+	}
+	if !sdutils.PathExists(licensePath) {
+		return fmt.Errorf("The license file %s does not exist", licensePath)
+	}
+	d.ctx.ConsoleLog(1, "Creating %d volumes of %d GB\n", clusterSize, sizeOfEachVolume)
+	return sdutils.WriteJSON(&exampleVolumes{VolumeSize: sizeOfEachVolume, ClusterSize: clusterSize}, d.volumesPath())
+}
+
+func (d *exampleDeployment) DeleteVolumeSet(ctx context.Context) error {
+	if !d.VolumeExists() {
+		return fmt.Errorf("No volume information exists for %s", d.name)
+	}
+	if d.InstanceExists() {
+		return fmt.Errorf("The volumes of %s are in use by a running instance", d.name)
+	}
+	return os.Remove(d.volumesPath())
+}
+
+func (d *exampleDeployment) StatusVolumeSet(ctx context.Context) error {
+	var v exampleVolumes
+	err := sdutils.LoadJSON(&v, d.volumesPath())
+	if err != nil {
+		return fmt.Errorf("No volume information exists for %s", d.name)
+	}
+	d.ctx.ConsoleLog(0, "%d volumes of %d GB\n", v.ClusterSize, v.VolumeSize)
+	return nil
+}
+
+func (d *exampleDeployment) VolumeExists() bool {
+	return sdutils.PathExists(d.volumesPath())
+}
+
+func (d *exampleDeployment) ClusterSize() (int, error) {
+	var v exampleVolumes
+	err := sdutils.LoadJSON(&v, d.volumesPath())
+	if err != nil {
+		return -1, fmt.Errorf("No volume information exists for %s", d.name)
+	}
+	return v.ClusterSize, nil
+}
+
+func (d *exampleDeployment) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	if !d.VolumeExists() {
+		return fmt.Errorf("The volumes for %s must be created first", d.name)
+	}
+	if d.InstanceExists() {
+		return fmt.Errorf("The instance for %s already exists", d.name)
+	}
+	d.ctx.ConsoleLog(1, "Starting %d ZooKeeper nodes\n", zookeeperSize)
+	return sdutils.WriteJSON(&exampleInstance{ZookeeperSize: zookeeperSize, Mask: "0.0.0.0/32"}, d.instancePath())
+}
+
+func (d *exampleDeployment) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	var i exampleInstance
+	err := sdutils.LoadJSON(&i, d.instancePath())
+	if err != nil {
+		return fmt.Errorf("No instance exists for %s", d.name)
+	}
+	i.Mask = mask
+	return sdutils.WriteJSON(&i, d.instancePath())
+}
+
+func (d *exampleDeployment) DeleteInstance(ctx context.Context) error {
+	if !d.InstanceExists() {
+		return fmt.Errorf("No instance exists for %s", d.name)
+	}
+	return os.Remove(d.instancePath())
+}
+
+func (d *exampleDeployment) StatusInstance(ctx context.Context) error {
+	var i exampleInstance
+	err := sdutils.LoadJSON(&i, d.instancePath())
+	if err != nil {
+		return fmt.Errorf("No instance exists for %s", d.name)
+	}
+	d.ctx.ConsoleLog(0, "%d ZooKeeper nodes open to %s\n", i.ZookeeperSize, i.Mask)
+	return nil
+}
+
+func (d *exampleDeployment) InstanceExists() bool {
+	return sdutils.PathExists(d.instancePath())
+}
+
+func (d *exampleDeployment) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	var v exampleVolumes
+	var i exampleInstance
+	if err := sdutils.LoadJSON(&v, d.volumesPath()); err != nil {
+		d.ctx.ConsoleLog(1, "No volume information found\n")
+	}
+	if err := sdutils.LoadJSON(&i, d.instancePath()); err != nil {
+		return nil, fmt.Errorf("No instance exists for %s", d.name)
+	}
+	sd := sdutils.StardogDescription{
+		StardogURL:          d.URL,
+		StardogInternalURL:  d.URL,
+		VolumeDescription:   &v,
+		InstanceDescription: &i,
+		TimeStamp:           time.Now(),
+	}
+	return &sd, nil
+}
+
+func (d *exampleDeployment) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func main() {
+	err := rpcplugin.Serve(&examplePlugin{URL: "http://localhost:5821"})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
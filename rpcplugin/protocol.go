@@ -0,0 +1,207 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcplugin
+
+import (
+	"encoding/json"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// ProtocolVersion is sent by graviton with every Describe call.  A plugin
+// must refuse to serve a version that it does not understand.
+const ProtocolVersion = 1
+
+// ExecutablePrefix is the file name prefix that graviton looks for when
+// searching for plugin executables.  The rest of the file name is the
+// cloud type, so graviton-plugin-openstack is selected with --cloud openstack.
+const ExecutablePrefix = "graviton-plugin-"
+
+// The methods a plugin must answer.  They mirror sdutils.Plugin and
+// sdutils.Deployment one to one.
+const (
+	MethodDescribe         = "Plugin.Describe"
+	MethodLoadDefaults     = "Plugin.LoadDefaults"
+	MethodDeploymentLoader = "Plugin.DeploymentLoader"
+	MethodBuildImage       = "Plugin.BuildImage"
+	MethodFindLeaks        = "Plugin.FindLeaks"
+	MethodHaveImage        = "Plugin.HaveImage"
+
+	MethodCreateVolumeSet   = "Deployment.CreateVolumeSet"
+	MethodDeleteVolumeSet   = "Deployment.DeleteVolumeSet"
+	MethodStatusVolumeSet   = "Deployment.StatusVolumeSet"
+	MethodVolumeExists      = "Deployment.VolumeExists"
+	MethodClusterSize       = "Deployment.ClusterSize"
+	MethodCreateInstance    = "Deployment.CreateInstance"
+	MethodOpenInstance      = "Deployment.OpenInstance"
+	MethodDeleteInstance    = "Deployment.DeleteInstance"
+	MethodStatusInstance    = "Deployment.StatusInstance"
+	MethodInstanceExists    = "Deployment.InstanceExists"
+	MethodFullStatus        = "Deployment.FullStatus"
+	MethodDestroyDeployment = "Deployment.DestroyDeployment"
+	MethodGatherLogs        = "Deployment.GatherLogs"
+	MethodRemoteCommand     = "Deployment.RemoteCommand"
+)
+
+// The notifications a plugin may send to graviton while it is handling a
+// request.  They carry the AppContext logging calls across the pipe.
+const (
+	NotifyConsoleLog = "Context.ConsoleLog"
+	NotifyLogf       = "Context.Logf"
+)
+
+// Message is a single line on the pipe.  Graviton writes requests with a
+// non zero ID to the plugins stdin.  The plugin answers each request with
+// exactly one message carrying the same ID and either Result or Error.
+// Before answering it may write any number of notifications, which have
+// a Method but no ID.  When the user interrupts graviton the plugin is sent
+// SIGINT and should answer the request it is working on with an error.
+type Message struct {
+	ID      int               `json:"id,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Context *ContextInfo      `json:"context,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+	Params  json.RawMessage   `json:"params,omitempty"`
+	Result  json.RawMessage   `json:"result,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// ContextInfo is the static part of sdutils.AppContext.  It is sent with
+// every request so that a plugin can build an AppContext of its own.
+type ContextInfo struct {
+	ConfigDir string `json:"config_dir,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+// LogParams are the parameters of the logging notifications.
+type LogParams struct {
+	Level   int    `json:"level"`
+	Message string `json:"message"`
+}
+
+// FlagSpec describes a single command line flag that a plugin adds to one of
+// the graviton commands.
+type FlagSpec struct {
+	Command string `json:"command"`
+	Name    string `json:"name"`
+	Help    string `json:"help,omitempty"`
+	Default string `json:"default,omitempty"`
+	Bool    bool   `json:"bool,omitempty"`
+}
+
+// DescribeResult is the answer to Plugin.Describe.
+type DescribeResult struct {
+	Name            string               `json:"name"`
+	ProtocolVersion int                  `json:"protocol_version"`
+	Flags           []FlagSpec           `json:"flags,omitempty"`
+	Capabilities    sdutils.Capabilities `json:"capabilities"`
+}
+
+// Params holds the arguments of every method.  Each method only reads the
+// fields that match the arguments of the Go interface it mirrors.
+type Params struct {
+	ProtocolVersion int                     `json:"protocol_version,omitempty"`
+	Defaults        interface{}             `json:"defaults,omitempty"`
+	Base            *sdutils.BaseDeployment `json:"base,omitempty"`
+	New             bool                    `json:"new,omitempty"`
+	ReleaseFile     string                  `json:"release_file,omitempty"`
+	Version         string                  `json:"version,omitempty"`
+	DeploymentName  string                  `json:"deployment_name,omitempty"`
+	Destroy         bool                    `json:"destroy,omitempty"`
+	Force           bool                    `json:"force,omitempty"`
+	LicensePath     string                  `json:"license_path,omitempty"`
+	VolumeSize      int                     `json:"volume_size,omitempty"`
+	ClusterSize     int                     `json:"cluster_size,omitempty"`
+	ZookeeperSize   int                     `json:"zookeeper_size,omitempty"`
+	IdleTimeout     int                     `json:"idle_timeout,omitempty"`
+	Mask            string                  `json:"mask,omitempty"`
+	Outfile         string                  `json:"outfile,omitempty"`
+	Interactive     bool                    `json:"interactive,omitempty"`
+}
+
+// Result holds the return values of every method.  Methods that only return
+// an error leave it empty.
+type Result struct {
+	Bool        bool                        `json:"bool,omitempty"`
+	Int         int                         `json:"int,omitempty"`
+	Base        *sdutils.BaseDeployment     `json:"base,omitempty"`
+	Description *sdutils.StardogDescription `json:"description,omitempty"`
+	Command     []string                    `json:"command,omitempty"`
+	GatherLogs  bool                        `json:"gather_logs,omitempty"`
+	Remote      bool                        `json:"remote_command,omitempty"`
+}
+
+// commandClauses names the commands that a plugin may add flags to.  The
+// names are part of the protocol.
+func commandClauses(cmdOpts *sdutils.CommandOpts) map[string]**kingpin.CmdClause {
+	return map[string]**kingpin.CmdClause{
+		"launch":                 &cmdOpts.LaunchCmd,
+		"destroy":                &cmdOpts.DestroyCmd,
+		"status":                 &cmdOpts.StatusCmd,
+		"leaks":                  &cmdOpts.LeaksCmd,
+		"pause":                  &cmdOpts.PauseCmd,
+		"resume":                 &cmdOpts.ResumeCmd,
+		"client":                 &cmdOpts.ClientCmd,
+		"ssh":                    &cmdOpts.SSHCmd,
+		"baseami":                &cmdOpts.BuildCmd,
+		"image list":             &cmdOpts.ListImagesCmd,
+		"image show":             &cmdOpts.ShowImageCmd,
+		"image delete":           &cmdOpts.DeleteImageCmd,
+		"image copy":             &cmdOpts.CopyImageCmd,
+		"deployment new":         &cmdOpts.NewDeploymentCmd,
+		"deployment destroy":     &cmdOpts.DestroyDeploymentCmd,
+		"deployment scale":       &cmdOpts.ScaleCmd,
+		"deployment upgrade":     &cmdOpts.UpgradeCmd,
+		"volume new":             &cmdOpts.NewVolumesCmd,
+		"volume destroy":         &cmdOpts.DestroyVolumesCmd,
+		"volume status":          &cmdOpts.StatusVolumesCmd,
+		"volume modify":          &cmdOpts.ModifyVolumesCmd,
+		"volume snapshot":        &cmdOpts.SnapshotVolumesCmd,
+		"volume snapshots":       &cmdOpts.ListSnapshotsCmd,
+		"volume prune-snapshots": &cmdOpts.PruneSnapshotsCmd,
+		"volume restore":         &cmdOpts.RestoreVolumesCmd,
+		"backup new":             &cmdOpts.NewBackupCmd,
+		"backup list":            &cmdOpts.ListBackupsCmd,
+		"backup restore":         &cmdOpts.RestoreBackupCmd,
+		"db list":                &cmdOpts.ListDatabasesCmd,
+		"db create":              &cmdOpts.CreateDatabaseCmd,
+		"db drop":                &cmdOpts.DropDatabaseCmd,
+		"db options":             &cmdOpts.DatabaseOptionsCmd,
+		"db online":              &cmdOpts.OnlineDatabaseCmd,
+		"db offline":             &cmdOpts.OfflineDatabaseCmd,
+		"query":                  &cmdOpts.QueryCmd,
+		"load":                   &cmdOpts.LoadCmd,
+		"user list":              &cmdOpts.ListUsersCmd,
+		"user add":               &cmdOpts.AddUserCmd,
+		"user remove":            &cmdOpts.RemoveUserCmd,
+		"user passwd":            &cmdOpts.PasswdCmd,
+		"role list":              &cmdOpts.ListRolesCmd,
+		"role add":               &cmdOpts.AddRoleCmd,
+		"role remove":            &cmdOpts.RemoveRoleCmd,
+		"role grant":             &cmdOpts.GrantRoleCmd,
+		"role revoke":            &cmdOpts.RevokeRoleCmd,
+		"permission list":        &cmdOpts.ListPermissionsCmd,
+		"permission grant":       &cmdOpts.GrantPermissionCmd,
+		"permission revoke":      &cmdOpts.RevokePermissionCmd,
+		"permission export":      &cmdOpts.ExportPermissionsCmd,
+		"permission import":      &cmdOpts.ImportPermissionsCmd,
+		"instance new":           &cmdOpts.LaunchInstanceCmd,
+		"instance destroy":       &cmdOpts.DestroyInstanceCmd,
+		"instance status":        &cmdOpts.StatusInstanceCmd,
+		"instance resize":        &cmdOpts.ResizeInstanceCmd,
+	}
+}
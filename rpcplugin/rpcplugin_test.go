@@ -0,0 +1,356 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcplugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type recordContext struct {
+	sdutils.TestContext
+	console []string
+	logs    []string
+}
+
+func (c *recordContext) ConsoleLog(level int, format string, v ...interface{}) {
+	c.console = append(c.console, fmt.Sprintf(format, v...))
+}
+
+func (c *recordContext) Logf(level int, format string, v ...interface{}) {
+	c.logs = append(c.logs, fmt.Sprintf(format, v...))
+}
+
+type pipePlugin struct {
+	Region string
+	Fast   bool
+}
+
+func (p *pipePlugin) Register(cmdOpts *sdutils.CommandOpts) error {
+	cmdOpts.LaunchCmd.Flag("pipe-region", "The region.").Default("north").StringVar(&p.Region)
+	cmdOpts.LaunchCmd.Flag("pipe-fast", "Go fast.").BoolVar(&p.Fast)
+	cmdOpts.NewDeploymentCmd.Flag("pipe-region", "The region.").Default("north").StringVar(&p.Region)
+	return nil
+}
+
+func (p *pipePlugin) DeploymentLoader(ctx context.Context, context sdutils.AppContext, baseD *sdutils.BaseDeployment, new bool) (sdutils.Deployment, error) {
+	if new {
+		baseD.CloudOpts = map[string]string{"region": p.Region}
+	}
+	opts, ok := baseD.CloudOpts.(map[string]interface{})
+	if !new && (!ok || opts["region"] == nil) {
+		return nil, fmt.Errorf("The deployment %s has no region", baseD.Name)
+	}
+	return &pipeDeployment{ctx: context, name: baseD.Name}, nil
+}
+
+func (p *pipePlugin) LoadDefaults(defaultCliOpts interface{}) error {
+	return nil
+}
+
+func (p *pipePlugin) BuildImage(ctx context.Context, context sdutils.AppContext, sdReleaseFilePath string, version string) error {
+	return fmt.Errorf("No image for %s", version)
+}
+
+func (p *pipePlugin) GetName() string {
+	return "pipe"
+}
+
+func (p *pipePlugin) FindLeaks(ctx context.Context, context sdutils.AppContext, deploymentName string, destroy bool, force bool) error {
+	return nil
+}
+
+func (p *pipePlugin) Capabilities() sdutils.Capabilities {
+	return sdutils.Capabilities{Volumes: true, Scaling: true}
+}
+
+func (p *pipePlugin) HaveImage(ctx context.Context, context sdutils.AppContext) bool {
+	context.ConsoleLog(1, "Looking in %s for %s\n", p.Region, context.GetVersion())
+	context.Logf(sdutils.DEBUG, "fast %t", p.Fast)
+	return p.Fast
+}
+
+type pipeDeployment struct {
+	ctx  sdutils.AppContext
+	name string
+}
+
+func (d *pipeDeployment) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
+	return nil
+}
+
+func (d *pipeDeployment) DeleteVolumeSet(ctx context.Context) error {
+	return fmt.Errorf("The volumes of %s are busy", d.name)
+}
+
+func (d *pipeDeployment) StatusVolumeSet(ctx context.Context) error {
+	return nil
+}
+
+func (d *pipeDeployment) VolumeExists() bool {
+	return true
+}
+
+func (d *pipeDeployment) ClusterSize() (int, error) {
+	return 5, nil
+}
+
+func (d *pipeDeployment) CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error {
+	return nil
+}
+
+func (d *pipeDeployment) OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error {
+	return nil
+}
+
+func (d *pipeDeployment) DeleteInstance(ctx context.Context) error {
+	return nil
+}
+
+func (d *pipeDeployment) StatusInstance(ctx context.Context) error {
+	if d.name == "slow" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (d *pipeDeployment) InstanceExists() bool {
+	return false
+}
+
+func (d *pipeDeployment) FullStatus(ctx context.Context) (*sdutils.StardogDescription, error) {
+	return &sdutils.StardogDescription{StardogURL: "http://" + d.name}, nil
+}
+
+func (d *pipeDeployment) DestroyDeployment(ctx context.Context) error {
+	return nil
+}
+
+func (d *pipeDeployment) GatherLogs(ctx context.Context, outfile string) error {
+	return ioutil.WriteFile(outfile, []byte(d.name), 0644)
+}
+
+func startPipePlugin() *Plugin {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	go func() {
+		ServeConn(&pipePlugin{}, reqR, respW)
+		respW.Close()
+	}()
+	return newConnPlugin("pipe", respR, reqW)
+}
+
+func testCommandOpts() (*kingpin.Application, *sdutils.CommandOpts) {
+	app := kingpin.New("test", "")
+	cmdOpts := &sdutils.CommandOpts{Cli: app}
+	cmdOpts.LaunchCmd = app.Command("launch", "")
+	deployCmd := app.Command("deployment", "")
+	cmdOpts.NewDeploymentCmd = deployCmd.Command("new", "")
+	return app, cmdOpts
+}
+
+func TestRegisterForwardsFlags(t *testing.T) {
+	p := startPipePlugin()
+	defer p.Close()
+
+	app, cmdOpts := testCommandOpts()
+	err := p.Register(cmdOpts)
+	if err != nil {
+		t.Fatalf("Register failed %s", err)
+	}
+	caps := p.Capabilities()
+	if !caps.Volumes || !caps.Scaling || caps.Images || caps.Firewall || caps.Snapshots {
+		t.Fatalf("The capabilities were not described %v", caps)
+	}
+	ctx := &recordContext{TestContext: sdutils.TestContext{Version: "5.0.0"}}
+	if p.HaveImage(context.Background(), ctx) {
+		t.Fatal("The bool flag should default to false")
+	}
+	_, err = app.Parse([]string{"launch", "--pipe-region", "south", "--pipe-fast"})
+	if err != nil {
+		t.Fatalf("Parse failed %s", err)
+	}
+	if !p.HaveImage(context.Background(), ctx) {
+		t.Fatal("The bool flag was not forwarded")
+	}
+	if len(ctx.console) != 2 || ctx.console[1] != "Looking in south for 5.0.0\n" {
+		t.Fatalf("The console output was not forwarded %v", ctx.console)
+	}
+	if len(ctx.logs) != 2 || ctx.logs[1] != "fast true" {
+		t.Fatalf("The log output was not forwarded %v", ctx.logs)
+	}
+}
+
+func TestRegisterWrongName(t *testing.T) {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	go ServeConn(&pipePlugin{}, reqR, respW)
+	p := newConnPlugin("other", respR, reqW)
+	defer p.Close()
+
+	_, cmdOpts := testCommandOpts()
+	err := p.Register(cmdOpts)
+	if err == nil || !strings.Contains(err.Error(), "describes itself as pipe") {
+		t.Fatalf("A mismatched name should fail %v", err)
+	}
+}
+
+func TestDeploymentRoundTrip(t *testing.T) {
+	p := startPipePlugin()
+	defer p.Close()
+	ctx := &recordContext{}
+
+	baseD := sdutils.BaseDeployment{Name: "dep1", Type: "pipe"}
+	d, err := p.DeploymentLoader(context.Background(), ctx, &baseD, true)
+	if err != nil {
+		t.Fatalf("DeploymentLoader failed %s", err)
+	}
+	opts, ok := baseD.CloudOpts.(map[string]interface{})
+	if !ok || opts["region"] != "north" {
+		t.Fatalf("The cloud options were not returned %v", baseD.CloudOpts)
+	}
+	if !d.VolumeExists() || d.InstanceExists() {
+		t.Fatal("The booleans were not returned")
+	}
+	size, err := d.ClusterSize()
+	if err != nil || size != 5 {
+		t.Fatalf("The cluster size was wrong %d %v", size, err)
+	}
+	sd, err := d.FullStatus(context.Background())
+	if err != nil || sd.StardogURL != "http://dep1" {
+		t.Fatalf("The status was wrong %v %v", sd, err)
+	}
+	err = d.DeleteVolumeSet(context.Background())
+	if err == nil || err.Error() != "The volumes of dep1 are busy" {
+		t.Fatalf("The error was not returned %v", err)
+	}
+
+	if _, ok := d.(sdutils.RemoteRunner); ok {
+		t.Fatal("The deployment does not run remote commands")
+	}
+	lg, ok := d.(sdutils.LogGatherer)
+	if !ok {
+		t.Fatal("The deployment gathers logs")
+	}
+	dir, err := ioutil.TempDir("", "rpcplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outfile := path.Join(dir, "logs")
+	err = lg.GatherLogs(context.Background(), outfile)
+	if err != nil {
+		t.Fatalf("GatherLogs failed %s", err)
+	}
+	if b, _ := ioutil.ReadFile(outfile); string(b) != "dep1" {
+		t.Fatalf("The logs were wrong %s", string(b))
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	p := startPipePlugin()
+	defer p.Close()
+
+	err := p.call(context.Background(), nil, "Plugin.Bogus", &Params{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "Plugin.Bogus") {
+		t.Fatalf("An unknown method should fail %v", err)
+	}
+	err = p.call(context.Background(), nil, MethodDescribe, &Params{ProtocolVersion: ProtocolVersion + 1}, nil)
+	if err == nil {
+		t.Fatal("An unknown protocol version should fail")
+	}
+	err = p.BuildImage(context.Background(), &recordContext{}, "/no/file", "1.0")
+	if err == nil || err.Error() != "No image for 1.0" {
+		t.Fatalf("The error was not returned %v", err)
+	}
+}
+
+func TestInterruptCancelsRequest(t *testing.T) {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	s, err := newServer(&pipePlugin{}, respW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		s.serve(reqR)
+		respW.Close()
+	}()
+	p := newConnPlugin("pipe", respR, reqW)
+	p.stop = s.interrupt
+	defer p.Close()
+
+	baseD := sdutils.BaseDeployment{Name: "slow", Type: "pipe"}
+	d, err := p.DeploymentLoader(context.Background(), &recordContext{}, &baseD, true)
+	if err != nil {
+		t.Fatalf("DeploymentLoader failed %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	err = d.StatusInstance(ctx)
+	if _, ok := err.(*sdutils.InterruptedError); !ok {
+		t.Fatalf("The request should have been interrupted %v", err)
+	}
+	err = d.StatusVolumeSet(context.Background())
+	if err != nil {
+		t.Fatalf("The plugin should keep serving after an interrupt %s", err)
+	}
+	err = d.StatusInstance(ctx)
+	if _, ok := err.(*sdutils.InterruptedError); !ok {
+		t.Fatalf("A cancelled context should not be sent %v", err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir1, err := ioutil.TempDir("", "rpcplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir1)
+	dir2, err := ioutil.TempDir("", "rpcplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+
+	ioutil.WriteFile(path.Join(dir1, "graviton-plugin-one"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(path.Join(dir1, "graviton-plugin-notexec"), []byte("#!/bin/sh\n"), 0644)
+	ioutil.WriteFile(path.Join(dir1, "something-else"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(path.Join(dir2, "graviton-plugin-one"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(path.Join(dir2, "graviton-plugin-two"), []byte("#!/bin/sh\n"), 0755)
+
+	plugins := Discover([]string{dir1, path.Join(dir1, "missing"), dir2})
+	if len(plugins) != 2 {
+		t.Fatalf("Expected 2 plugins but found %d", len(plugins))
+	}
+	if plugins[0].GetName() != "one" || plugins[0].path != path.Join(dir1, "graviton-plugin-one") {
+		t.Fatalf("The first directory should win %s %s", plugins[0].GetName(), plugins[0].path)
+	}
+	if plugins[1].GetName() != "two" {
+		t.Fatalf("The second plugin is wrong %s", plugins[1].GetName())
+	}
+}
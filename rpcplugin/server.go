@@ -0,0 +1,351 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcplugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/stardog-union/stardog-graviton/sdutils"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Serve runs a plugin on stdin and stdout until graviton closes the pipe.
+// It is the whole main function of a plugin written in Go.  Anything the
+// plugin writes to os.Stdout afterwards is sent to stderr instead so that
+// it cannot corrupt the protocol.  An interrupt cancels the context of the
+// request that is being served instead of killing the plugin.
+func Serve(p sdutils.Plugin) error {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	s, err := newServer(p, out)
+	if err != nil {
+		return err
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+	go func() {
+		for range sigs {
+			s.interrupt()
+		}
+	}()
+	return s.serve(os.Stdin)
+}
+
+// ServeConn runs a plugin on the given reader and writer.
+func ServeConn(p sdutils.Plugin, r io.Reader, w io.Writer) error {
+	s, err := newServer(p, w)
+	if err != nil {
+		return err
+	}
+	return s.serve(r)
+}
+
+func (s *server) serve(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var msg Message
+		err := json.Unmarshal(line, &msg)
+		if err != nil {
+			s.write(&Message{Error: fmt.Sprintf("The request could not be parsed: %s", err)})
+			continue
+		}
+		s.handle(&msg)
+	}
+	return scanner.Err()
+}
+
+type server struct {
+	plugin sdutils.Plugin
+	app    *kingpin.Application
+	flags  map[string][]*kingpin.FlagModel
+	specs  []FlagSpec
+	w      io.Writer
+	cancel context.CancelFunc
+	lock   sync.Mutex
+}
+
+func newServer(p sdutils.Plugin, w io.Writer) (*server, error) {
+	s := &server{
+		plugin: p,
+		app:    kingpin.New(p.GetName(), ""),
+		flags:  make(map[string][]*kingpin.FlagModel),
+		w:      w,
+	}
+	cmdOpts := sdutils.CommandOpts{Cli: s.app}
+	clauses := commandClauses(&cmdOpts)
+	names := []string{}
+	for name, clause := range clauses {
+		*clause = s.app.Command(name, "")
+		names = append(names, name)
+	}
+	err := p.Register(&cmdOpts)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, f := range (*clauses[name]).Model().Flags {
+			if f.Name == "help" {
+				continue
+			}
+			spec := FlagSpec{
+				Command: name,
+				Name:    f.Name,
+				Help:    f.Help,
+				Bool:    f.IsBoolFlag(),
+			}
+			if len(f.Default) > 0 {
+				spec.Default = f.Default[0]
+				f.Value.Set(spec.Default)
+			}
+			s.specs = append(s.specs, spec)
+			s.flags[f.Name] = append(s.flags[f.Name], f)
+		}
+	}
+	return s, nil
+}
+
+func (s *server) write(msg *Message) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		b, _ = json.Marshal(&Message{ID: msg.ID, Error: err.Error()})
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.w.Write(append(b, '\n'))
+}
+
+func (s *server) notify(method string, level int, format string, v ...interface{}) {
+	params, _ := json.Marshal(&LogParams{Level: level, Message: fmt.Sprintf(format, v...)})
+	s.write(&Message{Method: method, Params: params})
+}
+
+func (s *server) applyOptions(options map[string]string) error {
+	for name, value := range options {
+		for _, f := range s.flags[name] {
+			if value == "" {
+				continue
+			}
+			err := f.Value.Set(value)
+			if err != nil {
+				return fmt.Errorf("The value %s is not valid for the flag %s: %s", value, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// interrupt cancels the request that is being handled, if there is one.
+func (s *server) interrupt() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+func (s *server) handle(msg *Message) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.lock.Lock()
+	s.cancel = cancel
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		s.cancel = nil
+		s.lock.Unlock()
+		cancel()
+	}()
+
+	reply := &Message{ID: msg.ID}
+	result, err := s.dispatch(ctx, msg)
+	if err != nil {
+		reply.Error = err.Error()
+	} else if result != nil {
+		reply.Result, err = json.Marshal(result)
+		if err != nil {
+			reply.Error = err.Error()
+		}
+	}
+	s.write(reply)
+}
+
+func (s *server) dispatch(ctx context.Context, msg *Message) (interface{}, error) {
+	if msg.ID == 0 {
+		return nil, fmt.Errorf("The request has no id")
+	}
+	var params Params
+	if len(msg.Params) > 0 {
+		err := json.Unmarshal(msg.Params, &params)
+		if err != nil {
+			return nil, fmt.Errorf("The parameters of %s could not be parsed: %s", msg.Method, err)
+		}
+	}
+	err := s.applyOptions(msg.Options)
+	if err != nil {
+		return nil, err
+	}
+	app := &remoteContext{server: s}
+	if msg.Context != nil {
+		app.info = *msg.Context
+	}
+
+	switch msg.Method {
+	case MethodDescribe:
+		if params.ProtocolVersion != ProtocolVersion {
+			return nil, fmt.Errorf("The protocol version %d is not supported", params.ProtocolVersion)
+		}
+		return &DescribeResult{
+			Name:            s.plugin.GetName(),
+			ProtocolVersion: ProtocolVersion,
+			Flags:           s.specs,
+			Capabilities:    s.plugin.Capabilities(),
+		}, nil
+	case MethodLoadDefaults:
+		return nil, s.plugin.LoadDefaults(params.Defaults)
+	case MethodBuildImage:
+		return nil, s.plugin.BuildImage(ctx, app, params.ReleaseFile, params.Version)
+	case MethodFindLeaks:
+		return nil, s.plugin.FindLeaks(ctx, app, params.DeploymentName, params.Destroy, params.Force)
+	case MethodHaveImage:
+		return &Result{Bool: s.plugin.HaveImage(ctx, app)}, nil
+	}
+
+	if params.Base == nil {
+		return nil, fmt.Errorf("The method %s is not known", msg.Method)
+	}
+	if msg.Method == MethodDeploymentLoader {
+		d, err := s.plugin.DeploymentLoader(ctx, app, params.Base, params.New)
+		if err != nil {
+			return nil, err
+		}
+		_, gatherer := d.(sdutils.LogGatherer)
+		_, runner := d.(sdutils.RemoteRunner)
+		return &Result{Base: params.Base, GatherLogs: gatherer, Remote: runner}, nil
+	}
+	d, err := s.plugin.DeploymentLoader(ctx, app, params.Base, false)
+	if err != nil {
+		return nil, err
+	}
+	return callDeployment(ctx, d, msg.Method, &params)
+}
+
+func callDeployment(ctx context.Context, d sdutils.Deployment, method string, params *Params) (*Result, error) {
+	var err error
+	result := &Result{}
+	switch method {
+	case MethodCreateVolumeSet:
+		err = d.CreateVolumeSet(ctx, params.LicensePath, params.VolumeSize, params.ClusterSize)
+	case MethodDeleteVolumeSet:
+		err = d.DeleteVolumeSet(ctx)
+	case MethodStatusVolumeSet:
+		err = d.StatusVolumeSet(ctx)
+	case MethodVolumeExists:
+		result.Bool = d.VolumeExists()
+	case MethodClusterSize:
+		result.Int, err = d.ClusterSize()
+	case MethodCreateInstance:
+		err = d.CreateInstance(ctx, params.VolumeSize, params.ZookeeperSize, params.IdleTimeout)
+	case MethodOpenInstance:
+		err = d.OpenInstance(ctx, params.VolumeSize, params.ZookeeperSize, params.Mask, params.IdleTimeout)
+	case MethodDeleteInstance:
+		err = d.DeleteInstance(ctx)
+	case MethodStatusInstance:
+		err = d.StatusInstance(ctx)
+	case MethodInstanceExists:
+		result.Bool = d.InstanceExists()
+	case MethodFullStatus:
+		result.Description, err = d.FullStatus(ctx)
+	case MethodDestroyDeployment:
+		err = d.DestroyDeployment(ctx)
+	case MethodGatherLogs:
+		lg, ok := d.(sdutils.LogGatherer)
+		if !ok {
+			return nil, fmt.Errorf("The deployment cannot gather logs")
+		}
+		err = lg.GatherLogs(ctx, params.Outfile)
+	case MethodRemoteCommand:
+		rr, ok := d.(sdutils.RemoteRunner)
+		if !ok {
+			return nil, fmt.Errorf("The deployment cannot run remote commands")
+		}
+		result.Command, err = rr.RemoteCommand(params.Interactive)
+	default:
+		return nil, fmt.Errorf("The method %s is not known", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// remoteContext is the AppContext handed to a plugin.  Logging is sent back
+// to graviton.  The plugin can never prompt the user because stdin is the
+// protocol pipe, so it always reports that it is not interactive.
+type remoteContext struct {
+	server *server
+	info   ContextInfo
+}
+
+func (c *remoteContext) ConsoleLog(level int, format string, v ...interface{}) {
+	c.server.notify(NotifyConsoleLog, level, format, v...)
+}
+
+func (c *remoteContext) Logf(level int, format string, v ...interface{}) {
+	c.server.notify(NotifyLogf, level, format, v...)
+}
+
+func (c *remoteContext) GetConfigDir() string {
+	return c.info.ConfigDir
+}
+
+func (c *remoteContext) GetVersion() string {
+	return c.info.Version
+}
+
+func (c *remoteContext) GetInteractive() bool {
+	return false
+}
+
+func (c *remoteContext) Prompt(prompt string, defaultValue string) (string, error) {
+	return "", fmt.Errorf("A plugin cannot ask for '%s', it must be configured", prompt)
+}
+
+func (c *remoteContext) HighlightString(a ...interface{}) string {
+	return fmt.Sprint(a...)
+}
+
+func (c *remoteContext) SuccessString(a ...interface{}) string {
+	return fmt.Sprint(a...)
+}
+
+func (c *remoteContext) FailString(a ...interface{}) string {
+	return fmt.Sprint(a...)
+}
@@ -25,6 +25,10 @@ echo $v-$t > etc/version
 export PATH=$GOPATH/bin:$PATH
 
 go-bindata -prefix aws -o aws/data.go -pkg aws aws/etc/...
+go-bindata -prefix docker -o docker/data.go -pkg docker docker/etc/...
+go-bindata -prefix kubernetes -o kubernetes/data.go -pkg kubernetes kubernetes/etc/...
+go-bindata -prefix gcp -o gcp/data.go -pkg gcp gcp/etc/...
+go-bindata -prefix azure -o azure/data.go -pkg azure azure/etc/...
 go-bindata -o data.go -pkg main etc/...
 
 go install github.com/stardog-union/stardog-graviton
@@ -0,0 +1,349 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupDir is the directory that holds the backup catalog of a deployment
+// and, unless another destination is given, its backups.  It is outside of the
+// deployment directory so that the backups outlive the deployment.
+func BackupDir(configDir string, deploymentName string) string {
+	return filepath.Join(configDir, "backups", deploymentName)
+}
+
+// backupCatalog lists the backups of one deployment wherever they are stored.
+type backupCatalog struct {
+	Backups []BackupDescription `json:"backups"`
+	dir     string
+}
+
+func loadBackupCatalog(dir string) (*backupCatalog, error) {
+	bc := &backupCatalog{dir: dir}
+	p := filepath.Join(dir, "catalog.json")
+	if !PathExists(p) {
+		return bc, nil
+	}
+	err := LoadJSON(bc, p)
+	if err != nil {
+		return nil, fmt.Errorf("The backup catalog %s could not be read: %s", p, err)
+	}
+	return bc, nil
+}
+
+func (bc *backupCatalog) save() error {
+	err := os.MkdirAll(bc.dir, 0755)
+	if err != nil {
+		return err
+	}
+	sort.Slice(bc.Backups, func(i, j int) bool { return bc.Backups[i].Created.Before(bc.Backups[j].Created) })
+	return WriteJSON(bc, filepath.Join(bc.dir, "catalog.json"))
+}
+
+func (bc *backupCatalog) get(id string) *BackupDescription {
+	for i := range bc.Backups {
+		if bc.Backups[i].ID == id {
+			return &bc.Backups[i]
+		}
+	}
+	return nil
+}
+
+// ListBackups returns the backups in the catalog of a deployment, oldest
+// first.
+func ListBackups(configDir string, deploymentName string) ([]BackupDescription, error) {
+	bc, err := loadBackupCatalog(BackupDir(configDir, deploymentName))
+	if err != nil {
+		return nil, err
+	}
+	return bc.Backups, nil
+}
+
+func isObjectURL(location string) bool {
+	return strings.Contains(location, "://")
+}
+
+func objectStore(baseD *BaseDeployment, dep Deployment, location string) (ObjectStore, error) {
+	store, ok := dep.(ObjectStore)
+	if !ok || !strings.HasPrefix(location, store.Scheme()+"://") {
+		return nil, fmt.Errorf("The cloud type %s cannot store backups at %s", baseD.Type, location)
+	}
+	return store, nil
+}
+
+// bastionCommand returns the ssh command that runs program on the bastion
+// node.  The bastion reaches the Stardog nodes with the forwarded agent.
+func bastionCommand(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, program string) ([]string, *StardogDescription, error) {
+	sd, err := dep.FullStatus(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, remote := dep.(RemoteRunner); remote || sd.SSHHost == "" {
+		return nil, nil, fmt.Errorf("The deployment %s has no bastion node to run %s on", baseD.Name, program)
+	}
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		return nil, nil, fmt.Errorf("ssh-agent needs to be setup to run %s on the bastion node", program)
+	}
+	sshBase, err := getSSHCommand(context, baseD, sd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sshBase, sd, nil
+}
+
+// shellQuote quotes s for the shell that ssh runs the remote command in.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
+// withCredentials builds the remote command line of a program run on the
+// bastion node.  Every argument is quoted because ssh hands the command line
+// to a shell.  The password is not on the command line, bastionExec writes it
+// to the standard input of the program.  The user is only named when it is
+// not admin because images built before STARDOG_USER was read always use
+// admin.
+func withCredentials(creds Credentials, args ...string) []string {
+	cmd := []string{}
+	if creds.Username != "" && creds.Username != "admin" {
+		cmd = append(cmd, "STARDOG_USER="+shellQuote(creds.Username))
+	}
+	for _, a := range args {
+		cmd = append(cmd, shellQuote(a))
+	}
+	return cmd
+}
+
+// bastionExec returns the ssh command that runs a program on the bastion node
+// and passes it the password on its standard input.  No terminal is asked
+// for because it would echo the password into the output.
+func bastionExec(ctx context.Context, sshBase []string, creds Credentials, args ...string) *exec.Cmd {
+	sshCmd := []string{}
+	for _, a := range sshBase {
+		if a != "-t" {
+			sshCmd = append(sshCmd, a)
+		}
+	}
+	sshCmd = append(sshCmd, withCredentials(creds, args...)...)
+	cmd := exec.CommandContext(ctx, sshCmd[0], sshCmd[1:]...)
+	cmd.Stdin = strings.NewReader(creds.Password + "\n")
+	return cmd
+}
+
+// runOnBastion runs a command on the bastion node with the credentials.
+func runOnBastion(ctx context.Context, context AppContext, sshBase []string, creds Credentials, args ...string) error {
+	cmd := bastionExec(ctx, sshBase, creds, args...)
+	context.Logf(DEBUG, "Running on the bastion: %s", strings.Join(cmd.Args, " "))
+	o, err := cmd.CombinedOutput()
+	context.Logf(DEBUG, "Output: %s", string(o))
+	if err != nil {
+		return fmt.Errorf("%s failed on the bastion node: %s", args[0], err)
+	}
+	return nil
+}
+
+func removeOnBastion(ctx context.Context, context AppContext, sshBase []string, remote string) {
+	sshCmd := append(append([]string{}, sshBase...), "rm", "-f", remote)
+	err := exec.CommandContext(ctx, sshCmd[0], sshCmd[1:]...).Run()
+	if err != nil {
+		context.Logf(WARN, "Failed to remove %s from the bastion node: %s", remote, err)
+	}
+}
+
+// BackupDatabases runs stardog-admin db backup for each database in dbs, or
+// for every database when dbs is empty, and copies the backups to dest.  dest
+// is a local directory or an object store URL.  When it is empty the backups
+// go to BackupDir.  Every backup is added to the catalog of the deployment as
+// soon as it is copied.  The backups are made with the credentials.
+func BackupDatabases(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials, dbs []string, dest string) ([]BackupDescription, error) {
+	catalog, err := loadBackupCatalog(BackupDir(context.GetConfigDir(), baseD.Name))
+	if err != nil {
+		return nil, err
+	}
+	if dest == "" {
+		dest = catalog.dir
+	}
+	var store ObjectStore
+	if isObjectURL(dest) {
+		store, err = objectStore(baseD, dep, dest)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sshBase, sd, err := bastionCommand(ctx, context, baseD, dep, "stardog-backup-db")
+	if err != nil {
+		return nil, err
+	}
+	if len(dbs) == 0 {
+		client := NewStardogClient(context, sd.StardogURL, creds.Username, creds.Password)
+		dbs, err = client.ListDatabases(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(dbs) == 0 {
+			return nil, fmt.Errorf("The deployment %s has no databases to back up", baseD.Name)
+		}
+	}
+
+	backups := []BackupDescription{}
+	for _, db := range dbs {
+		b, err := backupDatabase(ctx, context, baseD, sd, sshBase, creds, store, dest, db)
+		if err != nil {
+			return backups, err
+		}
+		catalog.Backups = append(catalog.Backups, *b)
+		err = catalog.save()
+		if err != nil {
+			return backups, err
+		}
+		backups = append(backups, *b)
+		context.ConsoleLog(1, "The database %s was backed up as %s.\n", db, b.ID)
+	}
+	return backups, nil
+}
+
+// backupDatabase backs up a single database to dest.  The temporary copy made
+// for an object store is removed before it returns so that a backup of many
+// databases never holds more than one of them on local disk.
+func backupDatabase(ctx context.Context, context AppContext, baseD *BaseDeployment, sd *StardogDescription, sshBase []string, creds Credentials, store ObjectStore, dest string, db string) (*BackupDescription, error) {
+	now := time.Now().UTC()
+	b := &BackupDescription{
+		ID:         fmt.Sprintf("%s-%s", db, now.Format("20060102150405")),
+		Deployment: baseD.Name,
+		Database:   db,
+		Version:    baseD.Version,
+		Created:    now,
+	}
+	context.ConsoleLog(1, "Backing up the database %s...\n", db)
+	remote := fmt.Sprintf("/tmp/graviton-%s.tar.gz", b.ID)
+	err := runOnBastion(ctx, context, sshBase, creds, "stardog-backup-db", sd.StardogInternalURL, db, remote)
+	if err != nil {
+		return nil, err
+	}
+	local := filepath.Join(dest, b.ID+".tar.gz")
+	if store != nil {
+		tmp, err := ioutil.TempFile("", "graviton-backup")
+		if err != nil {
+			return nil, err
+		}
+		tmp.Close()
+		local = tmp.Name()
+		defer os.Remove(local)
+	} else {
+		err = os.MkdirAll(dest, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = runSCPCommand(ctx, context, baseD, sd, local, remote, false)
+	removeOnBastion(ctx, context, sshBase, remote)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to copy the backup of %s from the bastion node: %s", db, err)
+	}
+	fi, err := os.Stat(local)
+	if err != nil {
+		return nil, err
+	}
+	b.Size = fi.Size()
+	b.Location, err = filepath.Abs(local)
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		b.Location = strings.TrimSuffix(dest, "/") + "/" + b.ID + ".tar.gz"
+		err = store.UploadObject(ctx, local, b.Location)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// RestoreBackup restores the database db of the deployment from the backup id
+// in the catalog of the deployment from.  An existing database is only
+// replaced when overwrite is set.  The restore is made with the credentials.
+func RestoreBackup(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials, db string, id string, from string, overwrite bool) error {
+	if from == "" {
+		from = baseD.Name
+	}
+	catalog, err := loadBackupCatalog(BackupDir(context.GetConfigDir(), from))
+	if err != nil {
+		return err
+	}
+	b := catalog.get(id)
+	if b == nil {
+		return fmt.Errorf("The backup %s is not in the catalog of %s", id, from)
+	}
+	if b.Database != db {
+		return fmt.Errorf("The backup %s is of the database %s, not %s", id, b.Database, db)
+	}
+	if b.Version != "" && baseD.Version != "" {
+		err = CheckDataVersion(b.Version, baseD.Version, "")
+		if err != nil {
+			return err
+		}
+	}
+	local := b.Location
+	if isObjectURL(b.Location) {
+		store, err := objectStore(baseD, dep, b.Location)
+		if err != nil {
+			return err
+		}
+		tmp, err := ioutil.TempFile("", "graviton-backup")
+		if err != nil {
+			return err
+		}
+		tmp.Close()
+		local = tmp.Name()
+		defer os.Remove(local)
+		err = store.DownloadObject(ctx, b.Location, local)
+		if err != nil {
+			return err
+		}
+	} else if !PathExists(local) {
+		return fmt.Errorf("The backup file %s does not exist", local)
+	}
+	sshBase, sd, err := bastionCommand(ctx, context, baseD, dep, "stardog-restore-db")
+	if err != nil {
+		return err
+	}
+
+	context.ConsoleLog(1, "Restoring the database %s from %s...\n", db, id)
+	remote := fmt.Sprintf("/tmp/graviton-%s.tar.gz", b.ID)
+	err = runSCPCommand(ctx, context, baseD, sd, local, remote, true)
+	if err != nil {
+		return fmt.Errorf("Failed to copy the backup %s to the bastion node: %s", id, err)
+	}
+	defer removeOnBastion(ctx, context, sshBase, remote)
+	args := []string{"stardog-restore-db", sd.StardogInternalURL, db, remote}
+	if overwrite {
+		args = append(args, "overwrite")
+	}
+	err = runOnBastion(ctx, context, sshBase, creds, args...)
+	if err != nil {
+		return err
+	}
+	context.ConsoleLog(1, "%s\n", context.SuccessString(fmt.Sprintf("The database %s was restored", db)))
+	return nil
+}
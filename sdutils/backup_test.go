@@ -0,0 +1,184 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTransport puts an ssh that appends its arguments and the first line of
+// its input to a log and an scp that writes the downloaded file on the PATH.
+func fakeTransport(t *testing.T) (string, func()) {
+	exedir, err := ioutil.TempDir("", "stardogtest")
+	if err != nil {
+		t.Fatalf("Failed to make the temp dir %s", err)
+	}
+	sshLog := filepath.Join(exedir, "ssh.log")
+	ssh := fmt.Sprintf("#!/usr/bin/env bash\necho ${@} >> %s\nif read -r line; then echo \"stdin $line\" >> %s; fi\n", sshLog, sshLog)
+	scp := "#!/usr/bin/env bash\nlast=${@: -1}\nif [[ $last != *:* ]]; then echo backup > $last; fi\n"
+	err = ioutil.WriteFile(filepath.Join(exedir, "ssh"), []byte(ssh), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(exedir, "scp"), []byte(scp), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the file %s", err)
+	}
+	startPath := os.Getenv("PATH")
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", exedir, startPath))
+	agent := os.Getenv("SSH_AUTH_SOCK")
+	os.Setenv("SSH_AUTH_SOCK", "/tmp/agent")
+	return sshLog, func() {
+		os.Setenv("PATH", startPath)
+		os.Setenv("SSH_AUTH_SOCK", agent)
+		os.RemoveAll(exedir)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshLog, cleanup := fakeTransport(t)
+	defer cleanup()
+
+	app := &TestContext{ConfigDir: dir}
+	baseD := &BaseDeployment{Name: "dep1", Type: "tst", Version: "5.0.0"}
+	dep := &tpDeployment{SdDesc: &StardogDescription{SSHHost: "bastion", StardogInternalURL: "http://internal:5821"}}
+
+	backups, err := BackupDatabases(context.Background(), app, baseD, dep, Credentials{Username: "admin", Password: "admin"}, []string{"db1", "db2"}, "")
+	if err != nil {
+		t.Fatalf("The backup failed %s", err)
+	}
+	if len(backups) != 2 || backups[0].Database != "db1" || backups[1].Database != "db2" {
+		t.Fatalf("There should be a backup of each database %v", backups)
+	}
+	if !PathExists(backups[0].Location) || filepath.Dir(backups[0].Location) != BackupDir(dir, "dep1") {
+		t.Fatalf("The backup should be in the backup directory %s", backups[0].Location)
+	}
+	listed, err := ListBackups(dir, "dep1")
+	if err != nil {
+		t.Fatalf("Listing the backups failed %s", err)
+	}
+	if len(listed) != 2 || listed[0].ID != backups[0].ID {
+		t.Fatalf("The backups should be in the catalog %v", listed)
+	}
+	data, _ := ioutil.ReadFile(sshLog)
+	if !strings.Contains(string(data), "'stardog-backup-db' 'http://internal:5821' 'db1'") {
+		t.Fatalf("The backup should run on the bastion: %s", data)
+	}
+
+	_, err = BackupDatabases(context.Background(), app, baseD, dep, Credentials{Username: "admin", Password: "admin"}, []string{"db1"}, "s3://bucket/backups")
+	if err == nil {
+		t.Fatal("A deployment without an object store cannot back up to a bucket")
+	}
+
+	err = RestoreBackup(context.Background(), app, baseD, dep, Credentials{Username: "admin", Password: "admin"}, "db1", "nothing", "", false)
+	if err == nil {
+		t.Fatal("A backup that is not in the catalog cannot be restored")
+	}
+	err = RestoreBackup(context.Background(), app, baseD, dep, Credentials{Username: "admin", Password: "admin"}, "db2", backups[0].ID, "", false)
+	if err == nil {
+		t.Fatal("A backup cannot be restored to another database")
+	}
+	otherD := &BaseDeployment{Name: "dep2", Type: "tst", Version: "4.2"}
+	err = RestoreBackup(context.Background(), app, otherD, dep, Credentials{Username: "admin", Password: "admin"}, "db1", backups[0].ID, "dep1", false)
+	if err == nil {
+		t.Fatal("A backup of Stardog 5 cannot be restored to Stardog 4")
+	}
+	otherD.Version = "5.1.0"
+	err = RestoreBackup(context.Background(), app, otherD, dep, Credentials{Username: "admin", Password: "admin"}, "db1", backups[0].ID, "dep1", true)
+	if err != nil {
+		t.Fatalf("The restore failed %s", err)
+	}
+	data, _ = ioutil.ReadFile(sshLog)
+	if !strings.Contains(string(data), "'stardog-restore-db' 'http://internal:5821' 'db1' '/tmp/graviton-"+backups[0].ID+".tar.gz' 'overwrite'") {
+		t.Fatalf("The restore should run on the bastion: %s", data)
+	}
+
+	dep.SdDesc.SSHHost = ""
+	_, err = BackupDatabases(context.Background(), app, baseD, dep, Credentials{Username: "admin", Password: "admin"}, []string{"db1"}, "")
+	if err == nil {
+		t.Fatal("A deployment without a bastion cannot be backed up")
+	}
+}
+
+// storeDeployment is a tpDeployment with an object store that records the
+// local files it uploads.
+type storeDeployment struct {
+	*tpDeployment
+	uploaded []string
+	leftover []string
+}
+
+func (d *storeDeployment) Scheme() string {
+	return "s3"
+}
+
+func (d *storeDeployment) UploadObject(ctx context.Context, localPath string, url string) error {
+	for _, p := range d.uploaded {
+		if PathExists(p) {
+			d.leftover = append(d.leftover, p)
+		}
+	}
+	d.uploaded = append(d.uploaded, localPath)
+	return nil
+}
+
+func (d *storeDeployment) DownloadObject(ctx context.Context, url string, localPath string) error {
+	return nil
+}
+
+func TestBackupToObjectStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	sshLog, cleanup := fakeTransport(t)
+	defer cleanup()
+
+	app := &TestContext{ConfigDir: dir}
+	baseD := &BaseDeployment{Name: "dep1", Type: "tst", Version: "5.0.0"}
+	dep := &storeDeployment{tpDeployment: &tpDeployment{SdDesc: &StardogDescription{SSHHost: "bastion", StardogInternalURL: "http://internal:5821"}}}
+
+	backups, err := BackupDatabases(context.Background(), app, baseD, dep, Credentials{Username: "ops", Password: "it's a $secret;"}, []string{"db1", "db2", "db3"}, "s3://bucket/backups")
+	if err != nil {
+		t.Fatalf("The backup failed %s", err)
+	}
+	if len(backups) != 3 || backups[2].Location != "s3://bucket/backups/"+backups[2].ID+".tar.gz" {
+		t.Fatalf("The backups should be in the bucket %v", backups)
+	}
+	data, _ := ioutil.ReadFile(sshLog)
+	if !strings.Contains(string(data), "STARDOG_USER='ops' 'stardog-backup-db' 'http://internal:5821' 'db1' '/tmp/graviton-"+backups[0].ID+".tar.gz'\nstdin it's a $secret;\n") {
+		t.Fatalf("The backup should run as the user of the credentials with the password on its input: %s", data)
+	}
+	for _, l := range strings.Split(string(data), "\n") {
+		if strings.Contains(l, "stardog-backup-db") && (strings.Contains(l, "-t ") || strings.Contains(l, "secret")) {
+			t.Fatalf("The password should only be on the input and never echoed by a terminal: %s", l)
+		}
+	}
+	if len(dep.leftover) != 0 {
+		t.Fatalf("The copy of each database should be removed before the next one %v", dep.leftover)
+	}
+	for _, p := range dep.uploaded {
+		if PathExists(p) {
+			t.Fatalf("The temporary copy %s was not removed", p)
+		}
+	}
+}
@@ -16,6 +16,7 @@
 package sdutils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -60,9 +61,53 @@ func DeleteDeployment(context AppContext, name string) {
 	os.RemoveAll(deploymentDir)
 }
 
+// InterruptedStep is written to the deployment directory when a command is
+// interrupted so that the user can be told what was left unfinished.
+type InterruptedStep struct {
+	Command string    `json:"command"`
+	Program string    `json:"program,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+func interruptedPath(deploymentDir string) string {
+	return path.Join(deploymentDir, "interrupted.json")
+}
+
+// RecordInterruptedStep saves the step in the deployment directory.  Nothing
+// is recorded for a deployment that was never created.
+func RecordInterruptedStep(deploymentDir string, step *InterruptedStep) error {
+	if _, err := os.Stat(deploymentDir); err != nil {
+		return err
+	}
+	data, err := json.Marshal(step)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(interruptedPath(deploymentDir), data, 0600)
+}
+
+// LoadInterruptedStep returns the step that was interrupted in the
+// deployment, or nil when the last command was not interrupted.
+func LoadInterruptedStep(deploymentDir string) *InterruptedStep {
+	data, err := ioutil.ReadFile(interruptedPath(deploymentDir))
+	if err != nil {
+		return nil
+	}
+	var step InterruptedStep
+	if json.Unmarshal(data, &step) != nil {
+		return nil
+	}
+	return &step
+}
+
+// ClearInterruptedStep forgets the interrupted step of a deployment.
+func ClearInterruptedStep(deploymentDir string) {
+	os.Remove(interruptedPath(deploymentDir))
+}
+
 // LoadDeployment inflates a Deployment object from the information stored in the
 // configuration directory.
-func LoadDeployment(context AppContext, baseD *BaseDeployment, new bool) (Deployment, error) {
+func LoadDeployment(ctx context.Context, context AppContext, baseD *BaseDeployment, new bool) (Deployment, error) {
 	confPath := path.Join(baseD.Directory, "config.json")
 
 	plugin, err := GetPlugin(baseD.Type)
@@ -83,25 +128,40 @@ func LoadDeployment(context AppContext, baseD *BaseDeployment, new bool) (Deploy
 			return nil, err
 		}
 		context.Logf(DEBUG, "Loading the default %s from %s", baseD, confPath)
-		return plugin.DeploymentLoader(context, baseD, new)
+		// The stored type wins over the one requested on the command line
+		plugin, err = GetPlugin(baseD.Type)
+		if err != nil {
+			return nil, err
+		}
+		return plugin.DeploymentLoader(ctx, context, baseD, new)
 	}
 	os.MkdirAll(baseD.Directory, 0755)
 
-	d, err := plugin.DeploymentLoader(context, baseD, new)
+	d, err := plugin.DeploymentLoader(ctx, context, baseD, new)
 	if err != nil {
 		os.RemoveAll(baseD.Directory)
 	}
 	return d, err
 }
 
-func runClient(context AppContext, sd *StardogDescription, baseD *BaseDeployment, d Deployment, cmdArray []string) error {
-	baseSSH, err := getSSHCommand(context, baseD, sd)
-	if err != nil {
-		return nil
+func runClient(ctx context.Context, context AppContext, sd *StardogDescription, baseD *BaseDeployment, d Deployment, cmdArray []string) error {
+	var chpwCmd []string
+	if rr, ok := d.(RemoteRunner); ok {
+		base, err := rr.RemoteCommand(false)
+		if err != nil {
+			return err
+		}
+		chpwCmd = append(base, "/usr/local/stardog/bin/stardog-admin")
+	} else {
+		baseSSH, err := getSSHCommand(context, baseD, sd)
+		if err != nil {
+			return nil
+		}
+		chpwCmd = append(baseSSH,
+			"sudo",
+			"/usr/local/stardog/bin/stardog-admin")
 	}
-	chpwCmd := append(baseSSH,
-		"sudo",
-		"/usr/local/stardog/bin/stardog-admin",
+	chpwCmd = append(chpwCmd,
 		"--server",
 		sd.StardogInternalURL)
 	chpwCmd = append(chpwCmd,
@@ -111,7 +171,7 @@ func runClient(context AppContext, sd *StardogDescription, baseD *BaseDeployment
 		Path: chpwCmd[0],
 		Args: chpwCmd,
 	}
-	_, err = RunCommand(context, cmd, linePrinter, nil)
+	_, err := RunCommand(ctx, context, cmd, linePrinter, nil)
 	return err
 }
 
@@ -126,16 +186,36 @@ func getSSHCommand(context AppContext, baseD *BaseDeployment, sd *StardogDescrip
 	sshCmd := []string{sshPath,
 		"-t", "-t",
 		"-A",
-		"-i", baseD.PrivateKey,
+	}
+	sshCmd = append(sshCmd, identityOpts(baseD)...)
+	sshCmd = append(sshCmd,
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
-		fmt.Sprintf("%s@%s", "ubuntu", sd.SSHHost),
-	}
+		fmt.Sprintf("%s@%s", sshUser(sd), sd.SSHHost),
+	)
 
 	return sshCmd, nil
 }
 
-func runSCPCommand(context AppContext, baseD *BaseDeployment, sd *StardogDescription, local string, remote string, upload bool) error {
+// sshUser is the account used to log into the ssh host.  The images built
+// for aws use ubuntu.
+func sshUser(sd *StardogDescription) string {
+	if sd.SSHUser == "" {
+		return "ubuntu"
+	}
+	return sd.SSHUser
+}
+
+// identityOpts returns the ssh options for the private key of a deployment.
+// Without a key ssh falls back to the agent and the default identities.
+func identityOpts(baseD *BaseDeployment) []string {
+	if baseD.PrivateKey == "" {
+		return []string{}
+	}
+	return []string{"-i", baseD.PrivateKey}
+}
+
+func runSCPCommand(ctx context.Context, context AppContext, baseD *BaseDeployment, sd *StardogDescription, local string, remote string, upload bool) error {
 	context.Logf(DEBUG, "sshing to %s to run the stardog client\n", sd.SSHHost)
 
 	scpPath, err := exec.LookPath("scp")
@@ -143,31 +223,18 @@ func runSCPCommand(context AppContext, baseD *BaseDeployment, sd *StardogDescrip
 		return err
 	}
 
-	var scpStrA []string
-	remoteTarget := fmt.Sprintf("%s@%s:%s", "ubuntu", sd.SSHHost, remote)
+	remoteTarget := fmt.Sprintf("%s@%s:%s", sshUser(sd), sd.SSHHost, remote)
+	scpStrA := append([]string{scpPath, "-v"}, identityOpts(baseD)...)
+	scpStrA = append(scpStrA,
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+	)
 	if upload {
-		scpStrA = []string{scpPath,
-			"-v",
-			"-i", baseD.PrivateKey,
-			"-o", "StrictHostKeyChecking=no",
-			"-o", "UserKnownHostsFile=/dev/null",
-			local,
-			remoteTarget,
-		}
+		scpStrA = append(scpStrA, local, remoteTarget)
 	} else {
-		scpStrA = []string{scpPath,
-			"-v",
-			"-i", baseD.PrivateKey,
-			"-o", "StrictHostKeyChecking=no",
-			"-o", "UserKnownHostsFile=/dev/null",
-			remoteTarget,
-			local,
-		}
-	}
-	scpCmd := exec.Cmd{
-		Path: scpStrA[0],
-		Args: scpStrA,
+		scpStrA = append(scpStrA, remoteTarget, local)
 	}
+	scpCmd := exec.CommandContext(ctx, scpStrA[0], scpStrA[1:]...)
 	o, err := scpCmd.CombinedOutput()
 	if err != nil {
 		context.Logf(ERROR, "scp error: %s.  %s", err, string(o))
@@ -177,15 +244,26 @@ func runSCPCommand(context AppContext, baseD *BaseDeployment, sd *StardogDescrip
 }
 
 // RunSSH will start an ssh session on the bastion node
-func RunSSH(context AppContext, baseD *BaseDeployment, d Deployment) error {
-	sd, err := d.FullStatus()
+func RunSSH(ctx context.Context, context AppContext, baseD *BaseDeployment, d Deployment) error {
+	sd, err := d.FullStatus(ctx)
 	if err != nil {
 		return err
 	}
-
-	baseSSH, err := getSSHCommand(context, baseD, sd)
-	if err != nil {
-		return nil
+	var baseSSH []string
+	if rr, ok := d.(RemoteRunner); ok {
+		baseSSH, err = rr.RemoteCommand(true)
+		if err != nil {
+			return err
+		}
+		baseSSH = append(baseSSH, "/bin/bash")
+	} else {
+		if sd.SSHHost == "" {
+			return fmt.Errorf("The deployment %s does not have an ssh host", baseD.Name)
+		}
+		baseSSH, err = getSSHCommand(context, baseD, sd)
+		if err != nil {
+			return nil
+		}
 	}
 
 	cmd := exec.Cmd{
@@ -207,8 +285,8 @@ func RunSSH(context AppContext, baseD *BaseDeployment, d Deployment) error {
 
 // IsHealthy checks the deployment to see if the Stardog service is healthy.  if
 // internal is set to true it will test by sshing into the bastion node first.
-func IsHealthy(context AppContext, baseD *BaseDeployment, d Deployment, internal bool) bool {
-	sd, err := d.FullStatus()
+func IsHealthy(ctx context.Context, context AppContext, baseD *BaseDeployment, d Deployment, internal bool) bool {
+	sd, err := d.FullStatus(ctx)
 	if err != nil {
 		context.Logf(WARN, "Status failure %s", err)
 		return false
@@ -225,9 +303,27 @@ func IsHealthy(context AppContext, baseD *BaseDeployment, d Deployment, internal
 		return true
 	}
 
+	_, remote := d.(RemoteRunner)
+	if internal && sd.SSHHost == "" && !remote {
+		// Without a bastion node the internal URL is reachable directly
+		url := fmt.Sprintf("%s/admin/healthcheck", sd.StardogInternalURL)
+		context.Logf(DEBUG, "Checking internal health at %s.", url)
+		response, err := healthGet(ctx, url)
+		if err != nil {
+			context.Logf(DEBUG, "Error getting the health check %s", err)
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == 200
+	}
 	if internal {
 		context.Logf(DEBUG, "Checking health via ssh.")
-		sshBase, err := getSSHCommand(context, baseD, sd)
+		var sshBase []string
+		if remote {
+			sshBase, err = d.(RemoteRunner).RemoteCommand(false)
+		} else {
+			sshBase, err = getSSHCommand(context, baseD, sd)
+		}
 		if err != nil {
 			context.Logf(DEBUG, "ssh command error %s", err)
 			return false
@@ -240,10 +336,7 @@ func IsHealthy(context AppContext, baseD *BaseDeployment, d Deployment, internal
 			fmt.Sprintf("%s/admin/healthcheck", sd.StardogInternalURL),
 		}...)
 
-		cmd := exec.Cmd{
-			Path: sshCmd[0],
-			Args: sshCmd,
-		}
+		cmd := exec.CommandContext(ctx, sshCmd[0], sshCmd[1:]...)
 
 		context.Logf(INFO, "Running the remote health checker %s.", strings.Join(sshCmd, " "))
 		b, err := cmd.Output()
@@ -256,18 +349,27 @@ func IsHealthy(context AppContext, baseD *BaseDeployment, d Deployment, internal
 	url := fmt.Sprintf("%s/admin/healthcheck", sd.StardogURL)
 	context.Logf(DEBUG, "Checking health at %s.", url)
 
-	response, err := http.Get(url)
+	response, err := healthGet(ctx, url)
 	if err != nil {
 		context.Logf(DEBUG, "Error getting the health check %s", err)
 		return false
 	}
+	response.Body.Close()
 	return response.StatusCode == 200
 }
 
+func healthGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req.WithContext(ctx))
+}
+
 // WaitForHealth will block until the deployment is considered healthy or the
 // timeout expires.  If internal is true it will ssh into the bastion node
 // before checking the health URL.
-func WaitForHealth(context AppContext, baseD *BaseDeployment, d Deployment, waitTimeout int, internal bool) error {
+func WaitForHealth(ctx context.Context, context AppContext, baseD *BaseDeployment, d Deployment, waitTimeout int, internal bool) error {
 	last := ""
 	pollInterval := 2
 	itCnt := waitTimeout / pollInterval
@@ -278,30 +380,32 @@ func WaitForHealth(context AppContext, baseD *BaseDeployment, d Deployment, wait
 	} else {
 		spin = NewSpinner(context, 1, "Waiting for external health check to pass")
 	}
-	for i := 0; !IsHealthy(context, baseD, d, internal); i++ {
+	for i := 0; !IsHealthy(ctx, context, baseD, d, internal); i++ {
 		if i >= itCnt {
 			return fmt.Errorf("Timed out waiting for the instance to get healthy")
 		}
 		spin.EchoNext()
 		context.ConsoleLog(1, "\r%s", last)
-		time.Sleep(time.Duration(pollInterval) * time.Second)
+		select {
+		case <-ctx.Done():
+			spin.Close()
+			return ctx.Err()
+		case <-time.After(time.Duration(pollInterval) * time.Second):
+		}
 	}
 	spin.Close()
 	context.ConsoleLog(1, "%s\n", context.SuccessString("The instance is healthy"))
 	return nil
 }
 
-func WaitForNClusterNodes(context AppContext, size int, sdURL string, pw string, waitTimeout int) error {
+func WaitForNClusterNodes(ctx context.Context, context AppContext, size int, sdURL string, creds Credentials, waitTimeout int) error {
 	var err error
 	pollInterval := 2
 	itCnt := waitTimeout / pollInterval
 
-	client := stardogClientImpl{
-		sdURL: sdURL,
-		logger: context,
-		username: "admin",
-		password: pw,
-	}
+	client := NewStardogClient(context, sdURL, creds.Username, creds.Password)
+	// The loop below already polls until the cluster forms
+	client.Retries = 0
 	spinner := NewSpinner(context, 2, "Waiting for the node to be healthy internally")
 	nodes := &[]string{}
 	for i := 0; len(*nodes) < size; i++ {
@@ -310,8 +414,13 @@ func WaitForNClusterNodes(context AppContext, size int, sdURL string, pw string,
 			return fmt.Errorf("Timed out waiting for all the cluster nodes")
 		}
 		spinner.EchoNext()
-		time.Sleep(time.Duration(pollInterval) * time.Second)
-		nodes, err = client.GetClusterInfo()
+		select {
+		case <-ctx.Done():
+			spinner.Close()
+			return ctx.Err()
+		case <-time.After(time.Duration(pollInterval) * time.Second):
+		}
+		nodes, err = client.GetClusterInfo(ctx)
 		if err != nil {
 			context.Logf(WARN, "Cluster info failed: %s", err)
 			nodes = &[]string{}
@@ -329,11 +438,26 @@ func linePrinter(cliContext AppContext, line string) *ScanResult {
 }
 
 // CreateInstance wraps up the deployment.CreateInstance method and blocks until
-// the deployment is considered healthy.  It will then change the password by
-// SSHing into the bastion node.  Once that is complete it will open up the
-// the firewall.
-func CreateInstance(context AppContext, baseD *BaseDeployment, dep Deployment, volumeSize int, zkSize int, waitMaxTimeSec int, timeoutSec int, mask string, noWait bool) error {
-	err := dep.CreateInstance(volumeSize, zkSize, timeoutSec)
+// the deployment is considered healthy.  It will then change the default
+// password of the admin user to the password of creds by SSHing into the
+// bastion node.  Once that is complete it will open up the the firewall.
+// Each of those steps is recorded in the journal, which may be nil, and the
+// steps that already finished are skipped.
+func CreateInstance(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials, volumeSize int, zkSize int, waitMaxTimeSec int, timeoutSec int, mask string, noWait bool, journal *Journal) error {
+	err := CheckLaunchCredentials(creds)
+	if err != nil {
+		return err
+	}
+	// The only user of a new cluster
+	user := "admin"
+	err = SaveInstanceParams(baseD, InstanceParams{RootVolumeSize: volumeSize, ZookeeperSize: zkSize, IdleTimeout: timeoutSec, HTTPMask: mask})
+	if err != nil {
+		return err
+	}
+	params := map[string]interface{}{"root_volume_size": volumeSize, "zookeeper_size": zkSize, "idle_timeout": timeoutSec}
+	err = journal.Step(StepCreateInstance, params, func() error {
+		return dep.CreateInstance(ctx, volumeSize, zkSize, timeoutSec)
+	})
 	if err != nil {
 		return err
 	}
@@ -342,43 +466,68 @@ func CreateInstance(context AppContext, baseD *BaseDeployment, dep Deployment, v
 		return nil
 	}
 
-	context.ConsoleLog(1, "Waiting for stardog to come up...\n")
-	err = WaitForHealth(context, baseD, dep, waitMaxTimeSec, true)
+	err = journal.Step(StepWaitHealthy, nil, func() error {
+		context.ConsoleLog(1, "Waiting for stardog to come up...\n")
+		return WaitForHealth(ctx, context, baseD, dep, waitMaxTimeSec, true)
+	})
 	if err != nil {
 		return err
 	}
-	sd, err := dep.FullStatus()
+	sd, err := dep.FullStatus(ctx)
 	if err != nil {
 		return err
 	}
 	pw := "admin"
-	newPw := os.Getenv("STARDOG_ADMIN_PASSWORD")
+	newPw := creds.Password
+	if newPw == pw {
+		newPw = ""
+	}
+	if newPw == "" && journal.Finished(StepChangePassword) {
+		return fmt.Errorf("The admin password was changed by an earlier launch.  The new password is needed to resume")
+	}
 	if newPw != "" {
-		context.ConsoleLog(1, "Changing the default password...\n")
-		err = runClient(context, sd, baseD, dep, []string{"user", "passwd", "-u", "admin", "-N", newPw, "-p", "admin"})
+		err = journal.Step(StepChangePassword, nil, func() error {
+			context.ConsoleLog(1, "Changing the default password...\n")
+			if _, remote := dep.(RemoteRunner); sd.SSHHost == "" && !remote {
+				client := NewStardogClient(context, sd.StardogInternalURL, user, pw)
+				return client.ChangePassword(ctx, user, newPw)
+			}
+			return runClient(ctx, context, sd, baseD, dep, []string{"user", "passwd", "-u", user, "-N", newPw, "-p", pw})
+		})
 		if err != nil {
 			return err
 		}
 		pw = newPw
 	}
-	err = dep.OpenInstance(volumeSize, zkSize, mask, timeoutSec)
-	if err != nil {
-		return err
-	}
-	clusterSize, err := dep.ClusterSize()
+	err = journal.Step(StepOpenInstance, map[string]interface{}{"mask": mask}, func() error {
+		return dep.OpenInstance(ctx, volumeSize, zkSize, mask, timeoutSec)
+	})
 	if err != nil {
 		return err
 	}
-	err = WaitForNClusterNodes(context, clusterSize, sd.StardogURL, pw, waitMaxTimeSec)
-	return err
+	return journal.Step(StepWaitCluster, nil, func() error {
+		clusterSize, err := dep.ClusterSize()
+		if err != nil {
+			return err
+		}
+		return WaitForNClusterNodes(ctx, context, clusterSize, sd.StardogURL, Credentials{Username: user, Password: pw}, waitMaxTimeSec)
+	})
 }
 
-func GatherLogs(context AppContext, baseD *BaseDeployment, dep Deployment, outfile string) error {
+func GatherLogs(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials, outfile string) error {
+	if lg, ok := dep.(LogGatherer); ok {
+		context.ConsoleLog(2, "Gathering logs...\n")
+		outfile = strings.TrimSpace(outfile)
+		if outfile == "" {
+			outfile = "stardoglogs.tar.gz"
+		}
+		return lg.GatherLogs(ctx, outfile)
+	}
 	if os.Getenv("SSH_AUTH_SOCK") == "" {
 		return fmt.Errorf("ssh-agent needs to be setup for log gathering to work")
 	}
 	context.ConsoleLog(2, "Gathering logs...\n")
-	sd, err := dep.FullStatus()
+	sd, err := dep.FullStatus(ctx)
 	if err != nil {
 		return err
 	}
@@ -386,22 +535,12 @@ func GatherLogs(context AppContext, baseD *BaseDeployment, dep Deployment, outfi
 	if err != nil {
 		return err
 	}
-	pw := os.Getenv("STARDOG_ADMIN_PASSWORD")
-	if pw == "" {
-		pw = "admin"
-	}
 	dst_log_file := fmt.Sprintf("/tmp/stardog%d.tar.gz", rand.Int())
-	sshCmd := append(sshBase, []string{
+	cmd := bastionExec(ctx, sshBase, creds,
 		"/usr/local/bin/stardog-gather-logs",
 		sd.StardogInternalURL,
-		dst_log_file,
-		pw,
-	}...)
-	cmd := exec.Cmd{
-		Path: sshCmd[0],
-		Args: sshCmd,
-	}
-	context.Logf(DEBUG, "Running the log gathering command: %s", strings.Join(sshCmd[:len(sshCmd)-1], " "))
+		dst_log_file)
+	context.Logf(DEBUG, "Running the log gathering command: %s", strings.Join(cmd.Args, " "))
 	o, err := cmd.Output()
 	if err != nil {
 		context.Logf(ERROR, "Failed to get the logs: %s", string(o))
@@ -416,18 +555,46 @@ func GatherLogs(context AppContext, baseD *BaseDeployment, dep Deployment, outfi
 	if outfile == "" {
 		outfile = "stardoglogs.tar.gz"
 	}
-	return runSCPCommand(context, baseD, sd, outfile, dst_log_file, false)
+	return runSCPCommand(ctx, context, baseD, sd, outfile, dst_log_file, false)
 }
 
-// FullStatus inspects the state of a deployment and prints it out to the console.
-func FullStatus(context AppContext, baseD *BaseDeployment, dep Deployment, internal bool, outfile string) error {
-	context.ConsoleLog(2, "Checking status...\n")
-	sd, err := dep.FullStatus()
+// GrowFilesystems grows the filesystem on the volume of every Stardog node to
+// the size of the volume.  The nodes that are not running grow theirs when
+// they start.
+func GrowFilesystems(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials) error {
+	sshBase, sd, err := bastionCommand(ctx, context, baseD, dep, "stardog-grow-fs")
 	if err != nil {
 		return err
 	}
+	context.ConsoleLog(1, "Growing the filesystems of the Stardog nodes...\n")
+	return runOnBastion(ctx, context, sshBase, creds, "stardog-grow-fs", sd.StardogInternalURL)
+}
+
+// DeploymentStatus gathers the state of a deployment, its health and the
+// nodes in the Stardog cluster.  The description is returned even when the
+// cluster nodes could not be listed.  The nodes are listed with creds.
+func DeploymentStatus(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials, internal bool) (*StardogDescription, error) {
+	sd, err := dep.FullStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sd.Healthy = IsHealthy(ctx, context, baseD, dep, internal)
+	if os.Getenv("STARDOG_GRAVITON_UNIT_TEST") != "" {
+		return sd, nil
+	}
 
-	sd.Healthy = IsHealthy(context, baseD, dep, internal)
+	client := NewStardogClient(context, sd.StardogURL, creds.Username, creds.Password)
+	nodes, err := client.GetClusterInfo(ctx)
+	if err != nil {
+		return sd, err
+	}
+	sd.StardogNodes = *nodes
+	return sd, nil
+}
+
+// PrintStatus writes a description to the console and, when outfile is set,
+// to a JSON file.
+func PrintStatus(context AppContext, sd *StardogDescription, outfile string) error {
 	if sd.Healthy {
 		context.ConsoleLog(1, "%s\n", context.SuccessString("The instance is healthy"))
 	} else {
@@ -438,38 +605,130 @@ func FullStatus(context AppContext, baseD *BaseDeployment, dep Deployment, inter
 	}
 
 	context.ConsoleLog(1, "Stardog is available here: %s\n", context.HighlightString(sd.StardogURL))
-	context.ConsoleLog(1, "ssh is available here: %s\n", sd.SSHHost)
-
-	pw := os.Getenv("STARDOG_ADMIN_PASSWORD")
-	if pw == "" {
-		pw = "admin"
+	if sd.SSHHost != "" {
+		context.ConsoleLog(1, "ssh is available here: %s\n", sd.SSHHost)
 	}
-
-	client := stardogClientImpl{
-		sdURL: sd.StardogURL,
-		logger: context,
-		username: "admin",
-		password: pw,
-	}
-	nodes, err := client.GetClusterInfo()
-	if err != nil {
-		return err
+	if sd.StardogNodes == nil {
+		return nil
 	}
-	context.ConsoleLog(1, "Using %d stardog nodes\n", len(*nodes))
-	for _, n := range *nodes {
+	context.ConsoleLog(1, "Using %d stardog nodes\n", len(sd.StardogNodes))
+	for _, n := range sd.StardogNodes {
 		context.ConsoleLog(1, "\t%s\n", n)
 	}
-	sd.StardogNodes = *nodes
 
 	if outfile != "" {
-		err = WriteJSON(sd, outfile)
-		if err != nil {
-			return err
-		}
+		return WriteJSON(sd, outfile)
 	}
 	return nil
 }
 
+// FullStatus inspects the state of a deployment and prints it out to the console.
+func FullStatus(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials, internal bool, outfile string) error {
+	context.ConsoleLog(2, "Checking status...\n")
+	sd, err := DeploymentStatus(ctx, context, baseD, dep, creds, internal)
+	if sd == nil {
+		return err
+	}
+	perr := PrintStatus(context, sd, outfile)
+	if err != nil {
+		return err
+	}
+	return perr
+}
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
+
+// SaveInstanceParams records the settings of the instance in the deployment
+// configuration.
+func SaveInstanceParams(baseD *BaseDeployment, params InstanceParams) error {
+	confPath := path.Join(baseD.Directory, "config.json")
+	var stored BaseDeployment
+	err := LoadJSON(&stored, confPath)
+	if err != nil {
+		return err
+	}
+	stored.Instance = &params
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(confPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	baseD.Instance = &params
+	return nil
+}
+
+// ResumeInstance creates the instance of a paused deployment again with the
+// settings it was last created with.  Unlike CreateInstance it leaves the
+// admin password alone because the volumes already have it, so the firewall
+// is opened right away.  The cluster is checked with creds.
+func ResumeInstance(ctx context.Context, context AppContext, baseD *BaseDeployment, dep Deployment, creds Credentials, waitMaxTimeSec int, noWait bool) error {
+	p := baseD.Instance
+	if p == nil {
+		return fmt.Errorf("The instance settings of %s were not recorded.  Use 'instance new' to create the instance", baseD.Name)
+	}
+	err := dep.CreateInstance(ctx, p.RootVolumeSize, p.ZookeeperSize, p.IdleTimeout)
+	if err != nil {
+		return err
+	}
+	err = dep.OpenInstance(ctx, p.RootVolumeSize, p.ZookeeperSize, p.HTTPMask, p.IdleTimeout)
+	if err != nil {
+		return err
+	}
+	if noWait {
+		context.ConsoleLog(1, "Not waiting...\n")
+		return nil
+	}
+	context.ConsoleLog(1, "Waiting for stardog to come up...\n")
+	err = WaitForHealth(ctx, context, baseD, dep, waitMaxTimeSec, true)
+	if err != nil {
+		return err
+	}
+	sd, err := dep.FullStatus(ctx)
+	if err != nil {
+		return err
+	}
+	clusterSize, err := dep.ClusterSize()
+	if err != nil {
+		return err
+	}
+	return WaitForNClusterNodes(ctx, context, clusterSize, sd.StardogURL, creds, waitMaxTimeSec)
+}
+
+// PauseWrites takes every database of the Stardog cluster at sdURL offline so
+// that the data on the volumes stops changing.  The returned function brings
+// them back online.
+func PauseWrites(ctx context.Context, c AppContext, sdURL string, creds Credentials) (func(context.Context) error, error) {
+	client := NewStardogClient(c, sdURL, creds.Username, creds.Password)
+	dbs, err := client.ListDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resume := func(ctx context.Context) error {
+		var firstErr error
+		for _, db := range dbs {
+			err := client.SetDatabaseOnline(ctx, db, true)
+			if err != nil {
+				c.Logf(WARN, "Failed to bring the database %s back online: %s", db, err)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		return firstErr
+	}
+	for i, db := range dbs {
+		c.ConsoleLog(1, "Taking the database %s offline.\n", db)
+		err = client.SetDatabaseOnline(ctx, db, false)
+		if err != nil {
+			dbs = dbs[:i]
+			resume(context.Background())
+			return nil, err
+		}
+	}
+	return resume, nil
+}
@@ -16,7 +16,10 @@
 package sdutils
 
 import (
+	"context"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"testing"
@@ -45,7 +48,7 @@ func TestLoadDeploymentNoPlugin(t *testing.T) {
 		Name: "notreal",
 	}
 
-	_, err = LoadDeployment(&app, &baseD, false)
+	_, err = LoadDeployment(context.Background(), &app, &baseD, false)
 	if err == nil {
 		t.Fatal("No pluging should exist")
 	}
@@ -69,7 +72,7 @@ func TestLoadDeploymentNoLoadPlugin(t *testing.T) {
 	}
 	AddCloudType(plugin)
 
-	_, err = LoadDeployment(&app, &baseD, false)
+	_, err = LoadDeployment(context.Background(), &app, &baseD, false)
 	if err == nil {
 		t.Fatal("No deployment load should fail")
 	}
@@ -94,7 +97,7 @@ func TestLoadNewDeploymentFailLoad(t *testing.T) {
 	}
 	AddCloudType(plugin)
 
-	_, err = LoadDeployment(&app, &baseD, true)
+	_, err = LoadDeployment(context.Background(), &app, &baseD, true)
 	if err == nil {
 		t.Fatal("No deployment load should fail")
 	}
@@ -121,7 +124,7 @@ func TestLoadNewDeploymentGoodLoad(t *testing.T) {
 	}
 	AddCloudType(plugin)
 
-	_, err = LoadDeployment(&app, &baseD, true)
+	_, err = LoadDeployment(context.Background(), &app, &baseD, true)
 	if err != nil {
 		t.Fatalf("The deployment should load %s", err)
 	}
@@ -160,7 +163,7 @@ func TestLoadExistingDeploymentGoodLoad(t *testing.T) {
 	confPath := path.Join(baseD.Directory, "config.json")
 	WriteJSON(&baseD, confPath)
 
-	_, err = LoadDeployment(&app, &baseD, true)
+	_, err = LoadDeployment(context.Background(), &app, &baseD, true)
 	if err != nil {
 		t.Fatalf("The deployment should load %s", err)
 	}
@@ -169,3 +172,66 @@ func TestLoadExistingDeploymentGoodLoad(t *testing.T) {
 	}
 	DeleteDeployment(&app, baseD.Name)
 }
+
+func TestInterruptedStep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtest")
+	if err != nil {
+		t.Fatal("Temp dir failed")
+	}
+	defer os.RemoveAll(dir)
+
+	if LoadInterruptedStep(dir) != nil {
+		t.Fatal("Nothing was interrupted yet")
+	}
+	err = RecordInterruptedStep(path.Join(dir, "missing"), &InterruptedStep{Command: "launch"})
+	if err == nil {
+		t.Fatal("A step should not be recorded for a missing deployment")
+	}
+	err = RecordInterruptedStep(dir, &InterruptedStep{Command: "instance new", Program: "/bin/terraform"})
+	if err != nil {
+		t.Fatalf("The step was not recorded %s", err)
+	}
+	step := LoadInterruptedStep(dir)
+	if step == nil || step.Command != "instance new" || step.Program != "/bin/terraform" {
+		t.Fatalf("The step was not loaded %v", step)
+	}
+	ClearInterruptedStep(dir)
+	if LoadInterruptedStep(dir) != nil {
+		t.Fatal("The step was not cleared")
+	}
+}
+
+func TestPauseWrites(t *testing.T) {
+	calls := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if r.Method == "GET" {
+			w.Write([]byte(`{"databases":["db1","db2"]}`))
+		}
+	}))
+	defer ts.Close()
+
+	resume, err := PauseWrites(context.Background(), &TestContext{}, ts.URL, Credentials{Username: "admin", Password: "admin"})
+	if err != nil {
+		t.Fatalf("PauseWrites failed %s", err)
+	}
+	err = resume(context.Background())
+	if err != nil {
+		t.Fatalf("Resuming the writes failed %s", err)
+	}
+	expected := []string{
+		"GET /admin/databases",
+		"PUT /admin/databases/db1/offline",
+		"PUT /admin/databases/db2/offline",
+		"PUT /admin/databases/db1/online",
+		"PUT /admin/databases/db2/online",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected the calls %v but got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("Expected the calls %v but got %v", expected, calls)
+		}
+	}
+}
@@ -16,8 +16,10 @@
 package sdutils
 
 import (
-	"gopkg.in/alecthomas/kingpin.v2"
+	"context"
 	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 // ConsoleEffect is a function for writing lines to the console in a
@@ -27,16 +29,27 @@ type ConsoleEffect func(a ...interface{}) string
 // BaseDeployment hold information about the deployments and is serialized
 // to JSON.  CloudOpts is defined by the specific plugin in use.
 type BaseDeployment struct {
-	Type            string      `json:"type,omitempty"`
-	Name            string      `json:"name,omitempty"`
-	Directory       string      `json:"directory,omitempty"`
-	Version         string      `json:"version,omitempty"`
-	PrivateKey      string      `json:"private_key,omitempty"`
-	CustomPropsFile string      `json:"custom_props,omitempty"`
-	IdleTimeout     int         `json:"idle_timeout,omitempty"`
-	Environment     []string    `json:"environment,omitempty"`
-	DisableSecurity bool        `json:"disable_security,omitempty"`
-	CloudOpts       interface{} `json:"cloud_opts,omitempty"`
+	Type            string          `json:"type,omitempty"`
+	Name            string          `json:"name,omitempty"`
+	Directory       string          `json:"directory,omitempty"`
+	Version         string          `json:"version,omitempty"`
+	PrivateKey      string          `json:"private_key,omitempty"`
+	CustomPropsFile string          `json:"custom_props,omitempty"`
+	IdleTimeout     int             `json:"idle_timeout,omitempty"`
+	Environment     []string        `json:"environment,omitempty"`
+	DisableSecurity bool            `json:"disable_security,omitempty"`
+	Instance        *InstanceParams `json:"instance,omitempty"`
+	CloudOpts       interface{}     `json:"cloud_opts,omitempty"`
+}
+
+// InstanceParams are the settings the instance of a deployment was created
+// with.  They are kept so that a paused deployment can be resumed the same
+// way.
+type InstanceParams struct {
+	RootVolumeSize int    `json:"root_volume_size,omitempty"`
+	ZookeeperSize  int    `json:"zookeeper_size,omitempty"`
+	IdleTimeout    int    `json:"idle_timeout,omitempty"`
+	HTTPMask       string `json:"http_mask,omitempty"`
 }
 
 // AppContext provides and abstraction to logging, console interaction and
@@ -47,6 +60,7 @@ type AppContext interface {
 	GetConfigDir() string
 	GetVersion() string
 	GetInteractive() bool
+	Prompt(prompt string, defaultValue string) (string, error)
 	HighlightString(a ...interface{}) string
 	SuccessString(a ...interface{}) string
 	FailString(a ...interface{}) string
@@ -59,6 +73,7 @@ type StardogDescription struct {
 	StardogInternalURL  string      `json:"stardog_internal_url,omitempty"`
 	StardogNodes        []string    `json:"stardog_nodes,omitempty"`
 	SSHHost             string      `json:"ssh_host,omitempty"`
+	SSHUser             string      `json:"ssh_user,omitempty"`
 	Healthy             bool        `json:"healthy,omitempty"`
 	TimeStamp           time.Time   `json:"timestamp,omitempty"`
 	VolumeDescription   interface{} `json:"volume,omitempty"`
@@ -66,22 +81,137 @@ type StardogDescription struct {
 }
 
 // Deployment is an interface to a plugin that is managing the actual Stardog services.
+// Every method that talks to the cloud takes a context.  When it is cancelled
+// the running tool is interrupted and the method returns an error.
 type Deployment interface {
-	CreateVolumeSet(licensePath string, sizeOfEachVolume int, clusterSize int) error
-	DeleteVolumeSet() error
-	StatusVolumeSet() error
+	CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error
+	DeleteVolumeSet(ctx context.Context) error
+	StatusVolumeSet(ctx context.Context) error
 	VolumeExists() bool
 	ClusterSize() (int, error)
 
-	CreateInstance(volumeSize int, zookeeperSize int, idleTimeout int) error
-	OpenInstance(volumeSize int, zookeeperSize int, mask string, idleTimeout int) error
-	DeleteInstance() error
-	StatusInstance() error
+	CreateInstance(ctx context.Context, volumeSize int, zookeeperSize int, idleTimeout int) error
+	OpenInstance(ctx context.Context, volumeSize int, zookeeperSize int, mask string, idleTimeout int) error
+	DeleteInstance(ctx context.Context) error
+	StatusInstance(ctx context.Context) error
 	InstanceExists() bool
 
-	FullStatus() (*StardogDescription, error)
+	FullStatus(ctx context.Context) (*StardogDescription, error)
+
+	DestroyDeployment(ctx context.Context) error
+}
+
+// LogGatherer can be implemented by a Deployment that is able to collect the
+// Stardog logs itself rather than through the bastion node.
+type LogGatherer interface {
+	GatherLogs(ctx context.Context, outfile string) error
+}
+
+// RemoteRunner can be implemented by a Deployment that has no bastion node
+// but can still run commands next to the Stardog nodes.  The returned prefix
+// is used in place of the ssh command.
+type RemoteRunner interface {
+	RemoteCommand(interactive bool) ([]string, error)
+}
+
+// Scaler can be implemented by a Deployment whose plugin supports scaling.
+// Scale changes the number of Stardog nodes of the deployment.
+type Scaler interface {
+	Scale(ctx context.Context, clusterSize int) error
+}
+
+// Resizer can be implemented by a Deployment whose plugin supports scaling.
+// ResizeInstance changes the instance types of a running deployment.  The
+// nodes are replaced one at a time and the cluster has to be healthy again
+// before the next one goes, which is checked with creds.  An empty instance
+// type is left as it is.
+type Resizer interface {
+	ResizeInstance(ctx context.Context, sdInstanceType string, zkInstanceType string, waitTimeout int, creds Credentials) error
+}
 
-	DestroyDeployment() error
+// Upgrader can be implemented by a Deployment whose plugin builds images.
+// Upgrade moves a running deployment to the base image of another Stardog
+// version.  The nodes are replaced one at a time and keep their volumes.  The
+// cluster is checked with creds after each node.
+type Upgrader interface {
+	Upgrade(ctx context.Context, version string, waitTimeout int, creds Credentials) error
+}
+
+// SnapshotDescription describes a snapshot set, the snapshots of all the
+// volumes of a deployment that were taken together.
+type SnapshotDescription struct {
+	ID          string    `json:"id"`
+	Deployment  string    `json:"deployment"`
+	Version     string    `json:"version,omitempty"`
+	Created     time.Time `json:"created"`
+	SnapshotIDs []string  `json:"snapshot_ids"`
+	VolumeSize  int       `json:"volume_size,omitempty"`
+	Complete    bool      `json:"complete"`
+}
+
+// VolumeModifier can be implemented by a Deployment whose plugin supports
+// volumes.  ModifyVolumes changes the size, the type and the IOPS per
+// gigabyte of every volume without losing the data on them.  A zero or empty
+// value keeps the current setting.  The filesystems are not grown.
+type VolumeModifier interface {
+	ModifyVolumes(ctx context.Context, size int, volumeType string, iops int, waitTimeout int) error
+}
+
+// BackupDescription describes a logical backup of one Stardog database made
+// with stardog-admin db backup.  Location is a local path or an object store
+// URL.
+type BackupDescription struct {
+	ID         string    `json:"id"`
+	Deployment string    `json:"deployment"`
+	Database   string    `json:"database"`
+	Version    string    `json:"version,omitempty"`
+	Created    time.Time `json:"created"`
+	Location   string    `json:"location"`
+	Size       int64     `json:"size,omitempty"`
+}
+
+// ObjectStore can be implemented by a Deployment whose cloud has an object
+// store that backups can be kept in.  The URLs have the form
+// scheme://bucket/key where scheme is the one returned by Scheme.
+type ObjectStore interface {
+	Scheme() string
+	UploadObject(ctx context.Context, localPath string, url string) error
+	DownloadObject(ctx context.Context, url string, localPath string) error
+}
+
+// Snapshotter can be implemented by a Deployment whose plugin supports
+// snapshots.  SnapshotVolumes returns as soon as every snapshot was started,
+// the data on the volumes may change after that.  ListSnapshots returns every
+// snapshot set that can be restored where the deployment runs and
+// RestoreVolumes creates the volume set of the deployment from one of them.
+type Snapshotter interface {
+	SnapshotVolumes(ctx context.Context) (*SnapshotDescription, error)
+	ListSnapshots(ctx context.Context) ([]SnapshotDescription, error)
+	DeleteSnapshots(ctx context.Context, id string) error
+	RestoreVolumes(ctx context.Context, id string) error
+}
+
+// ImageDescription describes a base image in the catalog of a plugin.
+type ImageDescription struct {
+	ID              string    `json:"id"`
+	Version         string    `json:"version"`
+	Region          string    `json:"region,omitempty"`
+	Architecture    string    `json:"architecture,omitempty"`
+	BaseImage       string    `json:"base_image,omitempty"`
+	SourceImage     string    `json:"source_image,omitempty"`
+	Built           time.Time `json:"built,omitempty"`
+	ReleaseChecksum string    `json:"release_sha256,omitempty"`
+	BuildLog        string    `json:"build_log,omitempty"`
+}
+
+// ImageCataloger can be implemented by a Plugin that keeps a catalog of the
+// base images it built.  DeleteImage removes the image from the cloud as well
+// as from the catalog and CopyImage makes the image available in another
+// region.
+type ImageCataloger interface {
+	ListImages(ctx context.Context, c AppContext) ([]ImageDescription, error)
+	DeleteImage(ctx context.Context, c AppContext, id string) error
+	CopyImage(ctx context.Context, c AppContext, id string, region string) (*ImageDescription, error)
 }
 
 // CommandOpts holds all of the CLI parsing information for the system.
@@ -93,29 +223,80 @@ type CommandOpts struct {
 	DestroyCmd           *kingpin.CmdClause
 	StatusCmd            *kingpin.CmdClause
 	LeaksCmd             *kingpin.CmdClause
+	PauseCmd             *kingpin.CmdClause
+	ResumeCmd            *kingpin.CmdClause
 	ClientCmd            *kingpin.CmdClause
 	SSHCmd               *kingpin.CmdClause
 	PasswdCmd            *kingpin.CmdClause
 	AboutCmd             *kingpin.CmdClause
 	BuildCmd             *kingpin.CmdClause
+	ListImagesCmd        *kingpin.CmdClause
+	ShowImageCmd         *kingpin.CmdClause
+	DeleteImageCmd       *kingpin.CmdClause
+	CopyImageCmd         *kingpin.CmdClause
 	NewDeploymentCmd     *kingpin.CmdClause
 	DestroyDeploymentCmd *kingpin.CmdClause
 	ListDeploymentCmd    *kingpin.CmdClause
+	ScaleCmd             *kingpin.CmdClause
+	UpgradeCmd           *kingpin.CmdClause
 	NewVolumesCmd        *kingpin.CmdClause
 	DestroyVolumesCmd    *kingpin.CmdClause
 	StatusVolumesCmd     *kingpin.CmdClause
+	SnapshotVolumesCmd   *kingpin.CmdClause
+	ListSnapshotsCmd     *kingpin.CmdClause
+	PruneSnapshotsCmd    *kingpin.CmdClause
+	RestoreVolumesCmd    *kingpin.CmdClause
+	ModifyVolumesCmd     *kingpin.CmdClause
+	NewBackupCmd         *kingpin.CmdClause
+	ListBackupsCmd       *kingpin.CmdClause
+	RestoreBackupCmd     *kingpin.CmdClause
+	ListDatabasesCmd     *kingpin.CmdClause
+	CreateDatabaseCmd    *kingpin.CmdClause
+	DropDatabaseCmd      *kingpin.CmdClause
+	DatabaseOptionsCmd   *kingpin.CmdClause
+	OnlineDatabaseCmd    *kingpin.CmdClause
+	OfflineDatabaseCmd   *kingpin.CmdClause
+	QueryCmd             *kingpin.CmdClause
+	LoadCmd              *kingpin.CmdClause
+	ListUsersCmd         *kingpin.CmdClause
+	AddUserCmd           *kingpin.CmdClause
+	RemoveUserCmd        *kingpin.CmdClause
+	ListRolesCmd         *kingpin.CmdClause
+	AddRoleCmd           *kingpin.CmdClause
+	RemoveRoleCmd        *kingpin.CmdClause
+	GrantRoleCmd         *kingpin.CmdClause
+	RevokeRoleCmd        *kingpin.CmdClause
+	ListPermissionsCmd   *kingpin.CmdClause
+	GrantPermissionCmd   *kingpin.CmdClause
+	RevokePermissionCmd  *kingpin.CmdClause
+	ExportPermissionsCmd *kingpin.CmdClause
+	ImportPermissionsCmd *kingpin.CmdClause
 	LaunchInstanceCmd    *kingpin.CmdClause
 	DestroyInstanceCmd   *kingpin.CmdClause
+	ResizeInstanceCmd    *kingpin.CmdClause
 	StatusInstanceCmd    *kingpin.CmdClause
 }
 
+// Capabilities says which parts of the Deployment interface a plugin really
+// implements.  The CLI refuses commands and skips launch steps for anything a
+// plugin does not support, so such a plugin may implement those methods as
+// no-ops.
+type Capabilities struct {
+	Images    bool `json:"supports_images,omitempty"`
+	Volumes   bool `json:"supports_volumes,omitempty"`
+	Firewall  bool `json:"supports_firewall,omitempty"`
+	Scaling   bool `json:"supports_scaling,omitempty"`
+	Snapshots bool `json:"supports_snapshots,omitempty"`
+}
+
 // Plugin defines the interface for adding drivers to the system
 type Plugin interface {
 	Register(cmdOpts *CommandOpts) error
-	DeploymentLoader(context AppContext, baseD *BaseDeployment, new bool) (Deployment, error)
+	DeploymentLoader(ctx context.Context, context AppContext, baseD *BaseDeployment, new bool) (Deployment, error)
 	LoadDefaults(defaultCliOpts interface{}) error
-	BuildImage(context AppContext, sdReleaseFilePath string, version string) error
+	BuildImage(ctx context.Context, context AppContext, sdReleaseFilePath string, version string) error
 	GetName() string
-	FindLeaks(context AppContext, deploymentName string, destroy bool, force bool) error
-	HaveImage(context AppContext) bool
+	FindLeaks(ctx context.Context, context AppContext, deploymentName string, destroy bool, force bool) error
+	HaveImage(ctx context.Context, context AppContext) bool
+	Capabilities() Capabilities
 }
@@ -0,0 +1,162 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+)
+
+// The steps of a launch in the order they run.
+const (
+	StepBuildImage       = "build_image"
+	StepCreateDeployment = "create_deployment"
+	StepCreateVolumes    = "create_volumes"
+	StepCreateInstance   = "create_instance"
+	StepWaitHealthy      = "wait_healthy"
+	StepChangePassword   = "change_password"
+	StepOpenInstance     = "open_instance"
+	StepWaitCluster      = "wait_cluster"
+)
+
+// JournalEntry is one step of a launch.  A step that was started but has no
+// finish time failed or was interrupted.
+type JournalEntry struct {
+	Step     string                 `json:"step"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+	Started  time.Time              `json:"started"`
+	Finished *time.Time             `json:"finished,omitempty"`
+}
+
+// Journal records the steps of a launch in the deployment directory so that
+// an unfinished launch can be resumed or rolled back.  Steps taken before
+// the deployment directory exists are only kept in memory.  All methods
+// may be called on a nil Journal, in which case every step simply runs.
+type Journal struct {
+	Entries []JournalEntry `json:"entries"`
+	dir     string
+	context AppContext
+}
+
+func journalPath(deploymentDir string) string {
+	return path.Join(deploymentDir, "launch.json")
+}
+
+// NewJournal starts an empty journal for the deployment.
+func NewJournal(context AppContext, deploymentDir string) *Journal {
+	return &Journal{dir: deploymentDir, context: context}
+}
+
+// LoadJournal returns the journal of an unfinished launch of the deployment
+// or nil when there is none.
+func LoadJournal(context AppContext, deploymentDir string) *Journal {
+	data, err := ioutil.ReadFile(journalPath(deploymentDir))
+	if err != nil {
+		return nil
+	}
+	j := NewJournal(context, deploymentDir)
+	err = json.Unmarshal(data, j)
+	if err != nil {
+		context.Logf(WARN, "The launch journal %s could not be read: %s", journalPath(deploymentDir), err)
+		return nil
+	}
+	return j
+}
+
+func (j *Journal) save() error {
+	if _, err := os.Stat(j.dir); err != nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(journalPath(j.dir), data, 0600)
+}
+
+func (j *Journal) find(step string) *JournalEntry {
+	for i := range j.Entries {
+		if j.Entries[i].Step == step {
+			return &j.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Finished reports whether the step completed in this or an earlier launch.
+func (j *Journal) Finished(step string) bool {
+	if j == nil {
+		return false
+	}
+	e := j.find(step)
+	return e != nil && e.Finished != nil
+}
+
+// Step runs fn as the named step unless the journal shows that it already
+// finished.  The step is recorded before fn runs so that a rollback also
+// undoes a step that stopped halfway.
+func (j *Journal) Step(step string, params map[string]interface{}, fn func() error) error {
+	if j == nil {
+		return fn()
+	}
+	e := j.find(step)
+	if e != nil && e.Finished != nil {
+		j.context.ConsoleLog(1, "Skipping the %s step, it finished at %s.\n", step, e.Finished.Format(time.RFC1123))
+		return nil
+	}
+	if e == nil {
+		j.Entries = append(j.Entries, JournalEntry{Step: step})
+		e = &j.Entries[len(j.Entries)-1]
+	} else if fmt.Sprint(e.Params) != fmt.Sprint(params) {
+		j.context.ConsoleLog(1, "The %s step was started with %v and is now run with %v.\n", step, e.Params, params)
+	}
+	e.Params = params
+	e.Started = time.Now()
+	err := j.save()
+	if err != nil {
+		return err
+	}
+	j.context.Logf(INFO, "Starting the launch step %s %v", step, params)
+	err = fn()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	j.find(step).Finished = &now
+	return j.save()
+}
+
+// Forget removes a step from the journal once it has been rolled back.
+func (j *Journal) Forget(step string) error {
+	for i := range j.Entries {
+		if j.Entries[i].Step == step {
+			j.Entries = append(j.Entries[:i], j.Entries[i+1:]...)
+			break
+		}
+	}
+	return j.save()
+}
+
+// Remove deletes the journal once the launch is complete.
+func (j *Journal) Remove() {
+	if j != nil {
+		os.Remove(journalPath(j.dir))
+	}
+}
@@ -0,0 +1,122 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestJournalNil(t *testing.T) {
+	var j *Journal
+	ran := false
+	err := j.Step(StepCreateInstance, nil, func() error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatal("A nil journal should run every step")
+	}
+	if j.Finished(StepCreateInstance) {
+		t.Fatal("A nil journal has no finished steps")
+	}
+	j.Remove()
+}
+
+func TestJournalResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtest")
+	if err != nil {
+		t.Fatal("Temp dir failed")
+	}
+	defer os.RemoveAll(dir)
+	app := TestContext{ConfigDir: dir}
+
+	if LoadJournal(&app, dir) != nil {
+		t.Fatal("There should be no journal yet")
+	}
+	j := NewJournal(&app, dir)
+	err = j.Step(StepCreateVolumes, map[string]interface{}{"cluster_size": 3}, func() error { return nil })
+	if err != nil {
+		t.Fatalf("The step failed %s", err)
+	}
+	err = j.Step(StepCreateInstance, nil, func() error { return fmt.Errorf("no capacity") })
+	if err == nil || err.Error() != "no capacity" {
+		t.Fatalf("The error of the step was not returned %v", err)
+	}
+
+	j = LoadJournal(&app, dir)
+	if j == nil || len(j.Entries) != 2 {
+		t.Fatalf("The journal was not saved %v", j)
+	}
+	if !j.Finished(StepCreateVolumes) || j.Finished(StepCreateInstance) {
+		t.Fatal("The wrong steps are finished")
+	}
+	if j.Entries[0].Params["cluster_size"] != float64(3) {
+		t.Fatalf("The parameters were not saved %v", j.Entries[0].Params)
+	}
+	ran := []string{}
+	for _, step := range []string{StepCreateVolumes, StepCreateInstance} {
+		err = j.Step(step, nil, func() error {
+			ran = append(ran, step)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("The step %s failed %s", step, err)
+		}
+	}
+	if len(ran) != 1 || ran[0] != StepCreateInstance {
+		t.Fatalf("Only the unfinished step should run %v", ran)
+	}
+
+	err = j.Forget(StepCreateInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j = LoadJournal(&app, dir); len(j.Entries) != 1 {
+		t.Fatalf("The step was not forgotten %v", j.Entries)
+	}
+	j.Remove()
+	if PathExists(path.Join(dir, "launch.json")) {
+		t.Fatal("The journal was not removed")
+	}
+}
+
+func TestJournalBeforeDeployment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtest")
+	if err != nil {
+		t.Fatal("Temp dir failed")
+	}
+	defer os.RemoveAll(dir)
+	app := TestContext{ConfigDir: dir}
+
+	depDir := path.Join(dir, "dep")
+	j := NewJournal(&app, depDir)
+	err = j.Step(StepBuildImage, nil, func() error { return nil })
+	if err != nil {
+		t.Fatalf("A step before the deployment exists should not fail %s", err)
+	}
+	err = j.Step(StepCreateDeployment, nil, func() error { return os.Mkdir(depDir, 0755) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	j = LoadJournal(&app, depDir)
+	if j == nil || !j.Finished(StepBuildImage) || !j.Finished(StepCreateDeployment) {
+		t.Fatalf("The steps were not saved once the deployment existed %v", j)
+	}
+}
@@ -1,70 +1,244 @@
 package sdutils
 
 import (
-	"io"
-	"fmt"
-	"net/http"
-	"io/ioutil"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
-type stardogClientImpl struct {
-	sdURL    string
-	password string
-	username string
-	logger   SdVaLogger
+const (
+	// DefaultStardogTimeout is how long one request to Stardog may take.
+	DefaultStardogTimeout = 5 * time.Minute
+	// DefaultStardogRetries is how many times a request that Stardog
+	// answered with 503 is sent again.
+	DefaultStardogRetries = 10
+	// DefaultStardogRetryDelay is the wait before the first retry.  It
+	// doubles with every retry up to MaxStardogRetryDelay.
+	DefaultStardogRetryDelay = 500 * time.Millisecond
+	// MaxStardogRetryDelay is the longest wait between two retries.
+	MaxStardogRetryDelay = 10 * time.Second
+)
+
+// StardogClient talks to the HTTP API of a Stardog server or cluster.  Every
+// request is authenticated with the basic auth credentials of Username.
+// Requests that Stardog answers with 503, which a cluster does while it is
+// still forming, are retried with a growing delay.  A client can be used by
+// several goroutines at once.
+type StardogClient struct {
+	URL        string
+	Username   string
+	Password   string
+	Timeout    time.Duration
+	Retries    int
+	RetryDelay time.Duration
+	logger     SdVaLogger
+}
+
+// StardogError is returned when Stardog answers a request with a status
+// other than 2xx.  Code is the Stardog error code when there is one.
+type StardogError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// DatabaseFile is a data file that is loaded into a database when it is
+// created.  Graph is the named graph to load it into.  The default graph is
+// used when it is empty.
+type DatabaseFile struct {
+	Path  string
+	Graph string
+}
+
+func (e *StardogError) Error() string {
+	msg := fmt.Sprintf("Stardog returned %d for %s %s", e.StatusCode, e.Method, e.URL)
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+	return msg
+}
+
+// StardogStatus returns the HTTP status of a StardogError, or 0 for any other
+// error.
+func StardogStatus(err error) int {
+	if sdErr, ok := err.(*StardogError); ok {
+		return sdErr.StatusCode
+	}
+	return 0
 }
 
+// Credentials are the Stardog user and password that graviton manages a
+// deployment with.
+type Credentials struct {
+	Username string
+	Password string
+}
 
-func (s *stardogClientImpl) doRequest(method, urlStr string, body io.Reader, contentType string, expectedCode int) ([]byte, int, error) {
-	return s.doRequestWithAccept(method, urlStr, body, contentType, contentType, expectedCode)
+// CheckLaunchCredentials tells if a new cluster can be launched with creds.
+// A new cluster only has the admin user, whose default password is changed to
+// the password of creds.
+func CheckLaunchCredentials(creds Credentials) error {
+	if creds.Username != "" && creds.Username != "admin" {
+		return fmt.Errorf("A new Stardog cluster only has the admin user so it cannot be launched as %s.  Launch it as admin and add %s afterwards", creds.Username, creds.Username)
+	}
+	return nil
+}
+
+// AdminPassword returns the password of the Stardog admin user that the
+// command line uses.  It is read from STARDOG_ADMIN_PASSWORD and is the
+// Stardog default when that is not set.
+func AdminPassword() string {
+	pw := os.Getenv("STARDOG_ADMIN_PASSWORD")
+	if pw == "" {
+		pw = "admin"
+	}
+	return pw
+}
+
+// NewStardogClient returns a client for the Stardog server at sdURL with the
+// default timeout and retries.
+func NewStardogClient(logger SdVaLogger, sdURL string, username string, password string) *StardogClient {
+	return &StardogClient{
+		URL:        strings.TrimRight(sdURL, "/"),
+		Username:   username,
+		Password:   password,
+		Timeout:    DefaultStardogTimeout,
+		Retries:    DefaultStardogRetries,
+		RetryDelay: DefaultStardogRetryDelay,
+		logger:     logger,
+	}
+}
+
+// do sends a request to the path below the Stardog URL and returns the body
+// of the response.  The body of the request is a byte slice so that it can be
+// sent again when the request is retried.
+func (s *StardogClient) do(ctx context.Context, method string, urlPath string, body []byte, contentType string, accept string) ([]byte, error) {
+	urlStr := s.URL + urlPath
+	delay := s.RetryDelay
+	for i := 0; ; i++ {
+		content, code, err := s.send(ctx, method, urlStr, bytes.NewReader(body), contentType, accept)
+		if code != http.StatusServiceUnavailable || i >= s.Retries {
+			return content, err
+		}
+		s.logger.Logf(WARN, "Stardog is not available for %s to %s, retrying in %s", method, urlStr, delay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = delay * 2
+		if delay > MaxStardogRetryDelay {
+			delay = MaxStardogRetryDelay
+		}
+	}
 }
 
-func (s *stardogClientImpl) doRequestWithAccept(method, urlStr string, body io.Reader, contentType string, accept string, expectedCode int) ([]byte, int, error) {
+func (s *StardogClient) send(ctx context.Context, method string, urlStr string, body io.Reader, contentType string, accept string) ([]byte, int, error) {
 	req, err := http.NewRequest(method, urlStr, body)
 	if err != nil {
 		return nil, -1, err
 	}
-	req.SetBasicAuth(s.username, s.password)
-	client := &http.Client{}
-	req.Header.Set("Content-Type", contentType)
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(s.Username, s.Password)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	if accept != "" {
 		req.Header.Set("Accept", accept)
 	}
+	client := &http.Client{Timeout: s.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, -1, fmt.Errorf("Failed do the post %s", err)
+		return nil, -1, fmt.Errorf("Failed to %s to %s: %s", method, urlStr, err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != expectedCode {
-		return nil, resp.StatusCode, fmt.Errorf("Expected %d but got %d when %s to %s", expectedCode, resp.StatusCode, method, urlStr)
-	}
 	content, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		sdErr := &StardogError{
+			Method:     method,
+			URL:        urlStr,
+			StatusCode: resp.StatusCode,
+			Code:       resp.Header.Get("SD-Error-Code"),
+		}
+		var msg struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+		if json.Unmarshal(content, &msg) == nil {
+			sdErr.Message = msg.Message
+			if sdErr.Code == "" {
+				sdErr.Code = msg.Code
+			}
+		} else {
+			sdErr.Message = strings.TrimSpace(string(content))
+		}
+		return nil, resp.StatusCode, sdErr
+	}
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
 	s.logger.Logf(DEBUG, "Completed %s to %s", method, urlStr)
 	return content, resp.StatusCode, nil
 }
 
-func (s *stardogClientImpl) GetClusterInfo() (*[]string, error) {
-	s.logger.Logf(DEBUG, "GetClusterInfo\n")
-
-	dbURL := fmt.Sprintf("%s/admin/cluster", s.sdURL)
-	bodyBuf := &bytes.Buffer{}
-	content, code, err := s.doRequest("GET", dbURL, bodyBuf, "application/json", 200)
-	for i := 0; code == 503; i++ {
-		if i > 10 {
-			return nil, fmt.Errorf("timeout waiting to get cluster information")
+func (s *StardogClient) doJSON(ctx context.Context, method string, urlPath string, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
 		}
-		s.logger.Logf(WARN, "The first request to admin/cluster failed")
-		time.Sleep(2 * time.Second)
-		content, code, err = s.doRequest("GET", dbURL, bodyBuf, "application/json", 200)
 	}
+	content, err := s.do(ctx, method, urlPath, body, "application/json", "application/json")
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(content, out)
+}
+
+func pathName(name string) string {
+	return url.PathEscape(name)
+}
+
+// ChangePassword sets the password of user.  The client uses the new password
+// from then on when user is the one it authenticates as.
+func (s *StardogClient) ChangePassword(ctx context.Context, user string, newPw string) error {
+	s.logger.Logf(DEBUG, "ChangePassword %s\n", user)
+
+	pwURL := fmt.Sprintf("/admin/users/%s/pwd", pathName(user))
+	err := s.doJSON(ctx, "PUT", pwURL, map[string]string{"password": newPw}, nil)
+	if err != nil {
+		return err
 	}
+	if user == s.Username {
+		s.Password = newPw
+	}
+	return nil
+}
+
+// GetClusterInfo returns the addresses of the nodes in the Stardog cluster.
+func (s *StardogClient) GetClusterInfo(ctx context.Context) (*[]string, error) {
+	s.logger.Logf(DEBUG, "GetClusterInfo\n")
+
 	var nodesMap map[string]interface{}
-	err = json.Unmarshal(content, &nodesMap)
+	err := s.doJSON(ctx, "GET", "/admin/cluster", nil, &nodesMap)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +253,7 @@ func (s *stardogClientImpl) GetClusterInfo() (*[]string, error) {
 		s.logger.Logf(DEBUG, "Interface list %s", v)
 		ifaceList = v
 	default:
-	// no match; here v has the same type as i
+		// no match; here v has the same type as i
 		return nil, fmt.Errorf("The returned cluster information was not expected %s", v)
 	}
 
@@ -88,4 +262,204 @@ func (s *stardogClientImpl) GetClusterInfo() (*[]string, error) {
 		outSList[i] = nodeI.(string)
 	}
 	return &outSList, nil
-}
\ No newline at end of file
+}
+
+// Alive returns nil when the Stardog server answers requests.
+func (s *StardogClient) Alive(ctx context.Context) error {
+	_, err := s.do(ctx, "GET", "/admin/alive", nil, "", "")
+	return err
+}
+
+// ServerStatus returns the metrics that the Stardog server reports about
+// itself and its databases.
+func (s *StardogClient) ServerStatus(ctx context.Context) (map[string]interface{}, error) {
+	s.logger.Logf(DEBUG, "ServerStatus\n")
+
+	status := make(map[string]interface{})
+	err := s.doJSON(ctx, "GET", "/admin/status", nil, &status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ListDatabases returns the names of the databases.
+func (s *StardogClient) ListDatabases(ctx context.Context) ([]string, error) {
+	s.logger.Logf(DEBUG, "ListDatabases\n")
+
+	var dbs struct {
+		Databases []string `json:"databases"`
+	}
+	err := s.doJSON(ctx, "GET", "/admin/databases", nil, &dbs)
+	if err != nil {
+		return nil, err
+	}
+	return dbs.Databases, nil
+}
+
+// CreateDatabase creates the database db with the options and loads the files
+// into it.  The files are sent in the request and the format of each is taken
+// from its extension, which may be followed by .gz.
+func (s *StardogClient) CreateDatabase(ctx context.Context, db string, options map[string]interface{}, files []DatabaseFile) error {
+	s.logger.Logf(DEBUG, "CreateDatabase %s\n", db)
+
+	if options == nil {
+		options = make(map[string]interface{})
+	}
+	type rootFile struct {
+		Filename string `json:"filename"`
+		Context  string `json:"context,omitempty"`
+	}
+	root := struct {
+		DbName  string                 `json:"dbname"`
+		Options map[string]interface{} `json:"options"`
+		Files   []rootFile             `json:"files"`
+	}{DbName: db, Options: options, Files: []rootFile{}}
+	for _, f := range files {
+		root.Files = append(root.Files, rootFile{Filename: filepath.Base(f.Path), Context: f.Graph})
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		return err
+	}
+
+	bodyBuf := &bytes.Buffer{}
+	bodyWriter := multipart.NewWriter(bodyBuf)
+	err = bodyWriter.WriteField("root", string(data))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		err = addFilePart(bodyWriter, f.Path)
+		if err != nil {
+			return err
+		}
+	}
+	err = bodyWriter.Close()
+	if err != nil {
+		return err
+	}
+	_, err = s.do(ctx, "POST", "/admin/databases", bodyBuf.Bytes(), bodyWriter.FormDataContentType(), "application/json")
+	return err
+}
+
+func addFilePart(w *multipart.Writer, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	name := filepath.Base(filePath)
+	part, err := w.CreateFormFile(name, name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
+
+// DropDatabase deletes the database db and all of its data.
+func (s *StardogClient) DropDatabase(ctx context.Context, db string) error {
+	s.logger.Logf(DEBUG, "DropDatabase %s\n", db)
+
+	_, err := s.do(ctx, "DELETE", fmt.Sprintf("/admin/databases/%s", pathName(db)), nil, "", "")
+	return err
+}
+
+// SetDatabaseOnline brings the database db online or takes it offline.
+func (s *StardogClient) SetDatabaseOnline(ctx context.Context, db string, online bool) error {
+	s.logger.Logf(DEBUG, "SetDatabaseOnline %s %t\n", db, online)
+
+	state := "offline"
+	if online {
+		state = "online"
+	}
+	dbURL := fmt.Sprintf("/admin/databases/%s/%s", pathName(db), state)
+	_, err := s.do(ctx, "PUT", dbURL, nil, "application/json", "")
+	return err
+}
+
+// GetDatabaseOptions returns the values of the named options of the database
+// db.
+func (s *StardogClient) GetDatabaseOptions(ctx context.Context, db string, names []string) (map[string]interface{}, error) {
+	s.logger.Logf(DEBUG, "GetDatabaseOptions %s\n", db)
+
+	query := make(map[string]interface{})
+	for _, n := range names {
+		query[n] = ""
+	}
+	options := make(map[string]interface{})
+	err := s.doJSON(ctx, "PUT", fmt.Sprintf("/admin/databases/%s/options", pathName(db)), query, &options)
+	if err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// SetDatabaseOptions changes the options of the database db.  Most options
+// can only be changed while the database is offline.
+func (s *StardogClient) SetDatabaseOptions(ctx context.Context, db string, options map[string]interface{}) error {
+	s.logger.Logf(DEBUG, "SetDatabaseOptions %s\n", db)
+
+	return s.doJSON(ctx, "POST", fmt.Sprintf("/admin/databases/%s/options", pathName(db)), options, nil)
+}
+
+// DatabaseSize returns the number of triples in the database db.
+func (s *StardogClient) DatabaseSize(ctx context.Context, db string) (int64, error) {
+	content, err := s.do(ctx, "GET", fmt.Sprintf("/%s/size", pathName(db)), nil, "", "text/plain")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// BeginTransaction starts a transaction on the database db and returns its
+// ID.
+func (s *StardogClient) BeginTransaction(ctx context.Context, db string) (string, error) {
+	s.logger.Logf(DEBUG, "BeginTransaction %s\n", db)
+
+	content, err := s.do(ctx, "POST", fmt.Sprintf("/%s/transaction/begin", pathName(db)), nil, "", "text/plain")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// AddData adds the RDF read from data, in the format of contentType, to the
+// named graph in the transaction txID.  The default graph is used when graph
+// is empty.  data is streamed to Stardog so the request is not retried when
+// Stardog is unavailable, the caller has to send the data again.
+func (s *StardogClient) AddData(ctx context.Context, db string, txID string, data io.Reader, contentType string, graph string) error {
+	return s.changeData(ctx, "add", db, txID, data, contentType, graph)
+}
+
+// RemoveData removes the RDF in data from the named graph in the transaction
+// txID.  See AddData.
+func (s *StardogClient) RemoveData(ctx context.Context, db string, txID string, data io.Reader, contentType string, graph string) error {
+	return s.changeData(ctx, "remove", db, txID, data, contentType, graph)
+}
+
+func (s *StardogClient) changeData(ctx context.Context, op string, db string, txID string, data io.Reader, contentType string, graph string) error {
+	dbURL := fmt.Sprintf("/%s/%s/%s", pathName(db), pathName(txID), op)
+	if graph != "" {
+		dbURL = fmt.Sprintf("%s?graph-uri=%s", dbURL, url.QueryEscape(graph))
+	}
+	_, _, err := s.send(ctx, "POST", s.URL+dbURL, data, contentType, "")
+	return err
+}
+
+// CommitTransaction commits the transaction txID.
+func (s *StardogClient) CommitTransaction(ctx context.Context, db string, txID string) error {
+	s.logger.Logf(DEBUG, "CommitTransaction %s %s\n", db, txID)
+
+	_, err := s.do(ctx, "POST", fmt.Sprintf("/%s/transaction/commit/%s", pathName(db), pathName(txID)), nil, "", "")
+	return err
+}
+
+// RollbackTransaction throws away the changes of the transaction txID.
+func (s *StardogClient) RollbackTransaction(ctx context.Context, db string, txID string) error {
+	s.logger.Logf(DEBUG, "RollbackTransaction %s %s\n", db, txID)
+
+	_, err := s.do(ctx, "POST", fmt.Sprintf("/%s/transaction/rollback/%s", pathName(db), pathName(txID)), nil, "", "")
+	return err
+}
@@ -0,0 +1,225 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStardogClientRetries(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"nodes":["10.0.0.1:5821","10.0.0.2:5821"]}`))
+	}))
+	defer ts.Close()
+
+	client := NewStardogClient(&TestContext{}, ts.URL, "admin", "admin")
+	client.RetryDelay = time.Millisecond
+	nodes, err := client.GetClusterInfo(context.Background())
+	if err != nil {
+		t.Fatalf("The request should have been retried %s", err)
+	}
+	if calls != 3 || len(*nodes) != 2 {
+		t.Fatalf("Expected 2 nodes after 3 calls but got %v after %d", *nodes, calls)
+	}
+
+	calls = 0
+	client.Retries = 1
+	_, err = client.GetClusterInfo(context.Background())
+	if StardogStatus(err) != http.StatusServiceUnavailable {
+		t.Fatalf("The 503 should be returned after the retries %s", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls but got %d", calls)
+	}
+}
+
+func TestStardogClientErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pw, ok := r.BasicAuth()
+		if !ok || user != "admin" || pw != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("SD-Error-Code", "0D0DU2")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Database 'nope' does not exist."}`))
+	}))
+	defer ts.Close()
+
+	client := NewStardogClient(&TestContext{}, ts.URL, "admin", "admin")
+	err := client.DropDatabase(context.Background(), "nope")
+	if StardogStatus(err) != http.StatusUnauthorized {
+		t.Fatalf("Expected a 401 but got %s", err)
+	}
+	client.Password = "secret"
+	err = client.DropDatabase(context.Background(), "nope")
+	sdErr, ok := err.(*StardogError)
+	if !ok {
+		t.Fatalf("Expected a StardogError but got %s", err)
+	}
+	if sdErr.StatusCode != 404 || sdErr.Code != "0D0DU2" || sdErr.Message != "Database 'nope' does not exist." || sdErr.Method != "DELETE" {
+		t.Fatalf("The error was not filled in %#v", sdErr)
+	}
+}
+
+func TestStardogClientCreateDatabase(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "stardogtest")
+	defer os.RemoveAll(dir)
+	dataFile := path.Join(dir, "rows.ttl")
+	ioutil.WriteFile(dataFile, []byte("<urn:a> <urn:b> <urn:c> ."), 0644)
+
+	var root map[string]interface{}
+	var data string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/admin/databases" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.Unmarshal([]byte(r.FormValue("root")), &root)
+		f, _, err := r.FormFile("rows.ttl")
+		if err == nil {
+			b, _ := ioutil.ReadAll(f)
+			data = string(b)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	client := NewStardogClient(&TestContext{}, ts.URL+"/", "admin", "admin")
+	opts := map[string]interface{}{"search.enabled": true}
+	err := client.CreateDatabase(context.Background(), "mydb", opts, []DatabaseFile{{Path: dataFile, Graph: "urn:g"}})
+	if err != nil {
+		t.Fatalf("The create failed %s", err)
+	}
+	if root["dbname"] != "mydb" || root["options"].(map[string]interface{})["search.enabled"] != true {
+		t.Fatalf("The database description is wrong %v", root)
+	}
+	files := root["files"].([]interface{})
+	if len(files) != 1 || files[0].(map[string]interface{})["filename"] != "rows.ttl" || files[0].(map[string]interface{})["context"] != "urn:g" {
+		t.Fatalf("The files are wrong %v", files)
+	}
+	if data != "<urn:a> <urn:b> <urn:c> ." {
+		t.Fatalf("The data file was not sent %s", data)
+	}
+}
+
+func TestStardogClientTransaction(t *testing.T) {
+	calls := []string{}
+	var added string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/mydb/transaction/begin":
+			w.Write([]byte("tx1\n"))
+		case "/mydb/tx1/add":
+			b, _ := ioutil.ReadAll(r.Body)
+			added = r.Header.Get("Content-Type") + " " + string(b)
+		case "/mydb/size":
+			w.Write([]byte("42"))
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client := NewStardogClient(&TestContext{}, ts.URL, "admin", "admin")
+	tx, err := client.BeginTransaction(ctx, "mydb")
+	if err != nil || tx != "tx1" {
+		t.Fatalf("The begin failed %s %s", tx, err)
+	}
+	err = client.AddData(ctx, "mydb", tx, strings.NewReader("<urn:a> <urn:b> <urn:c> ."), "text/turtle", "urn:g")
+	if err != nil {
+		t.Fatalf("The add failed %s", err)
+	}
+	err = client.CommitTransaction(ctx, "mydb", tx)
+	if err != nil {
+		t.Fatalf("The commit failed %s", err)
+	}
+	size, err := client.DatabaseSize(ctx, "mydb")
+	if err != nil || size != 42 {
+		t.Fatalf("The size is wrong %d %s", size, err)
+	}
+	expected := []string{
+		"POST /mydb/transaction/begin",
+		"POST /mydb/tx1/add?graph-uri=urn%3Ag",
+		"POST /mydb/transaction/commit/tx1",
+		"GET /mydb/size",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected the calls %v but got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("Expected the calls %v but got %v", expected, calls)
+		}
+	}
+	if added != "text/turtle <urn:a> <urn:b> <urn:c> ." {
+		t.Fatalf("The data was not added %s", added)
+	}
+}
+
+func TestStardogClientPermissions(t *testing.T) {
+	calls := []string{}
+	var perm StardogPermission
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if r.Method == "GET" {
+			w.Write([]byte(`{"permissions":[{"action":"read","resource_type":"db","resource":["mydb"]}]}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&perm)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client := NewStardogClient(&TestContext{}, ts.URL, "admin", "admin")
+	perms, err := client.RolePermissions(ctx, "reader")
+	if err != nil || len(perms) != 1 || perms[0].Resource[0] != "mydb" {
+		t.Fatalf("The permissions are wrong %v %s", perms, err)
+	}
+	err = client.GrantUserPermission(ctx, "bob", StardogPermission{Action: "write", ResourceType: "db", Resource: []string{"mydb"}})
+	if err != nil || perm.Action != "write" {
+		t.Fatalf("The grant failed %v %s", perm, err)
+	}
+	err = client.RevokeRolePermission(ctx, "reader", perms[0])
+	if err != nil || perm.Action != "read" {
+		t.Fatalf("The revoke failed %v %s", perm, err)
+	}
+	expected := []string{
+		"GET /admin/permissions/role/reader",
+		"PUT /admin/permissions/user/bob",
+		"POST /admin/permissions/role/reader/delete",
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("Expected the calls %v but got %v", expected, calls)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The kinds of SPARQL requests that QueryType tells apart.
+const (
+	SelectQuery    = "select"
+	AskQuery       = "ask"
+	ConstructQuery = "construct"
+	DescribeQuery  = "describe"
+	UpdateQuery    = "update"
+)
+
+// QueryOptions controls how Stardog runs a query or an update.
+type QueryOptions struct {
+	// Reasoning turns on reasoning for the query.
+	Reasoning bool
+	// Timeout is how long Stardog lets the query run.  The server default
+	// is used when it is zero.
+	Timeout time.Duration
+	// DefaultGraphs and NamedGraphs set the dataset of the query.  For an
+	// update they are the using-graph-uri and using-named-graph-uri.
+	DefaultGraphs []string
+	NamedGraphs   []string
+}
+
+var prologueRE = regexp.MustCompile(`(?is)^\s*(?:(?:#[^\n]*\n)|(?:prefix\s+[^\s:]*:\s*<[^>]*>)|(?:base\s*<[^>]*>)|\s+)*`)
+
+// QueryType returns the kind of the SPARQL request in q by looking at the
+// first keyword after the prologue.
+func QueryType(q string) (string, error) {
+	rest := q[len(prologueRE.FindString(q)):]
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("The query is empty")
+	}
+	keyword := strings.ToLower(fields[0])
+	if i := strings.IndexAny(keyword, "{*?$("); i > 0 {
+		keyword = keyword[:i]
+	}
+	switch keyword {
+	case SelectQuery, AskQuery, ConstructQuery, DescribeQuery:
+		return keyword, nil
+	case "insert", "delete", "load", "clear", "create", "drop", "copy", "move", "add", "with":
+		return UpdateQuery, nil
+	}
+	return "", fmt.Errorf("The query does not start with a SPARQL query or update keyword: %s", fields[0])
+}
+
+func (o QueryOptions) values(field string, q string, defaultParam string, namedParam string) url.Values {
+	form := url.Values{}
+	form.Set(field, q)
+	if o.Reasoning {
+		form.Set("reasoning", "true")
+	}
+	if o.Timeout > 0 {
+		form.Set("timeout", fmt.Sprintf("%d", int64(o.Timeout/time.Millisecond)))
+	}
+	for _, g := range o.DefaultGraphs {
+		form.Add(defaultParam, g)
+	}
+	for _, g := range o.NamedGraphs {
+		form.Add(namedParam, g)
+	}
+	return form
+}
+
+// Query runs the SPARQL query q against the database db and returns the
+// results in the format of accept, for example
+// application/sparql-results+json, text/csv or text/turtle.
+func (s *StardogClient) Query(ctx context.Context, db string, q string, opts QueryOptions, accept string) ([]byte, error) {
+	s.logger.Logf(DEBUG, "Query %s\n", db)
+
+	form := opts.values("query", q, "default-graph-uri", "named-graph-uri")
+	return s.do(ctx, "POST", fmt.Sprintf("/%s/query", pathName(db)), []byte(form.Encode()), "application/x-www-form-urlencoded", accept)
+}
+
+// Update runs the SPARQL update u against the database db.
+func (s *StardogClient) Update(ctx context.Context, db string, u string, opts QueryOptions) error {
+	s.logger.Logf(DEBUG, "Update %s\n", db)
+
+	form := opts.values("update", u, "using-graph-uri", "using-named-graph-uri")
+	_, err := s.do(ctx, "POST", fmt.Sprintf("/%s/update", pathName(db)), []byte(form.Encode()), "application/x-www-form-urlencoded", "")
+	return err
+}
@@ -0,0 +1,92 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryType(t *testing.T) {
+	queries := map[string]string{
+		"select * where { ?s ?p ?o }": SelectQuery,
+		"PREFIX foaf: <http://xmlns.com/foaf/0.1/>\nSELECT ?n { ?p foaf:name ?n }": SelectQuery,
+		"# a comment\nBASE <http://example.com/>\nASK { ?s ?p ?o }":                AskQuery,
+		"construct{?s ?p ?o} where {?s ?p ?o}":                                     ConstructQuery,
+		"DESCRIBE <urn:a>":                                                         DescribeQuery,
+		"prefix : <urn:x#>\ninsert data { :a :b :c }":                              UpdateQuery,
+		"DELETE WHERE { ?s ?p ?o }":                                                UpdateQuery,
+		"CLEAR ALL":                                                                UpdateQuery,
+	}
+	for q, expected := range queries {
+		qType, err := QueryType(q)
+		if err != nil || qType != expected {
+			t.Fatalf("Expected %s for %s but got %s %v", expected, q, qType, err)
+		}
+	}
+	for _, q := range []string{"", "  # only a comment\n", "explain select * {}"} {
+		_, err := QueryType(q)
+		if err == nil {
+			t.Fatalf("The query %q should be refused", q)
+		}
+	}
+}
+
+func TestStardogClientQuery(t *testing.T) {
+	var form map[string][]string
+	var accept, path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		accept = r.Header.Get("Accept")
+		path = r.URL.Path
+		w.Write([]byte("s\nurn:a\n"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client := NewStardogClient(&TestContext{}, ts.URL, "admin", "admin")
+	opts := QueryOptions{
+		Reasoning:     true,
+		Timeout:       90 * time.Second,
+		DefaultGraphs: []string{"urn:g1"},
+		NamedGraphs:   []string{"urn:g2", "urn:g3"},
+	}
+	content, err := client.Query(ctx, "mydb", "select ?s {?s ?p ?o}", opts, "text/csv")
+	if err != nil {
+		t.Fatalf("The query failed %s", err)
+	}
+	if string(content) != "s\nurn:a\n" || accept != "text/csv" || path != "/mydb/query" {
+		t.Fatalf("The query was not sent right %s %s %s", content, accept, path)
+	}
+	if form["query"][0] != "select ?s {?s ?p ?o}" || form["reasoning"][0] != "true" || form["timeout"][0] != "90000" {
+		t.Fatalf("The query parameters are wrong %v", form)
+	}
+	if form["default-graph-uri"][0] != "urn:g1" || len(form["named-graph-uri"]) != 2 {
+		t.Fatalf("The dataset is wrong %v", form)
+	}
+
+	err = client.Update(ctx, "mydb", "clear all", QueryOptions{DefaultGraphs: []string{"urn:g1"}})
+	if err != nil {
+		t.Fatalf("The update failed %s", err)
+	}
+	if path != "/mydb/update" || form["update"][0] != "clear all" || form["using-graph-uri"][0] != "urn:g1" {
+		t.Fatalf("The update was not sent right %s %v", path, form)
+	}
+}
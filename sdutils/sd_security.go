@@ -0,0 +1,192 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"context"
+	"fmt"
+)
+
+// StardogPermission allows an action on resources of one type.  The action is
+// one of read, write, create, delete, grant, revoke, execute or all.  The
+// resource type is one of db, user, role, admin, metadata, named-graph,
+// virtual-graph, icv-constraints or *.  Resource holds the names of the
+// resources and * means all of them.
+type StardogPermission struct {
+	Action       string   `json:"action"`
+	ResourceType string   `json:"resource_type"`
+	Resource     []string `json:"resource"`
+}
+
+// ListUsers returns the names of the users.
+func (s *StardogClient) ListUsers(ctx context.Context) ([]string, error) {
+	s.logger.Logf(DEBUG, "ListUsers\n")
+
+	var users struct {
+		Users []string `json:"users"`
+	}
+	err := s.doJSON(ctx, "GET", "/admin/users", nil, &users)
+	if err != nil {
+		return nil, err
+	}
+	return users.Users, nil
+}
+
+// CreateUser adds the user with password.  A superuser may do everything
+// regardless of its permissions.
+func (s *StardogClient) CreateUser(ctx context.Context, user string, password string, superuser bool) error {
+	s.logger.Logf(DEBUG, "CreateUser %s\n", user)
+
+	// Stardog takes the password as an array of characters
+	chars := []string{}
+	for _, c := range password {
+		chars = append(chars, string(c))
+	}
+	body := map[string]interface{}{
+		"username":  user,
+		"superuser": superuser,
+		"password":  chars,
+	}
+	return s.doJSON(ctx, "POST", "/admin/users", body, nil)
+}
+
+// DeleteUser removes the user.
+func (s *StardogClient) DeleteUser(ctx context.Context, user string) error {
+	s.logger.Logf(DEBUG, "DeleteUser %s\n", user)
+
+	_, err := s.do(ctx, "DELETE", fmt.Sprintf("/admin/users/%s", pathName(user)), nil, "", "")
+	return err
+}
+
+// SetUserEnabled enables or disables the user.  A disabled user cannot log in.
+func (s *StardogClient) SetUserEnabled(ctx context.Context, user string, enabled bool) error {
+	s.logger.Logf(DEBUG, "SetUserEnabled %s %t\n", user, enabled)
+
+	return s.doJSON(ctx, "PUT", fmt.Sprintf("/admin/users/%s/enabled", pathName(user)), map[string]bool{"enabled": enabled}, nil)
+}
+
+// UserRoles returns the roles of the user.
+func (s *StardogClient) UserRoles(ctx context.Context, user string) ([]string, error) {
+	s.logger.Logf(DEBUG, "UserRoles %s\n", user)
+
+	var roles struct {
+		Roles []string `json:"roles"`
+	}
+	err := s.doJSON(ctx, "GET", fmt.Sprintf("/admin/users/%s/roles", pathName(user)), nil, &roles)
+	if err != nil {
+		return nil, err
+	}
+	return roles.Roles, nil
+}
+
+// AddUserRole gives the role to the user.
+func (s *StardogClient) AddUserRole(ctx context.Context, user string, role string) error {
+	s.logger.Logf(DEBUG, "AddUserRole %s %s\n", user, role)
+
+	return s.doJSON(ctx, "POST", fmt.Sprintf("/admin/users/%s/roles", pathName(user)), map[string]string{"rolename": role}, nil)
+}
+
+// RemoveUserRole takes the role away from the user.
+func (s *StardogClient) RemoveUserRole(ctx context.Context, user string, role string) error {
+	s.logger.Logf(DEBUG, "RemoveUserRole %s %s\n", user, role)
+
+	_, err := s.do(ctx, "DELETE", fmt.Sprintf("/admin/users/%s/roles/%s", pathName(user), pathName(role)), nil, "", "")
+	return err
+}
+
+// ListRoles returns the names of the roles.
+func (s *StardogClient) ListRoles(ctx context.Context) ([]string, error) {
+	s.logger.Logf(DEBUG, "ListRoles\n")
+
+	var roles struct {
+		Roles []string `json:"roles"`
+	}
+	err := s.doJSON(ctx, "GET", "/admin/roles", nil, &roles)
+	if err != nil {
+		return nil, err
+	}
+	return roles.Roles, nil
+}
+
+// CreateRole adds the role.
+func (s *StardogClient) CreateRole(ctx context.Context, role string) error {
+	s.logger.Logf(DEBUG, "CreateRole %s\n", role)
+
+	return s.doJSON(ctx, "POST", "/admin/roles", map[string]string{"rolename": role}, nil)
+}
+
+// DeleteRole removes the role.  Stardog refuses to remove a role that users
+// still have unless force is set.
+func (s *StardogClient) DeleteRole(ctx context.Context, role string, force bool) error {
+	s.logger.Logf(DEBUG, "DeleteRole %s\n", role)
+
+	roleURL := fmt.Sprintf("/admin/roles/%s?force=%t", pathName(role), force)
+	_, err := s.do(ctx, "DELETE", roleURL, nil, "", "")
+	return err
+}
+
+// UserPermissions returns the permissions that were granted to the user
+// itself, not those it has through its roles.
+func (s *StardogClient) UserPermissions(ctx context.Context, user string) ([]StardogPermission, error) {
+	return s.listPermissions(ctx, "user", user)
+}
+
+// RolePermissions returns the permissions of the role.
+func (s *StardogClient) RolePermissions(ctx context.Context, role string) ([]StardogPermission, error) {
+	return s.listPermissions(ctx, "role", role)
+}
+
+// GrantUserPermission grants the permission to the user.
+func (s *StardogClient) GrantUserPermission(ctx context.Context, user string, perm StardogPermission) error {
+	return s.changePermission(ctx, "user", user, perm, true)
+}
+
+// RevokeUserPermission revokes the permission from the user.
+func (s *StardogClient) RevokeUserPermission(ctx context.Context, user string, perm StardogPermission) error {
+	return s.changePermission(ctx, "user", user, perm, false)
+}
+
+// GrantRolePermission grants the permission to the role.
+func (s *StardogClient) GrantRolePermission(ctx context.Context, role string, perm StardogPermission) error {
+	return s.changePermission(ctx, "role", role, perm, true)
+}
+
+// RevokeRolePermission revokes the permission from the role.
+func (s *StardogClient) RevokeRolePermission(ctx context.Context, role string, perm StardogPermission) error {
+	return s.changePermission(ctx, "role", role, perm, false)
+}
+
+func (s *StardogClient) listPermissions(ctx context.Context, kind string, name string) ([]StardogPermission, error) {
+	s.logger.Logf(DEBUG, "listPermissions %s %s\n", kind, name)
+
+	var perms struct {
+		Permissions []StardogPermission `json:"permissions"`
+	}
+	err := s.doJSON(ctx, "GET", fmt.Sprintf("/admin/permissions/%s/%s", kind, pathName(name)), nil, &perms)
+	if err != nil {
+		return nil, err
+	}
+	return perms.Permissions, nil
+}
+
+func (s *StardogClient) changePermission(ctx context.Context, kind string, name string, perm StardogPermission, grant bool) error {
+	s.logger.Logf(DEBUG, "changePermission %s %s %s %t\n", kind, name, perm.Action, grant)
+
+	if grant {
+		return s.doJSON(ctx, "PUT", fmt.Sprintf("/admin/permissions/%s/%s", kind, pathName(name)), perm, nil)
+	}
+	return s.doJSON(ctx, "POST", fmt.Sprintf("/admin/permissions/%s/%s/delete", kind, pathName(name)), perm, nil)
+}
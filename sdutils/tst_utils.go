@@ -16,7 +16,11 @@
 package sdutils
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
 )
 
 // TestContext is used for mocking out a context in many tests
@@ -30,6 +34,11 @@ func (c *TestContext) GetInteractive() bool {
 	return true
 }
 
+// Prompt refuses every question so that a test never waits on stdin.
+func (c *TestContext) Prompt(prompt string, defaultValue string) (string, error) {
+	return "", fmt.Errorf("No answer for '%s' in a test", prompt)
+}
+
 func (c *TestContext) Logf(level int, format string, v ...interface{}) {
 }
 
@@ -56,6 +65,37 @@ func (c *TestContext) FailString(a ...interface{}) string {
 	return fmt.Sprint(a...)
 }
 
+// CreateTestExec writes a file to the system for use as a mock for an external
+// program in testing.  The program prints output, exits with rc and records
+// its arguments in the file params next to it.
+func CreateTestExec(pgmName string, output string, rc int) (string, string, error) {
+	exedir, err := ioutil.TempDir("/tmp", "stardogtest")
+	if err != nil {
+		return "", "", err
+	}
+
+	dataFile := path.Join(exedir, "datafile")
+	err = ioutil.WriteFile(dataFile, []byte(output), 0644)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to write the file %s", err)
+	}
+	paramsFile := path.Join(exedir, "params")
+
+	exeTemplate := fmt.Sprintf("#!/usr/bin/env bash\necho ${@} > %s\ncat %s\nexit %d", paramsFile, dataFile, rc)
+	exeFile := path.Join(exedir, pgmName)
+	err = ioutil.WriteFile(exeFile, []byte(exeTemplate), 0755)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to write the file %s", err)
+	}
+	startPath := os.Getenv("PATH")
+	newPath := fmt.Sprintf("%s:%s", exedir, startPath)
+	err = os.Setenv("PATH", newPath)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to set env %s", err)
+	}
+	return exedir, exeFile, nil
+}
+
 type tstPlugin struct {
 	HasImage bool
 	Dep      Deployment
@@ -65,7 +105,7 @@ func (tp *tstPlugin) Register(cmdOpts *CommandOpts) error {
 	return nil
 }
 
-func (tp *tstPlugin) DeploymentLoader(context AppContext, baseD *BaseDeployment, new bool) (Deployment, error) {
+func (tp *tstPlugin) DeploymentLoader(ctx context.Context, context AppContext, baseD *BaseDeployment, new bool) (Deployment, error) {
 	if tp.Dep == nil {
 		return nil, fmt.Errorf("This test plugin loads no deployment")
 	}
@@ -76,7 +116,7 @@ func (tp *tstPlugin) LoadDefaults(defaultCliOpts interface{}) error {
 	return nil
 }
 
-func (tp *tstPlugin) BuildImage(context AppContext, sdReleaseFilePath string, version string) error {
+func (tp *tstPlugin) BuildImage(ctx context.Context, context AppContext, sdReleaseFilePath string, version string) error {
 	return nil
 }
 
@@ -84,29 +124,33 @@ func (tp *tstPlugin) GetName() string {
 	return "test1loaderror"
 }
 
-func (tp *tstPlugin) FindLeaks(context AppContext, deploymentName string, destroy bool, force bool) error {
+func (tp *tstPlugin) FindLeaks(ctx context.Context, context AppContext, deploymentName string, destroy bool, force bool) error {
 	return nil
 }
 
-func (tp *tstPlugin) HaveImage(context AppContext) bool {
+func (tp *tstPlugin) HaveImage(ctx context.Context, context AppContext) bool {
 	return tp.HasImage
 }
 
+func (tp *tstPlugin) Capabilities() Capabilities {
+	return Capabilities{Images: true, Volumes: true, Firewall: true}
+}
+
 type tpDeployment struct {
 	TstInstanceExists bool
 	TstVolumeExists   bool
 	SdDesc            *StardogDescription
 }
 
-func (tstDep *tpDeployment) CreateVolumeSet(licensePath string, sizeOfEachVolume int, clusterSize int) error {
+func (tstDep *tpDeployment) CreateVolumeSet(ctx context.Context, licensePath string, sizeOfEachVolume int, clusterSize int) error {
 	return nil
 }
 
-func (tstDep *tpDeployment) DeleteVolumeSet() error {
+func (tstDep *tpDeployment) DeleteVolumeSet(ctx context.Context) error {
 	return nil
 }
 
-func (tstDep *tpDeployment) StatusVolumeSet() error {
+func (tstDep *tpDeployment) StatusVolumeSet(ctx context.Context) error {
 	return nil
 }
 
@@ -114,19 +158,19 @@ func (tstDep *tpDeployment) VolumeExists() bool {
 	return tstDep.TstVolumeExists
 }
 
-func (tstDep *tpDeployment) CreateInstance(rootSize int, zookeeperSize int, idleTimeout int) error {
+func (tstDep *tpDeployment) CreateInstance(ctx context.Context, rootSize int, zookeeperSize int, idleTimeout int) error {
 	return nil
 }
 
-func (tstDep *tpDeployment) OpenInstance(rootSize int, zookeeperSize int, mask string, idleTimeout int) error {
+func (tstDep *tpDeployment) OpenInstance(ctx context.Context, rootSize int, zookeeperSize int, mask string, idleTimeout int) error {
 	return nil
 }
 
-func (tstDep *tpDeployment) DeleteInstance() error {
+func (tstDep *tpDeployment) DeleteInstance(ctx context.Context) error {
 	return nil
 }
 
-func (tstDep *tpDeployment) StatusInstance() error {
+func (tstDep *tpDeployment) StatusInstance(ctx context.Context) error {
 	return nil
 }
 
@@ -138,7 +182,7 @@ func (tstDep *tpDeployment) InstanceExists() bool {
 	return tstDep.TstInstanceExists
 }
 
-func (tstDep *tpDeployment) FullStatus() (*StardogDescription, error) {
+func (tstDep *tpDeployment) FullStatus(ctx context.Context) (*StardogDescription, error) {
 	return tstDep.SdDesc, nil
 }
 
@@ -146,6 +190,6 @@ func (tstDep *tpDeployment) ClusterSize() (int, error) {
 	return 1, nil
 }
 
-func (tstDep *tpDeployment) DestroyDeployment() error {
+func (tstDep *tpDeployment) DestroyDeployment(ctx context.Context) error {
 	return nil
 }
@@ -16,15 +16,21 @@
 package sdutils
 
 import (
+	"archive/tar"
 	"bufio"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"crypto/rand"
 	"crypto/rsa"
@@ -34,14 +40,24 @@ import (
 
 	"github.com/fatih/color"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 type validatorFunc func(key string) (interface{}, error)
 
+// Prompter answers the questions that graviton and the plugins ask through
+// the Prompt method of their AppContext.
+type Prompter func(prompt string, defaultValue string) (string, error)
+
 // AskUser prompts a console user to enter input.  prompt is the string
 // that will be displayed to them and defaultValue will be the result if
 // the user just hits enter.
 func AskUser(prompt string, defaultValue string) (string, error) {
+	return ConsolePrompt(prompt, defaultValue)
+}
+
+// ConsolePrompt is the default Prompter.  It reads the answer from stdin.
+func ConsolePrompt(prompt string, defaultValue string) (string, error) {
 	reader := bufio.NewReader(os.Stdin)
 	if defaultValue != "" {
 		prompt = fmt.Sprintf("%s (%s)", prompt, defaultValue)
@@ -58,6 +74,38 @@ func AskUser(prompt string, defaultValue string) (string, error) {
 	return resultValue, nil
 }
 
+// ReadSecret reads a password without it ever being on the command line.  On
+// a terminal it prompts for it without echo, twice when confirm is set.
+// Otherwise the first line of stdin is the secret.
+func ReadSecret(prompt string, confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("Failed to read the secret from stdin: %s", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	fmt.Print(color.WhiteString("%s: ", prompt))
+	secret, err := terminal.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		fmt.Print(color.WhiteString("%s again: ", prompt))
+		again, err := terminal.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(secret) {
+			return "", fmt.Errorf("The two entries do not match")
+		}
+	}
+	return string(secret), nil
+}
+
 // AskUserYesOrNo is just a convenience wrapper around AskUser that looks for
 // a yes or no answer.  A case insensitive yes will return true and all other
 // values will return false.
@@ -69,6 +117,17 @@ func AskUserYesOrNo(prompt string) bool {
 	return strings.ToLower(res) == "yes"
 }
 
+// AskYesOrNo is AskUserYesOrNo for code that is handed an AppContext.  The
+// answer comes from the Prompt of the context so that a program embedding
+// graviton decides how questions are answered.
+func AskYesOrNo(c AppContext, prompt string) bool {
+	res, err := c.Prompt(prompt, "yes/no")
+	if err != nil {
+		return false
+	}
+	return strings.ToLower(res) == "yes"
+}
+
 func askUserInteractive(prompt string, defaultValue string, skipIfDefault bool, vf validatorFunc) (interface{}, error) {
 	if skipIfDefault && defaultValue != "" {
 		return vf(defaultValue)
@@ -151,6 +210,11 @@ func (s *Spinner) EchoNext() {
 	}
 }
 
+// SetMessage changes the message shown next to the spinner.
+func (s *Spinner) SetMessage(message string) {
+	s.message = message
+}
+
 // Close ends the spinner session.
 func (s *Spinner) Close() {
 	s.context.ConsoleLog(s.level, "\n")
@@ -168,10 +232,31 @@ type ScanResult struct {
 // results in a ScanResult if it finds something.  It may return nil
 type LineScanner func(cliContext AppContext, line string) *ScanResult
 
+// InterruptedError is returned by RunCommand when the program was stopped
+// because the context was cancelled.
+type InterruptedError struct {
+	Program string
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("The program %s was interrupted", e.Program)
+}
+
 // RunCommand will fork and execute a command in the shell.  The lineScanner object
-// will be used to collect output and return it to the caller.
-func RunCommand(cliContext AppContext, cmd exec.Cmd, lineScanner LineScanner, spinner *Spinner) (*[]ScanResult, error) {
+// will be used to collect output and return it to the caller.  The program
+// runs in its own process group so that a Ctrl-C at the terminal does not
+// reach it directly.  When ctx is cancelled it is sent a single SIGINT,
+// which lets tools like terraform and packer stop cleanly, and RunCommand
+// waits for it to exit.
+func RunCommand(ctx context.Context, cliContext AppContext, cmd exec.Cmd, lineScanner LineScanner, spinner *Spinner) (*[]ScanResult, error) {
 	cliContext.Logf(DEBUG, "Start the program %s\n", cmd.Args[0])
+	if ctx.Err() != nil {
+		return nil, &InterruptedError{Program: cmd.Args[0]}
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -196,6 +281,17 @@ func RunCommand(cliContext AppContext, cmd exec.Cmd, lineScanner LineScanner, sp
 	}
 	cliContext.Logf(DEBUG, "Started the program %s\n", cmd.Args[0])
 
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cliContext.Logf(WARN, "Interrupting the program %s", cmd.Args[0])
+			cmd.Process.Signal(os.Interrupt)
+		case <-exited:
+		}
+	}()
+
 	var scanResults []ScanResult
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
@@ -217,6 +313,10 @@ func RunCommand(cliContext AppContext, cmd exec.Cmd, lineScanner LineScanner, sp
 
 	cliContext.Logf(DEBUG, "Waiting for the program %s to complete\n", cmd.Args[0])
 	err = cmd.Wait()
+	if ctx.Err() != nil {
+		cliContext.Logf(WARN, "The program %s stopped after the interrupt: %v\n", cmd.Args[0], err)
+		return nil, &InterruptedError{Program: cmd.Args[0]}
+	}
 	if err != nil {
 		cliContext.Logf(ERROR, "The program %s failed to complete: %s\n", cmd.Args[0], err)
 		return nil, err
@@ -380,3 +480,103 @@ func ValueStringToInt(i string) (int, error) {
 	}
 	return base * mult, nil
 }
+
+// TarDirectory writes the files found directly in dir into a gzipped tarball.
+func TarDirectory(dir string, outfile string) error {
+	f, err := os.Create(outfile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	files, err := filepath.Glob(path.Join(dir, "*"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		hdr := tar.Header{Name: path.Base(file), Mode: 0644, Size: fi.Size(), ModTime: fi.ModTime()}
+		err = tw.WriteHeader(&hdr)
+		if err != nil {
+			return err
+		}
+		lf, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(tw, lf, fi.Size())
+		lf.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionParts returns the numbers at the start of each dotted part of a
+// Stardog version.  4.2.4 becomes [4 2 4] and 5.0-beta becomes [5 0].
+func versionParts(version string) []int {
+	parts := []int{}
+	for _, p := range strings.Split(version, ".") {
+		end := 0
+		for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(p[:end])
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// CompareVersions returns -1, 0 or 1 when the Stardog version a is older,
+// the same or newer than b.
+func CompareVersions(a string, b string) int {
+	ap := versionParts(a)
+	bp := versionParts(b)
+	for i := 0; i < len(ap) || i < len(bp); i++ {
+		an, bn := 0, 0
+		if i < len(ap) {
+			an = ap[i]
+		}
+		if i < len(bp) {
+			bn = bp[i]
+		}
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+	}
+	return 0
+}
+
+// CheckDataVersion returns an error when Stardog version cannot run on the
+// data written by dataVersion.  Stardog only reads the data of its own major
+// version, and it may not be moved back to an older release unless that
+// release is previousVersion, the one the deployment ran before its last
+// upgrade.
+func CheckDataVersion(dataVersion string, version string, previousVersion string) error {
+	dp := versionParts(dataVersion)
+	vp := versionParts(version)
+	if len(dp) == 0 || len(vp) == 0 {
+		return fmt.Errorf("The Stardog versions %s and %s cannot be compared", dataVersion, version)
+	}
+	if dp[0] != vp[0] {
+		return fmt.Errorf("The data written by Stardog %s has to be migrated before Stardog %s can use it", dataVersion, version)
+	}
+	if CompareVersions(version, dataVersion) < 0 && version != previousVersion {
+		return fmt.Errorf("The data written by Stardog %s cannot be used by the older Stardog %s", dataVersion, version)
+	}
+	return nil
+}
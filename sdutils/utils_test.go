@@ -0,0 +1,107 @@
+//
+//  Copyright (c) 2017, Stardog Union. <http://stardog.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdutils
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestRunCommandInterrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stardogtest")
+	if err != nil {
+		t.Fatalf("Failed to make a temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The program only exits once it has been interrupted
+	marker := path.Join(dir, "interrupted")
+	script := path.Join(dir, "slowtool")
+	body := "#!/usr/bin/env bash\ntrap 'echo stopping; touch " + marker + "; exit 1' INT\necho started\nwhile true; do sleep 0.1; done\n"
+	err = ioutil.WriteFile(script, []byte(body), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write the script %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+	}()
+	app := TestContext{ConfigDir: dir}
+	cmd := exec.Cmd{
+		Path: script,
+		Args: []string{script},
+	}
+	_, err = RunCommand(ctx, &app, cmd, nil, nil)
+	if _, ok := err.(*InterruptedError); !ok {
+		t.Fatalf("The command should have been interrupted, got %v", err)
+	}
+	if !PathExists(marker) {
+		t.Fatal("The program did not get the interrupt")
+	}
+}
+
+func TestRunCommandCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	app := TestContext{}
+	cmd := exec.Cmd{
+		Path: "/bin/true",
+		Args: []string{"/bin/true"},
+	}
+	_, err := RunCommand(ctx, &app, cmd, nil, nil)
+	if _, ok := err.(*InterruptedError); !ok {
+		t.Fatalf("The command should not have started, got %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		cmp  int
+	}{
+		{"4.2.4", "4.2.4", 0},
+		{"4.2", "4.2.0", 0},
+		{"4.2.4", "4.10", -1},
+		{"5.0-beta", "4.2.4", 1},
+	}
+	for _, c := range cases {
+		if CompareVersions(c.a, c.b) != c.cmp {
+			t.Fatalf("Comparing %s to %s should give %d", c.a, c.b, c.cmp)
+		}
+	}
+}
+
+func TestCheckDataVersion(t *testing.T) {
+	if err := CheckDataVersion("4.2.1", "4.2.4", ""); err != nil {
+		t.Fatalf("A patch upgrade should be allowed %s", err)
+	}
+	if CheckDataVersion("4.2.4", "5.0.0", "") == nil {
+		t.Fatal("A major upgrade should be refused")
+	}
+	if CheckDataVersion("4.2.4", "4.2.1", "") == nil {
+		t.Fatal("A downgrade should be refused")
+	}
+	if err := CheckDataVersion("4.2.4", "4.2.1", "4.2.1"); err != nil {
+		t.Fatalf("Going back to the previous version should be allowed %s", err)
+	}
+}